@@ -28,6 +28,7 @@ const EnvVaultTLSServerName = "VAULT_TLS_SERVER_NAME"
 const EnvVaultWrapTTL = "VAULT_WRAP_TTL"
 const EnvVaultMaxRetries = "VAULT_MAX_RETRIES"
 const EnvVaultToken = "VAULT_TOKEN"
+const EnvVaultNamespace = "VAULT_NAMESPACE"
 
 // WrappingLookupFunc is a function that, given an HTTP verb and a path,
 // returns an optional string duration to be used for response wrapping (e.g.
@@ -223,6 +224,7 @@ type Client struct {
 	addr               *url.URL
 	config             *Config
 	token              string
+	namespace          string
 	wrappingLookupFunc WrappingLookupFunc
 }
 
@@ -230,7 +232,8 @@ type Client struct {
 //
 // If the environment variable `VAULT_TOKEN` is present, the token will be
 // automatically added to the client. Otherwise, you must manually call
-// `SetToken()`.
+// `SetToken()`. Likewise, `VAULT_NAMESPACE` sets the namespace requests are
+// made in.
 func NewClient(c *Config) (*Client, error) {
 	if c == nil {
 		c = DefaultConfig()
@@ -278,6 +281,10 @@ func NewClient(c *Config) (*Client, error) {
 		client.SetToken(token)
 	}
 
+	if namespace := os.Getenv(EnvVaultNamespace); namespace != "" {
+		client.SetNamespace(namespace)
+	}
+
 	return client, nil
 }
 
@@ -326,6 +333,22 @@ func (c *Client) ClearToken() {
 	c.token = ""
 }
 
+// Namespace returns the path of the namespace requests are made in. It will
+// return the empty string for the root namespace.
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// SetNamespace sets the path of the namespace requests are made in.
+func (c *Client) SetNamespace(namespace string) {
+	c.namespace = namespace
+}
+
+// ClearNamespace makes requests in the root namespace.
+func (c *Client) ClearNamespace() {
+	c.namespace = ""
+}
+
 // NewRequest creates a new raw request object to query the Vault server
 // configured for this client. This is an advanced method and generally
 // doesn't need to be called externally.
@@ -339,6 +362,7 @@ func (c *Client) NewRequest(method, path string) *Request {
 			Path:   path,
 		},
 		ClientToken: c.token,
+		Namespace:   c.namespace,
 		Params:      make(map[string][]string),
 	}
 
@@ -44,7 +44,17 @@ func (c *Client) Logical() *Logical {
 }
 
 func (c *Logical) Read(path string) (*Secret, error) {
+	return c.ReadWithData(path, nil)
+}
+
+// ReadWithData performs a read, passing the given data as query parameters
+func (c *Logical) ReadWithData(path string, data map[string][]string) (*Secret, error) {
 	r := c.c.NewRequest("GET", "/v1/"+path)
+	for k, v := range data {
+		for _, val := range v {
+			r.Params.Add(k, val)
+		}
+	}
 	resp, err := c.c.RawRequest(r)
 	if resp != nil {
 		defer resp.Body.Close()
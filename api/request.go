@@ -16,6 +16,7 @@ type Request struct {
 	Params      url.Values
 	ClientToken string
 	WrapTTL     string
+	Namespace   string
 	Obj         interface{}
 	Body        io.Reader
 	BodySize    int64
@@ -68,5 +69,9 @@ func (r *Request) ToHTTP() (*http.Request, error) {
 		req.Header.Set("X-Vault-Wrap-TTL", r.WrapTTL)
 	}
 
+	if len(r.Namespace) != 0 {
+		req.Header.Set("X-Vault-Namespace", r.Namespace)
+	}
+
 	return req, nil
 }
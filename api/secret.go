@@ -40,6 +40,7 @@ type Secret struct {
 // available in WrappedAccessor.
 type SecretWrapInfo struct {
 	Token           string    `json:"token"`
+	Accessor        string    `json:"accessor"`
 	TTL             int       `json:"ttl"`
 	CreationTime    time.Time `json:"creation_time"`
 	WrappedAccessor string    `json:"wrapped_accessor"`
@@ -52,8 +53,9 @@ type SecretAuth struct {
 	Policies    []string          `json:"policies"`
 	Metadata    map[string]string `json:"metadata"`
 
-	LeaseDuration int  `json:"lease_duration"`
-	Renewable     bool `json:"renewable"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+	TokenType     string `json:"token_type"`
 }
 
 // ParseSecret is used to parse a secret value from JSON from an io.Reader.
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+func (c *Sys) ListNamespaces() ([]string, error) {
+	r := c.c.NewRequest("LIST", "/v1/sys/namespaces")
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var result struct {
+		Keys []string `mapstructure:"keys"`
+	}
+	if err := mapstructure.Decode(secret.Data, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Keys, nil
+}
+
+func (c *Sys) GetNamespace(name string) (*NamespaceOutput, error) {
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/namespaces/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNamespaceOutput(resp)
+}
+
+func (c *Sys) CreateNamespace(name string) (*NamespaceOutput, error) {
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/sys/namespaces/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return parseNamespaceOutput(resp)
+}
+
+func (c *Sys) DeleteNamespace(name string) error {
+	r := c.c.NewRequest("DELETE", fmt.Sprintf("/v1/sys/namespaces/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+func parseNamespaceOutput(resp *Response) (*NamespaceOutput, error) {
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var result NamespaceOutput
+	if err := mapstructure.Decode(secret.Data, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+type NamespaceOutput struct {
+	ID   string `json:"id" structs:"id" mapstructure:"id"`
+	Path string `json:"path" structs:"path" mapstructure:"path"`
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/fatih/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+func (c *Sys) ListPlugins() ([]string, error) {
+	r := c.c.NewRequest("LIST", "/v1/sys/plugins/catalog")
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var result struct {
+		Keys []string `mapstructure:"keys"`
+	}
+	if err := mapstructure.Decode(secret.Data, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Keys, nil
+}
+
+func (c *Sys) GetPlugin(name string) (*PluginOutput, error) {
+	r := c.c.NewRequest("GET", fmt.Sprintf("/v1/sys/plugins/catalog/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var result PluginOutput
+	if err := mapstructure.Decode(secret.Data, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Sys) RegisterPlugin(name string, pluginInfo *PluginInput) error {
+	body := structs.Map(pluginInfo)
+
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/sys/plugins/catalog/%s", name))
+	if err := r.SetJSONBody(body); err != nil {
+		return err
+	}
+
+	resp, err := c.c.RawRequest(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (c *Sys) DeregisterPlugin(name string) error {
+	r := c.c.NewRequest("DELETE", fmt.Sprintf("/v1/sys/plugins/catalog/%s", name))
+	resp, err := c.c.RawRequest(r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+type PluginInput struct {
+	Command string `json:"command" structs:"command"`
+	SHA256  string `json:"sha256" structs:"sha256"`
+}
+
+type PluginOutput struct {
+	Name    string   `json:"name" structs:"name" mapstructure:"name"`
+	Command string   `json:"command" structs:"command" mapstructure:"command"`
+	Args    []string `json:"args" structs:"args" mapstructure:"args"`
+	SHA256  string   `json:"sha256" structs:"sha256" mapstructure:"sha256"`
+}
@@ -63,6 +63,12 @@ func (b *backend) pathLogin(
 				"org":      *verifyResp.Org.Login,
 			},
 			DisplayName: *verifyResp.User.Login,
+			Alias: &logical.Alias{
+				Name: *verifyResp.User.Login,
+				Metadata: map[string]string{
+					"org": *verifyResp.Org.Login,
+				},
+			},
 			LeaseOptions: logical.LeaseOptions{
 				TTL:       ttl,
 				Renewable: true,
@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	return Backend().Setup(conf)
+}
+
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathRoleList(&b),
+			pathRole(&b),
+			pathGroupsList(&b),
+			pathGroups(&b),
+			pathLogin(&b),
+		},
+
+		Invalidate: b.invalidate,
+		AuthRenew:  b.pathLoginRenew,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// keys caches the public keys of the current configuration, which for
+	// a JWKS URL saves fetching them on every login. keysTime is when they
+	// were parsed or fetched, and keysAttempt when that was last attempted.
+	keys        []*publicKey
+	keysTime    time.Time
+	keysAttempt time.Time
+	keysLock    sync.RWMutex
+}
+
+func (b *backend) invalidate(key string) {
+	switch key {
+	case "config":
+		b.resetKeys()
+	}
+}
+
+func (b *backend) resetKeys() {
+	b.keysLock.Lock()
+	b.keys = nil
+	b.keysTime = time.Time{}
+	b.keysAttempt = time.Time{}
+	b.keysLock.Unlock()
+}
+
+const backendHelp = `
+The JWT credential provider allows authentication with JSON Web Tokens
+signed by a trusted issuer, such as an OIDC provider or a Kubernetes service
+account token.
+
+The keys tokens are validated with are set through the "config" endpoint,
+either as PEM encoded public keys or as a JWKS document. Roles created
+through the "role" endpoint bind the claims a token must have and the
+policies it is granted. Authentication is then done by supplying a role and
+a token to the "login" endpoint.
+`
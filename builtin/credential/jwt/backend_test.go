@@ -0,0 +1,450 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func testBackend(t *testing.T) (*backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend()
+	if _, err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr:%v", resp, err)
+	}
+	return resp
+}
+
+func testRequestError(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected error: resp: %#v", resp)
+	}
+}
+
+// testSign creates a compact JWT with the given header and claims
+func testSign(t *testing.T, key crypto.Signer, alg, kid string, claims map[string]interface{}) string {
+	header := map[string]interface{}{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signingInput := encode(header) + "." + encode(claims)
+
+	method := signingMethods[alg]
+	h := method.hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	var signature []byte
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, method.hash, digest)
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func testClaims(overrides map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss":    "https://issuer.example.com",
+		"sub":    "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients",
+		"aud":    []string{"vault", "other"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"nbf":    time.Now().Add(-time.Minute).Unix(),
+		"email":  "jane@example.com",
+		"team":   "ops",
+		"groups": []string{"admins", "users"},
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	return claims
+}
+
+func TestBackend_PEM(t *testing.T) {
+	b, s := testBackend(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"jwt_validation_pubkeys": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		"bound_issuer":           "https://issuer.example.com",
+	})
+
+	testRequest(t, b, s, logical.UpdateOperation, "role/test", map[string]interface{}{
+		"policies":        "foo,bar",
+		"bound_audiences": "vault",
+		"bound_subject":   "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients",
+		"bound_claims":    map[string]interface{}{"team": []interface{}{"ops", "dev"}},
+		"user_claim":      "email",
+		"claim_mappings":  map[string]interface{}{"team": "team_name"},
+		"ttl":             "1h",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "role/test", nil)
+	if resp.Data["user_claim"] != "email" || resp.Data["ttl"] != int64(3600) ||
+		!reflect.DeepEqual(resp.Data["bound_claims"], map[string]interface{}{"team": []string{"ops", "dev"}}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "test",
+		"jwt":  testSign(t, key, "RS256", "", testClaims(nil)),
+	})
+	if resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	sort.Strings(resp.Auth.Policies)
+	if !reflect.DeepEqual(resp.Auth.Policies, []string{"bar", "foo"}) {
+		t.Fatalf("bad: %#v", resp.Auth.Policies)
+	}
+	expected := map[string]string{"role": "test", "team_name": "ops"}
+	if !reflect.DeepEqual(resp.Auth.Metadata, expected) {
+		t.Fatalf("bad: %#v", resp.Auth.Metadata)
+	}
+	if resp.Auth.Alias.Name != "jane@example.com" || resp.Auth.DisplayName != "jane@example.com" || resp.Auth.TTL != time.Hour {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"wrong key":      testSign(t, other, "RS256", "", testClaims(nil)),
+		"wrong key type": testSign(t, ecKey, "ES256", "", testClaims(nil)),
+		"expired":        testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"no expiration":  testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"exp": nil})),
+		"not yet valid":  testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})),
+		"issuer":         testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"iss": "https://other.example.com"})),
+		"audience":       testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"aud": "other"})),
+		"no audience":    testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"aud": nil})),
+		"subject":        testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"sub": "someone"})),
+		"bound claim":    testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"team": "sales"})),
+		"missing claim":  testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"team": nil})),
+		"no user":        testSign(t, key, "RS256", "", testClaims(map[string]interface{}{"email": nil})),
+		"unsigned":       base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{}`)) + ".",
+		"malformed":      "foo.bar",
+	}
+	for name, token := range cases {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   s,
+			Data:      map[string]interface{}{"role": "test", "jwt": token},
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("%s: expected error: resp: %#v", name, resp)
+		}
+	}
+
+	testRequestError(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "missing",
+		"jwt":  testSign(t, key, "RS256", "", testClaims(nil)),
+	})
+}
+
+func TestBackend_JWKS(t *testing.T) {
+	b, s := testBackend(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk := func(kid string, k *ecdsa.PrivateKey) map[string]interface{} {
+		return map[string]interface{}{
+			"kty": "EC",
+			"kid": kid,
+			"use": "sig",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(k.X.Bytes()),
+			"y":   base64.RawURLEncoding.EncodeToString(k.Y.Bytes()),
+		}
+	}
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []interface{}{jwk("key-1", key), jwk("key-2", other)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"jwks": string(jwks),
+	})
+
+	testRequest(t, b, s, logical.UpdateOperation, "role/test", map[string]interface{}{
+		"bound_audiences": "vault",
+		"groups_claim":    "groups",
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "groups/admins", map[string]interface{}{
+		"policies": "admin",
+	})
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "test",
+		"jwt":  testSign(t, key, "ES256", "key-1", testClaims(nil)),
+	})
+	if resp.Auth == nil || !reflect.DeepEqual(resp.Auth.Policies, []string{"admin"}) {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp.Auth.Alias.Name != "r3qXcK2bix9eFECzsU3Sbmh0K16fatW6@clients" {
+		t.Fatalf("bad: %#v", resp.Auth.Alias)
+	}
+
+	// Any key is tried for tokens without a key ID, but only the named one
+	// for tokens with one
+	testRequest(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "test",
+		"jwt":  testSign(t, other, "ES256", "", testClaims(nil)),
+	})
+	testRequestError(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "test",
+		"jwt":  testSign(t, other, "ES256", "key-1", testClaims(nil)),
+	})
+
+	// Tokens with no matching group are granted nothing
+	testRequestError(t, b, s, logical.UpdateOperation, "login", map[string]interface{}{
+		"role": "test",
+		"jwt":  testSign(t, key, "ES256", "key-1", testClaims(map[string]interface{}{"groups": []string{"users"}})),
+	})
+
+	// Renewal fails once the policies of the groups change
+	auth := resp.Auth
+	auth.InternalData = map[string]interface{}{"role": "test", "groups": []interface{}{"admins", "users"}}
+	auth.IssueTime = time.Now()
+	renew := func() (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.RenewOperation,
+			Path:      "login",
+			Storage:   s,
+			Auth:      auth,
+		})
+	}
+	if resp, err := renew(); err != nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr:%v", resp, err)
+	}
+	testRequest(t, b, s, logical.UpdateOperation, "groups/users", map[string]interface{}{
+		"policies": "user",
+	})
+	if _, err := renew(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestBackend_JWKSURL(t *testing.T) {
+	b, s := testBackend(t)
+
+	key1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := func(kid string, k *ecdsa.PrivateKey) []byte {
+		doc, err := json.Marshal(map[string]interface{}{
+			"keys": []interface{}{map[string]interface{}{
+				"kty": "EC",
+				"kid": kid,
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(k.X.Bytes()),
+				"y":   base64.RawURLEncoding.EncodeToString(k.Y.Bytes()),
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	var lock sync.Mutex
+	served := jwks("key-1", key1)
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		fetches++
+		if served == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(served)
+	}))
+	defer srv.Close()
+	serve := func(doc []byte) {
+		lock.Lock()
+		served = doc
+		lock.Unlock()
+	}
+	expectFetches := func(expected int) {
+		lock.Lock()
+		defer lock.Unlock()
+		if fetches != expected {
+			t.Fatalf("expected %d fetches, got %d", expected, fetches)
+		}
+	}
+	// Moves the time of the last fetch back instead of waiting
+	age := func(d time.Duration) {
+		b.keysLock.Lock()
+		b.keysTime = b.keysTime.Add(-d)
+		b.keysAttempt = b.keysAttempt.Add(-d)
+		b.keysLock.Unlock()
+	}
+
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"jwks_url": srv.URL,
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "role/test", map[string]interface{}{
+		"bound_audiences": "vault",
+		"policies":        "default",
+	})
+	login := func(key *ecdsa.PrivateKey, kid string) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"role": "test",
+				"jwt":  testSign(t, key, "ES256", kid, testClaims(nil)),
+			},
+		})
+	}
+	expectLogin := func(key *ecdsa.PrivateKey, kid string, ok bool) {
+		resp, err := login(key, kid)
+		if ok && (err != nil || resp == nil || resp.Auth == nil) {
+			t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+		}
+		if !ok && err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected error: resp: %#v", resp)
+		}
+	}
+
+	expectLogin(key1, "key-1", true)
+	expectLogin(key1, "key-1", true)
+	expectFetches(1)
+
+	// Tokens signed with an unknown key do not trigger a fetch right after
+	// the keys were fetched
+	serve(jwks("key-2", key2))
+	expectLogin(key2, "key-2", false)
+	expectFetches(1)
+
+	// But do once enough time passed, picking up the rotated key
+	age(jwksMinRefreshInterval)
+	expectLogin(key2, "key-2", true)
+	expectLogin(key1, "key-1", false)
+	expectFetches(2)
+
+	// Keys are fetched again once they expire, and kept if that fails
+	serve(nil)
+	age(jwksCacheTTL)
+	expectLogin(key2, "key-2", true)
+	expectLogin(key2, "key-2", true)
+	expectFetches(3)
+}
+
+func TestBackend_Config(t *testing.T) {
+	b, s := testBackend(t)
+
+	cases := map[string]map[string]interface{}{
+		"no keys":    {"bound_issuer": "foo"},
+		"bad pem":    {"jwt_validation_pubkeys": "foo"},
+		"bad jwks":   {"jwks": `{"keys": [{"kty": "RSA", "n": "AQAB"}]}`},
+		"empty jwks": {"jwks": `{"keys": [{"kty": "oct", "k": "AQAB"}]}`},
+		"both": {
+			"jwks":     `{"keys": []}`,
+			"jwks_url": "https://example.com/.well-known/jwks.json",
+		},
+	}
+	for name, data := range cases {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   s,
+			Data:      data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("%s: expected error: resp: %#v", name, resp)
+		}
+	}
+
+	testRequest(t, b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"jwks_url":     "https://example.com/.well-known/jwks.json",
+		"bound_issuer": "https://example.com",
+	})
+	resp := testRequest(t, b, s, logical.ReadOperation, "config", nil)
+	if resp.Data["jwks_url"] != "https://example.com/.well-known/jwks.json" || resp.Data["bound_issuer"] != "https://example.com" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
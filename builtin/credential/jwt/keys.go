@@ -0,0 +1,264 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// publicKey is a key tokens can be validated with. The ID is only set for
+// keys coming from a JWKS document.
+type publicKey struct {
+	id  string
+	key crypto.PublicKey
+}
+
+// signingMethod describes one of the supported "alg" values
+type signingMethod struct {
+	hash crypto.Hash
+
+	// kty is the type of key the method requires, "RSA" or "EC"
+	kty string
+
+	// pss is set for the RSASSA-PSS methods
+	pss bool
+
+	// curve is the curve the ECDSA methods require
+	curve elliptic.Curve
+}
+
+// signingMethods are the algorithms tokens can be signed with. Symmetric
+// and unsigned tokens are deliberately not supported.
+var signingMethods = map[string]*signingMethod{
+	"RS256": {hash: crypto.SHA256, kty: "RSA"},
+	"RS384": {hash: crypto.SHA384, kty: "RSA"},
+	"RS512": {hash: crypto.SHA512, kty: "RSA"},
+	"PS256": {hash: crypto.SHA256, kty: "RSA", pss: true},
+	"PS384": {hash: crypto.SHA384, kty: "RSA", pss: true},
+	"PS512": {hash: crypto.SHA512, kty: "RSA", pss: true},
+	"ES256": {hash: crypto.SHA256, kty: "EC", curve: elliptic.P256()},
+	"ES384": {hash: crypto.SHA384, kty: "EC", curve: elliptic.P384()},
+	"ES512": {hash: crypto.SHA512, kty: "EC", curve: elliptic.P521()},
+}
+
+// verify checks the signature of the signing input of a token
+func (m *signingMethod) verify(key crypto.PublicKey, signingInput, signature []byte) bool {
+	h := m.hash.New()
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if m.kty != "RSA" {
+			return false
+		}
+		if m.pss {
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+			return rsa.VerifyPSS(k, m.hash, digest, signature, opts) == nil
+		}
+		return rsa.VerifyPKCS1v15(k, m.hash, digest, signature) == nil
+
+	case *ecdsa.PublicKey:
+		if m.kty != "EC" || k.Curve != m.curve {
+			return false
+		}
+		// JWS signatures are the fixed size concatenation of R and S
+		// rather than the ASN.1 encoding
+		size := (m.curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(k, digest, r, s)
+	}
+
+	return false
+}
+
+// parsePEMKeys parses one or more concatenated PEM encoded public keys or
+// certificates
+func parsePEMKeys(data string) ([]*publicKey, error) {
+	var keys []*publicKey
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		var key crypto.PublicKey
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate: %v", err)
+			}
+			key = cert.PublicKey
+		case "RSA PUBLIC KEY":
+			k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse public key: %v", err)
+			}
+			key = k
+		default:
+			k, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse public key: %v", err)
+			}
+			key = k
+		}
+
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+		keys = append(keys, &publicKey{key: key})
+	}
+
+	if strings.TrimSpace(string(rest)) != "" {
+		return nil, errors.New("data after the last PEM block could not be parsed")
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM encoded public keys found")
+	}
+	return keys, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS parses the signing keys of a JWKS document. Keys of other types
+// or uses are skipped.
+func parseJWKS(data []byte) ([]*publicKey, error) {
+	var set struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	var keys []*publicKey
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d in JWKS: %v", i, err)
+		}
+		keys = append(keys, &publicKey{id: jwk.Kid, key: key})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys found in JWKS")
+	}
+	return keys, nil
+}
+
+func (k *jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeJWKInt(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %v", err)
+	}
+	e, err := decodeJWKInt(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %v", err)
+	}
+	if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k *jsonWebKey) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := decodeJWKInt(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate: %v", err)
+	}
+	y, err := decodeJWKInt(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate: %v", err)
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// fetchJWKS retrieves and parses the JWKS document at the given URL
+func fetchJWKS(url string) ([]*publicKey, error) {
+	resp, err := cleanhttp.DefaultClient().Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	// A JWKS document has no business being large
+	body, err := ioutil.ReadAll(&io.LimitedReader{R: resp.Body, N: 1 << 20})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	return parseJWKS(body)
+}
@@ -0,0 +1,239 @@
+package jwt
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `config`,
+		Fields: map[string]*framework.FieldSchema{
+			"jwt_validation_pubkeys": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "PEM encoded public keys or certificates to validate tokens with. Several can be concatenated.",
+			},
+
+			"jwks": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "JWKS document holding the public keys to validate tokens with.",
+			},
+
+			"jwks_url": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "URL of a JWKS document holding the public keys to validate tokens with.",
+			},
+
+			"bound_issuer": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The value the 'iss' claim of tokens must have. Not checked if empty.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+// Config returns the configuration of the backend, or nil if it was not
+// configured yet
+func (b *backend) Config(s logical.Storage) (*ConfigEntry, error) {
+	entry, err := s.Get("config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result ConfigEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+const (
+	// jwksCacheTTL is how long keys fetched from a JWKS URL are used before
+	// being fetched again, so that rotated keys are eventually dropped
+	jwksCacheTTL = time.Hour
+
+	// jwksMinRefreshInterval is the minimum time between two fetches of a
+	// JWKS URL triggered by tokens the cached keys cannot verify, which
+	// anyone able to reach the login endpoint can send
+	jwksMinRefreshInterval = time.Minute
+)
+
+// publicKeys returns the keys tokens are validated with. Keys are parsed
+// once and cached until the configuration changes; keys of a JWKS URL are
+// fetched again once they are older than jwksCacheTTL, and kept if that
+// fails.
+func (b *backend) publicKeys(config *ConfigEntry) ([]*publicKey, error) {
+	b.keysLock.RLock()
+	keys, keysTime := b.keys, b.keysTime
+	b.keysLock.RUnlock()
+	if keys != nil && (config.JWKSURL == "" || time.Since(keysTime) < jwksCacheTTL) {
+		return keys, nil
+	}
+
+	b.keysLock.Lock()
+	defer b.keysLock.Unlock()
+	if b.keys != nil && (config.JWKSURL == "" || time.Since(b.keysTime) < jwksCacheTTL ||
+		time.Since(b.keysAttempt) < jwksMinRefreshInterval) {
+		return b.keys, nil
+	}
+
+	keys, err := b.loadKeys(config)
+	if err != nil {
+		if b.keys != nil {
+			return b.keys, nil
+		}
+		return nil, err
+	}
+	return keys, nil
+}
+
+// refreshPublicKeys fetches the keys of a JWKS URL again, for tokens the
+// cached keys cannot verify since the issuer may have rotated its keys. The
+// second return value is false if no new keys were fetched, because they do
+// not come from a URL, were fetched too recently or could not be fetched.
+func (b *backend) refreshPublicKeys(config *ConfigEntry) ([]*publicKey, bool) {
+	if config.JWKSURL == "" {
+		return nil, false
+	}
+
+	b.keysLock.Lock()
+	defer b.keysLock.Unlock()
+	if time.Since(b.keysAttempt) < jwksMinRefreshInterval {
+		return nil, false
+	}
+
+	keys, err := b.loadKeys(config)
+	if err != nil {
+		b.Logger().Warn("jwt: failed to refresh JWKS", "url", config.JWKSURL, "error", err)
+		return nil, false
+	}
+	return keys, true
+}
+
+// loadKeys parses or fetches the keys of the configuration into the cache.
+// The keys lock must be held.
+func (b *backend) loadKeys(config *ConfigEntry) ([]*publicKey, error) {
+	b.keysAttempt = time.Now()
+	keys, err := config.parseKeys()
+	if err != nil {
+		return nil, err
+	}
+	b.keys = keys
+	b.keysTime = b.keysAttempt
+	return keys, nil
+}
+
+func (b *backend) pathConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"jwt_validation_pubkeys": config.JWTValidationPubKeys,
+			"jwks":                   config.JWKS,
+			"jwks_url":               config.JWKSURL,
+			"bound_issuer":           config.BoundIssuer,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config := &ConfigEntry{
+		JWTValidationPubKeys: d.Get("jwt_validation_pubkeys").(string),
+		JWKS:                 d.Get("jwks").(string),
+		JWKSURL:              d.Get("jwks_url").(string),
+		BoundIssuer:          d.Get("bound_issuer").(string),
+	}
+
+	sources := 0
+	for _, source := range []string{config.JWTValidationPubKeys, config.JWKS, config.JWKSURL} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return logical.ErrorResponse("exactly one of 'jwt_validation_pubkeys', 'jwks' and 'jwks_url' must be set"), nil
+	}
+
+	// Remote keys may not be reachable yet, so only the URL is checked
+	if config.JWKSURL != "" {
+		if _, err := url.Parse(config.JWKSURL); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid 'jwks_url': %v", err)), nil
+		}
+	} else if _, err := config.parseKeys(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	b.resetKeys()
+
+	return nil, nil
+}
+
+// ConfigEntry is the configuration of the backend
+type ConfigEntry struct {
+	JWTValidationPubKeys string `json:"jwt_validation_pubkeys"`
+	JWKS                 string `json:"jwks"`
+	JWKSURL              string `json:"jwks_url"`
+	BoundIssuer          string `json:"bound_issuer"`
+}
+
+// parseKeys parses, or fetches, the keys tokens are validated with
+func (c *ConfigEntry) parseKeys() ([]*publicKey, error) {
+	switch {
+	case c.JWTValidationPubKeys != "":
+		return parsePEMKeys(c.JWTValidationPubKeys)
+	case c.JWKS != "":
+		return parseJWKS([]byte(c.JWKS))
+	case c.JWKSURL != "":
+		return fetchJWKS(c.JWKSURL)
+	}
+	return nil, fmt.Errorf("no keys are configured")
+}
+
+const pathConfigHelpSyn = `
+Configure the keys JWTs are validated with.
+`
+
+const pathConfigHelpDesc = `
+Tokens are validated against the public keys set with exactly one of:
+
+  * jwt_validation_pubkeys: PEM encoded public keys or certificates.
+  * jwks: a JWKS document, as served by OIDC providers.
+  * jwks_url: the URL of a JWKS document. The document is fetched on first
+    use and cached for an hour, or until the configuration is written again.
+    It is fetched again earlier, at most once a minute, when a token cannot
+    be verified with the cached keys.
+
+If bound_issuer is set, the "iss" claim of tokens must match it.
+`
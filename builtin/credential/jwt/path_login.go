@@ -0,0 +1,369 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	jwtclaims "github.com/SermoDigital/jose/jwt"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// clockSkewLeeway is how far the clocks of Vault and of the token issuer
+// may drift apart when checking the "exp" and "nbf" claims
+const clockSkewLeeway = 60 * time.Second
+
+func pathLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `login$`,
+		Fields: map[string]*framework.FieldSchema{
+			"role": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The role to log in with.",
+			},
+
+			"jwt": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The signed JWT to validate.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathLogin,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+func (b *backend) pathLogin(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roleName := d.Get("role").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+	token := d.Get("jwt").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing jwt"), nil
+	}
+
+	config, err := b.Config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("JWT backend not configured"), nil
+	}
+
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("role %q could not be found", roleName)), nil
+	}
+
+	keys, err := b.publicKeys(config)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := validateToken(token, keys, time.Now())
+	if err == errTokenSignature {
+		// The issuer may have rotated its keys since they were fetched
+		if keys, ok := b.refreshPublicKeys(config); ok {
+			claims, err = validateToken(token, keys, time.Now())
+		}
+	}
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if err := validateBoundClaims(claims, config, role); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	user, ok := claims.Get(role.UserClaim).(string)
+	if !ok || user == "" {
+		return logical.ErrorResponse(fmt.Sprintf("claim %q not found in token", role.UserClaim)), nil
+	}
+
+	var groups []string
+	if role.GroupsClaim != "" {
+		groups, err = claimStrings(claims, role.GroupsClaim)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	policies, err := b.policies(req.Storage, role, groups)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return logical.ErrorResponse("token is not granted any policies"), nil
+	}
+
+	metadata := map[string]string{
+		"role": roleName,
+	}
+	for claim, key := range role.ClaimMappings {
+		if value, ok := claimString(claims.Get(claim)); ok {
+			metadata[key] = value
+		}
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			InternalData: map[string]interface{}{
+				"role":   roleName,
+				"groups": groups,
+			},
+			Policies:    policies,
+			Metadata:    metadata,
+			DisplayName: user,
+			Alias: &logical.Alias{
+				Name:     user,
+				Metadata: metadata,
+			},
+			LeaseOptions: logical.LeaseOptions{
+				TTL:       role.TTL,
+				Renewable: true,
+			},
+		},
+	}, nil
+}
+
+func (b *backend) pathLoginRenew(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if req.Auth == nil {
+		return nil, fmt.Errorf("request auth was nil")
+	}
+
+	roleName, ok := req.Auth.InternalData["role"].(string)
+	if !ok {
+		return nil, fmt.Errorf("no role found in the token's internal data")
+	}
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %q no longer exists", roleName)
+	}
+
+	// The internal data went through JSON, so the groups are no longer a
+	// string slice
+	var groups []string
+	if raw, ok := req.Auth.InternalData["groups"].([]interface{}); ok {
+		for _, group := range raw {
+			if s, ok := group.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	policies, err := b.policies(req.Storage, role, groups)
+	if err != nil {
+		return nil, err
+	}
+	if !policyutil.EquivalentPolicies(policies, req.Auth.Policies) {
+		return nil, fmt.Errorf("policies have changed, not renewing")
+	}
+
+	return framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(req, d)
+}
+
+// policies returns the policies of the role along with those of the given
+// groups
+func (b *backend) policies(s logical.Storage, role *RoleEntry, groups []string) ([]string, error) {
+	policies := append([]string(nil), role.Policies...)
+	for _, name := range groups {
+		group, err := b.Group(s, name)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil {
+			policies = append(policies, group.Policies...)
+		}
+	}
+	return strutil.RemoveDuplicates(policies), nil
+}
+
+// errTokenSignature is returned for tokens none of the keys can verify,
+// which includes tokens naming an unknown key
+var errTokenSignature = errors.New("failed to verify token signature")
+
+// validateToken checks the signature and the validity period of a token
+// and returns its claims
+func validateToken(token string, keys []*publicKey, now time.Time) (jwtclaims.Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a signed JWT in compact serialization")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %v", err)
+	}
+	method, ok := signingMethods[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %v", err)
+	}
+
+	// Keys with an ID are only tried for tokens naming them
+	signingInput := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if key.id != "" && header.Kid != "" && key.id != header.Kid {
+			continue
+		}
+		if method.verify(key.key, signingInput, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errTokenSignature
+	}
+
+	// Claims implements json.Unmarshaler for base64 encoded segments, so
+	// the payload is decoded into a plain map
+	var payload map[string]interface{}
+	if err := decodeSegment(parts[1], &payload); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %v", err)
+	}
+	claims := jwtclaims.Claims(payload)
+
+	exp, ok := claims.Expiration()
+	if !ok {
+		return nil, errors.New("token has no expiration")
+	}
+	if now.After(exp.Add(clockSkewLeeway)) {
+		return nil, errors.New("token is expired")
+	}
+	if nbf, ok := claims.NotBefore(); ok && now.Add(clockSkewLeeway).Before(nbf) {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	return claims, nil
+}
+
+// validateBoundClaims checks the claims of a token against the issuer of
+// the configuration and the bindings of the role
+func validateBoundClaims(claims jwtclaims.Claims, config *ConfigEntry, role *RoleEntry) error {
+	if config.BoundIssuer != "" {
+		if iss, _ := claims.Issuer(); iss != config.BoundIssuer {
+			return errors.New("token issuer does not match the bound issuer")
+		}
+	}
+
+	// A token meant for specific audiences must be bound to one of them, so
+	// tokens issued to other services cannot be replayed against Vault
+	aud, hasAud := claims.Audience()
+	switch {
+	case len(role.BoundAudiences) > 0:
+		if !hasAud || !anyStrInList(aud, role.BoundAudiences) {
+			return errors.New("token audience does not match the bound audiences")
+		}
+	case hasAud:
+		return errors.New("token has an audience but the role has no bound audiences")
+	}
+
+	if role.BoundSubject != "" {
+		if sub, _ := claims.Subject(); sub != role.BoundSubject {
+			return errors.New("token subject does not match the bound subject")
+		}
+	}
+
+	for claim, allowed := range role.BoundClaims {
+		values, err := claimStrings(claims, claim)
+		if err != nil {
+			return err
+		}
+		if !anyStrInList(values, allowed) {
+			return fmt.Errorf("claim %q does not match the bound values", claim)
+		}
+	}
+
+	return nil
+}
+
+// claimStrings returns the values of a claim that is either a string or a
+// list of strings
+func claimStrings(claims jwtclaims.Claims, claim string) ([]string, error) {
+	switch value := claims.Get(claim).(type) {
+	case string:
+		return []string{value}, nil
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("claim %q is not a list of strings", claim)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	case nil:
+		return nil, fmt.Errorf("claim %q not found in token", claim)
+	default:
+		return nil, fmt.Errorf("claim %q is not a string or a list of strings", claim)
+	}
+}
+
+// claimString formats a scalar claim for the token metadata
+func claimString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+func anyStrInList(values, list []string) bool {
+	for _, v := range values {
+		if strutil.StrListContains(list, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+const pathLoginHelpSyn = `
+Authenticates with a JWT.
+`
+
+const pathLoginHelpDesc = `
+The token is validated against the configured public keys, after which its
+claims must satisfy the bindings of the given role. Tokens must have an
+expiration and may only be signed with the RS, PS and ES algorithms.
+`
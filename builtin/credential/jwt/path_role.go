@@ -0,0 +1,253 @@
+package jwt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathRoleList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"policies": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Comma-separated list of policies granted by the role.",
+			},
+
+			"bound_audiences": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Comma-separated list of values one of which the 'aud' claim must have. Required if tokens have an 'aud' claim.",
+			},
+
+			"bound_subject": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The value the 'sub' claim must have. Not checked if empty.",
+			},
+
+			"bound_claims": &framework.FieldSchema{
+				Type: framework.TypeMap,
+				Description: `Map of claims to the value, or list of values,
+they must have. A claim that is a list matches if any of its values does.`,
+			},
+
+			"user_claim": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "sub",
+				Description: "The claim identifying the user, used as the alias name and display name.",
+			},
+
+			"groups_claim": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The claim holding the groups of the user. The policies of the matching groups are granted along with the role's.",
+			},
+
+			"claim_mappings": &framework.FieldSchema{
+				Type:        framework.TypeMap,
+				Description: "Map of claims to the token metadata keys their values are copied to.",
+			},
+
+			"ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Duration after which the token expires. Defaults to the mount's default TTL.",
+			},
+
+			"max_ttl": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum duration the token can be renewed for. Defaults to the mount's maximum TTL.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.DeleteOperation: b.pathRoleDelete,
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.UpdateOperation: b.pathRoleWrite,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+// Role returns the role with the given name, or nil if it does not exist
+func (b *backend) Role(s logical.Storage, n string) (*RoleEntry, error) {
+	entry, err := s.Get("role/" + n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result RoleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathRoleDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete("role/" + d.Get("name").(string)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.Role(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	boundClaims := make(map[string]interface{}, len(role.BoundClaims))
+	for claim, values := range role.BoundClaims {
+		boundClaims[claim] = values
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"policies":        role.Policies,
+			"bound_audiences": role.BoundAudiences,
+			"bound_subject":   role.BoundSubject,
+			"bound_claims":    boundClaims,
+			"user_claim":      role.UserClaim,
+			"groups_claim":    role.GroupsClaim,
+			"claim_mappings":  role.ClaimMappings,
+			"ttl":             int64(role.TTL.Seconds()),
+			"max_ttl":         int64(role.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role := &RoleEntry{
+		Policies:       policyutil.SanitizePolicies(strings.Split(d.Get("policies").(string), ","), policyutil.DoNotAddDefaultPolicy),
+		BoundAudiences: strutil.ParseDedupAndSortStrings(d.Get("bound_audiences").(string), ","),
+		BoundSubject:   d.Get("bound_subject").(string),
+		BoundClaims:    make(map[string][]string),
+		UserClaim:      d.Get("user_claim").(string),
+		GroupsClaim:    d.Get("groups_claim").(string),
+		ClaimMappings:  make(map[string]string),
+		TTL:            time.Duration(d.Get("ttl").(int)) * time.Second,
+		MaxTTL:         time.Duration(d.Get("max_ttl").(int)) * time.Second,
+	}
+
+	if role.UserClaim == "" {
+		return logical.ErrorResponse("'user_claim' must not be empty"), nil
+	}
+	if role.MaxTTL != 0 && role.TTL > role.MaxTTL {
+		return logical.ErrorResponse("'ttl' must not be greater than 'max_ttl'"), nil
+	}
+
+	for claim, raw := range d.Get("bound_claims").(map[string]interface{}) {
+		switch value := raw.(type) {
+		case string:
+			role.BoundClaims[claim] = []string{value}
+		case []interface{}:
+			for _, v := range value {
+				s, ok := v.(string)
+				if !ok {
+					return logical.ErrorResponse(fmt.Sprintf("bound claim %q must be a string or a list of strings", claim)), nil
+				}
+				role.BoundClaims[claim] = append(role.BoundClaims[claim], s)
+			}
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("bound claim %q must be a string or a list of strings", claim)), nil
+		}
+		if len(role.BoundClaims[claim]) == 0 {
+			return logical.ErrorResponse(fmt.Sprintf("bound claim %q has no values", claim)), nil
+		}
+	}
+
+	for claim, raw := range d.Get("claim_mappings").(map[string]interface{}) {
+		key, ok := raw.(string)
+		if !ok || key == "" {
+			return logical.ErrorResponse(fmt.Sprintf("claim mapping %q must be a non-empty string", claim)), nil
+		}
+		if key == "role" {
+			return logical.ErrorResponse(fmt.Sprintf("claim mapping %q cannot use the reserved metadata key %q", claim, key)), nil
+		}
+		role.ClaimMappings[claim] = key
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List("role/")
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+// RoleEntry binds the claims tokens must have to the policies they are
+// granted
+type RoleEntry struct {
+	Policies       []string            `json:"policies"`
+	BoundAudiences []string            `json:"bound_audiences"`
+	BoundSubject   string              `json:"bound_subject"`
+	BoundClaims    map[string][]string `json:"bound_claims"`
+	UserClaim      string              `json:"user_claim"`
+	GroupsClaim    string              `json:"groups_claim"`
+	ClaimMappings  map[string]string   `json:"claim_mappings"`
+	TTL            time.Duration       `json:"ttl"`
+	MaxTTL         time.Duration       `json:"max_ttl"`
+}
+
+const pathRoleHelpSyn = `
+Manage the roles tokens can log in with.
+`
+
+const pathRoleHelpDesc = `
+A role binds the claims a token must have to log in with it, and the
+policies the resulting Vault token is granted.
+
+The "aud" claim of a token must match one of bound_audiences, the "sub"
+claim must match bound_subject and each of bound_claims must match. If
+groups_claim is set, the policies of the groups the token lists in that
+claim are granted as well. The claims listed in claim_mappings are copied
+into the metadata of the Vault token.
+`
@@ -65,6 +65,9 @@ func (b *backend) pathLogin(
 			"password": password,
 		},
 		DisplayName: username,
+		Alias: &logical.Alias{
+			Name: username,
+		},
 		LeaseOptions: logical.LeaseOptions{
 			Renewable: true,
 		},
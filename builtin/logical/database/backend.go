@@ -0,0 +1,212 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/hashicorp/vault/plugins/database/cassandra"
+	"github.com/hashicorp/vault/plugins/database/mongodb"
+	"github.com/hashicorp/vault/plugins/database/mssql"
+	"github.com/hashicorp/vault/plugins/database/mysql"
+	"github.com/hashicorp/vault/plugins/database/postgresql"
+)
+
+const databaseConfigPath = "config/"
+
+// BuiltinDrivers are the database drivers that can be referenced by the
+// plugin_name of a connection
+var BuiltinDrivers = map[string]dbplugin.Factory{
+	"mysql-database-plugin":        mysql.New,
+	"mysql-legacy-database-plugin": mysql.NewLegacy,
+	"postgresql-database-plugin":   postgresql.New,
+	"mssql-database-plugin":        mssql.New,
+	"cassandra-database-plugin":    cassandra.New,
+	"mongodb-database-plugin":      mongodb.New,
+}
+
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	return Backend(conf, BuiltinDrivers).Setup(conf)
+}
+
+func Backend(conf *logical.BackendConfig, drivers map[string]dbplugin.Factory) *databaseBackend {
+	var b databaseBackend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		Paths: []*framework.Path{
+			pathListPluginConnection(&b),
+			pathConfigurePluginConnection(&b),
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathCredsCreate(&b),
+			pathResetConnection(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretCreds(&b),
+		},
+
+		Clean:      b.closeAllDBs,
+		Invalidate: b.invalidate,
+	}
+
+	b.logger = conf.Logger
+	b.drivers = drivers
+	b.connections = make(map[string]dbplugin.Database)
+	return &b
+}
+
+type databaseBackend struct {
+	*framework.Backend
+
+	// connections holds the initialized drivers, keyed by connection name
+	connections map[string]dbplugin.Database
+	drivers     map[string]dbplugin.Factory
+	logger      log.Logger
+
+	sync.RWMutex
+}
+
+// DatabaseConfig is used by the Factory function to configure a Database
+// object.
+type DatabaseConfig struct {
+	PluginName string `json:"plugin_name" structs:"plugin_name" mapstructure:"plugin_name"`
+	// ConnectionDetails stores the database specific connection settings
+	// needed by each database type.
+	ConnectionDetails map[string]interface{} `json:"connection_details" structs:"connection_details" mapstructure:"connection_details"`
+	AllowedRoles      []string               `json:"allowed_roles" structs:"allowed_roles" mapstructure:"allowed_roles"`
+}
+
+// Config returns the stored configuration of the named connection
+func (b *databaseBackend) Config(s logical.Storage, name string) (*DatabaseConfig, error) {
+	entry, err := s.Get(databaseConfigPath + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection configuration: %s", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config DatabaseConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Role returns the stored role with the given name
+func (b *databaseBackend) Role(s logical.Storage, roleName string) (*roleEntry, error) {
+	entry, err := s.Get("role/" + roleName)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// newDatabase creates an uninitialized driver for the given plugin name
+func (b *databaseBackend) newDatabase(pluginName string) (dbplugin.Database, error) {
+	factory, ok := b.drivers[pluginName]
+	if !ok {
+		return nil, fmt.Errorf("unknown database plugin: %s", pluginName)
+	}
+
+	return factory()
+}
+
+// getOrCreateDatabaseObject returns the initialized driver of the named
+// connection, creating it from the stored configuration if needed.
+func (b *databaseBackend) getOrCreateDatabaseObject(s logical.Storage, name string) (dbplugin.Database, error) {
+	b.RLock()
+	db, ok := b.connections[name]
+	b.RUnlock()
+	if ok {
+		return db, nil
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	// Check again in case another request created it in the meantime
+	if db, ok := b.connections[name]; ok {
+		return db, nil
+	}
+
+	config, err := b.Config(s, name)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("unknown database connection: %s", name)
+	}
+
+	db, err = b.newDatabase(config.PluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Initialize(config.ConnectionDetails, false); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	b.connections[name] = db
+	return db, nil
+}
+
+// clearConnection closes and removes the driver of the named connection. It
+// must be called with the lock held.
+func (b *databaseBackend) clearConnection(name string) {
+	db, ok := b.connections[name]
+	if ok {
+		if err := db.Close(); err != nil && b.logger != nil {
+			b.logger.Error("database: error closing connection", "name", name, "error", err)
+		}
+		delete(b.connections, name)
+	}
+}
+
+// closeAllDBs closes every open connection of the backend
+func (b *databaseBackend) closeAllDBs() {
+	b.Lock()
+	defer b.Unlock()
+
+	for name := range b.connections {
+		b.clearConnection(name)
+	}
+}
+
+func (b *databaseBackend) invalidate(key string) {
+	switch {
+	case strings.HasPrefix(key, databaseConfigPath):
+		name := strings.TrimPrefix(key, databaseConfigPath)
+
+		b.Lock()
+		b.clearConnection(name)
+		b.Unlock()
+	}
+}
+
+const backendHelp = `
+The database backend dynamically generates database users.
+
+Each mount can hold several named connections, each using one of the
+supported database plugins: cassandra, mongodb, mssql, mysql and postgresql.
+After mounting this backend, configure connections using the endpoints
+within the "config/" path, then create roles referencing them.
+`
@@ -0,0 +1,537 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/logical"
+)
+
+// fakeDatabase is an in-memory database driver used to test the backend
+// without a live database
+type fakeDatabase struct {
+	sync.Mutex
+
+	config      map[string]interface{}
+	initialized bool
+	closed      bool
+
+	// users maps the created usernames to their expiration
+	users map[string]time.Time
+	count int
+}
+
+func (f *fakeDatabase) Type() (string, error) {
+	return "fake", nil
+}
+
+func (f *fakeDatabase) CreateUser(statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (string, string, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if !f.initialized {
+		return "", "", fmt.Errorf("not initialized")
+	}
+	if statements.CreationStatements == "fail" {
+		return "", "", fmt.Errorf("creation failed")
+	}
+
+	f.count++
+	username := fmt.Sprintf("%s-%s-%d", usernameConfig.DisplayName, usernameConfig.RoleName, f.count)
+	f.users[username] = expiration
+	return username, "password", nil
+}
+
+func (f *fakeDatabase) RenewUser(statements dbplugin.Statements, username string, expiration time.Time) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.users[username]; !ok {
+		return fmt.Errorf("unknown user: %s", username)
+	}
+	f.users[username] = expiration
+	return nil
+}
+
+func (f *fakeDatabase) RevokeUser(statements dbplugin.Statements, username string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.users, username)
+	return nil
+}
+
+func (f *fakeDatabase) Initialize(config map[string]interface{}, verifyConnection bool) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if verifyConnection && config["connection_url"] != "valid" {
+		return fmt.Errorf("could not connect")
+	}
+
+	f.config = config
+	f.initialized = true
+	return nil
+}
+
+func (f *fakeDatabase) Close() error {
+	f.Lock()
+	defer f.Unlock()
+
+	f.closed = true
+	return nil
+}
+
+// fakeDrivers records every database object created by the backend
+type fakeDrivers struct {
+	sync.Mutex
+	dbs []*fakeDatabase
+}
+
+func (d *fakeDrivers) factory() (dbplugin.Database, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	db := &fakeDatabase{users: make(map[string]time.Time)}
+	d.dbs = append(d.dbs, db)
+	return db, nil
+}
+
+func (d *fakeDrivers) last() *fakeDatabase {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.dbs[len(d.dbs)-1]
+}
+
+func testBackend(t *testing.T) (*databaseBackend, logical.Storage, *fakeDrivers) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	drivers := &fakeDrivers{}
+	b := Backend(config, map[string]dbplugin.Factory{
+		"fake-database-plugin": drivers.factory,
+	})
+	if _, err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+
+	return b, config.StorageView, drivers
+}
+
+func testRequest(t *testing.T, b logical.Backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation:   op,
+		Path:        path,
+		Storage:     s,
+		Data:        data,
+		DisplayName: "token",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("%s %s: err:%s resp:%#v", op, path, err, resp)
+	}
+	return resp
+}
+
+func TestBackend_config_connection(t *testing.T) {
+	b, s, drivers := testBackend(t)
+
+	testRequest(t, b, s, logical.UpdateOperation, "config/plugin-test", map[string]interface{}{
+		"plugin_name":    "fake-database-plugin",
+		"connection_url": "valid",
+		"allowed_roles":  "foo,bar",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "config/plugin-test", nil)
+	expected := map[string]interface{}{
+		"plugin_name": "fake-database-plugin",
+		"connection_details": map[string]interface{}{
+			"connection_url": "valid",
+		},
+		"allowed_roles": []string{"bar", "foo"},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+	}
+	if !drivers.last().initialized {
+		t.Fatal("expected database to be initialized")
+	}
+
+	// Updating the allowed roles keeps the connection details
+	testRequest(t, b, s, logical.UpdateOperation, "config/plugin-test", map[string]interface{}{
+		"allowed_roles": "*",
+	})
+	resp = testRequest(t, b, s, logical.ReadOperation, "config/plugin-test", nil)
+	expected["allowed_roles"] = []string{"*"}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+	}
+	if !drivers.dbs[0].closed {
+		t.Fatal("expected the previous database to be closed")
+	}
+
+	// A connection that cannot be verified is rejected
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/plugin-bad",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"plugin_name":    "fake-database-plugin",
+			"connection_url": "invalid",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got err:%s resp:%#v", err, resp)
+	}
+
+	// Unless verification is disabled
+	resp = testRequest(t, b, s, logical.UpdateOperation, "config/plugin-bad", map[string]interface{}{
+		"plugin_name":       "fake-database-plugin",
+		"connection_url":    "invalid",
+		"verify_connection": false,
+	})
+	if resp == nil || len(resp.Warnings()) != 1 {
+		t.Fatalf("expected a warning, got %#v", resp)
+	}
+
+	// Unknown plugins are rejected
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/plugin-unknown",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"plugin_name": "unknown-database-plugin",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got err:%s resp:%#v", err, resp)
+	}
+
+	resp = testRequest(t, b, s, logical.ListOperation, "config/", nil)
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"plugin-bad", "plugin-test"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	testRequest(t, b, s, logical.DeleteOperation, "config/plugin-bad", nil)
+	resp = testRequest(t, b, s, logical.ReadOperation, "config/plugin-bad", nil)
+	if resp != nil {
+		t.Fatalf("expected nil response, got %#v", resp)
+	}
+	if !drivers.last().closed {
+		t.Fatal("expected the deleted database to be closed")
+	}
+}
+
+func TestBackend_roleCrud(t *testing.T) {
+	b, s, _ := testBackend(t)
+
+	testRequest(t, b, s, logical.UpdateOperation, "roles/plugin-role-test", map[string]interface{}{
+		"db_name":               "plugin-test",
+		"creation_statements":   "CREATE {{name}}",
+		"revocation_statements": "DROP {{name}}",
+		"default_ttl":           "5m",
+		"max_ttl":               "10m",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "roles/plugin-role-test", nil)
+	expected := map[string]interface{}{
+		"db_name":               "plugin-test",
+		"creation_statements":   "CREATE {{name}}",
+		"revocation_statements": "DROP {{name}}",
+		"rollback_statements":   "",
+		"renew_statements":      "",
+		"default_ttl":           float64(300),
+		"max_ttl":               float64(600),
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: expected %#v, got %#v", expected, resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.ListOperation, "roles/", nil)
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"plugin-role-test"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	testRequest(t, b, s, logical.DeleteOperation, "roles/plugin-role-test", nil)
+	resp = testRequest(t, b, s, logical.ReadOperation, "roles/plugin-role-test", nil)
+	if resp != nil {
+		t.Fatalf("expected nil response, got %#v", resp)
+	}
+
+	// The connection name is required
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/plugin-role-test",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"creation_statements": "CREATE {{name}}",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error response, got err:%s resp:%#v", err, resp)
+	}
+}
+
+func TestBackend_allowedRoles(t *testing.T) {
+	b, s, _ := testBackend(t)
+
+	testRequest(t, b, s, logical.UpdateOperation, "config/plugin-test", map[string]interface{}{
+		"plugin_name":    "fake-database-plugin",
+		"connection_url": "valid",
+	})
+	for _, role := range []string{"allowed", "denied"} {
+		testRequest(t, b, s, logical.UpdateOperation, "roles/"+role, map[string]interface{}{
+			"db_name":             "plugin-test",
+			"creation_statements": "CREATE {{name}}",
+		})
+	}
+
+	credsErr := func(role string) error {
+		_, err := b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + role,
+			Storage:   s,
+		})
+		return err
+	}
+
+	// No roles are allowed by default
+	if err := credsErr("allowed"); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// Only the listed roles are allowed
+	testRequest(t, b, s, logical.UpdateOperation, "config/plugin-test", map[string]interface{}{
+		"allowed_roles": "allowed",
+	})
+	if err := credsErr("allowed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := credsErr("denied"); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// Globs allow every role
+	testRequest(t, b, s, logical.UpdateOperation, "config/plugin-test", map[string]interface{}{
+		"allowed_roles": "*",
+	})
+	for _, role := range []string{"allowed", "denied"} {
+		if err := credsErr(role); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackend_credsRenewRevoke(t *testing.T) {
+	b, s, drivers := testBackend(t)
+
+	testRequest(t, b, s, logical.UpdateOperation, "config/plugin-test", map[string]interface{}{
+		"plugin_name":    "fake-database-plugin",
+		"connection_url": "valid",
+		"allowed_roles":  "plugin-role-test",
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "roles/plugin-role-test", map[string]interface{}{
+		"db_name":             "plugin-test",
+		"creation_statements": "CREATE {{name}}",
+		"default_ttl":         "5m",
+		"max_ttl":             "10m",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "creds/plugin-role-test", nil)
+	username := resp.Data["username"].(string)
+	if username != "token-plugin-role-test-1" || resp.Data["password"] != "password" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Secret.TTL != 5*time.Minute {
+		t.Fatalf("bad: %s", resp.Secret.TTL)
+	}
+
+	db := drivers.last()
+	expiration, ok := db.users[username]
+	if !ok {
+		t.Fatalf("user %q was not created", username)
+	}
+
+	// Renewing extends the expiration in the database
+	secret := resp.Secret
+	secret.IssueTime = time.Now()
+	secret.LeaseOptions.Increment = 10 * time.Minute
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.RenewOperation,
+		Storage:   s,
+		Secret:    secret,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v", err, resp)
+	}
+	if !db.users[username].After(expiration) {
+		t.Fatalf("expected expiration to be extended: %s, %s", expiration, db.users[username])
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   s,
+		Secret:    secret,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v", err, resp)
+	}
+	if _, ok := db.users[username]; ok {
+		t.Fatalf("user %q was not revoked", username)
+	}
+
+	// Creation errors are returned
+	testRequest(t, b, s, logical.UpdateOperation, "roles/plugin-role-test", map[string]interface{}{
+		"db_name":             "plugin-test",
+		"creation_statements": "fail",
+	})
+	if _, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/plugin-role-test",
+		Storage:   s,
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestBackend_credsRenewMovedRole(t *testing.T) {
+	b, s, drivers := testBackend(t)
+
+	testRequest(t, b, s, logical.UpdateOperation, "config/first", map[string]interface{}{
+		"plugin_name":    "fake-database-plugin",
+		"connection_url": "valid",
+		"allowed_roles":  "plugin-role-test",
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "roles/plugin-role-test", map[string]interface{}{
+		"db_name":             "first",
+		"creation_statements": "CREATE {{name}}",
+		"default_ttl":         "5m",
+		"max_ttl":             "10m",
+	})
+
+	resp := testRequest(t, b, s, logical.ReadOperation, "creds/plugin-role-test", nil)
+	username := resp.Data["username"].(string)
+	db := drivers.last()
+	expiration := db.users[username]
+
+	// Renewing after the role moved to another database still renews the
+	// user in the database it was created in
+	testRequest(t, b, s, logical.UpdateOperation, "config/second", map[string]interface{}{
+		"plugin_name":    "fake-database-plugin",
+		"connection_url": "valid",
+		"allowed_roles":  "plugin-role-test",
+	})
+	testRequest(t, b, s, logical.UpdateOperation, "roles/plugin-role-test", map[string]interface{}{
+		"db_name":             "second",
+		"creation_statements": "CREATE {{name}}",
+		"default_ttl":         "5m",
+		"max_ttl":             "10m",
+	})
+	secret := resp.Secret
+	secret.IssueTime = time.Now()
+	secret.LeaseOptions.Increment = 10 * time.Minute
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.RenewOperation,
+		Storage:   s,
+		Secret:    secret,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v", err, resp)
+	}
+	if !db.users[username].After(expiration) {
+		t.Fatalf("expected expiration to be extended: %s, %s", expiration, db.users[username])
+	}
+
+	// Malformed internal data is an error rather than a panic
+	secret.InternalData["db_name"] = 42
+	for _, op := range []logical.Operation{logical.RenewOperation, logical.RevokeOperation} {
+		if _, err := b.HandleRequest(&logical.Request{
+			Operation: op,
+			Storage:   s,
+			Secret:    secret,
+		}); err == nil {
+			t.Fatalf("%s: expected error", op)
+		}
+	}
+}
+
+// failingStorage fails all writes once failPuts is set
+type failingStorage struct {
+	logical.Storage
+	failPuts bool
+}
+
+func (f *failingStorage) Put(entry *logical.StorageEntry) error {
+	if f.failPuts {
+		return fmt.Errorf("injected failure")
+	}
+	return f.Storage.Put(entry)
+}
+
+func TestBackend_config_connectionStorageFailure(t *testing.T) {
+	b, storage, drivers := testBackend(t)
+	s := &failingStorage{Storage: storage}
+
+	testRequest(t, b, s, logical.UpdateOperation, "config/plugin-test", map[string]interface{}{
+		"plugin_name":    "fake-database-plugin",
+		"connection_url": "valid",
+	})
+	db := drivers.last()
+
+	// A configuration that is not stored does not replace the connection
+	s.failPuts = true
+	_, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/plugin-test",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"connection_url": "valid",
+			"extra":          "value",
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if db.closed || b.connections["plugin-test"] != db {
+		t.Fatal("expected the stored connection to stay in use")
+	}
+	if !drivers.last().closed {
+		t.Fatal("expected the new database to be closed")
+	}
+}
+
+func TestBackend_reset(t *testing.T) {
+	b, s, drivers := testBackend(t)
+
+	testRequest(t, b, s, logical.UpdateOperation, "config/plugin-test", map[string]interface{}{
+		"plugin_name":    "fake-database-plugin",
+		"connection_url": "valid",
+	})
+	db := drivers.last()
+
+	testRequest(t, b, s, logical.UpdateOperation, "reset/plugin-test", nil)
+	if !db.closed {
+		t.Fatal("expected database to be closed")
+	}
+	if len(drivers.dbs) != 2 || !drivers.last().initialized {
+		t.Fatalf("expected a new initialized database: %#v", drivers.dbs)
+	}
+
+	// Invalidation and cleanup close the connections
+	db = drivers.last()
+	b.invalidate("config/plugin-test")
+	if !db.closed {
+		t.Fatal("expected database to be closed")
+	}
+
+	testRequest(t, b, s, logical.UpdateOperation, "reset/plugin-test", nil)
+	db = drivers.last()
+	b.closeAllDBs()
+	if !db.closed {
+		t.Fatal("expected database to be closed")
+	}
+}
@@ -0,0 +1,48 @@
+package dbplugin
+
+import (
+	"time"
+)
+
+// Database is the interface that all database drivers must implement. A
+// driver manages the users of a single database connection.
+type Database interface {
+	// Type returns the type of the database, e.g. "mysql"
+	Type() (string, error)
+
+	// CreateUser creates a user with the given statements and returns its
+	// username and password. The user must not be usable after expiration.
+	CreateUser(statements Statements, usernameConfig UsernameConfig, expiration time.Time) (username string, password string, err error)
+
+	// RenewUser extends the validity of the user up to expiration
+	RenewUser(statements Statements, username string, expiration time.Time) error
+
+	// RevokeUser removes the user from the database
+	RevokeUser(statements Statements, username string) error
+
+	// Initialize configures the connection of the driver from the
+	// connection details given when configuring the connection. If
+	// verifyConnection is set, the connection is tested right away.
+	Initialize(config map[string]interface{}, verifyConnection bool) error
+
+	// Close closes the connection to the database
+	Close() error
+}
+
+// Factory creates a new, uninitialized, database driver
+type Factory func() (Database, error)
+
+// Statements set in a role, used by the drivers to manage the users of
+// the role. Empty statements let the driver fall back to its defaults.
+type Statements struct {
+	CreationStatements   string `json:"creation_statements" mapstructure:"creation_statements" structs:"creation_statements"`
+	RevocationStatements string `json:"revocation_statements" mapstructure:"revocation_statements" structs:"revocation_statements"`
+	RollbackStatements   string `json:"rollback_statements" mapstructure:"rollback_statements" structs:"rollback_statements"`
+	RenewStatements      string `json:"renew_statements" mapstructure:"renew_statements" structs:"renew_statements"`
+}
+
+// UsernameConfig holds the values the drivers use to generate usernames
+type UsernameConfig struct {
+	DisplayName string
+	RoleName    string
+}
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// pathResetConnection configures a path to reset a plugin.
+func pathResetConnection(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: fmt.Sprintf("reset/%s", framework.GenericNameRegex("name")),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of this database connection",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathConnectionReset,
+		},
+
+		HelpSynopsis:    pathResetConnectionHelpSyn,
+		HelpDescription: pathResetConnectionHelpDesc,
+	}
+}
+
+// pathConnectionReset closes the connection and reinitializes it from the
+// stored configuration
+func (b *databaseBackend) pathConnectionReset(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("empty name attribute given"), nil
+	}
+
+	// Grab the mutex lock
+	b.Lock()
+	b.clearConnection(name)
+	b.Unlock()
+
+	if _, err := b.getOrCreateDatabaseObject(req.Storage, name); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// pathListPluginConnection returns a configured framework.Path setup to list
+// the connections.
+func pathListPluginConnection(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathConnectionList,
+		},
+
+		HelpSynopsis:    pathConfigConnectionHelpSyn,
+		HelpDescription: pathConfigConnectionHelpDesc,
+	}
+}
+
+func (b *databaseBackend) pathConnectionList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(databaseConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+// pathConfigurePluginConnection returns a configured framework.Path setup to
+// operate on connections.
+func pathConfigurePluginConnection(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: fmt.Sprintf("config/%s", framework.GenericNameRegex("name")),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of this database connection",
+			},
+
+			"plugin_name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The name of a builtin database plugin to use for
+				this connection.`,
+			},
+
+			"verify_connection": &framework.FieldSchema{
+				Type:    framework.TypeBool,
+				Default: true,
+				Description: `If true, the connection details are verified by
+				actually connecting to the database. Defaults to true.`,
+			},
+
+			"allowed_roles": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Comma separated list of the roles allowed to use
+				this connection. Defaults to empty (no roles), if contains a "*"
+				any role can use this connection.`,
+			},
+		},
+
+		ExistenceCheck: b.connectionExistenceCheck,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.CreateOperation: b.connectionWriteHandler,
+			logical.UpdateOperation: b.connectionWriteHandler,
+			logical.ReadOperation:   b.connectionReadHandler,
+			logical.DeleteOperation: b.connectionDeleteHandler,
+		},
+
+		HelpSynopsis:    pathConfigConnectionHelpSyn,
+		HelpDescription: pathConfigConnectionHelpDesc,
+	}
+}
+
+func (b *databaseBackend) connectionExistenceCheck(
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	config, err := b.Config(req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+
+	return config != nil, nil
+}
+
+// connectionReadHandler reads out the connection configuration
+func (b *databaseBackend) connectionReadHandler(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("empty name attribute given"), nil
+	}
+
+	config, err := b.Config(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: structs.New(config).Map(),
+	}, nil
+}
+
+// connectionDeleteHandler deletes the connection configuration
+func (b *databaseBackend) connectionDeleteHandler(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("empty name attribute given"), nil
+	}
+
+	if err := req.Storage.Delete(databaseConfigPath + name); err != nil {
+		return nil, fmt.Errorf("failed to delete connection configuration: %s", err)
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.clearConnection(name)
+
+	return nil, nil
+}
+
+// connectionWriteHandler creates or updates a connection, initializing the
+// database plugin with the given connection details.
+func (b *databaseBackend) connectionWriteHandler(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("empty name attribute given"), nil
+	}
+
+	config := &DatabaseConfig{}
+	if req.Operation == logical.UpdateOperation {
+		existing, err := b.Config(req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			config = existing
+		}
+	}
+
+	if pluginNameRaw, ok := data.GetOk("plugin_name"); ok {
+		config.PluginName = pluginNameRaw.(string)
+	}
+	if config.PluginName == "" {
+		return logical.ErrorResponse("empty plugin name"), nil
+	}
+
+	if allowedRolesRaw, ok := data.GetOk("allowed_roles"); ok {
+		config.AllowedRoles = strutil.ParseDedupAndSortStrings(allowedRolesRaw.(string), ",")
+	}
+
+	verifyConnection := data.Get("verify_connection").(bool)
+
+	// Everything else in the request is specific to the database plugin
+	delete(data.Raw, "name")
+	delete(data.Raw, "plugin_name")
+	delete(data.Raw, "allowed_roles")
+	delete(data.Raw, "verify_connection")
+	if len(data.Raw) > 0 || config.ConnectionDetails == nil {
+		config.ConnectionDetails = data.Raw
+	}
+
+	db, err := b.newDatabase(config.PluginName)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error creating database object: %s", err)), nil
+	}
+
+	if err := db.Initialize(config.ConnectionDetails, verifyConnection); err != nil {
+		db.Close()
+		return logical.ErrorResponse(fmt.Sprintf("error creating database object: %s", err)), nil
+	}
+
+	// Grab the mutex lock
+	b.Lock()
+	defer b.Unlock()
+
+	// Store it before replacing the connection in use, so that a failure
+	// leaves the backend running with the stored configuration
+	entry, err := logical.StorageEntryJSON(databaseConfigPath+name, config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Close and remove the old connection
+	b.clearConnection(name)
+
+	// Save the new connection
+	b.connections[name] = db
+
+	if !verifyConnection {
+		resp := &logical.Response{}
+		resp.AddWarning("The connection details were not verified; any connection errors will surface when credentials are generated.")
+		return resp, nil
+	}
+
+	return nil, nil
+}
+
+// allowsRole reports whether the connection may be used by the given role
+func (c *DatabaseConfig) allowsRole(roleName string) bool {
+	return strutil.StrListContains(c.AllowedRoles, "*") ||
+		strutil.StrListContains(c.AllowedRoles, roleName)
+}
+
+const pathConfigConnectionHelpSyn = `
+Configure connection details to a database plugin.
+`
+
+const pathConfigConnectionHelpDesc = `
+This path configures the connection details used to connect to a particular
+database. This path runs the provided plugin name and passes the configured
+connection details to the plugin. See the documentation for the plugin specified
+for a full list of accepted connection details.
+
+In addition to the database specific connection details, this endpoint also
+accepts:
+
+	* "plugin_name" (required) - The name of a builtin database plugin to use
+	  for this connection.
+
+	* "verify_connection" (default: true) - A boolean value denoting if the
+	  plugin should verify it is able to connect to the database using the
+	  provided connection details.
+
+	* "allowed_roles" (default: none) - A comma separated list of the roles
+	  allowed to use this connection, or "*" to allow any role.
+`
+
+const pathResetConnectionHelpSyn = `
+Resets a database plugin.
+`
+
+const pathResetConnectionHelpDesc = `
+This path resets the database connection by closing the existing database plugin
+instance and running a new one.
+`
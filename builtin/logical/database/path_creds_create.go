@@ -0,0 +1,103 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathCredsCreate(b *databaseBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCredsCreateRead,
+		},
+
+		HelpSynopsis:    pathCredsCreateReadHelpSyn,
+		HelpDescription: pathCredsCreateReadHelpDesc,
+	}
+}
+
+func (b *databaseBackend) pathCredsCreateRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	// Get the role
+	role, err := b.Role(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	dbConfig, err := b.Config(req.Storage, role.DBName)
+	if err != nil {
+		return nil, err
+	}
+	if dbConfig == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown database connection: %s", role.DBName)), nil
+	}
+
+	// If role name isn't in the database's allowed roles, send back a
+	// permission denied.
+	if !dbConfig.allowsRole(name) {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	db, err := b.getOrCreateDatabaseObject(req.Storage, role.DBName)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve db with name: %s, got error: %s", role.DBName, err)
+	}
+
+	ttl := role.DefaultTTL
+	if ttl == 0 || (role.MaxTTL > 0 && ttl > role.MaxTTL) {
+		ttl = role.MaxTTL
+	}
+	if ttl == 0 {
+		ttl = b.System().DefaultLeaseTTL()
+	}
+	expiration := time.Now().Add(ttl)
+
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: req.DisplayName,
+		RoleName:    name,
+	}
+
+	// Create the user
+	username, password, err := db.CreateUser(role.Statements, usernameConfig, expiration)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := b.Secret(SecretCredsType).Response(map[string]interface{}{
+		"username": username,
+		"password": password,
+	}, map[string]interface{}{
+		"username": username,
+		"role":     name,
+		"db_name":  role.DBName,
+	})
+	resp.Secret.TTL = ttl
+	return resp, nil
+}
+
+const pathCredsCreateReadHelpSyn = `
+Request database credentials for a certain role.
+`
+
+const pathCredsCreateReadHelpDesc = `
+This path reads database credentials for a certain role. The
+database credentials will be generated on demand and will be automatically
+revoked when the lease is up.
+`
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const SecretCredsType = "creds"
+
+func secretCreds(b *databaseBackend) *framework.Secret {
+	return &framework.Secret{
+		Type: SecretCredsType,
+		Fields: map[string]*framework.FieldSchema{
+			"username": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Username",
+			},
+
+			"password": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Password",
+			},
+		},
+
+		Renew:  b.secretCredsRenew,
+		Revoke: b.secretCredsRevoke,
+	}
+}
+
+func (b *databaseBackend) secretCredsRenew(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// Get the username from the internal data
+	usernameRaw, ok := req.Secret.InternalData["username"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing username internal data")
+	}
+	username, ok := usernameRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid username internal data")
+	}
+
+	roleNameRaw, ok := req.Secret.InternalData["role"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing role internal data")
+	}
+	roleName, ok := roleNameRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid role internal data")
+	}
+
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("error during renew: could not find role with name %s", roleName)
+	}
+
+	// The user lives in the database it was created in, even if the role has
+	// since been pointed at another one
+	dbNameRaw, ok := req.Secret.InternalData["db_name"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing db_name internal data")
+	}
+	dbName, ok := dbNameRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid db_name internal data")
+	}
+
+	f := framework.LeaseExtend(role.DefaultTTL, role.MaxTTL, b.System())
+	resp, err := f(req, d)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the Database object
+	db, err := b.getOrCreateDatabaseObject(req.Storage, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure we increase the VALID UNTIL endpoint for this user.
+	if expireTime := resp.Secret.ExpirationTime(); !expireTime.IsZero() {
+		err := db.RenewUser(role.Statements, username, expireTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (b *databaseBackend) secretCredsRevoke(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	// Get the username from the internal data
+	usernameRaw, ok := req.Secret.InternalData["username"]
+	if !ok {
+		return nil, fmt.Errorf("secret is missing username internal data")
+	}
+	username, ok := usernameRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid username internal data")
+	}
+
+	var resp *logical.Response
+
+	roleNameRaw, ok := req.Secret.InternalData["role"]
+	if !ok {
+		return nil, fmt.Errorf("no role name was provided")
+	}
+	roleName, ok := roleNameRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid role internal data")
+	}
+	dbNameRaw, ok := req.Secret.InternalData["db_name"]
+	if !ok {
+		return nil, fmt.Errorf("no database name was provided")
+	}
+	dbName, ok := dbNameRaw.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid db_name internal data")
+	}
+
+	// Revoke with the role's statements if it still exists, otherwise fall
+	// back to the plugin's defaults
+	var statements dbplugin.Statements
+	role, err := b.Role(req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role != nil {
+		statements = role.Statements
+	} else {
+		resp = &logical.Response{}
+		resp.AddWarning(fmt.Sprintf("Role %q cannot be found. Using default revocation statements.", roleName))
+	}
+
+	// Get our connection
+	db, err := b.getOrCreateDatabaseObject(req.Storage, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.RevokeUser(statements, username); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,93 @@
+package pki
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	acmeChallengeHTTP01 = "http-01"
+	acmeChallengeDNS01  = "dns-01"
+)
+
+// acmeLookupTXTFunc resolves the TXT records of a name, as net.LookupTXT
+type acmeLookupTXTFunc func(name string) ([]string, error)
+
+func defaultACMEHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+	}
+}
+
+// validateACMEChallenge checks that the challenge of the given type for the
+// domain was fulfilled with the key authorization, returning the problem
+// reported to the client otherwise
+func (b *backend) validateACMEChallenge(challengeType, domain, keyAuthorization string) *acmeProblem {
+	switch challengeType {
+	case acmeChallengeHTTP01:
+		return b.validateACMEHTTP01(domain, keyAuthorization)
+	case acmeChallengeDNS01:
+		return b.validateACMEDNS01(domain, keyAuthorization)
+	default:
+		return acmeMalformed("unsupported challenge type %q", challengeType)
+	}
+}
+
+// validateACMEHTTP01 fetches the key authorization from the domain over HTTP
+// (RFC 8555 section 8.3)
+func (b *backend) validateACMEHTTP01(domain, keyAuthorization string) *acmeProblem {
+	token := strings.SplitN(keyAuthorization, ".", 2)[0]
+	url := fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", domain, token)
+
+	resp, err := b.acmeHTTPClient.Get(url)
+	if err != nil {
+		return acmeError("connection", 400, "error fetching %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return acmeError("unauthorized", 403, "unexpected status %d fetching %s", resp.StatusCode, url)
+	}
+
+	// The key authorization is short, don't read more than needed
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return acmeError("connection", 400, "error reading %s: %s", url, err)
+	}
+	if strings.TrimSpace(string(body)) != keyAuthorization {
+		return acmeError("incorrectResponse", 403, "the key authorization fetched from %s does not match", url)
+	}
+
+	return nil
+}
+
+// validateACMEDNS01 looks up the digest of the key authorization in the TXT
+// records of the domain (RFC 8555 section 8.4)
+func (b *backend) validateACMEDNS01(domain, keyAuthorization string) *acmeProblem {
+	name := "_acme-challenge." + domain
+
+	records, err := b.acmeLookupTXT(name)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.Timeout() {
+			return acmeError("dns", 400, "timeout looking up TXT records of %s", name)
+		}
+		return acmeError("dns", 400, "error looking up TXT records of %s: %s", name, err)
+	}
+
+	sum := sha256.Sum256([]byte(keyAuthorization))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	for _, record := range records {
+		if record == expected {
+			return nil
+		}
+	}
+
+	return acmeError("incorrectResponse", 403, "no TXT record of %s matches the key authorization", name)
+}
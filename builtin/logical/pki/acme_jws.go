@@ -0,0 +1,211 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// acmeJWK is a JSON Web Key (RFC 7517), the public key of an ACME account
+type acmeJWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// acmeJWS is a JSON Web Signature (RFC 7515) in the flattened JSON
+// serialization, the body of all ACME POST requests
+type acmeJWS struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// acmeJWSHeader is the protected header of an ACME request, which holds
+// either the key of the account for new accounts, or its URL
+type acmeJWSHeader struct {
+	Alg   string   `json:"alg"`
+	Nonce string   `json:"nonce"`
+	URL   string   `json:"url"`
+	JWK   *acmeJWK `json:"jwk"`
+	KID   string   `json:"kid"`
+}
+
+// publicKey returns the key, which must be an RSA key or an ECDSA key on one
+// of the NIST curves
+func (k *acmeJWK) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %s", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %s", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		if n.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA keys < 2048 bits are unsafe and not supported")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC point: %s", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC point: %s", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid EC point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// thumbprint returns the base64url encoded SHA-256 thumbprint of the key
+// (RFC 7638), which identifies accounts and is part of key authorizations
+func (k *acmeJWK) thumbprint() (string, error) {
+	// The required members, in lexicographic order and without whitespace
+	var canonical string
+	switch k.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Crv, k.X, k.Y)
+	default:
+		return "", fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// parseACMEJWS decodes a JWS and its protected header, without verifying it
+func parseACMEJWS(body []byte) (*acmeJWS, *acmeJWSHeader, error) {
+	var jws acmeJWS
+	if err := json.Unmarshal(body, &jws); err != nil {
+		return nil, nil, fmt.Errorf("request is not a JWS: %s", err)
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding protected header: %s", err)
+	}
+	var header acmeJWSHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, nil, fmt.Errorf("error decoding protected header: %s", err)
+	}
+
+	switch {
+	case header.JWK != nil && header.KID != "":
+		return nil, nil, fmt.Errorf("only one of jwk and kid can be set")
+	case header.JWK == nil && header.KID == "":
+		return nil, nil, fmt.Errorf("one of jwk and kid must be set")
+	}
+
+	return &jws, &header, nil
+}
+
+// verify checks the signature of the JWS with the given key, and returns the
+// decoded payload
+func (jws *acmeJWS) verify(alg string, key crypto.PublicKey) ([]byte, error) {
+	signature, err := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signature: %s", err)
+	}
+	signed := []byte(jws.Protected + "." + jws.Payload)
+
+	var hash crypto.Hash
+	var curve elliptic.Curve
+	switch alg {
+	case "RS256":
+		hash = crypto.SHA256
+	case "ES256":
+		hash, curve = crypto.SHA256, elliptic.P256()
+	case "ES384":
+		hash, curve = crypto.SHA384, elliptic.P384()
+	case "ES512":
+		hash, curve = crypto.SHA512, elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if curve != nil {
+			return nil, fmt.Errorf("signature algorithm %q does not match the key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+			return nil, fmt.Errorf("invalid signature")
+		}
+
+	case *ecdsa.PublicKey:
+		if curve != key.Curve {
+			return nil, fmt.Errorf("signature algorithm %q does not match the key", alg)
+		}
+		// The signature is the concatenation of R and S, each the size of
+		// the curve
+		size := (curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return nil, fmt.Errorf("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return nil, fmt.Errorf("invalid signature")
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported key type")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding payload: %s", err)
+	}
+	return payload, nil
+}
+
+func decodeBigInt(encoded string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
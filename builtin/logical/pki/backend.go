@@ -1,6 +1,8 @@
 package pki
 
 import (
+	"net"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -28,13 +30,29 @@ func Backend() *backend {
 				"ca",
 				"crl/pem",
 				"crl",
+				"crl/delta",
+				"crl/delta/pem",
+				"issuer/*",
+				"ocsp",
+				"ocsp/*",
+				"acme/*",
 			},
 
 			LocalStorage: []string{
 				"revoked/",
 				"crl",
+				"crls/",
+				"delta_crl",
+				"delta_crls/",
 				"certs/",
 			},
+
+			// OCSP requests are DER encoded, and ACME requests are JWS
+			// documents parsed by the backend
+			RawRequestBody: []string{
+				"ocsp",
+				"acme/*",
+			},
 		},
 
 		Paths: []*framework.Path{
@@ -45,8 +63,11 @@ func Backend() *backend {
 			pathSetSignedIntermediate(&b),
 			pathSignIntermediate(&b),
 			pathConfigCA(&b),
+			pathConfigIssuers(&b),
 			pathConfigCRL(&b),
 			pathConfigURLs(&b),
+			pathConfigOCSP(&b),
+			pathConfigACME(&b),
 			pathSignVerbatim(&b),
 			pathSign(&b),
 			pathIssue(&b),
@@ -57,6 +78,23 @@ func Backend() *backend {
 			pathFetchCRLViaCertPath(&b),
 			pathFetchValid(&b),
 			pathFetchListCerts(&b),
+			pathListIssuers(&b),
+			pathIssuers(&b),
+			pathCrossSignIssuer(&b),
+			pathFetchIssuer(&b),
+			pathOCSP(&b),
+			pathOCSPGet(&b),
+			pathACMEDirectory(&b),
+			pathACMENewNonce(&b),
+			pathACMENewAccount(&b),
+			pathACMEAccount(&b),
+			pathACMEAccountOrders(&b),
+			pathACMENewOrder(&b),
+			pathACMEOrder(&b),
+			pathACMEOrderFinalize(&b),
+			pathACMEAuthorization(&b),
+			pathACMEChallenge(&b),
+			pathACMECertificate(&b),
 			pathRevoke(&b),
 			pathTidy(&b),
 		},
@@ -64,9 +102,14 @@ func Backend() *backend {
 		Secrets: []*framework.Secret{
 			secretCerts(&b),
 		},
+
+		PeriodicFunc: b.periodicFunc,
 	}
 
 	b.crlLifetime = time.Hour * 72
+	b.acmeUsedNonces = make(map[string]time.Time)
+	b.acmeHTTPClient = defaultACMEHTTPClient()
+	b.acmeLookupTXT = net.LookupTXT
 
 	return &b
 }
@@ -76,6 +119,33 @@ type backend struct {
 
 	crlLifetime       time.Duration
 	revokeStorageLock sync.RWMutex
+
+	// acmeLock serializes updates of the ACME accounts, orders and
+	// authorizations
+	acmeLock sync.Mutex
+
+	// The key nonces are authenticated with, and the nonces that have been
+	// used with the time they were issued at, in the order they were used.
+	// Nonces issued no later than acmeNonceFloor are rejected.
+	acmeNonceKey       []byte
+	acmeUsedNonces     map[string]time.Time
+	acmeUsedNonceQueue []string
+	acmeNonceFloor     time.Time
+	acmeNonceLock      sync.Mutex
+
+	// The HTTP client and resolver used to validate ACME challenges, which
+	// tests replace
+	acmeHTTPClient *http.Client
+	acmeLookupTXT  acmeLookupTXTFunc
+}
+
+// periodicFunc of the backend will be invoked once a minute by the
+// RollbackManager. This rebuilds the CRLs of mounts with auto_rebuild set.
+func (b *backend) periodicFunc(req *logical.Request) error {
+	if b.System().Tainted() {
+		return nil
+	}
+	return rebuildCRLsIfNeeded(b, req)
 }
 
 const backendHelp = `
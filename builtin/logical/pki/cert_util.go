@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,12 +31,18 @@ const (
 	clientExtKeyUsage
 	codeSigningExtKeyUsage
 	emailProtectionExtKeyUsage
+	ocspSigningExtKeyUsage
 )
 
 type creationBundle struct {
 	CommonName     string
 	OU             []string
 	Organization   []string
+	Country        []string
+	Province       []string
+	Locality       []string
+	StreetAddress  []string
+	PostalCode     []string
 	DNSNames       []string
 	EmailAddresses []string
 	IPAddresses    []net.IP
@@ -47,9 +54,19 @@ type creationBundle struct {
 	KeyUsage       x509.KeyUsage
 	ExtKeyUsage    certExtKeyUsage
 
+	// Extended key usages and certificate policies given as OIDs
+	ExtKeyUsageOIDs   []asn1.ObjectIdentifier
+	PolicyIdentifiers []asn1.ObjectIdentifier
+
 	// Only used when signing a CA cert
 	UseCSRValues bool
 
+	// The name constraints to encode into a CA cert
+	PermittedDNSDomains []string
+	ExcludedDNSDomains  []string
+	PermittedIPRanges   []*net.IPNet
+	ExcludedIPRanges    []*net.IPNet
+
 	// URLs to encode into the certificate
 	URLs *urlEntries
 
@@ -78,6 +95,15 @@ func (b *caInfoBundle) GetCAChain() []*certutil.CertBlock {
 		if b.CAChain != nil && len(b.CAChain) > 0 {
 			chain = append(chain, b.CAChain...)
 		}
+	} else if len(b.CAChain) > 0 {
+		// A root which was cross-signed by another issuer includes itself
+		// and the cross-signed certificates, so that clients trusting
+		// either root can build a path
+		chain = append(chain, &certutil.CertBlock{
+			Certificate: b.Certificate,
+			Bytes:       b.CertificateBytes,
+		})
+		chain = append(chain, b.CAChain...)
 	}
 
 	return chain
@@ -85,6 +111,7 @@ func (b *caInfoBundle) GetCAChain() []*certutil.CertBlock {
 
 var (
 	hostnameRegex                = regexp.MustCompile(`^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9\-]*[A-Za-z0-9])$`)
+	countryRegex                 = regexp.MustCompile(`^[A-Za-z]{2}$`)
 	oidExtensionBasicConstraints = []int{2, 5, 29, 19}
 )
 
@@ -97,6 +124,104 @@ func oidInExtensions(oid asn1.ObjectIdentifier, extensions []pkix.Extension) boo
 	return false
 }
 
+// parseOIDs parses a comma-separated list of object identifiers in dotted
+// notation, such as "1.3.6.1.4.1.311.21.8"
+func parseOIDs(input string) ([]asn1.ObjectIdentifier, error) {
+	oids := []asn1.ObjectIdentifier{}
+	for _, v := range strutil.ParseDedupAndSortStrings(input, ",") {
+		arcs := strings.Split(v, ".")
+		if len(arcs) < 2 {
+			return nil, errutil.UserError{Err: fmt.Sprintf("invalid OID %q: at least two arcs are required", v)}
+		}
+
+		oid := make(asn1.ObjectIdentifier, len(arcs))
+		for i, arc := range arcs {
+			n, err := strconv.Atoi(arc)
+			if err != nil || n < 0 {
+				return nil, errutil.UserError{Err: fmt.Sprintf("invalid OID %q: arcs must be non-negative integers", v)}
+			}
+			oid[i] = n
+		}
+
+		// The first arc is 0, 1 or 2, and below 2 the second arc is below
+		// 40, as these are encoded together
+		if oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+			return nil, errutil.UserError{Err: fmt.Sprintf("invalid OID %q", v)}
+		}
+
+		oids = append(oids, oid)
+	}
+
+	return oids, nil
+}
+
+// parseSubjectValues parses a comma-separated list of values of a subject
+// field, keeping their case and order
+func parseSubjectValues(input string) []string {
+	values := []string{}
+	for _, v := range strutil.ParseStringSlice(input, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseCountries parses a comma-separated list of two-letter ISO 3166
+// country codes
+func parseCountries(input string) ([]string, error) {
+	countries := parseSubjectValues(input)
+	for i, v := range countries {
+		if !countryRegex.MatchString(v) {
+			return nil, errutil.UserError{Err: fmt.Sprintf("invalid country %q: a two-letter ISO 3166 code is required", v)}
+		}
+		countries[i] = strings.ToUpper(v)
+	}
+	return countries, nil
+}
+
+// parseDNSConstraints parses a comma-separated list of DNS domains of a name
+// constraint. A leading period limits the constraint to subdomains.
+func parseDNSConstraints(input string) ([]string, error) {
+	domains := strutil.ParseDedupAndSortStrings(input, ",")
+	for _, v := range domains {
+		if !hostnameRegex.MatchString(strings.TrimPrefix(v, ".")) {
+			return nil, errutil.UserError{Err: fmt.Sprintf("invalid DNS domain %q in name constraints", v)}
+		}
+	}
+	return domains, nil
+}
+
+// parseIPConstraints parses a comma-separated list of IP ranges in CIDR
+// notation of a name constraint
+func parseIPConstraints(input string) ([]*net.IPNet, error) {
+	ranges := []*net.IPNet{}
+	for _, v := range strutil.ParseDedupAndSortStrings(input, ",") {
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, errutil.UserError{Err: fmt.Sprintf("invalid IP range %q in name constraints: %s", v, err)}
+		}
+		ranges = append(ranges, ipNet)
+	}
+	return ranges, nil
+}
+
+// validateRoleProfile checks the subject fields and OIDs of a role, which
+// are otherwise only parsed when issuing
+func validateRoleProfile(role *roleEntry) error {
+	if _, err := parseCountries(role.Country); err != nil {
+		return err
+	}
+	if _, err := parseOIDs(role.PolicyIdentifiers); err != nil {
+		return err
+	}
+	if _, err := parseOIDs(role.ExtKeyUsageOIDs); err != nil {
+		return err
+	}
+	return nil
+}
+
 func getFormat(data *framework.FieldData) string {
 	format := data.Get("format").(string)
 	switch format {
@@ -138,47 +263,9 @@ func validateKeyTypeLength(keyType string, keyBits int) *logical.Response {
 	return nil
 }
 
-// Fetches the CA info. Unlike other certificates, the CA info is stored
-// in the backend as a CertBundle, because we are storing its private key
+// Fetches the CA info of the default issuer
 func fetchCAInfo(req *logical.Request) (*caInfoBundle, error) {
-	bundleEntry, err := req.Storage.Get("config/ca_bundle")
-	if err != nil {
-		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch local CA certificate/key: %v", err)}
-	}
-	if bundleEntry == nil {
-		return nil, errutil.UserError{Err: "backend must be configured with a CA certificate/key"}
-	}
-
-	var bundle certutil.CertBundle
-	if err := bundleEntry.DecodeJSON(&bundle); err != nil {
-		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode local CA certificate/key: %v", err)}
-	}
-
-	parsedBundle, err := bundle.ToParsedCertBundle()
-	if err != nil {
-		return nil, errutil.InternalError{Err: err.Error()}
-	}
-
-	if parsedBundle.Certificate == nil {
-		return nil, errutil.InternalError{Err: "stored CA information not able to be parsed"}
-	}
-
-	caInfo := &caInfoBundle{*parsedBundle, nil}
-
-	entries, err := getURLs(req)
-	if err != nil {
-		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch URL information: %v", err)}
-	}
-	if entries == nil {
-		entries = &urlEntries{
-			IssuingCertificates:   []string{},
-			CRLDistributionPoints: []string{},
-			OCSPServers:           []string{},
-		}
-	}
-	caInfo.URLs = entries
-
-	return caInfo, nil
+	return fetchIssuerInfo(req, "")
 }
 
 // Allows fetching certificates from the backend; it handles the slightly
@@ -191,10 +278,20 @@ func fetchCertBySerial(req *logical.Request, prefix, serial string) (*logical.St
 	// we actually want revocation info
 	case strings.HasPrefix(prefix, "revoked/"):
 		path = "revoked/" + strings.Replace(strings.ToLower(serial), "-", ":", -1)
-	case serial == "ca":
-		path = "ca"
-	case serial == "crl":
-		path = "crl"
+	case serial == "ca" || serial == "crl" || serial == "delta_crl":
+		// These refer to the default issuer
+		issuer, err := defaultIssuer(req.Storage)
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("error fetching the default issuer: %s", err)}
+		}
+		switch serial {
+		case "ca":
+			path = issuerCAPath(issuer)
+		case "crl":
+			path = issuerCRLPath(issuer)
+		default:
+			path = issuerDeltaCRLPath(issuer)
+		}
 	default:
 		path = "certs/" + strings.Replace(strings.ToLower(serial), "-", ":", -1)
 	}
@@ -677,6 +774,49 @@ func generateCreationBundle(b *backend,
 		}
 	}
 
+	// Set C (country) values if specified in the role
+	country, err := parseCountries(role.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the certificate policies and additional extended key usages
+	policyIdentifiers, err := parseOIDs(role.PolicyIdentifiers)
+	if err != nil {
+		return nil, err
+	}
+	extKeyUsageOIDs, err := parseOIDs(role.ExtKeyUsageOIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get and verify any name constraints, which are only requested when
+	// issuing CA certs
+	var permittedDNSDomains, excludedDNSDomains []string
+	var permittedIPRanges, excludedIPRanges []*net.IPNet
+	{
+		if v, ok := data.GetOk("permitted_dns_domains"); ok {
+			if permittedDNSDomains, err = parseDNSConstraints(v.(string)); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := data.GetOk("excluded_dns_domains"); ok {
+			if excludedDNSDomains, err = parseDNSConstraints(v.(string)); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := data.GetOk("permitted_ip_ranges"); ok {
+			if permittedIPRanges, err = parseIPConstraints(v.(string)); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := data.GetOk("excluded_ip_ranges"); ok {
+			if excludedIPRanges, err = parseIPConstraints(v.(string)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Get the TTL and very it against the max allowed
 	var ttlField string
 	var ttl time.Duration
@@ -745,21 +885,35 @@ func generateCreationBundle(b *backend,
 		if role.EmailProtectionFlag {
 			extUsage = extUsage | emailProtectionExtKeyUsage
 		}
+		if role.OCSPSigningFlag {
+			extUsage = extUsage | ocspSigningExtKeyUsage
+		}
 	}
 
 	creationBundle := &creationBundle{
-		CommonName:     cn,
-		OU:             ou,
-		Organization:   organization,
-		DNSNames:       dnsNames,
-		EmailAddresses: emailAddresses,
-		IPAddresses:    ipAddresses,
-		KeyType:        role.KeyType,
-		KeyBits:        role.KeyBits,
-		SigningBundle:  signingBundle,
-		TTL:            ttl,
-		KeyUsage:       x509.KeyUsage(parseKeyUsages(role.KeyUsage)),
-		ExtKeyUsage:    extUsage,
+		CommonName:          cn,
+		OU:                  ou,
+		Organization:        organization,
+		Country:             country,
+		Province:            parseSubjectValues(role.Province),
+		Locality:            parseSubjectValues(role.Locality),
+		StreetAddress:       parseSubjectValues(role.StreetAddress),
+		PostalCode:          parseSubjectValues(role.PostalCode),
+		DNSNames:            dnsNames,
+		EmailAddresses:      emailAddresses,
+		IPAddresses:         ipAddresses,
+		KeyType:             role.KeyType,
+		KeyBits:             role.KeyBits,
+		SigningBundle:       signingBundle,
+		TTL:                 ttl,
+		KeyUsage:            x509.KeyUsage(parseKeyUsages(role.KeyUsage)),
+		ExtKeyUsage:         extUsage,
+		ExtKeyUsageOIDs:     extKeyUsageOIDs,
+		PolicyIdentifiers:   policyIdentifiers,
+		PermittedDNSDomains: permittedDNSDomains,
+		ExcludedDNSDomains:  excludedDNSDomains,
+		PermittedIPRanges:   permittedIPRanges,
+		ExcludedIPRanges:    excludedIPRanges,
 	}
 
 	// Don't deal with URLs or max path length if it's self-signed, as these
@@ -797,6 +951,10 @@ func generateCreationBundle(b *backend,
 // addKeyUsages adds approrpiate key usages to the template given the creation
 // information
 func addKeyUsages(creationInfo *creationBundle, certTemplate *x509.Certificate) {
+	// Extended key usages given as OIDs are kept on CA certs, where they
+	// limit the usages of the certs the CA issues
+	certTemplate.UnknownExtKeyUsage = creationInfo.ExtKeyUsageOIDs
+
 	if creationInfo.IsCA {
 		certTemplate.KeyUsage = x509.KeyUsage(x509.KeyUsageCertSign | x509.KeyUsageCRLSign)
 		return
@@ -816,6 +974,38 @@ func addKeyUsages(creationInfo *creationBundle, certTemplate *x509.Certificate)
 	if creationInfo.ExtKeyUsage&emailProtectionExtKeyUsage != 0 {
 		certTemplate.ExtKeyUsage = append(certTemplate.ExtKeyUsage, x509.ExtKeyUsageEmailProtection)
 	}
+	if creationInfo.ExtKeyUsage&ocspSigningExtKeyUsage != 0 {
+		certTemplate.ExtKeyUsage = append(certTemplate.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning)
+	}
+}
+
+// addNameConstraints adds the requested name constraints to the template of
+// a CA cert. Name constraints are marked critical, as RFC 5280 requires.
+func addNameConstraints(creationInfo *creationBundle, certTemplate *x509.Certificate) {
+	if !creationInfo.IsCA {
+		return
+	}
+
+	certTemplate.PermittedDNSDomains = creationInfo.PermittedDNSDomains
+	certTemplate.ExcludedDNSDomains = creationInfo.ExcludedDNSDomains
+	certTemplate.PermittedIPRanges = creationInfo.PermittedIPRanges
+	certTemplate.ExcludedIPRanges = creationInfo.ExcludedIPRanges
+	certTemplate.PermittedDNSDomainsCritical = true
+}
+
+// subjectFromCreationBundle returns the subject of the cert described by
+// the creation information
+func subjectFromCreationBundle(creationInfo *creationBundle) pkix.Name {
+	return pkix.Name{
+		CommonName:         creationInfo.CommonName,
+		OrganizationalUnit: creationInfo.OU,
+		Organization:       creationInfo.Organization,
+		Country:            creationInfo.Country,
+		Province:           creationInfo.Province,
+		Locality:           creationInfo.Locality,
+		StreetAddress:      creationInfo.StreetAddress,
+		PostalCode:         creationInfo.PostalCode,
+	}
 }
 
 // Performs the heavy lifting of creating a certificate. Returns
@@ -840,22 +1030,17 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 		return nil, errutil.InternalError{Err: fmt.Sprintf("error getting subject key ID: %s", err)}
 	}
 
-	subject := pkix.Name{
-		CommonName:         creationInfo.CommonName,
-		OrganizationalUnit: creationInfo.OU,
-		Organization:       creationInfo.Organization,
-	}
-
 	certTemplate := &x509.Certificate{
-		SerialNumber:   serialNumber,
-		Subject:        subject,
-		NotBefore:      time.Now().Add(-30 * time.Second),
-		NotAfter:       time.Now().Add(creationInfo.TTL),
-		IsCA:           false,
-		SubjectKeyId:   subjKeyID,
-		DNSNames:       creationInfo.DNSNames,
-		EmailAddresses: creationInfo.EmailAddresses,
-		IPAddresses:    creationInfo.IPAddresses,
+		SerialNumber:      serialNumber,
+		Subject:           subjectFromCreationBundle(creationInfo),
+		NotBefore:         time.Now().Add(-30 * time.Second),
+		NotAfter:          time.Now().Add(creationInfo.TTL),
+		IsCA:              false,
+		SubjectKeyId:      subjKeyID,
+		DNSNames:          creationInfo.DNSNames,
+		EmailAddresses:    creationInfo.EmailAddresses,
+		IPAddresses:       creationInfo.IPAddresses,
+		PolicyIdentifiers: creationInfo.PolicyIdentifiers,
 	}
 
 	// Add this before calling addKeyUsages
@@ -864,6 +1049,7 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 	}
 
 	addKeyUsages(creationInfo, certTemplate)
+	addNameConstraints(creationInfo, certTemplate)
 
 	certTemplate.IssuingCertificateURL = creationInfo.URLs.IssuingCertificates
 	certTemplate.CRLDistributionPoints = creationInfo.URLs.CRLDistributionPoints
@@ -912,17 +1098,7 @@ func createCertificate(creationInfo *creationBundle) (*certutil.ParsedCertBundle
 	}
 
 	if creationInfo.SigningBundle != nil {
-		if len(creationInfo.SigningBundle.Certificate.AuthorityKeyId) > 0 &&
-			!bytes.Equal(creationInfo.SigningBundle.Certificate.AuthorityKeyId, creationInfo.SigningBundle.Certificate.SubjectKeyId) {
-
-			result.CAChain = []*certutil.CertBlock{
-				&certutil.CertBlock{
-					Certificate: creationInfo.SigningBundle.Certificate,
-					Bytes:       creationInfo.SigningBundle.CertificateBytes,
-				},
-			}
-			result.CAChain = append(result.CAChain, creationInfo.SigningBundle.CAChain...)
-		}
+		result.CAChain = creationInfo.SigningBundle.GetCAChain()
 	}
 
 	return result, nil
@@ -1004,18 +1180,13 @@ func signCertificate(creationInfo *creationBundle,
 	}
 	subjKeyID := sha1.Sum(marshaledKey)
 
-	subject := pkix.Name{
-		CommonName:         creationInfo.CommonName,
-		OrganizationalUnit: creationInfo.OU,
-		Organization:       creationInfo.Organization,
-	}
-
 	certTemplate := &x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject:      subject,
-		NotBefore:    time.Now().Add(-30 * time.Second),
-		NotAfter:     time.Now().Add(creationInfo.TTL),
-		SubjectKeyId: subjKeyID[:],
+		SerialNumber:      serialNumber,
+		Subject:           subjectFromCreationBundle(creationInfo),
+		NotBefore:         time.Now().Add(-30 * time.Second),
+		NotAfter:          time.Now().Add(creationInfo.TTL),
+		SubjectKeyId:      subjKeyID[:],
+		PolicyIdentifiers: creationInfo.PolicyIdentifiers,
 	}
 
 	switch creationInfo.SigningBundle.PrivateKeyType {
@@ -1040,6 +1211,7 @@ func signCertificate(creationInfo *creationBundle,
 	}
 
 	addKeyUsages(creationInfo, certTemplate)
+	addNameConstraints(creationInfo, certTemplate)
 
 	var certBytes []byte
 	caCert := creationInfo.SigningBundle.Certificate
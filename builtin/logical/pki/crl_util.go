@@ -1,16 +1,39 @@
 package pki
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"fmt"
+	"math/big"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/vault/helper/certutil"
+
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
 )
 
+// Revocations made while the CRLs are rebuilt periodically are marked under
+// this prefix until a complete CRL lists them
+const crlPendingPrefix = "crl_pending/"
+
+var (
+	oidExtensionAuthorityKeyId    = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionCRLNumber         = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtensionDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+
+	oidSignatureSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
 type revocationInfo struct {
 	CertificateBytes  []byte    `json:"certificate_bytes"`
 	RevocationTime    int64     `json:"revocation_time"`
@@ -98,12 +121,31 @@ func revokeCert(b *backend, req *logical.Request, serial string, fromLease bool)
 
 	}
 
-	crlErr := buildCRL(b, req)
-	switch crlErr.(type) {
-	case errutil.UserError:
-		return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
-	case errutil.InternalError:
-		return nil, fmt.Errorf("Error encountered during CRL building: %s", crlErr)
+	crlInfo, err := b.CRL(req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching CRL config information: %s", err)
+	}
+
+	if crlInfo != nil && crlInfo.AutoRebuild {
+		// The CRLs are rebuilt periodically; the revocation is only marked
+		// as pending, so that delta CRLs can list it in the meantime
+		if !alreadyRevoked {
+			err = req.Storage.Put(&logical.StorageEntry{
+				Key:   crlPendingPrefix + strings.TrimPrefix(revEntry.Key, "revoked/"),
+				Value: []byte{},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Error marking revoked certificate as pending")
+			}
+		}
+	} else {
+		crlErr := buildCRL(b, req)
+		switch crlErr.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
+		case errutil.InternalError:
+			return nil, fmt.Errorf("Error encountered during CRL building: %s", crlErr)
+		}
 	}
 
 	resp := &logical.Response{
@@ -117,39 +159,245 @@ func revokeCert(b *backend, req *logical.Request, serial string, fromLease bool)
 	return resp, nil
 }
 
-// Builds a CRL by going through the list of revoked certificates and building
-// a new CRL with the stored revocation times and serial numbers.
+// Builds the complete CRL of each issuer by going through the list of
+// revoked certificates and building a new CRL with the stored revocation
+// times and serial numbers of the certificates the issuer signed. When delta
+// CRLs are enabled, they are emptied against the new complete CRLs.
 func buildCRL(b *backend, req *logical.Request) error {
+	// Pending revocations are listed before the revoked certificates, so
+	// that only those the new CRLs list are cleared
+	pendingSerials, err := req.Storage.List(crlPendingPrefix)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching list of pending revocations: %s", err)}
+	}
+
 	revokedSerials, err := req.Storage.List("revoked/")
 	if err != nil {
 		return errutil.InternalError{Err: fmt.Sprintf("Error fetching list of revoked certs: %s", err)}
 	}
 
+	revokedCerts, parsedRevokedCerts, err := fetchRevokedCerts(req, revokedSerials)
+	if err != nil {
+		return err
+	}
+
+	crlLifetime := b.crlLifetime
+	crlInfo, err := b.CRL(req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL config information: %s", err)}
+	}
+	if crlInfo != nil {
+		crlDur, err := time.ParseDuration(crlInfo.Expiry)
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error parsing CRL duration of %s", crlInfo.Expiry)}
+		}
+		crlLifetime = crlDur
+	}
+
+	issuers, signingBundles, err := fetchCRLIssuers(req)
+	if err != nil {
+		return err
+	}
+	issuerRevokedCerts, err := groupRevokedCerts(req, issuers, signingBundles, revokedCerts, parsedRevokedCerts)
+	if err != nil {
+		return err
+	}
+
+	state, err := getCRLState(req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL state: %s", err)}
+	}
+
+	now := time.Now()
+	for _, issuer := range issuers {
+		issuerState := state.issuer(issuer)
+		crlBytes, err := createCRL(signingBundles[issuer], issuerRevokedCerts[issuer], issuerState.NextNumber, nil, now, now.Add(crlLifetime))
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL: %s", err)}
+		}
+
+		err = req.Storage.Put(&logical.StorageEntry{
+			Key:   issuerCRLPath(issuer),
+			Value: crlBytes,
+		})
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL: %s", err)}
+		}
+
+		issuerState.BaseNumber = issuerState.NextNumber
+		issuerState.NextNumber++
+	}
+	state.NextUpdate = now.Add(crlLifetime)
+
+	if err := putCRLState(req.Storage, state); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL state: %s", err)}
+	}
+
+	for _, serial := range pendingSerials {
+		if err := req.Storage.Delete(crlPendingPrefix + serial); err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error clearing pending revocation %s: %s", serial, err)}
+		}
+	}
+
+	if crlInfo != nil && crlInfo.EnableDelta {
+		return buildDeltaCRL(b, req)
+	}
+
+	return nil
+}
+
+// Builds the delta CRL of each issuer, listing the certificates revoked
+// since its last complete CRL
+func buildDeltaCRL(b *backend, req *logical.Request) error {
+	crlInfo, err := b.CRL(req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL config information: %s", err)}
+	}
+	if crlInfo == nil || !crlInfo.EnableDelta {
+		return errutil.UserError{Err: "delta CRLs are not enabled"}
+	}
+	deltaInterval, err := time.ParseDuration(crlInfo.DeltaRebuildInterval)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error parsing delta CRL interval of %s", crlInfo.DeltaRebuildInterval)}
+	}
+
+	pendingSerials, err := req.Storage.List(crlPendingPrefix)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching list of pending revocations: %s", err)}
+	}
+
+	revokedCerts, parsedRevokedCerts, err := fetchRevokedCerts(req, pendingSerials)
+	if err != nil {
+		return err
+	}
+
+	issuers, signingBundles, err := fetchCRLIssuers(req)
+	if err != nil {
+		return err
+	}
+	issuerRevokedCerts, err := groupRevokedCerts(req, issuers, signingBundles, revokedCerts, parsedRevokedCerts)
+	if err != nil {
+		return err
+	}
+
+	state, err := getCRLState(req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL state: %s", err)}
+	}
+
+	// A delta CRL stays valid across a missed rebuild, so that clients do
+	// not go without one between two periodic runs
+	now := time.Now()
+	for _, issuer := range issuers {
+		issuerState := state.issuer(issuer)
+		baseNumber := issuerState.BaseNumber
+		crlBytes, err := createCRL(signingBundles[issuer], issuerRevokedCerts[issuer], issuerState.NextNumber, &baseNumber, now, now.Add(2*deltaInterval))
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error creating new delta CRL: %s", err)}
+		}
+
+		err = req.Storage.Put(&logical.StorageEntry{
+			Key:   issuerDeltaCRLPath(issuer),
+			Value: crlBytes,
+		})
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("Error storing delta CRL: %s", err)}
+		}
+
+		issuerState.NextNumber++
+	}
+	state.LastDeltaBuild = now
+
+	if err := putCRLState(req.Storage, state); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL state: %s", err)}
+	}
+
+	return nil
+}
+
+// Rebuilds the CRLs when auto_rebuild is set: the complete CRLs once they
+// are within the grace period of their expiry, and the delta CRLs once the
+// delta interval has passed.
+func rebuildCRLsIfNeeded(b *backend, req *logical.Request) error {
+	crlInfo, err := b.CRL(req.Storage)
+	if err != nil {
+		return err
+	}
+	if crlInfo == nil || !crlInfo.AutoRebuild {
+		return nil
+	}
+
+	issuers, err := listSigningIssuers(req.Storage)
+	if err != nil {
+		return err
+	}
+	if len(issuers) == 0 {
+		return nil
+	}
+
+	gracePeriod, err := time.ParseDuration(crlInfo.AutoRebuildGracePeriod)
+	if err != nil {
+		return fmt.Errorf("Error parsing CRL grace period of %s", crlInfo.AutoRebuildGracePeriod)
+	}
+
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	state, err := getCRLState(req.Storage)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !now.Before(state.NextUpdate.Add(-gracePeriod)) {
+		return buildCRL(b, req)
+	}
+
+	if crlInfo.EnableDelta {
+		deltaInterval, err := time.ParseDuration(crlInfo.DeltaRebuildInterval)
+		if err != nil {
+			return fmt.Errorf("Error parsing delta CRL interval of %s", crlInfo.DeltaRebuildInterval)
+		}
+		if !now.Before(state.LastDeltaBuild.Add(deltaInterval)) {
+			return buildDeltaCRL(b, req)
+		}
+	}
+
+	return nil
+}
+
+// fetchRevokedCerts returns the CRL entries and parsed certificates of the
+// revoked certificates with the given serials
+func fetchRevokedCerts(req *logical.Request, serials []string) ([]pkix.RevokedCertificate, []*x509.Certificate, error) {
 	revokedCerts := []pkix.RevokedCertificate{}
-	var revInfo revocationInfo
-	for _, serial := range revokedSerials {
+	parsedRevokedCerts := []*x509.Certificate{}
+	for _, serial := range serials {
+		// The parsed certificates keep referencing the decoded bytes, so
+		// these are not reused between entries
+		var revInfo revocationInfo
+
 		revokedEntry, err := req.Storage.Get("revoked/" + serial)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Unable to fetch revoked cert with serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Unable to fetch revoked cert with serial %s: %s", serial, err)}
 		}
 		if revokedEntry == nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Revoked certificate entry for serial %s is nil", serial)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Revoked certificate entry for serial %s is nil", serial)}
 		}
 		if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
 			// TODO: In this case, remove it and continue? How likely is this to
 			// happen? Alternately, could skip it entirely, or could implement a
 			// delete function so that there is a way to remove these
-			return errutil.InternalError{Err: fmt.Sprintf("Found revoked serial but actual certificate is empty")}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Found revoked serial but actual certificate is empty")}
 		}
 
 		err = revokedEntry.DecodeJSON(&revInfo)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Error decoding revocation entry for serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Error decoding revocation entry for serial %s: %s", serial, err)}
 		}
 
 		revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Unable to parse stored revoked certificate with serial %s: %s", serial, err)}
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Unable to parse stored revoked certificate with serial %s: %s", serial, err)}
 		}
 
 		// NOTE: We have to change this to UTC time because the CRL standard
@@ -163,41 +411,247 @@ func buildCRL(b *backend, req *logical.Request) error {
 			newRevCert.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
 		}
 		revokedCerts = append(revokedCerts, newRevCert)
+		parsedRevokedCerts = append(parsedRevokedCerts, revokedCert)
 	}
 
-	signingBundle, caErr := fetchCAInfo(req)
-	switch caErr.(type) {
-	case errutil.UserError:
-		return errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
-	case errutil.InternalError:
-		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
+	return revokedCerts, parsedRevokedCerts, nil
+}
+
+// fetchCRLIssuers returns the names and CA info of the issuers with a
+// certificate. Issuers only holding a key pending their signed certificate
+// have no CRL yet.
+func fetchCRLIssuers(req *logical.Request) ([]string, map[string]*caInfoBundle, error) {
+	issuers, err := listSigningIssuers(req.Storage)
+	if err != nil {
+		return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Error fetching list of issuers: %s", err)}
+	}
+	if len(issuers) == 0 {
+		return nil, nil, errutil.UserError{Err: "Could not fetch the CA certificate: backend must be configured with a CA certificate/key"}
 	}
 
-	crlLifetime := b.crlLifetime
-	crlInfo, err := b.CRL(req.Storage)
+	signingBundles := make(map[string]*caInfoBundle, len(issuers))
+	for _, issuer := range issuers {
+		signingBundle, caErr := fetchIssuerInfo(req, issuer)
+		switch caErr.(type) {
+		case errutil.UserError:
+			return nil, nil, errutil.UserError{Err: fmt.Sprintf("Could not fetch the CA certificate: %s", caErr)}
+		case errutil.InternalError:
+			return nil, nil, errutil.InternalError{Err: fmt.Sprintf("Error fetching CA certificate: %s", caErr)}
+		}
+		signingBundles[issuer] = signingBundle
+	}
+
+	return issuers, signingBundles, nil
+}
+
+// Each revoked certificate is listed on the CRL of its issuer. Those not
+// issued by any issuer of the mount, such as certificates of a CA since
+// replaced, stay listed on the CRL of the default issuer.
+func groupRevokedCerts(req *logical.Request, issuers []string, signingBundles map[string]*caInfoBundle,
+	revokedCerts []pkix.RevokedCertificate, parsedRevokedCerts []*x509.Certificate) (map[string][]pkix.RevokedCertificate, error) {
+	defaultName, err := defaultIssuer(req.Storage)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error fetching CRL config information: %s", err)}
+		return nil, errutil.InternalError{Err: fmt.Sprintf("Error fetching the default issuer: %s", err)}
 	}
-	if crlInfo != nil {
-		crlDur, err := time.ParseDuration(crlInfo.Expiry)
+
+	issuerRevokedCerts := make(map[string][]pkix.RevokedCertificate, len(issuers))
+	for i, revokedCert := range parsedRevokedCerts {
+		issuer := defaultName
+		for _, name := range issuers {
+			if issuedBy(revokedCert, signingBundles[name].Certificate) {
+				issuer = name
+				break
+			}
+		}
+		issuerRevokedCerts[issuer] = append(issuerRevokedCerts[issuer], revokedCerts[i])
+	}
+
+	return issuerRevokedCerts, nil
+}
+
+// issuedBy returns whether the certificate names the CA as its issuer. The
+// key identifiers are compared when both are set, as issuers rotated within
+// a mount often keep the same subject.
+func issuedBy(cert, ca *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, ca.RawSubject) {
+		return false
+	}
+	if len(cert.AuthorityKeyId) > 0 && len(ca.SubjectKeyId) > 0 {
+		return bytes.Equal(cert.AuthorityKeyId, ca.SubjectKeyId)
+	}
+	return true
+}
+
+// crlState tracks the numbers of the CRLs of each issuer, and when the CRLs
+// were last built. Like the CRLs, it is kept in local storage.
+type crlState struct {
+	Issuers map[string]*issuerCRLState `json:"issuers"`
+
+	// NextUpdate is when the complete CRLs expire
+	NextUpdate     time.Time `json:"next_update"`
+	LastDeltaBuild time.Time `json:"last_delta_build"`
+}
+
+type issuerCRLState struct {
+	// Complete and delta CRLs of an issuer share one sequence of numbers,
+	// as RFC 5280 requires
+	NextNumber int64 `json:"next_number"`
+
+	// BaseNumber is the number of the last complete CRL, which the delta
+	// CRLs refer to
+	BaseNumber int64 `json:"base_number"`
+}
+
+func (s *crlState) issuer(name string) *issuerCRLState {
+	issuerState, ok := s.Issuers[name]
+	if !ok {
+		issuerState = &issuerCRLState{}
+		s.Issuers[name] = issuerState
+	}
+	return issuerState
+}
+
+func getCRLState(s logical.Storage) (*crlState, error) {
+	state := &crlState{
+		Issuers: map[string]*issuerCRLState{},
+	}
+
+	entry, err := s.Get("crl_state")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return state, nil
+	}
+
+	if err := entry.DecodeJSON(state); err != nil {
+		return nil, err
+	}
+	if state.Issuers == nil {
+		state.Issuers = map[string]*issuerCRLState{}
+	}
+
+	return state, nil
+}
+
+func putCRLState(s logical.Storage, state *crlState) error {
+	entry, err := logical.StorageEntryJSON("crl_state", state)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// The ASN.1 structures of a CRL, with the issuer kept as the raw subject of
+// the CA so that it matches byte for byte
+type tbsCertificateList struct {
+	Version             int `asn1:"optional,default:0"`
+	Signature           pkix.AlgorithmIdentifier
+	Issuer              asn1.RawValue
+	ThisUpdate          time.Time
+	NextUpdate          time.Time                 `asn1:"optional"`
+	RevokedCertificates []pkix.RevokedCertificate `asn1:"optional"`
+	Extensions          []pkix.Extension          `asn1:"tag:0,optional,explicit"`
+}
+
+type certificateList struct {
+	TBSCertList        asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type authorityKeyId struct {
+	Id []byte `asn1:"optional,tag:0"`
+}
+
+// createCRL signs a version 2 CRL carrying the given CRL number. Delta CRLs
+// are given the number of the complete CRL they are based on. The CRL is
+// built here rather than with x509.Certificate.CreateCRL, which has no way
+// to set these extensions.
+func createCRL(signingBundle *caInfoBundle, revokedCerts []pkix.RevokedCertificate, number int64, baseNumber *int64, thisUpdate, nextUpdate time.Time) ([]byte, error) {
+	var sigAlg pkix.AlgorithmIdentifier
+	var hashFunc crypto.Hash
+	switch signingBundle.PrivateKeyType {
+	case certutil.RSAPrivateKey:
+		sigAlg.Algorithm = oidSignatureSHA256WithRSA
+		sigAlg.Parameters = asn1.NullRawValue
+		hashFunc = crypto.SHA256
+	case certutil.ECPrivateKey:
+		publicKey, ok := signingBundle.PrivateKey.Public().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported EC key")
+		}
+		switch bits := publicKey.Curve.Params().BitSize; {
+		case bits > 384:
+			sigAlg.Algorithm = oidSignatureECDSAWithSHA512
+			hashFunc = crypto.SHA512
+		case bits > 256:
+			sigAlg.Algorithm = oidSignatureECDSAWithSHA384
+			hashFunc = crypto.SHA384
+		default:
+			sigAlg.Algorithm = oidSignatureECDSAWithSHA256
+			hashFunc = crypto.SHA256
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", signingBundle.PrivateKeyType)
+	}
+
+	var extensions []pkix.Extension
+	if len(signingBundle.Certificate.SubjectKeyId) > 0 {
+		value, err := asn1.Marshal(authorityKeyId{Id: signingBundle.Certificate.SubjectKeyId})
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("Error parsing CRL duration of %s", crlInfo.Expiry)}
+			return nil, err
 		}
-		crlLifetime = crlDur
+		extensions = append(extensions, pkix.Extension{
+			Id:    oidExtensionAuthorityKeyId,
+			Value: value,
+		})
 	}
 
-	crlBytes, err := signingBundle.Certificate.CreateCRL(rand.Reader, signingBundle.PrivateKey, revokedCerts, time.Now(), time.Now().Add(crlLifetime))
+	value, err := asn1.Marshal(big.NewInt(number))
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error creating new CRL: %s", err)}
+		return nil, err
+	}
+	extensions = append(extensions, pkix.Extension{
+		Id:    oidExtensionCRLNumber,
+		Value: value,
+	})
+
+	if baseNumber != nil {
+		value, err := asn1.Marshal(big.NewInt(*baseNumber))
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{
+			Id:       oidExtensionDeltaCRLIndicator,
+			Critical: true,
+			Value:    value,
+		})
 	}
 
-	err = req.Storage.Put(&logical.StorageEntry{
-		Key:   "crl",
-		Value: crlBytes,
+	tbsCertList, err := asn1.Marshal(tbsCertificateList{
+		Version:             1,
+		Signature:           sigAlg,
+		Issuer:              asn1.RawValue{FullBytes: signingBundle.Certificate.RawSubject},
+		ThisUpdate:          thisUpdate.UTC(),
+		NextUpdate:          nextUpdate.UTC(),
+		RevokedCertificates: revokedCerts,
+		Extensions:          extensions,
 	})
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("Error storing CRL: %s", err)}
+		return nil, err
 	}
 
-	return nil
+	hash := hashFunc.New()
+	hash.Write(tbsCertList)
+	signature, err := signingBundle.PrivateKey.Sign(rand.Reader, hash.Sum(nil), hashFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(certificateList{
+		TBSCertList:        asn1.RawValue{FullBytes: tbsCertList},
+		SignatureAlgorithm: sigAlg,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
 }
@@ -0,0 +1,226 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestBackend_DeltaCRL(t *testing.T) {
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+
+	b := Backend()
+	_, err := b.Setup(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawRequest := func(operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := rawRequest(operation, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path %s\nerr: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	requestError := func(operation logical.Operation, path string, data map[string]interface{}) {
+		resp, err := rawRequest(operation, path, data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error for path %s, got: %#v", path, resp)
+		}
+	}
+
+	periodic := func() {
+		if err := b.periodicFunc(&logical.Request{Storage: storage}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp := request(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+		"ttl":         "6h",
+	})
+	block, _ := pem.Decode([]byte(resp.Data["certificate"].(string)))
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fetchCRL returns the serials and CRL number of the CRL at the path,
+	// and the number of the complete CRL when it is a delta CRL
+	fetchCRL := func(path string) (map[string]bool, int64, int64) {
+		resp := request(logical.ReadOperation, path, nil)
+		crl, err := x509.ParseCRL(resp.Data[logical.HTTPRawBody].([]byte))
+		if err != nil {
+			t.Fatalf("bad: path %s: %s", path, err)
+		}
+		if err := caCert.CheckCRLSignature(crl); err != nil {
+			t.Fatalf("bad: path %s: %s", path, err)
+		}
+
+		number, baseNumber := int64(-1), int64(-1)
+		for _, ext := range crl.TBSCertList.Extensions {
+			switch {
+			case ext.Id.Equal(oidExtensionCRLNumber):
+				if _, err := asn1.Unmarshal(ext.Value, &number); err != nil {
+					t.Fatal(err)
+				}
+			case ext.Id.Equal(oidExtensionDeltaCRLIndicator):
+				if !ext.Critical {
+					t.Fatal("bad: delta CRL indicator is not critical")
+				}
+				if _, err := asn1.Unmarshal(ext.Value, &baseNumber); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if number < 0 {
+			t.Fatalf("bad: path %s: CRL has no number", path)
+		}
+
+		serials := map[string]bool{}
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			serials[revoked.SerialNumber.String()] = true
+		}
+		return serials, number, baseNumber
+	}
+
+	request(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "myvault.com",
+		"allow_subdomains": true,
+		"max_ttl":          "4h",
+	})
+	issue := func() (string, string) {
+		resp := request(logical.UpdateOperation, "issue/test", map[string]interface{}{
+			"common_name": "foo.myvault.com",
+		})
+		block, _ := pem.Decode([]byte(resp.Data["certificate"].(string)))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data["serial_number"].(string), cert.SerialNumber.String()
+	}
+
+	// Delta CRLs need revocations to be recorded rather than built
+	requestError(logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"enable_delta": true,
+	})
+	requestError(logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"auto_rebuild":              true,
+		"expiry":                    "12h",
+		"auto_rebuild_grace_period": "12h",
+	})
+
+	// Without auto_rebuild, revoking still rebuilds the CRL at once
+	firstSerial, firstNumber := issue()
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": firstSerial,
+	})
+	serials, _, baseNumber := fetchCRL("crl")
+	if !serials[firstNumber] || baseNumber != -1 {
+		t.Fatalf("bad: CRL %v, base %d", serials, baseNumber)
+	}
+
+	request(logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"auto_rebuild":           true,
+		"enable_delta":           true,
+		"delta_rebuild_interval": "10m",
+	})
+	resp = request(logical.ReadOperation, "config/crl", nil)
+	if resp.Data["auto_rebuild"] != true || resp.Data["enable_delta"] != true ||
+		resp.Data["auto_rebuild_grace_period"] != "12h" || resp.Data["delta_rebuild_interval"] != "10m" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	request(logical.ReadOperation, "crl/rotate", nil)
+	_, fullNumber, _ := fetchCRL("crl")
+	_, deltaNumber, baseNumber := fetchCRL("crl/delta")
+	if baseNumber != fullNumber || deltaNumber <= fullNumber {
+		t.Fatalf("bad: complete CRL %d, delta CRL %d based on %d", fullNumber, deltaNumber, baseNumber)
+	}
+
+	// With auto_rebuild, a revocation is only listed once the periodic
+	// function rebuilds the delta CRL
+	secondSerial, secondNumber := issue()
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": secondSerial,
+	})
+	if serials, _, _ := fetchCRL("crl"); serials[secondNumber] {
+		t.Fatal("bad: revocation built into the complete CRL at once")
+	}
+
+	periodic()
+	if serials, _, _ := fetchCRL("crl/delta"); len(serials) != 0 {
+		t.Fatalf("bad: delta CRL rebuilt before its interval: %v", serials)
+	}
+
+	state, err := getCRLState(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.LastDeltaBuild = time.Now().Add(-time.Hour)
+	if err := putCRLState(storage, state); err != nil {
+		t.Fatal(err)
+	}
+
+	periodic()
+	serials, number, baseNumber := fetchCRL("crl/delta")
+	if len(serials) != 1 || !serials[secondNumber] {
+		t.Fatalf("bad: delta CRL %v", serials)
+	}
+	if baseNumber != fullNumber || number <= deltaNumber {
+		t.Fatalf("bad: delta CRL %d based on %d", number, baseNumber)
+	}
+	if issuerSerials, _, _ := fetchCRL("issuer/default/crl/delta"); len(issuerSerials) != 1 {
+		t.Fatalf("bad: delta CRL of the default issuer %v", issuerSerials)
+	}
+	resp = request(logical.ReadOperation, "crl/delta/pem", nil)
+	if block, _ := pem.Decode(resp.Data[logical.HTTPRawBody].([]byte)); block == nil || block.Type != "X509 CRL" {
+		t.Fatal("bad: delta CRL not PEM encoded")
+	}
+
+	// Once the complete CRL is within its grace period, the periodic
+	// function rebuilds it with every revocation and empties the delta CRL
+	state, err = getCRLState(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.NextUpdate = time.Now().Add(time.Hour)
+	if err := putCRLState(storage, state); err != nil {
+		t.Fatal(err)
+	}
+
+	periodic()
+	serials, newFullNumber, _ := fetchCRL("crl")
+	if !serials[firstNumber] || !serials[secondNumber] || newFullNumber <= number {
+		t.Fatalf("bad: complete CRL %d: %v", newFullNumber, serials)
+	}
+	serials, _, baseNumber = fetchCRL("crl/delta")
+	if len(serials) != 0 || baseNumber != newFullNumber {
+		t.Fatalf("bad: delta CRL based on %d: %v", baseNumber, serials)
+	}
+
+	pending, err := storage.List(crlPendingPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("bad: pending revocations left %v", pending)
+	}
+}
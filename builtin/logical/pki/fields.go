@@ -146,3 +146,105 @@ func addCAIssueFields(fields map[string]*framework.FieldSchema) map[string]*fram
 
 	return fields
 }
+
+// addProfileFields adds the subject fields, certificate policies and
+// extended key usage OIDs of the certificate profile of roles and CA signing
+func addProfileFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["country"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `If set, the C (Country) will be set to these
+comma-separated two-letter ISO 3166 codes in
+issued certificates.`,
+	}
+
+	fields["province"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `If set, the ST (Province) will be set to these
+comma-separated values in issued certificates.`,
+	}
+
+	fields["locality"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `If set, the L (Locality) will be set to these
+comma-separated values in issued certificates.`,
+	}
+
+	fields["street_address"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `If set, the Street Address will be set to these
+comma-separated values in issued certificates.`,
+	}
+
+	fields["postal_code"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `If set, the Postal Code will be set to these
+comma-separated values in issued certificates.`,
+	}
+
+	fields["policy_identifiers"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `A comma-separated list of certificate policy
+OIDs in dotted notation, e.g. "2.23.140.1.2.1",
+added to the certificate policies extension of
+issued certificates.`,
+	}
+
+	fields["ext_key_usage_oids"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `A comma-separated list of extended key usage
+OIDs in dotted notation, added to the extended
+key usages set by the usage flags.`,
+	}
+
+	return fields
+}
+
+// addCANameConstraintFields adds the name constraints of CA certificates
+func addCANameConstraintFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["permitted_dns_domains"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `A comma-separated list of DNS domains the CA
+may issue certificates for. A leading period,
+e.g. ".example.com", permits only subdomains.`,
+	}
+
+	fields["excluded_dns_domains"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `A comma-separated list of DNS domains the CA
+may not issue certificates for.`,
+	}
+
+	fields["permitted_ip_ranges"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `A comma-separated list of IP ranges in CIDR
+notation the CA may issue certificates for.`,
+	}
+
+	fields["excluded_ip_ranges"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `A comma-separated list of IP ranges in CIDR
+notation the CA may not issue certificates for.`,
+	}
+
+	return fields
+}
+
+// addIssuerNameFields adds the field naming the issuer a CA is stored as
+func addIssuerNameFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["issuer_name"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: defaultIssuerName,
+		Description: `The name of the issuer the CA is stored as.
+Defaults to "default", the issuer used by
+mounts holding a single CA.`,
+	}
+
+	return fields
+}
@@ -0,0 +1,235 @@
+package pki
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// defaultIssuerName is the name of the issuer stored at the locations used
+// before a mount could hold more than one CA
+const defaultIssuerName = "default"
+
+var issuerNameRegex = regexp.MustCompile("^" + framework.GenericNameRegex("name") + "$")
+
+// The default issuer keeps the legacy storage locations, so that mounts
+// configured before named issuers existed keep working unchanged
+func issuerBundlePath(name string) string {
+	if name == defaultIssuerName {
+		return "config/ca_bundle"
+	}
+	return "issuers/" + name + "/bundle"
+}
+
+func issuerCAPath(name string) string {
+	if name == defaultIssuerName {
+		return "ca"
+	}
+	return "issuers/" + name + "/ca"
+}
+
+func issuerCRLPath(name string) string {
+	if name == defaultIssuerName {
+		return "crl"
+	}
+	return "crls/" + name
+}
+
+func issuerDeltaCRLPath(name string) string {
+	if name == defaultIssuerName {
+		return "delta_crl"
+	}
+	return "delta_crls/" + name
+}
+
+func validIssuerName(name string) bool {
+	return issuerNameRegex.MatchString(name)
+}
+
+// defaultIssuer returns the name of the issuer used when none is given
+func defaultIssuer(s logical.Storage) (string, error) {
+	config, err := getIssuersConfig(s)
+	if err != nil {
+		return "", err
+	}
+	if config == nil || config.Default == "" {
+		return defaultIssuerName, nil
+	}
+	return config.Default, nil
+}
+
+// listIssuers returns the sorted names of the issuers of the mount,
+// including issuers only holding a key pending their signed certificate
+func listIssuers(s logical.Storage) ([]string, error) {
+	names := []string{}
+
+	entry, err := s.Get(issuerBundlePath(defaultIssuerName))
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		names = append(names, defaultIssuerName)
+	}
+
+	keys, err := s.List("issuers/")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			names = append(names, strings.TrimSuffix(key, "/"))
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// listSigningIssuers returns the sorted names of the issuers of the mount
+// which have a certificate
+func listSigningIssuers(s logical.Storage) ([]string, error) {
+	issuers, err := listIssuers(s)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, issuer := range issuers {
+		bundle, err := fetchIssuerBundle(s, issuer)
+		if err != nil {
+			return nil, err
+		}
+		if bundle != nil && bundle.Certificate != "" {
+			names = append(names, issuer)
+		}
+	}
+
+	return names, nil
+}
+
+// fetchIssuerBundle returns the stored bundle of the issuer, or nil if there
+// is no such issuer
+func fetchIssuerBundle(s logical.Storage, name string) (*certutil.CertBundle, error) {
+	entry, err := s.Get(issuerBundlePath(name))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var bundle certutil.CertBundle
+	if err := entry.DecodeJSON(&bundle); err != nil {
+		return nil, err
+	}
+
+	return &bundle, nil
+}
+
+// storeIssuerBundle stores the bundle of the issuer, and its certificate
+// when it has one. The first issuer stored with a certificate in a mount
+// without a default issuer becomes the default.
+func storeIssuerBundle(s logical.Storage, name string, cb *certutil.CertBundle, certBytes []byte) error {
+	entry, err := logical.StorageEntryJSON(issuerBundlePath(name), cb)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(entry); err != nil {
+		return err
+	}
+
+	if certBytes == nil {
+		return nil
+	}
+
+	// For ease of later use, also store just the certificate at a known
+	// location
+	err = s.Put(&logical.StorageEntry{
+		Key:   issuerCAPath(name),
+		Value: certBytes,
+	})
+	if err != nil {
+		return err
+	}
+
+	if name == defaultIssuerName {
+		return nil
+	}
+	config, err := getIssuersConfig(s)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		return nil
+	}
+	legacy, err := s.Get(issuerBundlePath(defaultIssuerName))
+	if err != nil {
+		return err
+	}
+	if legacy != nil {
+		return nil
+	}
+	entry, err = logical.StorageEntryJSON("config/issuers", &issuersConfig{
+		Default: name,
+	})
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// Fetches the info of the named issuer, or of the default issuer if no name
+// is given. Unlike other certificates, the CA info is stored in the backend
+// as a CertBundle, because we are storing its private key
+func fetchIssuerInfo(req *logical.Request, name string) (*caInfoBundle, error) {
+	if name == "" {
+		var err error
+		name, err = defaultIssuer(req.Storage)
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch the default issuer: %v", err)}
+		}
+	}
+
+	bundle, err := fetchIssuerBundle(req.Storage, name)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch local CA certificate/key: %v", err)}
+	}
+	if bundle == nil {
+		if name == defaultIssuerName {
+			return nil, errutil.UserError{Err: "backend must be configured with a CA certificate/key"}
+		}
+		return nil, errutil.UserError{Err: fmt.Sprintf("unknown issuer %s", name)}
+	}
+
+	parsedBundle, err := bundle.ToParsedCertBundle()
+	if err != nil {
+		return nil, errutil.InternalError{Err: err.Error()}
+	}
+
+	if parsedBundle.Certificate == nil {
+		return nil, errutil.InternalError{Err: "stored CA information not able to be parsed"}
+	}
+
+	caInfo := &caInfoBundle{*parsedBundle, nil}
+
+	entries, err := getURLs(req)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch URL information: %v", err)}
+	}
+	if entries == nil {
+		entries = &urlEntries{
+			IssuingCertificates:   []string{},
+			CRLDistributionPoints: []string{},
+			OCSPServers:           []string{},
+		}
+	}
+	caInfo.URLs = entries
+
+	return caInfo, nil
+}
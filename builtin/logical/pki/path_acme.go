@@ -0,0 +1,1286 @@
+package pki
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// How long nonces can be used for after being issued
+	acmeNonceLifetime = time.Hour
+
+	// How many used nonces are remembered at most, to reject them if they
+	// are replayed
+	acmeMaxUsedNonces = 10000
+
+	acmeNonceRandomSize = 8
+	acmeNonceMACSize    = 16
+
+	// How long orders and their authorizations can be fulfilled for
+	acmeOrderLifetime = 24 * time.Hour
+
+	acmeStatusPending     = "pending"
+	acmeStatusReady       = "ready"
+	acmeStatusValid       = "valid"
+	acmeStatusInvalid     = "invalid"
+	acmeStatusDeactivated = "deactivated"
+)
+
+// acmeProblem is an ACME error, returned to clients as a problem document
+// (RFC 7807)
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status,omitempty"`
+}
+
+func (p *acmeProblem) Error() string {
+	return p.Detail
+}
+
+func acmeError(errorType string, status int, format string, args ...interface{}) *acmeProblem {
+	return &acmeProblem{
+		Type:   "urn:ietf:params:acme:error:" + errorType,
+		Detail: fmt.Sprintf(format, args...),
+		Status: status,
+	}
+}
+
+func acmeMalformed(format string, args ...interface{}) *acmeProblem {
+	return acmeError("malformed", 400, format, args...)
+}
+
+type acmeAccount struct {
+	ID        string    `json:"id"`
+	Key       *acmeJWK  `json:"key"`
+	Contact   []string  `json:"contact"`
+	Status    string    `json:"status"`
+	OrderIDs  []string  `json:"order_ids"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type acmeOrder struct {
+	ID               string           `json:"id"`
+	AccountID        string           `json:"account_id"`
+	Status           string           `json:"status"`
+	Expires          time.Time        `json:"expires"`
+	Identifiers      []acmeIdentifier `json:"identifiers"`
+	AuthorizationIDs []string         `json:"authorization_ids"`
+	SerialNumber     string           `json:"serial_number"`
+
+	// The PEM encoded certificate chain, once issued
+	Certificate string `json:"certificate"`
+}
+
+type acmeAuthorization struct {
+	ID         string           `json:"id"`
+	AccountID  string           `json:"account_id"`
+	Status     string           `json:"status"`
+	Expires    time.Time        `json:"expires"`
+	Identifier acmeIdentifier   `json:"identifier"`
+	Wildcard   bool             `json:"wildcard"`
+	Challenges []*acmeChallenge `json:"challenges"`
+}
+
+type acmeChallenge struct {
+	Type      string       `json:"type"`
+	Token     string       `json:"token"`
+	Status    string       `json:"status"`
+	Validated time.Time    `json:"validated"`
+	Error     *acmeProblem `json:"error"`
+}
+
+// acmeRequest is a verified ACME request
+type acmeRequest struct {
+	config *acmeConfig
+
+	// The account the request was signed by, nil for new accounts
+	account *acmeAccount
+
+	// The key the request was signed with
+	key *acmeJWK
+
+	payload []byte
+}
+
+// acmeResponse is the response of an ACME handler
+type acmeResponse struct {
+	status   int
+	body     interface{}
+	location string
+	links    []string
+
+	// The content type of the body if it is raw bytes, which otherwise is
+	// encoded as JSON
+	contentType string
+}
+
+// acmeHandler handles verified ACME requests, returning errors of type
+// *acmeProblem for errors to report to the client
+type acmeHandler func(*logical.Request, *framework.FieldData, *acmeRequest) (*acmeResponse, error)
+
+func pathACMEDirectory(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/directory",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathACMEDirectoryRead,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewNonce(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/new-nonce",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathACMENewNonceRead,
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewAccount(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/new-account",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(true, b.pathACMENewAccount),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEAccount(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/account/" + framework.GenericNameRegex("id"),
+		Fields: map[string]*framework.FieldSchema{
+			"id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The ID of the account",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(false, b.pathACMEAccount),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEAccountOrders(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/account/" + framework.GenericNameRegex("id") + "/orders",
+		Fields: map[string]*framework.FieldSchema{
+			"id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The ID of the account",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(false, b.pathACMEAccountOrders),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMENewOrder(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/new-order",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(false, b.pathACMENewOrder),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEOrder(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/order/" + framework.GenericNameRegex("id"),
+		Fields: map[string]*framework.FieldSchema{
+			"id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The ID of the order",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(false, b.pathACMEOrder),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEOrderFinalize(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/order/" + framework.GenericNameRegex("id") + "/finalize",
+		Fields: map[string]*framework.FieldSchema{
+			"id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The ID of the order",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(false, b.pathACMEOrderFinalize),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEAuthorization(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/authz/" + framework.GenericNameRegex("id"),
+		Fields: map[string]*framework.FieldSchema{
+			"id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The ID of the authorization",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(false, b.pathACMEAuthorization),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMEChallenge(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/challenge/" + framework.GenericNameRegex("id") + "/(?P<type>http-01|dns-01)",
+		Fields: map[string]*framework.FieldSchema{
+			"id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The ID of the authorization",
+			},
+
+			"type": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The type of the challenge",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(false, b.pathACMEChallenge),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func pathACMECertificate(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "acme/cert/" + framework.GenericNameRegex("id"),
+		Fields: map[string]*framework.FieldSchema{
+			"id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The ID of the order the certificate was issued for",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.acmeWrap(false, b.pathACMECertificate),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+func (b *backend) pathACMEDirectoryRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.enabledACMEConfig(req.Storage)
+	if err != nil {
+		return b.acmeRawResponse(nil, nil, err)
+	}
+
+	return b.acmeRawResponse(config, &acmeResponse{
+		status: 200,
+		body: map[string]interface{}{
+			"newNonce":   config.BaseURL + "/acme/new-nonce",
+			"newAccount": config.BaseURL + "/acme/new-account",
+			"newOrder":   config.BaseURL + "/acme/new-order",
+			"meta": map[string]interface{}{
+				"externalAccountRequired": false,
+			},
+		},
+	}, nil)
+}
+
+func (b *backend) pathACMENewNonceRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.enabledACMEConfig(req.Storage)
+	if err != nil {
+		return b.acmeRawResponse(nil, nil, err)
+	}
+
+	return b.acmeRawResponse(config, &acmeResponse{
+		status: 204,
+	}, nil)
+}
+
+func (b *backend) pathACMENewAccount(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	var payload struct {
+		Contact              []string `json:"contact"`
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		OnlyReturnExisting   bool     `json:"onlyReturnExisting"`
+	}
+	if err := json.Unmarshal(acmeReq.payload, &payload); err != nil {
+		return nil, acmeMalformed("error decoding payload: %s", err)
+	}
+
+	// Accounts are identified by the thumbprint of their key
+	id, err := acmeReq.key.thumbprint()
+	if err != nil {
+		return nil, acmeMalformed("%s", err)
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	account, err := b.acmeAccount(req.Storage, id)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		return &acmeResponse{
+			status:   200,
+			body:     acmeAccountBody(acmeReq.config, account),
+			location: acmeAccountURL(acmeReq.config, account.ID),
+		}, nil
+	}
+	if payload.OnlyReturnExisting {
+		return nil, acmeError("accountDoesNotExist", 400, "no account exists with the given key")
+	}
+
+	if err := validateACMEContact(payload.Contact); err != nil {
+		return nil, err
+	}
+
+	account = &acmeAccount{
+		ID:        id,
+		Key:       acmeReq.key,
+		Contact:   payload.Contact,
+		Status:    acmeStatusValid,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := putACMEEntry(req.Storage, "acme/accounts/"+id, account); err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status:   201,
+		body:     acmeAccountBody(acmeReq.config, account),
+		location: acmeAccountURL(acmeReq.config, account.ID),
+	}, nil
+}
+
+func (b *backend) pathACMEAccount(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	if data.Get("id").(string) != acmeReq.account.ID {
+		return nil, acmeError("unauthorized", 403, "the request was not signed by the account")
+	}
+
+	var payload struct {
+		Contact []string `json:"contact"`
+		Status  string   `json:"status"`
+	}
+	// Requests without a payload only fetch the account
+	if len(acmeReq.payload) > 0 {
+		if err := json.Unmarshal(acmeReq.payload, &payload); err != nil {
+			return nil, acmeMalformed("error decoding payload: %s", err)
+		}
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	account, err := b.acmeAccount(req.Storage, acmeReq.account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	modified := false
+	if payload.Contact != nil {
+		if err := validateACMEContact(payload.Contact); err != nil {
+			return nil, err
+		}
+		account.Contact = payload.Contact
+		modified = true
+	}
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		account.Status = acmeStatusDeactivated
+		modified = true
+	default:
+		return nil, acmeMalformed("the status of an account can only be set to %q", acmeStatusDeactivated)
+	}
+
+	if modified {
+		if err := putACMEEntry(req.Storage, "acme/accounts/"+account.ID, account); err != nil {
+			return nil, err
+		}
+	}
+
+	return &acmeResponse{
+		status: 200,
+		body:   acmeAccountBody(acmeReq.config, account),
+	}, nil
+}
+
+func (b *backend) pathACMEAccountOrders(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	if data.Get("id").(string) != acmeReq.account.ID {
+		return nil, acmeError("unauthorized", 403, "the request was not signed by the account")
+	}
+
+	orders := []string{}
+	for _, id := range acmeReq.account.OrderIDs {
+		orders = append(orders, acmeReq.config.BaseURL+"/acme/order/"+id)
+	}
+
+	return &acmeResponse{
+		status: 200,
+		body: map[string]interface{}{
+			"orders": orders,
+		},
+	}, nil
+}
+
+func (b *backend) pathACMENewOrder(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	var payload struct {
+		Identifiers []acmeIdentifier `json:"identifiers"`
+		NotBefore   string           `json:"notBefore"`
+		NotAfter    string           `json:"notAfter"`
+	}
+	if err := json.Unmarshal(acmeReq.payload, &payload); err != nil {
+		return nil, acmeMalformed("error decoding payload: %s", err)
+	}
+	if payload.NotBefore != "" || payload.NotAfter != "" {
+		return nil, acmeMalformed("notBefore and notAfter are not supported, the validity is set by the role")
+	}
+	if len(payload.Identifiers) == 0 {
+		return nil, acmeMalformed("no identifiers given")
+	}
+
+	role, err := b.getRole(req.Storage, acmeReq.config.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("the role configured for ACME does not exist: %s", acmeReq.config.Role)
+	}
+
+	// Only DNS names are supported, and they must be allowed by the role
+	names := []string{}
+	for _, identifier := range payload.Identifiers {
+		if identifier.Type != "dns" {
+			return nil, acmeError("unsupportedIdentifier", 400, "unsupported identifier type %q", identifier.Type)
+		}
+		names = append(names, strings.ToLower(identifier.Value))
+	}
+	names = strutil.RemoveDuplicates(names)
+	if badName := validateNames(req, names, role); badName != "" {
+		return nil, acmeError("rejectedIdentifier", 400, "name %s not allowed by the role", badName)
+	}
+
+	now := time.Now().UTC()
+	order := &acmeOrder{
+		AccountID: acmeReq.account.ID,
+		Status:    acmeStatusPending,
+		Expires:   now.Add(acmeOrderLifetime),
+	}
+	order.ID, err = uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		order.Identifiers = append(order.Identifiers, acmeIdentifier{
+			Type:  "dns",
+			Value: name,
+		})
+
+		authz, err := newACMEAuthorization(acmeReq.account.ID, name, order.Expires)
+		if err != nil {
+			return nil, err
+		}
+		if err := putACMEEntry(req.Storage, "acme/authorizations/"+authz.ID, authz); err != nil {
+			return nil, err
+		}
+		order.AuthorizationIDs = append(order.AuthorizationIDs, authz.ID)
+	}
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	if err := putACMEEntry(req.Storage, "acme/orders/"+order.ID, order); err != nil {
+		return nil, err
+	}
+
+	// Keep track of the orders of the account to list them
+	account, err := b.acmeAccount(req.Storage, acmeReq.account.ID)
+	if err != nil {
+		return nil, err
+	}
+	account.OrderIDs = append(account.OrderIDs, order.ID)
+	if err := putACMEEntry(req.Storage, "acme/accounts/"+account.ID, account); err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status:   201,
+		body:     acmeOrderBody(acmeReq.config, order),
+		location: acmeReq.config.BaseURL + "/acme/order/" + order.ID,
+	}, nil
+}
+
+func (b *backend) pathACMEOrder(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	order, err := b.acmeAccountOrder(req.Storage, acmeReq.account, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status: 200,
+		body:   acmeOrderBody(acmeReq.config, order),
+	}, nil
+}
+
+func (b *backend) pathACMEOrderFinalize(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := json.Unmarshal(acmeReq.payload, &payload); err != nil {
+		return nil, acmeMalformed("error decoding payload: %s", err)
+	}
+
+	csrBytes, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		return nil, acmeError("badCSR", 400, "error decoding CSR: %s", err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrBytes)
+	if err != nil {
+		return nil, acmeError("badCSR", 400, "error parsing CSR: %s", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, acmeError("badCSR", 400, "invalid CSR signature: %s", err)
+	}
+
+	// Only one certificate is issued per order
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	order, err := b.acmeAccountOrder(req.Storage, acmeReq.account, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusReady {
+		return nil, acmeError("orderNotReady", 403, "the order is %s", order.Status)
+	}
+
+	// The CSR must request exactly the names of the order
+	if len(csr.IPAddresses) > 0 || len(csr.EmailAddresses) > 0 {
+		return nil, acmeError("badCSR", 400, "the CSR can only contain DNS names")
+	}
+	csrNames := append([]string{}, csr.DNSNames...)
+	if csr.Subject.CommonName != "" {
+		csrNames = append(csrNames, csr.Subject.CommonName)
+	}
+	csrNames = strutil.RemoveDuplicates(csrNames)
+	orderNames := []string{}
+	for _, identifier := range order.Identifiers {
+		orderNames = append(orderNames, identifier.Value)
+	}
+	if !strutil.EquivalentSlices(csrNames, orderNames) {
+		return nil, acmeError("badCSR", 400, "the names of the CSR do not match the identifiers of the order")
+	}
+
+	role, err := b.getRole(req.Storage, acmeReq.config.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("the role configured for ACME does not exist: %s", acmeReq.config.Role)
+	}
+
+	signingBundle, err := fetchIssuerInfo(req, role.IssuerRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign the CSR as the sign path would, with the names of the order
+	commonName := csr.Subject.CommonName
+	if commonName == "" {
+		commonName = orderNames[0]
+	}
+	signData := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"csr": string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE REQUEST",
+				Bytes: csrBytes,
+			})),
+			"common_name": commonName,
+			"alt_names":   strings.Join(orderNames, ","),
+		},
+		Schema: pathSign(b).Fields,
+	}
+	parsedBundle, err := signCert(b, role, signingBundle, false, false, req, signData)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, acmeError("badCSR", 400, "%s", err)
+		default:
+			return nil, err
+		}
+	}
+
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("Error converting raw cert bundle to cert bundle: %s", err)
+	}
+	err = req.Storage.Put(&logical.StorageEntry{
+		Key:   "certs/" + cb.SerialNumber,
+		Value: parsedBundle.CertificateBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to store certificate locally: %v", err)
+	}
+
+	chain := []string{cb.Certificate}
+	chain = append(chain, cb.CAChain...)
+	order.Status = acmeStatusValid
+	order.SerialNumber = cb.SerialNumber
+	order.Certificate = strings.Join(chain, "\n") + "\n"
+	if err := putACMEEntry(req.Storage, "acme/orders/"+order.ID, order); err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status:   200,
+		body:     acmeOrderBody(acmeReq.config, order),
+		location: acmeReq.config.BaseURL + "/acme/order/" + order.ID,
+	}, nil
+}
+
+func (b *backend) pathACMEAuthorization(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	authz, err := b.acmeAccountAuthorization(req.Storage, acmeReq.account, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return &acmeResponse{
+		status: 200,
+		body:   acmeAuthorizationBody(acmeReq.config, authz),
+	}, nil
+}
+
+func (b *backend) pathACMEChallenge(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	authzID := data.Get("id").(string)
+	challengeType := data.Get("type").(string)
+
+	authz, err := b.acmeAccountAuthorization(req.Storage, acmeReq.account, authzID)
+	if err != nil {
+		return nil, err
+	}
+	challenge := authz.challenge(challengeType)
+	if challenge == nil {
+		return nil, acmeMalformed("no %s challenge for the authorization", challengeType)
+	}
+	links := []string{fmt.Sprintf(`<%s/acme/authz/%s>;rel="up"`, acmeReq.config.BaseURL, authz.ID)}
+
+	// Challenges are only validated once, further requests return their
+	// result
+	if authz.Status != acmeStatusPending || challenge.Status != acmeStatusPending {
+		return &acmeResponse{
+			status: 200,
+			body:   acmeChallengeBody(acmeReq.config, authz, challenge),
+			links:  links,
+		}, nil
+	}
+
+	thumbprint, err := acmeReq.account.Key.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	keyAuthorization := challenge.Token + "." + thumbprint
+
+	// The validation is not done under the lock as it reaches out to the
+	// domain; the authorization is loaded again to record the result
+	problem := b.validateACMEChallenge(challengeType, authz.Identifier.Value, keyAuthorization)
+
+	b.acmeLock.Lock()
+	defer b.acmeLock.Unlock()
+
+	authz, err = b.acmeAccountAuthorization(req.Storage, acmeReq.account, authzID)
+	if err != nil {
+		return nil, err
+	}
+	challenge = authz.challenge(challengeType)
+	if authz.Status == acmeStatusPending && challenge.Status == acmeStatusPending {
+		if problem != nil {
+			challenge.Status = acmeStatusInvalid
+			challenge.Error = problem
+			authz.Status = acmeStatusInvalid
+		} else {
+			challenge.Status = acmeStatusValid
+			challenge.Validated = time.Now().UTC()
+			authz.Status = acmeStatusValid
+		}
+		if err := putACMEEntry(req.Storage, "acme/authorizations/"+authz.ID, authz); err != nil {
+			return nil, err
+		}
+	}
+
+	return &acmeResponse{
+		status: 200,
+		body:   acmeChallengeBody(acmeReq.config, authz, challenge),
+		links:  links,
+	}, nil
+}
+
+func (b *backend) pathACMECertificate(
+	req *logical.Request, data *framework.FieldData, acmeReq *acmeRequest) (*acmeResponse, error) {
+	order, err := b.acmeAccountOrder(req.Storage, acmeReq.account, data.Get("id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if order.Certificate == "" {
+		return nil, acmeMalformed("no certificate was issued for the order")
+	}
+
+	return &acmeResponse{
+		status:      200,
+		body:        []byte(order.Certificate),
+		contentType: "application/pem-certificate-chain",
+	}, nil
+}
+
+// acmeWrap verifies ACME requests before passing them to the handler: the
+// request must be a JWS for the URL of the path, with a valid nonce, signed
+// by an existing account, or by the key given in the request for new
+// accounts
+func (b *backend) acmeWrap(newAccount bool, handler acmeHandler) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		config, err := b.enabledACMEConfig(req.Storage)
+		if err != nil {
+			return b.acmeRawResponse(nil, nil, err)
+		}
+
+		acmeReq, err := b.verifyACMERequest(req, config, newAccount)
+		if err != nil {
+			return b.acmeRawResponse(config, nil, err)
+		}
+
+		resp, err := handler(req, data, acmeReq)
+		return b.acmeRawResponse(config, resp, err)
+	}
+}
+
+func (b *backend) verifyACMERequest(req *logical.Request, config *acmeConfig, newAccount bool) (*acmeRequest, error) {
+	if len(req.RawBody) == 0 {
+		return nil, acmeMalformed("requests must have a JWS body")
+	}
+
+	jws, header, err := parseACMEJWS(req.RawBody)
+	if err != nil {
+		return nil, acmeMalformed("%s", err)
+	}
+
+	if header.URL != config.BaseURL+"/"+req.Path {
+		return nil, acmeError("unauthorized", 403, "the url of the request does not match")
+	}
+	if !b.useACMENonce(header.Nonce) {
+		return nil, acmeError("badNonce", 400, "invalid nonce")
+	}
+
+	acmeReq := &acmeRequest{
+		config: config,
+	}
+	switch {
+	case newAccount && header.JWK == nil:
+		return nil, acmeMalformed("new accounts must be requested with a jwk")
+	case newAccount:
+		acmeReq.key = header.JWK
+	case header.JWK != nil:
+		return nil, acmeMalformed("requests must be signed by an account with a kid")
+	default:
+		accountURL := acmeAccountURL(config, "")
+		if !strings.HasPrefix(header.KID, accountURL) {
+			return nil, acmeError("accountDoesNotExist", 400, "unknown account %s", header.KID)
+		}
+		account, err := b.acmeAccount(req.Storage, strings.TrimPrefix(header.KID, accountURL))
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			return nil, acmeError("accountDoesNotExist", 400, "unknown account %s", header.KID)
+		}
+		if account.Status != acmeStatusValid {
+			return nil, acmeError("unauthorized", 403, "the account is %s", account.Status)
+		}
+		acmeReq.account = account
+		acmeReq.key = account.Key
+	}
+
+	key, err := acmeReq.key.publicKey()
+	if err != nil {
+		return nil, acmeError("badPublicKey", 400, "%s", err)
+	}
+	acmeReq.payload, err = jws.verify(header.Alg, key)
+	if err != nil {
+		return nil, acmeMalformed("%s", err)
+	}
+
+	return acmeReq, nil
+}
+
+// acmeRawResponse builds the raw HTTP response of an ACME request, or the
+// problem document of the error. All responses carry a fresh nonce.
+func (b *backend) acmeRawResponse(config *acmeConfig, resp *acmeResponse, err error) (*logical.Response, error) {
+	if err != nil {
+		problem, ok := err.(*acmeProblem)
+		if !ok {
+			if b.Logger().IsWarn() {
+				b.Logger().Warn("pki: error handling ACME request", "error", err)
+			}
+			problem = acmeError("serverInternal", 500, "internal error handling the request")
+		}
+		resp = &acmeResponse{
+			status:      problem.Status,
+			body:        problem,
+			contentType: "application/problem+json",
+		}
+	}
+
+	headers := map[string][]string{
+		"Cache-Control": []string{"no-store"},
+	}
+
+	nonce, err := b.newACMENonce()
+	if err != nil {
+		return nil, err
+	}
+	headers["Replay-Nonce"] = []string{nonce}
+
+	if config != nil {
+		headers["Link"] = append(resp.links, fmt.Sprintf(`<%s/acme/directory>;rel="index"`, config.BaseURL))
+	}
+	if resp.location != "" {
+		headers["Location"] = []string{resp.location}
+	}
+
+	data := map[string]interface{}{
+		logical.HTTPStatusCode: resp.status,
+		logical.HTTPHeaders:    headers,
+	}
+	if resp.body != nil {
+		body, ok := resp.body.([]byte)
+		if !ok {
+			body, err = json.Marshal(resp.body)
+			if err != nil {
+				return nil, err
+			}
+		}
+		contentType := resp.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		data[logical.HTTPRawBody] = body
+		data[logical.HTTPContentType] = contentType
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// enabledACMEConfig returns the ACME configuration, or a problem if ACME is
+// not enabled
+func (b *backend) enabledACMEConfig(s logical.Storage) (*acmeConfig, error) {
+	config, err := b.ACMEConfig(s)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || !config.Enabled {
+		return nil, acmeError("unauthorized", 403, "ACME is not enabled on this mount")
+	}
+	return config, nil
+}
+
+// Nonces are the time they were issued at and random bytes, authenticated
+// with a key of the backend, so that issuing them takes no memory. Only the
+// nonces that have been used are remembered until they expire.
+func (b *backend) newACMENonce() (string, error) {
+	b.acmeNonceLock.Lock()
+	if b.acmeNonceKey == nil {
+		key, err := uuid.GenerateRandomBytes(32)
+		if err != nil {
+			b.acmeNonceLock.Unlock()
+			return "", err
+		}
+		b.acmeNonceKey = key
+	}
+	key := b.acmeNonceKey
+	b.acmeNonceLock.Unlock()
+
+	raw, err := uuid.GenerateRandomBytes(acmeNonceRandomSize)
+	if err != nil {
+		return "", err
+	}
+
+	return acmeNonceAt(key, time.Now(), raw), nil
+}
+
+func acmeNonceAt(key []byte, issued time.Time, random []byte) string {
+	msg := make([]byte, 8, 8+len(random)+acmeNonceMACSize)
+	binary.BigEndian.PutUint64(msg, uint64(issued.UnixNano()))
+	msg = append(msg, random...)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(msg)[:len(msg)+acmeNonceMACSize])
+}
+
+// useACMENonce returns whether the nonce was issued by this backend, has not
+// expired and was not used yet, and marks it as used
+func (b *backend) useACMENonce(nonce string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(raw) != 8+acmeNonceRandomSize+acmeNonceMACSize {
+		return false
+	}
+	msg := raw[:8+acmeNonceRandomSize]
+
+	b.acmeNonceLock.Lock()
+	defer b.acmeNonceLock.Unlock()
+
+	if b.acmeNonceKey == nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, b.acmeNonceKey)
+	mac.Write(msg)
+	if !hmac.Equal(mac.Sum(nil)[:acmeNonceMACSize], raw[len(msg):]) {
+		return false
+	}
+
+	now := time.Now()
+	issued := time.Unix(0, int64(binary.BigEndian.Uint64(msg)))
+	if issued.After(now) || now.Sub(issued) > acmeNonceLifetime {
+		return false
+	}
+	if !issued.After(b.acmeNonceFloor) {
+		return false
+	}
+	if _, ok := b.acmeUsedNonces[nonce]; ok {
+		return false
+	}
+
+	// Forget the oldest used nonces once they have expired, or when too many
+	// are remembered. Nonces issued no later than a forgotten one that has
+	// not expired are rejected from then on, so that it cannot be replayed.
+	for len(b.acmeUsedNonceQueue) > 0 {
+		oldest := b.acmeUsedNonceQueue[0]
+		oldestIssued := b.acmeUsedNonces[oldest]
+		expired := now.Sub(oldestIssued) > acmeNonceLifetime
+		if !expired && len(b.acmeUsedNonceQueue) < acmeMaxUsedNonces {
+			break
+		}
+		if !expired && oldestIssued.After(b.acmeNonceFloor) {
+			b.acmeNonceFloor = oldestIssued
+		}
+		delete(b.acmeUsedNonces, oldest)
+		b.acmeUsedNonceQueue = b.acmeUsedNonceQueue[1:]
+	}
+
+	// The nonce may be older than the floor that was just raised
+	if !issued.After(b.acmeNonceFloor) {
+		return false
+	}
+	b.acmeUsedNonces[nonce] = issued
+	b.acmeUsedNonceQueue = append(b.acmeUsedNonceQueue, nonce)
+
+	return true
+}
+
+func (b *backend) acmeAccount(s logical.Storage, id string) (*acmeAccount, error) {
+	entry, err := s.Get("acme/accounts/" + id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var account acmeAccount
+	if err := entry.DecodeJSON(&account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// acmeAccountOrder returns the order with the given ID if it belongs to the
+// account, with its status updated from its authorizations
+func (b *backend) acmeAccountOrder(s logical.Storage, account *acmeAccount, id string) (*acmeOrder, error) {
+	entry, err := s.Get("acme/orders/" + id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, acmeError("malformed", 404, "order not found")
+	}
+
+	var order acmeOrder
+	if err := entry.DecodeJSON(&order); err != nil {
+		return nil, err
+	}
+	if order.AccountID != account.ID {
+		return nil, acmeError("unauthorized", 403, "the order belongs to another account")
+	}
+
+	// The order is ready once all of its authorizations are valid, and
+	// invalid as soon as one of them is
+	if order.Status == acmeStatusPending || order.Status == acmeStatusReady {
+		order.Status = acmeStatusReady
+		for _, authzID := range order.AuthorizationIDs {
+			authz, err := b.acmeAccountAuthorization(s, account, authzID)
+			if err != nil {
+				return nil, err
+			}
+			switch authz.Status {
+			case acmeStatusInvalid:
+				order.Status = acmeStatusInvalid
+			case acmeStatusPending:
+				if order.Status == acmeStatusReady {
+					order.Status = acmeStatusPending
+				}
+			}
+		}
+		if time.Now().After(order.Expires) {
+			order.Status = acmeStatusInvalid
+		}
+	}
+
+	return &order, nil
+}
+
+// acmeAccountAuthorization returns the authorization with the given ID if it
+// belongs to the account
+func (b *backend) acmeAccountAuthorization(s logical.Storage, account *acmeAccount, id string) (*acmeAuthorization, error) {
+	entry, err := s.Get("acme/authorizations/" + id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, acmeError("malformed", 404, "authorization not found")
+	}
+
+	var authz acmeAuthorization
+	if err := entry.DecodeJSON(&authz); err != nil {
+		return nil, err
+	}
+	if authz.AccountID != account.ID {
+		return nil, acmeError("unauthorized", 403, "the authorization belongs to another account")
+	}
+	if authz.Status == acmeStatusPending && time.Now().After(authz.Expires) {
+		authz.Status = "expired"
+	}
+
+	return &authz, nil
+}
+
+func newACMEAuthorization(accountID, name string, expires time.Time) (*acmeAuthorization, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	authz := &acmeAuthorization{
+		ID:        id,
+		AccountID: accountID,
+		Status:    acmeStatusPending,
+		Expires:   expires,
+		Identifier: acmeIdentifier{
+			Type:  "dns",
+			Value: strings.TrimPrefix(name, "*."),
+		},
+		Wildcard: strings.HasPrefix(name, "*."),
+	}
+
+	// Wildcard names can only be validated with DNS
+	challengeTypes := []string{acmeChallengeHTTP01, acmeChallengeDNS01}
+	if authz.Wildcard {
+		challengeTypes = []string{acmeChallengeDNS01}
+	}
+	for _, challengeType := range challengeTypes {
+		token, err := uuid.GenerateRandomBytes(32)
+		if err != nil {
+			return nil, err
+		}
+		authz.Challenges = append(authz.Challenges, &acmeChallenge{
+			Type:   challengeType,
+			Token:  base64.RawURLEncoding.EncodeToString(token),
+			Status: acmeStatusPending,
+		})
+	}
+
+	return authz, nil
+}
+
+func (a *acmeAuthorization) challenge(challengeType string) *acmeChallenge {
+	for _, challenge := range a.Challenges {
+		if challenge.Type == challengeType {
+			return challenge
+		}
+	}
+	return nil
+}
+
+func putACMEEntry(s logical.Storage, key string, value interface{}) error {
+	entry, err := logical.StorageEntryJSON(key, value)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+// validateACMEContact checks that contacts are email addresses, the only
+// kind of contact defined by RFC 8555
+func validateACMEContact(contact []string) error {
+	for _, c := range contact {
+		if !strings.HasPrefix(c, "mailto:") {
+			return acmeError("unsupportedContact", 400, "unsupported contact %s", c)
+		}
+	}
+	return nil
+}
+
+func acmeAccountURL(config *acmeConfig, id string) string {
+	return config.BaseURL + "/acme/account/" + id
+}
+
+func acmeAccountBody(config *acmeConfig, account *acmeAccount) map[string]interface{} {
+	contact := account.Contact
+	if contact == nil {
+		contact = []string{}
+	}
+	return map[string]interface{}{
+		"status":  account.Status,
+		"contact": contact,
+		"orders":  acmeAccountURL(config, account.ID) + "/orders",
+	}
+}
+
+func acmeOrderBody(config *acmeConfig, order *acmeOrder) map[string]interface{} {
+	authorizations := []string{}
+	for _, id := range order.AuthorizationIDs {
+		authorizations = append(authorizations, config.BaseURL+"/acme/authz/"+id)
+	}
+
+	body := map[string]interface{}{
+		"status":         order.Status,
+		"expires":        order.Expires.Format(time.RFC3339),
+		"identifiers":    order.Identifiers,
+		"authorizations": authorizations,
+		"finalize":       config.BaseURL + "/acme/order/" + order.ID + "/finalize",
+	}
+	if order.Certificate != "" {
+		body["certificate"] = config.BaseURL + "/acme/cert/" + order.ID
+	}
+	return body
+}
+
+func acmeAuthorizationBody(config *acmeConfig, authz *acmeAuthorization) map[string]interface{} {
+	challenges := []map[string]interface{}{}
+	for _, challenge := range authz.Challenges {
+		challenges = append(challenges, acmeChallengeBody(config, authz, challenge))
+	}
+
+	body := map[string]interface{}{
+		"status":     authz.Status,
+		"expires":    authz.Expires.Format(time.RFC3339),
+		"identifier": authz.Identifier,
+		"challenges": challenges,
+	}
+	if authz.Wildcard {
+		body["wildcard"] = true
+	}
+	return body
+}
+
+func acmeChallengeBody(config *acmeConfig, authz *acmeAuthorization, challenge *acmeChallenge) map[string]interface{} {
+	body := map[string]interface{}{
+		"type":   challenge.Type,
+		"url":    config.BaseURL + "/acme/challenge/" + authz.ID + "/" + challenge.Type,
+		"status": challenge.Status,
+		"token":  challenge.Token,
+	}
+	if !challenge.Validated.IsZero() {
+		body["validated"] = challenge.Validated.Format(time.RFC3339)
+	}
+	if challenge.Error != nil {
+		body["error"] = challenge.Error
+	}
+	return body
+}
+
+const pathACMEHelpSyn = `
+ACME (RFC 8555) server issuing certificates with the configured role.
+`
+
+const pathACMEHelpDesc = `
+These unauthenticated endpoints implement an ACME server, for use by ACME
+clients such as certbot rather than directly. The directory of the server is
+served at "acme/directory".
+
+ACME accounts are identified by their key, and issue certificates with the role
+configured at "config/acme", for the DNS names allowed by the role that they
+proved control of with an http-01 or a dns-01 challenge.
+`
@@ -0,0 +1,512 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+const acmeTestBaseURL = "https://vault.example.com/v1/pki"
+
+// acmeTestClient is a minimal ACME client sending requests to the backend
+type acmeTestClient struct {
+	t       *testing.T
+	b       *backend
+	storage logical.Storage
+	key     *ecdsa.PrivateKey
+	kid     string
+	nonce   string
+}
+
+type acmeTestResponse struct {
+	status  int
+	headers map[string][]string
+	body    []byte
+}
+
+func (r *acmeTestResponse) decode(t *testing.T) map[string]interface{} {
+	var result map[string]interface{}
+	if err := json.Unmarshal(r.body, &result); err != nil {
+		t.Fatalf("err: %v\nbody: %s", err, r.body)
+	}
+	return result
+}
+
+// problemType returns the type of the problem document of the response
+func (r *acmeTestResponse) problemType(t *testing.T) string {
+	problemType, _ := r.decode(t)["type"].(string)
+	return strings.TrimPrefix(problemType, "urn:ietf:params:acme:error:")
+}
+
+func (c *acmeTestClient) request(operation logical.Operation, path string, rawBody []byte) *acmeTestResponse {
+	resp, err := c.b.HandleRequest(&logical.Request{
+		Operation: operation,
+		Path:      path,
+		Storage:   c.storage,
+		RawBody:   rawBody,
+	})
+	if err != nil || resp == nil {
+		c.t.Fatalf("bad: path %s\nerr: %v\nresp: %#v", path, err, resp)
+	}
+
+	headers := resp.Data[logical.HTTPHeaders].(map[string][]string)
+	if nonce := headers["Replay-Nonce"]; len(nonce) == 1 {
+		c.nonce = nonce[0]
+	}
+	body, _ := resp.Data[logical.HTTPRawBody].([]byte)
+	return &acmeTestResponse{
+		status:  resp.Data[logical.HTTPStatusCode].(int),
+		headers: headers,
+		body:    body,
+	}
+}
+
+func (c *acmeTestClient) jwk() map[string]string {
+	size := (c.key.Curve.Params().BitSize + 7) / 8
+	return map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(padBytes(c.key.X.Bytes(), size)),
+		"y":   base64.RawURLEncoding.EncodeToString(padBytes(c.key.Y.Bytes(), size)),
+	}
+}
+
+// postURL sends a JWS signed by the client to the path, for the given URL.
+// A nil payload sends a POST-as-GET request.
+func (c *acmeTestClient) postURL(path, url string, payload interface{}) *acmeTestResponse {
+	header := map[string]interface{}{
+		"alg":   "ES256",
+		"nonce": c.nonce,
+		"url":   url,
+	}
+	if c.kid != "" {
+		header["kid"] = c.kid
+	} else {
+		header["jwk"] = c.jwk()
+	}
+	protected, err := json.Marshal(header)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	var rawPayload []byte
+	if payload != nil {
+		rawPayload, err = json.Marshal(payload)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	jws := map[string]string{
+		"protected": base64.RawURLEncoding.EncodeToString(protected),
+		"payload":   base64.RawURLEncoding.EncodeToString(rawPayload),
+	}
+	digest := sha256.Sum256([]byte(jws["protected"] + "." + jws["payload"]))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	jws["signature"] = base64.RawURLEncoding.EncodeToString(append(padBytes(r.Bytes(), 32), padBytes(s.Bytes(), 32)...))
+
+	body, err := json.Marshal(jws)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.request(logical.UpdateOperation, path, body)
+}
+
+func (c *acmeTestClient) post(path string, payload interface{}) *acmeTestResponse {
+	return c.postURL(path, acmeTestBaseURL+"/"+path, payload)
+}
+
+// acmeTestPath returns the path of the backend an ACME URL points to
+func acmeTestPath(t *testing.T, url interface{}) string {
+	s, ok := url.(string)
+	if !ok || !strings.HasPrefix(s, acmeTestBaseURL+"/") {
+		t.Fatalf("bad: url %#v", url)
+	}
+	return strings.TrimPrefix(s, acmeTestBaseURL+"/")
+}
+
+func padBytes(b []byte, size int) []byte {
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// acmeTestTransport serves http-01 challenge responses
+type acmeTestTransport map[string]string
+
+func (t acmeTestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := t[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestBackend_ACME(t *testing.T) {
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+
+	b := Backend()
+	_, err := b.Setup(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Challenges are validated offline
+	httpResponses := acmeTestTransport{}
+	txtRecords := map[string][]string{}
+	b.acmeHTTPClient = &http.Client{Transport: httpResponses}
+	b.acmeLookupTXT = func(name string) ([]string, error) {
+		return txtRecords[name], nil
+	}
+
+	request := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path %s\nerr: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	resp := request("root/generate/internal", map[string]interface{}{
+		"common_name": "example.com",
+		"ttl":         "6h",
+	})
+	block, _ := pem.Decode([]byte(resp.Data["certificate"].(string)))
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request("roles/acme", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"max_ttl":          "4h",
+		"key_type":         "ec",
+		"key_bits":         256,
+	})
+
+	newClient := func() *acmeTestClient {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &acmeTestClient{
+			t:       t,
+			b:       b,
+			storage: storage,
+			key:     key,
+		}
+	}
+	client := newClient()
+
+	// ACME is disabled until configured
+	directory := client.request(logical.ReadOperation, "acme/directory", nil)
+	if directory.status != 403 || directory.problemType(t) != "unauthorized" {
+		t.Fatalf("bad: %d %s", directory.status, directory.body)
+	}
+
+	request("config/acme", map[string]interface{}{
+		"enabled":  true,
+		"role":     "acme",
+		"base_url": acmeTestBaseURL + "/",
+	})
+
+	directory = client.request(logical.ReadOperation, "acme/directory", nil)
+	if directory.status != 200 {
+		t.Fatalf("bad: %d %s", directory.status, directory.body)
+	}
+	urls := directory.decode(t)
+	if acmeTestPath(t, urls["newAccount"]) != "acme/new-account" {
+		t.Fatalf("bad: %#v", urls)
+	}
+
+	nonce := client.request(logical.ReadOperation, "acme/new-nonce", nil)
+	if nonce.status != 204 || client.nonce == "" {
+		t.Fatalf("bad: %d %#v", nonce.status, nonce.headers)
+	}
+
+	// Nonces can only be used once
+	usedNonce := client.nonce
+	account := client.post("acme/new-account", map[string]interface{}{
+		"contact":              []string{"mailto:admin@example.com"},
+		"termsOfServiceAgreed": true,
+	})
+	if account.status != 201 || len(account.headers["Location"]) != 1 {
+		t.Fatalf("bad: %d %s", account.status, account.body)
+	}
+	client.nonce = usedNonce
+	replayed := client.post("acme/new-account", map[string]interface{}{})
+	if replayed.status != 400 || replayed.problemType(t) != "badNonce" {
+		t.Fatalf("bad: %d %s", replayed.status, replayed.body)
+	}
+
+	// The same key returns the existing account
+	existing := client.post("acme/new-account", map[string]interface{}{})
+	if existing.status != 200 || existing.headers["Location"][0] != account.headers["Location"][0] {
+		t.Fatalf("bad: %d %s", existing.status, existing.body)
+	}
+	client.kid = account.headers["Location"][0]
+
+	// The URL of the request must be signed
+	wrongURL := client.postURL("acme/new-order", acmeTestBaseURL+"/acme/new-account", map[string]interface{}{})
+	if wrongURL.status != 403 || wrongURL.problemType(t) != "unauthorized" {
+		t.Fatalf("bad: %d %s", wrongURL.status, wrongURL.body)
+	}
+
+	// Names must be allowed by the role
+	rejected := client.post("acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.org"}},
+	})
+	if rejected.status != 400 || rejected.problemType(t) != "rejectedIdentifier" {
+		t.Fatalf("bad: %d %s", rejected.status, rejected.body)
+	}
+
+	order := client.post("acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{
+			{"type": "dns", "value": "www.example.com"},
+			{"type": "dns", "value": "*.api.example.com"},
+		},
+	})
+	if order.status != 201 || len(order.headers["Location"]) != 1 {
+		t.Fatalf("bad: %d %s", order.status, order.body)
+	}
+	orderPath := acmeTestPath(t, order.headers["Location"][0])
+	orderData := order.decode(t)
+	if orderData["status"] != "pending" {
+		t.Fatalf("bad: %#v", orderData)
+	}
+
+	// Orders cannot be finalized until their authorizations are valid
+	csrDER := func(names ...string) string {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: names[0]},
+			DNSNames: names,
+		}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(der)
+	}
+	finalizePath := acmeTestPath(t, orderData["finalize"])
+	notReady := client.post(finalizePath, map[string]interface{}{
+		"csr": csrDER("www.example.com", "*.api.example.com"),
+	})
+	if notReady.status != 403 || notReady.problemType(t) != "orderNotReady" {
+		t.Fatalf("bad: %d %s", notReady.status, notReady.body)
+	}
+
+	thumbprint, err := (&acmeJWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   client.jwk()["x"],
+		Y:   client.jwk()["y"],
+	}).thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, authzURL := range orderData["authorizations"].([]interface{}) {
+		authz := client.post(acmeTestPath(t, authzURL), nil).decode(t)
+		identifier := authz["identifier"].(map[string]interface{})["value"].(string)
+
+		// Wildcard names can only be validated with dns-01
+		var challenge map[string]interface{}
+		for _, c := range authz["challenges"].([]interface{}) {
+			c := c.(map[string]interface{})
+			if authz["wildcard"] == true && c["type"] == "http-01" {
+				t.Fatalf("bad: http-01 challenge for a wildcard name: %#v", authz)
+			}
+			if (authz["wildcard"] == true) == (c["type"] == "dns-01") {
+				challenge = c
+			}
+		}
+		if challenge == nil {
+			t.Fatalf("bad: %#v", authz)
+		}
+
+		keyAuthorization := challenge["token"].(string) + "." + thumbprint
+		switch challenge["type"] {
+		case "http-01":
+			httpResponses[fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", identifier, challenge["token"])] = keyAuthorization
+		case "dns-01":
+			sum := sha256.Sum256([]byte(keyAuthorization))
+			txtRecords["_acme-challenge."+identifier] = []string{"unrelated", base64.RawURLEncoding.EncodeToString(sum[:])}
+		}
+
+		validated := client.post(acmeTestPath(t, challenge["url"]), map[string]interface{}{})
+		if validated.status != 200 || validated.decode(t)["status"] != "valid" {
+			t.Fatalf("bad: %d %s", validated.status, validated.body)
+		}
+	}
+
+	orderData = client.post(orderPath, nil).decode(t)
+	if orderData["status"] != "ready" {
+		t.Fatalf("bad: %#v", orderData)
+	}
+
+	// The CSR must request the names of the order
+	badCSR := client.post(finalizePath, map[string]interface{}{
+		"csr": csrDER("www.example.com"),
+	})
+	if badCSR.status != 400 || badCSR.problemType(t) != "badCSR" {
+		t.Fatalf("bad: %d %s", badCSR.status, badCSR.body)
+	}
+
+	finalized := client.post(finalizePath, map[string]interface{}{
+		"csr": csrDER("www.example.com", "*.api.example.com"),
+	})
+	if finalized.status != 200 {
+		t.Fatalf("bad: %d %s", finalized.status, finalized.body)
+	}
+	orderData = finalized.decode(t)
+	if orderData["status"] != "valid" {
+		t.Fatalf("bad: %#v", orderData)
+	}
+
+	certificate := client.post(acmeTestPath(t, orderData["certificate"]), nil)
+	if certificate.status != 200 {
+		t.Fatalf("bad: %d %s", certificate.status, certificate.body)
+	}
+	block, _ = pem.Decode(certificate.body)
+	if block == nil {
+		t.Fatalf("bad: %s", certificate.body)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignatureFrom(caCert); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifyHostname("foo.api.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed challenges invalidate the order
+	failed := client.post("acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "fail.example.com"}},
+	})
+	failedOrderPath := acmeTestPath(t, failed.headers["Location"][0])
+	authzURL := failed.decode(t)["authorizations"].([]interface{})[0]
+	authz := client.post(acmeTestPath(t, authzURL), nil).decode(t)
+	challenge := authz["challenges"].([]interface{})[0].(map[string]interface{})
+	httpResponses[fmt.Sprintf("http://fail.example.com/.well-known/acme-challenge/%s", challenge["token"])] = "wrong"
+	invalid := client.post(acmeTestPath(t, challenge["url"]), map[string]interface{}{}).decode(t)
+	if invalid["status"] != "invalid" || invalid["error"] == nil {
+		t.Fatalf("bad: %#v", invalid)
+	}
+	if status := client.post(failedOrderPath, nil).decode(t)["status"]; status != "invalid" {
+		t.Fatalf("bad: order status %s", status)
+	}
+
+	// Orders of other accounts cannot be fetched
+	other := newClient()
+	other.nonce = client.nonce
+	otherAccount := other.post("acme/new-account", map[string]interface{}{})
+	other.kid = otherAccount.headers["Location"][0]
+	forbidden := other.post(orderPath, nil)
+	if forbidden.status != 403 || forbidden.problemType(t) != "unauthorized" {
+		t.Fatalf("bad: %d %s", forbidden.status, forbidden.body)
+	}
+}
+
+func TestBackend_ACMENonces(t *testing.T) {
+	b := Backend()
+
+	nonce, err := b.newACMENonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.useACMENonce(nonce) {
+		t.Fatal("expected a new nonce to be valid")
+	}
+	if b.useACMENonce(nonce) {
+		t.Fatal("expected a used nonce to be rejected")
+	}
+
+	// Nonces are rejected if they are altered, expired or issued with
+	// another key
+	nonce, err = b.newACMENonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(nonce)
+	raw[9] ^= 1
+	random := make([]byte, acmeNonceRandomSize)
+	invalid := map[string]string{
+		"altered": base64.RawURLEncoding.EncodeToString(raw),
+		"expired": acmeNonceAt(b.acmeNonceKey, time.Now().Add(-acmeNonceLifetime-time.Minute), random),
+		"future":  acmeNonceAt(b.acmeNonceKey, time.Now().Add(time.Minute), random),
+		"key":     acmeNonceAt(make([]byte, 32), time.Now(), random),
+		"garbage": "nonce",
+	}
+	for name, nonce := range invalid {
+		if b.useACMENonce(nonce) {
+			t.Fatalf("expected the %s nonce to be rejected", name)
+		}
+	}
+	if !b.useACMENonce(nonce) {
+		t.Fatal("expected a new nonce to be valid")
+	}
+
+	// Only so many used nonces are remembered, and the nonces issued before
+	// the ones that were forgotten are rejected instead
+	early, err := b.newACMENonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < acmeMaxUsedNonces; i++ {
+		nonce, err := b.newACMENonce()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !b.useACMENonce(nonce) {
+			t.Fatal("expected a new nonce to be valid")
+		}
+	}
+	if len(b.acmeUsedNonces) > acmeMaxUsedNonces || len(b.acmeUsedNonceQueue) > acmeMaxUsedNonces {
+		t.Fatalf("bad: %d used nonces remembered", len(b.acmeUsedNonces))
+	}
+	if b.useACMENonce(early) {
+		t.Fatal("expected a nonce issued before the forgotten ones to be rejected")
+	}
+	nonce, err = b.newACMENonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.useACMENonce(nonce) {
+		t.Fatal("expected a new nonce to be valid")
+	}
+}
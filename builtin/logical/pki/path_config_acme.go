@@ -0,0 +1,153 @@
+package pki
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// acmeConfig holds the configuration of the ACME server
+type acmeConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled" structs:"enabled"`
+
+	// The role certificates are issued with for all ACME accounts
+	Role string `json:"role" mapstructure:"role" structs:"role"`
+
+	// The URL the mount is reached at by ACME clients, which the URLs of the
+	// ACME resources are built from
+	BaseURL string `json:"base_url" mapstructure:"base_url" structs:"base_url"`
+}
+
+func pathConfigACME(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/acme",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Whether the ACME server is enabled`,
+			},
+
+			"role": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The role certificates are issued with for
+all ACME accounts`,
+			},
+
+			"base_url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The URL of the mount as reached by ACME
+clients, such as
+"https://vault.example.com:8200/v1/pki"`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathACMEConfigRead,
+			logical.UpdateOperation: b.pathACMEConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigACMEHelpSyn,
+		HelpDescription: pathConfigACMEHelpDesc,
+	}
+}
+
+func (b *backend) ACMEConfig(s logical.Storage) (*acmeConfig, error) {
+	entry, err := s.Get("config/acme")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result acmeConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathACMEConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.ACMEConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":  config.Enabled,
+			"role":     config.Role,
+			"base_url": config.BaseURL,
+		},
+	}, nil
+}
+
+func (b *backend) pathACMEConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.ACMEConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &acmeConfig{}
+	}
+
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+	if roleRaw, ok := data.GetOk("role"); ok {
+		config.Role = roleRaw.(string)
+	}
+	if baseURLRaw, ok := data.GetOk("base_url"); ok {
+		config.BaseURL = strings.TrimSuffix(baseURLRaw.(string), "/")
+	}
+
+	if config.BaseURL != "" {
+		if _, err := url.Parse(config.BaseURL); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("Given base_url could not be parsed: %s", err)), nil
+		}
+	}
+
+	if config.Enabled {
+		if config.Role == "" || config.BaseURL == "" {
+			return logical.ErrorResponse("role and base_url are required to enable ACME"), nil
+		}
+		role, err := b.getRole(req.Storage, config.Role)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("Unknown role: %s", config.Role)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config/acme", config)
+	if err != nil {
+		return nil, err
+	}
+	err = req.Storage.Put(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigACMEHelpSyn = `
+Configure the ACME server.
+`
+
+const pathConfigACMEHelpDesc = `
+This endpoint allows enabling the ACME server (RFC 8555) served at "acme/",
+and configuring the role certificates are issued with for ACME accounts. The
+URL the mount is reached at by ACME clients must be given, as the ACME
+resources are identified by absolute URLs.
+`
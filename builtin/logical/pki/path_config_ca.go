@@ -10,7 +10,7 @@ import (
 )
 
 func pathConfigCA(b *backend) *framework.Path {
-	return &framework.Path{
+	ret := &framework.Path{
 		Pattern: "config/ca",
 		Fields: map[string]*framework.FieldSchema{
 			"pem_bundle": &framework.FieldSchema{
@@ -29,12 +29,21 @@ endpoint, just the signed certificate.`,
 		HelpSynopsis:    pathConfigCAHelpSyn,
 		HelpDescription: pathConfigCAHelpDesc,
 	}
+
+	ret.Fields = addIssuerNameFields(ret.Fields)
+
+	return ret
 }
 
 func (b *backend) pathCAWrite(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	pemBundle := data.Get("pem_bundle").(string)
 
+	issuerName := data.Get("issuer_name").(string)
+	if !validIssuerName(issuerName) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid issuer name %q", issuerName)), nil
+	}
+
 	parsedBundle, err := certutil.ParsePEMBundle(pemBundle)
 	if err != nil {
 		switch err.(type) {
@@ -63,24 +72,12 @@ func (b *backend) pathCAWrite(
 		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
 	}
 
-	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
-	if err != nil {
-		return nil, err
-	}
-
-	// For ease of later use, also store just the certificate at a known
-	// location, plus a fresh CRL
-	entry.Key = "ca"
-	entry.Value = parsedBundle.CertificateBytes
-	err = req.Storage.Put(entry)
+	err = storeIssuerBundle(req.Storage, issuerName, cb, parsedBundle.CertificateBytes)
 	if err != nil {
 		return nil, err
 	}
 
+	// Also build a fresh CRL
 	err = buildCRL(b, req)
 
 	return nil, err
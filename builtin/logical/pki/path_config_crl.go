@@ -10,7 +10,11 @@ import (
 
 // CRLConfig holds basic CRL configuration information
 type crlConfig struct {
-	Expiry string `json:"expiry" mapstructure:"expiry" structs:"expiry"`
+	Expiry                 string `json:"expiry" mapstructure:"expiry" structs:"expiry"`
+	AutoRebuild            bool   `json:"auto_rebuild" mapstructure:"auto_rebuild" structs:"auto_rebuild"`
+	AutoRebuildGracePeriod string `json:"auto_rebuild_grace_period" mapstructure:"auto_rebuild_grace_period" structs:"auto_rebuild_grace_period"`
+	EnableDelta            bool   `json:"enable_delta" mapstructure:"enable_delta" structs:"enable_delta"`
+	DeltaRebuildInterval   string `json:"delta_rebuild_interval" mapstructure:"delta_rebuild_interval" structs:"delta_rebuild_interval"`
 }
 
 func pathConfigCRL(b *backend) *framework.Path {
@@ -23,6 +27,37 @@ func pathConfigCRL(b *backend) *framework.Path {
 valid; defaults to 72 hours`,
 				Default: "72h",
 			},
+
+			"auto_rebuild": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, revoking a certificate only records it,
+and the CRLs are rebuilt periodically before they
+expire instead of on each revocation`,
+				Default: false,
+			},
+
+			"auto_rebuild_grace_period": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `How long before the CRLs expire they are
+rebuilt when auto_rebuild is set; defaults to 12
+hours`,
+				Default: "12h",
+			},
+
+			"enable_delta": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, delta CRLs listing the certificates
+revoked since the last complete CRL are built
+periodically. Requires auto_rebuild.`,
+				Default: false,
+			},
+
+			"delta_rebuild_interval": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `How often the delta CRLs are rebuilt when
+enable_delta is set; defaults to 15 minutes`,
+				Default: "15m",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -64,7 +99,11 @@ func (b *backend) pathCRLRead(
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"expiry": config.Expiry,
+			"expiry":                    config.Expiry,
+			"auto_rebuild":              config.AutoRebuild,
+			"auto_rebuild_grace_period": config.AutoRebuildGracePeriod,
+			"enable_delta":              config.EnableDelta,
+			"delta_rebuild_interval":    config.DeltaRebuildInterval,
 		},
 	}, nil
 }
@@ -72,14 +111,44 @@ func (b *backend) pathCRLRead(
 func (b *backend) pathCRLWrite(
 	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	expiry := d.Get("expiry").(string)
+	autoRebuild := d.Get("auto_rebuild").(bool)
+	gracePeriod := d.Get("auto_rebuild_grace_period").(string)
+	enableDelta := d.Get("enable_delta").(bool)
+	deltaInterval := d.Get("delta_rebuild_interval").(string)
 
-	_, err := time.ParseDuration(expiry)
+	expiryDur, err := time.ParseDuration(expiry)
 	if err != nil {
 		return logical.ErrorResponse(fmt.Sprintf("Given expiry could not be decoded: %s", err)), nil
 	}
 
+	graceDur, err := time.ParseDuration(gracePeriod)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("Given auto_rebuild_grace_period could not be decoded: %s", err)), nil
+	}
+	if autoRebuild && graceDur >= expiryDur {
+		return logical.ErrorResponse("auto_rebuild_grace_period must be shorter than expiry"), nil
+	}
+
+	deltaDur, err := time.ParseDuration(deltaInterval)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("Given delta_rebuild_interval could not be decoded: %s", err)), nil
+	}
+	if deltaDur <= 0 {
+		return logical.ErrorResponse("delta_rebuild_interval must be positive"), nil
+	}
+
+	// Deltas list the certificates revoked since the last complete CRL,
+	// which is only tracked when revocations are not built synchronously
+	if enableDelta && !autoRebuild {
+		return logical.ErrorResponse("enable_delta requires auto_rebuild"), nil
+	}
+
 	config := &crlConfig{
-		Expiry: expiry,
+		Expiry:                 expiry,
+		AutoRebuild:            autoRebuild,
+		AutoRebuildGracePeriod: gracePeriod,
+		EnableDelta:            enableDelta,
+		DeltaRebuildInterval:   deltaInterval,
 	}
 
 	entry, err := logical.StorageEntryJSON("config/crl", config)
@@ -95,9 +164,14 @@ func (b *backend) pathCRLWrite(
 }
 
 const pathConfigCRLHelpSyn = `
-Configure the CRL expiration.
+Configure the CRL expiration and rebuilding.
 `
 
 const pathConfigCRLHelpDesc = `
-This endpoint allows configuration of the CRL lifetime.
+This endpoint allows configuration of the CRL lifetime. With "auto_rebuild"
+set, revoking a certificate no longer rebuilds the CRLs; they are rebuilt
+periodically once within "auto_rebuild_grace_period" of their expiry, or on
+a read of "crl/rotate". With "enable_delta" also set, delta CRLs listing the
+certificates revoked since the last complete CRL are built every
+"delta_rebuild_interval".
 `
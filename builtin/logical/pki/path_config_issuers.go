@@ -0,0 +1,109 @@
+package pki
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// issuersConfig holds the name of the issuer used when none is given
+type issuersConfig struct {
+	Default string `json:"default" mapstructure:"default" structs:"default"`
+}
+
+func pathConfigIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuers",
+		Fields: map[string]*framework.FieldSchema{
+			"default": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The name of the issuer used by roles without
+an issuer_ref, and served at "ca", "ca_chain"
+and "crl"`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuersConfigRead,
+			logical.UpdateOperation: b.pathIssuersConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigIssuersHelpSyn,
+		HelpDescription: pathConfigIssuersHelpDesc,
+	}
+}
+
+func getIssuersConfig(s logical.Storage) (*issuersConfig, error) {
+	entry, err := s.Get("config/issuers")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result issuersConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathIssuersConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := defaultIssuer(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"default": issuer,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuersConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer := data.Get("default").(string)
+	if issuer == "" {
+		return logical.ErrorResponse("default is required"), nil
+	}
+
+	bundle, err := fetchIssuerBundle(req.Storage, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown issuer %s", issuer)), nil
+	}
+	if bundle.Certificate == "" {
+		return logical.ErrorResponse(fmt.Sprintf("issuer %s has no certificate yet", issuer)), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config/issuers", &issuersConfig{
+		Default: issuer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = req.Storage.Put(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigIssuersHelpSyn = `
+Configure the default issuer.
+`
+
+const pathConfigIssuersHelpDesc = `
+This endpoint allows setting the issuer used by roles without an issuer_ref,
+by the endpoints signing certificates without an issuer_ref, and served at
+the "ca", "ca_chain" and "crl" endpoints. Changing it allows rotating the CA
+of the mount without reconfiguring roles or clients.
+`
@@ -0,0 +1,185 @@
+package pki
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// ocspConfig holds the configuration of the OCSP responder
+type ocspConfig struct {
+	// The delegated responder certificate and key, if the responses are not
+	// signed by the CA itself
+	ResponderBundle *certutil.CertBundle `json:"responder_bundle" mapstructure:"responder_bundle" structs:"responder_bundle"`
+
+	// How long responses are valid for
+	NextUpdate string `json:"next_update" mapstructure:"next_update" structs:"next_update"`
+}
+
+func pathConfigOCSP(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/ocsp",
+		Fields: map[string]*framework.FieldSchema{
+			"pem_bundle": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `PEM-format, concatenated unencrypted
+secret key and certificate of a delegated
+OCSP responder. The certificate must be
+issued by the CA and flagged for OCSP
+signing. If empty, responses are signed by
+the CA.`,
+			},
+
+			"next_update": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The amount of time responses are valid
+for; defaults to 12 hours`,
+				Default: "12h",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathOCSPConfigRead,
+			logical.UpdateOperation: b.pathOCSPConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigOCSPHelpSyn,
+		HelpDescription: pathConfigOCSPHelpDesc,
+	}
+}
+
+func (b *backend) OCSPConfig(s logical.Storage) (*ocspConfig, error) {
+	entry, err := s.Get("config/ocsp")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result ocspConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathOCSPConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.OCSPConfig(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"next_update": config.NextUpdate,
+		},
+	}
+	if config.ResponderBundle != nil {
+		resp.Data["certificate"] = config.ResponderBundle.Certificate
+	}
+
+	return resp, nil
+}
+
+func (b *backend) pathOCSPConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	nextUpdate := data.Get("next_update").(string)
+	if _, err := time.ParseDuration(nextUpdate); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("Given next_update could not be decoded: %s", err)), nil
+	}
+
+	config := &ocspConfig{
+		NextUpdate: nextUpdate,
+	}
+
+	if pemBundle := data.Get("pem_bundle").(string); pemBundle != "" {
+		parsedBundle, err := certutil.ParsePEMBundle(pemBundle)
+		if err != nil {
+			switch err.(type) {
+			case errutil.InternalError:
+				return nil, err
+			default:
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+
+		if parsedBundle.PrivateKey == nil ||
+			parsedBundle.PrivateKeyType == certutil.UnknownPrivateKey {
+			return logical.ErrorResponse("private key not found in the PEM bundle"), nil
+		}
+		if parsedBundle.Certificate == nil {
+			return logical.ErrorResponse("no certificate found in the PEM bundle"), nil
+		}
+		if err := parsedBundle.Verify(); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		ocspSigning := false
+		for _, usage := range parsedBundle.Certificate.ExtKeyUsage {
+			if usage == x509.ExtKeyUsageOCSPSigning {
+				ocspSigning = true
+			}
+		}
+		if !ocspSigning {
+			return logical.ErrorResponse("the given certificate is not flagged for OCSP signing"), nil
+		}
+
+		issuers, err := listSigningIssuers(req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if len(issuers) == 0 {
+			return logical.ErrorResponse("backend must be configured with a CA certificate/key"), nil
+		}
+		issued := false
+		for _, issuer := range issuers {
+			caInfo, err := fetchIssuerInfo(req, issuer)
+			if err != nil {
+				return nil, err
+			}
+			if ocspResponderIssuedBy(parsedBundle.Certificate, caInfo.Certificate, time.Now()) == nil {
+				issued = true
+				break
+			}
+		}
+		if !issued {
+			return logical.ErrorResponse("the given certificate is not a valid certificate issued by any issuer of the mount"), nil
+		}
+
+		config.ResponderBundle, err = parsedBundle.ToCertBundle()
+		if err != nil {
+			return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config/ocsp", config)
+	if err != nil {
+		return nil, err
+	}
+	err = req.Storage.Put(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigOCSPHelpSyn = `
+Configure the OCSP responder.
+`
+
+const pathConfigOCSPHelpDesc = `
+This endpoint allows configuration of the lifetime of OCSP responses, and
+of a delegated responder certificate to sign them with instead of the CA.
+`
@@ -24,6 +24,7 @@ func pathGenerateIntermediate(b *backend) *framework.Path {
 
 	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
 	ret.Fields = addCAKeyGenerationFields(ret.Fields)
+	ret.Fields = addIssuerNameFields(ret.Fields)
 
 	return ret
 }
@@ -50,6 +51,8 @@ endpoint.`,
 		HelpDescription: pathSetSignedIntermediateHelpDesc,
 	}
 
+	ret.Fields = addIssuerNameFields(ret.Fields)
+
 	return ret
 }
 
@@ -62,6 +65,11 @@ func (b *backend) pathGenerateIntermediate(
 		return errorResp, nil
 	}
 
+	issuerName := data.Get("issuer_name").(string)
+	if !validIssuerName(issuerName) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid issuer name %q", issuerName)), nil
+	}
+
 	var resp *logical.Response
 	parsedBundle, err := generateIntermediateCSR(b, role, nil, req, data)
 	if err != nil {
@@ -110,11 +118,7 @@ func (b *backend) pathGenerateIntermediate(
 	cb.PrivateKey = csrb.PrivateKey
 	cb.PrivateKeyType = csrb.PrivateKeyType
 
-	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
+	err = storeIssuerBundle(req.Storage, issuerName, cb, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -144,19 +148,18 @@ func (b *backend) pathSetSignedIntermediate(
 		return logical.ErrorResponse("supplied certificate could not be successfully parsed"), nil
 	}
 
-	cb := &certutil.CertBundle{}
-	entry, err := req.Storage.Get("config/ca_bundle")
-	if err != nil {
-		return nil, err
-	}
-	if entry == nil {
-		return logical.ErrorResponse("could not find any existing entry with a private key"), nil
+	issuerName := data.Get("issuer_name").(string)
+	if !validIssuerName(issuerName) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid issuer name %q", issuerName)), nil
 	}
 
-	err = entry.DecodeJSON(cb)
+	cb, err := fetchIssuerBundle(req.Storage, issuerName)
 	if err != nil {
 		return nil, err
 	}
+	if cb == nil {
+		return logical.ErrorResponse("could not find any existing entry with a private key"), nil
+	}
 
 	if len(cb.PrivateKey) == 0 || cb.PrivateKeyType == "" {
 		return logical.ErrorResponse("could not find an existing private key"), nil
@@ -187,27 +190,15 @@ func (b *backend) pathSetSignedIntermediate(
 		return nil, fmt.Errorf("error converting raw values into cert bundle: %s", err)
 	}
 
-	entry, err = logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
-	if err != nil {
-		return nil, err
-	}
-
-	entry.Key = "certs/" + cb.SerialNumber
-	entry.Value = inputBundle.CertificateBytes
-	err = req.Storage.Put(entry)
+	err = storeIssuerBundle(req.Storage, issuerName, cb, inputBundle.CertificateBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	// For ease of later use, also store just the certificate at a known
-	// location
-	entry.Key = "ca"
-	entry.Value = inputBundle.CertificateBytes
-	err = req.Storage.Put(entry)
+	err = req.Storage.Put(&logical.StorageEntry{
+		Key:   "certs/" + cb.SerialNumber,
+		Value: inputBundle.CertificateBytes,
+	})
 	if err != nil {
 		return nil, err
 	}
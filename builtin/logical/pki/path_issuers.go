@@ -0,0 +1,410 @@
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathIssuerList,
+		},
+
+		HelpSynopsis:    pathListIssuersHelpSyn,
+		HelpDescription: pathListIssuersHelpDesc,
+	}
+}
+
+func pathIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the issuer",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuerRead,
+			logical.DeleteOperation: b.pathIssuerDelete,
+		},
+
+		HelpSynopsis:    pathIssuersHelpSyn,
+		HelpDescription: pathIssuersHelpDesc,
+	}
+}
+
+func pathCrossSignIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/" + framework.GenericNameRegex("name") + "/cross-sign",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the issuer being cross-signed",
+			},
+
+			"issuer_ref": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the issuer signing the certificate",
+			},
+
+			"ttl": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The requested Time To Live for the certificate;
+sets the expiration date. If not specified
+the backend default or system default TTL is
+used. Cannot be later than the expiration of
+the signing issuer.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathIssuerCrossSign,
+		},
+
+		HelpSynopsis:    pathCrossSignIssuerHelpSyn,
+		HelpDescription: pathCrossSignIssuerHelpDesc,
+	}
+}
+
+// Returns the CA, CA chain or CRL of an issuer, in the formats of the
+// endpoints of the default issuer
+func pathFetchIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("name") + `/(?P<type>ca|ca/pem|ca_chain|crl|crl/pem|crl/delta|crl/delta/pem)`,
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the issuer",
+			},
+
+			"type": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `One of "ca", "ca/pem", "ca_chain", "crl",
+"crl/pem", "crl/delta" or "crl/delta/pem"`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchIssuerRead,
+		},
+
+		HelpSynopsis:    pathFetchIssuerHelpSyn,
+		HelpDescription: pathFetchIssuerHelpDesc,
+	}
+}
+
+func (b *backend) pathIssuerList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuers, err := listIssuers(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(issuers), nil
+}
+
+func (b *backend) pathIssuerRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	bundle, err := fetchIssuerBundle(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return nil, nil
+	}
+
+	defaultName, err := defaultIssuer(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	caChain := bundle.CAChain
+	if caChain == nil {
+		caChain = []string{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"certificate":   bundle.Certificate,
+			"ca_chain":      caChain,
+			"serial_number": bundle.SerialNumber,
+			"default":       name == defaultName,
+		},
+	}, nil
+}
+
+func (b *backend) pathIssuerDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	defaultName, err := defaultIssuer(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if name == defaultName {
+		return logical.ErrorResponse("the default issuer cannot be deleted; set another default issuer first"), nil
+	}
+
+	for _, path := range []string{issuerBundlePath(name), issuerCAPath(name), issuerCRLPath(name), issuerDeltaCRLPath(name)} {
+		if err := req.Storage.Delete(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathIssuerCrossSign(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	signerName := data.Get("issuer_ref").(string)
+
+	if signerName == "" {
+		return logical.ErrorResponse("issuer_ref is required"), nil
+	}
+	if signerName == name {
+		return logical.ErrorResponse("an issuer cannot cross-sign itself"), nil
+	}
+
+	target, err := fetchIssuerInfo(req, name)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+	signingBundle, err := fetchIssuerInfo(req, signerName)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+
+	// The key of the issuer requests a certificate for its own subject,
+	// which the signing issuer signs as it would an intermediate CA
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		RawSubject: target.Certificate.RawSubject,
+	}, target.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the request of the issuer: %s", err)
+	}
+
+	role := &roleEntry{
+		TTL:              data.Get("ttl").(string),
+		AllowLocalhost:   true,
+		AllowAnyName:     true,
+		AllowIPSANs:      true,
+		EnforceHostnames: false,
+		KeyType:          "any",
+		UseCSRCommonName: true,
+	}
+	if target.Certificate.MaxPathLen > 0 || target.Certificate.MaxPathLenZero {
+		maxPathLength := target.Certificate.MaxPathLen
+		role.MaxPathLength = &maxPathLength
+	}
+
+	signData := &framework.FieldData{
+		Raw: map[string]interface{}{
+			"csr": string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE REQUEST",
+				Bytes: csrBytes,
+			})),
+			"ttl":                  data.Get("ttl").(string),
+			"exclude_cn_from_sans": true,
+		},
+		Schema: pathSignIntermediate(b).Fields,
+	}
+	parsedBundle, err := signCert(b, role, signingBundle, true, true, req, signData)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), nil
+		default:
+			return nil, err
+		}
+	}
+
+	cb, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("Error converting raw cert bundle to cert bundle: %s", err)
+	}
+
+	err = req.Storage.Put(&logical.StorageEntry{
+		Key:   "certs/" + cb.SerialNumber,
+		Value: parsedBundle.CertificateBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to store certificate locally: %v", err)
+	}
+
+	// The cross-signed certificate is served in the chain of the issuer, so
+	// that clients only trusting the signing issuer can build a path
+	targetCB, err := fetchIssuerBundle(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	targetCB.CAChain = append(targetCB.CAChain, cb.Certificate)
+	if err := storeIssuerBundle(req.Storage, name, targetCB, nil); err != nil {
+		return nil, err
+	}
+
+	signingCB, err := signingBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("Error converting raw signing bundle to cert bundle: %s", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"certificate":   cb.Certificate,
+			"issuing_ca":    signingCB.Certificate,
+			"serial_number": cb.SerialNumber,
+			"expiration":    int64(parsedBundle.Certificate.NotAfter.Unix()),
+		},
+	}, nil
+}
+
+func (b *backend) pathFetchIssuerRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	fetchType := data.Get("type").(string)
+
+	bundle, err := fetchIssuerBundle(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown issuer %s", name)), nil
+	}
+
+	var contents []byte
+	var contentType, pemType string
+	switch fetchType {
+	case "ca", "ca/pem":
+		contentType = "application/pkix-cert"
+		if fetchType == "ca/pem" {
+			pemType = "CERTIFICATE"
+		}
+		entry, err := req.Storage.Get(issuerCAPath(name))
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			contents = entry.Value
+		}
+
+	case "ca_chain":
+		contentType = "application/pkix-cert"
+		if bundle.Certificate != "" {
+			caInfo, err := fetchIssuerInfo(req, name)
+			if err != nil {
+				return nil, err
+			}
+			for _, ca := range caInfo.GetCAChain() {
+				contents = append(contents, pem.EncodeToMemory(&pem.Block{
+					Type:  "CERTIFICATE",
+					Bytes: ca.Bytes,
+				})...)
+			}
+		}
+
+	case "crl", "crl/pem":
+		contentType = "application/pkix-crl"
+		if fetchType == "crl/pem" {
+			pemType = "X509 CRL"
+		}
+		entry, err := req.Storage.Get(issuerCRLPath(name))
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			contents = entry.Value
+		}
+
+	case "crl/delta", "crl/delta/pem":
+		contentType = "application/pkix-crl"
+		if fetchType == "crl/delta/pem" {
+			pemType = "X509 CRL"
+		}
+		entry, err := req.Storage.Get(issuerDeltaCRLPath(name))
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			contents = entry.Value
+		}
+	}
+
+	if len(contents) > 0 && pemType != "" {
+		contents = pem.EncodeToMemory(&pem.Block{
+			Type:  pemType,
+			Bytes: contents,
+		})
+	}
+
+	response := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     contents,
+		},
+	}
+	if len(contents) > 0 {
+		response.Data[logical.HTTPStatusCode] = 200
+	} else {
+		response.Data[logical.HTTPStatusCode] = 204
+	}
+
+	return response, nil
+}
+
+const pathListIssuersHelpSyn = `List the issuers of this backend`
+
+const pathListIssuersHelpDesc = `Issuers will be listed by name, including issuers still waiting for their
+signed certificate.`
+
+const pathIssuersHelpSyn = `Read or delete an issuer of this backend.`
+
+const pathIssuersHelpDesc = `
+This path returns the certificate and CA chain of an issuer, or deletes it.
+The private key of an issuer cannot be read. The default issuer cannot be
+deleted.
+`
+
+const pathCrossSignIssuerHelpSyn = `
+Cross-sign an issuer with another issuer of this backend.
+`
+
+const pathCrossSignIssuerHelpDesc = `
+This path signs the subject and key of an issuer with another issuer given in
+"issuer_ref". The cross-signed certificate is added to the CA chain of the
+issuer, so that clients trusting either issuer can verify the certificates it
+issues. This allows an old and a new CA to coexist while clients are moved
+from one to the other.
+`
+
+const pathFetchIssuerHelpSyn = `
+Fetch the CA, CA chain, CRL or delta CRL of an issuer.
+`
+
+const pathFetchIssuerHelpDesc = `
+This returns the CA, CRL or delta CRL of the named issuer in DER encoding, or
+in PEM encoding when adding "/pem". Using "ca_chain" fetches the CA trust chain of
+the issuer in PEM encoding.
+`
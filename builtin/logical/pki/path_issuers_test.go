@@ -0,0 +1,270 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestBackend_Issuers(t *testing.T) {
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+
+	b := Backend()
+	_, err := b.Setup(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawRequest := func(operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := rawRequest(operation, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path %s\nerr: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	requestError := func(operation logical.Operation, path string, data map[string]interface{}) {
+		resp, err := rawRequest(operation, path, data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error for path %s, got: %#v", path, resp)
+		}
+	}
+
+	parseCert := func(pemCert string) *x509.Certificate {
+		block, _ := pem.Decode([]byte(pemCert))
+		if block == nil {
+			t.Fatal("failed to decode certificate")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	rawBody := func(path string) []byte {
+		resp := request(logical.ReadOperation, path, nil)
+		return resp.Data[logical.HTTPRawBody].([]byte)
+	}
+
+	crlSerials := func(path string) map[string]bool {
+		crl, err := x509.ParseCRL(rawBody(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		serials := map[string]bool{}
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			serials[revoked.SerialNumber.String()] = true
+		}
+		return serials
+	}
+
+	// The issuer generated without a name is the default one, and a second
+	// issuer is added alongside it
+	resp := request(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "old.test.com",
+		"ttl":         "6h",
+	})
+	oldCA := parseCert(resp.Data["certificate"].(string))
+
+	resp = request(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "new.test.com",
+		"ttl":         "6h",
+		"issuer_name": "next",
+	})
+	newCA := parseCert(resp.Data["certificate"].(string))
+
+	requestError(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "bad.test.com",
+		"issuer_name": "not a name",
+	})
+
+	resp = request(logical.ListOperation, "issuers/", nil)
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"default", "next"}) {
+		t.Fatalf("bad: issuers %v", keys)
+	}
+
+	resp = request(logical.ReadOperation, "config/issuers", nil)
+	if resp.Data["default"] != "default" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = request(logical.ReadOperation, "issuers/next", nil)
+	if resp.Data["default"] != false || !parseCert(resp.Data["certificate"].(string)).Equal(newCA) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Each issuer is served at its own public endpoints
+	caCert, err := x509.ParseCertificate(rawBody("issuer/next/ca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caCert.Equal(newCA) {
+		t.Fatal("bad: issuer/next/ca is not the certificate of the issuer")
+	}
+	caCert, err = x509.ParseCertificate(rawBody("ca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caCert.Equal(oldCA) {
+		t.Fatal("bad: ca is not the certificate of the default issuer")
+	}
+	requestError(logical.ReadOperation, "issuer/missing/ca", nil)
+
+	// Roles issue with their issuer, or the default one
+	request(logical.UpdateOperation, "roles/old", map[string]interface{}{
+		"allowed_domains":  "test.com",
+		"allow_subdomains": true,
+		"max_ttl":          "4h",
+	})
+	request(logical.UpdateOperation, "roles/new", map[string]interface{}{
+		"allowed_domains":  "test.com",
+		"allow_subdomains": true,
+		"max_ttl":          "4h",
+		"issuer_ref":       "next",
+	})
+	requestError(logical.UpdateOperation, "roles/missing", map[string]interface{}{
+		"allowed_domains": "test.com",
+		"issuer_ref":      "missing",
+	})
+
+	issue := func(role string, issuer *x509.Certificate) (*x509.Certificate, string) {
+		resp := request(logical.UpdateOperation, "issue/"+role, map[string]interface{}{
+			"common_name": "example.test.com",
+		})
+		cert := parseCert(resp.Data["certificate"].(string))
+		if err := cert.CheckSignatureFrom(issuer); err != nil {
+			t.Fatalf("bad: certificate of role %s not issued by the expected issuer: %s", role, err)
+		}
+		return cert, resp.Data["serial_number"].(string)
+	}
+
+	oldCert, oldSerial := issue("old", oldCA)
+	newCert, newSerial := issue("new", newCA)
+
+	// Revoked certificates are listed on the CRL of their issuer
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": oldSerial,
+	})
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": newSerial,
+	})
+
+	oldCRL := crlSerials("crl")
+	newCRL := crlSerials("issuer/next/crl")
+	if !oldCRL[oldCert.SerialNumber.String()] || oldCRL[newCert.SerialNumber.String()] {
+		t.Fatalf("bad: CRL of the default issuer %v", oldCRL)
+	}
+	if !newCRL[newCert.SerialNumber.String()] || newCRL[oldCert.SerialNumber.String()] {
+		t.Fatalf("bad: CRL of the next issuer %v", newCRL)
+	}
+	if !reflect.DeepEqual(crlSerials("issuer/default/crl"), oldCRL) {
+		t.Fatal("bad: issuer/default/crl is not the CRL of the default issuer")
+	}
+
+	// Cross-signing the new issuer with the old one lets clients only
+	// trusting the old issuer verify certificates of the new one
+	requestError(logical.UpdateOperation, "issuers/next/cross-sign", map[string]interface{}{
+		"issuer_ref": "next",
+	})
+	resp = request(logical.UpdateOperation, "issuers/next/cross-sign", map[string]interface{}{
+		"issuer_ref": "default",
+		"ttl":        "1h",
+	})
+	crossSigned := parseCert(resp.Data["certificate"].(string))
+	if err := crossSigned.CheckSignatureFrom(oldCA); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(crossSigned.RawSubject, newCA.RawSubject) ||
+		!reflect.DeepEqual(crossSigned.RawSubjectPublicKeyInfo, newCA.RawSubjectPublicKeyInfo) ||
+		!crossSigned.IsCA {
+		t.Fatal("bad: cross-signed certificate does not hold the subject and key of the issuer")
+	}
+
+	chainPEM := rawBody("issuer/next/ca_chain")
+	chain := []*x509.Certificate{}
+	for block, rest := pem.Decode(chainPEM); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) != 2 || !chain[0].Equal(newCA) || !chain[1].Equal(crossSigned) {
+		t.Fatalf("bad: chain of the cross-signed issuer has %d certificates", len(chain))
+	}
+
+	resp = request(logical.UpdateOperation, "issue/new", map[string]interface{}{
+		"common_name": "example.test.com",
+	})
+	if caChain := resp.Data["ca_chain"].([]string); len(caChain) != 2 {
+		t.Fatalf("bad: ca_chain %v", caChain)
+	}
+	leaf := parseCert(resp.Data["certificate"].(string))
+	roots := x509.NewCertPool()
+	roots.AddCert(oldCA)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(crossSigned)
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		t.Fatalf("bad: certificate of the new issuer not verified from the old one: %s", err)
+	}
+
+	// Switching the default issuer moves roles without an issuer_ref and the
+	// legacy endpoints to it
+	requestError(logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default": "missing",
+	})
+	request(logical.UpdateOperation, "intermediate/generate/internal", map[string]interface{}{
+		"common_name": "pending.test.com",
+		"issuer_name": "pending",
+	})
+	requestError(logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default": "pending",
+	})
+	request(logical.UpdateOperation, "config/issuers", map[string]interface{}{
+		"default": "next",
+	})
+
+	issue("old", newCA)
+	caCert, err = x509.ParseCertificate(rawBody("ca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caCert.Equal(newCA) {
+		t.Fatal("bad: ca is not the certificate of the new default issuer")
+	}
+	if !reflect.DeepEqual(crlSerials("crl"), newCRL) {
+		t.Fatal("bad: crl is not the CRL of the new default issuer")
+	}
+
+	// The default issuer cannot be deleted, others can
+	requestError(logical.DeleteOperation, "issuers/next", nil)
+	request(logical.DeleteOperation, "issuers/default", nil)
+	request(logical.DeleteOperation, "issuers/pending", nil)
+
+	resp = request(logical.ListOperation, "issuers/", nil)
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"next"}) {
+		t.Fatalf("bad: issuers %v", keys)
+	}
+	if resp = request(logical.ReadOperation, "issuers/default", nil); resp != nil {
+		t.Fatalf("bad: deleted issuer still readable: %#v", resp)
+	}
+}
@@ -0,0 +1,294 @@
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"golang.org/x/crypto/ocsp"
+)
+
+const defaultOCSPNextUpdate = 12 * time.Hour
+
+// Answers OCSP requests sent with POST, as DER in the request body
+func pathOCSP(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathOCSPRequest,
+		},
+
+		HelpSynopsis:    pathOCSPHelpSyn,
+		HelpDescription: pathOCSPHelpDesc,
+	}
+}
+
+// Answers OCSP requests sent with GET, as base64 DER in the URL
+func pathOCSPGet(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "ocsp/(?P<req>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"req": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The base64 encoded, DER encoded OCSP request",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathOCSPRequest,
+		},
+
+		HelpSynopsis:    pathOCSPHelpSyn,
+		HelpDescription: pathOCSPHelpDesc,
+	}
+}
+
+func (b *backend) pathOCSPRequest(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var der []byte
+	switch req.Operation {
+	case logical.ReadOperation:
+		// The request may still be URL encoded, and is often base64 encoded
+		// with padding stripped
+		encoded := data.Get("req").(string)
+		if unescaped, err := url.PathUnescape(encoded); err == nil {
+			encoded = unescaped
+		}
+		var err error
+		der, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			der, err = base64.RawStdEncoding.DecodeString(encoded)
+		}
+		if err != nil {
+			return ocspResponse(ocsp.MalformedRequestErrorResponse), nil
+		}
+	default:
+		der = req.RawBody
+	}
+
+	ocspReq, err := ocsp.ParseRequest(der)
+	if err != nil {
+		return ocspResponse(ocsp.MalformedRequestErrorResponse), nil
+	}
+
+	caInfo, err := ocspIssuer(req, ocspReq)
+	if err != nil {
+		if _, ok := err.(errutil.UserError); ok {
+			return ocspResponse(ocsp.MalformedRequestErrorResponse), nil
+		}
+		if b.Logger().IsWarn() {
+			b.Logger().Warn("pki: unable to fetch the CA to answer an OCSP request", "error", err)
+		}
+		return ocspResponse(ocsp.InternalErrorErrorResponse), nil
+	}
+
+	// Only certificates issued by the CAs of this mount are answered for
+	if caInfo == nil {
+		return ocspResponse(ocsp.UnauthorizedErrorResponse), nil
+	}
+
+	template, err := b.ocspStatus(req, ocspReq.SerialNumber)
+	if err != nil {
+		if b.Logger().IsWarn() {
+			b.Logger().Warn("pki: unable to fetch the status of a certificate for an OCSP request", "error", err)
+		}
+		return ocspResponse(ocsp.InternalErrorErrorResponse), nil
+	}
+
+	config, err := b.OCSPConfig(req.Storage)
+	if err != nil {
+		return ocspResponse(ocsp.InternalErrorErrorResponse), nil
+	}
+
+	nextUpdate := defaultOCSPNextUpdate
+	responder := caInfo.Certificate
+	signer := caInfo.PrivateKey
+	if config != nil {
+		if nextUpdate, err = time.ParseDuration(config.NextUpdate); err != nil {
+			return ocspResponse(ocsp.InternalErrorErrorResponse), nil
+		}
+
+		// A delegated responder signs the response, and includes its
+		// certificate so that clients can verify it was issued by the CA.
+		// It can only answer for the issuer of its certificate, while that
+		// certificate is valid.
+		if config.ResponderBundle != nil {
+			responderBundle, err := config.ResponderBundle.ToParsedCertBundle()
+			if err != nil {
+				return ocspResponse(ocsp.InternalErrorErrorResponse), nil
+			}
+			if ocspResponderIssuedBy(responderBundle.Certificate, caInfo.Certificate, time.Now()) == nil {
+				responder = responderBundle.Certificate
+				signer = responderBundle.PrivateKey
+				template.Certificate = responder
+			}
+		}
+	}
+
+	now := time.Now()
+	template.ThisUpdate = now
+	template.NextUpdate = now.Add(nextUpdate)
+	template.IssuerHash = ocspReq.HashAlgorithm
+
+	resp, err := ocsp.CreateResponse(caInfo.Certificate, responder, *template, signer)
+	if err != nil {
+		if b.Logger().IsWarn() {
+			b.Logger().Warn("pki: unable to create an OCSP response", "error", err)
+		}
+		return ocspResponse(ocsp.InternalErrorErrorResponse), nil
+	}
+
+	return ocspResponse(resp), nil
+}
+
+// ocspStatus returns the status of the certificate with the given serial
+// number, from the storage of issued and revoked certificates
+func (b *backend) ocspStatus(req *logical.Request, serialNumber *big.Int) (*ocsp.Response, error) {
+	b.revokeStorageLock.RLock()
+	defer b.revokeStorageLock.RUnlock()
+
+	template := &ocsp.Response{
+		Status:       ocsp.Unknown,
+		SerialNumber: serialNumber,
+	}
+	serial := certutil.GetHexFormatted(serialNumber.Bytes(), ":")
+
+	revokedEntry, err := fetchCertBySerial(req, "revoked/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if revokedEntry != nil {
+		var revInfo revocationInfo
+		if err := revokedEntry.DecodeJSON(&revInfo); err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("error decoding revocation entry for serial %s: %s", serial, err)}
+		}
+
+		template.Status = ocsp.Revoked
+		template.RevocationReason = ocsp.Unspecified
+		if !revInfo.RevocationTimeUTC.IsZero() {
+			template.RevokedAt = revInfo.RevocationTimeUTC
+		} else {
+			template.RevokedAt = time.Unix(revInfo.RevocationTime, 0).UTC()
+		}
+	} else {
+		certEntry, err := fetchCertBySerial(req, "certs/", serial)
+		if err != nil {
+			return nil, err
+		}
+		if certEntry != nil {
+			template.Status = ocsp.Good
+		}
+	}
+
+	return template, nil
+}
+
+// ocspIssuer returns the issuer of the mount the request is for, or nil if
+// the certificate was not issued by any of them
+func ocspIssuer(req *logical.Request, ocspReq *ocsp.Request) (*caInfoBundle, error) {
+	issuers, err := listSigningIssuers(req.Storage)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("error fetching list of issuers: %s", err)}
+	}
+
+	for _, issuer := range issuers {
+		caInfo, err := fetchIssuerInfo(req, issuer)
+		if err != nil {
+			return nil, err
+		}
+		issued, err := ocspRequestForIssuer(ocspReq, caInfo.Certificate)
+		if err != nil {
+			return nil, errutil.UserError{Err: err.Error()}
+		}
+		if issued {
+			return caInfo, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ocspRequestForIssuer returns whether the request is for a certificate
+// issued by the given CA, by comparing the hashes of its name and public key
+func ocspRequestForIssuer(req *ocsp.Request, issuer *x509.Certificate) (bool, error) {
+	if req.HashAlgorithm == 0 || !req.HashAlgorithm.Available() {
+		return false, fmt.Errorf("unsupported hash algorithm")
+	}
+
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(hashBytes(req.HashAlgorithm, issuer.RawSubject), req.IssuerNameHash) &&
+		bytes.Equal(hashBytes(req.HashAlgorithm, publicKeyInfo.PublicKey.RightAlign()), req.IssuerKeyHash), nil
+}
+
+// ocspResponderIssuedBy checks that a delegated responder certificate
+// chains directly to the CA, as clients require of responders signing on
+// behalf of a CA (RFC 6960, section 4.2.2.2), and can sign OCSP responses at
+// the given time
+func ocspResponderIssuedBy(responder, ca *x509.Certificate, now time.Time) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	chains, err := responder.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	})
+	if err != nil {
+		return err
+	}
+	for _, chain := range chains {
+		if len(chain) == 2 {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate is not issued directly by the CA")
+}
+
+func hashBytes(hash crypto.Hash, input []byte) []byte {
+	h := hash.New()
+	h.Write(input)
+	return h.Sum(nil)
+}
+
+// ocspResponse returns the raw OCSP response. Errors are reported with
+// unsigned responses holding their status, rather than with HTTP statuses.
+func ocspResponse(body []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/ocsp-response",
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  200,
+		},
+	}
+}
+
+const pathOCSPHelpSyn = `
+Query the revocation status of a certificate with OCSP.
+`
+
+const pathOCSPHelpDesc = `
+This endpoint answers OCSP requests (RFC 6960) for certificates issued by the
+issuers of the mount, from the same storage the CRLs are built from. Requests
+are sent either with POST, as DER with the "application/ocsp-request" content
+type, or with GET, base64 encoded in the URL after "ocsp/".
+
+Responses are signed by the issuer, or by the delegated responder configured
+at "config/ocsp" for the issuer of its certificate.
+`
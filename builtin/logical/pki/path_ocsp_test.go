@@ -0,0 +1,270 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/certutil"
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestBackend_OCSP(t *testing.T) {
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+
+	b := Backend()
+	_, err := b.Setup(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path %s\nerr: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	postOCSP := func(der []byte) *logical.Response {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "ocsp",
+			Storage:   storage,
+			RawBody:   der,
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+		}
+		return resp
+	}
+
+	parseCert := func(pemCert string) *x509.Certificate {
+		block, _ := pem.Decode([]byte(pemCert))
+		if block == nil {
+			t.Fatal("failed to decode certificate")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	resp := request(logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "test.com",
+		"ttl":         "6h",
+	})
+	caCert := parseCert(resp.Data["certificate"].(string))
+
+	request(logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"allowed_domains":  "test.com",
+		"allow_subdomains": true,
+		"max_ttl":          "4h",
+	})
+
+	issue := func(role string) (*x509.Certificate, map[string]interface{}) {
+		resp := request(logical.UpdateOperation, "issue/"+role, map[string]interface{}{
+			"common_name": "example.test.com",
+		})
+		return parseCert(resp.Data["certificate"].(string)), resp.Data
+	}
+
+	good, _ := issue("test")
+	revoked, revokedData := issue("test")
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": revokedData["serial_number"],
+	})
+
+	// Checks the status returned for the certificate with the given serial
+	// number, with both POST and GET requests
+	checkStatus := func(serialNumber *big.Int, expected int, responder *x509.Certificate) {
+		der, err := ocsp.CreateRequest(&x509.Certificate{SerialNumber: serialNumber}, caCert, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		posted := postOCSP(der)
+		got := request(logical.ReadOperation, "ocsp/"+url.PathEscape(base64.StdEncoding.EncodeToString(der)), nil)
+
+		for _, resp := range []*logical.Response{posted, got} {
+			if resp.Data[logical.HTTPContentType] != "application/ocsp-response" {
+				t.Fatalf("bad: %#v", resp.Data)
+			}
+			parsed, err := ocsp.ParseResponse(resp.Data[logical.HTTPRawBody].([]byte), caCert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Status != expected {
+				t.Fatalf("bad: serial %s: expected status %d, got %d", serialNumber, expected, parsed.Status)
+			}
+			if parsed.SerialNumber.Cmp(serialNumber) != 0 {
+				t.Fatalf("bad: expected serial %s, got %s", serialNumber, parsed.SerialNumber)
+			}
+			if responder == nil && parsed.Certificate != nil {
+				t.Fatal("bad: response signed by the CA includes a certificate")
+			}
+			if responder != nil && (parsed.Certificate == nil || !parsed.Certificate.Equal(responder)) {
+				t.Fatal("bad: response not signed by the delegated responder")
+			}
+		}
+	}
+
+	checkStatus(good.SerialNumber, ocsp.Good, nil)
+	checkStatus(revoked.SerialNumber, ocsp.Revoked, nil)
+	checkStatus(big.NewInt(42), ocsp.Unknown, nil)
+
+	// Malformed requests are answered with an unsigned error response
+	resp = postOCSP([]byte("not a request"))
+	body := resp.Data[logical.HTTPRawBody].([]byte)
+	if string(body) != string(ocsp.MalformedRequestErrorResponse) {
+		t.Fatalf("bad: %#v", body)
+	}
+
+	// Only certificates flagged for OCSP signing can be delegated responders
+	_, notResponder := issue("test")
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/ocsp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"pem_bundle": strings.Join([]string{notResponder["private_key"].(string), notResponder["certificate"].(string)}, "\n"),
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error configuring a responder not flagged for OCSP signing\nerr: %v\nresp: %#v", err, resp)
+	}
+
+	request(logical.UpdateOperation, "roles/responder", map[string]interface{}{
+		"allowed_domains":   "test.com",
+		"allow_subdomains":  true,
+		"max_ttl":           "4h",
+		"server_flag":       false,
+		"client_flag":       false,
+		"ocsp_signing_flag": true,
+	})
+	responderCert, responderData := issue("responder")
+	request(logical.UpdateOperation, "config/ocsp", map[string]interface{}{
+		"pem_bundle":  strings.Join([]string{responderData["private_key"].(string), responderData["certificate"].(string)}, "\n"),
+		"next_update": "1h",
+	})
+
+	resp = request(logical.ReadOperation, "config/ocsp", nil)
+	if resp.Data["next_update"] != "1h" || resp.Data["certificate"] != responderData["certificate"] {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	checkStatus(good.SerialNumber, ocsp.Good, responderCert)
+	checkStatus(revoked.SerialNumber, ocsp.Revoked, responderCert)
+}
+
+func TestBackend_OCSPResponderChain(t *testing.T) {
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+
+	b := Backend()
+	_, err := b.Setup(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "root/generate/exported",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"common_name": "test.com",
+			"ttl":         "6h",
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+	caBundle, err := certutil.ParsePEMBundle(resp.Data["certificate"].(string) + "\n" + resp.Data["private_key"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Certificates signed with the key of the CA, but not chaining to it
+	issue := func(template *x509.Certificate) string {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parent := *caBundle.Certificate
+		template.SerialNumber = big.NewInt(42)
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}
+		template.Subject = pkix.Name{CommonName: "responder.test.com"}
+		if template.NotBefore.IsZero() {
+			template.NotBefore = time.Now().Add(-time.Minute)
+			template.NotAfter = time.Now().Add(time.Hour)
+		}
+		if template.Issuer.CommonName != "" {
+			parent.Subject = template.Issuer
+			parent.RawSubject = nil
+		}
+		certDER, err := x509.CreateCertificate(rand.Reader, template, &parent, key.Public(), caBundle.PrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})) +
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+	}
+
+	cases := map[string]*x509.Certificate{
+		"other issuer": &x509.Certificate{Issuer: pkix.Name{CommonName: "other.com"}},
+		"expired": &x509.Certificate{
+			NotBefore: time.Now().Add(-2 * time.Hour),
+			NotAfter:  time.Now().Add(-time.Hour),
+		},
+	}
+	for name, template := range cases {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/ocsp",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"pem_bundle": issue(template),
+			},
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected error configuring the responder\nerr: %v\nresp: %#v", name, err, resp)
+		}
+	}
+
+	// The same certificate chaining to the CA is accepted
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/ocsp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"pem_bundle": issue(&x509.Certificate{}),
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: err: %v\nresp: %#v", err, resp)
+	}
+}
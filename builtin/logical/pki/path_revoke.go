@@ -59,8 +59,10 @@ func (b *backend) pathRevokeWrite(req *logical.Request, data *framework.FieldDat
 }
 
 func (b *backend) pathRotateCRLRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	b.revokeStorageLock.RLock()
-	defer b.revokeStorageLock.RUnlock()
+	// Building the CRLs advances their numbers and clears the pending
+	// revocations, so this excludes revocations and other builds
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
 
 	crlErr := buildCRL(b, req)
 	switch crlErr.(type) {
@@ -90,5 +92,5 @@ Force a rebuild of the CRL.
 `
 
 const pathRotateCRLHelpDesc = `
-Force a rebuild of the CRL. This can be used to remove expired certificates from it if no certificates have been revoked. A root token is required.
+Force a rebuild of the CRL. This can be used to remove expired certificates from it if no certificates have been revoked, or to list revocations at once when the CRL is rebuilt automatically. A root token is required.
 `
@@ -26,7 +26,7 @@ func pathListRoles(b *backend) *framework.Path {
 }
 
 func pathRoles(b *backend) *framework.Path {
-	return &framework.Path{
+	ret := &framework.Path{
 		Pattern: "roles/" + framework.GenericNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": &framework.FieldSchema{
@@ -134,6 +134,13 @@ use. Defaults to false.`,
 protection use. Defaults to false.`,
 			},
 
+			"ocsp_signing_flag": &framework.FieldSchema{
+				Type:    framework.TypeBool,
+				Default: false,
+				Description: `If set, certificates are flagged for OCSP
+signing use, as needed by delegated OCSP responders. Defaults to false.`,
+			},
+
 			"key_type": &framework.FieldSchema{
 				Type:    framework.TypeString,
 				Default: "rsa",
@@ -191,6 +198,14 @@ this value in certificates issued by this role.`,
 this value in certificates issued by this role.`,
 			},
 
+			"issuer_ref": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "",
+				Description: `The name of the issuer certificates are issued
+and signed with by this role. If not set, the
+default issuer of the mount is used.`,
+			},
+
 			"generate_lease": &framework.FieldSchema{
 				Type:    framework.TypeBool,
 				Default: false,
@@ -215,6 +230,10 @@ leases adversely affect the startup time of Vault.`,
 		HelpSynopsis:    pathRoleHelpSyn,
 		HelpDescription: pathRoleHelpDesc,
 	}
+
+	ret.Fields = addProfileFields(ret.Fields)
+
+	return ret
 }
 
 func (b *backend) getRole(s logical.Storage, n string) (*roleEntry, error) {
@@ -376,6 +395,7 @@ func (b *backend) pathRoleCreate(
 		ClientFlag:          data.Get("client_flag").(bool),
 		CodeSigningFlag:     data.Get("code_signing_flag").(bool),
 		EmailProtectionFlag: data.Get("email_protection_flag").(bool),
+		OCSPSigningFlag:     data.Get("ocsp_signing_flag").(bool),
 		KeyType:             data.Get("key_type").(string),
 		KeyBits:             data.Get("key_bits").(int),
 		UseCSRCommonName:    data.Get("use_csr_common_name").(bool),
@@ -383,6 +403,14 @@ func (b *backend) pathRoleCreate(
 		KeyUsage:            data.Get("key_usage").(string),
 		OU:                  data.Get("ou").(string),
 		Organization:        data.Get("organization").(string),
+		Country:             data.Get("country").(string),
+		Province:            data.Get("province").(string),
+		Locality:            data.Get("locality").(string),
+		StreetAddress:       data.Get("street_address").(string),
+		PostalCode:          data.Get("postal_code").(string),
+		PolicyIdentifiers:   data.Get("policy_identifiers").(string),
+		ExtKeyUsageOIDs:     data.Get("ext_key_usage_oids").(string),
+		IssuerRef:           data.Get("issuer_ref").(string),
 		GenerateLease:       new(bool),
 	}
 
@@ -435,6 +463,20 @@ func (b *backend) pathRoleCreate(
 		return errResp, nil
 	}
 
+	if err := validateRoleProfile(entry); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	if entry.IssuerRef != "" {
+		issuer, err := fetchIssuerBundle(req.Storage, entry.IssuerRef)
+		if err != nil {
+			return nil, err
+		}
+		if issuer == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown issuer %s", entry.IssuerRef)), nil
+		}
+	}
+
 	// Store it
 	jsonEntry, err := logical.StorageEntryJSON("role/"+name, entry)
 	if err != nil {
@@ -495,6 +537,7 @@ type roleEntry struct {
 	ClientFlag            bool   `json:"client_flag" structs:"client_flag" mapstructure:"client_flag"`
 	CodeSigningFlag       bool   `json:"code_signing_flag" structs:"code_signing_flag" mapstructure:"code_signing_flag"`
 	EmailProtectionFlag   bool   `json:"email_protection_flag" structs:"email_protection_flag" mapstructure:"email_protection_flag"`
+	OCSPSigningFlag       bool   `json:"ocsp_signing_flag" structs:"ocsp_signing_flag" mapstructure:"ocsp_signing_flag"`
 	UseCSRCommonName      bool   `json:"use_csr_common_name" structs:"use_csr_common_name" mapstructure:"use_csr_common_name"`
 	UseCSRSANs            bool   `json:"use_csr_sans" structs:"use_csr_sans" mapstructure:"use_csr_sans"`
 	KeyType               string `json:"key_type" structs:"key_type" mapstructure:"key_type"`
@@ -503,6 +546,14 @@ type roleEntry struct {
 	KeyUsage              string `json:"key_usage" structs:"key_usage" mapstructure:"key_usage"`
 	OU                    string `json:"ou" structs:"ou" mapstructure:"ou"`
 	Organization          string `json:"organization" structs:"organization" mapstructure:"organization"`
+	Country               string `json:"country" structs:"country" mapstructure:"country"`
+	Province              string `json:"province" structs:"province" mapstructure:"province"`
+	Locality              string `json:"locality" structs:"locality" mapstructure:"locality"`
+	StreetAddress         string `json:"street_address" structs:"street_address" mapstructure:"street_address"`
+	PostalCode            string `json:"postal_code" structs:"postal_code" mapstructure:"postal_code"`
+	PolicyIdentifiers     string `json:"policy_identifiers" structs:"policy_identifiers" mapstructure:"policy_identifiers"`
+	ExtKeyUsageOIDs       string `json:"ext_key_usage_oids" structs:"ext_key_usage_oids" mapstructure:"ext_key_usage_oids"`
+	IssuerRef             string `json:"issuer_ref" structs:"issuer_ref" mapstructure:"issuer_ref"`
 	GenerateLease         *bool  `json:"generate_lease,omitempty" structs:"generate_lease,omitempty"`
 }
 
@@ -1,6 +1,13 @@
 package pki
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
@@ -204,3 +211,170 @@ func TestPki_CertsLease(t *testing.T) {
 		t.Fatalf("expected a response that contains a secret")
 	}
 }
+
+func TestPki_RoleProfile(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	rawRequest := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+
+	request := func(path string, data map[string]interface{}) *logical.Response {
+		resp, err := rawRequest(path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path %s\nerr: %v\nresp: %#v", path, err, resp)
+		}
+		return resp
+	}
+
+	requestError := func(path string, data map[string]interface{}) {
+		resp, err := rawRequest(path, data)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected an error for path %s, got: %#v", path, resp)
+		}
+	}
+
+	parseCert := func(resp *logical.Response) *x509.Certificate {
+		block, _ := pem.Decode([]byte(resp.Data["certificate"].(string)))
+		if block == nil {
+			t.Fatal("failed to decode certificate")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	request("root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+		"ttl":         "6h",
+	})
+
+	// Invalid countries and OIDs are refused when the role is written
+	for _, data := range []map[string]interface{}{
+		{"country": "USA"},
+		{"policy_identifiers": "1"},
+		{"policy_identifiers": "1.2.foo"},
+		{"ext_key_usage_oids": "3.1"},
+		{"ext_key_usage_oids": "1.40"},
+	} {
+		data["allowed_domains"] = "myvault.com"
+		requestError("roles/bad", data)
+	}
+
+	request("roles/profile", map[string]interface{}{
+		"allowed_domains":    "myvault.com",
+		"allow_subdomains":   true,
+		"max_ttl":            "4h",
+		"organization":       "Example",
+		"country":            "us",
+		"province":           "Nebraska",
+		"locality":           "Omaha",
+		"street_address":     "1 Main Street",
+		"postal_code":        "68102",
+		"policy_identifiers": "2.23.140.1.2.2, 1.3.6.1.4.1.44947.1.1.1",
+		"ext_key_usage_oids": "1.3.6.1.4.1.311.20.2.2",
+	})
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/profile",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("bad: err: %v resp: %#v", err, resp)
+	}
+	if resp.Data["country"] != "us" || resp.Data["policy_identifiers"] != "2.23.140.1.2.2, 1.3.6.1.4.1.44947.1.1.1" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	checkProfile := func(cert *x509.Certificate) {
+		subject := cert.Subject
+		if !reflect.DeepEqual(subject.Country, []string{"US"}) ||
+			!reflect.DeepEqual(subject.Province, []string{"Nebraska"}) ||
+			!reflect.DeepEqual(subject.Locality, []string{"Omaha"}) ||
+			!reflect.DeepEqual(subject.StreetAddress, []string{"1 Main Street"}) ||
+			!reflect.DeepEqual(subject.PostalCode, []string{"68102"}) {
+			t.Fatalf("bad: subject %#v", subject)
+		}
+
+		policies := []asn1.ObjectIdentifier{
+			{1, 3, 6, 1, 4, 1, 44947, 1, 1, 1},
+			{2, 23, 140, 1, 2, 2},
+		}
+		if !reflect.DeepEqual(cert.PolicyIdentifiers, policies) {
+			t.Fatalf("bad: policies %v", cert.PolicyIdentifiers)
+		}
+
+		if !reflect.DeepEqual(cert.UnknownExtKeyUsage, []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}}) {
+			t.Fatalf("bad: extended key usage OIDs %v", cert.UnknownExtKeyUsage)
+		}
+		if !reflect.DeepEqual(cert.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}) {
+			t.Fatalf("bad: extended key usages %v", cert.ExtKeyUsage)
+		}
+	}
+
+	checkProfile(parseCert(request("issue/profile", map[string]interface{}{
+		"common_name": "foo.myvault.com",
+	})))
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "bar.myvault.com"},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE REQUEST",
+		Bytes: csrBytes,
+	}))
+
+	checkProfile(parseCert(request("sign/profile", map[string]interface{}{
+		"csr":         csr,
+		"common_name": "bar.myvault.com",
+	})))
+
+	// Signed intermediates take the profile and name constraints from the
+	// request
+	requestError("root/sign-intermediate", map[string]interface{}{
+		"csr":                   csr,
+		"permitted_dns_domains": "not a domain",
+	})
+	requestError("root/sign-intermediate", map[string]interface{}{
+		"csr":                csr,
+		"excluded_ip_ranges": "10.0.0.0",
+	})
+
+	intermediate := parseCert(request("root/sign-intermediate", map[string]interface{}{
+		"csr":                   csr,
+		"ttl":                   "1h",
+		"country":               "US",
+		"policy_identifiers":    "2.23.140.1.2.2",
+		"permitted_dns_domains": ".myvault.com",
+		"excluded_dns_domains":  "internal.myvault.com",
+		"permitted_ip_ranges":   "10.0.0.0/8",
+	}))
+	if !reflect.DeepEqual(intermediate.Subject.Country, []string{"US"}) {
+		t.Fatalf("bad: subject %#v", intermediate.Subject)
+	}
+	if !reflect.DeepEqual(intermediate.PolicyIdentifiers, []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 2}}) {
+		t.Fatalf("bad: policies %v", intermediate.PolicyIdentifiers)
+	}
+	if !intermediate.PermittedDNSDomainsCritical ||
+		!reflect.DeepEqual(intermediate.PermittedDNSDomains, []string{".myvault.com"}) ||
+		!reflect.DeepEqual(intermediate.ExcludedDNSDomains, []string{"internal.myvault.com"}) ||
+		len(intermediate.PermittedIPRanges) != 1 || intermediate.PermittedIPRanges[0].String() != "10.0.0.0/8" {
+		t.Fatalf("bad: name constraints %v %v %v", intermediate.PermittedDNSDomains,
+			intermediate.ExcludedDNSDomains, intermediate.PermittedIPRanges)
+	}
+}
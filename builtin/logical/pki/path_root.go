@@ -24,6 +24,7 @@ func pathGenerateRoot(b *backend) *framework.Path {
 	ret.Fields = addCACommonFields(map[string]*framework.FieldSchema{})
 	ret.Fields = addCAKeyGenerationFields(ret.Fields)
 	ret.Fields = addCAIssueFields(ret.Fields)
+	ret.Fields = addIssuerNameFields(ret.Fields)
 
 	return ret
 }
@@ -63,6 +64,29 @@ certs signed by this path; for instance,
 the non-repudiation flag.`,
 	}
 
+	ret.Fields["issuer_ref"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `The name of the issuer signing the certificate.
+Defaults to the default issuer.`,
+	}
+
+	ret.Fields["ou"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `If set, the OU (OrganizationalUnit) will be set to
+this value in the signed certificate.`,
+	}
+
+	ret.Fields["organization"] = &framework.FieldSchema{
+		Type:    framework.TypeString,
+		Default: "",
+		Description: `If set, the O (Organization) will be set to
+this value in the signed certificate.`,
+	}
+
+	ret.Fields = addProfileFields(ret.Fields)
+	ret.Fields = addCANameConstraintFields(ret.Fields)
+
 	return ret
 }
 
@@ -75,6 +99,11 @@ func (b *backend) pathCAGenerateRoot(
 		return errorResp, nil
 	}
 
+	issuerName := data.Get("issuer_name").(string)
+	if !validIssuerName(issuerName) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid issuer name %q", issuerName)), nil
+	}
+
 	maxPathLengthIface, ok := data.GetOk("max_path_length")
 	if ok {
 		maxPathLength := maxPathLengthIface.(int)
@@ -132,12 +161,8 @@ func (b *backend) pathCAGenerateRoot(
 		}
 	}
 
-	// Store it as the CA bundle
-	entry, err := logical.StorageEntryJSON("config/ca_bundle", cb)
-	if err != nil {
-		return nil, err
-	}
-	err = req.Storage.Put(entry)
+	// Store it as the CA bundle of the issuer
+	err = storeIssuerBundle(req.Storage, issuerName, cb, parsedBundle.CertificateBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -152,15 +177,6 @@ func (b *backend) pathCAGenerateRoot(
 		return nil, fmt.Errorf("Unable to store certificate locally: %v", err)
 	}
 
-	// For ease of later use, also store just the certificate at a known
-	// location
-	entry.Key = "ca"
-	entry.Value = parsedBundle.CertificateBytes
-	err = req.Storage.Put(entry)
-	if err != nil {
-		return nil, err
-	}
-
 	// Build a fresh CRL
 	err = buildCRL(b, req)
 	if err != nil {
@@ -186,12 +202,21 @@ func (b *backend) pathCASignIntermediate(
 	}
 
 	role := &roleEntry{
-		TTL:              data.Get("ttl").(string),
-		AllowLocalhost:   true,
-		AllowAnyName:     true,
-		AllowIPSANs:      true,
-		EnforceHostnames: false,
-		KeyType:          "any",
+		TTL:               data.Get("ttl").(string),
+		AllowLocalhost:    true,
+		AllowAnyName:      true,
+		AllowIPSANs:       true,
+		EnforceHostnames:  false,
+		KeyType:           "any",
+		OU:                data.Get("ou").(string),
+		Organization:      data.Get("organization").(string),
+		Country:           data.Get("country").(string),
+		Province:          data.Get("province").(string),
+		Locality:          data.Get("locality").(string),
+		StreetAddress:     data.Get("street_address").(string),
+		PostalCode:        data.Get("postal_code").(string),
+		PolicyIdentifiers: data.Get("policy_identifiers").(string),
+		ExtKeyUsageOIDs:   data.Get("ext_key_usage_oids").(string),
 	}
 
 	if cn := data.Get("common_name").(string); len(cn) == 0 {
@@ -199,7 +224,7 @@ func (b *backend) pathCASignIntermediate(
 	}
 
 	var caErr error
-	signingBundle, caErr := fetchCAInfo(req)
+	signingBundle, caErr := fetchIssuerInfo(req, data.Get("issuer_ref").(string))
 	switch caErr.(type) {
 	case errutil.UserError:
 		return nil, errutil.UserError{Err: fmt.Sprintf(
@@ -0,0 +1,20 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	bplugin "github.com/hashicorp/vault/logical/plugin"
+)
+
+// Factory creates a backend served by the external plugin registered in the
+// plugin catalog under the "plugin_name" config value. The same factory is
+// used for both secret and credential backends.
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	name, ok := conf.Config["plugin_name"]
+	if !ok || name == "" {
+		return nil, fmt.Errorf("plugin_name not provided")
+	}
+
+	return bplugin.NewBackend(name, conf.System, conf)
+}
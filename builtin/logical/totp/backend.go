@@ -0,0 +1,51 @@
+package totp
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	cache "github.com/patrickmn/go-cache"
+)
+
+// Factory creates and configures the backend
+func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
+	return Backend().Setup(conf)
+}
+
+// Backend creates a new backend with all the paths belonging to it
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		Paths: []*framework.Path{
+			pathListKeys(&b),
+			pathKeys(&b),
+			pathCode(&b),
+		},
+	}
+
+	b.usedCodes = cache.New(0, 30*time.Second)
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// usedCodes holds the codes validated recently, so that each code can
+	// only be validated once
+	usedCodes *cache.Cache
+}
+
+const backendHelp = `
+The TOTP backend stores the shared keys of time-based one-time passwords,
+generating codes for them or validating the codes of users.
+
+Keys are either generated by the backend, which returns an otpauth:// URL
+and a QR code to enroll the key in an authenticator application, or
+imported from an otpauth:// URL or a base32 encoded key. Codes are read
+from and validated against the "code/" path.
+`
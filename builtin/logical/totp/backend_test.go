@@ -0,0 +1,205 @@
+package totp
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+func testBackend(t *testing.T) (*backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend()
+	if _, err := b.Setup(config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr:%v", resp, err)
+	}
+	return resp
+}
+
+func testRequestError(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) {
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected error: resp: %#v", resp)
+	}
+}
+
+func TestBackend_generatedKey(t *testing.T) {
+	b, s := testBackend(t)
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"generate":     true,
+		"issuer":       "Vault",
+		"account_name": "test@example.com",
+	})
+	if resp == nil || resp.Data["url"] == nil || resp.Data["barcode"] == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if _, err := base64.StdEncoding.DecodeString(resp.Data["barcode"].(string)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	key, err := otplib.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if key.Issuer() != "Vault" || key.AccountName() != "test@example.com" {
+		t.Fatalf("bad: %s", key)
+	}
+
+	resp = testRequest(t, b, s, logical.ReadOperation, "keys/test", nil)
+	expected := map[string]interface{}{
+		"issuer":       "Vault",
+		"account_name": "test@example.com",
+		"period":       int64(30),
+		"algorithm":    "SHA1",
+		"digits":       6,
+		"skew":         uint(1),
+	}
+	for k, v := range expected {
+		if resp.Data[k] != v {
+			t.Fatalf("bad: %s: expected %#v, got %#v", k, v, resp.Data[k])
+		}
+	}
+	if _, ok := resp.Data["key"]; ok {
+		t.Fatal("key was returned")
+	}
+
+	resp = testRequest(t, b, s, logical.ListOperation, "keys/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "test" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.ReadOperation, "code/test", nil)
+	code := resp.Data["code"].(string)
+	if code != totpCode(t, key.Secret(), totplib.ValidateOpts{}) {
+		t.Fatalf("bad: %s", code)
+	}
+
+	// Codes validate once
+	resp = testRequest(t, b, s, logical.UpdateOperation, "code/test", map[string]interface{}{"code": code})
+	if resp.Data["valid"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	testRequestError(t, b, s, logical.UpdateOperation, "code/test", map[string]interface{}{"code": code})
+
+	for _, invalid := range []string{"000000000", "abc"} {
+		resp = testRequest(t, b, s, logical.UpdateOperation, "code/test", map[string]interface{}{"code": invalid})
+		if resp.Data["valid"] != false {
+			t.Fatalf("%s: bad: %#v", invalid, resp.Data)
+		}
+	}
+
+	testRequest(t, b, s, logical.DeleteOperation, "keys/test", nil)
+	if resp := testRequest(t, b, s, logical.ReadOperation, "keys/test", nil); resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	testRequestError(t, b, s, logical.ReadOperation, "code/test", nil)
+}
+
+func TestBackend_generatedKeyNotExported(t *testing.T) {
+	b, s := testBackend(t)
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"generate":     true,
+		"exported":     false,
+		"issuer":       "Vault",
+		"account_name": "test@example.com",
+	})
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+	testRequest(t, b, s, logical.ReadOperation, "code/test", nil)
+}
+
+func TestBackend_importedKey(t *testing.T) {
+	b, s := testBackend(t)
+
+	secret := "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ"
+	testRequest(t, b, s, logical.UpdateOperation, "keys/url", map[string]interface{}{
+		"url": "otpauth://totp/Vault:test@example.com?secret=" + secret + "&algorithm=SHA512&digits=8&period=60",
+	})
+	resp := testRequest(t, b, s, logical.ReadOperation, "keys/url", nil)
+	if resp.Data["issuer"] != "Vault" || resp.Data["account_name"] != "test@example.com" ||
+		resp.Data["algorithm"] != "SHA512" || resp.Data["digits"] != 8 || resp.Data["period"] != int64(60) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = testRequest(t, b, s, logical.ReadOperation, "code/url", nil)
+	opts := totplib.ValidateOpts{Period: 60, Digits: otplib.DigitsEight, Algorithm: otplib.AlgorithmSHA512}
+	if resp.Data["code"] != totpCode(t, secret, opts) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Keys are accepted in lower case and without padding
+	testRequest(t, b, s, logical.UpdateOperation, "keys/key", map[string]interface{}{
+		"key":       "jbswy3dpehpk3pxpjbswy3dpeh",
+		"algorithm": "SHA256",
+		"skew":      0,
+	})
+	resp = testRequest(t, b, s, logical.ReadOperation, "code/key", nil)
+	opts = totplib.ValidateOpts{Algorithm: otplib.AlgorithmSHA256}
+	if resp.Data["code"] != totpCode(t, "JBSWY3DPEHPK3PXPJBSWY3DPEH======", opts) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestBackend_invalidKeys(t *testing.T) {
+	b, s := testBackend(t)
+
+	cases := map[string]map[string]interface{}{
+		"no key":          {},
+		"bad key":         {"key": "1!"},
+		"bad url":         {"url": "https://example.com"},
+		"hotp url":        {"url": "otpauth://hotp/Vault:test?secret=JBSWY3DPEHPK3PXP"},
+		"bad algorithm":   {"key": "JBSWY3DPEHPK3PXP", "algorithm": "MD5"},
+		"bad digits":      {"key": "JBSWY3DPEHPK3PXP", "digits": 7},
+		"bad skew":        {"key": "JBSWY3DPEHPK3PXP", "skew": 2},
+		"bad period":      {"key": "JBSWY3DPEHPK3PXP", "period": -1},
+		"no issuer":       {"generate": true, "account_name": "test"},
+		"no account name": {"generate": true, "issuer": "Vault"},
+		"generated url":   {"generate": true, "issuer": "Vault", "account_name": "test", "url": "otpauth://totp/a?secret=JBSWY3DPEHPK3PXP"},
+		"generated key":   {"generate": true, "issuer": "Vault", "account_name": "test", "key": "JBSWY3DPEHPK3PXP"},
+	}
+	for name, data := range cases {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "keys/test",
+			Storage:   s,
+			Data:      data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("%s: expected error: resp: %#v", name, resp)
+		}
+	}
+}
+
+func totpCode(t *testing.T, secret string, opts totplib.ValidateOpts) string {
+	if opts.Digits == 0 {
+		opts.Digits = otplib.DigitsSix
+	}
+	code, err := totplib.GenerateCodeCustom(secret, time.Now(), opts)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return code
+}
@@ -0,0 +1,119 @@
+package totp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+func pathCode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "code/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key.",
+			},
+
+			"code": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The code to validate.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathCodeRead,
+			logical.UpdateOperation: b.pathCodeValidate,
+		},
+
+		HelpSynopsis:    pathCodeHelpSyn,
+		HelpDescription: pathCodeHelpDesc,
+	}
+}
+
+func (k *keyEntry) validateOpts() totplib.ValidateOpts {
+	return totplib.ValidateOpts{
+		Period:    uint(k.Period.Seconds()),
+		Skew:      k.Skew,
+		Digits:    k.Digits,
+		Algorithm: k.Algorithm,
+	}
+}
+
+func (b *backend) pathCodeRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	key, err := b.Key(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
+	}
+
+	code, err := totplib.GenerateCodeCustom(key.Key, time.Now(), key.validateOpts())
+	if err != nil {
+		return nil, fmt.Errorf("error generating code: %v", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"code": code,
+		},
+	}, nil
+}
+
+func (b *backend) pathCodeValidate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	code := d.Get("code").(string)
+	if code == "" {
+		return logical.ErrorResponse("missing code"), nil
+	}
+
+	key, err := b.Key(req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
+	}
+
+	usedName := fmt.Sprintf("%s_%s", name, code)
+	if _, ok := b.usedCodes.Get(usedName); ok {
+		return logical.ErrorResponse("code already used; wait until the next time period"), nil
+	}
+
+	valid, err := totplib.ValidateCustom(code, key.Key, time.Now(), key.validateOpts())
+	if err != nil && err != otplib.ErrValidateInputInvalidLength {
+		return nil, fmt.Errorf("error validating code: %v", err)
+	}
+
+	// A code stays acceptable for the whole skew window, so it is
+	// remembered for that long
+	if valid {
+		window := time.Duration(2*key.Skew+1) * key.Period
+		if err := b.usedCodes.Add(usedName, nil, window); err != nil {
+			return logical.ErrorResponse("code already used; wait until the next time period"), nil
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"valid": valid,
+		},
+	}, nil
+}
+
+const pathCodeHelpSyn = `
+Generate or validate a code.
+`
+
+const pathCodeHelpDesc = `
+Reading this path generates the current code of the key. Writing the "code"
+parameter to it validates the code, returning "valid". A code can only be
+validated once.
+`
@@ -0,0 +1,371 @@
+package totp
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+func pathListKeys(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathKeyList,
+		},
+
+		HelpSynopsis:    pathKeyHelpSyn,
+		HelpDescription: pathKeyHelpDesc,
+	}
+}
+
+func pathKeys(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key.",
+			},
+
+			"generate": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Default:     false,
+				Description: "Whether the key is generated by the backend rather than imported.",
+			},
+
+			"exported": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Default:     true,
+				Description: "Whether a generated key is returned as a URL and QR code.",
+			},
+
+			"key_size": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     20,
+				Description: "Size in bytes of a generated key.",
+			},
+
+			"url": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "An otpauth:// URL to import the key and its settings from.",
+			},
+
+			"key": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The base32 encoded key to import.",
+			},
+
+			"issuer": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The name of the issuer of the key.",
+			},
+
+			"account_name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The name of the account the key belongs to.",
+			},
+
+			"period": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     30,
+				Description: "How long each code is valid for.",
+			},
+
+			"algorithm": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "SHA1",
+				Description: `The hash algorithm: "SHA1", "SHA256" or "SHA512".`,
+			},
+
+			"digits": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     6,
+				Description: "The number of digits of the codes: 6 or 8.",
+			},
+
+			"skew": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     1,
+				Description: "The number of periods before and after the current one codes are accepted for: 0 or 1.",
+			},
+
+			"qr_size": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     200,
+				Description: "The width and height in pixels of the QR code of a generated key. 0 disables it.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathKeyRead,
+			logical.UpdateOperation: b.pathKeyCreate,
+			logical.DeleteOperation: b.pathKeyDelete,
+		},
+
+		HelpSynopsis:    pathKeyHelpSyn,
+		HelpDescription: pathKeyHelpDesc,
+	}
+}
+
+// Key reads a key from the storage
+func (b *backend) Key(s logical.Storage, n string) (*keyEntry, error) {
+	entry, err := s.Get("key/" + n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result keyEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathKeyDelete(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete("key/" + d.Get("name").(string))
+}
+
+func (b *backend) pathKeyRead(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key, err := b.Key(req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	// The key itself is never returned
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer":       key.Issuer,
+			"account_name": key.AccountName,
+			"period":       int64(key.Period.Seconds()),
+			"algorithm":    key.Algorithm.String(),
+			"digits":       int(key.Digits),
+			"skew":         key.Skew,
+		},
+	}, nil
+}
+
+func (b *backend) pathKeyList(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	keys, err := req.Storage.List("key/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
+func (b *backend) pathKeyCreate(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	generate := d.Get("generate").(bool)
+	exported := d.Get("exported").(bool)
+	keySize := d.Get("key_size").(int)
+	keyURL := d.Get("url").(string)
+	keyString := d.Get("key").(string)
+	issuer := d.Get("issuer").(string)
+	accountName := d.Get("account_name").(string)
+	period := time.Duration(d.Get("period").(int)) * time.Second
+	algorithm := d.Get("algorithm").(string)
+	digits := d.Get("digits").(int)
+	skew := d.Get("skew").(int)
+	qrSize := d.Get("qr_size").(int)
+
+	// Imported URLs carry their own settings, which take precedence
+	if keyURL != "" {
+		if generate {
+			return logical.ErrorResponse("a url cannot be given when generating a key"), nil
+		}
+
+		u, err := url.Parse(keyURL)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing url: %s", err)), nil
+		}
+		if u.Scheme != "otpauth" || u.Host != "totp" {
+			return logical.ErrorResponse("url must be an otpauth://totp/ URL"), nil
+		}
+
+		otpKey, err := otplib.NewKeyFromURL(keyURL)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing url: %s", err)), nil
+		}
+		keyString = otpKey.Secret()
+		issuer = otpKey.Issuer()
+		accountName = otpKey.AccountName()
+
+		q := u.Query()
+		if v := q.Get("algorithm"); v != "" {
+			algorithm = v
+		}
+		if v := q.Get("digits"); v != "" {
+			if digits, err = strconv.Atoi(v); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid digits in url: %q", v)), nil
+			}
+		}
+		if v := q.Get("period"); v != "" {
+			p, err := strconv.Atoi(v)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid period in url: %q", v)), nil
+			}
+			period = time.Duration(p) * time.Second
+		}
+	}
+
+	var keyAlgorithm otplib.Algorithm
+	switch strings.ToUpper(algorithm) {
+	case "SHA1":
+		keyAlgorithm = otplib.AlgorithmSHA1
+	case "SHA256":
+		keyAlgorithm = otplib.AlgorithmSHA256
+	case "SHA512":
+		keyAlgorithm = otplib.AlgorithmSHA512
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %q", algorithm)), nil
+	}
+
+	var keyDigits otplib.Digits
+	switch digits {
+	case 6:
+		keyDigits = otplib.DigitsSix
+	case 8:
+		keyDigits = otplib.DigitsEight
+	default:
+		return logical.ErrorResponse("digits must be 6 or 8"), nil
+	}
+
+	switch {
+	case period <= 0:
+		return logical.ErrorResponse("period must be positive"), nil
+	case skew != 0 && skew != 1:
+		return logical.ErrorResponse("skew must be 0 or 1"), nil
+	case qrSize < 0:
+		return logical.ErrorResponse("qr_size cannot be negative"), nil
+	}
+
+	var resp *logical.Response
+	if generate {
+		switch {
+		case keyString != "":
+			return logical.ErrorResponse("a key cannot be given when generating a key"), nil
+		case issuer == "":
+			return logical.ErrorResponse("issuer must be set when generating a key"), nil
+		case accountName == "":
+			return logical.ErrorResponse("account_name must be set when generating a key"), nil
+		case keySize <= 0:
+			return logical.ErrorResponse("key_size must be positive"), nil
+		}
+
+		otpKey, err := totplib.Generate(totplib.GenerateOpts{
+			Issuer:      issuer,
+			AccountName: accountName,
+			Period:      uint(period.Seconds()),
+			SecretSize:  uint(keySize),
+			Digits:      keyDigits,
+			Algorithm:   keyAlgorithm,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error generating key: %v", err)
+		}
+		keyString = otpKey.Secret()
+
+		if exported {
+			resp = &logical.Response{
+				Data: map[string]interface{}{
+					"url": otpKey.String(),
+				},
+			}
+			if qrSize > 0 {
+				barcode, err := otpKey.Image(qrSize, qrSize)
+				if err != nil {
+					return nil, fmt.Errorf("error generating QR code: %v", err)
+				}
+				var buf bytes.Buffer
+				if err := png.Encode(&buf, barcode); err != nil {
+					return nil, fmt.Errorf("error encoding QR code: %v", err)
+				}
+				resp.Data["barcode"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+			}
+		}
+	} else {
+		if keyString == "" {
+			return logical.ErrorResponse("a url or key must be given unless generating a key"), nil
+		}
+
+		// Authenticator applications commonly leave out the padding and
+		// use lower case
+		keyString = strings.ToUpper(strings.TrimRight(keyString, "="))
+		if n := len(keyString) % 8; n != 0 {
+			keyString += strings.Repeat("=", 8-n)
+		}
+		if _, err := base32.StdEncoding.DecodeString(keyString); err != nil {
+			return logical.ErrorResponse("key must be base32 encoded"), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("key/"+name, &keyEntry{
+		Key:         keyString,
+		Issuer:      issuer,
+		AccountName: accountName,
+		Period:      period,
+		Algorithm:   keyAlgorithm,
+		Digits:      keyDigits,
+		Skew:        uint(skew),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// keyEntry is a stored TOTP key and the settings of its codes
+type keyEntry struct {
+	Key         string           `json:"key"`
+	Issuer      string           `json:"issuer"`
+	AccountName string           `json:"account_name"`
+	Period      time.Duration    `json:"period"`
+	Algorithm   otplib.Algorithm `json:"algorithm"`
+	Digits      otplib.Digits    `json:"digits"`
+	Skew        uint             `json:"skew"`
+}
+
+const pathKeyHelpSyn = `
+Manage the keys codes are generated and validated with.
+`
+
+const pathKeyHelpDesc = `
+This path lets you manage the TOTP keys of the backend.
+
+With "generate" set, the backend generates the key and, unless "exported" is
+false, returns it as an otpauth:// URL along with a base64 encoded PNG QR
+code of it in "barcode", which authenticator applications can scan. The
+"issuer" and "account_name" parameters are required in that case.
+
+Otherwise the key is imported from the "url" parameter, an otpauth://totp/
+URL whose settings take precedence over the other parameters, or from the
+base32 encoded "key" parameter.
+
+The "period", "algorithm" and "digits" parameters set how codes are
+computed, and "skew" the number of periods before and after the current
+one for which codes are accepted. Reading a key returns its settings but
+never the key itself.
+`
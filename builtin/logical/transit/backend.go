@@ -1,7 +1,9 @@
 package transit
 
 import (
+	"crypto/rsa"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
@@ -21,15 +23,39 @@ func Factory(conf *logical.BackendConfig) (logical.Backend, error) {
 func Backend(conf *logical.BackendConfig) *backend {
 	var b backend
 	b.Backend = &framework.Backend{
+		PathsSpecial: &logical.Paths{
+			// The cryptographic operations only read keys, unless they
+			// have to create one, which is forwarded to the active node
+			StandbyRead: []string{
+				"encrypt/*",
+				"decrypt/*",
+				"rewrap/*",
+				"datakey/*",
+				"hash",
+				"hash/*",
+				"hmac/*",
+				"sign/*",
+				"verify/*",
+				"random",
+				"random/*",
+				"export/*",
+			},
+		},
+
 		Paths: []*framework.Path{
-			// Rotate/Config needs to come before Keys
+			// Rotate/Config/Import needs to come before Keys
 			// as the handler is greedy
 			b.pathConfig(),
 			b.pathRotate(),
+			b.pathImport(),
+			b.pathImportVersion(),
+			b.pathWrappingKey(),
 			b.pathRewrap(),
 			b.pathKeys(),
 			b.pathListKeys(),
 			b.pathExportKeys(),
+			b.pathBackup(),
+			b.pathRestore(),
 			b.pathEncrypt(),
 			b.pathDecrypt(),
 			b.pathDatakey(),
@@ -53,6 +79,10 @@ func Backend(conf *logical.BackendConfig) *backend {
 type backend struct {
 	*framework.Backend
 	lm *keysutil.LockManager
+
+	// The key import material is wrapped with, loaded on first use
+	wrappingKey     *rsa.PrivateKey
+	wrappingKeyLock sync.RWMutex
 }
 
 func (b *backend) invalidate(key string) {
@@ -63,5 +93,9 @@ func (b *backend) invalidate(key string) {
 	case strings.HasPrefix(key, "policy/"):
 		name := strings.TrimPrefix(key, "policy/")
 		b.lm.InvalidatePolicy(name)
+	case key == wrappingKeyPath:
+		b.wrappingKeyLock.Lock()
+		b.wrappingKey = nil
+		b.wrappingKeyLock.Unlock()
 	}
 }
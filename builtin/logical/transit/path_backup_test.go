@@ -0,0 +1,173 @@
+package transit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTransit_BackupRestore(t *testing.T) {
+	for _, keyType := range []string{"aes256-gcm96", "chacha20-poly1305", "rsa-2048"} {
+		b, s := createBackendWithStorage(t)
+
+		request := func(b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+			return b.HandleRequest(&logical.Request{
+				Operation: op,
+				Path:      path,
+				Storage:   s,
+				Data:      data,
+			})
+		}
+		mustRequest := func(b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+			resp, err := request(b, s, op, path, data)
+			if err != nil || (resp != nil && resp.IsError()) {
+				t.Fatalf("%s: %s: err:%v resp:%#v", keyType, path, err, resp)
+			}
+			return resp
+		}
+
+		plaintext := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+		mustRequest(b, s, logical.UpdateOperation, "keys/test", map[string]interface{}{"type": keyType})
+		var ciphertexts []string
+		for i := 0; i < 3; i++ {
+			resp := mustRequest(b, s, logical.UpdateOperation, "encrypt/test", map[string]interface{}{"plaintext": plaintext})
+			ciphertexts = append(ciphertexts, resp.Data["ciphertext"].(string))
+			mustRequest(b, s, logical.UpdateOperation, "keys/test/rotate", nil)
+		}
+
+		// Older versions are moved to the archive
+		mustRequest(b, s, logical.UpdateOperation, "keys/test/config", map[string]interface{}{"min_decryption_version": 2})
+
+		// Backups must be allowed
+		if _, err := request(b, s, logical.ReadOperation, "backup/test", nil); err == nil {
+			t.Fatalf("%s: expected error backing up a key not allowing plaintext backups", keyType)
+		}
+		mustRequest(b, s, logical.UpdateOperation, "keys/test/config", map[string]interface{}{"allow_plaintext_backup": true})
+		resp, err := request(b, s, logical.UpdateOperation, "keys/test/config", map[string]interface{}{"allow_plaintext_backup": false})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected error disabling plaintext backups, err:%v resp:%#v", keyType, err, resp)
+		}
+
+		resp = mustRequest(b, s, logical.ReadOperation, "backup/test", nil)
+		backup := resp.Data["backup"].(string)
+		resp = mustRequest(b, s, logical.ReadOperation, "keys/test", nil)
+		if resp.Data["backup_info"] == nil {
+			t.Fatalf("%s: expected backup info, got %#v", keyType, resp.Data)
+		}
+
+		// The key cannot be restored over itself
+		if _, err := request(b, s, logical.UpdateOperation, "restore", map[string]interface{}{"backup": backup}); err == nil {
+			t.Fatalf("%s: expected error restoring over an existing key", keyType)
+		}
+
+		// The key is restored in another mount, under its own name and
+		// another one
+		b2, s2 := createBackendWithStorage(t)
+		mustRequest(b2, s2, logical.UpdateOperation, "restore", map[string]interface{}{"backup": backup})
+		mustRequest(b2, s2, logical.UpdateOperation, "restore/renamed", map[string]interface{}{"backup": backup})
+
+		for _, name := range []string{"test", "renamed"} {
+			resp = mustRequest(b2, s2, logical.ReadOperation, "keys/"+name, nil)
+			if resp.Data["latest_version"].(int) != 4 ||
+				resp.Data["min_decryption_version"].(int) != 2 ||
+				resp.Data["type"] != keyType ||
+				resp.Data["restore_info"] == nil {
+				t.Fatalf("%s: bad: %#v", keyType, resp.Data)
+			}
+
+			// Ciphertexts older than the minimum version are refused
+			if _, err := request(b2, s2, logical.UpdateOperation, "decrypt/"+name, map[string]interface{}{"ciphertext": ciphertexts[0]}); err == nil {
+				t.Fatalf("%s: expected error decrypting with a version below the minimum", keyType)
+			}
+			for _, ciphertext := range ciphertexts[1:] {
+				resp = mustRequest(b2, s2, logical.UpdateOperation, "decrypt/"+name, map[string]interface{}{"ciphertext": ciphertext})
+				if resp.Data["plaintext"] != plaintext {
+					t.Fatalf("%s: bad: plaintext: %q", keyType, resp.Data["plaintext"])
+				}
+			}
+
+			// Archived versions are restored too
+			mustRequest(b2, s2, logical.UpdateOperation, "keys/"+name+"/config", map[string]interface{}{"min_decryption_version": 1})
+			resp = mustRequest(b2, s2, logical.UpdateOperation, "decrypt/"+name, map[string]interface{}{"ciphertext": ciphertexts[0]})
+			if resp.Data["plaintext"] != plaintext {
+				t.Fatalf("%s: bad: plaintext: %q", keyType, resp.Data["plaintext"])
+			}
+		}
+	}
+}
+
+func TestTransit_RestoreInvalid(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+	for _, path := range []string{"keys/test", "keys/test/config"} {
+		resp, err := request(logical.UpdateOperation, path, map[string]interface{}{"allow_plaintext_backup": true})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: err:%v resp:%#v", path, err, resp)
+		}
+	}
+	resp, err := request(logical.ReadOperation, "backup/test", nil)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	raw, err := base64.StdEncoding.DecodeString(resp.Data["backup"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamper := func(modify func(policy map[string]interface{})) string {
+		var keyData map[string]interface{}
+		if err := json.Unmarshal(raw, &keyData); err != nil {
+			t.Fatal(err)
+		}
+		modify(keyData["policy"].(map[string]interface{}))
+		buf, err := json.Marshal(keyData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(buf)
+	}
+
+	backups := map[string]string{
+		"name": tamper(func(policy map[string]interface{}) {
+			policy["name"] = "../other"
+		}),
+		"type": tamper(func(policy map[string]interface{}) {
+			policy["type"] = 99
+		}),
+		"no keys": tamper(func(policy map[string]interface{}) {
+			policy["keys"] = map[string]interface{}{}
+		}),
+		"missing version": tamper(func(policy map[string]interface{}) {
+			policy["latest_version"] = 2
+		}),
+		"key material": tamper(func(policy map[string]interface{}) {
+			entry := policy["keys"].(map[string]interface{})["1"].(map[string]interface{})
+			entry["key"] = base64.StdEncoding.EncodeToString([]byte("short"))
+		}),
+	}
+	for name, backup := range backups {
+		resp, err := request(logical.UpdateOperation, "restore", map[string]interface{}{"backup": backup})
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected error, got err:%v resp:%#v", name, err, resp)
+		}
+	}
+
+	// Nothing was stored
+	resp, err = request(logical.ListOperation, "keys", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "test" {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
@@ -26,6 +26,12 @@ to be decrypted.`,
 				Type:        framework.TypeBool,
 				Description: "Whether to allow deletion of the key",
 			},
+
+			"allow_plaintext_backup": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables taking backups of the key, which hold
+its key material in plaintext. This cannot be disabled once enabled.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -92,6 +98,18 @@ func (b *backend) pathConfigWrite(
 		}
 	}
 
+	allowPlaintextBackupRaw, ok := d.GetOk("allow_plaintext_backup")
+	if ok {
+		allowPlaintextBackup := allowPlaintextBackupRaw.(bool)
+		if !allowPlaintextBackup && p.AllowPlaintextBackup {
+			return logical.ErrorResponse("allow_plaintext_backup cannot be disabled once enabled"), nil
+		}
+		if allowPlaintextBackup != p.AllowPlaintextBackup {
+			p.AllowPlaintextBackup = allowPlaintextBackup
+			persistNeeded = true
+		}
+	}
+
 	// Add this as a guard here before persisting since we now require the min
 	// decryption version to start at 1; even if it's not explicitly set here,
 	// force the upgrade
@@ -116,5 +134,7 @@ const pathConfigHelpSyn = `Configure a named encryption key`
 const pathConfigHelpDesc = `
 This path is used to configure the named key. Currently, this
 supports adjusting the minimum version of the key allowed to
-be used for decryption via the min_decryption_version paramter.
+be used for decryption via the min_decryption_version paramter,
+allowing deletion of the key and allowing plaintext backups of
+the key.
 `
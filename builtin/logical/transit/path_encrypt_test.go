@@ -1,6 +1,7 @@
 package transit
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
@@ -545,3 +546,170 @@ func TestTransit_BatchEncryptionCase12(t *testing.T) {
 		t.Fatalf("expected an error")
 	}
 }
+
+// Case13: RSA keys encrypt and decrypt with OAEP, across rotations
+func TestTransit_BatchEncryptionCase13(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/rsa",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"type": "rsa-2048",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	plaintext := "dGhlIHF1aWNrIGJyb3duIGZveA=="
+	encrypt := func() string {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "encrypt/rsa",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"plaintext": plaintext,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp.Data["ciphertext"].(string)
+	}
+	decrypt := func(ciphertext string) {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "decrypt/rsa",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"ciphertext": ciphertext,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		if resp.Data["plaintext"] != plaintext {
+			t.Fatalf("bad: plaintext: expected: %q, actual: %q", plaintext, resp.Data["plaintext"])
+		}
+	}
+
+	v1 := encrypt()
+	decrypt(v1)
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/rsa/rotate",
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	v2 := encrypt()
+	if !strings.HasPrefix(v2, "vault:v2:") {
+		t.Fatalf("bad: ciphertext: %q", v2)
+	}
+	decrypt(v2)
+	decrypt(v1)
+}
+
+// Case14: AES-128-GCM and ChaCha20-Poly1305 keys support upserts, derivation,
+// convergent encryption, batch input, rewrapping and data keys
+func TestTransit_BatchEncryptionCase14(t *testing.T) {
+	for _, keyType := range []string{"aes128-gcm96", "chacha20-poly1305"} {
+		b, s := createBackendWithStorage(t)
+
+		request := func(path string, data map[string]interface{}) *logical.Response {
+			resp, err := b.HandleRequest(&logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      path,
+				Storage:   s,
+				Data:      data,
+			})
+			if err != nil || (resp != nil && resp.IsError()) {
+				t.Fatalf("%s: err:%v resp:%#v", keyType, err, resp)
+			}
+			return resp
+		}
+
+		// The key is upserted as a derived convergent key
+		batchInput := []interface{}{
+			map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "context": "dmlzaGFsCg=="},
+			map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "context": "dmlzaGFsCg=="},
+			map[string]interface{}{"plaintext": "dGhlIHF1aWNrIGJyb3duIGZveA==", "context": "dGhlIHF1aWNrIGJyb3duIGZveA=="},
+		}
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "encrypt/upserted_key",
+			Storage:   s,
+			Data: map[string]interface{}{
+				"type":                  keyType,
+				"convergent_encryption": true,
+				"batch_input":           batchInput,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: err:%v resp:%#v", keyType, err, resp)
+		}
+
+		var batchResponse []BatchResponseItem
+		if err := mapstructure.Decode(resp.Data["batch_results"], &batchResponse); err != nil {
+			t.Fatal(err)
+		}
+		if len(batchResponse) != 3 {
+			t.Fatalf("%s: bad: batch results: %#v", keyType, batchResponse)
+		}
+		if batchResponse[0].Ciphertext != batchResponse[1].Ciphertext {
+			t.Fatalf("%s: expected the same ciphertext for the same context", keyType)
+		}
+		if batchResponse[0].Ciphertext == batchResponse[2].Ciphertext {
+			t.Fatalf("%s: expected different ciphertexts for different contexts", keyType)
+		}
+
+		resp, err = b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "keys/upserted_key",
+			Storage:   s,
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("%s: err:%v resp:%#v", keyType, err, resp)
+		}
+		if resp.Data["type"] != keyType || !resp.Data["derived"].(bool) || !resp.Data["convergent_encryption"].(bool) {
+			t.Fatalf("%s: bad: %#v", keyType, resp.Data)
+		}
+
+		// Ciphertexts are rewrapped with the latest version
+		request("keys/upserted_key/rotate", nil)
+		resp = request("rewrap/upserted_key", map[string]interface{}{
+			"ciphertext": batchResponse[2].Ciphertext,
+			"context":    "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		})
+		rewrapped := resp.Data["ciphertext"].(string)
+		if !strings.HasPrefix(rewrapped, "vault:v2:") {
+			t.Fatalf("%s: bad: ciphertext: %q", keyType, rewrapped)
+		}
+
+		for _, ciphertext := range []string{batchResponse[2].Ciphertext, rewrapped} {
+			resp = request("decrypt/upserted_key", map[string]interface{}{
+				"ciphertext": ciphertext,
+				"context":    "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			})
+			if resp.Data["plaintext"] != "dGhlIHF1aWNrIGJyb3duIGZveA==" {
+				t.Fatalf("%s: bad: plaintext: %q", keyType, resp.Data["plaintext"])
+			}
+		}
+
+		// Data keys are encrypted with the key
+		request("keys/datakey", map[string]interface{}{"type": keyType})
+		resp = request("datakey/plaintext/datakey", nil)
+		plaintext := resp.Data["plaintext"].(string)
+		resp = request("decrypt/datakey", map[string]interface{}{
+			"ciphertext": resp.Data["ciphertext"],
+		})
+		if resp.Data["plaintext"] != plaintext {
+			t.Fatalf("%s: bad: data key: expected %q, got %q", keyType, plaintext, resp.Data["plaintext"])
+		}
+	}
+}
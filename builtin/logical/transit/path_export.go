@@ -150,18 +150,27 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 
 	case exportTypeEncryptionKey:
 		switch policy.Type {
-		case keysutil.KeyType_AES256_GCM96:
+		case keysutil.KeyType_AES256_GCM96, keysutil.KeyType_AES128_GCM96, keysutil.KeyType_ChaCha20_Poly1305:
 			return strings.TrimSpace(base64.StdEncoding.EncodeToString(key.AESKey)), nil
+
+		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+			return keyEntryToRSAPrivateKey(key)
 		}
 
 	case exportTypeSigningKey:
 		switch policy.Type {
-		case keysutil.KeyType_ECDSA_P256:
-			ecKey, err := keyEntryToECPrivateKey(key, elliptic.P256())
+		case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521:
+			ecKey, err := keyEntryToECPrivateKey(key, policy.Type.Curve())
 			if err != nil {
 				return "", err
 			}
 			return ecKey, nil
+
+		case keysutil.KeyType_ED25519:
+			return strings.TrimSpace(base64.StdEncoding.EncodeToString(key.Ed25519Key)), nil
+
+		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA4096:
+			return keyEntryToRSAPrivateKey(key)
 		}
 	}
 
@@ -196,6 +205,18 @@ func keyEntryToECPrivateKey(k *keysutil.KeyEntry, curve elliptic.Curve) (string,
 	return strings.TrimSpace(string(pem.EncodeToMemory(&block))), nil
 }
 
+func keyEntryToRSAPrivateKey(k *keysutil.KeyEntry) (string, error) {
+	if k == nil || k.RSAKey == nil {
+		return "", errors.New("nil RSA key provided")
+	}
+
+	block := pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(k.RSAKey),
+	}
+	return strings.TrimSpace(string(pem.EncodeToMemory(&block))), nil
+}
+
 const pathExportHelpSyn = `Export named encryption or signing key`
 
 const pathExportHelpDesc = `
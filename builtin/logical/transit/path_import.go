@@ -0,0 +1,272 @@
+package transit
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// importHashFunctions are the hash functions the ephemeral AES key of
+// wrapped key material can be encrypted with
+var importHashFunctions = map[string]crypto.Hash{
+	"SHA1":   crypto.SHA1,
+	"SHA224": crypto.SHA224,
+	"SHA256": crypto.SHA256,
+	"SHA384": crypto.SHA384,
+	"SHA512": crypto.SHA512,
+}
+
+func (b *backend) pathImport() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/import",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"ciphertext": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The base64 encoded key material, wrapped as
+described in the help of this path.`,
+			},
+
+			"type": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "aes256-gcm96",
+				Description: `The type of the imported key, one of the
+types supported by "keys/<name>". Defaults to "aes256-gcm96".`,
+			},
+
+			"hash_function": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used with RSA-OAEP to
+wrap the ephemeral AES key. One of "SHA1", "SHA224", "SHA256",
+"SHA384" and "SHA512". Defaults to "SHA256".`,
+			},
+
+			"derived": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Enables key derivation mode.`,
+			},
+
+			"convergent_encryption": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether to support convergent encryption.
+This is only supported when using a key with key derivation
+enabled.`,
+			},
+
+			"exportable": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Enables the key to be exportable.`,
+			},
+
+			"allow_rotation": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Whether the key can be rotated, adding a
+version generated by Vault. Defaults to false.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportWrite,
+		},
+
+		HelpSynopsis:    pathImportHelpSyn,
+		HelpDescription: pathImportHelpDesc,
+	}
+}
+
+func (b *backend) pathImportVersion() *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameRegex("name") + "/import_version",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"ciphertext": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The base64 encoded key material, wrapped as
+described in the help of the "keys/<name>/import" path.`,
+			},
+
+			"hash_function": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used with RSA-OAEP to
+wrap the ephemeral AES key. One of "SHA1", "SHA224", "SHA256",
+"SHA384" and "SHA512". Defaults to "SHA256".`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportVersionWrite,
+		},
+
+		HelpSynopsis:    pathImportVersionHelpSyn,
+		HelpDescription: pathImportVersionHelpDesc,
+	}
+}
+
+func (b *backend) pathImportWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	keyType, err := keysutil.ParseKeyType(d.Get("type").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	key, err := b.unwrapImportedKey(req.Storage, d)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	default:
+		return nil, err
+	}
+
+	polReq := keysutil.PolicyRequest{
+		Storage:                  req.Storage,
+		Name:                     name,
+		KeyType:                  keyType,
+		Derived:                  d.Get("derived").(bool),
+		Convergent:               d.Get("convergent_encryption").(bool),
+		Exportable:               d.Get("exportable").(bool),
+		AllowImportedKeyRotation: d.Get("allow_rotation").(bool),
+	}
+
+	// Import does its own locking
+	err = b.lm.ImportPolicy(polReq, key)
+	if _, ok := err.(errutil.UserError); ok {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, err
+}
+
+func (b *backend) pathImportVersionWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	p, lock, err := b.lm.GetPolicyExclusive(req.Storage, name)
+	if lock != nil {
+		defer lock.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return logical.ErrorResponse("key not found"), logical.ErrInvalidRequest
+	}
+
+	// Keys generated by Vault never had their material leave it, which
+	// importing a version would silently end
+	if !p.Imported {
+		return logical.ErrorResponse("versions can only be imported into keys created with the import path"), logical.ErrInvalidRequest
+	}
+
+	key, err := b.unwrapImportedKey(req.Storage, d)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	default:
+		return nil, err
+	}
+
+	err = p.Import(req.Storage, key)
+	if _, ok := err.(errutil.UserError); ok {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, err
+}
+
+// unwrapImportedKey decrypts the key material of the request: the
+// ephemeral AES key encrypted with the wrapping key using RSA-OAEP,
+// followed by the key material wrapped with the ephemeral key using AES
+// key wrap with padding. Problems with the request are returned as user
+// errors.
+func (b *backend) unwrapImportedKey(s logical.Storage, d *framework.FieldData) ([]byte, error) {
+	hashFunction := strings.ToUpper(d.Get("hash_function").(string))
+	hash, ok := importHashFunctions[hashFunction]
+	if !ok {
+		return nil, errutil.UserError{Err: fmt.Sprintf("unsupported hash function %s", hashFunction)}
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(d.Get("ciphertext").(string))
+	if err != nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("error decoding ciphertext: %s", err)}
+	}
+
+	wrappingKey, err := b.getWrappingKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("error loading wrapping key: %s", err)
+	}
+
+	size := wrappingKey.Size()
+	if len(ciphertext) <= size {
+		return nil, errutil.UserError{Err: "ciphertext is too short"}
+	}
+
+	ephemeralKey, err := rsa.DecryptOAEP(hash.New(), rand.Reader, wrappingKey, ciphertext[:size], nil)
+	if err != nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("error decrypting ephemeral key: %s", err)}
+	}
+
+	key, err := keysutil.UnwrapKeyWithPadding(ephemeralKey, ciphertext[size:])
+	if err != nil {
+		return nil, errutil.UserError{Err: fmt.Sprintf("error unwrapping key: %s", err)}
+	}
+
+	return key, nil
+}
+
+const pathImportHelpSyn = `Imports an externally generated key as a new named key`
+
+const pathImportHelpDesc = `
+This path is used to import key material generated outside of Vault as
+a new named key. The key must not exist yet.
+
+The key material is the raw key for symmetric keys, and the PKCS#8 DER encoded
+private key for the asymmetric key types. It must be wrapped by:
+
+  1. generating an ephemeral 256-bit AES key,
+  2. wrapping the key material with the ephemeral key using AES key wrap
+     with padding (RFC 5649),
+  3. encrypting the ephemeral key with the public key returned by the
+     "wrapping_key" path using RSA-OAEP, with the hash function given in
+     hash_function,
+  4. concatenating the encrypted ephemeral key and the wrapped key
+     material, and base64 encoding the result.
+
+Imported keys cannot be rotated unless allow_rotation is set, but further
+versions can be imported with the "keys/<name>/import_version" path. Keys
+generated by Vault cannot have versions imported into them; key material
+meant to be imported must be imported into a new key with this path.
+`
+
+const pathImportVersionHelpSyn = `Imports an externally generated key as a new version of a named key`
+
+const pathImportVersionHelpDesc = `
+This path is used to import key material generated outside of Vault as
+the latest version of an imported key. The key material is wrapped as
+described in the help of the "keys/<name>/import" path, and must be of
+the type of the key. Only keys created with the "keys/<name>/import" path
+are accepted, so that keys generated by Vault keep only ever holding key
+material that never left it.
+`
@@ -0,0 +1,273 @@
+package transit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/keysutil"
+	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestTransit_Import(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "wrapping_key",
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+	block, _ := pem.Decode([]byte(resp.Data["public_key"].(string)))
+	if block == nil {
+		t.Fatal("failed to decode wrapping key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappingKey := parsed.(*rsa.PublicKey)
+
+	wrap := func(key []byte) string {
+		ephemeralKey, err := uuid.GenerateRandomBytes(32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, wrappingKey, ephemeralKey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wrappedKey, err := keysutil.WrapKeyWithPadding(ephemeralKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(append(encryptedKey, wrappedKey...))
+	}
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data:      data,
+		})
+	}
+
+	// AES keys are imported as is
+	aesKey, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request("keys/aes/import", map[string]interface{}{
+		"ciphertext": wrap(aesKey),
+		"exportable": true,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+	resp, err = b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "export/encryption-key/aes/1",
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+	if resp.Data["keys"].(map[string]string)["1"] != base64.StdEncoding.EncodeToString(aesKey) {
+		t.Fatalf("bad: exported key does not match the imported one")
+	}
+
+	// The key cannot be imported again
+	resp, err = request("keys/aes/import", map[string]interface{}{
+		"ciphertext": wrap(aesKey),
+	})
+	if err == nil {
+		t.Fatalf("expected error importing an existing key, resp: %#v", resp)
+	}
+
+	// Imported keys cannot be rotated unless allowed, but versions can be
+	// imported
+	resp, err = request("keys/aes/rotate", nil)
+	if err == nil {
+		t.Fatalf("expected error rotating an imported key, resp: %#v", resp)
+	}
+	resp, err = request("keys/aes/import_version", map[string]interface{}{
+		"ciphertext": wrap(aesKey[:16]),
+	})
+	if err == nil {
+		t.Fatalf("expected error importing a key of the wrong size, resp: %#v", resp)
+	}
+	resp, err = request("keys/aes/import_version", map[string]interface{}{
+		"ciphertext": wrap(aesKey),
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "keys/aes",
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+	if resp.Data["latest_version"].(int) != 2 || !resp.Data["imported_key"].(bool) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Versions cannot be imported into generated keys
+	if _, err := request("keys/generated", nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request("keys/generated/import_version", map[string]interface{}{
+		"ciphertext": wrap(aesKey),
+	})
+	if err == nil {
+		t.Fatalf("expected error importing into a generated key, resp: %#v", resp)
+	}
+
+	// Asymmetric keys are imported as PKCS#8
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, err := asn1.Marshal(edKey[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := asn1.Marshal(struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm: asn1.ObjectIdentifier{1, 3, 101, 112},
+		},
+		PrivateKey: seed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		keyType string
+		key     []byte
+	}{
+		{"ecdsa-p384", ecDER},
+		{"rsa-2048", rsaDER},
+		{"ed25519", edDER},
+	} {
+		// The key must be of the type imported
+		resp, err = request("keys/"+tc.keyType+"/import", map[string]interface{}{
+			"ciphertext": wrap(tc.key),
+			"type":       "rsa-4096",
+		})
+		if err == nil {
+			t.Fatalf("expected error importing %s key as rsa-4096, resp: %#v", tc.keyType, resp)
+		}
+
+		resp, err = request("keys/"+tc.keyType+"/import", map[string]interface{}{
+			"ciphertext":     wrap(tc.key),
+			"type":           tc.keyType,
+			"allow_rotation": true,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err: %v\nresp: %#v", err, resp)
+		}
+
+		resp, err = request("sign/"+tc.keyType, map[string]interface{}{
+			"input": "dGhlIHF1aWNrIGJyb3duIGZveA==",
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("err: %v\nresp: %#v", err, resp)
+		}
+		resp, err = request("verify/"+tc.keyType, map[string]interface{}{
+			"input":     "dGhlIHF1aWNrIGJyb3duIGZveA==",
+			"signature": resp.Data["signature"],
+		})
+		if err != nil || resp == nil || !resp.Data["valid"].(bool) {
+			t.Fatalf("err: %v\nresp: %#v", err, resp)
+		}
+
+		// Rotation was allowed
+		if _, err := request("keys/"+tc.keyType+"/rotate", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// failingWrappingKeyStorage fails to read the wrapping key
+type failingWrappingKeyStorage struct {
+	logical.Storage
+}
+
+func (s *failingWrappingKeyStorage) Get(key string) (*logical.StorageEntry, error) {
+	if key == wrappingKeyPath {
+		return nil, errors.New("storage unavailable")
+	}
+	return s.Storage.Get(key)
+}
+
+func TestTransit_ImportStorageFailure(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	aesKey, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.lm.ImportPolicy(keysutil.PolicyRequest{
+		Storage: storage,
+		Name:    "imported",
+		KeyType: keysutil.KeyType_AES256_GCM96,
+	}, aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Failing to load the wrapping key is not the caller's problem
+	for _, path := range []string{"keys/other/import", "keys/imported/import_version"} {
+		resp, err := b.HandleRequest(&logical.Request{
+			Storage:   &failingWrappingKeyStorage{Storage: storage},
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Data: map[string]interface{}{
+				"ciphertext": base64.StdEncoding.EncodeToString(make([]byte, 1024)),
+			},
+		})
+		if err == nil || err == logical.ErrInvalidRequest || resp != nil {
+			t.Fatalf("%s: expected internal error, got err: %v\nresp: %#v", path, err, resp)
+		}
+	}
+}
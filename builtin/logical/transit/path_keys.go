@@ -1,7 +1,6 @@
 package transit
 
 import (
-	"crypto/elliptic"
 	"fmt"
 	"strconv"
 
@@ -36,8 +35,9 @@ func (b *backend) pathKeys() *framework.Path {
 				Type:    framework.TypeString,
 				Default: "aes256-gcm96",
 				Description: `The type of key to create. Currently,
-"aes256-gcm96" (symmetric) and "ecdsa-p256" (asymmetric) are
-supported. Defaults to "aes256-gcm96".`,
+"aes256-gcm96", "aes128-gcm96", "chacha20-poly1305" (symmetric),
+"ecdsa-p256", "ecdsa-p384", "ecdsa-p521", "ed25519", "rsa-2048" and
+"rsa-4096" (asymmetric) are supported. Defaults to "aes256-gcm96".`,
 			},
 
 			"derived": &framework.FieldSchema{
@@ -104,21 +104,19 @@ func (b *backend) pathPolicyWrite(
 		return logical.ErrorResponse("convergent encryption requires derivation to be enabled"), nil
 	}
 
+	polKeyType, err := keysutil.ParseKeyType(keyType)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	polReq := keysutil.PolicyRequest{
 		Storage:    req.Storage,
 		Name:       name,
+		KeyType:    polKeyType,
 		Derived:    derived,
 		Convergent: convergent,
 		Exportable: exportable,
 	}
-	switch keyType {
-	case "aes256-gcm96":
-		polReq.KeyType = keysutil.KeyType_AES256_GCM96
-	case "ecdsa-p256":
-		polReq.KeyType = keysutil.KeyType_ECDSA_P256
-	default:
-		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
-	}
 
 	p, lock, upserted, err := b.lm.GetPolicyUpsert(polReq)
 	if lock != nil {
@@ -168,9 +166,28 @@ func (b *backend) pathPolicyRead(
 			"supports_decryption":    p.Type.DecryptionSupported(),
 			"supports_signing":       p.Type.SigningSupported(),
 			"supports_derivation":    p.Type.DerivationSupported(),
+			"imported_key":           p.Imported,
+			"allow_plaintext_backup": p.AllowPlaintextBackup,
 		},
 	}
 
+	if p.Imported {
+		resp.Data["allow_imported_key_rotation"] = p.AllowImportedKeyRotation
+	}
+
+	if p.BackupInfo != nil {
+		resp.Data["backup_info"] = map[string]interface{}{
+			"time":    p.BackupInfo.Time,
+			"version": p.BackupInfo.Version,
+		}
+	}
+	if p.RestoreInfo != nil {
+		resp.Data["restore_info"] = map[string]interface{}{
+			"time":    p.RestoreInfo.Time,
+			"version": p.RestoreInfo.Version,
+		}
+	}
+
 	if p.Derived {
 		switch p.KDF {
 		case keysutil.Kdf_hmac_sha256_counter:
@@ -186,23 +203,30 @@ func (b *backend) pathPolicyRead(
 	}
 
 	switch p.Type {
-	case keysutil.KeyType_AES256_GCM96:
+	case keysutil.KeyType_AES256_GCM96, keysutil.KeyType_AES128_GCM96, keysutil.KeyType_ChaCha20_Poly1305:
 		retKeys := map[string]int64{}
 		for k, v := range p.Keys {
 			retKeys[strconv.Itoa(k)] = v.CreationTime
 		}
 		resp.Data["keys"] = retKeys
 
-	case keysutil.KeyType_ECDSA_P256:
-		type ecdsaKey struct {
-			Name      string `json:"name"`
-			PublicKey string `json:"public_key"`
+	default:
+		// Ed25519 public keys are base64 encoded, the others PEM encoded
+		type asymKey struct {
+			Name         string `json:"name"`
+			PublicKey    string `json:"public_key"`
+			CreationTime int64  `json:"creation_time"`
+		}
+		name := p.Type.String()
+		if curve := p.Type.Curve(); curve != nil {
+			name = curve.Params().Name
 		}
-		retKeys := map[string]ecdsaKey{}
+		retKeys := map[string]asymKey{}
 		for k, v := range p.Keys {
-			retKeys[strconv.Itoa(k)] = ecdsaKey{
-				Name:      elliptic.P256().Params().Name,
-				PublicKey: v.FormattedPublicKey,
+			retKeys[strconv.Itoa(k)] = asymKey{
+				Name:         name,
+				PublicKey:    v.FormattedPublicKey,
+				CreationTime: v.CreationTime,
 			}
 		}
 		resp.Data["keys"] = retKeys
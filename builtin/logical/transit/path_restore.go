@@ -0,0 +1,57 @@
+package transit
+
+import (
+	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+func (b *backend) pathRestore() *framework.Path {
+	return &framework.Path{
+		Pattern: "restore" + framework.OptionalParamRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"backup": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Backup of the key, as returned by the backup path",
+			},
+
+			"name": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Name to restore the key under. Defaults to
+the name of the key that was backed up.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRestoreUpdate,
+		},
+
+		HelpSynopsis:    pathRestoreHelpSyn,
+		HelpDescription: pathRestoreHelpDesc,
+	}
+}
+
+func (b *backend) pathRestoreUpdate(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	backup := d.Get("backup").(string)
+	if backup == "" {
+		return logical.ErrorResponse("missing backup"), logical.ErrInvalidRequest
+	}
+
+	// Restore does its own locking
+	err := b.lm.RestorePolicy(req.Storage, d.Get("name").(string), backup)
+	if _, ok := err.(errutil.UserError); ok {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, err
+}
+
+const pathRestoreHelpSyn = `Restore a backed up key`
+
+const pathRestoreHelpDesc = `
+This path is used to restore a key backed up with the "backup/<name>" path,
+with all of its versions and configuration. The key is restored under the
+name given in the path, or the name it was backed up with. Restoring over an
+existing key is not allowed.
+`
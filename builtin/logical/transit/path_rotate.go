@@ -1,6 +1,7 @@
 package transit
 
 import (
+	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -42,6 +43,9 @@ func (b *backend) pathRotateWrite(
 
 	// Rotate the policy
 	err = p.Rotate(req.Storage)
+	if _, ok := err.(errutil.UserError); ok {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
 
 	return nil, err
 }
@@ -1,13 +1,11 @@
 package transit
 
 import (
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/base64"
 	"fmt"
-	"hash"
 
 	"github.com/hashicorp/vault/helper/errutil"
+	"github.com/hashicorp/vault/helper/keysutil"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -36,7 +34,15 @@ func (b *backend) pathSign() *framework.Path {
 * sha2-384
 * sha2-512
 
-Defaults to "sha2-256".`,
+Defaults to "sha2-256". Not used by ed25519 keys, which sign the input
+itself.`,
+			},
+
+			"signature_algorithm": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: keysutil.SignatureAlgorithmPSS,
+				Description: `The signature algorithm of RSA keys. Valid values are
+"pss" and "pkcs1v15". Defaults to "pss".`,
 			},
 
 			"urlalgorithm": &framework.FieldSchema{
@@ -93,7 +99,15 @@ func (b *backend) pathVerify() *framework.Path {
 * sha2-384
 * sha2-512
 
-Defaults to "sha2-256".`,
+Defaults to "sha2-256". Not used by ed25519 keys, which sign the input
+itself.`,
+			},
+
+			"signature_algorithm": &framework.FieldSchema{
+				Type:    framework.TypeString,
+				Default: keysutil.SignatureAlgorithmPSS,
+				Description: `The signature algorithm of RSA keys. Valid values are
+"pss" and "pkcs1v15". Defaults to "pss".`,
 			},
 		},
 
@@ -120,21 +134,10 @@ func (b *backend) pathSignWrite(
 		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
 	}
 
-	var hf hash.Hash
-	switch algorithm {
-	case "sha2-224":
-		hf = sha256.New224()
-	case "sha2-256":
-		hf = sha256.New()
-	case "sha2-384":
-		hf = sha512.New384()
-	case "sha2-512":
-		hf = sha512.New()
-	default:
+	hashAlgorithm, ok := keysutil.HashAlgorithms[algorithm]
+	if !ok {
 		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 	}
-	hf.Write(input)
-	hashedInput := hf.Sum(nil)
 
 	// Get the policy
 	p, lock, err := b.lm.GetPolicyShared(req.Storage, name)
@@ -152,8 +155,11 @@ func (b *backend) pathSignWrite(
 		return logical.ErrorResponse(fmt.Sprintf("key type %v does not support signing", p.Type)), logical.ErrInvalidRequest
 	}
 
-	sig, err := p.Sign(hashedInput)
+	sig, err := p.Sign(input, hashAlgorithm, d.Get("signature_algorithm").(string))
 	if err != nil {
+		if _, ok := err.(errutil.UserError); ok {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
 		return nil, err
 	}
 	if sig == "" {
@@ -197,21 +203,10 @@ func (b *backend) pathVerifyWrite(
 		return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
 	}
 
-	var hf hash.Hash
-	switch algorithm {
-	case "sha2-224":
-		hf = sha256.New224()
-	case "sha2-256":
-		hf = sha256.New()
-	case "sha2-384":
-		hf = sha512.New384()
-	case "sha2-512":
-		hf = sha512.New()
-	default:
+	hashAlgorithm, ok := keysutil.HashAlgorithms[algorithm]
+	if !ok {
 		return logical.ErrorResponse(fmt.Sprintf("unsupported algorithm %s", algorithm)), nil
 	}
-	hf.Write(input)
-	hashedInput := hf.Sum(nil)
 
 	// Get the policy
 	p, lock, err := b.lm.GetPolicyShared(req.Storage, name)
@@ -225,7 +220,7 @@ func (b *backend) pathVerifyWrite(
 		return logical.ErrorResponse("policy not found"), logical.ErrInvalidRequest
 	}
 
-	valid, err := p.VerifySignature(hashedInput, sig)
+	valid, err := p.VerifySignature(input, sig, hashAlgorithm, d.Get("signature_algorithm").(string))
 	if err != nil {
 		switch err.(type) {
 		case errutil.UserError:
@@ -1,9 +1,21 @@
 package transit
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/logical"
+	"golang.org/x/crypto/ed25519"
 )
 
 func TestTransit_SignVerify(t *testing.T) {
@@ -199,3 +211,160 @@ func TestTransit_SignVerify(t *testing.T) {
 	// Now try the v1
 	verifyRequest(req, true, "", v1sig)
 }
+
+func TestTransit_SignVerify_KeyTypes(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	input := []byte("the quick brown fox")
+	cases := []struct {
+		keyType string
+		sigAlgs []string
+	}{
+		{"ecdsa-p384", []string{""}},
+		{"ecdsa-p521", []string{""}},
+		{"ed25519", []string{""}},
+		{"rsa-2048", []string{"pss", "pkcs1v15"}},
+	}
+	for _, tc := range cases {
+		name := "key-" + tc.keyType
+		resp, err := b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data: map[string]interface{}{
+				"type": tc.keyType,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: err:%v resp:%#v", tc.keyType, err, resp)
+		}
+
+		resp, err = b.HandleRequest(&logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      "keys/" + name,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s: err:%v resp:%#v", tc.keyType, err, resp)
+		}
+		if resp.Data["type"] != tc.keyType || resp.Data["supports_signing"] != true {
+			t.Fatalf("%s: bad: %#v", tc.keyType, resp.Data)
+		}
+		publicKey := reflect.ValueOf(resp.Data["keys"]).MapIndex(reflect.ValueOf("1")).FieldByName("PublicKey").String()
+
+		for _, sigAlg := range tc.sigAlgs {
+			data := map[string]interface{}{
+				"input":     base64.StdEncoding.EncodeToString(input),
+				"algorithm": "sha2-384",
+			}
+			if sigAlg != "" {
+				data["signature_algorithm"] = sigAlg
+			}
+			resp, err = b.HandleRequest(&logical.Request{
+				Storage:   storage,
+				Operation: logical.UpdateOperation,
+				Path:      "sign/" + name,
+				Data:      data,
+			})
+			if err != nil || resp == nil || resp.IsError() {
+				t.Fatalf("%s: err:%v resp:%#v", tc.keyType, err, resp)
+			}
+			sig := resp.Data["signature"].(string)
+
+			data["signature"] = sig
+			resp, err = b.HandleRequest(&logical.Request{
+				Storage:   storage,
+				Operation: logical.UpdateOperation,
+				Path:      "verify/" + name,
+				Data:      data,
+			})
+			if err != nil || resp == nil || resp.Data["valid"] != true {
+				t.Fatalf("%s %s: err:%v resp:%#v", tc.keyType, sigAlg, err, resp)
+			}
+
+			// The signature can be verified with the public key alone
+			sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "vault:v1:"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !testVerifyWithPublicKey(t, tc.keyType, sigAlg, publicKey, input, sigBytes) {
+				t.Fatalf("%s %s: signature not valid for the public key", tc.keyType, sigAlg)
+			}
+
+			// Another input does not verify
+			data["input"] = base64.StdEncoding.EncodeToString([]byte("foo"))
+			resp, err = b.HandleRequest(&logical.Request{
+				Storage:   storage,
+				Operation: logical.UpdateOperation,
+				Path:      "verify/" + name,
+				Data:      data,
+			})
+			if err != nil || resp == nil || resp.Data["valid"] != false {
+				t.Fatalf("%s %s: err:%v resp:%#v", tc.keyType, sigAlg, err, resp)
+			}
+		}
+	}
+
+	// Keys other than RSA do not encrypt
+	resp, err := b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "encrypt/key-ed25519",
+		Data: map[string]interface{}{
+			"plaintext": base64.StdEncoding.EncodeToString(input),
+		},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected error: %#v", resp)
+	}
+
+	// Nor do asymmetric keys support derivation
+	resp, err = b.HandleRequest(&logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/derived",
+		Data: map[string]interface{}{
+			"type":    "ed25519",
+			"derived": true,
+		},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected error: %#v", resp)
+	}
+}
+
+func testVerifyWithPublicKey(t *testing.T, keyType, sigAlg, publicKey string, input, sig []byte) bool {
+	if keyType == "ed25519" {
+		pub, err := base64.StdEncoding.DecodeString(publicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ed25519.Verify(ed25519.PublicKey(pub), input, sig)
+	}
+
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		t.Fatalf("bad public key: %q", publicKey)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha512.Sum384(input)
+
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		var ecdsaSig struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(sig, &ecdsaSig); err != nil {
+			t.Fatal(err)
+		}
+		return ecdsa.Verify(pub, hashed[:], ecdsaSig.R, ecdsaSig.S)
+	case *rsa.PublicKey:
+		if sigAlg == "pkcs1v15" {
+			return rsa.VerifyPKCS1v15(pub, crypto.SHA384, hashed[:], sig) == nil
+		}
+		return rsa.VerifyPSS(pub, crypto.SHA384, hashed[:], sig, nil) == nil
+	}
+	t.Fatalf("unexpected public key type %T", pub)
+	return false
+}
@@ -0,0 +1,118 @@
+package transit
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const wrappingKeyPath = "wrapping_key"
+
+// wrappingKeyEntry is the storage entry of the wrapping key
+type wrappingKeyEntry struct {
+	// Key is the PKCS#1 DER encoded private key
+	Key []byte `json:"key"`
+}
+
+func (b *backend) pathWrappingKey() *framework.Path {
+	return &framework.Path{
+		Pattern: "wrapping_key",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathWrappingKeyRead,
+		},
+
+		HelpSynopsis:    pathWrappingKeyHelpSyn,
+		HelpDescription: pathWrappingKeyHelpDesc,
+	}
+}
+
+func (b *backend) pathWrappingKeyRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	key, err := b.getWrappingKey(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	derBytes, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling wrapping key: %s", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	})
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_key": string(pemBytes),
+		},
+	}, nil
+}
+
+// getWrappingKey returns the RSA key of the mount that key material to
+// import is wrapped with, generating it on first use
+func (b *backend) getWrappingKey(s logical.Storage) (*rsa.PrivateKey, error) {
+	b.wrappingKeyLock.RLock()
+	key := b.wrappingKey
+	b.wrappingKeyLock.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+
+	b.wrappingKeyLock.Lock()
+	defer b.wrappingKeyLock.Unlock()
+
+	// Check again, the key may have been loaded while waiting for the lock
+	if b.wrappingKey != nil {
+		return b.wrappingKey, nil
+	}
+
+	raw, err := s.Get(wrappingKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw != nil {
+		var entry wrappingKeyEntry
+		if err := raw.DecodeJSON(&entry); err != nil {
+			return nil, err
+		}
+		key, err = x509.ParsePKCS1PrivateKey(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing wrapping key: %s", err)
+		}
+	} else {
+		key, err = rsa.GenerateKey(rand.Reader, 4096)
+		if err != nil {
+			return nil, fmt.Errorf("error generating wrapping key: %s", err)
+		}
+
+		entry, err := logical.StorageEntryJSON(wrappingKeyPath, &wrappingKeyEntry{
+			Key: x509.MarshalPKCS1PrivateKey(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Put(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	b.wrappingKey = key
+	return key, nil
+}
+
+const pathWrappingKeyHelpSyn = `Returns the public key to wrap key material to import with`
+
+const pathWrappingKeyHelpDesc = `
+This path returns the public part of the 4096-bit RSA key of the mount,
+in PEM format. Key material imported with the "keys/<name>/import" and
+"keys/<name>/import_version" paths must be wrapped with it. The key is
+generated the first time it is requested.
+`
@@ -14,30 +14,39 @@ type AuthEnableCommand struct {
 }
 
 func (c *AuthEnableCommand) Run(args []string) int {
-	var description, path string
+	var description, path, pluginName string
 	var local bool
 	flags := c.Meta.FlagSet("auth-enable", meta.FlagSetDefault)
 	flags.StringVar(&description, "description", "", "")
 	flags.StringVar(&path, "path", "", "")
 	flags.BoolVar(&local, "local", false, "")
+	flags.StringVar(&pluginName, "plugin-name", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 
 	args = flags.Args()
-	if len(args) != 1 {
+	var authType string
+	switch {
+	case len(args) == 1:
+		authType = args[0]
+	case len(args) == 0 && pluginName != "":
+		authType = "plugin"
+	default:
 		flags.Usage()
 		c.Ui.Error(fmt.Sprintf(
 			"\nauth-enable expects one argument: the type to enable."))
 		return 1
 	}
 
-	authType := args[0]
-
-	// If no path is specified, we default the path to the backend type
+	// If no path is specified, we default the path to the backend type, or
+	// to the plugin name for plugin backends
 	if path == "" {
 		path = authType
+		if pluginName != "" {
+			path = pluginName
+		}
 	}
 
 	client, err := c.Client()
@@ -51,6 +60,7 @@ func (c *AuthEnableCommand) Run(args []string) int {
 		Type:        authType,
 		Description: description,
 		Local:       local,
+		PluginName:  pluginName,
 	}); err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error: %s", err))
@@ -92,6 +102,10 @@ Auth Enable Options:
   -local                  Mark the mount as a local mount. Local mounts
                           are not replicated nor (if a secondary)
                           removed by replication.
+
+  -plugin-name=<name>     Name of the plugin serving the auth provider, as
+                          registered in the plugin catalog. The type defaults
+                          to "plugin" and the path to the plugin name.
 `
 	return strings.TrimSpace(helpText)
 }
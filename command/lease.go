@@ -0,0 +1,33 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+	"github.com/mitchellh/cli"
+)
+
+// LeaseCommand is a Command that groups the lease inspection subcommands.
+type LeaseCommand struct {
+	meta.Meta
+}
+
+func (c *LeaseCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *LeaseCommand) Synopsis() string {
+	return "Inspect leases"
+}
+
+func (c *LeaseCommand) Help() string {
+	helpText := `
+Usage: vault lease <subcommand> [options] [args]
+
+  Inspect the leases Vault is tracking. Leases can be listed by prefix with
+  "vault lease list" and the details of a single lease are shown with
+  "vault lease lookup". To renew or revoke a lease, use "vault renew" and
+  "vault revoke".
+`
+	return strings.TrimSpace(helpText)
+}
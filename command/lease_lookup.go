@@ -0,0 +1,68 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/meta"
+)
+
+// LeaseLookupCommand is a Command that outputs details about a lease.
+type LeaseLookupCommand struct {
+	meta.Meta
+}
+
+func (c *LeaseLookupCommand) Run(args []string) int {
+	var format string
+	flags := c.Meta.FlagSet("lease lookup", meta.FlagSetDefault)
+	flags.StringVar(&format, "format", "table", "")
+	flags.Usage = func() { c.Ui.Error(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		flags.Usage()
+		c.Ui.Error(fmt.Sprintf(
+			"\nlease lookup expects one argument: the lease ID to look up"))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error initializing client: %s", err))
+		return 2
+	}
+
+	secret, err := client.Sys().Lookup(args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf(
+			"Error looking up lease: %s", err))
+		return 1
+	}
+
+	return OutputSecret(c.Ui, format, secret)
+}
+
+func (c *LeaseLookupCommand) Synopsis() string {
+	return "Display information about a lease"
+}
+
+func (c *LeaseLookupCommand) Help() string {
+	helpText := `
+Usage: vault lease lookup [options] id
+
+  Display the issue time, expiration time, last renewal time and
+  renewability of the lease with the given ID.
+
+General Options:
+` + meta.GeneralOptionsUsage() + `
+Lease Lookup Options:
+
+  -format=table           The format for output. By default it is a whitespace-
+                          delimited table. This can also be json or yaml.
+`
+	return strings.TrimSpace(helpText)
+}
@@ -14,7 +14,7 @@ type MountCommand struct {
 }
 
 func (c *MountCommand) Run(args []string) int {
-	var description, path, defaultLeaseTTL, maxLeaseTTL string
+	var description, path, defaultLeaseTTL, maxLeaseTTL, pluginName string
 	var local, forceNoCache bool
 	flags := c.Meta.FlagSet("mount", meta.FlagSetDefault)
 	flags.StringVar(&description, "description", "", "")
@@ -23,24 +23,33 @@ func (c *MountCommand) Run(args []string) int {
 	flags.StringVar(&maxLeaseTTL, "max-lease-ttl", "", "")
 	flags.BoolVar(&forceNoCache, "force-no-cache", false, "")
 	flags.BoolVar(&local, "local", false, "")
+	flags.StringVar(&pluginName, "plugin-name", "", "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 
 	args = flags.Args()
-	if len(args) != 1 {
+	var mountType string
+	switch {
+	case len(args) == 1:
+		mountType = args[0]
+	case len(args) == 0 && pluginName != "":
+		mountType = "plugin"
+	default:
 		flags.Usage()
 		c.Ui.Error(fmt.Sprintf(
 			"\nMount expects one argument: the type to mount."))
 		return 1
 	}
 
-	mountType := args[0]
-
-	// If no path is specified, we default the path to the backend type
+	// If no path is specified, we default the path to the backend type, or
+	// to the plugin name for plugin backends
 	if path == "" {
 		path = mountType
+		if pluginName != "" {
+			path = pluginName
+		}
 	}
 
 	client, err := c.Client()
@@ -57,6 +66,7 @@ func (c *MountCommand) Run(args []string) int {
 			DefaultLeaseTTL: defaultLeaseTTL,
 			MaxLeaseTTL:     maxLeaseTTL,
 			ForceNoCache:    forceNoCache,
+			PluginName:      pluginName,
 		},
 		Local: local,
 	}
@@ -116,6 +126,10 @@ Mount Options:
                                  are not replicated nor (if a secondary)
                                  removed by replication.
 
+  -plugin-name=<name>            Name of the plugin to mount, as registered in
+                                 the plugin catalog. The type defaults to
+                                 "plugin" and the path to the plugin name.
+
 `
 	return strings.TrimSpace(helpText)
 }
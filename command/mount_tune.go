@@ -16,9 +16,11 @@ type MountTuneCommand struct {
 
 func (c *MountTuneCommand) Run(args []string) int {
 	var defaultLeaseTTL, maxLeaseTTL string
+	var versioned bool
 	flags := c.Meta.FlagSet("mount-tune", meta.FlagSetDefault)
 	flags.StringVar(&defaultLeaseTTL, "default-lease-ttl", "", "")
 	flags.StringVar(&maxLeaseTTL, "max-lease-ttl", "", "")
+	flags.BoolVar(&versioned, "versioned", false, "")
 	flags.Usage = func() { c.Ui.Error(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -37,6 +39,7 @@ func (c *MountTuneCommand) Run(args []string) int {
 	mountConfig := api.MountConfigInput{
 		DefaultLeaseTTL: defaultLeaseTTL,
 		MaxLeaseTTL:     maxLeaseTTL,
+		Versioned:       versioned,
 	}
 
 	client, err := c.Client()
@@ -84,6 +87,10 @@ Mount Options:
                                  the previously set value. Set to 'system' to
                                  explicitly set it to use the system default.
 
+  -versioned                     Upgrade a generic backend to a versioned kv
+                                 backend. Existing secrets become version 1
+                                 of their keys. This cannot be undone.
+
 `
 	return strings.TrimSpace(helpText)
 }
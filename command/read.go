@@ -29,8 +29,8 @@ func (c *ReadCommand) Run(args []string) int {
 	}
 
 	args = flags.Args()
-	if len(args) != 1 || len(args[0]) == 0 {
-		c.Ui.Error("read expects one argument")
+	if len(args) < 1 || len(args[0]) == 0 {
+		c.Ui.Error("read expects at least one argument")
 		flags.Usage()
 		return 1
 	}
@@ -47,7 +47,19 @@ func (c *ReadCommand) Run(args []string) int {
 		return 2
 	}
 
-	secret, err = client.Logical().Read(path)
+	// Any remaining arguments are passed along as query parameters
+	data := make(map[string][]string)
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			c.Ui.Error(fmt.Sprintf(
+				"Invalid key/value pair %q: format must be key=value", arg))
+			return 1
+		}
+		data[parts[0]] = append(data[parts[0]], parts[1])
+	}
+
+	secret, err = client.Logical().ReadWithData(path, data)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf(
 			"Error reading %s: %s", path, err))
@@ -73,7 +85,7 @@ func (c *ReadCommand) Synopsis() string {
 
 func (c *ReadCommand) Help() string {
 	helpText := `
-Usage: vault read [options] path
+Usage: vault read [options] path [key=value...]
 
   Read data from Vault.
 
@@ -82,6 +94,12 @@ Usage: vault read [options] path
   materialized backends. Please reference the documentation for the
   backends in use to determine key structure.
 
+  Additional key=value pairs are sent as query parameters. Only the
+  version parameter is passed on to backends, for example to read a
+  specific version from a kv backend:
+
+      $ vault read kv/data/foo version=2
+
 General Options:
 ` + meta.GeneralOptionsUsage() + `
 Read Options:
@@ -32,6 +32,7 @@ import (
 	"github.com/hashicorp/vault/helper/flag-slice"
 	"github.com/hashicorp/vault/helper/gated-writer"
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/mlock"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/logical"
@@ -187,7 +188,8 @@ func (c *ServerCommand) Run(args []string) int {
 		c.Ui.Output("  Vault on an mlockall(2) enabled system is much more secure.\n")
 	}
 
-	if err := c.setupTelemetry(config); err != nil {
+	metricsHelper, err := c.setupTelemetry(config)
+	if err != nil {
 		c.Ui.Output(fmt.Sprintf("Error initializing telemetry: %s", err))
 		return 1
 	}
@@ -206,6 +208,23 @@ func (c *ServerCommand) Run(args []string) int {
 	info := make(map[string]string)
 
 	var seal vault.Seal = &vault.DefaultSeal{}
+	if config.Seal != nil {
+		switch config.Seal.Type {
+		case "transit":
+			transitSeal, err := vault.NewTransitSealFromConfig(config.Seal.Config)
+			if err != nil {
+				c.Ui.Output(fmt.Sprintf(
+					"Error initializing seal of type %s: %s",
+					config.Seal.Type, err))
+				return 1
+			}
+			seal = transitSeal
+		default:
+			c.Ui.Output(fmt.Sprintf(
+				"Unknown seal type %s", config.Seal.Type))
+			return 1
+		}
+	}
 
 	// Ensure that the seal finalizer is called, even if using verify-only
 	defer func() {
@@ -237,6 +256,9 @@ func (c *ServerCommand) Run(args []string) int {
 		DefaultLeaseTTL:    config.DefaultLeaseTTL,
 		ClusterName:        config.ClusterName,
 		CacheSize:          config.CacheSize,
+		PluginDirectory:    config.PluginDirectory,
+		EnableStandbyReads: config.EnableStandbyReads,
+		MetricsHelper:      metricsHelper,
 	}
 	if dev {
 		coreConfig.DevToken = devRootTokenID
@@ -408,6 +430,11 @@ CLUSTER_SYNTHESIS_COMPLETE:
 		}
 	}
 
+	if config.Seal != nil {
+		info["seal"] = config.Seal.Type
+		infoKeys = append(infoKeys, "seal")
+	}
+
 	clusterAddrs := []*net.TCPAddr{}
 
 	// Initialize the listeners
@@ -816,8 +843,9 @@ func (c *ServerCommand) detectRedirect(detect physical.RedirectDetect,
 	return url.String(), nil
 }
 
-// setupTelemetry is used to setup the telemetry sub-systems
-func (c *ServerCommand) setupTelemetry(config *server.Config) error {
+// setupTelemetry is used to setup the telemetry sub-systems and returns a
+// helper serving the in-memory metrics
+func (c *ServerCommand) setupTelemetry(config *server.Config) (*metricsutil.MetricsHelper, error) {
 	/* Setup telemetry
 	Aggregate on 10 second intervals for 1 minute. Expose the
 	metrics over stderr when there is a SIGUSR1 received.
@@ -840,7 +868,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 	if telConfig.StatsiteAddr != "" {
 		sink, err := metrics.NewStatsiteSink(telConfig.StatsiteAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -849,7 +877,7 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 	if telConfig.StatsdAddr != "" {
 		sink, err := metrics.NewStatsdSink(telConfig.StatsdAddr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -885,21 +913,22 @@ func (c *ServerCommand) setupTelemetry(config *server.Config) error {
 
 		sink, err := circonus.NewCirconusSink(cfg)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sink.Start()
 		fanout = append(fanout, sink)
 	}
 
-	// Initialize the global sink
-	if len(fanout) > 0 {
-		fanout = append(fanout, inm)
-		metrics.NewGlobal(metricsConf, fanout)
-	} else {
+	// The in-memory sinks are always set up so sys/metrics can serve them
+	if len(fanout) == 0 {
 		metricsConf.EnableHostname = false
-		metrics.NewGlobal(metricsConf, inm)
 	}
-	return nil
+	prom := metricsutil.NewPrometheusSink(telConfig.PrometheusRetentionTime)
+	fanout = append(fanout, inm, prom)
+
+	// Initialize the global sink
+	metrics.NewGlobal(metricsConf, fanout)
+	return metricsutil.NewMetricsHelper(inm, prom), nil
 }
 
 func (c *ServerCommand) Reload(configPath []string) error {
@@ -24,7 +24,8 @@ type Config struct {
 	Storage   *Storage    `hcl:"-"`
 	HAStorage *Storage    `hcl:"-"`
 
-	HSM *HSM `hcl:"-"`
+	HSM  *HSM  `hcl:"-"`
+	Seal *Seal `hcl:"-"`
 
 	CacheSize       int         `hcl:"cache_size"`
 	DisableCache    bool        `hcl:"-"`
@@ -35,6 +36,9 @@ type Config struct {
 	EnableUI    bool        `hcl:"-"`
 	EnableUIRaw interface{} `hcl:"ui"`
 
+	EnableStandbyReads    bool        `hcl:"-"`
+	EnableStandbyReadsRaw interface{} `hcl:"enable_standby_reads"`
+
 	Telemetry *Telemetry `hcl:"telemetry"`
 
 	MaxLeaseTTL        time.Duration `hcl:"-"`
@@ -43,6 +47,8 @@ type Config struct {
 	DefaultLeaseTTLRaw interface{}   `hcl:"default_lease_ttl"`
 
 	ClusterName string `hcl:"cluster_name"`
+
+	PluginDirectory string `hcl:"plugin_directory"`
 }
 
 // DevConfig is a Config that is used for dev mode of Vault.
@@ -118,6 +124,16 @@ func (h *HSM) GoString() string {
 	return fmt.Sprintf("*%#v", *h)
 }
 
+// Seal contains the seal configuration for the server
+type Seal struct {
+	Type   string
+	Config map[string]string
+}
+
+func (s *Seal) GoString() string {
+	return fmt.Sprintf("*%#v", *s)
+}
+
 // Telemetry is the telemetry configuration for the server
 type Telemetry struct {
 	StatsiteAddr string `hcl:"statsite_address"`
@@ -125,6 +141,12 @@ type Telemetry struct {
 
 	DisableHostname bool `hcl:"disable_hostname"`
 
+	// PrometheusRetentionTime is how long a metric that is no longer
+	// updated keeps being exposed on sys/metrics?format=prometheus.
+	// Default: 24h
+	PrometheusRetentionTime    time.Duration `hcl:"-"`
+	PrometheusRetentionTimeRaw interface{}   `hcl:"prometheus_retention_time"`
+
 	// Circonus: see https://github.com/circonus-labs/circonus-gometrics
 	// for more details on the various configuration options.
 	// Valid configuration combinations:
@@ -230,6 +252,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.HSM = c2.HSM
 	}
 
+	result.Seal = c.Seal
+	if c2.Seal != nil {
+		result.Seal = c2.Seal
+	}
+
 	result.Telemetry = c.Telemetry
 	if c2.Telemetry != nil {
 		result.Telemetry = c2.Telemetry
@@ -272,6 +299,16 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.EnableUI = c2.EnableUI
 	}
 
+	result.PluginDirectory = c.PluginDirectory
+	if c2.PluginDirectory != "" {
+		result.PluginDirectory = c2.PluginDirectory
+	}
+
+	result.EnableStandbyReads = c.EnableStandbyReads
+	if c2.EnableStandbyReads {
+		result.EnableStandbyReads = c2.EnableStandbyReads
+	}
+
 	return result
 }
 
@@ -330,6 +367,12 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if result.EnableStandbyReadsRaw != nil {
+		if result.EnableStandbyReads, err = parseutil.ParseBool(result.EnableStandbyReadsRaw); err != nil {
+			return nil, err
+		}
+	}
+
 	if result.DisableCacheRaw != nil {
 		if result.DisableCache, err = parseutil.ParseBool(result.DisableCacheRaw); err != nil {
 			return nil, err
@@ -354,6 +397,7 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		"backend",
 		"ha_backend",
 		"hsm",
+		"seal",
 		"listener",
 		"cache_size",
 		"disable_cache",
@@ -363,6 +407,8 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		"default_lease_ttl",
 		"max_lease_ttl",
 		"cluster_name",
+		"plugin_directory",
+		"enable_standby_reads",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return nil, err
@@ -399,6 +445,12 @@ func ParseConfig(d string, logger log.Logger) (*Config, error) {
 		}
 	}
 
+	if o := list.Filter("seal"); len(o.Items) > 0 {
+		if err := parseSeal(&result, o); err != nil {
+			return nil, fmt.Errorf("error parsing 'seal': %s", err)
+		}
+	}
+
 	if o := list.Filter("listener"); len(o.Items) > 0 {
 		if err := parseListeners(&result, o); err != nil {
 			return nil, fmt.Errorf("error parsing 'listener': %s", err)
@@ -640,6 +692,53 @@ func parseHSMs(result *Config, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseSeal(result *Config, list *ast.ObjectList) error {
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'seal' block is permitted")
+	}
+
+	// Get our item
+	item := list.Items[0]
+
+	key := "seal"
+	if len(item.Keys) > 0 {
+		key = item.Keys[0].Token.Value().(string)
+	}
+
+	var valid []string
+	switch key {
+	case "transit":
+		valid = []string{
+			"address",
+			"token",
+			"mount_path",
+			"key_name",
+			"tls_ca_cert",
+			"tls_client_cert",
+			"tls_client_key",
+			"tls_server_name",
+			"tls_skip_verify",
+		}
+	default:
+		return fmt.Errorf("invalid seal type %q", key)
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("seal.%s:", key))
+	}
+
+	var m map[string]string
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("seal.%s:", key))
+	}
+
+	result.Seal = &Seal{
+		Type:   strings.ToLower(key),
+		Config: m,
+	}
+
+	return nil
+}
+
 func parseListeners(result *Config, list *ast.ObjectList) error {
 	var foundAtlas bool
 
@@ -727,6 +826,7 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 		"circonus_broker_id",
 		"circonus_broker_select_tag",
 		"disable_hostname",
+		"prometheus_retention_time",
 		"statsd_address",
 		"statsite_address",
 	}
@@ -746,6 +846,13 @@ func parseTelemetry(result *Config, list *ast.ObjectList) error {
 	if err := hcl.DecodeObject(&result.Telemetry, item.Val); err != nil {
 		return multierror.Prefix(err, "telemetry:")
 	}
+
+	if result.Telemetry.PrometheusRetentionTimeRaw != nil {
+		var err error
+		if result.Telemetry.PrometheusRetentionTime, err = parseutil.ParseDurationSecond(result.Telemetry.PrometheusRetentionTimeRaw); err != nil {
+			return multierror.Prefix(err, "telemetry:")
+		}
+	}
 	return nil
 }
 
@@ -54,10 +54,21 @@ func TestLoadConfigFile(t *testing.T) {
 			DisableClustering: true,
 		},
 
+		Seal: &Seal{
+			Type: "transit",
+			Config: map[string]string{
+				"address":    "https://vault.example.com:8200",
+				"key_name":   "autounseal",
+				"mount_path": "transit/",
+			},
+		},
+
 		Telemetry: &Telemetry{
-			StatsdAddr:      "bar",
-			StatsiteAddr:    "foo",
-			DisableHostname: false,
+			StatsdAddr:                 "bar",
+			StatsiteAddr:               "foo",
+			DisableHostname:            false,
+			PrometheusRetentionTime:    30 * time.Second,
+			PrometheusRetentionTimeRaw: "30s",
 		},
 
 		DisableCache:    true,
@@ -72,6 +83,10 @@ func TestLoadConfigFile(t *testing.T) {
 		DefaultLeaseTTL:    10 * time.Hour,
 		DefaultLeaseTTLRaw: "10h",
 		ClusterName:        "testcluster",
+		PluginDirectory:    "/path/to/plugins",
+
+		EnableStandbyReads:    true,
+		EnableStandbyReadsRaw: true,
 	}
 	if !reflect.DeepEqual(config, expected) {
 		t.Fatalf("expected \n\n%#v\n\n to be \n\n%#v\n\n", config, expected)
@@ -328,3 +343,32 @@ telemetry {
 		t.Errorf("bad error: %q", err)
 	}
 }
+
+func TestParseConfig_badSeal(t *testing.T) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	_, err := ParseConfig(strings.TrimSpace(`
+seal "transit" {
+	key_name = "autounseal"
+	bad  = "one"
+}
+`), logger)
+
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if !strings.Contains(err.Error(), "seal.transit: invalid key 'bad' on line 3") {
+		t.Errorf("bad error: %q", err)
+	}
+
+	_, err = ParseConfig(strings.TrimSpace(`
+seal "unknown" {
+	key_name = "autounseal"
+}
+`), logger)
+
+	if err == nil || !strings.Contains(err.Error(), `invalid seal type "unknown"`) {
+		t.Errorf("bad error: %v", err)
+	}
+}
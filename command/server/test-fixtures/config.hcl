@@ -25,11 +25,20 @@ ha_backend "consul" {
     disable_clustering = "true"
 }
 
+seal "transit" {
+    address = "https://vault.example.com:8200"
+    key_name = "autounseal"
+    mount_path = "transit/"
+}
+
 telemetry {
     statsd_address = "bar"
     statsite_address = "foo"
+    prometheus_retention_time = "30s"
 }
 
 max_lease_ttl = "10h"
 default_lease_ttl = "10h"
 cluster_name = "testcluster"
+plugin_directory = "/path/to/plugins"
+enable_standby_reads = true
@@ -0,0 +1,111 @@
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// kwpIV is the alternative initial value of AES key wrap with padding, as
+// defined in RFC 5649
+var kwpIV = []byte{0xa6, 0x59, 0x59, 0xa6}
+
+// WrapKeyWithPadding wraps the key with the key encryption key using AES
+// key wrap with padding (RFC 5649)
+func WrapKeyWithPadding(kek, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 || uint64(len(key)) > 0xffffffff {
+		return nil, errors.New("invalid key length")
+	}
+
+	// The key is zero padded to a multiple of the semiblock size
+	padded := make([]byte, (len(key)+7)/8*8)
+	copy(padded, key)
+
+	a := make([]byte, 8)
+	copy(a, kwpIV)
+	binary.BigEndian.PutUint32(a[4:], uint32(len(key)))
+
+	// A single semiblock is encrypted directly
+	if len(padded) == 8 {
+		out := make([]byte, 16)
+		block.Encrypt(out, append(a, padded...))
+		return out, nil
+	}
+
+	n := len(padded) / 8
+	r := padded
+	b := make([]byte, 16)
+	for j := 0; j <= 5; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b, a)
+			copy(b[8:], r[(i-1)*8:i*8])
+			block.Encrypt(b, b)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:8])^t)
+			copy(r[(i-1)*8:i*8], b[8:])
+		}
+	}
+
+	return append(a, r...), nil
+}
+
+// UnwrapKeyWithPadding unwraps a key wrapped with AES key wrap with padding
+// (RFC 5649), checking its integrity
+func UnwrapKeyWithPadding(kek, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+		return nil, errors.New("invalid wrapped key length")
+	}
+
+	n := len(wrapped)/8 - 1
+	a := make([]byte, 8)
+	r := make([]byte, n*8)
+
+	if n == 1 {
+		b := make([]byte, 16)
+		block.Decrypt(b, wrapped)
+		copy(a, b[:8])
+		copy(r, b[8:])
+	} else {
+		copy(a, wrapped[:8])
+		copy(r, wrapped[8:])
+		b := make([]byte, 16)
+		for j := 5; j >= 0; j-- {
+			for i := n; i >= 1; i-- {
+				t := uint64(n*j + i)
+				binary.BigEndian.PutUint64(b, binary.BigEndian.Uint64(a)^t)
+				copy(b[8:], r[(i-1)*8:i*8])
+				block.Decrypt(b, b)
+
+				copy(a, b[:8])
+				copy(r[(i-1)*8:i*8], b[8:])
+			}
+		}
+	}
+
+	// Check the integrity of the result: the initial value, a message
+	// length fitting the semiblocks and zero padding
+	if subtle.ConstantTimeCompare(a[:4], kwpIV) != 1 {
+		return nil, errors.New("key unwrapping failed integrity check")
+	}
+	length := int(binary.BigEndian.Uint32(a[4:]))
+	if length <= 8*(n-1) || length > 8*n {
+		return nil, errors.New("key unwrapping failed integrity check")
+	}
+	for _, p := range r[length:] {
+		if p != 0 {
+			return nil, errors.New("key unwrapping failed integrity check")
+		}
+	}
+
+	return r[:length], nil
+}
@@ -0,0 +1,52 @@
+package keysutil
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeyWrapWithPadding(t *testing.T) {
+	// Test vectors from RFC 5649
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	cases := []struct {
+		key     string
+		wrapped string
+	}{
+		{
+			key:     "c37b7e6492584340bed12207808941155068f738",
+			wrapped: "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+		{
+			key:     "466f7250617369",
+			wrapped: "afbeb0f07dfbf5419200f2ccb50bb24f",
+		},
+	}
+
+	for _, tc := range cases {
+		key, _ := hex.DecodeString(tc.key)
+		expected, _ := hex.DecodeString(tc.wrapped)
+
+		wrapped, err := WrapKeyWithPadding(kek, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(wrapped, expected) {
+			t.Fatalf("bad: expected %x, got %x", expected, wrapped)
+		}
+
+		unwrapped, err := UnwrapKeyWithPadding(kek, wrapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(unwrapped, key) {
+			t.Fatalf("bad: expected %x, got %x", key, unwrapped)
+		}
+
+		// Tampering is detected
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err := UnwrapKeyWithPadding(kek, wrapped); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
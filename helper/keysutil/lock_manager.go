@@ -1,12 +1,17 @@
 package keysutil
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"regexp"
 	"sync"
+	"time"
 
+	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
 )
 
 const (
@@ -16,6 +21,10 @@ const (
 
 var (
 	errNeedExclusiveLock = errors.New("an exclusive lock is needed for this operation")
+
+	// policyNameRegex matches the key names allowed in the paths of the
+	// transit backend
+	policyNameRegex = regexp.MustCompile("^" + framework.GenericNameRegex("name") + "$")
 )
 
 // PolicyRequest holds values used when requesting a policy. Most values are
@@ -39,6 +48,9 @@ type PolicyRequest struct {
 	// Whether to allow export
 	Exportable bool
 
+	// Whether to allow rotation of an imported key
+	AllowImportedKeyRotation bool
+
 	// Whether to upsert
 	Upsert bool
 }
@@ -240,31 +252,10 @@ func (lm *LockManager) getPolicyCommon(req PolicyRequest, lockType bool) (*Polic
 			return nil, nil, false, errNeedExclusiveLock
 		}
 
-		switch req.KeyType {
-		case KeyType_AES256_GCM96:
-			if req.Convergent && !req.Derived {
-				return nil, nil, false, fmt.Errorf("convergent encryption requires derivation to be enabled")
-			}
-
-		case KeyType_ECDSA_P256:
-			if req.Derived || req.Convergent {
-				return nil, nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %s", KeyType_ECDSA_P256)
-			}
-
-		default:
-			return nil, nil, false, fmt.Errorf("unsupported key type %v", req.KeyType)
-		}
-
-		p = &Policy{
-			Name:       req.Name,
-			Type:       req.KeyType,
-			Derived:    req.Derived,
-			Exportable: req.Exportable,
-		}
-		if req.Derived {
-			p.KDF = Kdf_hkdf_sha256
-			p.ConvergentEncryption = req.Convergent
-			p.ConvergentVersion = 2
+		p, err = newPolicy(req)
+		if err != nil {
+			lm.UnlockPolicy(lock, lockType)
+			return nil, nil, false, err
 		}
 
 		err = p.Rotate(req.Storage)
@@ -325,6 +316,128 @@ func (lm *LockManager) getPolicyCommon(req PolicyRequest, lockType bool) (*Polic
 	return p, lock, false, nil
 }
 
+// ImportPolicy creates a policy whose first version is the given key
+// material. It is an error for the policy to already exist.
+func (lm *LockManager) ImportPolicy(req PolicyRequest, key []byte) error {
+	lm.cacheMutex.Lock()
+	lock := lm.policyLock(req.Name, exclusive)
+	defer lock.Unlock()
+	defer lm.cacheMutex.Unlock()
+
+	var p *Policy
+	var err error
+
+	if lm.CacheActive() {
+		p = lm.cache[req.Name]
+	}
+	if p == nil {
+		p, err = lm.getStoredPolicy(req.Storage, req.Name)
+		if err != nil {
+			return err
+		}
+	}
+	if p != nil {
+		return errutil.UserError{Err: fmt.Sprintf("key %s already exists", req.Name)}
+	}
+
+	p, err = newPolicy(req)
+	if err != nil {
+		return errutil.UserError{Err: err.Error()}
+	}
+	p.Imported = true
+	p.AllowImportedKeyRotation = req.AllowImportedKeyRotation
+
+	err = p.Import(req.Storage, key)
+	if err != nil {
+		return err
+	}
+
+	if lm.CacheActive() {
+		lm.cache[req.Name] = p
+	}
+
+	return nil
+}
+
+// RestorePolicy recreates a policy from a backup made by Policy.Backup,
+// under the given name or the name it was backed up with if empty. It is an
+// error for the policy to already exist.
+func (lm *LockManager) RestorePolicy(storage logical.Storage, name, backup string) error {
+	buf, err := base64.StdEncoding.DecodeString(backup)
+	if err != nil {
+		return errutil.UserError{Err: "failed to base64-decode backup"}
+	}
+
+	// The keys of the policy are decoded into an existing map
+	keyData := KeyData{
+		Policy: &Policy{
+			Keys: keyEntryMap{},
+		},
+	}
+	if err := jsonutil.DecodeJSON(buf, &keyData); err != nil {
+		return errutil.UserError{Err: fmt.Sprintf("failed to decode backup: %s", err)}
+	}
+	if keyData.Policy == nil || keyData.ArchivedKeys == nil {
+		return errutil.UserError{Err: "backup is missing the key or its archive"}
+	}
+	if err := keyData.Policy.validateRestored(); err != nil {
+		return errutil.UserError{Err: fmt.Sprintf("invalid backup: %s", err)}
+	}
+
+	if name == "" {
+		name = keyData.Policy.Name
+	}
+	if name == "" {
+		return errutil.UserError{Err: "missing name of the key to restore"}
+	}
+	// The name may come from the backup, so it is held to the same rules as
+	// the names of keys created through the API
+	if !policyNameRegex.MatchString(name) {
+		return errutil.UserError{Err: fmt.Sprintf("invalid key name %q", name)}
+	}
+
+	lm.cacheMutex.Lock()
+	lock := lm.policyLock(name, exclusive)
+	defer lock.Unlock()
+	defer lm.cacheMutex.Unlock()
+
+	var p *Policy
+	if lm.CacheActive() {
+		p = lm.cache[name]
+	}
+	if p == nil {
+		p, err = lm.getStoredPolicy(storage, name)
+		if err != nil {
+			return err
+		}
+	}
+	if p != nil {
+		return errutil.UserError{Err: fmt.Sprintf("key %s already exists", name)}
+	}
+
+	p = keyData.Policy
+	p.Name = name
+	p.RestoreInfo = &BackupInfo{
+		Time:    time.Now(),
+		Version: p.LatestVersion,
+	}
+
+	// The archive is written first, as persisting the policy brings it up
+	// to date with the keys of the policy
+	if err := p.storeArchive(keyData.ArchivedKeys, storage); err != nil {
+		return err
+	}
+	if err := p.Persist(storage); err != nil {
+		return err
+	}
+
+	if lm.CacheActive() {
+		lm.cache[name] = p
+	}
+
+	return nil
+}
+
 func (lm *LockManager) DeletePolicy(storage logical.Storage, name string) error {
 	lm.cacheMutex.Lock()
 	lock := lm.policyLock(name, exclusive)
@@ -389,3 +502,36 @@ func (lm *LockManager) getStoredPolicy(storage logical.Storage, name string) (*P
 
 	return policy, nil
 }
+
+// newPolicy returns a policy without any key versions for the request,
+// checking that the options requested are supported by its key type
+func newPolicy(req PolicyRequest) (*Policy, error) {
+	switch req.KeyType {
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		if req.Convergent && !req.Derived {
+			return nil, fmt.Errorf("convergent encryption requires derivation to be enabled")
+		}
+
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA4096:
+		if req.Derived || req.Convergent {
+			return nil, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %s", req.KeyType)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %v", req.KeyType)
+	}
+
+	p := &Policy{
+		Name:       req.Name,
+		Type:       req.KeyType,
+		Derived:    req.Derived,
+		Exportable: req.Exportable,
+	}
+	if req.Derived {
+		p.KDF = Kdf_hkdf_sha256
+		p.ConvergentEncryption = req.Convergent
+		p.ConvergentVersion = 2
+	}
+
+	return p, nil
+}
@@ -2,14 +2,18 @@ package keysutil
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/hmac"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	_ "crypto/sha512"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/json"
@@ -21,6 +25,8 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/hkdf"
 
 	uuid "github.com/hashicorp/go-uuid"
@@ -41,10 +47,32 @@ const (
 const (
 	KeyType_AES256_GCM96 = iota
 	KeyType_ECDSA_P256
+	KeyType_ED25519
+	KeyType_ECDSA_P384
+	KeyType_ECDSA_P521
+	KeyType_RSA2048
+	KeyType_RSA4096
+	KeyType_AES128_GCM96
+	KeyType_ChaCha20_Poly1305
+)
+
+// Signature algorithms of RSA keys
+const (
+	SignatureAlgorithmPSS      = "pss"
+	SignatureAlgorithmPKCS1v15 = "pkcs1v15"
 )
 
 const ErrTooOld = "ciphertext or signature version is disallowed by policy (too old)"
 
+// HashAlgorithms maps the names of the hash algorithms inputs can be signed
+// with to their implementation
+var HashAlgorithms = map[string]crypto.Hash{
+	"sha2-224": crypto.SHA224,
+	"sha2-256": crypto.SHA256,
+	"sha2-384": crypto.SHA384,
+	"sha2-512": crypto.SHA512,
+}
+
 type ecdsaSignature struct {
 	R, S *big.Int
 }
@@ -53,7 +81,7 @@ type KeyType int
 
 func (kt KeyType) EncryptionSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -61,15 +89,47 @@ func (kt KeyType) EncryptionSupported() bool {
 
 func (kt KeyType) DecryptionSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305, KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
 }
 
 func (kt KeyType) SigningSupported() bool {
+	switch kt {
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA4096:
+		return true
+	}
+	return false
+}
+
+// HashSignatureInput returns whether inputs are hashed before being signed.
+// Ed25519 signs the input itself.
+func (kt KeyType) HashSignatureInput() bool {
+	switch kt {
+	case KeyType_ED25519:
+		return false
+	}
+	return true
+}
+
+// Curve returns the curve of ECDSA key types, or nil for other types
+func (kt KeyType) Curve() elliptic.Curve {
 	switch kt {
 	case KeyType_ECDSA_P256:
+		return elliptic.P256()
+	case KeyType_ECDSA_P384:
+		return elliptic.P384()
+	case KeyType_ECDSA_P521:
+		return elliptic.P521()
+	}
+	return nil
+}
+
+// IsRSA returns whether the key type is one of the RSA types
+func (kt KeyType) IsRSA() bool {
+	switch kt {
+	case KeyType_RSA2048, KeyType_RSA4096:
 		return true
 	}
 	return false
@@ -77,32 +137,85 @@ func (kt KeyType) SigningSupported() bool {
 
 func (kt KeyType) DerivationSupported() bool {
 	switch kt {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
 		return true
 	}
 	return false
 }
 
+// symmetricKeySize returns the size in bytes of the keys of symmetric key
+// types, or 0 for other types
+func (kt KeyType) symmetricKeySize() int {
+	switch kt {
+	case KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305:
+		return 32
+	case KeyType_AES128_GCM96:
+		return 16
+	}
+	return 0
+}
+
 func (kt KeyType) String() string {
 	switch kt {
 	case KeyType_AES256_GCM96:
 		return "aes256-gcm96"
 	case KeyType_ECDSA_P256:
 		return "ecdsa-p256"
+	case KeyType_ED25519:
+		return "ed25519"
+	case KeyType_ECDSA_P384:
+		return "ecdsa-p384"
+	case KeyType_ECDSA_P521:
+		return "ecdsa-p521"
+	case KeyType_RSA2048:
+		return "rsa-2048"
+	case KeyType_RSA4096:
+		return "rsa-4096"
+	case KeyType_AES128_GCM96:
+		return "aes128-gcm96"
+	case KeyType_ChaCha20_Poly1305:
+		return "chacha20-poly1305"
 	}
 
 	return "[unknown]"
 }
 
+// ParseKeyType returns the key type with the given name
+func ParseKeyType(name string) (KeyType, error) {
+	switch name {
+	case "aes256-gcm96":
+		return KeyType_AES256_GCM96, nil
+	case "ecdsa-p256":
+		return KeyType_ECDSA_P256, nil
+	case "ed25519":
+		return KeyType_ED25519, nil
+	case "ecdsa-p384":
+		return KeyType_ECDSA_P384, nil
+	case "ecdsa-p521":
+		return KeyType_ECDSA_P521, nil
+	case "rsa-2048":
+		return KeyType_RSA2048, nil
+	case "rsa-4096":
+		return KeyType_RSA4096, nil
+	case "aes128-gcm96":
+		return KeyType_AES128_GCM96, nil
+	case "chacha20-poly1305":
+		return KeyType_ChaCha20_Poly1305, nil
+	}
+	return 0, fmt.Errorf("unknown key type %v", name)
+}
+
 // KeyEntry stores the key and metadata
 type KeyEntry struct {
-	AESKey             []byte   `json:"key"`
-	HMACKey            []byte   `json:"hmac_key"`
-	CreationTime       int64    `json:"creation_time"`
-	EC_X               *big.Int `json:"ec_x"`
-	EC_Y               *big.Int `json:"ec_y"`
-	EC_D               *big.Int `json:"ec_d"`
-	FormattedPublicKey string   `json:"public_key"`
+	AESKey             []byte          `json:"key"`
+	HMACKey            []byte          `json:"hmac_key"`
+	CreationTime       int64           `json:"creation_time"`
+	EC_X               *big.Int        `json:"ec_x"`
+	EC_Y               *big.Int        `json:"ec_y"`
+	EC_D               *big.Int        `json:"ec_d"`
+	Ed25519Key         []byte          `json:"ed25519_key"`
+	RSAKey             *rsa.PrivateKey `json:"rsa_key"`
+	FormattedPublicKey string          `json:"public_key"`
 }
 
 // keyEntryMap is used to allow JSON marshal/unmarshal
@@ -169,6 +282,32 @@ type Policy struct {
 
 	// The type of key
 	Type KeyType `json:"type"`
+
+	// Whether the key material was imported rather than generated, and
+	// whether such a key may still be rotated
+	Imported                 bool `json:"imported"`
+	AllowImportedKeyRotation bool `json:"allow_imported_key_rotation"`
+
+	// Whether the key may be backed up, which exposes its key material in
+	// plaintext
+	AllowPlaintextBackup bool `json:"allow_plaintext_backup"`
+
+	// When the policy was last backed up, and when it was restored
+	BackupInfo  *BackupInfo `json:"backup_info"`
+	RestoreInfo *BackupInfo `json:"restore_info"`
+}
+
+// BackupInfo records when a policy was backed up or restored, and the
+// latest version it had then
+type BackupInfo struct {
+	Time    time.Time `json:"time"`
+	Version int       `json:"version"`
+}
+
+// KeyData is the complete state of a policy, as held by its backups
+type KeyData struct {
+	Policy       *Policy       `json:"policy"`
+	ArchivedKeys *archivedKeys `json:"archived_keys"`
 }
 
 // ArchivedKeys stores old keys. This is used to keep the key loading time sane
@@ -320,6 +459,38 @@ func (p *Policy) Serialize() ([]byte, error) {
 	return json.Marshal(p)
 }
 
+// Backup returns the base64 encoded state of the policy, including all of
+// its key versions and archive. The key material is not encrypted, so the
+// policy must allow plaintext backups.
+func (p *Policy) Backup(storage logical.Storage) (string, error) {
+	if !p.AllowPlaintextBackup {
+		return "", errutil.UserError{Err: fmt.Sprintf("plaintext backup is not allowed for key %s", p.Name)}
+	}
+
+	archive, err := p.LoadArchive(storage)
+	if err != nil {
+		return "", err
+	}
+
+	p.BackupInfo = &BackupInfo{
+		Time:    time.Now(),
+		Version: p.LatestVersion,
+	}
+	if err := p.Persist(storage); err != nil {
+		return "", fmt.Errorf("error persisting backup information: %s", err)
+	}
+
+	buf, err := json.Marshal(&KeyData{
+		Policy:       p,
+		ArchivedKeys: archive,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
+
 func (p *Policy) NeedsUpgrade() bool {
 	// Ensure we've moved from Key -> Keys
 	if p.Key != nil && len(p.Key) > 0 {
@@ -441,19 +612,20 @@ func (p *Policy) DeriveKey(context []byte, ver int) ([]byte, error) {
 		prfLen := kdf.HMACSHA256PRFLen
 		return kdf.CounterMode(prf, prfLen, p.Keys[ver].AESKey, context, 256)
 	case Kdf_hkdf_sha256:
+		size := p.Type.symmetricKeySize()
 		reader := hkdf.New(sha256.New, p.Keys[ver].AESKey, nil, context)
 		derBytes := bytes.NewBuffer(nil)
-		derBytes.Grow(32)
+		derBytes.Grow(size)
 		limReader := &io.LimitedReader{
 			R: reader,
-			N: 32,
+			N: int64(size),
 		}
 		n, err := derBytes.ReadFrom(limReader)
 		if err != nil {
 			return nil, errutil.InternalError{Err: fmt.Sprintf("error reading returned derived bytes: %v", err)}
 		}
-		if n != 32 {
-			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to read enough derived bytes, needed 32, got %d", n)}
+		if n != int64(size) {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to read enough derived bytes, needed %d, got %d", size, n)}
 		}
 		return derBytes.Bytes(), nil
 	default:
@@ -466,80 +638,115 @@ func (p *Policy) Encrypt(context, nonce []byte, value string) (string, error) {
 		return "", errutil.UserError{Err: fmt.Sprintf("message encryption not supported for key type %v", p.Type)}
 	}
 
-	// Guard against a potentially invalid key type
-	switch p.Type {
-	case KeyType_AES256_GCM96:
-	default:
-		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
-	}
-
 	// Decode the plaintext value
 	plaintext, err := base64.StdEncoding.DecodeString(value)
 	if err != nil {
 		return "", errutil.UserError{Err: "failed to base64-decode plaintext"}
 	}
 
-	// Derive the key that should be used
-	key, err := p.DeriveKey(context, p.LatestVersion)
-	if err != nil {
-		return "", err
+	var full []byte
+	switch p.Type {
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		full, err = p.encryptSymmetric(context, nonce, plaintext)
+		if err != nil {
+			return "", err
+		}
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		key := p.Keys[p.LatestVersion].RSAKey
+		if key == nil {
+			return "", errutil.InternalError{Err: "no RSA key found for the latest key version"}
+		}
+		full, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, plaintext, nil)
+		if err != nil {
+			return "", errutil.UserError{Err: fmt.Sprintf("failed to RSA encrypt the plaintext: %v", err)}
+		}
+
+	default:
+		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
 
-	// Guard against a potentially invalid key type
+	// Convert to base64
+	encoded := base64.StdEncoding.EncodeToString(full)
+
+	// Prepend some information
+	encoded = "vault:v" + strconv.Itoa(p.LatestVersion) + ":" + encoded
+
+	return encoded, nil
+}
+
+// aead returns the AEAD of symmetric key types for the key
+func (p *Policy) aead(key []byte) (cipher.AEAD, error) {
 	switch p.Type {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96:
+		// Setup the cipher
+		aesCipher, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
+
+		// Setup the GCM AEAD
+		gcm, err := cipher.NewGCM(aesCipher)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
+		return gcm, nil
+
+	case KeyType_ChaCha20_Poly1305:
+		aead, err := chacha20poly1305.New(key)
+		if err != nil {
+			return nil, errutil.InternalError{Err: err.Error()}
+		}
+		return aead, nil
+
 	default:
-		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
+}
 
-	// Setup the cipher
-	aesCipher, err := aes.NewCipher(key)
+// encryptSymmetric encrypts the plaintext with the latest version of the
+// key, returning the nonce followed by the ciphertext
+func (p *Policy) encryptSymmetric(context, nonce, plaintext []byte) ([]byte, error) {
+	// Derive the key that should be used
+	key, err := p.DeriveKey(context, p.LatestVersion)
 	if err != nil {
-		return "", errutil.InternalError{Err: err.Error()}
+		return nil, err
 	}
 
-	// Setup the GCM AEAD
-	gcm, err := cipher.NewGCM(aesCipher)
+	aead, err := p.aead(key)
 	if err != nil {
-		return "", errutil.InternalError{Err: err.Error()}
+		return nil, err
 	}
 
 	if p.ConvergentEncryption {
 		switch p.ConvergentVersion {
 		case 1:
-			if len(nonce) != gcm.NonceSize() {
-				return "", errutil.UserError{Err: fmt.Sprintf("base64-decoded nonce must be %d bytes long when using convergent encryption with this key", gcm.NonceSize())}
+			if len(nonce) != aead.NonceSize() {
+				return nil, errutil.UserError{Err: fmt.Sprintf("base64-decoded nonce must be %d bytes long when using convergent encryption with this key", aead.NonceSize())}
 			}
 		default:
 			nonceHmac := hmac.New(sha256.New, context)
 			nonceHmac.Write(plaintext)
 			nonceSum := nonceHmac.Sum(nil)
-			nonce = nonceSum[:gcm.NonceSize()]
+			nonce = nonceSum[:aead.NonceSize()]
 		}
 	} else {
 		// Compute random nonce
-		nonce, err = uuid.GenerateRandomBytes(gcm.NonceSize())
+		nonce, err = uuid.GenerateRandomBytes(aead.NonceSize())
 		if err != nil {
-			return "", errutil.InternalError{Err: err.Error()}
+			return nil, errutil.InternalError{Err: err.Error()}
 		}
 	}
 
-	// Encrypt and tag with GCM
-	out := gcm.Seal(nil, nonce, plaintext, nil)
+	// Encrypt and tag with the AEAD
+	out := aead.Seal(nil, nonce, plaintext, nil)
 
 	// Place the encrypted data after the nonce
-	full := out
 	if !p.ConvergentEncryption || p.ConvergentVersion > 1 {
-		full = append(nonce, out...)
+		out = append(nonce, out...)
 	}
 
-	// Convert to base64
-	encoded := base64.StdEncoding.EncodeToString(full)
-
-	// Prepend some information
-	encoded = "vault:v" + strconv.Itoa(p.LatestVersion) + ":" + encoded
-
-	return encoded, nil
+	return out, nil
 }
 
 func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
@@ -580,35 +787,49 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 		return "", errutil.UserError{Err: ErrTooOld}
 	}
 
-	// Derive the key that should be used
-	key, err := p.DeriveKey(context, ver)
+	// Decode the base64
+	decoded, err := base64.StdEncoding.DecodeString(splitVerCiphertext[1])
 	if err != nil {
-		return "", err
+		return "", errutil.UserError{Err: "invalid ciphertext: could not decode base64"}
 	}
 
-	// Guard against a potentially invalid key type
+	var plain []byte
 	switch p.Type {
-	case KeyType_AES256_GCM96:
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		plain, err = p.decryptSymmetric(context, nonce, decoded, ver)
+		if err != nil {
+			return "", err
+		}
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		key := p.Keys[ver].RSAKey
+		if key == nil {
+			return "", errutil.InternalError{Err: "no RSA key found for the key version"}
+		}
+		plain, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, key, decoded, nil)
+		if err != nil {
+			return "", errutil.UserError{Err: "invalid ciphertext: unable to decrypt"}
+		}
+
 	default:
 		return "", errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
 
-	// Decode the base64
-	decoded, err := base64.StdEncoding.DecodeString(splitVerCiphertext[1])
-	if err != nil {
-		return "", errutil.UserError{Err: "invalid ciphertext: could not decode base64"}
-	}
+	return base64.StdEncoding.EncodeToString(plain), nil
+}
 
-	// Setup the cipher
-	aesCipher, err := aes.NewCipher(key)
+// decryptSymmetric decrypts the nonce and ciphertext with the given
+// version of the key
+func (p *Policy) decryptSymmetric(context, nonce, decoded []byte, ver int) ([]byte, error) {
+	// Derive the key that should be used
+	key, err := p.DeriveKey(context, ver)
 	if err != nil {
-		return "", errutil.InternalError{Err: err.Error()}
+		return nil, err
 	}
 
-	// Setup the GCM AEAD
-	gcm, err := cipher.NewGCM(aesCipher)
+	aead, err := p.aead(key)
 	if err != nil {
-		return "", errutil.InternalError{Err: err.Error()}
+		return nil, err
 	}
 
 	// Extract the nonce and ciphertext
@@ -616,17 +837,20 @@ func (p *Policy) Decrypt(context, nonce []byte, value string) (string, error) {
 	if p.ConvergentEncryption && p.ConvergentVersion < 2 {
 		ciphertext = decoded
 	} else {
-		nonce = decoded[:gcm.NonceSize()]
-		ciphertext = decoded[gcm.NonceSize():]
+		if len(decoded) < aead.NonceSize() {
+			return nil, errutil.UserError{Err: "invalid ciphertext: too short"}
+		}
+		nonce = decoded[:aead.NonceSize()]
+		ciphertext = decoded[aead.NonceSize():]
 	}
 
 	// Verify and Decrypt
-	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plain, err := aead.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return "", errutil.UserError{Err: "invalid ciphertext: unable to decrypt"}
+		return nil, errutil.UserError{Err: "invalid ciphertext: unable to decrypt"}
 	}
 
-	return base64.StdEncoding.EncodeToString(plain), nil
+	return plain, nil
 }
 
 func (p *Policy) HMACKey(version int) ([]byte, error) {
@@ -645,18 +869,32 @@ func (p *Policy) HMACKey(version int) ([]byte, error) {
 	return p.Keys[version].HMACKey, nil
 }
 
-func (p *Policy) Sign(hashedInput []byte) (string, error) {
+// Sign signs the input with the latest version of the key. Unless the key
+// type signs inputs directly, the input is first hashed with the given
+// algorithm. The signature algorithm only applies to RSA keys.
+func (p *Policy) Sign(input []byte, hashAlgorithm crypto.Hash, sigAlgorithm string) (string, error) {
 	if !p.Type.SigningSupported() {
 		return "", fmt.Errorf("message signing not supported for key type %v", p.Type)
 	}
 
+	hashedInput := input
+	if p.Type.HashSignatureInput() {
+		if !hashAlgorithm.Available() {
+			return "", errutil.UserError{Err: "unsupported hash algorithm"}
+		}
+		h := hashAlgorithm.New()
+		h.Write(input)
+		hashedInput = h.Sum(nil)
+	}
+
+	keyParams := p.Keys[p.LatestVersion]
+
 	var sig []byte
 	switch p.Type {
-	case KeyType_ECDSA_P256:
-		keyParams := p.Keys[p.LatestVersion]
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
 		key := &ecdsa.PrivateKey{
 			PublicKey: ecdsa.PublicKey{
-				Curve: elliptic.P256(),
+				Curve: p.Type.Curve(),
 				X:     keyParams.EC_X,
 				Y:     keyParams.EC_Y,
 			},
@@ -675,6 +913,23 @@ func (p *Policy) Sign(hashedInput []byte) (string, error) {
 		}
 		sig = marshaledSig
 
+	case KeyType_ED25519:
+		sig = ed25519.Sign(ed25519.PrivateKey(keyParams.Ed25519Key), hashedInput)
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		var err error
+		switch sigAlgorithm {
+		case SignatureAlgorithmPSS:
+			sig, err = rsa.SignPSS(rand.Reader, keyParams.RSAKey, hashAlgorithm, hashedInput, nil)
+		case SignatureAlgorithmPKCS1v15:
+			sig, err = rsa.SignPKCS1v15(rand.Reader, keyParams.RSAKey, hashAlgorithm, hashedInput)
+		default:
+			return "", errutil.UserError{Err: fmt.Sprintf("unsupported signature algorithm %s", sigAlgorithm)}
+		}
+		if err != nil {
+			return "", err
+		}
+
 	default:
 		return "", fmt.Errorf("unsupported key type %v", p.Type)
 	}
@@ -688,7 +943,9 @@ func (p *Policy) Sign(hashedInput []byte) (string, error) {
 	return encoded, nil
 }
 
-func (p *Policy) VerifySignature(hashedInput []byte, sig string) (bool, error) {
+// VerifySignature verifies a signature created by Sign with the same hash
+// and signature algorithms
+func (p *Policy) VerifySignature(input []byte, sig string, hashAlgorithm crypto.Hash, sigAlgorithm string) (bool, error) {
 	if !p.Type.SigningSupported() {
 		return false, errutil.UserError{Err: fmt.Sprintf("message verification not supported for key type %v", p.Type)}
 	}
@@ -716,15 +973,27 @@ func (p *Policy) VerifySignature(hashedInput []byte, sig string) (bool, error) {
 		return false, errutil.UserError{Err: ErrTooOld}
 	}
 
-	switch p.Type {
-	case KeyType_ECDSA_P256:
-		asn1Sig, err := base64.StdEncoding.DecodeString(splitVerSig[1])
-		if err != nil {
-			return false, errutil.UserError{Err: "invalid base64 signature value"}
+	sigBytes, err := base64.StdEncoding.DecodeString(splitVerSig[1])
+	if err != nil {
+		return false, errutil.UserError{Err: "invalid base64 signature value"}
+	}
+
+	hashedInput := input
+	if p.Type.HashSignatureInput() {
+		if !hashAlgorithm.Available() {
+			return false, errutil.UserError{Err: "unsupported hash algorithm"}
 		}
+		h := hashAlgorithm.New()
+		h.Write(input)
+		hashedInput = h.Sum(nil)
+	}
+
+	keyParams := p.Keys[ver]
 
+	switch p.Type {
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
 		var ecdsaSig ecdsaSignature
-		rest, err := asn1.Unmarshal(asn1Sig, &ecdsaSig)
+		rest, err := asn1.Unmarshal(sigBytes, &ecdsaSig)
 		if err != nil {
 			return false, errutil.UserError{Err: "supplied signature is invalid"}
 		}
@@ -732,72 +1001,243 @@ func (p *Policy) VerifySignature(hashedInput []byte, sig string) (bool, error) {
 			return false, errutil.UserError{Err: "supplied signature contains extra data"}
 		}
 
-		keyParams := p.Keys[ver]
 		key := &ecdsa.PublicKey{
-			Curve: elliptic.P256(),
+			Curve: p.Type.Curve(),
 			X:     keyParams.EC_X,
 			Y:     keyParams.EC_Y,
 		}
 
 		return ecdsa.Verify(key, hashedInput, ecdsaSig.R, ecdsaSig.S), nil
+
+	case KeyType_ED25519:
+		key := ed25519.PrivateKey(keyParams.Ed25519Key)
+		return ed25519.Verify(key.Public().(ed25519.PublicKey), hashedInput, sigBytes), nil
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		key := &keyParams.RSAKey.PublicKey
+		switch sigAlgorithm {
+		case SignatureAlgorithmPSS:
+			err = rsa.VerifyPSS(key, hashAlgorithm, hashedInput, sigBytes, nil)
+		case SignatureAlgorithmPKCS1v15:
+			err = rsa.VerifyPKCS1v15(key, hashAlgorithm, hashedInput, sigBytes)
+		default:
+			return false, errutil.UserError{Err: fmt.Sprintf("unsupported signature algorithm %s", sigAlgorithm)}
+		}
+		return err == nil, nil
+
 	default:
 		return false, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
-
-	return false, errutil.InternalError{Err: "no valid key type found"}
 }
 
 func (p *Policy) Rotate(storage logical.Storage) error {
-	if p.Keys == nil {
-		// This is an initial key rotation when generating a new policy. We
-		// don't need to call migrate here because if we've called getPolicy to
-		// get the policy in the first place it will have been run.
-		p.Keys = keyEntryMap{}
-	}
-
-	p.LatestVersion += 1
-	entry := KeyEntry{
-		CreationTime: time.Now().Unix(),
+	if p.Imported && !p.AllowImportedKeyRotation {
+		return errutil.UserError{Err: "rotation of imported keys is not allowed for this policy"}
 	}
 
-	hmacKey, err := uuid.GenerateRandomBytes(32)
+	entry, err := newKeyEntry()
 	if err != nil {
 		return err
 	}
-	entry.HMACKey = hmacKey
 
 	switch p.Type {
-	case KeyType_AES256_GCM96:
-		// Generate a 256bit key
-		newKey, err := uuid.GenerateRandomBytes(32)
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		newKey, err := uuid.GenerateRandomBytes(p.Type.symmetricKeySize())
 		if err != nil {
 			return err
 		}
 		entry.AESKey = newKey
 
-	case KeyType_ECDSA_P256:
-		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
+		privKey, err := ecdsa.GenerateKey(p.Type.Curve(), rand.Reader)
 		if err != nil {
 			return err
 		}
 		entry.EC_D = privKey.D
 		entry.EC_X = privKey.X
 		entry.EC_Y = privKey.Y
-		derBytes, err := x509.MarshalPKIXPublicKey(privKey.Public())
+		entry.FormattedPublicKey, err = formatPublicKey(privKey.Public())
+		if err != nil {
+			return err
+		}
+
+	case KeyType_ED25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		entry.Ed25519Key = priv
+		entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(pub)
+
+	case KeyType_RSA2048, KeyType_RSA4096:
+		bits := 2048
+		if p.Type == KeyType_RSA4096 {
+			bits = 4096
+		}
+		privKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return err
+		}
+		entry.RSAKey = privKey
+		entry.FormattedPublicKey, err = formatPublicKey(privKey.Public())
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.addKeyEntry(storage, entry)
+}
+
+// validateRestored checks a policy decoded from a backup before it is stored:
+// its type must be known, and every version that can be used must have key
+// material for it
+func (p *Policy) validateRestored() error {
+	if _, err := ParseKeyType(p.Type.String()); err != nil {
+		return fmt.Errorf("unknown key type %d", p.Type)
+	}
+	if len(p.Keys) == 0 || p.LatestVersion < 1 {
+		return fmt.Errorf("no key versions")
+	}
+	if p.MinDecryptionVersion < 1 || p.MinDecryptionVersion > p.LatestVersion {
+		return fmt.Errorf("invalid minimum decryption version %d", p.MinDecryptionVersion)
+	}
+
+	for version, entry := range p.Keys {
+		if version < 1 || version > p.LatestVersion {
+			return fmt.Errorf("invalid key version %d", version)
+		}
+		if err := p.Type.validateKeyEntry(entry); err != nil {
+			return fmt.Errorf("key version %d: %s", version, err)
+		}
+	}
+	for version := p.MinDecryptionVersion; version <= p.LatestVersion; version++ {
+		if _, ok := p.Keys[version]; !ok {
+			return fmt.Errorf("missing key version %d", version)
+		}
+	}
+	return nil
+}
+
+// validateKeyEntry checks that the entry holds key material of the type
+func (kt KeyType) validateKeyEntry(entry KeyEntry) error {
+	switch kt {
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		if len(entry.AESKey) != kt.symmetricKeySize() {
+			return fmt.Errorf("invalid key size %d bytes, expected %d", len(entry.AESKey), kt.symmetricKeySize())
+		}
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521:
+		if entry.EC_D == nil || entry.EC_X == nil || entry.EC_Y == nil {
+			return fmt.Errorf("missing %s key", kt)
+		}
+		if !kt.Curve().IsOnCurve(entry.EC_X, entry.EC_Y) {
+			return fmt.Errorf("key is not a valid %s key", kt)
+		}
+	case KeyType_ED25519:
+		if len(entry.Ed25519Key) != ed25519.PrivateKeySize {
+			return fmt.Errorf("invalid key size %d bytes, expected %d", len(entry.Ed25519Key), ed25519.PrivateKeySize)
+		}
+	case KeyType_RSA2048, KeyType_RSA4096:
+		if entry.RSAKey == nil {
+			return fmt.Errorf("missing %s key", kt)
+		}
+		if err := entry.RSAKey.Validate(); err != nil {
+			return fmt.Errorf("key is not a valid %s key: %s", kt, err)
+		}
+	}
+	return nil
+}
+
+// Import adds a new version of the policy holding the given key material:
+// the raw key for symmetric keys, and a PKCS#8 DER encoded private key for the
+// asymmetric key types
+func (p *Policy) Import(storage logical.Storage, key []byte) error {
+	entry, err := newKeyEntry()
+	if err != nil {
+		return err
+	}
+
+	switch p.Type {
+	case KeyType_AES256_GCM96, KeyType_AES128_GCM96, KeyType_ChaCha20_Poly1305:
+		if len(key) != p.Type.symmetricKeySize() {
+			return errutil.UserError{Err: fmt.Sprintf("invalid key size %d bytes, expected %d", len(key), p.Type.symmetricKeySize())}
+		}
+		entry.AESKey = key
+
+	case KeyType_ED25519:
+		priv, err := parseEd25519PrivateKey(key)
 		if err != nil {
-			return fmt.Errorf("error marshaling public key: %s", err)
+			return errutil.UserError{Err: fmt.Sprintf("error parsing ed25519 key: %s", err)}
 		}
-		pemBlock := &pem.Block{
-			Type:  "PUBLIC KEY",
-			Bytes: derBytes,
+		entry.Ed25519Key = priv
+		entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey))
+
+	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_RSA2048, KeyType_RSA4096:
+		parsed, err := x509.ParsePKCS8PrivateKey(key)
+		if err != nil {
+			return errutil.UserError{Err: fmt.Sprintf("error parsing key: %s", err)}
 		}
-		pemBytes := pem.EncodeToMemory(pemBlock)
-		if pemBytes == nil || len(pemBytes) == 0 {
-			return fmt.Errorf("error PEM-encoding public key")
+
+		switch privKey := parsed.(type) {
+		case *ecdsa.PrivateKey:
+			if p.Type.Curve() == nil || privKey.Curve != p.Type.Curve() {
+				return errutil.UserError{Err: fmt.Sprintf("key is not a valid %s key", p.Type)}
+			}
+			entry.EC_D = privKey.D
+			entry.EC_X = privKey.X
+			entry.EC_Y = privKey.Y
+			entry.FormattedPublicKey, err = formatPublicKey(privKey.Public())
+			if err != nil {
+				return err
+			}
+
+		case *rsa.PrivateKey:
+			bits := 2048
+			if p.Type == KeyType_RSA4096 {
+				bits = 4096
+			}
+			if !p.Type.IsRSA() || privKey.N.BitLen() != bits {
+				return errutil.UserError{Err: fmt.Sprintf("key is not a valid %s key", p.Type)}
+			}
+			entry.RSAKey = privKey
+			entry.FormattedPublicKey, err = formatPublicKey(privKey.Public())
+			if err != nil {
+				return err
+			}
+
+		default:
+			return errutil.UserError{Err: fmt.Sprintf("key is not a valid %s key", p.Type)}
 		}
-		entry.FormattedPublicKey = string(pemBytes)
+
+	default:
+		return errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
 
+	return p.addKeyEntry(storage, entry)
+}
+
+// newKeyEntry returns a key entry with a fresh HMAC key and no key material
+func newKeyEntry() (KeyEntry, error) {
+	hmacKey, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return KeyEntry{}, err
+	}
+
+	return KeyEntry{
+		HMACKey:      hmacKey,
+		CreationTime: time.Now().Unix(),
+	}, nil
+}
+
+// addKeyEntry stores the entry as the latest version of the policy
+func (p *Policy) addKeyEntry(storage logical.Storage, entry KeyEntry) error {
+	if p.Keys == nil {
+		// This is the initial version of a new policy. We don't need to call
+		// migrate here because if we've called getPolicy to get the policy in
+		// the first place it will have been run.
+		p.Keys = keyEntryMap{}
+	}
+
+	p.LatestVersion += 1
 	p.Keys[p.LatestVersion] = entry
 
 	// This ensures that with new key creations min decryption version is set
@@ -810,6 +1250,66 @@ func (p *Policy) Rotate(storage logical.Storage) error {
 	return p.Persist(storage)
 }
 
+// ed25519PrivateKey is the PKCS#8 structure of Ed25519 private keys, as
+// defined in RFC 8410
+type ed25519PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// parseEd25519PrivateKey parses a PKCS#8 DER encoded Ed25519 private key
+func parseEd25519PrivateKey(der []byte) (ed25519.PrivateKey, error) {
+	var key ed25519PrivateKey
+	if rest, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after private key")
+	}
+	if !key.Algorithm.Algorithm.Equal(oidEd25519) {
+		return nil, fmt.Errorf("not an ed25519 private key")
+	}
+
+	// The private key is itself an octet string holding the seed
+	var seed []byte
+	if rest, err := asn1.Unmarshal(key.PrivateKey, &seed); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after private key")
+	}
+	if len(seed) != 32 {
+		return nil, fmt.Errorf("invalid seed size %d bytes", len(seed))
+	}
+
+	// Key generation reads exactly the seed from the reader, so this derives
+	// the key the seed defines
+	_, priv, err := ed25519.GenerateKey(bytes.NewReader(seed))
+	if err != nil {
+		return nil, err
+	}
+
+	return priv, nil
+}
+
+// formatPublicKey PEM encodes a public key in PKIX form
+func formatPublicKey(pub crypto.PublicKey) (string, error) {
+	derBytes, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling public key: %s", err)
+	}
+	pemBlock := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	}
+	pemBytes := pem.EncodeToMemory(pemBlock)
+	if pemBytes == nil || len(pemBytes) == 0 {
+		return "", fmt.Errorf("error PEM-encoding public key")
+	}
+	return string(pemBytes), nil
+}
+
 func (p *Policy) MigrateKeyToKeysMap() {
 	p.Keys = keyEntryMap{
 		1: KeyEntry{
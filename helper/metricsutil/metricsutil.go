@@ -0,0 +1,150 @@
+package metricsutil
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// JSONFormat returns the last complete interval of the in-memory sink
+	JSONFormat = "json"
+
+	// PrometheusFormat returns the cumulative metrics in the Prometheus
+	// text exposition format
+	PrometheusFormat = "prometheus"
+)
+
+// MetricsHelper gives access to the metrics held by the in-memory sinks the
+// server sets up, so they can be served over the API
+type MetricsHelper struct {
+	inmemSink      *metrics.InmemSink
+	prometheusSink *PrometheusSink
+}
+
+// NewMetricsHelper creates a helper for the given sinks, either of which
+// may be nil if it is not in use
+func NewMetricsHelper(inmem *metrics.InmemSink, prometheus *PrometheusSink) *MetricsHelper {
+	return &MetricsHelper{
+		inmemSink:      inmem,
+		prometheusSink: prometheus,
+	}
+}
+
+// ResponseForFormat returns the metrics in the given format, defaulting to
+// JSON
+func (m *MetricsHelper) ResponseForFormat(format string) (*logical.Response, error) {
+	switch format {
+	case "", JSONFormat:
+		return m.JSONResponse()
+	case PrometheusFormat:
+		return m.PrometheusResponse()
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported metrics format %q", format)), logical.ErrInvalidRequest
+	}
+}
+
+// PrometheusResponse returns a raw response in the Prometheus text
+// exposition format
+func (m *MetricsHelper) PrometheusResponse() (*logical.Response, error) {
+	if m.prometheusSink == nil {
+		return logical.ErrorResponse("prometheus metrics are not enabled"), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: PrometheusContentType,
+			logical.HTTPRawBody:     m.prometheusSink.Format(),
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
+
+// JSONResponse returns the last complete interval of the in-memory sink
+func (m *MetricsHelper) JSONResponse() (*logical.Response, error) {
+	if m.inmemSink == nil {
+		return logical.ErrorResponse("in-memory metrics are not enabled"), logical.ErrInvalidRequest
+	}
+
+	// The current interval is still being written to, so prefer the one
+	// before it
+	data := m.inmemSink.Data()
+	var interval *metrics.IntervalMetrics
+	switch n := len(data); n {
+	case 0:
+		return nil, fmt.Errorf("no metric intervals have been initialized yet")
+	case 1:
+		interval = data[0]
+	default:
+		interval = data[n-2]
+	}
+
+	interval.RLock()
+	defer interval.RUnlock()
+
+	gauges := make([]map[string]interface{}, 0, len(interval.Gauges))
+	for _, name := range sortedKeys(interval.Gauges) {
+		gauges = append(gauges, map[string]interface{}{
+			"name":  name,
+			"value": interval.Gauges[name],
+		})
+	}
+
+	points := make([]map[string]interface{}, 0, len(interval.Points))
+	for name, values := range interval.Points {
+		points = append(points, map[string]interface{}{
+			"name":   name,
+			"points": values,
+		})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i]["name"].(string) < points[j]["name"].(string)
+	})
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"timestamp": interval.Interval.UTC().Format(time.RFC3339),
+			"gauges":    gauges,
+			"points":    points,
+			"counters":  formatSamples(interval.Counters),
+			"samples":   formatSamples(interval.Samples),
+		},
+	}, nil
+}
+
+func formatSamples(samples map[string]*metrics.AggregateSample) []map[string]interface{} {
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := make([]map[string]interface{}, 0, len(samples))
+	for _, name := range names {
+		s := samples[name]
+		ret = append(ret, map[string]interface{}{
+			"name":   name,
+			"count":  s.Count,
+			"rate":   s.Rate,
+			"sum":    s.Sum,
+			"min":    s.Min,
+			"max":    s.Max,
+			"mean":   s.Mean(),
+			"stddev": s.Stddev(),
+		})
+	}
+	return ret
+}
+
+func sortedKeys(m map[string]float32) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package metricsutil
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestPrometheusSink_Format(t *testing.T) {
+	sink := NewPrometheusSink(0)
+	sink.SetGauge([]string{"vault", "expire", "num_leases"}, 3)
+	sink.IncrCounter([]string{"vault", "route", "read", "secret-"}, 1)
+	sink.IncrCounter([]string{"vault", "route", "read", "secret-"}, 2)
+	sink.AddSample([]string{"vault", "core", "handle_request"}, 1.5)
+	sink.AddSample([]string{"vault", "core", "handle_request"}, 2.5)
+
+	expected := `# TYPE vault_core_handle_request summary
+vault_core_handle_request_sum 4
+vault_core_handle_request_count 2
+# TYPE vault_expire_num_leases gauge
+vault_expire_num_leases 3
+# TYPE vault_route_read_secret_ counter
+vault_route_read_secret_ 3
+`
+	if actual := string(sink.Format()); actual != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestPrometheusSink_Retention(t *testing.T) {
+	sink := NewPrometheusSink(time.Minute)
+	sink.SetGauge([]string{"old"}, 1)
+	sink.SetGauge([]string{"new"}, 1)
+	sink.series["old"].updated = time.Now().Add(-2 * time.Minute)
+
+	if actual := string(sink.Format()); actual != "# TYPE new gauge\nnew 1\n" {
+		t.Fatalf("bad: %s", actual)
+	}
+	if _, ok := sink.series["old"]; ok {
+		t.Fatal("expired metric should be removed")
+	}
+}
+
+func TestMetricsHelper_ResponseForFormat(t *testing.T) {
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	inm.SetGauge([]string{"foo"}, 1)
+	inm.IncrCounter([]string{"bar"}, 2)
+	helper := NewMetricsHelper(inm, NewPrometheusSink(0))
+
+	resp, err := helper.ResponseForFormat("")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	gauges := resp.Data["gauges"].([]map[string]interface{})
+	if len(gauges) != 1 || gauges[0]["name"] != "foo" || gauges[0]["value"] != float32(1) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	counters := resp.Data["counters"].([]map[string]interface{})
+	if len(counters) != 1 || counters[0]["name"] != "bar" || counters[0]["sum"] != float64(2) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp, err = helper.ResponseForFormat(PrometheusFormat)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data[logical.HTTPContentType] != PrometheusContentType ||
+		resp.Data[logical.HTTPStatusCode] != http.StatusOK {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	_, err = helper.ResponseForFormat("xml")
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Prometheus needs its own sink
+	helper = NewMetricsHelper(inm, nil)
+	resp, err = helper.ResponseForFormat(PrometheusFormat)
+	if err != logical.ErrInvalidRequest || !strings.Contains(resp.Data["error"].(string), "not enabled") {
+		t.Fatalf("err: %v", err)
+	}
+}
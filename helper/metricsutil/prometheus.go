@@ -0,0 +1,155 @@
+package metricsutil
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PrometheusContentType is the content type of the Prometheus text
+	// exposition format
+	PrometheusContentType = "text/plain; version=0.0.4"
+
+	// DefaultPrometheusRetentionTime is how long a metric that is no
+	// longer updated keeps being exposed
+	DefaultPrometheusRetentionTime = 24 * time.Hour
+)
+
+var prometheusNameSanitize = regexp.MustCompile("[^a-zA-Z0-9_:]")
+
+type prometheusKind int
+
+const (
+	prometheusGauge prometheusKind = iota
+	prometheusCounter
+	prometheusSummary
+)
+
+func (k prometheusKind) String() string {
+	switch k {
+	case prometheusCounter:
+		return "counter"
+	case prometheusSummary:
+		return "summary"
+	default:
+		return "gauge"
+	}
+}
+
+type prometheusSeries struct {
+	kind    prometheusKind
+	value   float64
+	count   uint64
+	updated time.Time
+}
+
+// PrometheusSink is a metrics.MetricSink that keeps the values of all
+// metrics in memory so they can be scraped by Prometheus. Unlike the
+// InmemSink it does not aggregate into intervals: counters and samples
+// are cumulative, as Prometheus expects.
+type PrometheusSink struct {
+	retention time.Duration
+
+	l      sync.Mutex
+	series map[string]*prometheusSeries
+}
+
+// NewPrometheusSink creates a sink exposing each metric until it has not
+// been updated for the retention time
+func NewPrometheusSink(retention time.Duration) *PrometheusSink {
+	if retention <= 0 {
+		retention = DefaultPrometheusRetentionTime
+	}
+	return &PrometheusSink{
+		retention: retention,
+		series:    make(map[string]*prometheusSeries),
+	}
+}
+
+func (p *PrometheusSink) SetGauge(key []string, val float32) {
+	p.update(key, prometheusGauge, func(s *prometheusSeries) {
+		s.value = float64(val)
+	})
+}
+
+func (p *PrometheusSink) EmitKey(key []string, val float32) {
+	p.SetGauge(key, val)
+}
+
+func (p *PrometheusSink) IncrCounter(key []string, val float32) {
+	p.update(key, prometheusCounter, func(s *prometheusSeries) {
+		s.value += float64(val)
+	})
+}
+
+func (p *PrometheusSink) AddSample(key []string, val float32) {
+	p.update(key, prometheusSummary, func(s *prometheusSeries) {
+		s.value += float64(val)
+		s.count++
+	})
+}
+
+func (p *PrometheusSink) update(key []string, kind prometheusKind, f func(*prometheusSeries)) {
+	name := prometheusName(key)
+
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	s, ok := p.series[name]
+	if !ok || s.kind != kind {
+		s = &prometheusSeries{kind: kind}
+		p.series[name] = s
+	}
+	f(s)
+	s.updated = time.Now()
+}
+
+// Format returns the metrics in the Prometheus text exposition format,
+// dropping those that expired
+func (p *PrometheusSink) Format() []byte {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	expired := time.Now().Add(-p.retention)
+	names := make([]string, 0, len(p.series))
+	for name, s := range p.series {
+		if s.updated.Before(expired) {
+			delete(p.series, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		s := p.series[name]
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, s.kind)
+		if s.kind == prometheusSummary {
+			fmt.Fprintf(&buf, "%s_sum %s\n", name, formatFloat(s.value))
+			fmt.Fprintf(&buf, "%s_count %d\n", name, s.count)
+			continue
+		}
+		fmt.Fprintf(&buf, "%s %s\n", name, formatFloat(s.value))
+	}
+	return buf.Bytes()
+}
+
+// prometheusName turns a metric key into a valid Prometheus metric name
+func prometheusName(key []string) string {
+	name := prometheusNameSanitize.ReplaceAllString(strings.Join(key, "_"), "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -14,6 +14,7 @@ package mfa
 
 import (
 	"github.com/hashicorp/vault/helper/mfa/duo"
+	"github.com/hashicorp/vault/helper/mfa/totp"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
 )
@@ -24,14 +25,16 @@ import (
 func MFAPaths(originalBackend *framework.Backend, loginPath *framework.Path) []*framework.Path {
 	var b backend
 	b.Backend = originalBackend
-	return append(duo.DuoPaths(), pathMFAConfig(&b), wrapLoginPath(&b, loginPath))
+	paths := append(duo.DuoPaths(), totp.TOTPPaths()...)
+	return append(paths, pathMFAConfig(&b), wrapLoginPath(&b, loginPath))
 }
 
 // MFARootPaths returns path strings used to configure MFA. When adding MFA
 // to a backend, these paths should be included in
 // Backend.PathsSpecial.Root.
 func MFARootPaths() []string {
-	return append(duo.DuoRootPaths(), "mfa_config")
+	paths := append(duo.DuoRootPaths(), totp.TOTPRootPaths()...)
+	return append(paths, "mfa_config")
 }
 
 // HandlerFunc is the callback called to handle MFA for a login request.
@@ -39,7 +42,8 @@ type HandlerFunc func(*logical.Request, *framework.FieldData, *logical.Response)
 
 // handlers maps each supported MFA type to its handler.
 var handlers = map[string]HandlerFunc{
-	"duo": duo.DuoHandler,
+	"duo":  duo.DuoHandler,
+	"totp": totp.TOTPHandler,
 }
 
 type backend struct {
@@ -65,7 +69,7 @@ func (b *backend) wrapLoginHandler(loginHandler framework.OperationFunc) framewo
 	return func(req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 		// login with original login function first
 		resp, err := loginHandler(req, d)
-		if err != nil || resp.Auth == nil {
+		if err != nil || resp == nil || resp.Auth == nil {
 			return resp, err
 		}
 
@@ -11,7 +11,7 @@ func pathMFAConfig(b *backend) *framework.Path {
 		Fields: map[string]*framework.FieldSchema{
 			"type": &framework.FieldSchema{
 				Type:        framework.TypeString,
-				Description: "Enables MFA with given backend (available: duo)",
+				Description: "Enables MFA with given backend (available: duo, totp)",
 			},
 		},
 
@@ -84,5 +84,5 @@ Configure multi factor backend.
 
 const pathMFAConfigHelpDesc = `
 This endpoint allows you to turn on multi-factor authentication with a given backend.
-Currently only Duo is supported.
+Duo and TOTP are supported.
 `
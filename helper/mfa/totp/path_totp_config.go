@@ -0,0 +1,142 @@
+package totp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	defaultIssuer          = "Vault"
+	defaultSkew            = 1
+	defaultMaxAttempts     = 5
+	defaultLockoutDuration = 15 * time.Minute
+)
+
+func pathTOTPConfig() *framework.Path {
+	return &framework.Path{
+		Pattern: `totp/config`,
+		Fields: map[string]*framework.FieldSchema{
+			"issuer": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Issuer of the keys users enroll (default \"Vault\")",
+			},
+			"skew": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     defaultSkew,
+				Description: "Number of periods before and after the current one passcodes are accepted for: 0 or 1 (default 1)",
+			},
+			"max_attempts": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     defaultMaxAttempts,
+				Description: "Number of failed passcodes after which the user is locked out, 0 to never lock out (default 5)",
+			},
+			"lockout_duration": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     int(defaultLockoutDuration.Seconds()),
+				Description: "How long users are locked out for (default 15m)",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: pathTOTPConfigWrite,
+			logical.ReadOperation:   pathTOTPConfigRead,
+		},
+
+		HelpSynopsis:    pathTOTPConfigHelpSyn,
+		HelpDescription: pathTOTPConfigHelpDesc,
+	}
+}
+
+func GetTOTPConfig(req *logical.Request) (*TOTPConfig, error) {
+	result := TOTPConfig{
+		Issuer:          defaultIssuer,
+		Skew:            defaultSkew,
+		MaxAttempts:     defaultMaxAttempts,
+		LockoutDuration: defaultLockoutDuration,
+	}
+	// all config parameters are optional, so path need not exist
+	entry, err := req.Storage.Get("totp/config")
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if err := entry.DecodeJSON(&result); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+func pathTOTPConfigWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	issuer := d.Get("issuer").(string)
+	if issuer == "" {
+		issuer = defaultIssuer
+	}
+	skew := d.Get("skew").(int)
+	if skew != 0 && skew != 1 {
+		return nil, errors.New("skew must be 0 or 1")
+	}
+	maxAttempts := d.Get("max_attempts").(int)
+	if maxAttempts < 0 {
+		return nil, errors.New("max_attempts cannot be negative")
+	}
+	lockoutDuration := d.Get("lockout_duration").(int)
+	if lockoutDuration < 0 {
+		return nil, errors.New("lockout_duration cannot be negative")
+	}
+
+	entry, err := logical.StorageEntryJSON("totp/config", TOTPConfig{
+		Issuer:          issuer,
+		Skew:            uint(skew),
+		MaxAttempts:     maxAttempts,
+		LockoutDuration: time.Duration(lockoutDuration) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func pathTOTPConfigRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+
+	config, err := GetTOTPConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer":           config.Issuer,
+			"skew":             config.Skew,
+			"max_attempts":     config.MaxAttempts,
+			"lockout_duration": int64(config.LockoutDuration.Seconds()),
+		},
+	}, nil
+}
+
+type TOTPConfig struct {
+	Issuer          string        `json:"issuer"`
+	Skew            uint          `json:"skew"`
+	MaxAttempts     int           `json:"max_attempts"`
+	LockoutDuration time.Duration `json:"lockout_duration"`
+}
+
+const pathTOTPConfigHelpSyn = `
+Configure TOTP second factor behavior.
+`
+
+const pathTOTPConfigHelpDesc = `
+This endpoint allows you to configure the issuer of the keys users enroll,
+how many periods of clock skew passcodes are accepted for, and how many
+failed passcodes lock a user out and for how long.
+`
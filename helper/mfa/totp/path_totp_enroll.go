@@ -0,0 +1,191 @@
+package totp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+func pathTOTPEnroll() *framework.Path {
+	return &framework.Path{
+		Pattern: `totp/enroll/(?P<username>.+)`,
+		Fields: map[string]*framework.FieldSchema{
+			"username": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Username of the user to enroll, as the auth backend reports it",
+			},
+			"period": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Default:     30,
+				Description: "How long each passcode is valid for (default 30s)",
+			},
+			"digits": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     6,
+				Description: "Number of digits of the passcodes: 6 or 8 (default 6)",
+			},
+			"algorithm": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "SHA1",
+				Description: "Hash algorithm: SHA1, SHA256 or SHA512 (default SHA1)",
+			},
+			"qr_size": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     200,
+				Description: "Width and height in pixels of the QR code, 0 to leave it out (default 200)",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: pathTOTPEnrollWrite,
+			logical.DeleteOperation: pathTOTPEnrollDelete,
+		},
+
+		HelpSynopsis:    pathTOTPEnrollHelpSyn,
+		HelpDescription: pathTOTPEnrollHelpDesc,
+	}
+}
+
+func getTOTPUser(s logical.Storage, username string) (*TOTPUser, error) {
+	entry, err := s.Get("totp/user/" + username)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result TOTPUser
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func putTOTPUser(s logical.Storage, username string, user *TOTPUser) error {
+	entry, err := logical.StorageEntryJSON("totp/user/"+username, user)
+	if err != nil {
+		return err
+	}
+	return s.Put(entry)
+}
+
+func pathTOTPEnrollWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := d.Get("username").(string)
+
+	config, err := GetTOTPConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	period := d.Get("period").(int)
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+
+	var digits otplib.Digits
+	switch d.Get("digits").(int) {
+	case 6:
+		digits = otplib.DigitsSix
+	case 8:
+		digits = otplib.DigitsEight
+	default:
+		return nil, errors.New("digits must be 6 or 8")
+	}
+
+	var algorithm otplib.Algorithm
+	switch d.Get("algorithm").(string) {
+	case "SHA1":
+		algorithm = otplib.AlgorithmSHA1
+	case "SHA256":
+		algorithm = otplib.AlgorithmSHA256
+	case "SHA512":
+		algorithm = otplib.AlgorithmSHA512
+	default:
+		return nil, errors.New("algorithm must be SHA1, SHA256 or SHA512")
+	}
+
+	qrSize := d.Get("qr_size").(int)
+	if qrSize < 0 {
+		return nil, errors.New("qr_size cannot be negative")
+	}
+
+	key, err := totplib.Generate(totplib.GenerateOpts{
+		Issuer:      config.Issuer,
+		AccountName: username,
+		Period:      uint(period),
+		SecretSize:  20,
+		Digits:      digits,
+		Algorithm:   algorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Enrolling again replaces the key and lifts any lockout
+	if err := putTOTPUser(req.Storage, username, &TOTPUser{
+		Key:       key.Secret(),
+		Period:    time.Duration(period) * time.Second,
+		Digits:    digits,
+		Algorithm: algorithm,
+	}); err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"key": key.Secret(),
+			"url": key.String(),
+		},
+	}
+	if qrSize > 0 {
+		img, err := key.Image(qrSize, qrSize)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		resp.Data["barcode"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return resp, nil
+}
+
+func pathTOTPEnrollDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete("totp/user/" + d.Get("username").(string))
+}
+
+// TOTPUser is the key a user enrolled and the state of their logins
+type TOTPUser struct {
+	Key       string           `json:"key"`
+	Period    time.Duration    `json:"period"`
+	Digits    otplib.Digits    `json:"digits"`
+	Algorithm otplib.Algorithm `json:"algorithm"`
+
+	FailedAttempts   int       `json:"failed_attempts"`
+	LockedUntil      time.Time `json:"locked_until"`
+	LastPasscode     string    `json:"last_passcode"`
+	LastPasscodeTime time.Time `json:"last_passcode_time"`
+}
+
+const pathTOTPEnrollHelpSyn = `
+Enroll a user for TOTP second factor authentication.
+`
+
+const pathTOTPEnrollHelpDesc = `
+Writing to this endpoint generates a key for the user and returns it, along
+with an otpauth:// URL and a base64 encoded PNG QR code of it that
+authenticator applications can scan. The key cannot be read back; enrolling
+again replaces it. Deleting the enrollment removes the key, after which the
+user cannot log in while TOTP is enabled.
+`
@@ -0,0 +1,126 @@
+// Package totp provides a TOTP MFA handler to authenticate users with
+// time-based one-time passcodes. This handler is registered as the "totp"
+// type in mfa_config.
+package totp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+// TOTPPaths returns path functions to configure TOTP.
+func TOTPPaths() []*framework.Path {
+	return []*framework.Path{
+		pathTOTPConfig(),
+		pathTOTPEnroll(),
+	}
+}
+
+// TOTPRootPaths returns the paths that are used to configure TOTP.
+func TOTPRootPaths() []string {
+	return []string{
+		"totp/config",
+		"totp/enroll/*",
+	}
+}
+
+// userLock serializes updates to the failed attempts of users
+var userLock sync.Mutex
+
+// TOTPHandler validates the passcode of a login request against the key
+// the user enrolled. If successful, the original response from the login
+// backend is returned. Users are locked out for a while after too many
+// failed passcodes.
+func TOTPHandler(req *logical.Request, d *framework.FieldData, resp *logical.Response) (
+	*logical.Response, error) {
+	config, err := GetTOTPConfig(req)
+	if err != nil || config == nil {
+		return logical.ErrorResponse("Could not load TOTP configuration"), nil
+	}
+
+	username, ok := resp.Auth.Metadata["username"]
+	if !ok {
+		return logical.ErrorResponse("Could not read username for MFA"), nil
+	}
+
+	return totpHandler(req, config, &totpAuthRequest{
+		successResp: resp,
+		username:    username,
+		passcode:    d.Get("passcode").(string),
+		now:         time.Now(),
+	})
+}
+
+type totpAuthRequest struct {
+	successResp *logical.Response
+	username    string
+	passcode    string
+	now         time.Time
+}
+
+func totpHandler(req *logical.Request, config *TOTPConfig, request *totpAuthRequest) (
+	*logical.Response, error) {
+	if request.passcode == "" {
+		return logical.ErrorResponse("TOTP passcode required"), nil
+	}
+
+	userLock.Lock()
+	defer userLock.Unlock()
+
+	user, err := getTOTPUser(req.Storage, request.username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return logical.ErrorResponse("User is not enrolled for TOTP"), nil
+	}
+
+	if request.now.Before(user.LockedUntil) {
+		return logical.ErrorResponse("Too many failed TOTP passcodes; try again later"), nil
+	}
+
+	period := uint(user.Period.Seconds())
+	valid, err := totplib.ValidateCustom(request.passcode, user.Key, request.now, totplib.ValidateOpts{
+		Period:    period,
+		Skew:      config.Skew,
+		Digits:    user.Digits,
+		Algorithm: user.Algorithm,
+	})
+	if err != nil && err != otplib.ErrValidateInputInvalidLength {
+		return nil, fmt.Errorf("error validating passcode: %v", err)
+	}
+
+	// A passcode is accepted for the whole skew window, so the last one used
+	// is remembered to keep it from being replayed
+	if valid && request.passcode == user.LastPasscode &&
+		request.now.Sub(user.LastPasscodeTime) <= time.Duration(2*config.Skew+1)*user.Period {
+		valid = false
+	}
+
+	if !valid {
+		user.FailedAttempts++
+		if config.MaxAttempts > 0 && user.FailedAttempts >= config.MaxAttempts {
+			user.FailedAttempts = 0
+			user.LockedUntil = request.now.Add(config.LockoutDuration)
+		}
+		if err := putTOTPUser(req.Storage, request.username, user); err != nil {
+			return nil, err
+		}
+		return logical.ErrorResponse("Invalid TOTP passcode"), nil
+	}
+
+	user.FailedAttempts = 0
+	user.LastPasscode = request.passcode
+	user.LastPasscodeTime = request.now
+	if err := putTOTPUser(req.Storage, request.username, user); err != nil {
+		return nil, err
+	}
+
+	return request.successResp, nil
+}
@@ -0,0 +1,116 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+func testEnroll(t *testing.T, s logical.Storage, username string) string {
+	b := &framework.Backend{Paths: TOTPPaths()}
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "totp/enroll/" + username,
+		Storage:   s,
+		Data:      map[string]interface{}{},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v %v", resp, err)
+	}
+	if resp.Data["url"] == nil || resp.Data["barcode"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	return resp.Data["key"].(string)
+}
+
+func testRequest(s logical.Storage, username, passcode string, now time.Time) *logical.Response {
+	resp, _ := totpHandler(&logical.Request{Storage: s}, &TOTPConfig{
+		Skew:            1,
+		MaxAttempts:     3,
+		LockoutDuration: time.Minute,
+	}, &totpAuthRequest{
+		successResp: &logical.Response{},
+		username:    username,
+		passcode:    passcode,
+		now:         now,
+	})
+	return resp
+}
+
+func testPasscode(t *testing.T, key string, now time.Time) string {
+	passcode, err := totplib.GenerateCodeCustom(key, now, totplib.ValidateOpts{Digits: otplib.DigitsSix})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return passcode
+}
+
+func TestTOTPHandlerSuccess(t *testing.T) {
+	s := &logical.InmemStorage{}
+	key := testEnroll(t, s, "user")
+	now := time.Now()
+
+	resp := testRequest(s, "user", testPasscode(t, key, now), now)
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Passcodes of the neighboring periods are accepted
+	resp = testRequest(s, "user", testPasscode(t, key, now.Add(-30*time.Second)), now)
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestTOTPHandlerReject(t *testing.T) {
+	s := &logical.InmemStorage{}
+	key := testEnroll(t, s, "user")
+	now := time.Now()
+
+	cases := map[string]*logical.Response{
+		"no passcode":  testRequest(s, "user", "", now),
+		"not enrolled": testRequest(s, "other", testPasscode(t, key, now), now),
+		"expired":      testRequest(s, "user", testPasscode(t, key, now.Add(-2*time.Minute)), now),
+	}
+	for name, resp := range cases {
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected error: %#v", name, resp)
+		}
+	}
+
+	// Passcodes cannot be replayed
+	passcode := testPasscode(t, key, now)
+	if resp := testRequest(s, "user", passcode, now); resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp := testRequest(s, "user", passcode, now.Add(time.Second)); !resp.IsError() {
+		t.Fatalf("expected error: %#v", resp)
+	}
+}
+
+func TestTOTPHandlerLockout(t *testing.T) {
+	s := &logical.InmemStorage{}
+	key := testEnroll(t, s, "user")
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if resp := testRequest(s, "user", "000000000", now); !resp.IsError() {
+			t.Fatalf("expected error: %#v", resp)
+		}
+	}
+
+	// Locked out even with a valid passcode
+	if resp := testRequest(s, "user", testPasscode(t, key, now), now); !resp.IsError() {
+		t.Fatalf("expected error: %#v", resp)
+	}
+
+	// Until the lockout expires
+	later := now.Add(2 * time.Minute)
+	if resp := testRequest(s, "user", testPasscode(t, key, later), later); resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
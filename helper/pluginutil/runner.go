@@ -0,0 +1,300 @@
+package pluginutil
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/yamux"
+	log "github.com/mgutz/logxi/v1"
+)
+
+const (
+	// PluginMagicCookieKey is the environment variable set by Vault when
+	// launching a plugin. Plugins refuse to serve without it, so that they
+	// are not accidentally run by hand.
+	PluginMagicCookieKey = "VAULT_BACKEND_PLUGIN"
+
+	// PluginMagicCookieValue is the value of PluginMagicCookieKey
+	PluginMagicCookieValue = "6669da05-b1c8-4f49-97d9-c8e5bed98e20"
+
+	// PluginProtocolVersion is the version of the protocol spoken between
+	// Vault and its plugins, announced by the plugin in its handshake
+	PluginProtocolVersion = 1
+)
+
+var (
+	// PluginStartTimeout is how long Vault waits for a plugin to announce
+	// itself once started
+	PluginStartTimeout = 1 * time.Minute
+
+	// ErrChecksumMismatch is returned when the executable of a plugin does
+	// not match the checksum registered in the catalog
+	ErrChecksumMismatch = errors.New("checksums did not match")
+
+	// pluginEnvAllowlist are the environment variables of Vault that are
+	// passed on to plugins. The rest of the environment, which may hold
+	// tokens and cloud credentials, is not.
+	pluginEnvAllowlist = []string{
+		"PATH",
+		"TMPDIR",
+		"TZ",
+		"SSL_CERT_FILE",
+		"SSL_CERT_DIR",
+	}
+)
+
+// Looker defines the plugin Lookup function that looks into the plugin
+// catalog for available plugins and returns a PluginRunner
+type Looker interface {
+	LookupPlugin(string) (*PluginRunner, error)
+}
+
+// PluginRunner defines the metadata needed to run a plugin securely
+type PluginRunner struct {
+	Name    string   `json:"name" structs:"name"`
+	Command string   `json:"command" structs:"command"`
+	Args    []string `json:"args" structs:"args"`
+	Sha256  []byte   `json:"sha256" structs:"sha256"`
+}
+
+// PluginClient is the Vault side of the connection to a running plugin
+type PluginClient struct {
+	name    string
+	cmd     *exec.Cmd
+	session *yamux.Session
+	logger  log.Logger
+
+	exitedCh chan struct{}
+	killOnce sync.Once
+}
+
+// pluginEnv returns the environment plugins are started with
+func pluginEnv() []string {
+	env := []string{fmt.Sprintf("%s=%s", PluginMagicCookieKey, PluginMagicCookieValue)}
+	for _, key := range pluginEnvAllowlist {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	return env
+}
+
+// Run verifies the checksum of the plugin executable, starts it and
+// establishes a connection to it
+func (r *PluginRunner) Run(logger log.Logger) (*PluginClient, error) {
+	if err := r.verifyChecksum(); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(r.Command, r.Args...)
+	cmd.Env = pluginEnv()
+
+	// Pipes are used instead of cmd.StdoutPipe so that waiting for the
+	// process does not close them before all the output is read
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		stdout.Close()
+		stdoutW.Close()
+		return nil, err
+	}
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	err = cmd.Start()
+	stdoutW.Close()
+	stderrW.Close()
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		return nil, fmt.Errorf("failed to start plugin %q: %v", r.Name, err)
+	}
+
+	client := &PluginClient{
+		name:     r.Name,
+		cmd:      cmd,
+		logger:   logger,
+		exitedCh: make(chan struct{}),
+	}
+
+	// The plugin logs to its standard error
+	go func() {
+		client.logOutput(stderr)
+		stderr.Close()
+	}()
+
+	linesCh := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			linesCh <- scanner.Text()
+		}
+		close(linesCh)
+		stdout.Close()
+	}()
+
+	go func() {
+		cmd.Wait()
+		close(client.exitedCh)
+	}()
+
+	// Wait for the plugin to announce where it is listening
+	var handshake string
+	var handshakeOk, timedOut bool
+	select {
+	case handshake, handshakeOk = <-linesCh:
+	case <-time.After(PluginStartTimeout):
+		timedOut = true
+	}
+
+	// Anything else written on the standard output is only logged
+	go func() {
+		for line := range linesCh {
+			logger.Debug("plugin: stdout", "plugin", r.Name, "output", line)
+		}
+	}()
+
+	if timedOut {
+		client.Kill()
+		return nil, fmt.Errorf("timed out waiting for plugin %q to start", r.Name)
+	}
+	if !handshakeOk {
+		<-client.exitedCh
+		return nil, fmt.Errorf("plugin %q exited before completing the handshake", r.Name)
+	}
+
+	conn, err := dialHandshake(handshake)
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed to connect to plugin %q: %v", r.Name, err)
+	}
+	session, err := yamux.Client(conn, nil)
+	if err != nil {
+		conn.Close()
+		client.Kill()
+		return nil, err
+	}
+	client.session = session
+
+	return client, nil
+}
+
+// verifyChecksum compares the SHA256 of the plugin executable to the one
+// registered in the catalog
+func (r *PluginRunner) verifyChecksum() error {
+	f, err := os.Open(r.Command)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin executable: %v", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return fmt.Errorf("failed to read plugin executable: %v", err)
+	}
+	if subtle.ConstantTimeCompare(hash.Sum(nil), r.Sha256) != 1 {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// dialHandshake connects to the address announced by the plugin. The
+// handshake has the form "<protocol version>|<network>|<address>".
+func dialHandshake(handshake string) (net.Conn, error) {
+	parts := strings.SplitN(handshake, "|", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid handshake %q", handshake)
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid handshake %q", handshake)
+	}
+	if version != PluginProtocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version %d, expected %d", version, PluginProtocolVersion)
+	}
+	return net.Dial(parts[1], parts[2])
+}
+
+func (c *PluginClient) logOutput(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		c.logger.Info("plugin: "+c.name, "output", scanner.Text())
+	}
+}
+
+// Open opens a new stream to the plugin
+func (c *PluginClient) Open() (net.Conn, error) {
+	return c.session.Open()
+}
+
+// Accept waits for the plugin to open a stream to Vault
+func (c *PluginClient) Accept() (net.Conn, error) {
+	return c.session.Accept()
+}
+
+// Kill closes the connection to the plugin, which makes it exit, and kills
+// it if it does not exit in time
+func (c *PluginClient) Kill() {
+	c.killOnce.Do(func() {
+		if c.session != nil {
+			c.session.Close()
+		}
+
+		select {
+		case <-c.exitedCh:
+		case <-time.After(2 * time.Second):
+			c.cmd.Process.Kill()
+			<-c.exitedCh
+		}
+	})
+}
+
+// Exited returns a channel closed once the plugin process exits
+func (c *PluginClient) Exited() <-chan struct{} {
+	return c.exitedCh
+}
+
+// ServePlugin is used by plugin executables to accept the connection from
+// Vault. It announces the address to connect to on the standard output and
+// returns once Vault is connected.
+func ServePlugin() (*yamux.Session, error) {
+	if os.Getenv(PluginMagicCookieKey) != PluginMagicCookieValue {
+		return nil, errors.New("this executable is a Vault plugin and is meant to be run by Vault")
+	}
+
+	// The socket lives in a directory only accessible to the current user
+	dir, err := ioutil.TempDir("", "vault-plugin")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ln, err := net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	fmt.Fprintf(os.Stdout, "%d|%s|%s\n", PluginProtocolVersion, ln.Addr().Network(), ln.Addr().String())
+
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return yamux.Server(conn, nil)
+}
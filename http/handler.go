@@ -1,9 +1,11 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
@@ -32,6 +34,10 @@ const (
 	// not to use request forwarding
 	NoRequestForwardingHeaderName = "X-Vault-No-Request-Forwarding"
 
+	// NamespaceHeaderName is the name of the header containing the path of
+	// the namespace the request is made in.
+	NamespaceHeaderName = "X-Vault-Namespace"
+
 	// MaxRequestSize is the maximum accepted request size. This is to prevent
 	// a denial of service attack where no Content-Length is provided and the server
 	// is fed ever more data until it exhausts memory.
@@ -61,9 +67,9 @@ func Handler(core *vault.Core) http.Handler {
 	mux.Handle("/v1/sys/wrapping/lookup", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/rewrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
 	mux.Handle("/v1/sys/wrapping/unwrap", handleRequestForwarding(core, handleLogical(core, false, wrappingVerificationFunc)))
-	mux.Handle("/v1/sys/capabilities-self", handleRequestForwarding(core, handleLogical(core, true, nil)))
-	mux.Handle("/v1/sys/", handleRequestForwarding(core, handleLogical(core, true, nil)))
-	mux.Handle("/v1/", handleRequestForwarding(core, handleLogical(core, false, nil)))
+	mux.Handle("/v1/sys/capabilities-self", handleStandbyReads(core, handleLogical(core, true, nil)))
+	mux.Handle("/v1/sys/", handleStandbyReads(core, handleLogical(core, true, nil)))
+	mux.Handle("/v1/", handleStandbyReads(core, handleLogical(core, false, nil)))
 
 	// Wrap the handler in another handler to trigger all help paths.
 	helpWrappedHandler := wrapHelpHandler(mux, core)
@@ -133,6 +139,40 @@ func parseRequest(r *http.Request, w http.ResponseWriter, out interface{}) error
 	return err
 }
 
+// readQueryParams are the query parameters of a read request that are passed
+// to backends as request data, with the path they are limited to if any. Any
+// other parameter is ignored so that backends never see data on reads that
+// they do not expect.
+var readQueryParams = map[string]string{
+	"version": "",
+	"format":  "sys/metrics",
+}
+
+// parseQuery converts the query parameters of a read request of the given
+// path that are allowed into request data.
+func parseQuery(path string, values url.Values) map[string]interface{} {
+	data := map[string]interface{}{}
+	for k, v := range values {
+		limit, ok := readQueryParams[k]
+		if !ok || (limit != "" && limit != path) {
+			continue
+		}
+
+		switch {
+		case len(v) == 0:
+		case len(v) == 1:
+			data[k] = v[0]
+		default:
+			data[k] = v
+		}
+	}
+
+	if len(data) > 0 {
+		return data
+	}
+	return nil
+}
+
 // handleRequestForwarding determines whether to forward a request or not,
 // falling back on the older behavior of redirecting the client
 func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handler {
@@ -203,6 +243,72 @@ func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handle
 	})
 }
 
+// handleStandbyReads serves requests locally on standbys that serve reads,
+// forwarding those the standby cannot serve to the active node as
+// handleRequestForwarding would
+func handleStandbyReads(core *vault.Core, handler http.Handler) http.Handler {
+	forwarding := handleRequestForwarding(core, handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !core.StandbyReadsEnabled() ||
+			r.Header.Get(vault.IntNoForwardingHeaderName) != "" ||
+			r.Header.Get(NoRequestForwardingHeaderName) != "" {
+			forwarding.ServeHTTP(w, r)
+			return
+		}
+
+		// Keep the body around in case the request has to be forwarded after
+		// all
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxRequestSize+1))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		sw := &standbyResponseWriter{
+			header: make(http.Header),
+			body:   new(bytes.Buffer),
+		}
+		handler.ServeHTTP(sw, r)
+		if sw.forward {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			forwarding.ServeHTTP(w, r)
+			return
+		}
+
+		for k, v := range sw.header {
+			for _, j := range v {
+				w.Header().Add(k, j)
+			}
+		}
+		if sw.status != 0 {
+			w.WriteHeader(sw.status)
+		}
+		w.Write(sw.body.Bytes())
+	})
+}
+
+// standbyResponseWriter holds the response to a request a standby attempts
+// to serve locally until it is known that the request needs no forwarding
+type standbyResponseWriter struct {
+	header  http.Header
+	status  int
+	body    *bytes.Buffer
+	forward bool
+}
+
+func (w *standbyResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *standbyResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *standbyResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
 // request is a helper to perform a request and properly exit in the
 // case of an error.
 func request(core *vault.Core, w http.ResponseWriter, rawReq *http.Request, r *logical.Request) (*logical.Response, bool) {
@@ -220,6 +326,12 @@ func request(core *vault.Core, w http.ResponseWriter, rawReq *http.Request, r *l
 
 // respondStandby is used to trigger a redirect in the case that this Vault is currently a hot standby
 func respondStandby(core *vault.Core, w http.ResponseWriter, reqURL *url.URL) {
+	// Requests a standby attempted to serve locally are forwarded instead
+	if sw, ok := w.(*standbyResponseWriter); ok {
+		sw.forward = true
+		return
+	}
+
 	// Request the leader address
 	_, redirectAddr, err := core.Leader()
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
 
@@ -104,6 +105,16 @@ func TestSysMounts_headerAuth(t *testing.T) {
 				},
 				"local": true,
 			},
+			"identity/": map[string]interface{}{
+				"description": "identity store",
+				"type":        "identity",
+				"config": map[string]interface{}{
+					"default_lease_ttl": json.Number("0"),
+					"max_lease_ttl":     json.Number("0"),
+					"force_no_cache":    false,
+				},
+				"local": false,
+			},
 		},
 		"secret/": map[string]interface{}{
 			"description": "generic secret storage",
@@ -135,6 +146,16 @@ func TestSysMounts_headerAuth(t *testing.T) {
 			},
 			"local": true,
 		},
+		"identity/": map[string]interface{}{
+			"description": "identity store",
+			"type":        "identity",
+			"config": map[string]interface{}{
+				"default_lease_ttl": json.Number("0"),
+				"max_lease_ttl":     json.Number("0"),
+				"force_no_cache":    false,
+			},
+			"local": false,
+		},
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -194,6 +215,12 @@ func TestSysMounts_headerAuth_Wrapped(t *testing.T) {
 	}
 	expected["wrap_info"].(map[string]interface{})["creation_time"] = actualCreationTime
 
+	actualAccessor, ok := actual["wrap_info"].(map[string]interface{})["accessor"]
+	if !ok || actualAccessor == "" {
+		t.Fatal("accessor missing in wrap info")
+	}
+	expected["wrap_info"].(map[string]interface{})["accessor"] = actualAccessor
+
 	if !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("bad:\nExpected: %#v\nActual: %#v\n%T %T", expected, actual, actual["warnings"], actual["data"])
 	}
@@ -243,3 +270,31 @@ func TestHandler_error(t *testing.T) {
 	}
 
 }
+
+func TestHandler_parseQuery(t *testing.T) {
+	values := url.Values{
+		"version": []string{"2"},
+		"format":  []string{"prometheus"},
+		"list":    []string{"true"},
+		"help":    []string{"1"},
+		"ttl":     []string{"1h"},
+	}
+	data := parseQuery("secret/data/foo", values)
+	expected := map[string]interface{}{
+		"version": "2",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	// Parameters limited to a path are only passed on for it
+	data = parseQuery("sys/metrics", values)
+	expected["format"] = "prometheus"
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	if data := parseQuery("secret/foo", url.Values{"ttl": []string{"1h"}}); data != nil {
+		t.Fatalf("bad: %#v", data)
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strconv"
@@ -26,8 +27,14 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		return nil, http.StatusNotFound, nil
 	}
 
+	// A namespace given in the header is the same as a path prefix
+	if ns := strings.Trim(r.Header.Get(NamespaceHeaderName), "/"); ns != "" {
+		path = ns + "/" + path
+	}
+
 	// Determine the operation
 	var op logical.Operation
+	var data map[string]interface{}
 	switch r.Method {
 	case "DELETE":
 		op = logical.DeleteOperation
@@ -45,6 +52,9 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 				op = logical.ListOperation
 			}
 		}
+		if op == logical.ReadOperation {
+			data = parseQuery(path, queryVals)
+		}
 	case "POST", "PUT":
 		op = logical.UpdateOperation
 	case "LIST":
@@ -59,9 +69,16 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	// Parse the request if we can
-	var data map[string]interface{}
-	if op == logical.UpdateOperation {
+	// Parse the request if we can; backends taking bodies that are not JSON
+	// get them as is
+	var rawBody []byte
+	if op == logical.UpdateOperation && core.RawRequestBodyPath(path) {
+		var err error
+		rawBody, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxRequestSize))
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	} else if op == logical.UpdateOperation {
 		err := parseRequest(r, w, &data)
 		if err == io.EOF {
 			data = nil
@@ -83,6 +100,7 @@ func buildLogicalRequest(core *vault.Core, w http.ResponseWriter, r *http.Reques
 		Operation:  op,
 		Path:       path,
 		Data:       data,
+		RawBody:    rawBody,
 		Connection: getConnection(r),
 		Headers:    r.Header,
 	})
@@ -150,6 +168,7 @@ func respondLogical(w http.ResponseWriter, r *http.Request, req *logical.Request
 			httpResp = &logical.HTTPResponse{
 				WrapInfo: &logical.HTTPWrapInfo{
 					Token:           resp.WrapInfo.Token,
+					Accessor:        resp.WrapInfo.Accessor,
 					TTL:             int(resp.WrapInfo.TTL.Seconds()),
 					CreationTime:    resp.WrapInfo.CreationTime.Format(time.RFC3339Nano),
 					WrappedAccessor: resp.WrapInfo.WrappedAccessor,
@@ -210,7 +229,7 @@ func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response)
 
 	// Get the content type header; don't require it if the body is empty
 	contentTypeRaw, ok := resp.Data[logical.HTTPContentType]
-	if !ok && !nonEmpty {
+	if !ok && nonEmpty {
 		retErr(w, "no content type given")
 		return
 	}
@@ -236,6 +255,20 @@ func respondRaw(w http.ResponseWriter, r *http.Request, resp *logical.Response)
 		}
 	}
 
+	// Get any additional headers
+	if headersRaw, ok := resp.Data[logical.HTTPHeaders]; ok {
+		headers, ok := headersRaw.(map[string][]string)
+		if !ok {
+			retErr(w, "cannot decode headers")
+			return
+		}
+		for name, values := range headers {
+			for _, value := range values {
+				w.Header().Add(name, value)
+			}
+		}
+	}
+
 	// Write the response
 	if contentType != "" {
 		w.Header().Set("Content-Type", contentType)
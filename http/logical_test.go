@@ -152,6 +152,8 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 			"ttl":              json.Number("0"),
 			"creation_ttl":     json.Number("0"),
 			"explicit_max_ttl": json.Number("0"),
+			"entity_id":        "",
+			"type":             "service",
 		},
 		"warnings":  nilWarnings,
 		"wrap_info": nil,
@@ -177,6 +179,81 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 	logger.Trace("307 test two stopping")
 }
 
+func TestLogical_StandbyReads(t *testing.T) {
+	ln1, addr1 := TestListener(t)
+	defer ln1.Close()
+	ln2, addr2 := TestListener(t)
+	defer ln2.Close()
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	inmha := physical.NewInmemHA(logger)
+	core1, err := vault.NewCore(&vault.CoreConfig{
+		Physical:     inmha,
+		HAPhysical:   inmha,
+		RedirectAddr: addr1,
+		DisableMlock: true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys, root := vault.TestCoreInit(t, core1)
+	for _, key := range keys {
+		if _, err := core1.Unseal(vault.TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	vault.TestWaitActive(t, core1)
+
+	core2, err := vault.NewCore(&vault.CoreConfig{
+		Physical:           inmha,
+		HAPhysical:         inmha,
+		RedirectAddr:       addr2,
+		DisableMlock:       true,
+		EnableStandbyReads: true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := core2.Unseal(vault.TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+
+	TestServerWithListener(t, ln1, addr1, core1)
+	TestServerWithListener(t, ln2, addr2, core2)
+	TestServerAuth(t, addr1, root)
+
+	resp := testHttpPut(t, root, addr1+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+
+	for i := 0; !core2.StandbyReadsEnabled(); i++ {
+		if i == 50 {
+			t.Fatal("standby did not start serving reads")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// READ from the standby is served locally
+	resp = testHttpData(t, "GET", root, addr2+"/v1/secret/foo", nil, true)
+	testResponseStatus(t, resp, 200)
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	if actual["data"].(map[string]interface{})["data"] != "bar" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// WRITE to the standby cannot be forwarded without clustering, so it
+	// falls back to a redirect
+	resp = testHttpPutDisableRedirect(t, root, addr2+"/v1/secret/foo", map[string]interface{}{
+		"data": "baz",
+	})
+	testResponseStatus(t, resp, 307)
+}
+
 func TestLogical_CreateToken(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -201,6 +278,7 @@ func TestLogical_CreateToken(t *testing.T) {
 			"metadata":       nil,
 			"lease_duration": json.Number("0"),
 			"renewable":      false,
+			"token_type":     "service",
 		},
 		"warnings": nilWarnings,
 	}
@@ -242,6 +320,39 @@ func TestLogical_RawHTTP(t *testing.T) {
 	}
 }
 
+func TestLogical_RawRequestBody(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPost(t, token, addr+"/v1/sys/mounts/foo", map[string]interface{}{
+		"type": "http",
+	})
+	testResponseStatus(t, resp, 204)
+
+	post := func(path string) *http.Response {
+		resp, err := http.Post(addr+"/v1/foo/"+path, "application/octet-stream", strings.NewReader("\x00not json"))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp
+	}
+
+	// Paths the backend takes raw bodies for get them as is
+	resp = post("echo")
+	testResponseStatus(t, resp, 200)
+	body := new(bytes.Buffer)
+	io.Copy(body, resp.Body)
+	if body.String() != "\x00not json" {
+		t.Fatalf("Bad: %q", body.Bytes())
+	}
+
+	// Other paths only take JSON, whatever the content type
+	resp = post("raw")
+	testResponseStatus(t, resp, 400)
+}
+
 func TestLogical_RequestSizeLimit(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -293,3 +404,19 @@ func TestLogical_ListSuffix(t *testing.T) {
 		t.Fatal("trailing slash not found on path")
 	}
 }
+
+func TestLogical_NamespaceHeader(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+	req, _ := http.NewRequest("GET", "http://127.0.0.1:8200/v1/secret/foo", nil)
+	req.Header.Set(NamespaceHeaderName, "/team-a/team-b/")
+	lreq, status, err := buildLogicalRequest(core, nil, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 0 {
+		t.Fatalf("got status %d", status)
+	}
+	if lreq.Path != "team-a/team-b/secret/foo" {
+		t.Fatalf("bad: %s", lreq.Path)
+	}
+}
@@ -8,6 +8,20 @@ import (
 	"github.com/hashicorp/vault/vault"
 )
 
+// testSysAuthAccessors copies the generated mount accessors of the actual
+// sys/auth response into the expected one
+func testSysAuthAccessors(t *testing.T, expected, actual map[string]interface{}) {
+	data := expected["data"].(map[string]interface{})
+	for path := range data {
+		accessor := actual[path].(map[string]interface{})["accessor"]
+		if accessor == nil || accessor == "" {
+			t.Fatalf("missing accessor for %q: %#v", path, actual)
+		}
+		data[path].(map[string]interface{})["accessor"] = accessor
+		expected[path].(map[string]interface{})["accessor"] = accessor
+	}
+}
+
 func TestSysAuth(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -49,6 +63,7 @@ func TestSysAuth(t *testing.T) {
 	testResponseBody(t, resp, &actual)
 
 	expected["request_id"] = actual["request_id"]
+	testSysAuthAccessors(t, expected, actual)
 
 	if !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("bad: expected:%#v\nactual:%#v", expected, actual)
@@ -120,6 +135,7 @@ func TestSysEnableAuth(t *testing.T) {
 	testResponseBody(t, resp, &actual)
 
 	expected["request_id"] = actual["request_id"]
+	testSysAuthAccessors(t, expected, actual)
 
 	if !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("bad: expected:%#v\nactual:%#v", expected, actual)
@@ -176,6 +192,7 @@ func TestSysDisableAuth(t *testing.T) {
 	testResponseBody(t, resp, &actual)
 
 	expected["request_id"] = actual["request_id"]
+	testSysAuthAccessors(t, expected, actual)
 
 	if !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("bad: expected:%#v\nactual:%#v", expected, actual)
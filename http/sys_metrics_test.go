@@ -0,0 +1,37 @@
+package http
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysMetrics_Prometheus(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	prom := metricsutil.NewPrometheusSink(0)
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	vault.TestCoreSetMetricsHelper(core, metricsutil.NewMetricsHelper(inm, prom))
+	prom.SetGauge([]string{"vault", "token", "count"}, 2)
+
+	resp := testHttpGet(t, token, addr+"/v1/sys/metrics?format=prometheus")
+	testResponseStatus(t, resp, 200)
+	if ct := resp.Header.Get("Content-Type"); ct != metricsutil.PrometheusContentType {
+		t.Fatalf("bad: content type %q", ct)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.Contains(string(body), "vault_token_count 2\n") {
+		t.Fatalf("bad: %s", body)
+	}
+}
@@ -5,6 +5,35 @@ import (
 	"time"
 )
 
+// TokenType is the type of a token issued by Vault
+type TokenType string
+
+const (
+	// TokenTypeDefault issues service tokens
+	TokenTypeDefault TokenType = ""
+
+	// TokenTypeService tokens are persisted, renewable and revocable, and
+	// can create child tokens
+	TokenTypeService TokenType = "service"
+
+	// TokenTypeBatch tokens are encrypted blobs carrying their own
+	// properties. They are not persisted, so they are cheap to create, but
+	// they cannot be renewed or revoked and have no accessor.
+	TokenTypeBatch TokenType = "batch"
+)
+
+// ParseTokenType parses the name of a token type
+func ParseTokenType(name string) (TokenType, error) {
+	switch TokenType(name) {
+	case TokenTypeDefault, TokenTypeService:
+		return TokenTypeService, nil
+	case TokenTypeBatch:
+		return TokenTypeBatch, nil
+	default:
+		return TokenTypeDefault, fmt.Errorf("unknown token type %q", name)
+	}
+}
+
 // Auth is the resulting authentication information that is part of
 // Response for credential backends.
 type Auth struct {
@@ -51,6 +80,15 @@ type Auth struct {
 
 	// Number of allowed uses of the issued token
 	NumUses int `json:"num_uses" mapstructure:"num_uses" structs:"num_uses"`
+
+	// Alias is the identity of the authenticated user within the credential
+	// backend. If set, the generated token is tied to the entity the alias
+	// belongs to, creating the entity on first login.
+	Alias *Alias `json:"alias" mapstructure:"alias" structs:"alias"`
+
+	// TokenType is the type of token to issue; service tokens are issued
+	// unless the backend asks for another type
+	TokenType TokenType `json:"token_type" mapstructure:"token_type" structs:"token_type"`
 }
 
 func (a *Auth) GoString() string {
@@ -587,6 +587,8 @@ func (t FieldType) Zero() interface{} {
 		return map[string]interface{}{}
 	case TypeDurationSecond:
 		return 0
+	case TypeCommaIntSlice:
+		return []int{}
 	default:
 		panic("unknown type: " + t.String())
 	}
@@ -146,6 +146,37 @@ func TestFieldDataGet(t *testing.T) {
 			"foo",
 			0,
 		},
+
+		"comma int slice type, comma string value": {
+			map[string]*FieldSchema{
+				"foo": &FieldSchema{Type: TypeCommaIntSlice},
+			},
+			map[string]interface{}{
+				"foo": "1,2,3",
+			},
+			"foo",
+			[]int{1, 2, 3},
+		},
+
+		"comma int slice type, slice value": {
+			map[string]*FieldSchema{
+				"foo": &FieldSchema{Type: TypeCommaIntSlice},
+			},
+			map[string]interface{}{
+				"foo": []interface{}{1, "2", 3.0},
+			},
+			"foo",
+			[]int{1, 2, 3},
+		},
+
+		"comma int slice type, unset value": {
+			map[string]*FieldSchema{
+				"foo": &FieldSchema{Type: TypeCommaIntSlice},
+			},
+			map[string]interface{}{},
+			"foo",
+			[]int{},
+		},
 	}
 
 	for name, tc := range cases {
@@ -13,6 +13,11 @@ const (
 	// TypeDurationSecond represent as seconds, this can be either an
 	// integer or go duration format string (e.g. 24h)
 	TypeDurationSecond
+
+	// TypeCommaIntSlice is a helper for TypeSlice that returns a sanitized
+	// slice of Ints. It accepts either a list of integers or a single
+	// comma-separated string.
+	TypeCommaIntSlice
 )
 
 func (t FieldType) String() string {
@@ -27,6 +32,8 @@ func (t FieldType) String() string {
 		return "map"
 	case TypeDurationSecond:
 		return "duration (sec)"
+	case TypeCommaIntSlice:
+		return "slice"
 	default:
 		return "unknown type"
 	}
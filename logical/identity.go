@@ -0,0 +1,14 @@
+package logical
+
+// Alias identifies an authenticated user within a single credential
+// backend mount. Vault maps aliases to entities in its identity store so
+// that the same user logging in through different backends is recognized
+// as one entity.
+type Alias struct {
+	// Name is the identifier of the user in the credential backend, such
+	// as a username. It must be unique within the backend's mount.
+	Name string `json:"name" mapstructure:"name" structs:"name"`
+
+	// Metadata is attached to the alias in the identity store
+	Metadata map[string]string `json:"metadata" mapstructure:"metadata" structs:"metadata"`
+}
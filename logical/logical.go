@@ -79,4 +79,16 @@ type Paths struct {
 	// LocalStorage are paths (prefixes) that are local to this instance; this
 	// indicates that these paths should not be replicated
 	LocalStorage []string
+
+	// StandbyRead are paths that do not need to write storage to serve
+	// reads, so standby nodes may handle their read, list and update
+	// operations locally. Requests that turn out to need a write are still
+	// forwarded to the active node.
+	StandbyRead []string
+
+	// RawRequestBody are paths whose update requests do not have a JSON
+	// body, such as paths implementing a specification with its own
+	// encoding. The HTTP front end passes their body as is in the RawBody
+	// field of the request instead of parsing it into the request data.
+	RawRequestBody []string
 }
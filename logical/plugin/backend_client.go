@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"net/rpc"
+
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+// BackendPluginClient implements logical.Backend by forwarding the calls to
+// a backend served by a plugin
+type BackendPluginClient struct {
+	client *rpc.Client
+	plugin *pluginutil.PluginClient
+	system logical.SystemView
+}
+
+// SetupArgs is the args for the Setup method
+type SetupArgs struct {
+	Config map[string]string
+}
+
+// SetupReply is the reply for the Setup method
+type SetupReply struct {
+	Error *PluginError
+}
+
+// HandleRequestArgs is the args for the HandleRequest method
+type HandleRequestArgs struct {
+	Request *logical.Request
+}
+
+// HandleRequestReply is the reply for the HandleRequest method
+type HandleRequestReply struct {
+	Response *logical.Response
+	Warnings []string
+	Error    *PluginError
+}
+
+// SpecialPathsReply is the reply for the SpecialPaths method
+type SpecialPathsReply struct {
+	Paths *logical.Paths
+}
+
+// HandleExistenceCheckArgs is the args for the HandleExistenceCheck method
+type HandleExistenceCheckArgs struct {
+	Request *logical.Request
+}
+
+// HandleExistenceCheckReply is the reply for the HandleExistenceCheck method
+type HandleExistenceCheckReply struct {
+	CheckFound bool
+	Exists     bool
+	Error      *PluginError
+}
+
+// InitializeReply is the reply for the Initialize method
+type InitializeReply struct {
+	Error *PluginError
+}
+
+// InvalidateKeyArgs is the args for the InvalidateKey method
+type InvalidateKeyArgs struct {
+	Key string
+}
+
+func (b *BackendPluginClient) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	args := &HandleRequestArgs{
+		Request: translateRequest(req),
+	}
+	var reply HandleRequestReply
+	if err := b.client.Call("Plugin.HandleRequest", args, &reply); err != nil {
+		return nil, err
+	}
+
+	if reply.Response != nil {
+		for _, warning := range reply.Warnings {
+			reply.Response.AddWarning(warning)
+		}
+	}
+	if reply.Error != nil {
+		return reply.Response, reply.Error.unwrap()
+	}
+	return reply.Response, nil
+}
+
+func (b *BackendPluginClient) SpecialPaths() *logical.Paths {
+	var reply SpecialPathsReply
+	if err := b.client.Call("Plugin.SpecialPaths", struct{}{}, &reply); err != nil {
+		return nil
+	}
+	return reply.Paths
+}
+
+// System returns the system view of the backend, which is served locally
+func (b *BackendPluginClient) System() logical.SystemView {
+	return b.system
+}
+
+func (b *BackendPluginClient) HandleExistenceCheck(req *logical.Request) (bool, bool, error) {
+	args := &HandleExistenceCheckArgs{
+		Request: translateRequest(req),
+	}
+	var reply HandleExistenceCheckReply
+	if err := b.client.Call("Plugin.HandleExistenceCheck", args, &reply); err != nil {
+		return false, false, err
+	}
+	if reply.Error != nil {
+		return reply.CheckFound, reply.Exists, reply.Error.unwrap()
+	}
+	return reply.CheckFound, reply.Exists, nil
+}
+
+// Cleanup cleans up the backend and stops the plugin
+func (b *BackendPluginClient) Cleanup() {
+	b.client.Call("Plugin.Cleanup", struct{}{}, &struct{}{})
+	b.client.Close()
+	b.plugin.Kill()
+}
+
+func (b *BackendPluginClient) Initialize() error {
+	var reply InitializeReply
+	if err := b.client.Call("Plugin.Initialize", struct{}{}, &reply); err != nil {
+		return err
+	}
+	if reply.Error != nil {
+		return reply.Error.unwrap()
+	}
+	return nil
+}
+
+func (b *BackendPluginClient) InvalidateKey(key string) {
+	b.client.Call("Plugin.InvalidateKey", &InvalidateKeyArgs{Key: key}, &struct{}{})
+}
+
+// translateRequest returns a copy of the request that can be sent to the
+// plugin. The storage is served to the plugin separately, and only the
+// remote address of the connection is kept since the TLS connection state
+// can not be serialized.
+func translateRequest(req *logical.Request) *logical.Request {
+	r := *req
+	r.Storage = nil
+	if req.Connection != nil {
+		r.Connection = &logical.Connection{
+			RemoteAddr: req.Connection.RemoteAddr,
+		}
+	}
+	return &r
+}
@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"net/rpc"
+	"sync"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/yamux"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// BackendPluginServer serves the backend of a plugin to Vault
+type BackendPluginServer struct {
+	session *yamux.Session
+	factory logical.Factory
+	logger  log.Logger
+
+	// backend is created by the factory on Setup, along with the storage
+	// served by Vault that is given to the requests
+	backend logical.Backend
+	storage logical.Storage
+	lock    sync.RWMutex
+}
+
+// Setup creates the backend, with a storage and a system view served by
+// Vault
+func (b *BackendPluginServer) Setup(args *SetupArgs, reply *SetupReply) error {
+	conn, err := b.session.Open()
+	if err != nil {
+		return err
+	}
+	client := rpc.NewClient(conn)
+	storage := &StorageClient{client: client}
+
+	backend, err := b.factory(&logical.BackendConfig{
+		StorageView: storage,
+		Logger:      b.logger,
+		System:      &SystemViewClient{client: client},
+		Config:      args.Config,
+	})
+	if err != nil {
+		client.Close()
+		reply.Error = wrapError(err)
+		return nil
+	}
+
+	b.lock.Lock()
+	b.backend = backend
+	b.storage = storage
+	b.lock.Unlock()
+	return nil
+}
+
+func (b *BackendPluginServer) getBackend() (logical.Backend, logical.Storage, error) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if b.backend == nil {
+		return nil, nil, errNotSetup
+	}
+	return b.backend, b.storage, nil
+}
+
+func (b *BackendPluginServer) HandleRequest(args *HandleRequestArgs, reply *HandleRequestReply) error {
+	backend, storage, err := b.getBackend()
+	if err != nil {
+		return err
+	}
+
+	req := args.Request
+	req.Storage = storage
+
+	resp, err := backend.HandleRequest(req)
+	if resp != nil {
+		reply.Warnings = resp.Warnings()
+	}
+	reply.Response = resp
+	reply.Error = wrapError(err)
+	return nil
+}
+
+func (b *BackendPluginServer) SpecialPaths(_ struct{}, reply *SpecialPathsReply) error {
+	backend, _, err := b.getBackend()
+	if err != nil {
+		return err
+	}
+
+	reply.Paths = backend.SpecialPaths()
+	return nil
+}
+
+func (b *BackendPluginServer) HandleExistenceCheck(args *HandleExistenceCheckArgs, reply *HandleExistenceCheckReply) error {
+	backend, storage, err := b.getBackend()
+	if err != nil {
+		return err
+	}
+
+	req := args.Request
+	req.Storage = storage
+
+	checkFound, exists, err := backend.HandleExistenceCheck(req)
+	reply.CheckFound = checkFound
+	reply.Exists = exists
+	reply.Error = wrapError(err)
+	return nil
+}
+
+func (b *BackendPluginServer) Cleanup(_ struct{}, _ *struct{}) error {
+	backend, _, err := b.getBackend()
+	if err != nil {
+		return err
+	}
+
+	backend.Cleanup()
+	return nil
+}
+
+func (b *BackendPluginServer) Initialize(_ struct{}, reply *InitializeReply) error {
+	backend, _, err := b.getBackend()
+	if err != nil {
+		return err
+	}
+
+	reply.Error = wrapError(backend.Initialize())
+	return nil
+}
+
+func (b *BackendPluginServer) InvalidateKey(args *InvalidateKeyArgs, _ *struct{}) error {
+	backend, _, err := b.getBackend()
+	if err != nil {
+		return err
+	}
+
+	backend.InvalidateKey(args.Key)
+	return nil
+}
@@ -0,0 +1,192 @@
+package plugin
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"os"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/logical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func init() {
+	// Register the concrete types commonly found in the interface values of
+	// request and response data so that gob can encode them
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]string{})
+	gob.Register(map[string]string{})
+	gob.Register([]int{})
+	gob.Register(json.Number(""))
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+}
+
+// NewBackend launches the plugin registered in the catalog under pluginName
+// and returns a logical.Backend served by it. The plugin uses the storage
+// and system view of the given configuration.
+func NewBackend(pluginName string, sys pluginutil.Looker, conf *logical.BackendConfig) (logical.Backend, error) {
+	runner, err := sys.LookupPlugin(pluginName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := runner.Run(conf.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serve the storage and the system view to the plugin
+	server := rpc.NewServer()
+	server.RegisterName("Storage", &StorageServer{impl: conf.StorageView})
+	server.RegisterName("SystemView", &SystemViewServer{impl: conf.System})
+	go func() {
+		for {
+			conn, err := client.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn)
+		}
+	}()
+
+	conn, err := client.Open()
+	if err != nil {
+		client.Kill()
+		return nil, err
+	}
+
+	b := &BackendPluginClient{
+		client: rpc.NewClient(conn),
+		plugin: client,
+		system: conf.System,
+	}
+
+	args := &SetupArgs{
+		Config: conf.Config,
+	}
+	var reply SetupReply
+	if err := b.client.Call("Plugin.Setup", args, &reply); err != nil {
+		b.Cleanup()
+		return nil, err
+	}
+	if reply.Error != nil {
+		b.Cleanup()
+		return nil, reply.Error.unwrap()
+	}
+
+	return b, nil
+}
+
+// Serve is used by plugin executables to serve the backend created by
+// factory to Vault. It returns once Vault closes the connection.
+func Serve(factory logical.Factory) error {
+	session, err := pluginutil.ServePlugin()
+	if err != nil {
+		return err
+	}
+
+	server := rpc.NewServer()
+	server.RegisterName("Plugin", &BackendPluginServer{
+		session: session,
+		factory: factory,
+		logger:  logformat.NewVaultLoggerWithWriter(os.Stderr, log.LevelTrace),
+	})
+
+	for {
+		conn, err := session.Accept()
+		if err != nil {
+			if session.IsClosed() {
+				return nil
+			}
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+// errorType identifies the errors that callers compare against, so that
+// they can be restored on the other side of the RPC boundary
+type errorType uint32
+
+const (
+	errorTypeUnknown errorType = iota
+	errorTypeUnsupportedOperation
+	errorTypeUnsupportedPath
+	errorTypeInvalidRequest
+	errorTypePermissionDenied
+	errorTypeReadOnly
+	errorTypeCoded
+	errorTypeStatusBadRequest
+)
+
+// PluginError is the serializable form of an error returned across the
+// RPC boundary
+type PluginError struct {
+	Type    errorType
+	Message string
+	Code    int
+}
+
+// wrapError converts err to its serializable form
+func wrapError(err error) *PluginError {
+	if err == nil {
+		return nil
+	}
+
+	e := &PluginError{
+		Message: err.Error(),
+	}
+	switch err {
+	case logical.ErrUnsupportedOperation:
+		e.Type = errorTypeUnsupportedOperation
+	case logical.ErrUnsupportedPath:
+		e.Type = errorTypeUnsupportedPath
+	case logical.ErrInvalidRequest:
+		e.Type = errorTypeInvalidRequest
+	case logical.ErrPermissionDenied:
+		e.Type = errorTypePermissionDenied
+	case logical.ErrReadOnly:
+		e.Type = errorTypeReadOnly
+	default:
+		switch t := err.(type) {
+		case logical.HTTPCodedError:
+			e.Type = errorTypeCoded
+			e.Code = t.Code()
+		case *logical.StatusBadRequest:
+			e.Type = errorTypeStatusBadRequest
+		}
+	}
+	return e
+}
+
+// unwrap restores the error
+func (e *PluginError) unwrap() error {
+	switch e.Type {
+	case errorTypeUnsupportedOperation:
+		return logical.ErrUnsupportedOperation
+	case errorTypeUnsupportedPath:
+		return logical.ErrUnsupportedPath
+	case errorTypeInvalidRequest:
+		return logical.ErrInvalidRequest
+	case errorTypePermissionDenied:
+		return logical.ErrPermissionDenied
+	case errorTypeReadOnly:
+		return logical.ErrReadOnly
+	case errorTypeCoded:
+		return logical.CodedError(e.Code, e.Message)
+	case errorTypeStatusBadRequest:
+		return &logical.StatusBadRequest{Err: e.Message}
+	default:
+		return errors.New(e.Message)
+	}
+}
+
+// errNotSetup is returned by the plugin when called before being set up
+var errNotSetup = fmt.Errorf("plugin backend has not been set up")
@@ -0,0 +1,330 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// TestBackendPlugin_Main is not a real test. It is run as the plugin
+// executable by the other tests, which launch the test binary itself.
+func TestBackendPlugin_Main(t *testing.T) {
+	if os.Getenv(pluginutil.PluginMagicCookieKey) == "" {
+		return
+	}
+
+	if err := Serve(mockFactory); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackendPlugin_HandleRequest(t *testing.T) {
+	b, storage := testBackend(t)
+	defer b.Cleanup()
+
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "kv/foo",
+		Data: map[string]interface{}{
+			"value": "bar",
+		},
+		Storage: storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The plugin must have written through the storage of the backend
+	entry, err := storage.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || string(entry.Value) != "bar" {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	resp, err = b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "kv/foo",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"value": "bar",
+		"keys":  []string{"foo"},
+	}
+	if resp == nil || !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if len(resp.Warnings()) != 1 {
+		t.Fatalf("bad: %#v", resp.Warnings())
+	}
+}
+
+func TestBackendPlugin_Errors(t *testing.T) {
+	b, storage := testBackend(t)
+	defer b.Cleanup()
+
+	cases := map[string]error{
+		"invalid-request": logical.ErrInvalidRequest,
+		"permission":      logical.ErrPermissionDenied,
+		"coded":           logical.CodedError(418, "teapot"),
+		"other":           fmt.Errorf("other error"),
+	}
+	for name, expected := range cases {
+		_, err := b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "errors/" + name,
+			Storage:   storage,
+		})
+		if !reflect.DeepEqual(err, expected) {
+			t.Fatalf("%s: expected %#v, got %#v", name, expected, err)
+		}
+	}
+
+	// Paths the plugin does not handle are reported as such
+	_, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "missing",
+		Storage:   storage,
+	})
+	if err != logical.ErrUnsupportedPath {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestBackendPlugin_SpecialPaths(t *testing.T) {
+	b, _ := testBackend(t)
+	defer b.Cleanup()
+
+	paths := b.SpecialPaths()
+	if paths == nil || !reflect.DeepEqual(paths.Unauthenticated, []string{"special"}) {
+		t.Fatalf("bad: %#v", paths)
+	}
+}
+
+func TestBackendPlugin_System(t *testing.T) {
+	b, storage := testBackend(t)
+	defer b.Cleanup()
+
+	// The plugin reads the system view served by Vault
+	resp, err := b.HandleRequest(&logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "system",
+		Storage:   storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["default_lease_ttl"] != int64(24*60*60) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestBackendPlugin_Environment(t *testing.T) {
+	os.Setenv("VAULT_PLUGIN_TEST_SECRET", "secret")
+	defer os.Unsetenv("VAULT_PLUGIN_TEST_SECRET")
+
+	b, storage := testBackend(t)
+	defer b.Cleanup()
+
+	// Only the allowed variables of Vault reach the plugin
+	expected := map[string]string{
+		"VAULT_PLUGIN_TEST_SECRET": "",
+		"PATH":                     os.Getenv("PATH"),
+	}
+	for name, value := range expected {
+		resp, err := b.HandleRequest(&logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "env/" + name,
+			Storage:   storage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data["value"] != value {
+			t.Fatalf("bad: %s: %#v", name, resp.Data)
+		}
+	}
+}
+
+func TestBackendPlugin_ChecksumMismatch(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = new(logical.InmemStorage)
+
+	looker := testLooker(t)
+	looker.runner.Sha256 = []byte("bad")
+	if _, err := NewBackend("mock", looker, config); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func testBackend(t *testing.T) (logical.Backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = new(logical.InmemStorage)
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: 24 * time.Hour,
+		MaxLeaseTTLVal:     48 * time.Hour,
+	}
+
+	b, err := NewBackend("mock", testLooker(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+// staticLooker looks up the test binary as the plugin
+type staticLooker struct {
+	runner *pluginutil.PluginRunner
+}
+
+func (l *staticLooker) LookupPlugin(name string) (*pluginutil.PluginRunner, error) {
+	return l.runner, nil
+}
+
+func testLooker(t *testing.T) *staticLooker {
+	f, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		t.Fatal(err)
+	}
+
+	return &staticLooker{
+		runner: &pluginutil.PluginRunner{
+			Name:    "mock",
+			Command: os.Args[0],
+			Args:    []string{"-test.run=TestBackendPlugin_Main"},
+			Sha256:  hash.Sum(nil),
+		},
+	}
+}
+
+// mockFactory creates the backend served by the test plugin
+func mockFactory(conf *logical.BackendConfig) (logical.Backend, error) {
+	b := &framework.Backend{}
+	*b = framework.Backend{
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{"special"},
+		},
+
+		Paths: []*framework.Path{
+			&framework.Path{
+				Pattern: "kv/(?P<key>.+)",
+				Fields: map[string]*framework.FieldSchema{
+					"key":   &framework.FieldSchema{Type: framework.TypeString},
+					"value": &framework.FieldSchema{Type: framework.TypeString},
+				},
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   mockKVRead,
+					logical.UpdateOperation: mockKVWrite,
+				},
+			},
+			&framework.Path{
+				Pattern: "errors/(?P<type>.+)",
+				Fields: map[string]*framework.FieldSchema{
+					"type": &framework.FieldSchema{Type: framework.TypeString},
+				},
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: mockErrors,
+				},
+			},
+			&framework.Path{
+				Pattern: "env/(?P<name>.+)",
+				Fields: map[string]*framework.FieldSchema{
+					"name": &framework.FieldSchema{Type: framework.TypeString},
+				},
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: mockEnv,
+				},
+			},
+			&framework.Path{
+				Pattern: "system",
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation: mockSystem(b),
+				},
+			},
+		},
+	}
+	return b.Setup(conf)
+}
+
+func mockKVRead(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entry, err := req.Storage.Get(data.Get("key").(string))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	keys, err := req.Storage.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"value": string(entry.Value),
+			"keys":  keys,
+		},
+	}
+	resp.AddWarning("read from a plugin")
+	return resp, nil
+}
+
+func mockKVWrite(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Put(&logical.StorageEntry{
+		Key:   data.Get("key").(string),
+		Value: []byte(data.Get("value").(string)),
+	})
+}
+
+func mockErrors(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	switch data.Get("type").(string) {
+	case "invalid-request":
+		return nil, logical.ErrInvalidRequest
+	case "permission":
+		return nil, logical.ErrPermissionDenied
+	case "coded":
+		return nil, logical.CodedError(418, "teapot")
+	default:
+		return nil, fmt.Errorf("other error")
+	}
+}
+
+func mockEnv(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"value": os.Getenv(data.Get("name").(string)),
+		},
+	}, nil
+}
+
+func mockSystem(b *framework.Backend) framework.OperationFunc {
+	return func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"default_lease_ttl": int64(b.System().DefaultLeaseTTL().Seconds()),
+			},
+		}, nil
+	}
+}
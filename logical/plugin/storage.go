@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"net/rpc"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// StorageClient is an implementation of logical.Storage that communicates
+// over RPC.
+type StorageClient struct {
+	client *rpc.Client
+}
+
+// StorageListReply is the reply for the List method
+type StorageListReply struct {
+	Keys  []string
+	Error *PluginError
+}
+
+// StorageGetReply is the reply for the Get method
+type StorageGetReply struct {
+	Entry *logical.StorageEntry
+	Error *PluginError
+}
+
+// StoragePutReply is the reply for the Put and Delete methods
+type StoragePutReply struct {
+	Error *PluginError
+}
+
+func (s *StorageClient) List(prefix string) ([]string, error) {
+	var reply StorageListReply
+	if err := s.client.Call("Storage.List", prefix, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != nil {
+		return nil, reply.Error.unwrap()
+	}
+	return reply.Keys, nil
+}
+
+func (s *StorageClient) Get(key string) (*logical.StorageEntry, error) {
+	var reply StorageGetReply
+	if err := s.client.Call("Storage.Get", key, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != nil {
+		return nil, reply.Error.unwrap()
+	}
+	return reply.Entry, nil
+}
+
+func (s *StorageClient) Put(entry *logical.StorageEntry) error {
+	var reply StoragePutReply
+	if err := s.client.Call("Storage.Put", entry, &reply); err != nil {
+		return err
+	}
+	if reply.Error != nil {
+		return reply.Error.unwrap()
+	}
+	return nil
+}
+
+func (s *StorageClient) Delete(key string) error {
+	var reply StoragePutReply
+	if err := s.client.Call("Storage.Delete", key, &reply); err != nil {
+		return err
+	}
+	if reply.Error != nil {
+		return reply.Error.unwrap()
+	}
+	return nil
+}
+
+// StorageServer is a net/rpc compatible structure for serving the storage
+// of a backend to its plugin
+type StorageServer struct {
+	impl logical.Storage
+}
+
+func (s *StorageServer) List(prefix string, reply *StorageListReply) error {
+	keys, err := s.impl.List(prefix)
+	reply.Keys = keys
+	reply.Error = wrapError(err)
+	return nil
+}
+
+func (s *StorageServer) Get(key string, reply *StorageGetReply) error {
+	entry, err := s.impl.Get(key)
+	reply.Entry = entry
+	reply.Error = wrapError(err)
+	return nil
+}
+
+func (s *StorageServer) Put(entry *logical.StorageEntry, reply *StoragePutReply) error {
+	reply.Error = wrapError(s.impl.Put(entry))
+	return nil
+}
+
+func (s *StorageServer) Delete(key string, reply *StoragePutReply) error {
+	reply.Error = wrapError(s.impl.Delete(key))
+	return nil
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"errors"
+	"net/rpc"
+	"time"
+
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+// SystemViewClient is an implementation of logical.SystemView that
+// communicates over RPC.
+type SystemViewClient struct {
+	client *rpc.Client
+}
+
+// SudoPrivilegeArgs is the args for the SudoPrivilege method
+type SudoPrivilegeArgs struct {
+	Path  string
+	Token string
+}
+
+// SystemViewReply is the reply for the methods of the system view
+type SystemViewReply struct {
+	Duration         time.Duration
+	Bool             bool
+	ReplicationState consts.ReplicationState
+}
+
+func (s *SystemViewClient) call(method string, args interface{}) SystemViewReply {
+	var reply SystemViewReply
+	if args == nil {
+		args = struct{}{}
+	}
+	// The interface has no way to report errors; the zero values are the
+	// safest to return
+	s.client.Call("SystemView."+method, args, &reply)
+	return reply
+}
+
+func (s *SystemViewClient) DefaultLeaseTTL() time.Duration {
+	return s.call("DefaultLeaseTTL", nil).Duration
+}
+
+func (s *SystemViewClient) MaxLeaseTTL() time.Duration {
+	return s.call("MaxLeaseTTL", nil).Duration
+}
+
+func (s *SystemViewClient) SudoPrivilege(path string, token string) bool {
+	return s.call("SudoPrivilege", &SudoPrivilegeArgs{Path: path, Token: token}).Bool
+}
+
+func (s *SystemViewClient) Tainted() bool {
+	return s.call("Tainted", nil).Bool
+}
+
+func (s *SystemViewClient) CachingDisabled() bool {
+	return s.call("CachingDisabled", nil).Bool
+}
+
+func (s *SystemViewClient) ReplicationState() consts.ReplicationState {
+	return s.call("ReplicationState", nil).ReplicationState
+}
+
+// LookupPlugin is not available to plugins
+func (s *SystemViewClient) LookupPlugin(name string) (*pluginutil.PluginRunner, error) {
+	return nil, errors.New("cannot call LookupPlugin from a plugin backend")
+}
+
+// SystemViewServer is a net/rpc compatible structure for serving the system
+// view of a backend to its plugin
+type SystemViewServer struct {
+	impl logical.SystemView
+}
+
+func (s *SystemViewServer) DefaultLeaseTTL(_ struct{}, reply *SystemViewReply) error {
+	reply.Duration = s.impl.DefaultLeaseTTL()
+	return nil
+}
+
+func (s *SystemViewServer) MaxLeaseTTL(_ struct{}, reply *SystemViewReply) error {
+	reply.Duration = s.impl.MaxLeaseTTL()
+	return nil
+}
+
+func (s *SystemViewServer) SudoPrivilege(args *SudoPrivilegeArgs, reply *SystemViewReply) error {
+	reply.Bool = s.impl.SudoPrivilege(args.Path, args.Token)
+	return nil
+}
+
+func (s *SystemViewServer) Tainted(_ struct{}, reply *SystemViewReply) error {
+	reply.Bool = s.impl.Tainted()
+	return nil
+}
+
+func (s *SystemViewServer) CachingDisabled(_ struct{}, reply *SystemViewReply) error {
+	reply.Bool = s.impl.CachingDisabled()
+	return nil
+}
+
+func (s *SystemViewServer) ReplicationState(_ struct{}, reply *SystemViewReply) error {
+	reply.ReplicationState = s.impl.ReplicationState()
+	return nil
+}
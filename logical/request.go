@@ -41,6 +41,10 @@ type Request struct {
 	// Request data is an opaque map that must have string keys.
 	Data map[string]interface{} `json:"map" structs:"data" mapstructure:"data"`
 
+	// RawBody is the unparsed body of update requests to the paths a backend
+	// lists in the RawRequestBody special paths; Data is empty for them.
+	RawBody []byte `json:"-" structs:"-" mapstructure:"-"`
+
 	// Storage can be used to durably store and retrieve state.
 	Storage Storage `json:"-"`
 
@@ -92,6 +96,10 @@ type Request struct {
 	// For replication, contains the last WAL on the remote side after handling
 	// the request, used for best-effort avoidance of stale read-after-write
 	lastRemoteWAL uint64
+
+	// Set when a request to a control grouped path is replayed after it was
+	// authorized, so that it is not held for approval again
+	controlGroupApproved bool
 }
 
 // Get returns a data field and guards for nil Data
@@ -121,6 +129,14 @@ func (r *Request) SetLastRemoteWAL(last uint64) {
 	r.lastRemoteWAL = last
 }
 
+func (r *Request) ControlGroupApproved() bool {
+	return r.controlGroupApproved
+}
+
+func (r *Request) SetControlGroupApproved(approved bool) {
+	r.controlGroupApproved = approved
+}
+
 // RenewRequest creates the structure of the renew request.
 func RenewRequest(
 	path string, secret *Secret, data map[string]interface{}) *Request {
@@ -26,6 +26,12 @@ const (
 	// This can only be specified for non-secrets, and should should be similarly
 	// avoided like the HTTPContentType. The value must be an integer.
 	HTTPStatusCode = "http_status_code"
+
+	// HTTPHeaders are additional headers of the response that goes with the
+	// HTTPContentType. This can only be specified for non-secrets, and should
+	// be similarly avoided like the HTTPContentType. The value must be a
+	// map[string][]string.
+	HTTPHeaders = "http_headers"
 )
 
 type ResponseWrapInfo struct {
@@ -36,6 +42,9 @@ type ResponseWrapInfo struct {
 	// The token containing the wrapped response
 	Token string `json:"token" structs:"token" mapstructure:"token"`
 
+	// The accessor of the wrapping token
+	Accessor string `json:"accessor" structs:"accessor" mapstructure:"accessor"`
+
 	// The creation time. This can be used with the TTL to figure out an
 	// expected expiration.
 	CreationTime time.Time `json:"creation_time" structs:"creation_time" mapstructure:"cration_time"`
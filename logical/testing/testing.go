@@ -1,17 +1,23 @@
 package testing
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	log "github.com/mgutz/logxi/v1"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/builtin/logical/plugin"
 	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/logical"
@@ -38,6 +44,13 @@ type TestCase struct {
 	// backend requires more construction
 	Factory logical.Factory
 
+	// PluginCommand can be used instead of Backend or Factory to test a
+	// backend served by an external plugin. It is the path of the plugin
+	// executable, which is registered in the plugin catalog and mounted
+	// with the "plugin" type. PluginArgs are given to the executable.
+	PluginCommand string
+	PluginArgs    []string
+
 	// Steps are the set of operations that are run for this test case.
 	Steps []TestStep
 
@@ -128,15 +141,15 @@ func Test(tt TestT, c TestCase) {
 	}
 
 	// Check that something is provided
-	if c.Backend == nil && c.Factory == nil {
-		tt.Fatal("Must provide either Backend or Factory")
+	if c.Backend == nil && c.Factory == nil && c.PluginCommand == "" {
+		tt.Fatal("Must provide either Backend, Factory or PluginCommand")
 		return
 	}
 
 	// Create an in-memory Vault core
 	logger := logformat.NewVaultLogger(log.LevelTrace)
 
-	core, err := vault.NewCore(&vault.CoreConfig{
+	coreConfig := &vault.CoreConfig{
 		Physical: physical.NewInmem(logger),
 		LogicalBackends: map[string]logical.Factory{
 			"test": func(conf *logical.BackendConfig) (logical.Backend, error) {
@@ -147,7 +160,20 @@ func Test(tt TestT, c TestCase) {
 			},
 		},
 		DisableMlock: true,
-	})
+	}
+
+	// Plugins are run from the directory of the plugin executable
+	if c.PluginCommand != "" {
+		pluginCommand, err := filepath.Abs(c.PluginCommand)
+		if err != nil {
+			tt.Fatal("error resolving plugin command: ", err)
+			return
+		}
+		coreConfig.PluginDirectory = filepath.Dir(pluginCommand)
+		coreConfig.LogicalBackends["plugin"] = plugin.Factory
+	}
+
+	core, err := vault.NewCore(coreConfig)
 	if err != nil {
 		tt.Fatal("error initializing core: ", err)
 		return
@@ -195,6 +221,14 @@ func Test(tt TestT, c TestCase) {
 		Type:        "test",
 		Description: "acceptance test",
 	}
+	if c.PluginCommand != "" {
+		if err := testRegisterPlugin(client, "test-plugin", c.PluginCommand, c.PluginArgs); err != nil {
+			tt.Fatal("error registering plugin: ", err)
+			return
+		}
+		mountInfo.Type = "plugin"
+		mountInfo.Config.PluginName = "test-plugin"
+	}
 	if err := client.Sys().Mount(prefix, mountInfo); err != nil {
 		tt.Fatal("error mounting backend: ", err)
 		return
@@ -334,6 +368,27 @@ func Test(tt TestT, c TestCase) {
 	}
 }
 
+// testRegisterPlugin registers the plugin executable at the given path in
+// the plugin catalog, under the given name
+func testRegisterPlugin(client *api.Client, name, command string, args []string) error {
+	f, err := os.Open(command)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+
+	parts := append([]string{filepath.Base(command)}, args...)
+	return client.Sys().RegisterPlugin(name, &api.PluginInput{
+		Command: strings.Join(parts, " "),
+		SHA256:  hex.EncodeToString(hash.Sum(nil)),
+	})
+}
+
 // TestCheckMulti is a helper to have multiple checks.
 func TestCheckMulti(fs ...TestCheckFunc) TestCheckFunc {
 	return func(resp *logical.Response) error {
@@ -1,8 +1,14 @@
 package testing
 
 import (
+	"fmt"
 	"os"
 	"testing"
+
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/hashicorp/vault/logical/plugin"
 )
 
 func init() {
@@ -43,6 +49,62 @@ func TestTest_preCheck(t *testing.T) {
 	}
 }
 
+// TestTest_pluginMain is not a real test. It is run as the plugin
+// executable by TestTest_plugin, which launches the test binary itself.
+func TestTest_pluginMain(t *testing.T) {
+	if os.Getenv(pluginutil.PluginMagicCookieKey) == "" {
+		return
+	}
+
+	err := plugin.Serve(func(conf *logical.BackendConfig) (logical.Backend, error) {
+		b := &framework.Backend{
+			Paths: []*framework.Path{
+				&framework.Path{
+					Pattern: "echo",
+					Fields: map[string]*framework.FieldSchema{
+						"value": &framework.FieldSchema{Type: framework.TypeString},
+					},
+					Callbacks: map[logical.Operation]framework.OperationFunc{
+						logical.UpdateOperation: func(req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+							return &logical.Response{
+								Data: map[string]interface{}{
+									"value": data.Get("value"),
+								},
+							}, nil
+						},
+					},
+				},
+			},
+		}
+		return b.Setup(conf)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTest_plugin(t *testing.T) {
+	Test(t, TestCase{
+		PluginCommand: os.Args[0],
+		PluginArgs:    []string{"-test.run=TestTest_pluginMain"},
+		Steps: []TestStep{
+			TestStep{
+				Operation: logical.UpdateOperation,
+				Path:      "echo",
+				Data: map[string]interface{}{
+					"value": "foo",
+				},
+				Check: func(resp *logical.Response) error {
+					if resp == nil || resp.Data["value"] != "foo" {
+						return fmt.Errorf("bad: %#v", resp)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
 // mockT implements TestT for testing
 type mockT struct {
 	ErrorCalled bool
@@ -30,6 +30,7 @@ func LogicalResponseToHTTPResponse(input *Response) *HTTPResponse {
 			ClientToken:   input.Auth.ClientToken,
 			Accessor:      input.Auth.Accessor,
 			Policies:      input.Auth.Policies,
+			TokenType:     string(input.Auth.TokenType),
 			Metadata:      input.Auth.Metadata,
 			LeaseDuration: int(input.Auth.TTL.Seconds()),
 			Renewable:     input.Auth.Renewable,
@@ -85,10 +86,12 @@ type HTTPAuth struct {
 	Metadata      map[string]string `json:"metadata"`
 	LeaseDuration int               `json:"lease_duration"`
 	Renewable     bool              `json:"renewable"`
+	TokenType     string            `json:"token_type,omitempty"`
 }
 
 type HTTPWrapInfo struct {
 	Token           string `json:"token"`
+	Accessor        string `json:"accessor,omitempty"`
 	TTL             int    `json:"ttl"`
 	CreationTime    string `json:"creation_time"`
 	WrappedAccessor string `json:"wrapped_accessor,omitempty"`
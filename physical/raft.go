@@ -0,0 +1,859 @@
+package physical
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/mgutz/logxi/v1"
+
+	"github.com/armon/go-radix"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/vault/helper/jsonutil"
+)
+
+const (
+	// raftApplyTimeout is how long we wait for a command to be committed
+	raftApplyTimeout = 10 * time.Second
+
+	// raftIdleLeaderTransferDelay is how long a leader without anyone
+	// contending for the HA lock keeps leadership before handing it off,
+	// so that a sealed node does not remain the raft leader.
+	raftIdleLeaderTransferDelay = 15 * time.Second
+
+	// raftLockRetryInterval is how long we wait before retrying to grab
+	// the HA lock after a failed attempt while still the leader
+	raftLockRetryInterval = time.Second
+
+	raftOpPut    = "put"
+	raftOpDelete = "delete"
+	raftOpLock   = "lock"
+	raftOpUnlock = "unlock"
+)
+
+// RaftBackend is a physical backend that stores data in an embedded raft
+// cluster, removing the need for an external storage system. Every node
+// keeps a full copy of the data in memory; writes are only accepted on the
+// raft leader. The HA lock is tied to raft leadership, so the active Vault
+// node is always the raft leader.
+type RaftBackend struct {
+	logger log.Logger
+	nodeID string
+
+	raft      *raft.Raft
+	fsm       *raftFSM
+	transport raft.Transport
+	snapStore raft.SnapshotStore
+	closers   []io.Closer
+
+	// leaderLock guards the leadership tracking state
+	leaderLock     sync.RWMutex
+	isLeader       bool
+	leaderChangeCh chan struct{}
+	lockWaiters    int
+}
+
+// RaftPeer describes a single member of the raft cluster
+type RaftPeer struct {
+	ID       string `json:"node_id" structs:"node_id" mapstructure:"node_id"`
+	Address  string `json:"address" structs:"address" mapstructure:"address"`
+	Leader   bool   `json:"leader" structs:"leader" mapstructure:"leader"`
+	Voter    bool   `json:"voter" structs:"voter" mapstructure:"voter"`
+	Suffrage string `json:"-" structs:"-" mapstructure:"-"`
+}
+
+// raftSnapshot is the serialized form of a snapshot as returned by Snapshot
+// and accepted by RestoreSnapshot
+type raftSnapshot struct {
+	Meta *raft.SnapshotMeta `json:"meta"`
+	Data []byte             `json:"data"`
+}
+
+// newRaftBackend constructs a raft backend using the given configuration
+func newRaftBackend(conf map[string]string, logger log.Logger) (Backend, error) {
+	path, ok := conf["path"]
+	if !ok || path == "" {
+		return nil, fmt.Errorf("'path' must be set")
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create raft directory: %v", err)
+	}
+
+	nodeID, err := raftNodeID(path, conf["node_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	address, ok := conf["address"]
+	if !ok || address == "" {
+		return nil, fmt.Errorf("'address' must be set")
+	}
+
+	bootstrap := false
+	if raw, ok := conf["bootstrap"]; ok {
+		bootstrap, err = strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing 'bootstrap' parameter: %v", err)
+		}
+	}
+
+	logWriter := &raftLogWriter{logger: logger}
+
+	var advertise net.Addr
+	if raw, ok := conf["advertise_address"]; ok && raw != "" {
+		advertise, err = net.ResolveTCPAddr("tcp", raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing 'advertise_address' parameter: %v", err)
+		}
+	}
+
+	var transport raft.Transport
+	if _, ok := conf["tls_cert_file"]; ok {
+		tlsConfig, err := raftTLSConfig(conf)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := newRaftTLSStreamLayer(address, advertise, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		transport = raft.NewNetworkTransport(stream, 3, 10*time.Second, logWriter)
+	} else {
+		transport, err = raft.NewTCPTransport(address, advertise, 3, 10*time.Second, logWriter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create raft transport: %v", err)
+		}
+	}
+
+	store, err := newRaftBoltStore(filepath.Join(path, "raft.db"))
+	if err != nil {
+		transport.(io.Closer).Close()
+		return nil, fmt.Errorf("failed to open raft log store: %v", err)
+	}
+
+	snapStore, err := raft.NewFileSnapshotStore(path, 2, logWriter)
+	if err != nil {
+		store.Close()
+		transport.(io.Closer).Close()
+		return nil, fmt.Errorf("failed to create raft snapshot store: %v", err)
+	}
+
+	b, err := newRaftBackendWithStores(logger, nodeID, raft.DefaultConfig(), store, store, snapStore, transport, bootstrap)
+	if err != nil {
+		store.Close()
+		transport.(io.Closer).Close()
+		return nil, err
+	}
+	b.closers = append(b.closers, store, transport.(io.Closer))
+
+	return b, nil
+}
+
+// newRaftBackendWithStores constructs a raft backend out of the given raft
+// components. It is split out from newRaftBackend so tests can run clusters
+// over in-memory transports and stores.
+func newRaftBackendWithStores(logger log.Logger, nodeID string, raftConfig *raft.Config,
+	logs raft.LogStore, stable raft.StableStore, snaps raft.SnapshotStore,
+	transport raft.Transport, bootstrap bool) (*RaftBackend, error) {
+
+	notifyCh := make(chan bool, 10)
+
+	raftConfig.LocalID = raft.ServerID(nodeID)
+	raftConfig.NotifyCh = notifyCh
+	raftConfig.LogOutput = &raftLogWriter{logger: logger}
+
+	b := &RaftBackend{
+		logger:         logger,
+		nodeID:         nodeID,
+		fsm:            newRaftFSM(),
+		transport:      transport,
+		snapStore:      snaps,
+		leaderChangeCh: make(chan struct{}),
+	}
+
+	if bootstrap {
+		hasState, err := raft.HasExistingState(logs, stable, snaps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for existing raft state: %v", err)
+		}
+		if !hasState {
+			configuration := raft.Configuration{
+				Servers: []raft.Server{
+					{
+						Suffrage: raft.Voter,
+						ID:       raftConfig.LocalID,
+						Address:  transport.LocalAddr(),
+					},
+				},
+			}
+			if err := raft.BootstrapCluster(raftConfig, logs, stable, snaps, transport, configuration); err != nil {
+				return nil, fmt.Errorf("failed to bootstrap raft cluster: %v", err)
+			}
+		}
+	}
+
+	r, err := raft.NewRaft(raftConfig, b.fsm, logs, stable, snaps, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start raft: %v", err)
+	}
+	b.raft = r
+
+	go b.monitorLeadership(notifyCh)
+
+	return b, nil
+}
+
+// raftNodeID returns the configured node ID or, if none was given, an ID
+// persisted in the data directory so a node keeps its identity across
+// restarts.
+func raftNodeID(path, configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+
+	idPath := filepath.Join(path, "node-id")
+	raw, err := ioutil.ReadFile(idPath)
+	switch {
+	case err == nil:
+		return strings.TrimSpace(string(raw)), nil
+	case !os.IsNotExist(err):
+		return "", fmt.Errorf("failed to read raft node id: %v", err)
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(idPath, []byte(id), 0600); err != nil {
+		return "", fmt.Errorf("failed to persist raft node id: %v", err)
+	}
+	return id, nil
+}
+
+// monitorLeadership tracks raft leadership changes, waking up anyone
+// waiting on them
+func (b *RaftBackend) monitorLeadership(notifyCh <-chan bool) {
+	var transferTimer *time.Timer
+	for isLeader := range notifyCh {
+		b.leaderLock.Lock()
+		b.isLeader = isLeader
+		close(b.leaderChangeCh)
+		b.leaderChangeCh = make(chan struct{})
+		b.leaderLock.Unlock()
+
+		if transferTimer != nil {
+			transferTimer.Stop()
+			transferTimer = nil
+		}
+		if isLeader {
+			transferTimer = time.AfterFunc(raftIdleLeaderTransferDelay, b.transferIdleLeadership)
+		}
+	}
+}
+
+// transferIdleLeadership gives up raft leadership when nobody on this node
+// is waiting on the HA lock, e.g. because the Vault node is sealed
+func (b *RaftBackend) transferIdleLeadership() {
+	b.leaderLock.RLock()
+	idle := b.isLeader && b.lockWaiters == 0
+	b.leaderLock.RUnlock()
+	if !idle || !b.hasOtherVoters() {
+		return
+	}
+
+	b.logger.Info("raft: transferring leadership since no active node holds the lock")
+	if err := b.raft.LeadershipTransfer().Error(); err != nil {
+		b.logger.Warn("raft: failed to transfer leadership", "error", err)
+	}
+}
+
+// leadershipState returns whether this node is the raft leader along with a
+// channel that is closed on the next leadership change
+func (b *RaftBackend) leadershipState() (bool, <-chan struct{}) {
+	b.leaderLock.RLock()
+	defer b.leaderLock.RUnlock()
+	return b.isLeader, b.leaderChangeCh
+}
+
+func (b *RaftBackend) addLockWaiter(delta int) {
+	b.leaderLock.Lock()
+	b.lockWaiters += delta
+	b.leaderLock.Unlock()
+}
+
+func (b *RaftBackend) hasOtherVoters() bool {
+	peers, err := b.Peers()
+	if err != nil {
+		return false
+	}
+	for _, p := range peers {
+		if p.Voter && p.ID != b.nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// applyOps commits the given operations through the raft log
+func (b *RaftBackend) applyOps(ops []*raftOp) error {
+	cmd, err := json.Marshal(&raftCommand{Ops: ops})
+	if err != nil {
+		return err
+	}
+
+	future := b.raft.Apply(cmd, raftApplyTimeout)
+	if err := future.Error(); err != nil {
+		if err == raft.ErrNotLeader {
+			return fmt.Errorf("raft: node is not the leader, writes must go to %q", b.raft.Leader())
+		}
+		return err
+	}
+	if err, ok := future.Response().(error); ok && err != nil {
+		return err
+	}
+	return nil
+}
+
+// Put is used to insert or update an entry
+func (b *RaftBackend) Put(entry *Entry) error {
+	return b.applyOps([]*raftOp{{Op: raftOpPut, Key: entry.Key, Value: entry.Value}})
+}
+
+// Get is used to fetch an entry
+func (b *RaftBackend) Get(key string) (*Entry, error) {
+	return b.fsm.get(key), nil
+}
+
+// Delete is used to permanently delete an entry
+func (b *RaftBackend) Delete(key string) error {
+	return b.applyOps([]*raftOp{{Op: raftOpDelete, Key: key}})
+}
+
+// List is used ot list all the keys under a given
+// prefix, up to the next prefix.
+func (b *RaftBackend) List(prefix string) ([]string, error) {
+	return b.fsm.list(prefix), nil
+}
+
+// Transaction applies all the given operations atomically through a single
+// raft log entry
+func (b *RaftBackend) Transaction(txns []TxnEntry) error {
+	ops := make([]*raftOp, 0, len(txns))
+	for _, txn := range txns {
+		switch txn.Operation {
+		case PutOperation:
+			ops = append(ops, &raftOp{Op: raftOpPut, Key: txn.Entry.Key, Value: txn.Entry.Value})
+		case DeleteOperation:
+			ops = append(ops, &raftOp{Op: raftOpDelete, Key: txn.Entry.Key})
+		default:
+			return fmt.Errorf("%q is not a supported transaction operation", txn.Operation)
+		}
+	}
+	return b.applyOps(ops)
+}
+
+// LockWith is used for mutual exclusion based on the given key.
+func (b *RaftBackend) LockWith(key, value string) (Lock, error) {
+	return &RaftLock{
+		b:     b,
+		key:   key,
+		value: value,
+	}, nil
+}
+
+// HAEnabled indicates whether the HA functionality should be exposed.
+// Currently always returns true.
+func (b *RaftBackend) HAEnabled() bool {
+	return true
+}
+
+// NodeID returns the raft ID of this node
+func (b *RaftBackend) NodeID() string {
+	return b.nodeID
+}
+
+// Peers returns the current raft configuration
+func (b *RaftBackend) Peers() ([]*RaftPeer, error) {
+	future := b.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+
+	leader := b.raft.Leader()
+	var peers []*RaftPeer
+	for _, server := range future.Configuration().Servers {
+		peers = append(peers, &RaftPeer{
+			ID:       string(server.ID),
+			Address:  string(server.Address),
+			Leader:   server.Address == leader,
+			Voter:    server.Suffrage == raft.Voter,
+			Suffrage: server.Suffrage.String(),
+		})
+	}
+	return peers, nil
+}
+
+// AddPeer adds a new voting member to the raft cluster. It must be called
+// on the leader.
+func (b *RaftBackend) AddPeer(id, address string) error {
+	b.logger.Info("raft: adding peer", "node_id", id, "address", address)
+	return b.raft.AddVoter(raft.ServerID(id), raft.ServerAddress(address), 0, raftApplyTimeout).Error()
+}
+
+// RemovePeer removes a member from the raft cluster. It must be called on
+// the leader.
+func (b *RaftBackend) RemovePeer(id string) error {
+	b.logger.Info("raft: removing peer", "node_id", id)
+	return b.raft.RemoveServer(raft.ServerID(id), 0, raftApplyTimeout).Error()
+}
+
+// Snapshot takes a snapshot of the raft state and returns it serialized
+func (b *RaftBackend) Snapshot() ([]byte, error) {
+	var meta *raft.SnapshotMeta
+	var reader io.ReadCloser
+
+	future := b.raft.Snapshot()
+	err := future.Error()
+	switch {
+	case err == nil:
+		meta, reader, err = future.Open()
+	case err == raft.ErrNothingNewToSnapshot:
+		// Nothing changed since the last snapshot, so use the latest one
+		var snapshots []*raft.SnapshotMeta
+		snapshots, err = b.snapStore.List()
+		if err == nil {
+			if len(snapshots) == 0 {
+				return nil, fmt.Errorf("raft: no snapshot available")
+			}
+			meta, reader, err = b.snapStore.Open(snapshots[0].ID)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take raft snapshot: %v", err)
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&raftSnapshot{
+		Meta: meta,
+		Data: data,
+	})
+}
+
+// RestoreSnapshot replaces the state of the whole cluster with the given
+// snapshot, as returned by Snapshot. It must be called on the leader.
+func (b *RaftBackend) RestoreSnapshot(snapshot []byte) error {
+	var snap raftSnapshot
+	if err := jsonutil.DecodeJSON(snapshot, &snap); err != nil {
+		return fmt.Errorf("failed to decode raft snapshot: %v", err)
+	}
+	if snap.Meta == nil {
+		return fmt.Errorf("raft snapshot is missing metadata")
+	}
+
+	// The HA lock values of the snapshot name the nodes that were active
+	// when it was taken, so the current ones are kept instead
+	var state raftFSMState
+	if err := jsonutil.DecodeJSON(snap.Data, &state); err != nil {
+		return fmt.Errorf("failed to decode raft snapshot data: %v", err)
+	}
+	state.Locks = b.fsm.lockValues()
+	data, err := json.Marshal(&state)
+	if err != nil {
+		return err
+	}
+	snap.Meta.Size = int64(len(data))
+
+	return b.raft.Restore(snap.Meta, bytes.NewReader(data), raftApplyTimeout)
+}
+
+// shutdown stops raft and closes the underlying stores
+func (b *RaftBackend) shutdown() error {
+	if err := b.raft.Shutdown().Error(); err != nil {
+		return err
+	}
+	for _, c := range b.closers {
+		c.Close()
+	}
+	return nil
+}
+
+// RaftLock is the HA lock for the raft backend. Holding the lock requires
+// being the raft leader.
+type RaftLock struct {
+	b     *RaftBackend
+	key   string
+	value string
+
+	held      bool
+	leaderCh  chan struct{}
+	stopCh    chan struct{}
+	closeOnce *sync.Once
+	l         sync.Mutex
+}
+
+// Lock waits until this node is the raft leader and then records the lock
+// value in the replicated state. The returned channel is closed when raft
+// leadership is lost.
+func (l *RaftLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.l.Lock()
+	defer l.l.Unlock()
+	if l.held {
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	l.b.addLockWaiter(1)
+	for {
+		var retryCh <-chan time.Time
+		isLeader, changeCh := l.b.leadershipState()
+		if isLeader {
+			err := l.b.applyOps([]*raftOp{{Op: raftOpLock, Key: l.key, Value: []byte(l.value)}})
+			if err == nil {
+				l.held = true
+				l.leaderCh = make(chan struct{})
+				l.stopCh = make(chan struct{})
+				l.closeOnce = new(sync.Once)
+				go l.monitorLeadership(l.leaderCh, l.stopCh, l.closeOnce)
+				return l.leaderCh, nil
+			}
+			l.b.logger.Warn("raft: failed to acquire lock", "error", err)
+			retryCh = time.After(raftLockRetryInterval)
+		}
+
+		select {
+		case <-changeCh:
+		case <-retryCh:
+		case <-stopCh:
+			l.b.addLockWaiter(-1)
+			return nil, nil
+		}
+	}
+}
+
+// monitorLeadership closes leaderCh as soon as raft leadership is lost
+func (l *RaftLock) monitorLeadership(leaderCh, stopCh chan struct{}, once *sync.Once) {
+	for {
+		isLeader, changeCh := l.b.leadershipState()
+		if !isLeader {
+			once.Do(func() { close(leaderCh) })
+			return
+		}
+		select {
+		case <-changeCh:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Unlock releases the lock and hands raft leadership to another node so
+// that it can become active
+func (l *RaftLock) Unlock() error {
+	l.l.Lock()
+	defer l.l.Unlock()
+	if !l.held {
+		return nil
+	}
+
+	close(l.stopCh)
+	l.closeOnce.Do(func() { close(l.leaderCh) })
+	l.held = false
+	l.b.addLockWaiter(-1)
+
+	if isLeader, _ := l.b.leadershipState(); !isLeader {
+		return nil
+	}
+	if err := l.b.applyOps([]*raftOp{{Op: raftOpUnlock, Key: l.key}}); err != nil {
+		return err
+	}
+	if l.b.hasOtherVoters() {
+		if err := l.b.raft.LeadershipTransfer().Error(); err != nil {
+			l.b.logger.Warn("raft: failed to transfer leadership", "error", err)
+		}
+	}
+	return nil
+}
+
+// Value returns the value of the lock and if it is held.
+func (l *RaftLock) Value() (bool, string, error) {
+	value, ok := l.b.fsm.lockValue(l.key)
+	return ok, value, nil
+}
+
+// raftOp is a single operation applied to the FSM
+type raftOp struct {
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+// raftCommand is the payload of a raft log entry; all of its operations are
+// applied atomically
+type raftCommand struct {
+	Ops []*raftOp `json:"ops"`
+}
+
+// raftFSM is the replicated state machine holding the stored entries and
+// the HA lock values
+type raftFSM struct {
+	l     sync.RWMutex
+	root  *radix.Tree
+	locks map[string]string
+}
+
+// raftFSMState is the serialized form of the FSM used for snapshots
+type raftFSMState struct {
+	Entries []*Entry          `json:"entries"`
+	Locks   map[string]string `json:"locks"`
+}
+
+func newRaftFSM() *raftFSM {
+	return &raftFSM{
+		root:  radix.New(),
+		locks: make(map[string]string),
+	}
+}
+
+// Apply implements raft.FSM
+func (f *raftFSM) Apply(entry *raft.Log) interface{} {
+	var cmd raftCommand
+	if err := jsonutil.DecodeJSON(entry.Data, &cmd); err != nil {
+		return fmt.Errorf("failed to decode raft command: %v", err)
+	}
+
+	// Validate everything up front so a command is either fully applied
+	// or not at all
+	for _, op := range cmd.Ops {
+		switch op.Op {
+		case raftOpPut, raftOpDelete, raftOpLock, raftOpUnlock:
+		default:
+			return fmt.Errorf("unknown raft operation %q", op.Op)
+		}
+	}
+
+	f.l.Lock()
+	defer f.l.Unlock()
+	for _, op := range cmd.Ops {
+		switch op.Op {
+		case raftOpPut:
+			f.root.Insert(op.Key, op.Value)
+		case raftOpDelete:
+			f.root.Delete(op.Key)
+		case raftOpLock:
+			f.locks[op.Key] = string(op.Value)
+		case raftOpUnlock:
+			delete(f.locks, op.Key)
+		}
+	}
+	return nil
+}
+
+// Snapshot implements raft.FSM
+func (f *raftFSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	state := &raftFSMState{
+		Locks: make(map[string]string, len(f.locks)),
+	}
+	f.root.Walk(func(k string, v interface{}) bool {
+		state.Entries = append(state.Entries, &Entry{Key: k, Value: v.([]byte)})
+		return false
+	})
+	for k, v := range f.locks {
+		state.Locks[k] = v
+	}
+	return state, nil
+}
+
+// Restore implements raft.FSM
+func (f *raftFSM) Restore(r io.ReadCloser) error {
+	defer r.Close()
+
+	var state raftFSMState
+	if err := jsonutil.DecodeJSONFromReader(r, &state); err != nil {
+		return fmt.Errorf("failed to decode raft snapshot: %v", err)
+	}
+
+	root := radix.New()
+	for _, entry := range state.Entries {
+		root.Insert(entry.Key, entry.Value)
+	}
+	if state.Locks == nil {
+		state.Locks = make(map[string]string)
+	}
+
+	f.l.Lock()
+	f.root = root
+	f.locks = state.Locks
+	f.l.Unlock()
+	return nil
+}
+
+func (f *raftFSM) get(key string) *Entry {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	value, ok := f.root.Get(key)
+	if !ok {
+		return nil
+	}
+	return &Entry{
+		Key:   key,
+		Value: value.([]byte),
+	}
+}
+
+func (f *raftFSM) list(prefix string) []string {
+	f.l.RLock()
+	defer f.l.RUnlock()
+
+	var out []string
+	seen := make(map[string]interface{})
+	f.root.WalkPrefix(prefix, func(s string, v interface{}) bool {
+		trimmed := strings.TrimPrefix(s, prefix)
+		sep := strings.Index(trimmed, "/")
+		if sep == -1 {
+			out = append(out, trimmed)
+		} else {
+			trimmed = trimmed[:sep+1]
+			if _, ok := seen[trimmed]; !ok {
+				out = append(out, trimmed)
+				seen[trimmed] = struct{}{}
+			}
+		}
+		return false
+	})
+	return out
+}
+
+func (f *raftFSM) lockValue(key string) (string, bool) {
+	f.l.RLock()
+	defer f.l.RUnlock()
+	value, ok := f.locks[key]
+	return value, ok
+}
+
+func (f *raftFSM) lockValues() map[string]string {
+	f.l.RLock()
+	defer f.l.RUnlock()
+	locks := make(map[string]string, len(f.locks))
+	for k, v := range f.locks {
+		locks[k] = v
+	}
+	return locks
+}
+
+// Persist implements raft.FSMSnapshot
+func (s *raftFSMState) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+// Release implements raft.FSMSnapshot
+func (s *raftFSMState) Release() {}
+
+// raftTLSConfig builds the mutual TLS configuration used between raft peers
+func raftTLSConfig(conf map[string]string) (*tls.Config, error) {
+	certFile, keyFile, caFile := conf["tls_cert_file"], conf["tls_key_file"], conf["tls_ca_file"]
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, fmt.Errorf("'tls_cert_file', 'tls_key_file' and 'tls_ca_file' must all be set to use TLS")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load raft TLS key pair: %v", err)
+	}
+	caPEM, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raft TLS CA file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("failed to parse raft TLS CA file")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ServerName:   conf["tls_server_name"],
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// raftTLSStreamLayer is a raft.StreamLayer that speaks mutual TLS
+type raftTLSStreamLayer struct {
+	net.Listener
+	advertise net.Addr
+	tlsConfig *tls.Config
+}
+
+func newRaftTLSStreamLayer(address string, advertise net.Addr, tlsConfig *tls.Config) (*raftTLSStreamLayer, error) {
+	listener, err := tls.Listen("tcp", address, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on raft address: %v", err)
+	}
+	if advertise == nil {
+		advertise = listener.Addr()
+	}
+	return &raftTLSStreamLayer{
+		Listener:  listener,
+		advertise: advertise,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+// Dial implements raft.StreamLayer
+func (s *raftTLSStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	return tls.DialWithDialer(dialer, "tcp", string(address), s.tlsConfig)
+}
+
+// Addr implements net.Listener, returning the advertised address
+func (s *raftTLSStreamLayer) Addr() net.Addr {
+	return s.advertise
+}
+
+// raftLogWriter forwards the output of the raft library to a logxi logger
+type raftLogWriter struct {
+	logger log.Logger
+}
+
+func (w *raftLogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.Contains(line, "[ERROR]") || strings.Contains(line, "[ERR]"):
+			w.logger.Error(line)
+		case strings.Contains(line, "[WARN]"):
+			w.logger.Warn(line)
+		case strings.Contains(line, "[DEBUG]") || strings.Contains(line, "[TRACE]"):
+			w.logger.Debug(line)
+		default:
+			w.logger.Info(line)
+		}
+	}
+	return len(p), nil
+}
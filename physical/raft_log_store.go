@@ -0,0 +1,213 @@
+package physical
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/boltdb/bolt"
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/hashicorp/raft"
+)
+
+var (
+	// raftLogsBucket holds the raft log entries keyed by their index
+	raftLogsBucket = []byte("logs")
+
+	// raftConfBucket holds the raft stable store values
+	raftConfBucket = []byte("conf")
+
+	// errRaftKeyNotFound is returned by the stable store when a key is
+	// missing; raft checks the error message for this exact string.
+	errRaftKeyNotFound = errors.New("not found")
+)
+
+// raftBoltStore is a bolt backed implementation of both the raft LogStore
+// and StableStore interfaces.
+type raftBoltStore struct {
+	conn *bolt.DB
+}
+
+// newRaftBoltStore opens (or creates) the bolt database at the given path
+func newRaftBoltStore(path string) (*raftBoltStore, error) {
+	conn, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.Begin(true)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, bucket := range [][]byte{raftLogsBucket, raftConfBucket} {
+		if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &raftBoltStore{conn: conn}, nil
+}
+
+// Close closes the underlying bolt database
+func (s *raftBoltStore) Close() error {
+	return s.conn.Close()
+}
+
+// FirstIndex returns the first index written. 0 for no entries.
+func (s *raftBoltStore) FirstIndex() (uint64, error) {
+	tx, err := s.conn.Begin(false)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	first, _ := tx.Bucket(raftLogsBucket).Cursor().First()
+	if first == nil {
+		return 0, nil
+	}
+	return raftBytesToUint64(first), nil
+}
+
+// LastIndex returns the last index written. 0 for no entries.
+func (s *raftBoltStore) LastIndex() (uint64, error) {
+	tx, err := s.conn.Begin(false)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	last, _ := tx.Bucket(raftLogsBucket).Cursor().Last()
+	if last == nil {
+		return 0, nil
+	}
+	return raftBytesToUint64(last), nil
+}
+
+// GetLog gets a log entry at a given index.
+func (s *raftBoltStore) GetLog(idx uint64, log *raft.Log) error {
+	tx, err := s.conn.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	val := tx.Bucket(raftLogsBucket).Get(raftUint64ToBytes(idx))
+	if val == nil {
+		return raft.ErrLogNotFound
+	}
+
+	var h codec.MsgpackHandle
+	return codec.NewDecoder(bytes.NewReader(val), &h).Decode(log)
+}
+
+// StoreLog stores a log entry.
+func (s *raftBoltStore) StoreLog(log *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{log})
+}
+
+// StoreLogs stores multiple log entries.
+func (s *raftBoltStore) StoreLogs(logs []*raft.Log) error {
+	tx, err := s.conn.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	bucket := tx.Bucket(raftLogsBucket)
+	for _, log := range logs {
+		var buf bytes.Buffer
+		var h codec.MsgpackHandle
+		if err := codec.NewEncoder(&buf, &h).Encode(log); err != nil {
+			return err
+		}
+		if err := bucket.Put(raftUint64ToBytes(log.Index), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRange deletes a range of log entries. The range is inclusive.
+func (s *raftBoltStore) DeleteRange(min, max uint64) error {
+	tx, err := s.conn.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	curs := tx.Bucket(raftLogsBucket).Cursor()
+	for k, _ := curs.Seek(raftUint64ToBytes(min)); k != nil; k, _ = curs.Next() {
+		if raftBytesToUint64(k) > max {
+			break
+		}
+		if err := curs.Delete(); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Set is used to set a key/value pair outside of the raft log
+func (s *raftBoltStore) Set(key []byte, val []byte) error {
+	tx, err := s.conn.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.Bucket(raftConfBucket).Put(key, val); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get returns the value for key, or an empty byte slice if key was not found.
+func (s *raftBoltStore) Get(key []byte) ([]byte, error) {
+	tx, err := s.conn.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	val := tx.Bucket(raftConfBucket).Get(key)
+	if val == nil {
+		return nil, errRaftKeyNotFound
+	}
+
+	// The value is only valid for the life of the transaction
+	return append([]byte(nil), val...), nil
+}
+
+// SetUint64 is like Set, but handles uint64 values
+func (s *raftBoltStore) SetUint64(key []byte, val uint64) error {
+	return s.Set(key, raftUint64ToBytes(val))
+}
+
+// GetUint64 returns the uint64 value for key, or 0 if key was not found.
+func (s *raftBoltStore) GetUint64(key []byte) (uint64, error) {
+	val, err := s.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return raftBytesToUint64(val), nil
+}
+
+func raftBytesToUint64(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
+
+func raftUint64ToBytes(u uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, u)
+	return buf
+}
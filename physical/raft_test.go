@@ -0,0 +1,325 @@
+package physical
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/hashicorp/vault/helper/logformat"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestRaft_Backend(t *testing.T) {
+	nodes, cleanup := testRaftCluster(t, 3)
+	defer cleanup()
+
+	testBackend(t, nodes[0])
+	testBackend_ListPrefix(t, nodes[0])
+}
+
+func TestRaft_Replication(t *testing.T) {
+	nodes, cleanup := testRaftCluster(t, 3)
+	defer cleanup()
+
+	e := &Entry{Key: "foo/bar", Value: []byte("baz")}
+	if err := nodes[0].Put(e); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, n := range nodes[1:] {
+		testRaftWaitForEntry(t, n, e)
+	}
+
+	// Followers must reject writes
+	if err := nodes[1].Put(&Entry{Key: "foo/zip", Value: []byte("zap")}); err == nil {
+		t.Fatal("expected error writing to a follower")
+	}
+
+	// Transactions are applied atomically on every node
+	err := nodes[0].Transaction([]TxnEntry{
+		{Operation: DeleteOperation, Entry: &Entry{Key: "foo/bar"}},
+		{Operation: PutOperation, Entry: &Entry{Key: "foo/zip", Value: []byte("zap")}},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, n := range nodes {
+		testRaftWaitForEntry(t, n, &Entry{Key: "foo/zip", Value: []byte("zap")})
+		keys, err := n.List("foo/")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !reflect.DeepEqual(keys, []string{"zip"}) {
+			t.Fatalf("bad: %v", keys)
+		}
+	}
+}
+
+func TestRaft_HABackend(t *testing.T) {
+	nodes, cleanup := testRaftCluster(t, 3)
+	defer cleanup()
+
+	type acquired struct {
+		lock     Lock
+		leaderCh <-chan struct{}
+		value    string
+	}
+	acquiredCh := make(chan *acquired, len(nodes))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	for _, n := range nodes {
+		lock, err := n.LockWith("core/lock", n.NodeID())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		go func(lock Lock, value string) {
+			leaderCh, err := lock.Lock(stopCh)
+			if err != nil || leaderCh == nil {
+				return
+			}
+			acquiredCh <- &acquired{lock: lock, leaderCh: leaderCh, value: value}
+		}(lock, n.NodeID())
+	}
+
+	var first *acquired
+	select {
+	case first = <-acquiredCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for lock")
+	}
+	if first.value != nodes[0].NodeID() {
+		t.Fatalf("expected the raft leader to get the lock, got %q", first.value)
+	}
+	held, val, err := first.lock.Value()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !held || val != first.value {
+		t.Fatalf("bad: %v %v", held, val)
+	}
+
+	// Releasing the lock hands leadership, and so the lock, to another node
+	if err := first.lock.Unlock(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	select {
+	case <-first.leaderCh:
+	default:
+		t.Fatal("expected leader channel to be closed")
+	}
+
+	var second *acquired
+	select {
+	case second = <-acquiredCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for lock handover")
+	}
+	if second.value == first.value {
+		t.Fatalf("expected another node to get the lock")
+	}
+	held, val, err = second.lock.Value()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !held || val != second.value {
+		t.Fatalf("bad: %v %v", held, val)
+	}
+	second.lock.Unlock()
+}
+
+func TestRaft_Snapshot(t *testing.T) {
+	nodes, cleanup := testRaftCluster(t, 3)
+	defer cleanup()
+	leader := nodes[0]
+
+	foo := &Entry{Key: "foo", Value: []byte("bar")}
+	if err := leader.Put(foo); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := leader.applyOps([]*raftOp{{Op: raftOpLock, Key: "core/lock", Value: []byte("old")}}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	snapshot, err := leader.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Taking a snapshot without changes returns the latest one
+	if _, err := leader.Snapshot(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := leader.Delete("foo"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := leader.Put(&Entry{Key: "zip", Value: []byte("zap")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := leader.applyOps([]*raftOp{{Op: raftOpLock, Key: "core/lock", Value: []byte("current")}}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := leader.RestoreSnapshot(snapshot); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, n := range nodes {
+		testRaftWaitForEntry(t, n, foo)
+		testRaftWaitForEntry(t, n, &Entry{Key: "zip"})
+	}
+
+	// The HA lock is not restored along with the data
+	if value, ok := leader.fsm.lockValue("core/lock"); !ok || value != "current" {
+		t.Fatalf("bad: lock value %q", value)
+	}
+}
+
+func TestRaft_Peers(t *testing.T) {
+	nodes, cleanup := testRaftCluster(t, 3)
+	defer cleanup()
+
+	peers, err := nodes[0].Peers()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(peers) != 3 {
+		t.Fatalf("bad: %#v", peers)
+	}
+	if !peers[0].Leader || peers[0].ID != nodes[0].NodeID() {
+		t.Fatalf("bad: %#v", peers[0])
+	}
+
+	if err := nodes[0].RemovePeer(nodes[2].NodeID()); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	peers, err = nodes[0].Peers()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("bad: %#v", peers)
+	}
+	for _, p := range peers {
+		if p.ID == nodes[2].NodeID() {
+			t.Fatalf("expected peer to be removed: %#v", peers)
+		}
+	}
+}
+
+func TestRaft_Factory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-raft")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	b, err := NewBackend("raft", logger, map[string]string{
+		"path":      dir,
+		"address":   "127.0.0.1:0",
+		"bootstrap": "true",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rb := b.(*RaftBackend)
+	defer rb.shutdown()
+
+	testRaftWaitForLeader(t, rb)
+	testBackend(t, rb)
+
+	// The generated node ID is persisted
+	id, err := raftNodeID(dir, "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if id != rb.NodeID() {
+		t.Fatalf("bad: expected %q, got %q", rb.NodeID(), id)
+	}
+}
+
+// testRaftCluster creates a cluster of raft backends connected through
+// in-memory transports. The first node is bootstrapped and is the leader.
+func testRaftCluster(t *testing.T, n int) ([]*RaftBackend, func()) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+
+	transports := make([]*raft.InmemTransport, n)
+	for i := range transports {
+		_, transports[i] = raft.NewInmemTransport("")
+	}
+	for _, t1 := range transports {
+		for _, t2 := range transports {
+			if t1 != t2 {
+				t1.Connect(t2.LocalAddr(), t2)
+			}
+		}
+	}
+
+	nodes := make([]*RaftBackend, n)
+	for i := range nodes {
+		conf := raft.DefaultConfig()
+		conf.HeartbeatTimeout = 50 * time.Millisecond
+		conf.ElectionTimeout = 50 * time.Millisecond
+		conf.LeaderLeaseTimeout = 50 * time.Millisecond
+		conf.CommitTimeout = 5 * time.Millisecond
+
+		store := raft.NewInmemStore()
+		b, err := newRaftBackendWithStores(logger, fmt.Sprintf("node%d", i), conf,
+			store, store, raft.NewInmemSnapshotStore(), transports[i], i == 0)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		nodes[i] = b
+	}
+
+	cleanup := func() {
+		for _, b := range nodes {
+			b.shutdown()
+		}
+	}
+
+	testRaftWaitForLeader(t, nodes[0])
+	for _, b := range nodes[1:] {
+		if err := nodes[0].AddPeer(b.NodeID(), string(b.transport.LocalAddr())); err != nil {
+			cleanup()
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	return nodes, cleanup
+}
+
+func testRaftWaitForLeader(t *testing.T, b *RaftBackend) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if isLeader, _ := b.leadershipState(); isLeader {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("node %s did not become leader", b.NodeID())
+}
+
+// testRaftWaitForEntry waits until the node's state matches the given
+// entry; an entry with a nil value waits for the key to be absent.
+func testRaftWaitForEntry(t *testing.T, b *RaftBackend, e *Entry) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		out, err := b.Get(e.Key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if (e.Value == nil && out == nil) || (e.Value != nil && reflect.DeepEqual(out, e)) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("node %s: bad entry for %q: %#v", b.NodeID(), e.Key, out)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,69 @@
+package credsutil
+
+import (
+	"strings"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+)
+
+// SQLCredentialsProducer implements CredentialsProducer and provides a generic credentials producer for most sql database types.
+type SQLCredentialsProducer struct {
+	DisplayNameLen int
+	RoleNameLen    int
+	UsernameLen    int
+	Separator      string
+}
+
+// GenerateUsername returns a username made of the truncated display name of
+// the token, the truncated role name and a UUID, the whole being truncated
+// to UsernameLen.
+func (scp *SQLCredentialsProducer) GenerateUsername(config dbplugin.UsernameConfig) (string, error) {
+	parts := []string{"v"}
+
+	if scp.DisplayNameLen > 0 {
+		displayName := config.DisplayName
+		if len(displayName) > scp.DisplayNameLen {
+			displayName = displayName[:scp.DisplayNameLen]
+		}
+		parts = append(parts, displayName)
+	}
+
+	if scp.RoleNameLen > 0 {
+		roleName := config.RoleName
+		if len(roleName) > scp.RoleNameLen {
+			roleName = roleName[:scp.RoleNameLen]
+		}
+		parts = append(parts, roleName)
+	}
+
+	userUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	parts = append(parts, userUUID)
+
+	username := strings.Join(parts, scp.Separator)
+	if scp.UsernameLen > 0 && len(username) > scp.UsernameLen {
+		username = username[:scp.UsernameLen]
+	}
+
+	return username, nil
+}
+
+// GeneratePassword returns a random password. The fixed prefix satisfies
+// the complexity requirements of the databases that enforce them.
+func (scp *SQLCredentialsProducer) GeneratePassword() (string, error) {
+	password, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	return "A1a-" + password, nil
+}
+
+// GenerateExpiration formats the expiration the way SQL databases expect it
+func (scp *SQLCredentialsProducer) GenerateExpiration(ttl time.Time) (string, error) {
+	return ttl.Format("2006-01-02 15:04:05-0700"), nil
+}
@@ -0,0 +1,44 @@
+package credsutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/builtin/logical/database/dbplugin"
+)
+
+func TestSQLCredentialsProducer_GenerateUsername(t *testing.T) {
+	scp := &SQLCredentialsProducer{
+		DisplayNameLen: 4,
+		RoleNameLen:    4,
+		UsernameLen:    16,
+		Separator:      "-",
+	}
+
+	username, err := scp.GenerateUsername(dbplugin.UsernameConfig{
+		DisplayName: "token-display",
+		RoleName:    "readonly",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(username) != 16 {
+		t.Fatalf("bad length: %q", username)
+	}
+	if !strings.HasPrefix(username, "v-toke-read-") {
+		t.Fatalf("bad: %q", username)
+	}
+
+	// Without a limit, the username keeps the whole UUID
+	scp.UsernameLen = 0
+	username, err = scp.GenerateUsername(dbplugin.UsernameConfig{
+		DisplayName: "tok",
+		RoleName:    "ro",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(username) != len("v-tok-ro-")+36 {
+		t.Fatalf("bad: %q", username)
+	}
+}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -20,10 +21,23 @@ type ACL struct {
 
 	// root is enabled if the "root" named policy is present.
 	root bool
+
+	// namespacePath is the path of the namespace the policies belong to.
+	// Paths outside of the namespace are denied, and paths within it are
+	// matched relative to it.
+	namespacePath string
 }
 
 // New is used to construct a policy based ACL from a set of policies.
+// Templated paths grant nothing as there is no token to render them for.
 func NewACL(policies []*Policy) (*ACL, error) {
+	return newACL(policies, nil)
+}
+
+// newACL constructs an ACL from a set of policies, rendering their templated
+// paths with the given data. Paths referring to values missing from the data
+// are left out, so they grant nothing.
+func newACL(policies []*Policy, data *policyTemplateData) (*ACL, error) {
 	// Initialize
 	a := &ACL{
 		exactRules: radix.New(),
@@ -42,6 +56,23 @@ func NewACL(policies []*Policy) (*ACL, error) {
 			a.root = true
 		}
 		for _, pc := range policy.Paths {
+			prefix := pc.Prefix
+			if pc.Templated {
+				var ok bool
+				var err error
+				prefix, ok, err = renderPolicyTemplate(pc.Prefix, data)
+				if err != nil {
+					return nil, fmt.Errorf("policy %q: path %q: %v", policy.Name, pc.Prefix, err)
+				}
+				if !ok {
+					continue
+				}
+			}
+
+			// The permissions are merged into a copy, as the policy is
+			// cached and shared by the ACLs of every token holding it
+			perms := pc.Permissions.Clone()
+
 			// Check which tree to use
 			tree := a.exactRules
 			if pc.Glob {
@@ -49,9 +80,9 @@ func NewACL(policies []*Policy) (*ACL, error) {
 			}
 
 			// Check for an existing policy
-			raw, ok := tree.Get(pc.Prefix)
+			raw, ok := tree.Get(prefix)
 			if !ok {
-				tree.Insert(pc.Prefix, pc.Permissions)
+				tree.Insert(prefix, perms)
 				continue
 			}
 
@@ -64,17 +95,18 @@ func NewACL(policies []*Policy) (*ACL, error) {
 				// don't save anything else
 				continue
 
-			case pc.Permissions.CapabilitiesBitmap&DenyCapabilityInt > 0:
+			case perms.CapabilitiesBitmap&DenyCapabilityInt > 0:
 				// If this new policy explicitly denies, only save the deny value
-				pc.Permissions.CapabilitiesBitmap = DenyCapabilityInt
-				pc.Permissions.AllowedParameters = nil
-				pc.Permissions.DeniedParameters = nil
+				perms.CapabilitiesBitmap = DenyCapabilityInt
+				perms.AllowedParameters = nil
+				perms.DeniedParameters = nil
+				perms.ControlGroup = nil
 				goto INSERT
 
 			default:
 				// Insert the capabilities in this new policy into the existing
 				// value
-				pc.Permissions.CapabilitiesBitmap = existingPerms.CapabilitiesBitmap | pc.Permissions.CapabilitiesBitmap
+				perms.CapabilitiesBitmap = existingPerms.CapabilitiesBitmap | perms.CapabilitiesBitmap
 			}
 
 			// Note: In these stanzas, we're preferring minimum lifetimes. So
@@ -86,58 +118,61 @@ func NewACL(policies []*Policy) (*ACL, error) {
 			// max, or the current is greater than the previous, use the
 			// existing.
 			if existingPerms.MaxWrappingTTL > 0 &&
-				(pc.Permissions.MaxWrappingTTL == 0 ||
-					existingPerms.MaxWrappingTTL < pc.Permissions.MaxWrappingTTL) {
-				pc.Permissions.MaxWrappingTTL = existingPerms.MaxWrappingTTL
+				(perms.MaxWrappingTTL == 0 ||
+					existingPerms.MaxWrappingTTL < perms.MaxWrappingTTL) {
+				perms.MaxWrappingTTL = existingPerms.MaxWrappingTTL
 			}
 			// If we have an existing min, and we either don't have a current
 			// min, or the current is greater than the previous, use the
 			// existing
 			if existingPerms.MinWrappingTTL > 0 &&
-				(pc.Permissions.MinWrappingTTL == 0 ||
-					existingPerms.MinWrappingTTL < pc.Permissions.MinWrappingTTL) {
-				pc.Permissions.MinWrappingTTL = existingPerms.MinWrappingTTL
+				(perms.MinWrappingTTL == 0 ||
+					existingPerms.MinWrappingTTL < perms.MinWrappingTTL) {
+				perms.MinWrappingTTL = existingPerms.MinWrappingTTL
 			}
 
 			if len(existingPerms.AllowedParameters) > 0 {
-				if pc.Permissions.AllowedParameters == nil {
-					pc.Permissions.AllowedParameters = existingPerms.AllowedParameters
+				if perms.AllowedParameters == nil {
+					perms.AllowedParameters = existingPerms.AllowedParameters
 				} else {
 					for key, value := range existingPerms.AllowedParameters {
-						pcValue, ok := pc.Permissions.AllowedParameters[key]
+						pcValue, ok := perms.AllowedParameters[key]
 						// If an empty array exist it should overwrite any other
 						// value.
 						if len(value) == 0 || (ok && len(pcValue) == 0) {
-							pc.Permissions.AllowedParameters[key] = []interface{}{}
+							perms.AllowedParameters[key] = []interface{}{}
 						} else {
 							// Merge the two maps, appending values on key conflict.
-							pc.Permissions.AllowedParameters[key] = append(value, pc.Permissions.AllowedParameters[key]...)
+							perms.AllowedParameters[key] = append(value, perms.AllowedParameters[key]...)
 						}
 					}
 				}
 			}
 
 			if len(existingPerms.DeniedParameters) > 0 {
-				if pc.Permissions.DeniedParameters == nil {
-					pc.Permissions.DeniedParameters = existingPerms.DeniedParameters
+				if perms.DeniedParameters == nil {
+					perms.DeniedParameters = existingPerms.DeniedParameters
 				} else {
 					for key, value := range existingPerms.DeniedParameters {
-						pcValue, ok := pc.Permissions.DeniedParameters[key]
+						pcValue, ok := perms.DeniedParameters[key]
 						// If an empty array exist it should overwrite any other
 						// value.
 						if len(value) == 0 || (ok && len(pcValue) == 0) {
-							pc.Permissions.DeniedParameters[key] = []interface{}{}
+							perms.DeniedParameters[key] = []interface{}{}
 						} else {
 							// Merge the two maps, appending values on key conflict.
-							pc.Permissions.DeniedParameters[key] = append(value, pc.Permissions.DeniedParameters[key]...)
+							perms.DeniedParameters[key] = append(value, perms.DeniedParameters[key]...)
 						}
 					}
 				}
 			}
 
+			// Requests must satisfy the control groups of every policy
+			perms.ControlGroup = mergeControlGroups(existingPerms.ControlGroup, perms.ControlGroup)
+
 		INSERT:
 
-			tree.Insert(pc.Prefix, pc.Permissions)
+			tree.Insert(prefix, perms)
 
 		}
 	}
@@ -145,6 +180,11 @@ func NewACL(policies []*Policy) (*ACL, error) {
 }
 
 func (a *ACL) Capabilities(path string) (pathCapabilities []string) {
+	if !strings.HasPrefix(path, a.namespacePath) {
+		return []string{DenyCapability}
+	}
+	path = strings.TrimPrefix(path, a.namespacePath)
+
 	// Fast-path root
 	if a.root {
 		return []string{RootCapability}
@@ -201,12 +241,16 @@ CHECK:
 // first bool indicates if an op is allowed, the second whether sudo priviliges
 // exist for that op and path.
 func (a *ACL) AllowOperation(req *logical.Request) (bool, bool) {
+	if !strings.HasPrefix(req.Path, a.namespacePath) {
+		return false, false
+	}
+
 	// Fast-path root
 	if a.root {
 		return true, true
 	}
 	op := req.Operation
-	path := req.Path
+	path := strings.TrimPrefix(req.Path, a.namespacePath)
 
 	// Help is always allowed
 	if op == logical.HelpOperation {
@@ -338,6 +382,23 @@ CHECK:
 	return true, sudo
 }
 
+// ControlGroup returns the control group requests to the path are held
+// for, if any
+func (a *ACL) ControlGroup(path string) *ControlGroup {
+	if a.root || !strings.HasPrefix(path, a.namespacePath) {
+		return nil
+	}
+	path = strings.TrimPrefix(path, a.namespacePath)
+
+	if raw, ok := a.exactRules.Get(path); ok {
+		return raw.(*Permissions).ControlGroup
+	}
+	if _, raw, ok := a.globRules.LongestPrefix(path); ok {
+		return raw.(*Permissions).ControlGroup
+	}
+	return nil
+}
+
 func valueInParameterList(v interface{}, list []interface{}) bool {
 	// Empty list is equivalent to the item always existing in the list
 	if len(list) == 0 {
@@ -415,6 +415,165 @@ func TestACL_ValuePermissions(t *testing.T) {
 	}
 }
 
+func TestACL_Templated(t *testing.T) {
+	policy, err := Parse(templatedPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	data := &policyTemplateData{
+		token: &TokenEntry{
+			DisplayName: "userpass-armon",
+			Meta: map[string]string{
+				"app": "app1",
+			},
+			AuthMeta: true,
+		},
+		entity: &IdentityEntity{
+			Name:     "armon",
+			Metadata: map[string]string{"team": "ops"},
+		},
+	}
+	acl, err := newACL([]*Policy{policy}, data)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	type tcase struct {
+		path    string
+		allowed bool
+	}
+	tcases := []tcase{
+		{"secret/app1/foo", true},
+		{"secret/app2/foo", false},
+		{"secret/users/userpass-armon/foo", true},
+		{"secret/users/other/foo", false},
+		{"secret/teams/ops", true},
+		{"secret/teams/dev", false},
+		{"secret/envs/", false},
+		{"secret/region/", false},
+	}
+	for _, tc := range tcases {
+		request := &logical.Request{Operation: logical.ReadOperation, Path: tc.path}
+		if allowed, _ := acl.AllowOperation(request); allowed != tc.allowed {
+			t.Fatalf("bad: path: %s, allowed: %v", tc.path, allowed)
+		}
+	}
+
+	// Values that could reach outside of the templated prefix, and
+	// metadata not set by a credential backend, grant nothing
+	for _, data := range []*policyTemplateData{
+		{token: &TokenEntry{Meta: map[string]string{"app": "app1/../app2"}, AuthMeta: true}},
+		{token: &TokenEntry{Meta: map[string]string{"app": "*"}, AuthMeta: true}},
+		{token: &TokenEntry{Meta: map[string]string{"app": "+"}, AuthMeta: true}},
+		{token: &TokenEntry{Meta: map[string]string{"app": "app1"}}},
+	} {
+		acl, err = newACL([]*Policy{policy}, data)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		for _, path := range []string{"secret/app1/foo", "secret/app2/foo"} {
+			request := &logical.Request{Operation: logical.ReadOperation, Path: path}
+			if allowed, _ := acl.AllowOperation(request); allowed {
+				t.Fatalf("path %s should not be allowed with meta %v", path, data.token.Meta)
+			}
+		}
+	}
+
+	// Without data, templated paths grant nothing
+	acl, err = NewACL([]*Policy{policy})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	request := &logical.Request{Operation: logical.ReadOperation, Path: "secret/app1/foo"}
+	if allowed, _ := acl.AllowOperation(request); allowed {
+		t.Fatalf("templated path should not be allowed")
+	}
+}
+
+func TestACL_TemplatedSharedPolicy(t *testing.T) {
+	templated, err := Parse(`
+name = "templated"
+path "secret/{{identity.entity.name}}" {
+	capabilities = ["read"]
+	allowed_parameters = {
+		"foo" = ["bar"]
+	}
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alice, err := Parse(`
+name = "alice"
+path "secret/alice" {
+	capabilities = ["update"]
+	allowed_parameters = {
+		"foo" = ["baz"]
+	}
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Both ACLs are built from the same policy objects, as they are when
+	// cached by the policy store
+	aliceACL, err := newACL([]*Policy{alice, templated}, &policyTemplateData{
+		entity: &IdentityEntity{Name: "alice"},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	bobACL, err := newACL([]*Policy{alice, templated}, &policyTemplateData{
+		entity: &IdentityEntity{Name: "bob"},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	request := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "secret/alice",
+		Data:      map[string]interface{}{"foo": "baz"},
+	}
+	if allowed, _ := aliceACL.AllowOperation(request); !allowed {
+		t.Fatalf("expected alice to be allowed to update her path")
+	}
+	if caps := aliceACL.Capabilities("secret/alice"); !reflect.DeepEqual(caps, []string{"read", "update"}) {
+		t.Fatalf("bad: %v", caps)
+	}
+
+	// What was merged for alice does not apply to bob's path
+	if caps := bobACL.Capabilities("secret/bob"); !reflect.DeepEqual(caps, []string{"read"}) {
+		t.Fatalf("bad: %v", caps)
+	}
+	raw, _ := bobACL.exactRules.Get("secret/bob")
+	expected := map[string][]interface{}{"foo": []interface{}{"bar"}}
+	if params := raw.(*Permissions).AllowedParameters; !reflect.DeepEqual(params, expected) {
+		t.Fatalf("bad: %#v", params)
+	}
+}
+
+var templatedPolicy = `
+name = "templated"
+path "secret/{{token.metadata.app}}/*" {
+	capabilities = ["read"]
+}
+path "secret/users/{{auth.display_name}}/*" {
+	capabilities = ["read"]
+}
+path "secret/teams/{{ identity.entity.metadata.team }}" {
+	capabilities = ["read"]
+}
+path "secret/envs/{{token.metadata.env}}*" {
+	capabilities = ["read"]
+}
+path "secret/region/{{identity.entity.metadata.region}}/*" {
+	capabilities = ["read"]
+}
+`
+
 var tokenCreationPolicy = `
 name = "tokenCreation"
 path "auth/token/create*" {
@@ -49,6 +49,19 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 		return fmt.Errorf("backend path must be specified")
 	}
 
+	// Credential backends cannot overlap a child namespace
+	ns := c.namespaceByID(entry.NamespaceID)
+	if ns == nil {
+		return fmt.Errorf("unknown namespace")
+	}
+	if !ns.contains(entry.Path) {
+		return fmt.Errorf("backend path is not within namespace")
+	}
+	if other := c.namespaceConflict(ns, entry.Path); other != nil {
+		return logical.CodedError(409, fmt.Sprintf("existing namespace at %s", other.Path))
+	}
+	routePath := credentialRoutePath(ns, entry.Path)
+
 	c.authLock.Lock()
 	defer c.authLock.Unlock()
 
@@ -69,7 +82,7 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 		return fmt.Errorf("token credential backend cannot be instantiated")
 	}
 
-	if match := c.router.MatchingMount(credentialRoutePrefix + entry.Path); match != "" {
+	if match := c.router.MatchingMount(routePath); match != "" {
 		return logical.CodedError(409, fmt.Sprintf("existing mount at %s", match))
 	}
 
@@ -81,13 +94,20 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 		}
 		entry.UUID = entryUUID
 	}
+	if entry.Accessor == "" {
+		accessor, err := c.generateMountAccessor("auth_" + entry.Type)
+		if err != nil {
+			return err
+		}
+		entry.Accessor = accessor
+	}
 
 	viewPath := credentialBarrierPrefix + entry.UUID + "/"
 	view := NewBarrierView(c.barrier, viewPath)
 	sysView := c.mountEntrySysView(entry)
 
 	// Create the new backend
-	backend, err := c.newCredentialBackend(entry.Type, sysView, view, nil)
+	backend, err := c.newCredentialBackend(entry.Type, sysView, view, entry.backendConfig())
 	if err != nil {
 		return err
 	}
@@ -108,8 +128,7 @@ func (c *Core) enableCredential(entry *MountEntry) error {
 
 	c.auth = newTable
 
-	path := credentialRoutePrefix + entry.Path
-	if err := c.router.Mount(backend, path, entry, view); err != nil {
+	if err := c.router.Mount(backend, routePath, entry, view); err != nil {
 		return err
 	}
 
@@ -128,12 +147,13 @@ func (c *Core) disableCredential(path string) (bool, error) {
 	}
 
 	// Ensure the token backend is not affected
-	if path == "token/" {
+	ns := c.namespaceByPath(path)
+	if path == ns.Path+"token/" {
 		return true, fmt.Errorf("token credential backend cannot be disabled")
 	}
 
 	// Store the view for this backend
-	fullPath := credentialRoutePrefix + path
+	fullPath := credentialRoutePath(ns, path)
 	view := c.router.MatchingStorageView(fullPath)
 	if view == nil {
 		return false, fmt.Errorf("no matching backend %s", fullPath)
@@ -281,11 +301,25 @@ func (c *Core) loadCredentials() error {
 			}
 		}
 
+		// Upgrade to entries with accessors
+		changed, err := c.setMountAccessors(c.auth)
+		if err != nil {
+			c.logger.Error("core: failed to generate auth accessors", "error", err)
+			return errLoadAuthFailed
+		}
+		if changed {
+			needPersist = true
+		}
+
 		if !needPersist {
 			return nil
 		}
 	} else {
 		c.auth = defaultAuthTable()
+		if _, err := c.setMountAccessors(c.auth); err != nil {
+			c.logger.Error("core: failed to generate auth accessors", "error", err)
+			return errLoadAuthFailed
+		}
 	}
 
 	if err := c.persistAuth(c.auth, false); err != nil {
@@ -390,21 +424,31 @@ func (c *Core) setupCredentials() error {
 		sysView := c.mountEntrySysView(entry)
 
 		// Initialize the backend
-		backend, err = c.newCredentialBackend(entry.Type, sysView, view, nil)
-		if err != nil {
-			c.logger.Error("core: failed to create credential entry", "path", entry.Path, "error", err)
-			return errLoadAuthFailed
-		}
-		if backend == nil {
+		backend, err = c.newCredentialBackend(entry.Type, sysView, view, entry.backendConfig())
+		if err == nil && backend == nil {
 			return fmt.Errorf("nil backend returned from %q factory", entry.Type)
 		}
-
-		if err := backend.Initialize(); err != nil {
-			return err
+		if err == nil {
+			err = backend.Initialize()
+		}
+		if err != nil {
+			c.logger.Error("core: failed to create credential entry", "path", entry.Path, "error", err)
+			// A plugin that fails to start should not prevent Vault from
+			// unsealing; the mount stays routed to a backend reporting the
+			// failure instead
+			if entry.Config.PluginName == "" {
+				return errLoadAuthFailed
+			}
+			backend = newPluginUnavailableBackend(entry, sysView, err)
 		}
 
 		// Mount the backend
-		path := credentialRoutePrefix + entry.Path
+		ns := c.namespaceByID(entry.NamespaceID)
+		if ns == nil {
+			c.logger.Error("core: unknown namespace of auth entry", "path", entry.Path, "namespace_id", entry.NamespaceID)
+			return errLoadAuthFailed
+		}
+		path := credentialRoutePath(ns, entry.Path)
 		err = c.router.Mount(backend, path, entry, view)
 		if err != nil {
 			c.logger.Error("core: failed to mount auth entry", "path", entry.Path, "error", err)
@@ -442,7 +486,7 @@ func (c *Core) teardownCredentials() error {
 	if c.auth != nil {
 		authTable := c.auth.shallowClone()
 		for _, e := range authTable.Entries {
-			backend := c.router.MatchingBackend(credentialRoutePrefix + e.Path)
+			backend := c.router.MatchingBackend(c.credentialRoutePath(e.Path))
 			if backend != nil {
 				backend.Cleanup()
 			}
@@ -454,6 +498,19 @@ func (c *Core) teardownCredentials() error {
 	return nil
 }
 
+// credentialRoutePath returns the router path of a credential backend
+// mounted at the given path within a namespace. Backends of child namespaces
+// are routed under the auth/ prefix of their namespace.
+func credentialRoutePath(ns *Namespace, path string) string {
+	return ns.Path + credentialRoutePrefix + strings.TrimPrefix(path, ns.Path)
+}
+
+// credentialRoutePath returns the router path of a credential backend
+// mounted at the given path
+func (c *Core) credentialRoutePath(path string) string {
+	return credentialRoutePath(c.namespaceByPath(path), path)
+}
+
 // newCredentialBackend is used to create and configure a new credential backend by name
 func (c *Core) newCredentialBackend(
 	t string, sysView logical.SystemView, view logical.Storage, conf map[string]string) (logical.Backend, error) {
@@ -99,16 +99,18 @@ func TestCore_EnableCredential_Local(t *testing.T) {
 		Type: credentialTableType,
 		Entries: []*MountEntry{
 			&MountEntry{
-				Table: credentialTableType,
-				Path:  "noop/",
-				Type:  "noop",
-				UUID:  "abcd",
+				Table:    credentialTableType,
+				Path:     "noop/",
+				Type:     "noop",
+				UUID:     "abcd",
+				Accessor: "auth_noop_abcd",
 			},
 			&MountEntry{
-				Table: credentialTableType,
-				Path:  "noop2/",
-				Type:  "noop",
-				UUID:  "bcde",
+				Table:    credentialTableType,
+				Path:     "noop2/",
+				Type:     "noop",
+				UUID:     "bcde",
+				Accessor: "auth_noop_bcde",
 			},
 		},
 	}
@@ -28,9 +28,14 @@ func (c *Core) Capabilities(token, path string) ([]string, error) {
 		return []string{DenyCapability}, nil
 	}
 
+	policyStore := c.tokenPolicyStore(te)
+	if policyStore == nil {
+		return []string{DenyCapability}, nil
+	}
+
 	var policies []*Policy
 	for _, tePolicy := range te.Policies {
-		policy, err := c.policyStore.GetPolicy(tePolicy)
+		policy, err := policyStore.GetPolicy(tePolicy)
 		if err != nil {
 			return nil, err
 		}
@@ -41,10 +46,15 @@ func (c *Core) Capabilities(token, path string) ([]string, error) {
 		return []string{DenyCapability}, nil
 	}
 
-	acl, err := NewACL(policies)
+	data, err := c.policyTemplateData(te)
+	if err != nil {
+		return nil, err
+	}
+	acl, err := newACL(policies, data)
 	if err != nil {
 		return nil, err
 	}
+	acl.namespacePath = policyStore.namespacePath
 
 	capabilities := acl.Capabilities(path)
 	sort.Strings(capabilities)
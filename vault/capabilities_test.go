@@ -3,6 +3,8 @@ package vault
 import (
 	"reflect"
 	"testing"
+
+	"github.com/hashicorp/vault/logical"
 )
 
 func TestCapabilities(t *testing.T) {
@@ -43,3 +45,98 @@ func TestCapabilities(t *testing.T) {
 		t.Fatalf("bad: got\n%#v\nexpected\n%#v\n", actual, expected)
 	}
 }
+
+func TestCapabilities_Templated(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	policy, _ := Parse(`path "secret/{{token.metadata.app}}/*" { capabilities = ["read"] }`)
+	policy.Name = "templated"
+	if err := c.policyStore.SetPolicy(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ent := &TokenEntry{
+		ID:       "capabilitiestoken",
+		Path:     "testpath",
+		Policies: []string{"templated"},
+		Meta:     map[string]string{"app": "app1"},
+		AuthMeta: true,
+	}
+	if err := c.tokenStore.create(ent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cases := map[string][]string{
+		"secret/app1/foo": []string{"read"},
+		"secret/app2/foo": []string{"deny"},
+	}
+	for path, expected := range cases {
+		actual, err := c.Capabilities("capabilitiestoken", path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: path: %s, got\n%#v\nexpected\n%#v\n", path, actual, expected)
+		}
+	}
+}
+
+func TestCapabilities_TemplatedForgedMeta(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	for name, raw := range map[string]string{
+		"templated":     `path "secret/{{token.metadata.app}}/*" { capabilities = ["read"] }`,
+		"tokencreation": tokenCreationPolicy,
+	} {
+		policy, _ := Parse(raw)
+		policy.Name = name
+		if err := c.policyStore.SetPolicy(policy); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// A token as issued by a credential backend, scoped to app1
+	ent := &TokenEntry{
+		ID:       "parenttoken",
+		Path:     "auth/userpass/login/armon",
+		Policies: []string{"templated", "tokencreation"},
+		Meta:     map[string]string{"app": "app1"},
+		AuthMeta: true,
+	}
+	if err := c.tokenStore.create(ent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A child token claiming to belong to app2
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = "parenttoken"
+	req.Data = map[string]interface{}{
+		"policies": []string{"templated"},
+		"meta":     map[string]string{"app": "app2"},
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil || resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	child := resp.Auth.ClientToken
+
+	cases := []struct {
+		token    string
+		path     string
+		expected []string
+	}{
+		{"parenttoken", "secret/app1/foo", []string{"read"}},
+		{"parenttoken", "secret/app2/foo", []string{"deny"}},
+		{child, "secret/app1/foo", []string{"deny"}},
+		{child, "secret/app2/foo", []string{"deny"}},
+	}
+	for _, tc := range cases {
+		actual, err := c.Capabilities(tc.token, tc.path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Fatalf("bad: token: %s, path: %s, got\n%#v\nexpected\n%#v\n", tc.token, tc.path, actual, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,544 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// controlGroupCubbyholePath is where a held request and its approvals
+	// are stored in the cubbyhole of the wrapping token returned for it
+	controlGroupCubbyholePath = "cubbyhole/control-group"
+
+	// defaultControlGroupTTL is how long a held request can be authorized
+	// and unwrapped if its control group does not set a TTL
+	defaultControlGroupTTL = 24 * time.Hour
+)
+
+// ControlGroup holds requests to a path until they are authorized by
+// approvers satisfying each of its factors
+type ControlGroup struct {
+	TTL     time.Duration         `json:"ttl"`
+	Factors []*ControlGroupFactor `json:"factors"`
+}
+
+// ControlGroupFactor is satisfied once the required number of approvers,
+// each holding one of the policies or belonging to one of the identity
+// groups, authorized the request
+type ControlGroupFactor struct {
+	Name       string   `json:"name" hcl:"-"`
+	Policies   []string `json:"policies" hcl:"policies"`
+	GroupNames []string `json:"group_names" hcl:"group_names"`
+	Approvals  int      `json:"approvals" hcl:"approvals"`
+}
+
+// parseControlGroup parses the control_group stanza of a path
+func parseControlGroup(node ast.Node) (*ControlGroup, error) {
+	obj, ok := node.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("control_group must be an object")
+	}
+	if err := checkHCLKeys(obj, []string{"ttl", "factor"}); err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		TTL interface{} `hcl:"ttl"`
+	}
+	if err := hcl.DecodeObject(&raw, obj); err != nil {
+		return nil, err
+	}
+
+	var cg ControlGroup
+	if raw.TTL != nil {
+		dur, err := parseutil.ParseDurationSecond(raw.TTL)
+		if err != nil {
+			return nil, errwrap.Wrapf("error parsing ttl: {{err}}", err)
+		}
+		cg.TTL = dur
+	}
+
+	for _, item := range obj.List.Filter("factor").Items {
+		if len(item.Keys) != 1 {
+			return nil, fmt.Errorf("factor must have a name")
+		}
+		name := item.Keys[0].Token.Value().(string)
+
+		if err := checkHCLKeys(item.Val, []string{"policies", "group_names", "approvals"}); err != nil {
+			return nil, multierror.Prefix(err, fmt.Sprintf("factor %q:", name))
+		}
+		factor := &ControlGroupFactor{Name: name}
+		if err := hcl.DecodeObject(factor, item.Val); err != nil {
+			return nil, multierror.Prefix(err, fmt.Sprintf("factor %q:", name))
+		}
+
+		switch {
+		case factor.Approvals < 0:
+			return nil, fmt.Errorf("factor %q: approvals must be positive", name)
+		case factor.Approvals == 0:
+			factor.Approvals = 1
+		}
+		if len(factor.Policies) == 0 && len(factor.GroupNames) == 0 {
+			return nil, fmt.Errorf("factor %q: policies or group_names must be set", name)
+		}
+
+		cg.Factors = append(cg.Factors, factor)
+	}
+	if len(cg.Factors) == 0 {
+		return nil, fmt.Errorf("at least one factor is required")
+	}
+
+	return &cg, nil
+}
+
+// clone returns a copy of the control group and its factors
+func (g *ControlGroup) clone() *ControlGroup {
+	clone := &ControlGroup{
+		TTL:     g.TTL,
+		Factors: make([]*ControlGroupFactor, 0, len(g.Factors)),
+	}
+	for _, factor := range g.Factors {
+		f := *factor
+		f.Policies = append([]string(nil), factor.Policies...)
+		f.GroupNames = append([]string(nil), factor.GroupNames...)
+		clone.Factors = append(clone.Factors, &f)
+	}
+	return clone
+}
+
+// mergeControlGroups returns a control group requiring the factors of both
+// groups, with the shorter TTL. Factors are identified by name.
+func mergeControlGroups(a, b *ControlGroup) *ControlGroup {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	merged := &ControlGroup{
+		TTL: a.TTL,
+	}
+	if merged.TTL == 0 || (b.TTL != 0 && b.TTL < merged.TTL) {
+		merged.TTL = b.TTL
+	}
+
+	seen := make(map[string]struct{})
+	for _, factors := range [][]*ControlGroupFactor{a.Factors, b.Factors} {
+		for _, factor := range factors {
+			if _, ok := seen[factor.Name]; ok {
+				continue
+			}
+			seen[factor.Name] = struct{}{}
+			merged.Factors = append(merged.Factors, factor)
+		}
+	}
+	return merged
+}
+
+// controlGroupRequest is a request held until its control group is
+// satisfied
+type controlGroupRequest struct {
+	RequestID   string                 `json:"request_id"`
+	Operation   logical.Operation      `json:"operation"`
+	Path        string                 `json:"path"`
+	Data        map[string]interface{} `json:"data"`
+	ClientToken string                 `json:"client_token"`
+
+	RequesterEntityID    string `json:"requester_entity_id"`
+	RequesterAccessor    string `json:"requester_accessor"`
+	RequesterDisplayName string `json:"requester_display_name"`
+
+	// RequesterEntityIDs holds the entities of the requester's token and of
+	// the tokens it descends from, none of which can approve
+	RequesterEntityIDs []string `json:"requester_entity_ids"`
+
+	ControlGroup *ControlGroup `json:"control_group"`
+
+	// Approvals maps the name of each factor to the approvers that
+	// satisfied it so far
+	Approvals    map[string][]string `json:"approvals"`
+	CreationTime time.Time           `json:"creation_time"`
+}
+
+// approved checks if all factors of the control group are satisfied
+func (r *controlGroupRequest) approved() bool {
+	for _, factor := range r.ControlGroup.Factors {
+		if len(r.Approvals[factor.Name]) < factor.Approvals {
+			return false
+		}
+	}
+	return true
+}
+
+// holdControlGroupRequest stores a request in the cubbyhole of a new
+// wrapping token instead of handling it. The request is replayed when the
+// token is unwrapped after enough approvers authorized it.
+func (c *Core) holdControlGroupRequest(req *logical.Request, te *TokenEntry, cg *ControlGroup) (*logical.Response, error) {
+	ttl := cg.TTL
+	if ttl == 0 {
+		ttl = defaultControlGroupTTL
+	}
+
+	// Unlike other wrapping tokens, these can be used until the request is
+	// authorized; they are revoked once unwrapped
+	creationTime := time.Now()
+	wrappingTE := TokenEntry{
+		Path:           req.Path,
+		Policies:       []string{responseWrappingPolicyName},
+		CreationTime:   creationTime.Unix(),
+		TTL:            ttl,
+		ExplicitMaxTTL: ttl,
+	}
+	if err := c.tokenStore.create(&wrappingTE); err != nil {
+		c.logger.Error("core: failed to create control group wrapping token", "error", err)
+		return nil, ErrInternalError
+	}
+
+	requesterEntityIDs, err := c.tokenTreeEntityIDs(te)
+	if err != nil {
+		c.tokenStore.Revoke(wrappingTE.ID)
+		c.logger.Error("core: failed to lookup requester token tree", "error", err)
+		return nil, ErrInternalError
+	}
+
+	held := &controlGroupRequest{
+		RequestID:            req.ID,
+		Operation:            req.Operation,
+		Path:                 req.Path,
+		Data:                 req.Data,
+		ClientToken:          req.ClientToken,
+		RequesterEntityID:    te.EntityID,
+		RequesterAccessor:    te.Accessor,
+		RequesterDisplayName: te.DisplayName,
+		RequesterEntityIDs:   requesterEntityIDs,
+		ControlGroup:         cg,
+		Approvals:            make(map[string][]string),
+		CreationTime:         creationTime,
+	}
+	if err := c.storeControlGroupRequest(wrappingTE.ID, held); err != nil {
+		c.tokenStore.Revoke(wrappingTE.ID)
+		c.logger.Error("core: failed to store control group request", "error", err)
+		return nil, ErrInternalError
+	}
+
+	// Store info for lookup
+	cubbyResp, err := c.router.Route(&logical.Request{
+		Operation:   logical.CreateOperation,
+		Path:        "cubbyhole/wrapinfo",
+		ClientToken: wrappingTE.ID,
+		Data: map[string]interface{}{
+			"creation_ttl":  ttl,
+			"creation_time": creationTime,
+		},
+	})
+	if err == nil && cubbyResp != nil && cubbyResp.IsError() {
+		err = cubbyResp.Error()
+	}
+	if err != nil {
+		c.tokenStore.Revoke(wrappingTE.ID)
+		c.logger.Error("core: failed to store wrapping information", "error", err)
+		return nil, ErrInternalError
+	}
+
+	auth := &logical.Auth{
+		ClientToken: wrappingTE.ID,
+		Policies:    wrappingTE.Policies,
+		LeaseOptions: logical.LeaseOptions{
+			TTL:       ttl,
+			Renewable: false,
+		},
+	}
+	if err := c.expiration.RegisterAuth(wrappingTE.Path, auth); err != nil {
+		c.tokenStore.Revoke(wrappingTE.ID)
+		c.logger.Error("core: failed to register control group wrapping token lease", "request_path", req.Path, "error", err)
+		return nil, ErrInternalError
+	}
+
+	return &logical.Response{
+		WrapInfo: &logical.ResponseWrapInfo{
+			TTL:          ttl,
+			Token:        wrappingTE.ID,
+			Accessor:     wrappingTE.Accessor,
+			CreationTime: creationTime,
+		},
+	}, nil
+}
+
+// heldControlGroupRequest returns the request held with the given wrapping
+// token, or nil if the token does not hold one
+func (c *Core) heldControlGroupRequest(token string) (*controlGroupRequest, error) {
+	// Only wrapping tokens can hold requests; any other token could have
+	// written its own cubbyhole
+	te, err := c.tokenStore.Lookup(token)
+	if err != nil {
+		return nil, err
+	}
+	if te == nil || len(te.Policies) != 1 || te.Policies[0] != responseWrappingPolicyName {
+		return nil, nil
+	}
+
+	resp, err := c.router.Route(&logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        controlGroupCubbyholePath,
+		ClientToken: token,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error looking up control group request: %v", err)
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, nil
+	}
+
+	raw, ok := resp.Data["request"].(string)
+	if !ok {
+		return nil, fmt.Errorf("could not decode control group request")
+	}
+	var held controlGroupRequest
+	if err := jsonutil.DecodeJSON([]byte(raw), &held); err != nil {
+		return nil, fmt.Errorf("could not decode control group request: %v", err)
+	}
+	return &held, nil
+}
+
+func (c *Core) storeControlGroupRequest(token string, held *controlGroupRequest) error {
+	encoded, err := jsonutil.EncodeJSON(held)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.router.Route(&logical.Request{
+		Operation:   logical.CreateOperation,
+		Path:        controlGroupCubbyholePath,
+		ClientToken: token,
+		Data: map[string]interface{}{
+			"request": string(encoded),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if resp != nil && resp.IsError() {
+		return resp.Error()
+	}
+	return nil
+}
+
+// controlGroupRequestByAccessor returns the wrapping token with the given
+// accessor and the request it holds
+func (c *Core) controlGroupRequestByAccessor(accessor string) (string, *controlGroupRequest, error) {
+	aEntry, err := c.tokenStore.lookupByAccessor(accessor)
+	if err != nil {
+		return "", nil, err
+	}
+	if aEntry.TokenID == "" {
+		return "", nil, nil
+	}
+
+	held, err := c.heldControlGroupRequest(aEntry.TokenID)
+	if err != nil {
+		return "", nil, err
+	}
+	return aEntry.TokenID, held, nil
+}
+
+// authorizeControlGroupRequest records the approval of the given token on
+// each factor of the held request it satisfies
+func (c *Core) authorizeControlGroupRequest(accessor string, approver *TokenEntry) (*controlGroupRequest, error) {
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	token, held, err := c.controlGroupRequestByAccessor(accessor)
+	if err != nil {
+		return nil, err
+	}
+	if held == nil {
+		return nil, &logical.StatusBadRequest{Err: "no control group request found for the accessor"}
+	}
+
+	// Approvers are identified by their entity so that they cannot approve
+	// twice using several tokens. Anyone able to create tokens could count
+	// as many approvers if tokens without an entity were accepted, so those
+	// cannot approve at all.
+	approverID := approver.EntityID
+	if approverID == "" {
+		return nil, &logical.StatusBadRequest{Err: "only tokens belonging to an identity entity can authorize requests"}
+	}
+	if c.identityStore == nil {
+		return nil, fmt.Errorf("identity store is unavailable")
+	}
+	entity, err := c.identityStore.entityByID(approverID)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, &logical.StatusBadRequest{Err: "entity of the approver token no longer exists"}
+	}
+
+	// Neither the requester nor tokens descending from the requester's
+	// token can approve
+	own, err := c.controlGroupRequesterToken(approver, held)
+	if err != nil {
+		return nil, err
+	}
+	if own {
+		return nil, &logical.StatusBadRequest{Err: "requesters cannot authorize their own requests"}
+	}
+
+	var groupNames []string
+	groups, err := c.identityStore.groupsByMember(approverID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		groupNames = append(groupNames, group.Name)
+	}
+
+	var satisfied bool
+	for _, factor := range held.ControlGroup.Factors {
+		if !strListsIntersect(factor.Policies, approver.Policies) &&
+			!strListsIntersect(factor.GroupNames, groupNames) {
+			continue
+		}
+		satisfied = true
+		if !strutil.StrListContains(held.Approvals[factor.Name], approverID) {
+			held.Approvals[factor.Name] = append(held.Approvals[factor.Name], approverID)
+		}
+	}
+	if !satisfied {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	if err := c.storeControlGroupRequest(token, held); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+// controlGroupRequesterToken checks if the token, or any of the tokens it
+// descends from, belongs to the requester of the held request
+func (c *Core) controlGroupRequesterToken(te *TokenEntry, held *controlGroupRequest) (bool, error) {
+	var own bool
+	err := c.walkTokenTree(te, func(te *TokenEntry) bool {
+		own = (te.Accessor != "" && te.Accessor == held.RequesterAccessor) ||
+			strutil.StrListContains(held.RequesterEntityIDs, te.EntityID) ||
+			(te.EntityID != "" && te.EntityID == held.RequesterEntityID)
+		return !own
+	})
+	return own, err
+}
+
+// tokenTreeEntityIDs returns the entities of the token and of the tokens it
+// descends from
+func (c *Core) tokenTreeEntityIDs(te *TokenEntry) ([]string, error) {
+	var entityIDs []string
+	err := c.walkTokenTree(te, func(te *TokenEntry) bool {
+		if te.EntityID != "" && !strutil.StrListContains(entityIDs, te.EntityID) {
+			entityIDs = append(entityIDs, te.EntityID)
+		}
+		return true
+	})
+	return entityIDs, err
+}
+
+// walkTokenTree calls fn with the token and then each of its parents, until
+// it returns false
+func (c *Core) walkTokenTree(te *TokenEntry, fn func(*TokenEntry) bool) error {
+	for te != nil && fn(te) && te.Parent != "" {
+		parent, err := c.tokenStore.Lookup(te.Parent)
+		if err != nil {
+			return err
+		}
+		te = parent
+	}
+	return nil
+}
+
+func strListsIntersect(a, b []string) bool {
+	for _, item := range a {
+		if strutil.StrListContains(b, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapControlGroupRequest replays the request held with the given
+// wrapping token once it was authorized, returning its response the way an
+// unwrapped response is returned. The wrapping token is revoked so that the
+// request is replayed only once. A nil response and error are returned if
+// the token does not hold a request.
+func (c *Core) unwrapControlGroupRequest(token string) (*logical.Response, error) {
+	c.controlGroupLock.Lock()
+	held, err := c.heldControlGroupRequest(token)
+	if err != nil || held == nil {
+		c.controlGroupLock.Unlock()
+		return nil, err
+	}
+	if !held.approved() {
+		c.controlGroupLock.Unlock()
+		return logical.ErrorResponse("request has not been authorized yet"), logical.ErrInvalidRequest
+	}
+	err = c.tokenStore.Revoke(token)
+	c.controlGroupLock.Unlock()
+	if err != nil {
+		c.logger.Error("core: failed to revoke control group wrapping token", "error", err)
+		return nil, ErrInternalError
+	}
+
+	req := &logical.Request{
+		ID:          held.RequestID,
+		Operation:   held.Operation,
+		Path:        held.Path,
+		Data:        held.Data,
+		ClientToken: held.ClientToken,
+	}
+	req.SetControlGroupApproved(true)
+
+	resp, _, err := c.handleRequest(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp == nil {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPStatusCode: http.StatusNoContent,
+			},
+		}, nil
+	}
+
+	// Ensure we don't leak internal data
+	if resp.Secret != nil {
+		resp.Secret.InternalData = nil
+	}
+	if resp.Auth != nil {
+		resp.Auth.InternalData = nil
+	}
+
+	httpResp := logical.LogicalResponseToHTTPResponse(resp)
+	httpResp.RequestID = req.ID
+	body, err := json.Marshal(httpResp)
+	if err != nil {
+		c.logger.Error("core: failed to marshal control group response", "error", err)
+		return nil, ErrInternalError
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPRawBody:     body,
+			logical.HTTPContentType: "application/json",
+		},
+	}, nil
+}
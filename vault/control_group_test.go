@@ -0,0 +1,302 @@
+package vault
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestACL_ControlGroup(t *testing.T) {
+	policy1, err := Parse(`
+path "secret/prod/*" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "4h"
+		factor "managers" { policies = ["manager"] }
+	}
+}
+path "secret/prod/exact" {
+	capabilities = ["read"]
+}
+path "secret/denied/*" {
+	capabilities = ["read"]
+	control_group = {
+		factor "managers" { policies = ["manager"] }
+	}
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy2, err := Parse(`
+path "secret/prod/*" {
+	capabilities = ["list"]
+	control_group = {
+		ttl = "1h"
+		factor "security" { group_names = ["security"] }
+	}
+}
+path "secret/denied/*" {
+	capabilities = ["deny"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	acl, err := NewACL([]*Policy{policy1, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expect := &ControlGroup{
+		TTL: time.Hour,
+		Factors: []*ControlGroupFactor{
+			&ControlGroupFactor{Name: "managers", Policies: []string{"manager"}, Approvals: 1},
+			&ControlGroupFactor{Name: "security", GroupNames: []string{"security"}, Approvals: 1},
+		},
+	}
+	if cg := acl.ControlGroup("secret/prod/foo"); !reflect.DeepEqual(cg, expect) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v", expect, cg)
+	}
+	for _, path := range []string{"secret/prod/exact", "secret/denied/foo", "secret/other"} {
+		if cg := acl.ControlGroup(path); cg != nil {
+			t.Fatalf("%s: bad: %#v", path, cg)
+		}
+	}
+
+	// Merging is idempotent since the permissions of cached policies are
+	// merged again for every token
+	acl, err = NewACL([]*Policy{policy1, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if cg := acl.ControlGroup("secret/prod/foo"); len(cg.Factors) != 2 {
+		t.Fatalf("bad: %#v", cg)
+	}
+
+	// The factors of other policies do not stick to a policy once merged
+	for _, policy := range []*Policy{policy2, policy1} {
+		acl, err = NewACL([]*Policy{policy})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if cg := acl.ControlGroup("secret/prod/foo"); len(cg.Factors) != 1 {
+			t.Fatalf("bad: %#v", cg)
+		}
+	}
+	if cg := acl.ControlGroup("secret/denied/foo"); cg == nil {
+		t.Fatal("expected a control group for a policy merged with a deny")
+	}
+
+	// Root tokens are never held
+	acl, err = NewACL([]*Policy{policy1, &Policy{Name: "root"}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if cg := acl.ControlGroup("secret/prod/foo"); cg != nil {
+		t.Fatalf("bad: %#v", cg)
+	}
+}
+
+func TestCore_ControlGroup(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	policies := map[string]string{
+		"requester": `
+path "secret/prod/*" {
+	capabilities = ["read"]
+	control_group = {
+		factor "managers" {
+			policies = ["manager"]
+			approvals = 2
+		}
+	}
+}`,
+		"manager": `
+path "sys/control-group/*" {
+	capabilities = ["update"]
+}
+path "auth/token/create" {
+	capabilities = ["update"]
+}`,
+		"other": `
+path "sys/control-group/*" {
+	capabilities = ["update"]
+}`,
+	}
+	for name, rules := range policies {
+		policy, err := Parse(rules)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		policy.Name = name
+		if err := c.policyStore.SetPolicy(policy); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/prod/foo")
+	req.ClientToken = root
+	req.Data["foo"] = "bar"
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	newEntity := func(name string) string {
+		req := logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+		req.ClientToken = root
+		req.Data["name"] = name
+		resp, err := c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+		return resp.Data["id"].(string)
+	}
+	newToken := func(entityID, parent string, policies ...string) string {
+		te := &TokenEntry{
+			Path:         "auth/userpass/login",
+			Parent:       parent,
+			Policies:     policies,
+			EntityID:     entityID,
+			CreationTime: time.Now().Unix(),
+		}
+		if err := c.tokenStore.create(te); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return te.ID
+	}
+	childToken := func(parent string) string {
+		req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+		req.ClientToken = parent
+		req.Data["policies"] = []string{"manager"}
+		resp, err := c.HandleRequest(req)
+		if err != nil {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+		return resp.Auth.ClientToken
+	}
+	requesterEntity := newEntity("requester")
+	manager1Entity := newEntity("manager1")
+
+	// The requester could approve if it were not the requester
+	requester := newToken(requesterEntity, "", "requester", "manager")
+	manager1 := newToken(manager1Entity, "", "manager")
+	manager2 := newToken(newEntity("manager2"), "", "manager")
+	other := newToken(newEntity("other"), "", "other")
+
+	// The request is held
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/prod/foo")
+	req.ClientToken = requester
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data != nil || resp.WrapInfo == nil || resp.WrapInfo.Token == "" || resp.WrapInfo.Accessor == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	wrapInfo := resp.WrapInfo
+
+	unwrap := func() (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+		req.ClientToken = wrapInfo.Token
+		return c.HandleRequest(req)
+	}
+	authorize := func(token string) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/authorize")
+		req.ClientToken = token
+		req.Data["accessor"] = wrapInfo.Accessor
+		return c.HandleRequest(req)
+	}
+
+	// It cannot be unwrapped before it is authorized
+	if resp, err := unwrap(); err == nil {
+		t.Fatalf("expected error: %#v", resp)
+	}
+
+	// Neither the requester nor tokens not satisfying a factor can approve
+	if resp, err := authorize(requester); err == nil {
+		t.Fatalf("expected error: %#v", resp)
+	}
+	if resp, err := authorize(other); err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied: %#v %v", resp, err)
+	}
+
+	// Nor can tokens created by the requester, or other tokens of its
+	// entity, whatever their entity
+	for _, token := range []string{
+		childToken(requester),
+		newToken(requesterEntity, "", "manager"),
+		newToken(manager1Entity, requester, "manager"),
+	} {
+		if resp, err := authorize(token); err == nil {
+			t.Fatalf("expected error: %#v", resp)
+		}
+	}
+
+	// Approving twice does not count twice, even with another token of the
+	// same entity
+	for _, token := range []string{manager1, newToken(manager1Entity, "", "manager")} {
+		resp, err := authorize(token)
+		if err != nil {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+		if resp.Data["approved"] != false {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+	}
+
+	// Tokens without an entity cannot approve, so an approver cannot count
+	// several times by creating child tokens
+	for i := 0; i < 2; i++ {
+		if resp, err := authorize(childToken(manager1)); err == nil {
+			t.Fatalf("expected error: %#v", resp)
+		}
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/request")
+	req.ClientToken = manager2
+	req.Data["accessor"] = wrapInfo.Accessor
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["request_path"] != "secret/prod/foo" || resp.Data["operation"] != "read" ||
+		resp.Data["approved"] != false || resp.Data["requester_display_name"] != "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	factors := resp.Data["factors"].([]map[string]interface{})
+	if len(factors) != 1 || len(factors[0]["approvers"].([]string)) != 1 {
+		t.Fatalf("bad: %#v", factors)
+	}
+
+	resp, err = authorize(manager2)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["approved"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Unwrapping now replays the request
+	resp, err = unwrap()
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	var httpResp logical.HTTPResponse
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &httpResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if httpResp.Data["foo"] != "bar" {
+		t.Fatalf("bad: %#v", httpResp)
+	}
+
+	// But only once
+	if resp, err := unwrap(); err == nil {
+		t.Fatalf("expected error: %#v", resp)
+	}
+}
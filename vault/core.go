@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"github.com/hashicorp/vault/helper/errutil"
 	"github.com/hashicorp/vault/helper/jsonutil"
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/mlock"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/physical"
@@ -62,6 +64,10 @@ const (
 	// of orphaned leader keys, to prevent slamming the backend.
 	leaderPrefixCleanDelay = 200 * time.Millisecond
 
+	// countMetricsInterval is how often the number of mounts and tokens
+	// is emitted
+	countMetricsInterval = time.Minute
+
 	// coreKeyringCanaryPath is used as a canary to indicate to replicated
 	// clusters that they need to perform a rekey operation synchronously; this
 	// isn't keyring-canary to avoid ignoring it when ignoring core/keyring
@@ -159,6 +165,14 @@ type Core struct {
 	// physical backend is the un-trusted backend with durable data
 	physical physical.Backend
 
+	// underlyingPhysical is the physical backend as configured, without
+	// the cache layer
+	underlyingPhysical physical.Backend
+
+	// barrierPhysical is the physical backend the barrier is built on; it
+	// is a standbyPhysical if standby reads are enabled
+	barrierPhysical physical.Backend
+
 	// Our Seal, for seal configuration information
 	seal Seal
 
@@ -186,6 +200,11 @@ type Core struct {
 	standbyStopCh    chan struct{}
 	manualStepDownCh chan struct{}
 
+	// enableStandbyReads allows this node to serve reads while a standby,
+	// and standbyReads is set while the state for that is set up
+	enableStandbyReads bool
+	standbyReads       bool
+
 	// unlockInfo has the keys provided to Unseal until the threshold number of parts is available, as well as the operation nonce
 	unlockInfo *unlockInformation
 
@@ -195,6 +214,10 @@ type Core struct {
 	generateRootProgress [][]byte
 	generateRootLock     sync.Mutex
 
+	// controlGroupLock serializes updates to the approvals of held
+	// control group requests
+	controlGroupLock sync.Mutex
+
 	// These variables holds the config and shares we have until we reach
 	// enough to verify the appropriate master key. Note that the same lock is
 	// used; this isn't time-critical so this shouldn't be a problem.
@@ -249,9 +272,21 @@ type Core struct {
 	// policy store is used to manage named ACL policies
 	policyStore *PolicyStore
 
+	// namespaceStore is used to manage the child namespaces
+	namespaceStore *NamespaceStore
+
 	// token store is used to manage authentication tokens
 	tokenStore *TokenStore
 
+	// identity store is used to map authenticated users to entities
+	identityStore *IdentityStore
+
+	// pluginDirectory is the location vault will look for plugin binaries
+	pluginDirectory string
+
+	// pluginCatalog is used to manage plugin configurations
+	pluginCatalog *PluginCatalog
+
 	// metricsCh is used to stop the metrics streaming
 	metricsCh chan struct{}
 
@@ -259,6 +294,9 @@ type Core struct {
 	// metrics emission and sealing leading to a nil pointer
 	metricsMutex sync.Mutex
 
+	// metricsHelper serves the in-memory metrics over sys/metrics
+	metricsHelper *metricsutil.MetricsHelper
+
 	defaultLeaseTTL time.Duration
 	maxLeaseTTL     time.Duration
 
@@ -374,6 +412,14 @@ type CoreConfig struct {
 
 	EnableUI bool `json:"ui" structs:"ui" mapstructure:"ui"`
 
+	PluginDirectory string `json:"plugin_directory" structs:"plugin_directory" mapstructure:"plugin_directory"`
+
+	// Allows standbys to serve reads locally rather than forwarding them
+	EnableStandbyReads bool `json:"enable_standby_reads" structs:"enable_standby_reads" mapstructure:"enable_standby_reads"`
+
+	// Gives sys/metrics access to the in-memory metrics sinks
+	MetricsHelper *metricsutil.MetricsHelper `json:"-" structs:"-" mapstructure:"-"`
+
 	ReloadFuncs     *map[string][]ReloadFunc
 	ReloadFuncsLock *sync.RWMutex
 }
@@ -417,6 +463,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	c := &Core{
 		devToken:                         conf.DevToken,
 		physical:                         conf.Physical,
+		underlyingPhysical:               conf.Physical,
 		redirectAddr:                     conf.RedirectAddr,
 		clusterAddr:                      conf.ClusterAddr,
 		seal:                             conf.Seal,
@@ -427,6 +474,8 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		defaultLeaseTTL:                  conf.DefaultLeaseTTL,
 		maxLeaseTTL:                      conf.MaxLeaseTTL,
 		cachingDisabled:                  conf.DisableCache,
+		enableStandbyReads:               conf.EnableStandbyReads,
+		metricsHelper:                    conf.MetricsHelper,
 		clusterName:                      conf.ClusterName,
 		clusterListenerShutdownCh:        make(chan struct{}),
 		clusterListenerShutdownSuccessCh: make(chan struct{}),
@@ -453,9 +502,26 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		}
 	}
 
-	// Construct a new AES-GCM barrier
 	var err error
-	c.barrier, err = NewAESGCMBarrier(c.physical)
+	if conf.PluginDirectory != "" {
+		c.pluginDirectory, err = filepath.Abs(conf.PluginDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("core setup failed, could not verify plugin directory: %v", err)
+		}
+	}
+
+	// Standbys serving reads need to control how the barrier reaches the
+	// physical backend
+	c.barrierPhysical = c.physical
+	if conf.EnableStandbyReads {
+		c.barrierPhysical = &standbyPhysical{
+			cached:     c.physical,
+			underlying: conf.Physical,
+		}
+	}
+
+	// Construct a new AES-GCM barrier
+	c.barrier, err = NewAESGCMBarrier(c.barrierPhysical)
 	if err != nil {
 		return nil, fmt.Errorf("barrier setup failed: %v", err)
 	}
@@ -481,10 +547,17 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	if !ok {
 		logicalBackends["generic"] = PassthroughBackendFactory
 	}
+	_, ok = logicalBackends["kv"]
+	if !ok {
+		logicalBackends["kv"] = KVBackendFactory
+	}
 	logicalBackends["cubbyhole"] = CubbyholeBackendFactory
 	logicalBackends["system"] = func(config *logical.BackendConfig) (logical.Backend, error) {
 		return NewSystemBackend(c, config)
 	}
+	logicalBackends["identity"] = func(config *logical.BackendConfig) (logical.Backend, error) {
+		return NewIdentityStore(c, config)
+	}
 	c.logicalBackends = logicalBackends
 
 	credentialBackends := make(map[string]logical.Factory)
@@ -529,6 +602,13 @@ func (c *Core) Shutdown() error {
 	return c.sealInternal()
 }
 
+// RawRequestBodyPath checks if the backend mounted at the path takes the
+// body of requests to it unparsed, in which case the HTTP front end passes it
+// as the RawBody of the request
+func (c *Core) RawRequestBodyPath(path string) bool {
+	return c.router.RawRequestBodyPath(path)
+}
+
 // LookupToken returns the properties of the token from the token store. This
 // is particularly useful to fetch the accessor of the client token and get it
 // populated in the logical request along with the client token. The accessor
@@ -543,7 +623,7 @@ func (c *Core) LookupToken(token string) (*TokenEntry, error) {
 	if c.sealed {
 		return nil, consts.ErrSealed
 	}
-	if c.standby {
+	if c.standby && !c.standbyReads {
 		return nil, consts.ErrStandby
 	}
 
@@ -580,8 +660,19 @@ func (c *Core) fetchACLandTokenEntry(req *logical.Request) (*ACL, *TokenEntry, e
 		return nil, nil, logical.ErrPermissionDenied
 	}
 
+	// Tokens of a deleted namespace are no longer valid
+	policyStore := c.tokenPolicyStore(te)
+	if policyStore == nil {
+		return nil, nil, logical.ErrPermissionDenied
+	}
+
 	// Construct the corresponding ACL object
-	acl, err := c.policyStore.ACL(te.Policies...)
+	data, err := c.policyTemplateData(te)
+	if err != nil {
+		c.logger.Error("core: failed to fetch policy template data", "error", err)
+		return nil, nil, ErrInternalError
+	}
+	acl, err := policyStore.templatedACL(data, te.Policies...)
 	if err != nil {
 		c.logger.Error("core: failed to construct ACL", "error", err)
 		return nil, nil, ErrInternalError
@@ -590,12 +681,12 @@ func (c *Core) fetchACLandTokenEntry(req *logical.Request) (*ACL, *TokenEntry, e
 	return acl, te, nil
 }
 
-func (c *Core) checkToken(req *logical.Request) (*logical.Auth, *TokenEntry, error) {
+func (c *Core) checkToken(req *logical.Request) (*logical.Auth, *TokenEntry, *ControlGroup, error) {
 	defer metrics.MeasureSince([]string{"core", "check_token"}, time.Now())
 
 	acl, te, err := c.fetchACLandTokenEntry(req)
 	if err != nil {
-		return nil, te, err
+		return nil, te, nil, err
 	}
 
 	// Check if this is a root protected path
@@ -617,9 +708,9 @@ func (c *Core) checkToken(req *logical.Request) (*logical.Auth, *TokenEntry, err
 		default:
 			c.logger.Error("core: failed to run existence check", "error", err)
 			if _, ok := err.(errutil.UserError); ok {
-				return nil, nil, err
+				return nil, nil, nil, err
 			} else {
-				return nil, nil, ErrInternalError
+				return nil, nil, nil, ErrInternalError
 			}
 		}
 
@@ -638,14 +729,22 @@ func (c *Core) checkToken(req *logical.Request) (*logical.Auth, *TokenEntry, err
 		}
 	}
 
+	// Cubbyholes live as long as the token that owns them, and batch tokens
+	// are never revoked
+	if te.Type == logical.TokenTypeBatch {
+		if entry := c.router.MatchingMountEntry(req.Path); entry != nil && entry.Type == "cubbyhole" {
+			return nil, te, nil, fmt.Errorf("batch tokens cannot use the cubbyhole")
+		}
+	}
+
 	// Check the standard non-root ACLs. Return the token entry if it's not
 	// allowed so we can decrement the use count.
 	allowed, rootPrivs := acl.AllowOperation(req)
 	if !allowed {
-		return nil, te, logical.ErrPermissionDenied
+		return nil, te, nil, logical.ErrPermissionDenied
 	}
 	if rootPath && !rootPrivs {
-		return nil, te, logical.ErrPermissionDenied
+		return nil, te, nil, logical.ErrPermissionDenied
 	}
 
 	// Create the auth response
@@ -655,7 +754,7 @@ func (c *Core) checkToken(req *logical.Request) (*logical.Auth, *TokenEntry, err
 		Metadata:    te.Meta,
 		DisplayName: te.DisplayName,
 	}
-	return auth, te, nil
+	return auth, te, acl.ControlGroup(req.Path), nil
 }
 
 // Sealed checks if the Vault is current sealed
@@ -1162,6 +1261,34 @@ func (c *Core) StepDown(req *logical.Request) (retErr error) {
 	return retErr
 }
 
+// reloadFromStorage gives up active operation after the data in storage was
+// replaced, as the keyring, mount tables and policies held in memory no
+// longer match it. With HA the node steps down and loads them again once it
+// is active; otherwise it is sealed and has to be unsealed again. The caller
+// must hold the stateLock for reading, and the node seals once it is
+// released.
+func (c *Core) reloadFromStorage() {
+	if c.ha != nil {
+		select {
+		case c.manualStepDownCh <- struct{}{}:
+			return
+		default:
+		}
+	}
+
+	go func() {
+		c.stateLock.Lock()
+		defer c.stateLock.Unlock()
+		if c.sealed {
+			return
+		}
+		c.logger.Warn("core: sealing to reload state from storage")
+		if err := c.sealInternal(); err != nil {
+			c.logger.Error("core: failed to seal", "error", err)
+		}
+	}()
+}
+
 // sealInternal is an internal method used to seal the vault.  It does not do
 // any authorization checking. The stateLock must be held prior to calling.
 func (c *Core) sealInternal() error {
@@ -1250,6 +1377,12 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.ensureWrappingKey(); err != nil {
 		return err
 	}
+	if err := c.setupPluginCatalog(); err != nil {
+		return err
+	}
+	if err := c.loadNamespaces(); err != nil {
+		return err
+	}
 	if err := c.loadMounts(); err != nil {
 		return err
 	}
@@ -1268,6 +1401,9 @@ func (c *Core) postUnseal() (retErr error) {
 	if err := c.setupCredentials(); err != nil {
 		return err
 	}
+	if err := c.setupNamespaces(); err != nil {
+		return err
+	}
 	if err := c.setupExpiration(); err != nil {
 		return err
 	}
@@ -1329,6 +1465,9 @@ func (c *Core) preSeal() error {
 	if err := c.unloadMounts(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error unloading mounts: {{err}}", err))
 	}
+	if err := c.teardownNamespaces(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down namespaces: {{err}}", err))
+	}
 	if err := enterprisePreSeal(c); err != nil {
 		result = multierror.Append(result, err)
 	}
@@ -1399,9 +1538,23 @@ func (c *Core) runStandby(doneCh, stopCh, manualStepDownCh chan struct{}) {
 			return
 		}
 
+		// Serve reads while waiting for the lock
+		var readsDoneCh, readsStopCh chan struct{}
+		if c.enableStandbyReads {
+			readsDoneCh = make(chan struct{})
+			readsStopCh = make(chan struct{})
+			go c.runStandbyReads(readsDoneCh, readsStopCh)
+		}
+
 		// Attempt the acquisition
 		leaderLostCh := c.acquireLock(lock, stopCh)
 
+		// Stop serving reads before becoming active
+		if readsStopCh != nil {
+			close(readsStopCh)
+			<-readsDoneCh
+		}
+
 		// Bail if we are being shutdown
 		if leaderLostCh == nil {
 			return
@@ -1728,6 +1881,10 @@ func (c *Core) clearLeader(uuid string) error {
 
 // emitMetrics is used to periodically expose metrics while runnig
 func (c *Core) emitMetrics(stopCh chan struct{}) {
+	// Counting tokens means listing them all, so it is done less often
+	countTicker := time.NewTicker(countMetricsInterval)
+	defer countTicker.Stop()
+
 	for {
 		select {
 		case <-time.After(time.Second):
@@ -1736,12 +1893,37 @@ func (c *Core) emitMetrics(stopCh chan struct{}) {
 				c.expiration.emitMetrics()
 			}
 			c.metricsMutex.Unlock()
+		case <-countTicker.C:
+			c.emitCountMetrics()
 		case <-stopCh:
 			return
 		}
 	}
 }
 
+// emitCountMetrics emits the number of mounts and tokens
+func (c *Core) emitCountMetrics() {
+	c.mountsLock.RLock()
+	if c.mounts != nil {
+		metrics.SetGauge([]string{"core", "mount_table", "num_entries"}, float32(len(c.mounts.Entries)))
+	}
+	c.mountsLock.RUnlock()
+
+	c.authLock.RLock()
+	if c.auth != nil {
+		metrics.SetGauge([]string{"core", "auth_table", "num_entries"}, float32(len(c.auth.Entries)))
+	}
+	c.authLock.RUnlock()
+
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+	if c.tokenStore != nil {
+		if err := c.tokenStore.emitMetrics(); err != nil {
+			c.logger.Error("core: failed to count tokens", "error", err)
+		}
+	}
+}
+
 func (c *Core) ReplicationState() consts.ReplicationState {
 	var state consts.ReplicationState
 	c.clusterParamsLock.RLock()
@@ -1,9 +1,11 @@
 package vault
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/pluginutil"
 	"github.com/hashicorp/vault/logical"
 )
 
@@ -36,8 +38,19 @@ func (d dynamicSystemView) SudoPrivilege(path string, token string) bool {
 		return false
 	}
 
+	policyStore := d.core.tokenPolicyStore(te)
+	if policyStore == nil {
+		d.core.logger.Error("namespace of token not found", "namespace_id", te.NamespaceID)
+		return false
+	}
+
 	// Construct the corresponding ACL object
-	acl, err := d.core.policyStore.ACL(te.Policies...)
+	data, err := d.core.policyTemplateData(te)
+	if err != nil {
+		d.core.logger.Error("failed to fetch policy template data", "error", err)
+		return false
+	}
+	acl, err := policyStore.templatedACL(data, te.Policies...)
 	if err != nil {
 		d.core.logger.Error("failed to retrieve ACL for token's policies", "token_policies", te.Policies, "error", err)
 		return false
@@ -76,7 +89,7 @@ func (d dynamicSystemView) Tainted() bool {
 
 // CachingDisabled indicates whether to use caching behavior
 func (d dynamicSystemView) CachingDisabled() bool {
-	return d.core.cachingDisabled || (d.mountEntry != nil && d.mountEntry.Config.ForceNoCache)
+	return d.core.cachingDisabled || d.core.standbyReads || (d.mountEntry != nil && d.mountEntry.Config.ForceNoCache)
 }
 
 // Checks if this is a primary Vault instance.
@@ -87,3 +100,17 @@ func (d dynamicSystemView) ReplicationState() consts.ReplicationState {
 	d.core.clusterParamsLock.RUnlock()
 	return state
 }
+
+// LookupPlugin looks for a plugin with the given name in the plugin catalog. It
+// returns a PluginRunner or an error if no plugin was found.
+func (d dynamicSystemView) LookupPlugin(name string) (*pluginutil.PluginRunner, error) {
+	r, err := d.core.pluginCatalog.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("no plugin found with name: %s", name)
+	}
+
+	return r, nil
+}
@@ -291,7 +291,7 @@ func (m *ExpirationManager) revokeCommon(leaseID string, force, skipToken bool)
 	}
 
 	// Delete the secondary index, but only if it's a leased secret (not auth)
-	if le.Secret != nil {
+	if le.Secret != nil && le.ClientToken != "" {
 		if err := m.removeIndexByToken(le.ClientToken, le.LeaseID); err != nil {
 			return err
 		}
@@ -515,9 +515,23 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 	if err != nil {
 		return "", err
 	}
+	// Batch tokens are never revoked, so leases they create are tied to their
+	// parent, if any, instead
+	clientToken := req.ClientToken
+	if isBatchToken(clientToken) {
+		te, err := m.tokenStore.Lookup(clientToken)
+		if err != nil {
+			return "", err
+		}
+		if te == nil {
+			return "", fmt.Errorf("batch token is no longer valid")
+		}
+		clientToken = te.Parent
+	}
+
 	le := leaseEntry{
 		LeaseID:     path.Join(req.Path, leaseUUID),
-		ClientToken: req.ClientToken,
+		ClientToken: clientToken,
 		Path:        req.Path,
 		Data:        resp.Data,
 		Secret:      resp.Secret,
@@ -531,8 +545,10 @@ func (m *ExpirationManager) Register(req *logical.Request, resp *logical.Respons
 	}
 
 	// Maintain secondary index by token
-	if err := m.createIndexByToken(le.ClientToken, le.LeaseID); err != nil {
-		return "", err
+	if le.ClientToken != "" {
+		if err := m.createIndexByToken(le.ClientToken, le.LeaseID); err != nil {
+			return "", err
+		}
 	}
 
 	// Setup revocation timer if there is a lease
@@ -705,7 +721,8 @@ func (m *ExpirationManager) renewAuthEntry(req *logical.Request, le *leaseEntry,
 	auth := *le.Auth
 	auth.IssueTime = le.IssueTime
 	auth.Increment = increment
-	if strings.HasPrefix(le.Path, "auth/token/") {
+	_, tokenStore := m.router.MatchingBackend(le.Path).(*TokenStore)
+	if tokenStore || strings.HasPrefix(le.Path, "auth/token/") {
 		auth.ClientToken = le.ClientToken
 	} else {
 		auth.ClientToken = ""
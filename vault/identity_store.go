@@ -0,0 +1,1047 @@
+package vault
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/policyutil"
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// IdentityStore is the backend mounted at "identity/". It maps the aliases
+// of authenticated users in each credential backend mount to persistent
+// entities, and allows attaching policies and metadata to entities and to
+// groups of entities. Policies of an entity and its groups are merged into
+// the tokens issued to it.
+type IdentityStore struct {
+	*framework.Backend
+
+	core *Core
+	view logical.Storage
+
+	// lock serializes all modifications, keeping the storage indexes
+	// consistent with the entries they point to
+	lock sync.RWMutex
+}
+
+// IdentityEntity represents a single user or machine, known to Vault through
+// one or more aliases
+type IdentityEntity struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Policies       []string          `json:"policies"`
+	Metadata       map[string]string `json:"metadata"`
+	AliasIDs       []string          `json:"alias_ids"`
+	CreationTime   time.Time         `json:"creation_time"`
+	LastUpdateTime time.Time         `json:"last_update_time"`
+}
+
+// IdentityAlias ties the identity of a user in a credential backend mount to
+// an entity
+type IdentityAlias struct {
+	ID             string            `json:"id"`
+	EntityID       string            `json:"entity_id"`
+	MountAccessor  string            `json:"mount_accessor"`
+	MountType      string            `json:"mount_type"`
+	Name           string            `json:"name"`
+	Metadata       map[string]string `json:"metadata"`
+	CreationTime   time.Time         `json:"creation_time"`
+	LastUpdateTime time.Time         `json:"last_update_time"`
+}
+
+// IdentityGroup is a named set of entities sharing policies
+type IdentityGroup struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Policies        []string          `json:"policies"`
+	Metadata        map[string]string `json:"metadata"`
+	MemberEntityIDs []string          `json:"member_entity_ids"`
+	CreationTime    time.Time         `json:"creation_time"`
+	LastUpdateTime  time.Time         `json:"last_update_time"`
+}
+
+// NewIdentityStore creates the identity store backend
+func NewIdentityStore(core *Core, config *logical.BackendConfig) (*IdentityStore, error) {
+	i := &IdentityStore{
+		core: core,
+		view: config.StorageView,
+	}
+
+	i.Backend = &framework.Backend{
+		Help: strings.TrimSpace(identityHelp),
+
+		Paths: []*framework.Path{
+			&framework.Path{
+				Pattern: "entity$",
+				Fields:  identityEntityFields(),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: i.pathEntityWrite,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["entity"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["entity"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "entity/id/?$",
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: i.pathEntityList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["entity-list"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["entity-list"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "entity/id/" + framework.GenericNameRegex("id"),
+				Fields:  identityEntityFields(),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   i.pathEntityRead,
+					logical.UpdateOperation: i.pathEntityWrite,
+					logical.DeleteOperation: i.pathEntityDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["entity-id"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["entity-id"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "entity/name/(?P<name>.+)",
+				Fields:  identityEntityFields(),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   i.pathEntityRead,
+					logical.UpdateOperation: i.pathEntityWrite,
+					logical.DeleteOperation: i.pathEntityDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["entity-name"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["entity-name"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "entity-alias$",
+				Fields:  identityAliasFields(),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: i.pathAliasWrite,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["alias"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["alias"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "entity-alias/id/?$",
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: i.pathAliasList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["alias-list"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["alias-list"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "entity-alias/id/" + framework.GenericNameRegex("id"),
+				Fields:  identityAliasFields(),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   i.pathAliasRead,
+					logical.UpdateOperation: i.pathAliasWrite,
+					logical.DeleteOperation: i.pathAliasDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["alias-id"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["alias-id"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "group$",
+				Fields:  identityGroupFields(),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: i.pathGroupWrite,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["group"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["group"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "group/id/?$",
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: i.pathGroupList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["group-list"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["group-list"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "group/id/" + framework.GenericNameRegex("id"),
+				Fields:  identityGroupFields(),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   i.pathGroupRead,
+					logical.UpdateOperation: i.pathGroupWrite,
+					logical.DeleteOperation: i.pathGroupDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["group-id"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["group-id"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "group/name/(?P<name>.+)",
+				Fields:  identityGroupFields(),
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   i.pathGroupRead,
+					logical.UpdateOperation: i.pathGroupWrite,
+					logical.DeleteOperation: i.pathGroupDelete,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(identityHelpText["group-name"][0]),
+				HelpDescription: strings.TrimSpace(identityHelpText["group-name"][1]),
+			},
+		},
+	}
+
+	if _, err := i.Backend.Setup(config); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func identityEntityFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"id": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "ID of the entity. If set on the entity endpoint, updates the existing entity.",
+		},
+		"name": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Name of the entity. Generated if not set on creation.",
+		},
+		"policies": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Comma-separated list of policies added to the tokens issued to the entity.",
+		},
+		"metadata": &framework.FieldSchema{
+			Type:        framework.TypeMap,
+			Description: "Metadata to associate with the entity, as string keys and values.",
+		},
+	}
+}
+
+func identityAliasFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"id": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "ID of the alias. If set on the entity-alias endpoint, updates the existing alias.",
+		},
+		"entity_id": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "ID of the entity the alias belongs to.",
+		},
+		"mount_accessor": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Accessor of the credential backend mount the alias belongs to.",
+		},
+		"mount_path": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: `Path of the credential backend mount the alias belongs to, such as "userpass/". Used to look up the mount accessor if mount_accessor is not set.`,
+		},
+		"name": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Name of the user in the credential backend, such as the username.",
+		},
+		"metadata": &framework.FieldSchema{
+			Type:        framework.TypeMap,
+			Description: "Metadata to associate with the alias, as string keys and values.",
+		},
+	}
+}
+
+func identityGroupFields() map[string]*framework.FieldSchema {
+	return map[string]*framework.FieldSchema{
+		"id": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "ID of the group. If set on the group endpoint, updates the existing group.",
+		},
+		"name": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Name of the group. Generated if not set on creation.",
+		},
+		"policies": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Comma-separated list of policies added to the tokens issued to the members of the group.",
+		},
+		"metadata": &framework.FieldSchema{
+			Type:        framework.TypeMap,
+			Description: "Metadata to associate with the group, as string keys and values.",
+		},
+		"member_entity_ids": &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "Comma-separated list of IDs of the entities that are members of the group.",
+		},
+	}
+}
+
+// CreateOrFetchEntity returns the entity the given alias of the credential
+// backend mount with the given accessor belongs to. On the first login of an
+// alias, a new entity is created for it.
+func (i *IdentityStore) CreateOrFetchEntity(mountAccessor, mountType string, alias *logical.Alias) (*IdentityEntity, error) {
+	if alias == nil || alias.Name == "" {
+		return nil, fmt.Errorf("missing alias name")
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	existing, err := i.aliasByFactors(mountAccessor, alias.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		entity, err := i.entityByID(existing.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return nil, fmt.Errorf("entity %q of alias %q not found", existing.EntityID, existing.ID)
+		}
+
+		// Keep the alias metadata in sync with the credential backend
+		if len(alias.Metadata) > 0 && !reflect.DeepEqual(existing.Metadata, alias.Metadata) {
+			existing.Metadata = alias.Metadata
+			existing.LastUpdateTime = time.Now().UTC()
+			if err := i.storeAlias(existing); err != nil {
+				return nil, err
+			}
+		}
+		return entity, nil
+	}
+
+	entity, err := i.newEntity("")
+	if err != nil {
+		return nil, err
+	}
+	newAlias, err := i.newAlias(entity.ID, mountAccessor, mountType, alias.Name)
+	if err != nil {
+		return nil, err
+	}
+	newAlias.Metadata = alias.Metadata
+	entity.AliasIDs = []string{newAlias.ID}
+
+	if err := i.storeEntity(entity, ""); err != nil {
+		return nil, err
+	}
+	if err := i.storeAlias(newAlias); err != nil {
+		return nil, err
+	}
+
+	i.Logger().Info("identity: created entity for alias", "entity_id", entity.ID, "mount_accessor", mountAccessor)
+
+	return entity, nil
+}
+
+// entityPolicies returns the policies of the given entity and of all the
+// groups it is a member of
+func (i *IdentityStore) entityPolicies(entityID string) ([]string, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	entity, err := i.entityByID(entityID)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, nil
+	}
+
+	policies := append([]string{}, entity.Policies...)
+
+	groups, err := i.groupsByMember(entityID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		policies = append(policies, group.Policies...)
+	}
+
+	return strutil.RemoveDuplicates(policies), nil
+}
+
+// pathEntityWrite creates or updates an entity
+func (i *IdentityStore) pathEntityWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, resp, err := i.entityFromRequest(d, true)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	oldName := entity.Name
+	if name, ok := d.GetOk("name"); ok && name.(string) != "" && name.(string) != entity.Name {
+		existing, err := i.entityByName(name.(string))
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return logical.ErrorResponse(fmt.Sprintf("entity name %q is already in use", name)), logical.ErrInvalidRequest
+		}
+		entity.Name = name.(string)
+	}
+	if policies, ok := d.GetOk("policies"); ok {
+		entity.Policies, err = identitySanitizePolicies(policies.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+	if raw, ok := d.GetOk("metadata"); ok {
+		entity.Metadata, err = identityParseMetadata(raw.(map[string]interface{}))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+	entity.LastUpdateTime = time.Now().UTC()
+
+	if oldName == entity.Name {
+		oldName = ""
+	}
+	if err := i.storeEntity(entity, oldName); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   entity.ID,
+			"name": entity.Name,
+		},
+	}, nil
+}
+
+// pathEntityRead returns an entity along with its aliases
+func (i *IdentityStore) pathEntityRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	entity, resp, err := i.entityFromRequest(d, false)
+	if resp != nil || err != nil || entity == nil {
+		return resp, err
+	}
+
+	aliases := make([]map[string]interface{}, 0, len(entity.AliasIDs))
+	for _, aliasID := range entity.AliasIDs {
+		alias, err := i.aliasByID(aliasID)
+		if err != nil {
+			return nil, err
+		}
+		if alias != nil {
+			aliases = append(aliases, i.aliasResponseData(alias))
+		}
+	}
+
+	groups, err := i.groupsByMember(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	groupIDs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		groupIDs = append(groupIDs, group.ID)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":               entity.ID,
+			"name":             entity.Name,
+			"policies":         entity.Policies,
+			"metadata":         entity.Metadata,
+			"aliases":          aliases,
+			"group_ids":        groupIDs,
+			"creation_time":    entity.CreationTime,
+			"last_update_time": entity.LastUpdateTime,
+		},
+	}, nil
+}
+
+// pathEntityDelete removes an entity, its aliases and its group
+// memberships
+func (i *IdentityStore) pathEntityDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, resp, err := i.entityFromRequest(d, false)
+	if resp != nil || err != nil || entity == nil {
+		return resp, err
+	}
+
+	for _, aliasID := range entity.AliasIDs {
+		alias, err := i.aliasByID(aliasID)
+		if err != nil {
+			return nil, err
+		}
+		if alias != nil {
+			if err := i.deleteAlias(alias); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	groups, err := i.groupsByMember(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		group.MemberEntityIDs = strutil.StrListDelete(group.MemberEntityIDs, entity.ID)
+		if err := i.storeGroup(group, "", []string{entity.ID}); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, i.deleteEntity(entity)
+}
+
+// pathEntityList lists the IDs of all entities
+func (i *IdentityStore) pathEntityList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	keys, err := i.view.List(identityEntityPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+// entityFromRequest fetches the entity addressed by the request, either by
+// its ID or its name. If create is set, a new entity is returned when the
+// request does not address an existing one.
+func (i *IdentityStore) entityFromRequest(d *framework.FieldData, create bool) (*IdentityEntity, *logical.Response, error) {
+	if id, ok := d.GetOk("id"); ok && id.(string) != "" {
+		entity, err := i.entityByID(id.(string))
+		if err != nil {
+			return nil, nil, err
+		}
+		if entity == nil && create {
+			return nil, logical.ErrorResponse(fmt.Sprintf("entity %q not found", id)), logical.ErrInvalidRequest
+		}
+		return entity, nil, nil
+	}
+
+	name := d.Get("name").(string)
+	if name != "" {
+		entity, err := i.entityByName(name)
+		if err != nil || entity != nil || !create {
+			return entity, nil, err
+		}
+	}
+	if !create {
+		return nil, nil, nil
+	}
+
+	entity, err := i.newEntity(name)
+	return entity, nil, err
+}
+
+// pathAliasWrite creates or updates an alias
+func (i *IdentityStore) pathAliasWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	var alias *IdentityAlias
+	if id := d.Get("id").(string); id != "" {
+		var err error
+		alias, err = i.aliasByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if alias == nil {
+			return logical.ErrorResponse(fmt.Sprintf("alias %q not found", id)), logical.ErrInvalidRequest
+		}
+	}
+
+	name := d.Get("name").(string)
+	mountAccessor := d.Get("mount_accessor").(string)
+	mountPath := d.Get("mount_path").(string)
+	entityID := d.Get("entity_id").(string)
+
+	if alias == nil {
+		if name == "" || (mountAccessor == "" && mountPath == "") || entityID == "" {
+			return logical.ErrorResponse("name, mount_accessor or mount_path, and entity_id are required to create an alias"), logical.ErrInvalidRequest
+		}
+	}
+	if alias != nil && name == "" {
+		name = alias.Name
+	}
+
+	// Aliases always refer to credential backend mounts
+	var mountEntry *MountEntry
+	switch {
+	case mountAccessor != "":
+		mountEntry = i.core.router.MatchingMountByAccessor(mountAccessor)
+		if mountEntry == nil || mountEntry.Table != credentialTableType {
+			return logical.ErrorResponse(fmt.Sprintf("no credential backend with accessor %q", mountAccessor)), logical.ErrInvalidRequest
+		}
+	case mountPath != "":
+		mountPath = strings.TrimPrefix(mountPath, credentialRoutePrefix)
+		if !strings.HasSuffix(mountPath, "/") {
+			mountPath += "/"
+		}
+		mountEntry = i.core.router.MatchingMountEntry(i.core.credentialRoutePath(mountPath))
+		if mountEntry == nil || mountEntry.Path != mountPath {
+			return logical.ErrorResponse(fmt.Sprintf("no credential backend mounted at %q", mountPath)), logical.ErrInvalidRequest
+		}
+		mountAccessor = mountEntry.Accessor
+	}
+	mountType := ""
+	if mountEntry != nil {
+		mountType = mountEntry.Type
+	} else {
+		// Updating an alias without naming a mount keeps its mount
+		mountAccessor = alias.MountAccessor
+		mountType = alias.MountType
+	}
+
+	// The combination of mount and name must be unique
+	existing, err := i.aliasByFactors(mountAccessor, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && (alias == nil || existing.ID != alias.ID) {
+		return logical.ErrorResponse(fmt.Sprintf("alias %q already exists for mount %q", name, mountAccessor)), logical.ErrInvalidRequest
+	}
+
+	var oldEntity *IdentityEntity
+	if alias == nil {
+		alias, err = i.newAlias(entityID, mountAccessor, mountType, name)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if alias.Name != name || alias.MountAccessor != mountAccessor {
+			if err := i.view.Delete(identityAliasIndexKey(alias.MountAccessor, alias.Name)); err != nil {
+				return nil, err
+			}
+		}
+		if entityID != "" && entityID != alias.EntityID {
+			oldEntity, err = i.entityByID(alias.EntityID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		alias.Name = name
+		alias.MountAccessor = mountAccessor
+		alias.MountType = mountType
+		alias.LastUpdateTime = time.Now().UTC()
+	}
+	if entityID == "" {
+		entityID = alias.EntityID
+	}
+
+	entity, err := i.entityByID(entityID)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse(fmt.Sprintf("entity %q not found", entityID)), logical.ErrInvalidRequest
+	}
+
+	if raw, ok := d.GetOk("metadata"); ok {
+		alias.Metadata, err = identityParseMetadata(raw.(map[string]interface{}))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+
+	// Move the alias between entities if needed
+	if oldEntity != nil {
+		oldEntity.AliasIDs = strutil.StrListDelete(oldEntity.AliasIDs, alias.ID)
+		if err := i.storeEntity(oldEntity, ""); err != nil {
+			return nil, err
+		}
+	}
+	alias.EntityID = entity.ID
+	if !strutil.StrListContains(entity.AliasIDs, alias.ID) {
+		entity.AliasIDs = append(entity.AliasIDs, alias.ID)
+		if err := i.storeEntity(entity, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.storeAlias(alias); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":        alias.ID,
+			"entity_id": alias.EntityID,
+		},
+	}, nil
+}
+
+// pathAliasRead returns an alias
+func (i *IdentityStore) pathAliasRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	alias, err := i.aliasByID(d.Get("id").(string))
+	if err != nil || alias == nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: i.aliasResponseData(alias),
+	}, nil
+}
+
+// pathAliasDelete removes an alias from its entity
+func (i *IdentityStore) pathAliasDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	alias, err := i.aliasByID(d.Get("id").(string))
+	if err != nil || alias == nil {
+		return nil, err
+	}
+
+	entity, err := i.entityByID(alias.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	if entity != nil {
+		entity.AliasIDs = strutil.StrListDelete(entity.AliasIDs, alias.ID)
+		if err := i.storeEntity(entity, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, i.deleteAlias(alias)
+}
+
+// pathAliasList lists the IDs of all aliases
+func (i *IdentityStore) pathAliasList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	keys, err := i.view.List(identityAliasPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+// pathGroupWrite creates or updates a group
+func (i *IdentityStore) pathGroupWrite(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	group, resp, err := i.groupFromRequest(d, true)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	oldName := group.Name
+	if name, ok := d.GetOk("name"); ok && name.(string) != "" && name.(string) != group.Name {
+		existing, err := i.groupByName(name.(string))
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return logical.ErrorResponse(fmt.Sprintf("group name %q is already in use", name)), logical.ErrInvalidRequest
+		}
+		group.Name = name.(string)
+	}
+	if policies, ok := d.GetOk("policies"); ok {
+		group.Policies, err = identitySanitizePolicies(policies.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+	if raw, ok := d.GetOk("metadata"); ok {
+		group.Metadata, err = identityParseMetadata(raw.(map[string]interface{}))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+
+	var removedMembers []string
+	if raw, ok := d.GetOk("member_entity_ids"); ok {
+		members := strutil.ParseDedupAndSortStrings(raw.(string), ",")
+		for _, entityID := range members {
+			entity, err := i.entityByID(entityID)
+			if err != nil {
+				return nil, err
+			}
+			if entity == nil {
+				return logical.ErrorResponse(fmt.Sprintf("entity %q not found", entityID)), logical.ErrInvalidRequest
+			}
+		}
+		for _, entityID := range group.MemberEntityIDs {
+			if !strutil.StrListContains(members, entityID) {
+				removedMembers = append(removedMembers, entityID)
+			}
+		}
+		group.MemberEntityIDs = members
+	}
+	group.LastUpdateTime = time.Now().UTC()
+
+	if oldName == group.Name {
+		oldName = ""
+	}
+	if err := i.storeGroup(group, oldName, removedMembers); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   group.ID,
+			"name": group.Name,
+		},
+	}, nil
+}
+
+// pathGroupRead returns a group
+func (i *IdentityStore) pathGroupRead(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	group, resp, err := i.groupFromRequest(d, false)
+	if resp != nil || err != nil || group == nil {
+		return resp, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":                group.ID,
+			"name":              group.Name,
+			"policies":          group.Policies,
+			"metadata":          group.Metadata,
+			"member_entity_ids": group.MemberEntityIDs,
+			"creation_time":     group.CreationTime,
+			"last_update_time":  group.LastUpdateTime,
+		},
+	}, nil
+}
+
+// pathGroupDelete removes a group
+func (i *IdentityStore) pathGroupDelete(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	group, resp, err := i.groupFromRequest(d, false)
+	if resp != nil || err != nil || group == nil {
+		return resp, err
+	}
+
+	return nil, i.deleteGroup(group)
+}
+
+// pathGroupList lists the IDs of all groups
+func (i *IdentityStore) pathGroupList(
+	req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	keys, err := i.view.List(identityGroupPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(keys), nil
+}
+
+// groupFromRequest fetches the group addressed by the request, either by
+// its ID or its name. If create is set, a new group is returned when the
+// request does not address an existing one.
+func (i *IdentityStore) groupFromRequest(d *framework.FieldData, create bool) (*IdentityGroup, *logical.Response, error) {
+	if id, ok := d.GetOk("id"); ok && id.(string) != "" {
+		group, err := i.groupByID(id.(string))
+		if err != nil {
+			return nil, nil, err
+		}
+		if group == nil && create {
+			return nil, logical.ErrorResponse(fmt.Sprintf("group %q not found", id)), logical.ErrInvalidRequest
+		}
+		return group, nil, nil
+	}
+
+	name := d.Get("name").(string)
+	if name != "" {
+		group, err := i.groupByName(name)
+		if err != nil || group != nil || !create {
+			return group, nil, err
+		}
+	}
+	if !create {
+		return nil, nil, nil
+	}
+
+	group, err := i.newGroup(name)
+	return group, nil, err
+}
+
+func (i *IdentityStore) newEntity(name string) (*IdentityEntity, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = "entity_" + id[:8]
+	}
+	now := time.Now().UTC()
+	return &IdentityEntity{
+		ID:             id,
+		Name:           name,
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}, nil
+}
+
+func (i *IdentityStore) newAlias(entityID, mountAccessor, mountType, name string) (*IdentityAlias, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	return &IdentityAlias{
+		ID:             id,
+		EntityID:       entityID,
+		MountAccessor:  mountAccessor,
+		MountType:      mountType,
+		Name:           name,
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}, nil
+}
+
+func (i *IdentityStore) newGroup(name string) (*IdentityGroup, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = "group_" + id[:8]
+	}
+	now := time.Now().UTC()
+	return &IdentityGroup{
+		ID:             id,
+		Name:           name,
+		CreationTime:   now,
+		LastUpdateTime: now,
+	}, nil
+}
+
+// aliasResponseData returns the response data of an alias, including the
+// current path of its mount
+func (i *IdentityStore) aliasResponseData(alias *IdentityAlias) map[string]interface{} {
+	mountPath := ""
+	if mountEntry := i.core.router.MatchingMountByAccessor(alias.MountAccessor); mountEntry != nil {
+		mountPath = mountEntry.Path
+	}
+	return map[string]interface{}{
+		"id":               alias.ID,
+		"entity_id":        alias.EntityID,
+		"mount_accessor":   alias.MountAccessor,
+		"mount_path":       mountPath,
+		"mount_type":       alias.MountType,
+		"name":             alias.Name,
+		"metadata":         alias.Metadata,
+		"creation_time":    alias.CreationTime,
+		"last_update_time": alias.LastUpdateTime,
+	}
+}
+
+// identitySanitizePolicies normalizes the policies given to an entity or a
+// group, refusing the ones that cannot be assigned to tokens
+func identitySanitizePolicies(policiesRaw string) ([]string, error) {
+	policies := policyutil.SanitizePolicies(strutil.ParseStringSlice(policiesRaw, ","), policyutil.DoNotAddDefaultPolicy)
+	for _, policy := range policies {
+		if policy == "root" || strutil.StrListContains(nonAssignablePolicies, policy) {
+			return nil, fmt.Errorf("cannot assign policy %q", policy)
+		}
+	}
+	return policies, nil
+}
+
+// identityParseMetadata converts the metadata given in a request to a map of
+// strings
+func identityParseMetadata(raw map[string]interface{}) (map[string]string, error) {
+	metadata := make(map[string]string, len(raw))
+	for k, v := range raw {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("metadata value for %q must be a string", k)
+		}
+		metadata[k] = s
+	}
+	return metadata, nil
+}
+
+const identityHelp = `
+The identity store maps the users authenticating through the credential
+backends to entities, so that a user known to several backends is handled as
+a single entity. Policies and metadata can be attached to entities and to
+groups of entities; they are added to the tokens issued to the entity.
+`
+
+var identityHelpText = map[string][2]string{
+	"entity": {
+		"Create or update an entity.",
+		`
+Creates a new entity, or updates the entity with the given ID. Policies
+set on an entity are added to the tokens issued to it on login.
+		`,
+	},
+	"entity-list": {
+		"List the IDs of all entities.",
+		"",
+	},
+	"entity-id": {
+		"Read, update or delete an entity by ID.",
+		"",
+	},
+	"entity-name": {
+		"Read, create, update or delete an entity by name.",
+		"",
+	},
+	"alias": {
+		"Create or update an entity alias.",
+		`
+An alias ties a user of a credential backend mount, identified by the name
+the backend reports for it (such as the username), to an entity. Aliases are
+created automatically on the first login of a user.
+		`,
+	},
+	"alias-list": {
+		"List the IDs of all entity aliases.",
+		"",
+	},
+	"alias-id": {
+		"Read, update or delete an entity alias by ID.",
+		"",
+	},
+	"group": {
+		"Create or update a group.",
+		`
+Creates a new group of entities, or updates the group with the given ID.
+Policies set on a group are added to the tokens issued to its members.
+		`,
+	},
+	"group-list": {
+		"List the IDs of all groups.",
+		"",
+	},
+	"group-id": {
+		"Read, update or delete a group by ID.",
+		"",
+	},
+	"group-name": {
+		"Read, create, update or delete a group by name.",
+		"",
+	},
+}
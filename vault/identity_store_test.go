@@ -0,0 +1,401 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+// testIdentityLogin logs in through the noop credential backend mounted at
+// mount, which authenticates the given alias
+func testIdentityLogin(t *testing.T, c *Core, noop *NoopBackend, mount string, alias *logical.Alias, policies []string) string {
+	noop.Response = &logical.Response{
+		Auth: &logical.Auth{
+			Policies:    policies,
+			DisplayName: alias.Name,
+			Alias:       alias,
+		},
+	}
+
+	lresp, err := c.HandleRequest(&logical.Request{
+		Path: "auth/" + mount + "login",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if lresp == nil || lresp.Auth == nil || lresp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", lresp)
+	}
+	return lresp.Auth.ClientToken
+}
+
+// testIdentityEnableNoop mounts a shared noop credential backend at each of
+// the given paths
+func testIdentityEnableNoop(t *testing.T, c *Core, root string, mounts ...string) *NoopBackend {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+	}
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+	for _, mount := range mounts {
+		req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/"+mount)
+		req.Data["type"] = "noop"
+		req.ClientToken = root
+		if _, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	return noop
+}
+
+func TestIdentityStore_Mounted(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	if c.identityStore == nil {
+		t.Fatal("expected identity store to be set up")
+	}
+	entry := c.router.MatchingMountEntry("identity/")
+	if entry == nil || entry.Type != "identity" {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	// The identity store can not be remounted
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/remount")
+	req.Data["from"] = "identity"
+	req.Data["to"] = "foo"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestIdentityStore_LoginCreatesEntity(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	noop := testIdentityEnableNoop(t, c, root, "foo", "bar")
+
+	alias := &logical.Alias{Name: "armon", Metadata: map[string]string{"org": "hashicorp"}}
+	token1 := testIdentityLogin(t, c, noop, "foo/", alias, []string{"foo"})
+	token2 := testIdentityLogin(t, c, noop, "foo/", &logical.Alias{Name: "armon"}, []string{"foo"})
+	token3 := testIdentityLogin(t, c, noop, "bar/", &logical.Alias{Name: "armon"}, []string{"bar"})
+
+	te1, err := c.tokenStore.Lookup(token1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	te2, err := c.tokenStore.Lookup(token2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	te3, err := c.tokenStore.Lookup(token3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Logins with the same alias map to the same entity
+	if te1.EntityID == "" || te1.EntityID != te2.EntityID {
+		t.Fatalf("bad: %q %q", te1.EntityID, te2.EntityID)
+	}
+
+	// The same name on another mount is another alias, and so another entity
+	if te3.EntityID == "" || te3.EntityID == te1.EntityID {
+		t.Fatalf("bad: %q %q", te1.EntityID, te3.EntityID)
+	}
+
+	// The entity lists its alias
+	req := logical.TestRequest(t, logical.ReadOperation, "identity/entity/id/"+te1.EntityID)
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	aliases := resp.Data["aliases"].([]map[string]interface{})
+	if len(aliases) != 1 {
+		t.Fatalf("bad: %#v", aliases)
+	}
+	if aliases[0]["mount_path"] != "foo/" || aliases[0]["mount_type"] != "noop" || aliases[0]["name"] != "armon" {
+		t.Fatalf("bad: %#v", aliases[0])
+	}
+	if accessor := c.router.MatchingMountEntry("auth/foo/").Accessor; accessor == "" || aliases[0]["mount_accessor"] != accessor {
+		t.Fatalf("bad: %#v", aliases[0])
+	}
+	if !reflect.DeepEqual(aliases[0]["metadata"], alias.Metadata) {
+		t.Fatalf("bad: %#v", aliases[0])
+	}
+
+	// The entity is reported by the token lookup
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = token1
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["entity_id"] != te1.EntityID {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestIdentityStore_AliasMountAccessor(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	noop := testIdentityEnableNoop(t, c, root, "foo")
+
+	token := testIdentityLogin(t, c, noop, "foo/", &logical.Alias{Name: "armon"}, []string{"foo"})
+	te, err := c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	entityID := te.EntityID
+
+	// A new mount at the same path does not inherit the aliases of the old one
+	req := logical.TestRequest(t, logical.DeleteOperation, "sys/auth/foo")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	noop = testIdentityEnableNoop(t, c, root, "foo")
+
+	token = testIdentityLogin(t, c, noop, "foo/", &logical.Alias{Name: "armon"}, []string{"foo"})
+	te, err = c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.EntityID == "" || te.EntityID == entityID {
+		t.Fatalf("bad: %q %q", te.EntityID, entityID)
+	}
+
+	// Aliases can be created by mount accessor
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity-alias")
+	req.ClientToken = root
+	req.Data["name"] = "mitchellh"
+	req.Data["mount_accessor"] = c.router.MatchingMountEntry("auth/foo/").Accessor
+	req.Data["entity_id"] = entityID
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token = testIdentityLogin(t, c, noop, "foo/", &logical.Alias{Name: "mitchellh"}, []string{"foo"})
+	te, err = c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.EntityID != entityID {
+		t.Fatalf("bad: expected %q, got %q", entityID, te.EntityID)
+	}
+
+	// The accessor must belong to a credential backend
+	req.Data["name"] = "jefferai"
+	req.Data["mount_accessor"] = c.router.MatchingMountEntry("secret/").Accessor
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+}
+
+func TestIdentityStore_EntityPolicies(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	noop := testIdentityEnableNoop(t, c, root, "foo", "bar")
+
+	token := testIdentityLogin(t, c, noop, "foo/", &logical.Alias{Name: "armon"}, []string{"foo"})
+	te, err := c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	entityID := te.EntityID
+
+	// Attach the alias of the other mount to the same entity
+	req := logical.TestRequest(t, logical.UpdateOperation, "identity/entity-alias")
+	req.ClientToken = root
+	req.Data["name"] = "mitchellh"
+	req.Data["mount_path"] = "bar"
+	req.Data["entity_id"] = entityID
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// Set policies on the entity and on a group it belongs to
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity/id/"+entityID)
+	req.ClientToken = root
+	req.Data["policies"] = "ent1,ent2"
+	req.Data["metadata"] = map[string]interface{}{"team": "core"}
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/group")
+	req.ClientToken = root
+	req.Data["name"] = "engineering"
+	req.Data["policies"] = "grp"
+	req.Data["member_entity_ids"] = entityID
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	groupID := resp.Data["id"].(string)
+
+	// Logging in through the other mount now yields the same entity and
+	// all of its policies
+	token = testIdentityLogin(t, c, noop, "bar/", &logical.Alias{Name: "mitchellh"}, []string{"bar"})
+	te, err = c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te.EntityID != entityID {
+		t.Fatalf("bad: expected %q, got %q", entityID, te.EntityID)
+	}
+	expected := []string{"bar", "default", "ent1", "ent2", "grp"}
+	if !reflect.DeepEqual(te.Policies, expected) {
+		t.Fatalf("bad: expected %v, got %v", expected, te.Policies)
+	}
+
+	// Removing the entity from the group drops the group policy
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/group/id/"+groupID)
+	req.ClientToken = root
+	req.Data["member_entity_ids"] = ""
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	policies, err := c.identityStore.entityPolicies(entityID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(policies, []string{"ent1", "ent2"}) {
+		t.Fatalf("bad: %v", policies)
+	}
+}
+
+func TestIdentityStore_EntityCRUD(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+	req.ClientToken = root
+	req.Data["name"] = "armon"
+	req.Data["policies"] = "foo"
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	id := resp.Data["id"].(string)
+
+	// Names are unique
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+	req.ClientToken = root
+	req.Data["id"] = id
+	req.Data["name"] = "armon"
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity/name/mitchellh")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity/id/"+id)
+	req.ClientToken = root
+	req.Data["name"] = "mitchellh"
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+
+	// The root policy can not be assigned
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity/id/"+id)
+	req.ClientToken = root
+	req.Data["policies"] = "root"
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+
+	// Read by name
+	req = logical.TestRequest(t, logical.ReadOperation, "identity/entity/name/armon")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["id"] != id || !reflect.DeepEqual(resp.Data["policies"], []string{"foo"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "identity/entity/id")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// Delete
+	req = logical.TestRequest(t, logical.DeleteOperation, "identity/entity/id/"+id)
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "identity/entity/name/armon")
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestIdentityStore_AliasValidation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testIdentityEnableNoop(t, c, root, "foo")
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+	req.ClientToken = root
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	entityID := resp.Data["id"].(string)
+
+	// Unknown mount
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity-alias")
+	req.ClientToken = root
+	req.Data["name"] = "armon"
+	req.Data["mount_path"] = "nope"
+	req.Data["entity_id"] = entityID
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+
+	req.Data["mount_path"] = "auth/foo/"
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	aliasID := resp.Data["id"].(string)
+
+	// Duplicate alias
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity-alias")
+	req.ClientToken = root
+	req.Data["name"] = "armon"
+	req.Data["mount_path"] = "foo"
+	req.Data["entity_id"] = entityID
+	if _, err := c.HandleRequest(req); err == nil || !errwrap.Contains(err, logical.ErrInvalidRequest.Error()) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+
+	// Deleting the entity removes its aliases
+	req = logical.TestRequest(t, logical.DeleteOperation, "identity/entity/id/"+entityID)
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "identity/entity-alias/id/"+aliasID)
+	req.ClientToken = root
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
@@ -0,0 +1,248 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// identityEntityPrefix is the storage prefix of the entities, keyed by ID
+	identityEntityPrefix = "entity/id/"
+
+	// identityEntityNamePrefix indexes the entity IDs by entity name
+	identityEntityNamePrefix = "entity/name/"
+
+	// identityAliasPrefix is the storage prefix of the aliases, keyed by ID
+	identityAliasPrefix = "alias/id/"
+
+	// identityAliasIndexPrefix indexes the alias IDs by mount accessor and
+	// alias name
+	identityAliasIndexPrefix = "alias/index/"
+
+	// identityGroupPrefix is the storage prefix of the groups, keyed by ID
+	identityGroupPrefix = "group/id/"
+
+	// identityGroupNamePrefix indexes the group IDs by group name
+	identityGroupNamePrefix = "group/name/"
+
+	// identityGroupMemberPrefix indexes the group IDs by member entity ID
+	identityGroupMemberPrefix = "group/member/"
+)
+
+// identityIndexHash hashes the given values into a storage key, so that
+// arbitrary names can be used as index keys
+func identityIndexHash(values ...string) string {
+	h := sha256.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func identityAliasIndexKey(mountAccessor, name string) string {
+	return identityAliasIndexPrefix + identityIndexHash(mountAccessor, name)
+}
+
+// identityGet decodes the JSON entry stored at key into out, returning
+// false if there is no such entry
+func (i *IdentityStore) identityGet(key string, out interface{}) (bool, error) {
+	entry, err := i.view.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %q: %v", key, err)
+	}
+	if entry == nil {
+		return false, nil
+	}
+	if err := entry.DecodeJSON(out); err != nil {
+		return false, fmt.Errorf("failed to decode %q: %v", key, err)
+	}
+	return true, nil
+}
+
+// identityIndexGet returns the ID stored at the given index key
+func (i *IdentityStore) identityIndexGet(key string) (string, error) {
+	entry, err := i.view.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read index %q: %v", key, err)
+	}
+	if entry == nil {
+		return "", nil
+	}
+	return string(entry.Value), nil
+}
+
+func (i *IdentityStore) identityPut(key string, value interface{}) error {
+	entry, err := logical.StorageEntryJSON(key, value)
+	if err != nil {
+		return err
+	}
+	return i.view.Put(entry)
+}
+
+func (i *IdentityStore) identityIndexPut(key, id string) error {
+	return i.view.Put(&logical.StorageEntry{
+		Key:   key,
+		Value: []byte(id),
+	})
+}
+
+func (i *IdentityStore) entityByID(id string) (*IdentityEntity, error) {
+	if id == "" {
+		return nil, nil
+	}
+	var entity IdentityEntity
+	found, err := i.identityGet(identityEntityPrefix+id, &entity)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func (i *IdentityStore) entityByName(name string) (*IdentityEntity, error) {
+	id, err := i.identityIndexGet(identityEntityNamePrefix + identityIndexHash(name))
+	if err != nil {
+		return nil, err
+	}
+	return i.entityByID(id)
+}
+
+// storeEntity persists the entity and its name index. If the entity was
+// renamed, oldName is the name to remove from the index.
+func (i *IdentityStore) storeEntity(entity *IdentityEntity, oldName string) error {
+	if err := i.identityPut(identityEntityPrefix+entity.ID, entity); err != nil {
+		return err
+	}
+	if oldName != "" {
+		if err := i.view.Delete(identityEntityNamePrefix + identityIndexHash(oldName)); err != nil {
+			return err
+		}
+	}
+	return i.identityIndexPut(identityEntityNamePrefix+identityIndexHash(entity.Name), entity.ID)
+}
+
+func (i *IdentityStore) deleteEntity(entity *IdentityEntity) error {
+	if err := i.view.Delete(identityEntityNamePrefix + identityIndexHash(entity.Name)); err != nil {
+		return err
+	}
+	return i.view.Delete(identityEntityPrefix + entity.ID)
+}
+
+func (i *IdentityStore) aliasByID(id string) (*IdentityAlias, error) {
+	if id == "" {
+		return nil, nil
+	}
+	var alias IdentityAlias
+	found, err := i.identityGet(identityAliasPrefix+id, &alias)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// aliasByFactors returns the alias with the given name in the credential
+// backend mount with the given accessor
+func (i *IdentityStore) aliasByFactors(mountAccessor, name string) (*IdentityAlias, error) {
+	id, err := i.identityIndexGet(identityAliasIndexKey(mountAccessor, name))
+	if err != nil {
+		return nil, err
+	}
+	return i.aliasByID(id)
+}
+
+func (i *IdentityStore) storeAlias(alias *IdentityAlias) error {
+	if err := i.identityPut(identityAliasPrefix+alias.ID, alias); err != nil {
+		return err
+	}
+	return i.identityIndexPut(identityAliasIndexKey(alias.MountAccessor, alias.Name), alias.ID)
+}
+
+func (i *IdentityStore) deleteAlias(alias *IdentityAlias) error {
+	if err := i.view.Delete(identityAliasIndexKey(alias.MountAccessor, alias.Name)); err != nil {
+		return err
+	}
+	return i.view.Delete(identityAliasPrefix + alias.ID)
+}
+
+func (i *IdentityStore) groupByID(id string) (*IdentityGroup, error) {
+	if id == "" {
+		return nil, nil
+	}
+	var group IdentityGroup
+	found, err := i.identityGet(identityGroupPrefix+id, &group)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (i *IdentityStore) groupByName(name string) (*IdentityGroup, error) {
+	id, err := i.identityIndexGet(identityGroupNamePrefix + identityIndexHash(name))
+	if err != nil {
+		return nil, err
+	}
+	return i.groupByID(id)
+}
+
+// groupsByMember returns the groups the given entity is a member of
+func (i *IdentityStore) groupsByMember(entityID string) ([]*IdentityGroup, error) {
+	groupIDs, err := i.view.List(identityGroupMemberPrefix + entityID + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*IdentityGroup, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		group, err := i.groupByID(groupID)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// storeGroup persists the group along with its name and member indexes. If
+// the group was renamed, oldName is the name to remove from the index;
+// removedMembers are the entities that are no longer members.
+func (i *IdentityStore) storeGroup(group *IdentityGroup, oldName string, removedMembers []string) error {
+	if err := i.identityPut(identityGroupPrefix+group.ID, group); err != nil {
+		return err
+	}
+	if oldName != "" {
+		if err := i.view.Delete(identityGroupNamePrefix + identityIndexHash(oldName)); err != nil {
+			return err
+		}
+	}
+	if err := i.identityIndexPut(identityGroupNamePrefix+identityIndexHash(group.Name), group.ID); err != nil {
+		return err
+	}
+	for _, entityID := range removedMembers {
+		if err := i.view.Delete(identityGroupMemberPrefix + entityID + "/" + group.ID); err != nil {
+			return err
+		}
+	}
+	for _, entityID := range group.MemberEntityIDs {
+		if err := i.identityIndexPut(identityGroupMemberPrefix+entityID+"/"+group.ID, group.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *IdentityStore) deleteGroup(group *IdentityGroup) error {
+	for _, entityID := range group.MemberEntityIDs {
+		if err := i.view.Delete(identityGroupMemberPrefix + entityID + "/" + group.ID); err != nil {
+			return err
+		}
+	}
+	if err := i.view.Delete(identityGroupNamePrefix + identityIndexHash(group.Name)); err != nil {
+		return err
+	}
+	return i.view.Delete(identityGroupPrefix + group.ID)
+}
@@ -16,6 +16,10 @@ func CubbyholeBackendFactory(conf *logical.BackendConfig) (logical.Backend, erro
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(cubbyholeHelp),
 
+		PathsSpecial: &logical.Paths{
+			StandbyRead: []string{"*"},
+		},
+
 		Paths: []*framework.Path{
 			&framework.Path{
 				Pattern: ".*",
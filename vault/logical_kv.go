@@ -0,0 +1,998 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/locksutil"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+const (
+	// kvMetadataPrefix is the storage prefix for per-key metadata
+	kvMetadataPrefix = "metadata/"
+
+	// kvVersionsPrefix is the storage prefix for the data of each version
+	kvVersionsPrefix = "versions/"
+
+	// kvConfigPath is the storage path of the backend configuration
+	kvConfigPath = "config"
+
+	// kvUpgradingPath is written while passthrough data is being migrated so
+	// that an interrupted upgrade can be resumed
+	kvUpgradingPath = "upgrading"
+
+	// kvUpgradedPath is written once passthrough data has been migrated
+	kvUpgradedPath = "upgraded"
+
+	// kvDefaultMaxVersions is the number of versions kept for a key when
+	// neither the key nor the backend configuration sets a limit
+	kvDefaultMaxVersions = 10
+)
+
+// KVBackendFactory returns a versioned key/value backend
+func KVBackendFactory(conf *logical.BackendConfig) (logical.Backend, error) {
+	if conf == nil {
+		return nil, fmt.Errorf("Configuation passed into backend is nil")
+	}
+
+	b := &KVBackend{
+		view:  conf.StorageView,
+		locks: locksutil.CreateLocks(),
+	}
+
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(kvHelp),
+
+		PathsSpecial: &logical.Paths{
+			StandbyRead: []string{
+				"config",
+				"data/*",
+				"metadata/*",
+			},
+		},
+
+		Paths: []*framework.Path{
+			&framework.Path{
+				Pattern: "config$",
+
+				Fields: map[string]*framework.FieldSchema{
+					"max_versions": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "The number of versions to keep for each key. Defaults to 10.",
+					},
+					"cas_required": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: "If true, all keys require the cas parameter to be set on writes.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleConfigRead,
+					logical.UpdateOperation: b.handleConfigWrite,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(kvHelp_config[0]),
+				HelpDescription: strings.TrimSpace(kvHelp_config[1]),
+			},
+
+			&framework.Path{
+				Pattern: "data/(?P<path>.+)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Location of the secret.",
+					},
+					"version": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "The version to read. If not set, the latest version is returned.",
+					},
+					"data": &framework.FieldSchema{
+						Type:        framework.TypeMap,
+						Description: "The contents of the new version of the secret.",
+					},
+					"options": &framework.FieldSchema{
+						Type: framework.TypeMap,
+						Description: `Options for the write. If "cas" is set the write only succeeds if
+the current version of the key matches it; a "cas" of 0 only
+allows the write if the key does not exist.`,
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleDataRead,
+					logical.CreateOperation: b.handleDataWrite,
+					logical.UpdateOperation: b.handleDataWrite,
+					logical.DeleteOperation: b.handleDataDelete,
+				},
+
+				ExistenceCheck: b.handleExistenceCheck,
+
+				HelpSynopsis:    strings.TrimSpace(kvHelp_data[0]),
+				HelpDescription: strings.TrimSpace(kvHelp_data[1]),
+			},
+
+			b.versionsPath("delete", b.handleVersionsDelete, kvHelp_delete),
+			b.versionsPath("undelete", b.handleVersionsUndelete, kvHelp_undelete),
+			b.versionsPath("destroy", b.handleVersionsDestroy, kvHelp_destroy),
+
+			&framework.Path{
+				Pattern: "metadata/?(?P<path>.*)",
+
+				Fields: map[string]*framework.FieldSchema{
+					"path": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: "Location of the secret.",
+					},
+					"max_versions": &framework.FieldSchema{
+						Type:        framework.TypeInt,
+						Description: "The number of versions to keep. If not set, the backend's configured value is used.",
+					},
+					"cas_required": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: "If true, the key requires the cas parameter to be set on writes.",
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ReadOperation:   b.handleMetadataRead,
+					logical.UpdateOperation: b.handleMetadataWrite,
+					logical.DeleteOperation: b.handleMetadataDelete,
+					logical.ListOperation:   b.handleMetadataList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(kvHelp_metadata[0]),
+				HelpDescription: strings.TrimSpace(kvHelp_metadata[1]),
+			},
+		},
+
+		Init: b.upgrade,
+	}
+
+	b.Backend.Setup(conf)
+
+	return b, nil
+}
+
+// KVBackend is a versioned key/value store. Every write creates a new
+// version of the key; older versions can be read, soft deleted, restored or
+// permanently destroyed.
+type KVBackend struct {
+	*framework.Backend
+
+	view  logical.Storage
+	locks []*locksutil.LockEntry
+}
+
+// kvConfig is the mount-wide configuration of the backend
+type kvConfig struct {
+	MaxVersions int  `json:"max_versions"`
+	CASRequired bool `json:"cas_required"`
+}
+
+// kvKeyMetadata is the metadata stored for every key
+type kvKeyMetadata struct {
+	Key            string                        `json:"key"`
+	Versions       map[uint64]*kvVersionMetadata `json:"versions"`
+	CurrentVersion uint64                        `json:"current_version"`
+	OldestVersion  uint64                        `json:"oldest_version"`
+	MaxVersions    int                           `json:"max_versions"`
+	CASRequired    bool                          `json:"cas_required"`
+	CreatedTime    time.Time                     `json:"created_time"`
+	UpdatedTime    time.Time                     `json:"updated_time"`
+}
+
+// kvVersionMetadata is the metadata stored for every version of a key
+type kvVersionMetadata struct {
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime time.Time `json:"deletion_time"`
+	Destroyed    bool      `json:"destroyed"`
+}
+
+// kvVersion is the stored data of a single version
+type kvVersion struct {
+	Data        map[string]interface{} `json:"data"`
+	CreatedTime time.Time              `json:"created_time"`
+}
+
+func (v *kvVersionMetadata) toMap(version uint64) map[string]interface{} {
+	deletionTime := ""
+	if !v.DeletionTime.IsZero() {
+		deletionTime = v.DeletionTime.Format(time.RFC3339Nano)
+	}
+	ret := map[string]interface{}{
+		"created_time":  v.CreatedTime.Format(time.RFC3339Nano),
+		"deletion_time": deletionTime,
+		"destroyed":     v.Destroyed,
+	}
+	if version != 0 {
+		ret["version"] = version
+	}
+	return ret
+}
+
+func (b *KVBackend) versionsPath(op string, callback framework.OperationFunc, help [2]string) *framework.Path {
+	return &framework.Path{
+		Pattern: op + "/(?P<path>.+)",
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"versions": &framework.FieldSchema{
+				Type:        framework.TypeCommaIntSlice,
+				Description: "The versions to operate on.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: callback,
+		},
+
+		HelpSynopsis:    strings.TrimSpace(help[0]),
+		HelpDescription: strings.TrimSpace(help[1]),
+	}
+}
+
+func (b *KVBackend) lock(key string) *locksutil.LockEntry {
+	return locksutil.LockForKey(b.locks, key)
+}
+
+func (b *KVBackend) config(s logical.Storage) (*kvConfig, error) {
+	entry, err := s.Get(kvConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &kvConfig{}
+	if entry == nil {
+		return config, nil
+	}
+	if err := jsonutil.DecodeJSON(entry.Value, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (b *KVBackend) keyMetadata(s logical.Storage, key string) (*kvKeyMetadata, error) {
+	entry, err := s.Get(kvMetadataPrefix + key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var meta kvKeyMetadata
+	if err := jsonutil.DecodeJSON(entry.Value, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %v", err)
+	}
+	if meta.Versions == nil {
+		meta.Versions = make(map[uint64]*kvVersionMetadata)
+	}
+	return &meta, nil
+}
+
+func (b *KVBackend) writeKeyMetadata(s logical.Storage, meta *kvKeyMetadata) error {
+	buf, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %v", err)
+	}
+	return s.Put(&logical.StorageEntry{
+		Key:   kvMetadataPrefix + meta.Key,
+		Value: buf,
+	})
+}
+
+func kvVersionKey(key string, version uint64) string {
+	return kvVersionsPrefix + key + "/" + strconv.FormatUint(version, 10)
+}
+
+// writeVersion stores data as the next version of the key described by meta
+// and prunes versions beyond the configured maximum. The caller must hold
+// the key's lock.
+func (b *KVBackend) writeVersion(s logical.Storage, config *kvConfig, meta *kvKeyMetadata, data map[string]interface{}, now time.Time) (uint64, error) {
+	version := meta.CurrentVersion + 1
+	buf, err := json.Marshal(&kvVersion{
+		Data:        data,
+		CreatedTime: now,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("json encoding failed: %v", err)
+	}
+	if err := s.Put(&logical.StorageEntry{
+		Key:   kvVersionKey(meta.Key, version),
+		Value: buf,
+	}); err != nil {
+		return 0, fmt.Errorf("failed to write: %v", err)
+	}
+
+	if meta.CreatedTime.IsZero() {
+		meta.CreatedTime = now
+	}
+	if meta.OldestVersion == 0 {
+		meta.OldestVersion = version
+	}
+	meta.UpdatedTime = now
+	meta.CurrentVersion = version
+	meta.Versions[version] = &kvVersionMetadata{
+		CreatedTime: now,
+	}
+
+	if err := b.pruneVersions(s, config, meta); err != nil {
+		return 0, err
+	}
+
+	if err := b.writeKeyMetadata(s, meta); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// pruneVersions removes the oldest versions of a key until no more than the
+// maximum number of versions remain
+func (b *KVBackend) pruneVersions(s logical.Storage, config *kvConfig, meta *kvKeyMetadata) error {
+	maxVersions := kvDefaultMaxVersions
+	switch {
+	case meta.MaxVersions > 0:
+		maxVersions = meta.MaxVersions
+	case config.MaxVersions > 0:
+		maxVersions = config.MaxVersions
+	}
+
+	for meta.CurrentVersion-meta.OldestVersion >= uint64(maxVersions) {
+		if err := s.Delete(kvVersionKey(meta.Key, meta.OldestVersion)); err != nil {
+			return fmt.Errorf("failed to prune version: %v", err)
+		}
+		delete(meta.Versions, meta.OldestVersion)
+		meta.OldestVersion++
+	}
+	return nil
+}
+
+func (b *KVBackend) handleConfigRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"max_versions": config.MaxVersions,
+			"cas_required": config.CASRequired,
+		},
+	}, nil
+}
+
+func (b *KVBackend) handleConfigWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxRaw, ok := data.GetOk("max_versions"); ok {
+		config.MaxVersions = maxRaw.(int)
+		if config.MaxVersions < 0 {
+			return logical.ErrorResponse("max_versions cannot be negative"), nil
+		}
+	}
+	if casRaw, ok := data.GetOk("cas_required"); ok {
+		config.CASRequired = casRaw.(bool)
+	}
+
+	entry, err := logical.StorageEntryJSON(kvConfigPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *KVBackend) handleExistenceCheck(
+	req *logical.Request, data *framework.FieldData) (bool, error) {
+	meta, err := b.keyMetadata(req.Storage, data.Get("path").(string))
+	if err != nil {
+		return false, fmt.Errorf("existence check failed: %v", err)
+	}
+
+	return meta != nil, nil
+}
+
+func (b *KVBackend) handleDataRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+
+	lock := b.lock(key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	version := meta.CurrentVersion
+	if versionRaw, ok := data.GetOk("version"); ok && versionRaw.(int) > 0 {
+		version = uint64(versionRaw.(int))
+	}
+
+	vm, ok := meta.Versions[version]
+	if !ok {
+		return nil, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"data":     nil,
+			"metadata": vm.toMap(version),
+		},
+	}
+
+	// Deleted and destroyed versions only return their metadata
+	if vm.Destroyed || !vm.DeletionTime.IsZero() {
+		return resp, nil
+	}
+
+	out, err := req.Storage.Get(kvVersionKey(key, version))
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %v", err)
+	}
+	if out == nil {
+		return resp, nil
+	}
+
+	var stored kvVersion
+	if err := jsonutil.DecodeJSON(out.Value, &stored); err != nil {
+		return nil, fmt.Errorf("json decoding failed: %v", err)
+	}
+	resp.Data["data"] = stored.Data
+
+	return resp, nil
+}
+
+func (b *KVBackend) handleDataWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+
+	secretData := data.Get("data").(map[string]interface{})
+	if len(secretData) == 0 {
+		return logical.ErrorResponse("missing data fields"), nil
+	}
+
+	var cas int
+	var casSet bool
+	if casRaw, ok := data.Get("options").(map[string]interface{})["cas"]; ok {
+		casField := &framework.FieldData{
+			Raw: map[string]interface{}{
+				"cas": casRaw,
+			},
+			Schema: map[string]*framework.FieldSchema{
+				"cas": &framework.FieldSchema{Type: framework.TypeInt},
+			},
+		}
+		casVal, _, err := casField.GetOkErr("cas")
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid cas value: %v", err)), nil
+		}
+		cas = casVal.(int)
+		if cas < 0 {
+			return logical.ErrorResponse("cas must be >= 0"), nil
+		}
+		casSet = true
+	}
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := b.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = &kvKeyMetadata{
+			Key:      key,
+			Versions: make(map[uint64]*kvVersionMetadata),
+		}
+	}
+
+	switch {
+	case casSet:
+		if uint64(cas) != meta.CurrentVersion {
+			return logical.ErrorResponse(fmt.Sprintf(
+				"check-and-set parameter did not match the current version; current version is %d", meta.CurrentVersion)), logical.ErrInvalidRequest
+		}
+	case config.CASRequired || meta.CASRequired:
+		return logical.ErrorResponse("check-and-set parameter required for this call"), logical.ErrInvalidRequest
+	}
+
+	now := time.Now().UTC()
+	version, err := b.writeVersion(req.Storage, config, meta, secretData, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: meta.Versions[version].toMap(version),
+	}, nil
+}
+
+func (b *KVBackend) handleDataDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+
+	lock := b.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	vm, ok := meta.Versions[meta.CurrentVersion]
+	if !ok || vm.Destroyed || !vm.DeletionTime.IsZero() {
+		return nil, nil
+	}
+	vm.DeletionTime = time.Now().UTC()
+
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+// updateVersions loads the metadata of the requested key and calls cb for
+// every requested version that exists, persisting the metadata afterwards
+func (b *KVBackend) updateVersions(req *logical.Request, data *framework.FieldData,
+	cb func(string, uint64, *kvVersionMetadata) error) (*logical.Response, error) {
+	key := data.Get("path").(string)
+	versions := data.Get("versions").([]int)
+	if len(versions) == 0 {
+		return logical.ErrorResponse("no versions provided"), logical.ErrInvalidRequest
+	}
+
+	lock := b.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	for _, v := range versions {
+		if v <= 0 {
+			return logical.ErrorResponse(fmt.Sprintf("invalid version %d", v)), logical.ErrInvalidRequest
+		}
+		vm, ok := meta.Versions[uint64(v)]
+		if !ok {
+			continue
+		}
+		if err := cb(key, uint64(v), vm); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+func (b *KVBackend) handleVersionsDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	now := time.Now().UTC()
+	return b.updateVersions(req, data, func(key string, version uint64, vm *kvVersionMetadata) error {
+		if vm.DeletionTime.IsZero() && !vm.Destroyed {
+			vm.DeletionTime = now
+		}
+		return nil
+	})
+}
+
+func (b *KVBackend) handleVersionsUndelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.updateVersions(req, data, func(key string, version uint64, vm *kvVersionMetadata) error {
+		if !vm.Destroyed {
+			vm.DeletionTime = time.Time{}
+		}
+		return nil
+	})
+}
+
+func (b *KVBackend) handleVersionsDestroy(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return b.updateVersions(req, data, func(key string, version uint64, vm *kvVersionMetadata) error {
+		if err := req.Storage.Delete(kvVersionKey(key, version)); err != nil {
+			return fmt.Errorf("failed to destroy version %d: %v", version, err)
+		}
+		vm.Destroyed = true
+		return nil
+	})
+}
+
+func (b *KVBackend) handleMetadataRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), nil
+	}
+
+	lock := b.lock(key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	versions := make(map[string]interface{}, len(meta.Versions))
+	for v, vm := range meta.Versions {
+		versions[strconv.FormatUint(v, 10)] = vm.toMap(0)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"versions":        versions,
+			"current_version": meta.CurrentVersion,
+			"oldest_version":  meta.OldestVersion,
+			"max_versions":    meta.MaxVersions,
+			"cas_required":    meta.CASRequired,
+			"created_time":    meta.CreatedTime.Format(time.RFC3339Nano),
+			"updated_time":    meta.UpdatedTime.Format(time.RFC3339Nano),
+		},
+	}, nil
+}
+
+func (b *KVBackend) handleMetadataWrite(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), nil
+	}
+
+	config, err := b.config(req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := b.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if meta == nil {
+		meta = &kvKeyMetadata{
+			Key:         key,
+			Versions:    make(map[uint64]*kvVersionMetadata),
+			CreatedTime: now,
+		}
+	}
+
+	if maxRaw, ok := data.GetOk("max_versions"); ok {
+		meta.MaxVersions = maxRaw.(int)
+		if meta.MaxVersions < 0 {
+			return logical.ErrorResponse("max_versions cannot be negative"), nil
+		}
+	}
+	if casRaw, ok := data.GetOk("cas_required"); ok {
+		meta.CASRequired = casRaw.(bool)
+	}
+	meta.UpdatedTime = now
+
+	if meta.CurrentVersion > 0 {
+		if err := b.pruneVersions(req.Storage, config, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, b.writeKeyMetadata(req.Storage, meta)
+}
+
+func (b *KVBackend) handleMetadataDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	key := data.Get("path").(string)
+	if key == "" {
+		return logical.ErrorResponse("missing path"), nil
+	}
+
+	lock := b.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.keyMetadata(req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	// Remove all versions before the metadata so that a failure leaves the
+	// key in a state where the delete can be retried
+	for v := range meta.Versions {
+		if err := req.Storage.Delete(kvVersionKey(key, v)); err != nil {
+			return nil, err
+		}
+	}
+	if err := req.Storage.Delete(kvMetadataPrefix + key); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *KVBackend) handleMetadataList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// As with the passthrough backend only directories can be listed, so
+	// ensure the prefix ends with a slash unless it's the root
+	path := data.Get("path").(string)
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path = path + "/"
+	}
+
+	keys, err := req.Storage.List(kvMetadataPrefix + path)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(keys), nil
+}
+
+// kvReservedKey returns whether the storage key is used by the backend
+// itself, and so cannot hold passthrough data
+func kvReservedKey(key string) bool {
+	return key == kvConfigPath || key == kvUpgradingPath || key == kvUpgradedPath ||
+		strings.HasPrefix(key, kvMetadataPrefix) ||
+		strings.HasPrefix(key, kvVersionsPrefix)
+}
+
+// checkKVUpgrade verifies that the passthrough data in the view can be
+// migrated. This must run before the mount is switched to kv, as a secret
+// stored at the path of an upgrade marker would otherwise be taken for it.
+func checkKVUpgrade(view logical.Storage) error {
+	keys, err := logical.CollectKeys(view)
+	if err != nil {
+		return fmt.Errorf("failed to collect keys for upgrade: %v", err)
+	}
+	for _, key := range keys {
+		if kvReservedKey(key) {
+			return fmt.Errorf("cannot upgrade: existing key %q uses a reserved path", key)
+		}
+	}
+	return nil
+}
+
+// upgrade migrates data written by the passthrough backend into versioned
+// storage. Each existing secret becomes version 1 of the same key. This runs
+// once per mount; a marker records that the migration completed.
+func (b *KVBackend) upgrade() error {
+	done, err := b.view.Get(kvUpgradedPath)
+	if err != nil {
+		return err
+	}
+	if done != nil {
+		return nil
+	}
+
+	migrated, err := b.migratePassthrough()
+	if err != nil {
+		return err
+	}
+
+	// The passthrough entries are only removed once everything has been
+	// copied, as a mount that is still generic keeps serving them
+	for _, key := range migrated {
+		if err := b.view.Delete(key); err != nil {
+			return fmt.Errorf("failed to remove %q after migration: %v", key, err)
+		}
+	}
+
+	if err := b.view.Put(&logical.StorageEntry{
+		Key:   kvUpgradedPath,
+		Value: []byte("1"),
+	}); err != nil {
+		return err
+	}
+	if err := b.view.Delete(kvUpgradingPath); err != nil {
+		return err
+	}
+
+	if len(migrated) > 0 && b.Logger().IsInfo() {
+		b.Logger().Info("kv: upgrade complete")
+	}
+	return nil
+}
+
+// migratePassthrough copies the data written by the passthrough backend into
+// versioned storage, and returns the keys of the passthrough entries. Keys
+// that already have metadata were copied by an earlier, interrupted run and
+// are skipped, so this can be run again until it succeeds.
+func (b *KVBackend) migratePassthrough() ([]string, error) {
+	resuming, err := b.view.Get(kvUpgradingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := logical.CollectKeys(b.view)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect keys for upgrade: %v", err)
+	}
+	sort.Strings(keys)
+
+	var toMigrate []string
+	for _, key := range keys {
+		reserved := kvReservedKey(key)
+		switch {
+		case !reserved:
+			toMigrate = append(toMigrate, key)
+		case resuming == nil:
+			// Passthrough data stored under a path this backend uses for
+			// itself cannot be migrated without clobbering it
+			return nil, fmt.Errorf("cannot upgrade: existing key %q uses a reserved path", key)
+		}
+	}
+
+	if len(toMigrate) > 0 {
+		if b.Logger().IsInfo() {
+			b.Logger().Info("kv: upgrading passthrough data", "num_keys", len(toMigrate))
+		}
+		if err := b.view.Put(&logical.StorageEntry{
+			Key:   kvUpgradingPath,
+			Value: []byte("1"),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := b.config(b.view)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var migrated []string
+	for _, key := range toMigrate {
+		out, err := b.view.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q during upgrade: %v", key, err)
+		}
+		if out == nil {
+			continue
+		}
+		migrated = append(migrated, key)
+
+		meta, err := b.keyMetadata(b.view, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta != nil {
+			continue
+		}
+
+		var rawData map[string]interface{}
+		if err := jsonutil.DecodeJSON(out.Value, &rawData); err != nil {
+			return nil, fmt.Errorf("failed to decode %q during upgrade: %v", key, err)
+		}
+
+		meta = &kvKeyMetadata{
+			Key:      key,
+			Versions: make(map[uint64]*kvVersionMetadata),
+		}
+		if _, err := b.writeVersion(b.view, config, meta, rawData, now); err != nil {
+			return nil, fmt.Errorf("failed to migrate %q: %v", key, err)
+		}
+	}
+
+	return migrated, nil
+}
+
+// discardUpgrade removes everything migratePassthrough wrote, for when the
+// mount stays a passthrough mount. It must only be used if checkKVUpgrade
+// passed before the migration, so that no passthrough data is removed.
+func (b *KVBackend) discardUpgrade() error {
+	keys, err := logical.CollectKeys(b.view)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key != kvUpgradingPath &&
+			!strings.HasPrefix(key, kvMetadataPrefix) &&
+			!strings.HasPrefix(key, kvVersionsPrefix) {
+			continue
+		}
+		if err := b.view.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const kvHelp = `
+The kv backend stores arbitrary secrets in versioned form.
+
+Every write to a key creates a new version. Previous versions can be read
+by number, soft deleted and undeleted, or permanently destroyed. Writes can
+use check-and-set to avoid overwriting concurrent changes.
+`
+
+var kvHelp_config = [2]string{
+	"Configures settings for the kv backend.",
+	`
+The max_versions parameter sets how many versions are kept for each key
+unless the key's metadata overrides it. Setting cas_required requires the
+check-and-set option on every write.
+`,
+}
+
+var kvHelp_data = [2]string{
+	"Write, read, and delete versioned secrets.",
+	`
+Writing creates a new version of the key from the "data" field. The
+optional "options" map may contain "cas", the version the write expects to
+be current. Reading returns the latest version unless "version" is given.
+Deleting soft deletes the latest version; it can be restored with undelete.
+`,
+}
+
+var kvHelp_delete = [2]string{
+	"Soft deletes one or more versions of a key.",
+	`
+The data of deleted versions is retained and can be restored using the
+undelete endpoint.
+`,
+}
+
+var kvHelp_undelete = [2]string{
+	"Restores soft deleted versions of a key.",
+	`
+Versions that have been destroyed cannot be restored.
+`,
+}
+
+var kvHelp_destroy = [2]string{
+	"Permanently removes the data of one or more versions of a key.",
+	`
+The version metadata is kept and marked as destroyed.
+`,
+}
+
+var kvHelp_metadata = [2]string{
+	"Read, configure, list, and delete key metadata.",
+	`
+Reading returns the versions of a key along with their creation and
+deletion times. Writing sets the key's max_versions and cas_required
+settings. Deleting removes every version and the metadata of the key.
+Listing returns the keys under a prefix.
+`,
+}
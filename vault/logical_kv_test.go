@@ -0,0 +1,324 @@
+package vault
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestKVBackend_WriteRead(t *testing.T) {
+	b, storage := testKVBackend(t)
+
+	for i := 1; i <= 3; i++ {
+		resp := testKVRequest(t, b, storage, logical.UpdateOperation, "data/foo", map[string]interface{}{
+			"data": map[string]interface{}{
+				"value": i,
+			},
+		})
+		if resp == nil || resp.Data["version"] != uint64(i) {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+
+	resp := testKVRequest(t, b, storage, logical.ReadOperation, "data/foo", nil)
+	if resp.Data["data"].(map[string]interface{})["value"] != json.Number("3") {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["metadata"].(map[string]interface{})["version"] != uint64(3) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testKVRequest(t, b, storage, logical.ReadOperation, "data/foo", map[string]interface{}{
+		"version": "2",
+	})
+	if resp.Data["data"].(map[string]interface{})["value"] != json.Number("2") {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testKVRequest(t, b, storage, logical.ReadOperation, "data/foo", map[string]interface{}{
+		"version": 10,
+	})
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testKVRequest(t, b, storage, logical.ListOperation, "metadata/", nil)
+	expected := &logical.Response{
+		Data: map[string]interface{}{
+			"keys": []string{"foo"},
+		},
+	}
+	if !reflect.DeepEqual(resp, expected) {
+		t.Fatalf("bad response.\n\nexpected: %#v\n\nGot: %#v", expected, resp)
+	}
+}
+
+func TestKVBackend_CAS(t *testing.T) {
+	b, storage := testKVBackend(t)
+
+	write := func(cas interface{}) (*logical.Response, error) {
+		req := logical.TestRequest(t, logical.UpdateOperation, "data/foo")
+		req.Storage = storage
+		req.Data["data"] = map[string]interface{}{"bar": "baz"}
+		if cas != nil {
+			req.Data["options"] = map[string]interface{}{"cas": cas}
+		}
+		return b.HandleRequest(req)
+	}
+
+	// A cas of 0 only succeeds if the key does not exist
+	if _, err := write(0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp, err := write(0); err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected cas failure, got %#v, %v", resp, err)
+	}
+	if _, err := write(1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp, err := write(1); err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected cas failure, got %#v, %v", resp, err)
+	}
+	if resp, err := write(-1); err != nil || !resp.IsError() {
+		t.Fatalf("expected negative cas to be rejected, got %#v, %v", resp, err)
+	}
+
+	// Requiring cas rejects writes without it
+	testKVRequest(t, b, storage, logical.UpdateOperation, "config", map[string]interface{}{
+		"cas_required": true,
+	})
+	if resp, err := write(nil); err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected cas failure, got %#v, %v", resp, err)
+	}
+	if _, err := write(2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestKVBackend_DeleteUndeleteDestroy(t *testing.T) {
+	b, storage := testKVBackend(t)
+
+	for i := 1; i <= 2; i++ {
+		testKVRequest(t, b, storage, logical.UpdateOperation, "data/foo", map[string]interface{}{
+			"data": map[string]interface{}{
+				"value": i,
+			},
+		})
+	}
+
+	// Soft delete the latest version
+	testKVRequest(t, b, storage, logical.DeleteOperation, "data/foo", nil)
+	resp := testKVRequest(t, b, storage, logical.ReadOperation, "data/foo", nil)
+	if resp.Data["data"] != nil {
+		t.Fatalf("expected no data, got %#v", resp.Data)
+	}
+	if resp.Data["metadata"].(map[string]interface{})["deletion_time"] == "" {
+		t.Fatalf("expected deletion time, got %#v", resp.Data)
+	}
+
+	// Older versions are still readable
+	resp = testKVRequest(t, b, storage, logical.ReadOperation, "data/foo", map[string]interface{}{
+		"version": 1,
+	})
+	if resp.Data["data"] == nil {
+		t.Fatalf("expected data, got %#v", resp.Data)
+	}
+
+	testKVRequest(t, b, storage, logical.UpdateOperation, "undelete/foo", map[string]interface{}{
+		"versions": "2",
+	})
+	resp = testKVRequest(t, b, storage, logical.ReadOperation, "data/foo", nil)
+	if resp.Data["data"] == nil {
+		t.Fatalf("expected data, got %#v", resp.Data)
+	}
+
+	testKVRequest(t, b, storage, logical.UpdateOperation, "destroy/foo", map[string]interface{}{
+		"versions": []interface{}{1, 2},
+	})
+	resp = testKVRequest(t, b, storage, logical.ReadOperation, "data/foo", nil)
+	if resp.Data["data"] != nil || resp.Data["metadata"].(map[string]interface{})["destroyed"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if out, _ := storage.Get(kvVersionKey("foo", 1)); out != nil {
+		t.Fatalf("expected version data to be removed")
+	}
+
+	// Destroyed versions cannot be restored
+	testKVRequest(t, b, storage, logical.UpdateOperation, "undelete/foo", map[string]interface{}{
+		"versions": "2",
+	})
+	resp = testKVRequest(t, b, storage, logical.ReadOperation, "data/foo", nil)
+	if resp.Data["data"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	testKVRequest(t, b, storage, logical.DeleteOperation, "metadata/foo", nil)
+	resp = testKVRequest(t, b, storage, logical.ReadOperation, "metadata/foo", nil)
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestKVBackend_MaxVersions(t *testing.T) {
+	b, storage := testKVBackend(t)
+
+	testKVRequest(t, b, storage, logical.UpdateOperation, "metadata/foo", map[string]interface{}{
+		"max_versions": 2,
+	})
+	for i := 1; i <= 4; i++ {
+		testKVRequest(t, b, storage, logical.UpdateOperation, "data/foo", map[string]interface{}{
+			"data": map[string]interface{}{
+				"value": i,
+			},
+		})
+	}
+
+	resp := testKVRequest(t, b, storage, logical.ReadOperation, "metadata/foo", nil)
+	if resp.Data["current_version"] != uint64(4) || resp.Data["oldest_version"] != uint64(3) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if len(resp.Data["versions"].(map[string]interface{})) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if out, _ := storage.Get(kvVersionKey("foo", 2)); out != nil {
+		t.Fatalf("expected pruned version to be removed")
+	}
+}
+
+func TestKVBackend_Upgrade(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	passthrough := testPassthroughBackend()
+	for _, key := range []string{"foo", "bar/baz"} {
+		req := logical.TestRequest(t, logical.UpdateOperation, key)
+		req.Storage = storage
+		req.Data["value"] = key
+		if _, err := passthrough.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	b, err := KVBackendFactory(&logical.BackendConfig{
+		StorageView: storage,
+		System:      logical.TestSystemView(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"foo", "bar/baz"} {
+		resp := testKVRequest(t, b, storage, logical.ReadOperation, "data/"+key, nil)
+		if resp.Data["data"].(map[string]interface{})["value"] != key {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+		if out, _ := storage.Get(key); out != nil {
+			t.Fatalf("expected passthrough entry %q to be removed", key)
+		}
+	}
+
+	// A second initialization must not migrate anything again
+	if err := b.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	resp := testKVRequest(t, b, storage, logical.ReadOperation, "metadata/foo", nil)
+	if resp.Data["current_version"] != uint64(1) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestKVBackend_UpgradeResume(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	passthrough := testPassthroughBackend()
+	for _, key := range []string{"foo", "bar"} {
+		req := logical.TestRequest(t, logical.UpdateOperation, key)
+		req.Storage = storage
+		req.Data["value"] = key
+		if _, err := passthrough.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	b, err := KVBackendFactory(&logical.BackendConfig{
+		StorageView: storage,
+		System:      logical.TestSystemView(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An upgrade interrupted after copying the data leaves the passthrough
+	// entries in place
+	if _, err := b.(*KVBackend).migratePassthrough(); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := storage.Get("foo"); out == nil {
+		t.Fatal("expected passthrough entry to be kept")
+	}
+
+	// Resuming must not add another version of the copied keys
+	if err := b.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"foo", "bar"} {
+		resp := testKVRequest(t, b, storage, logical.ReadOperation, "metadata/"+key, nil)
+		if resp.Data["current_version"] != uint64(1) {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+		if out, _ := storage.Get(key); out != nil {
+			t.Fatalf("expected passthrough entry %q to be removed", key)
+		}
+	}
+}
+
+func TestKVBackend_UpgradeReservedKey(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	entry, _ := logical.StorageEntryJSON("metadata/foo", map[string]interface{}{"a": "b"})
+	storage.Put(entry)
+
+	b, err := KVBackendFactory(&logical.BackendConfig{
+		StorageView: storage,
+		System:      logical.TestSystemView(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Initialize(); err == nil {
+		t.Fatal("expected error upgrading a reserved key")
+	}
+}
+
+func testKVRequest(t *testing.T, b logical.Backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	req := logical.TestRequest(t, op, path)
+	req.Storage = s
+	req.Data = data
+	resp, err := b.HandleRequest(req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	return resp
+}
+
+func testKVBackend(t *testing.T) (logical.Backend, logical.Storage) {
+	storage := &logical.InmemStorage{}
+	b, err := KVBackendFactory(&logical.BackendConfig{
+		StorageView: storage,
+		System: logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour * 24,
+			MaxLeaseTTLVal:     time.Hour * 24 * 32,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return b, storage
+}
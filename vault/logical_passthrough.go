@@ -31,6 +31,10 @@ func LeaseSwitchedPassthroughBackend(conf *logical.BackendConfig, leases bool) (
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(passthroughHelp),
 
+		PathsSpecial: &logical.Paths{
+			StandbyRead: []string{"*"},
+		},
+
 		Paths: []*framework.Path{
 			&framework.Path{
 				Pattern: ".*",
@@ -11,9 +11,12 @@ import (
 func TestPassthroughBackend_RootPaths(t *testing.T) {
 	b := testPassthroughBackend()
 	test := func(b logical.Backend) {
-		root := b.SpecialPaths()
-		if root != nil {
-			t.Fatalf("unexpected: %v", root)
+		paths := b.SpecialPaths()
+		if len(paths.Root) != 0 || len(paths.Unauthenticated) != 0 {
+			t.Fatalf("unexpected: %v", paths)
+		}
+		if !reflect.DeepEqual(paths.StandbyRead, []string{"*"}) {
+			t.Fatalf("unexpected: %v", paths)
 		}
 	}
 	test(b)
@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// pluginCatalogPaths returns the paths used to manage the catalog of
+// external plugins
+func pluginCatalogPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "plugins/catalog/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handlePluginCatalogList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "plugins/catalog/(?P<name>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["plugin-catalog_name"][0]),
+				},
+				"sha256": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["plugin-catalog_sha-256"][0]),
+				},
+				"command": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["plugin-catalog_command"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handlePluginCatalogUpdate,
+				logical.DeleteOperation: b.handlePluginCatalogDelete,
+				logical.ReadOperation:   b.handlePluginCatalogRead,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["plugin-catalog"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["plugin-catalog"][1]),
+		},
+	}
+}
+
+// handlePluginCatalogList returns the names of the registered plugins
+func (b *SystemBackend) handlePluginCatalogList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	plugins, err := b.Core.pluginCatalog.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(plugins), nil
+}
+
+// handlePluginCatalogUpdate registers or updates a plugin in the catalog
+func (b *SystemBackend) handlePluginCatalogUpdate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pluginName := data.Get("name").(string)
+	if pluginName == "" {
+		return logical.ErrorResponse("missing plugin name"), logical.ErrInvalidRequest
+	}
+
+	sha256 := data.Get("sha256").(string)
+	if sha256 == "" {
+		return logical.ErrorResponse("missing SHA-256 value"), logical.ErrInvalidRequest
+	}
+
+	command := data.Get("command").(string)
+	if command == "" {
+		return logical.ErrorResponse("missing command value"), logical.ErrInvalidRequest
+	}
+
+	sha256Bytes, err := hex.DecodeString(sha256)
+	if err != nil {
+		return logical.ErrorResponse("Could not decode SHA-256 value from Hex"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.pluginCatalog.Set(pluginName, command, sha256Bytes); err != nil {
+		b.Backend.Logger().Error("sys: failed to register plugin", "name", pluginName, "error", err)
+		return handleError(err)
+	}
+
+	return nil, nil
+}
+
+// handlePluginCatalogRead returns the registration of a plugin
+func (b *SystemBackend) handlePluginCatalogRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pluginName := data.Get("name").(string)
+	if pluginName == "" {
+		return logical.ErrorResponse("missing plugin name"), logical.ErrInvalidRequest
+	}
+
+	plugin, err := b.Core.pluginCatalog.Get(pluginName)
+	if err != nil {
+		return nil, err
+	}
+	if plugin == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":    plugin.Name,
+			"command": plugin.Command,
+			"args":    plugin.Args,
+			"sha256":  hex.EncodeToString(plugin.Sha256),
+		},
+	}, nil
+}
+
+// handlePluginCatalogDelete removes a plugin from the catalog
+func (b *SystemBackend) handlePluginCatalogDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	pluginName := data.Get("name").(string)
+	if pluginName == "" {
+		return logical.ErrorResponse("missing plugin name"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.pluginCatalog.Delete(pluginName); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestSystemBackend_pluginCatalog(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+
+	dir, err := ioutil.TempDir("", "vault-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "test-plugin"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	c.pluginCatalog.directory = dir
+
+	// The SHA256 is required
+	req := logical.TestRequest(t, logical.UpdateOperation, "plugins/catalog/test-plugin")
+	req.Data["command"] = "test-plugin"
+	resp, err := b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected error, got %#v, %v", resp, err)
+	}
+
+	req.Data["sha256"] = "not-hex"
+	resp, err = b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected error, got %#v, %v", resp, err)
+	}
+
+	sha256 := hex.EncodeToString([]byte("12345"))
+	req.Data["sha256"] = sha256
+	req.Data["command"] = "test-plugin --flag"
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "plugins/catalog/test-plugin")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"name":    "test-plugin",
+		"command": filepath.Join(dir, "test-plugin"),
+		"args":    []string{"--flag"},
+		"sha256":  sha256,
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.ListOperation, "plugins/catalog/")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"test-plugin"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "plugins/catalog/test-plugin")
+	if _, err := b.HandleRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "plugins/catalog/test-plugin")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestSystemBackend_mountPluginRequiresName(t *testing.T) {
+	b := testSystemBackend(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "mounts/plugin")
+	req.Data["type"] = "plugin"
+	resp, err := b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected error, got %#v, %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/plugin")
+	req.Data["type"] = "plugin"
+	resp, err = b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected error, got %#v, %v", resp, err)
+	}
+}
@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// pluginUnavailableBackend is routed in place of the backend of a plugin
+// mount whose plugin failed to start when the mount table was loaded. Vault
+// can still unseal, and requests to the mount fail with the reason instead of
+// falling through as if nothing was mounted there.
+type pluginUnavailableBackend struct {
+	pluginName string
+	err        error
+	system     logical.SystemView
+}
+
+func newPluginUnavailableBackend(entry *MountEntry, sysView logical.SystemView, err error) *pluginUnavailableBackend {
+	return &pluginUnavailableBackend{
+		pluginName: entry.Config.PluginName,
+		err:        err,
+		system:     sysView,
+	}
+}
+
+func (b *pluginUnavailableBackend) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	// Periodic rollbacks are not worth reporting every minute
+	if req.Operation == logical.RollbackOperation {
+		return nil, logical.ErrUnsupportedOperation
+	}
+	return nil, logical.CodedError(503, fmt.Sprintf("plugin %q is unavailable: %v", b.pluginName, b.err))
+}
+
+func (b *pluginUnavailableBackend) SpecialPaths() *logical.Paths {
+	// Let logins reach the backend so that they get the error too
+	return &logical.Paths{
+		Unauthenticated: []string{"*"},
+	}
+}
+
+func (b *pluginUnavailableBackend) System() logical.SystemView {
+	return b.system
+}
+
+func (b *pluginUnavailableBackend) HandleExistenceCheck(req *logical.Request) (bool, bool, error) {
+	return false, false, nil
+}
+
+func (b *pluginUnavailableBackend) Cleanup() {}
+
+func (b *pluginUnavailableBackend) Initialize() error {
+	return nil
+}
+
+func (b *pluginUnavailableBackend) InvalidateKey(key string) {}
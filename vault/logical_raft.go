@@ -0,0 +1,226 @@
+package vault
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+	"github.com/hashicorp/vault/physical"
+)
+
+// raftStoragePaths returns the paths used to manage the integrated raft
+// storage backend
+func raftStoragePaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "storage/raft/join$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"node_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The raft ID of the node joining the cluster.",
+				},
+				"address": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The raft address of the node joining the cluster.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRaftJoin,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-join"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-join"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/remove-peer$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"node_id": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The raft ID of the node to remove from the cluster.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRaftRemovePeer,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-remove-peer"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-remove-peer"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/configuration$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleRaftConfiguration,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-configuration"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-configuration"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "storage/raft/snapshot$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"snapshot": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The base64-encoded snapshot to restore.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   b.handleRaftSnapshotRead,
+				logical.UpdateOperation: b.handleRaftSnapshotRestore,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["raft-snapshot"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["raft-snapshot"][1]),
+		},
+	}
+}
+
+// raftBackend returns the raft storage backend, or an error response if
+// Vault is not using raft for storage
+func (b *SystemBackend) raftBackend() (*physical.RaftBackend, *logical.Response) {
+	raftBackend, ok := b.Core.underlyingPhysical.(*physical.RaftBackend)
+	if !ok {
+		return nil, logical.ErrorResponse("raft is not the configured storage backend")
+	}
+	return raftBackend, nil
+}
+
+// handleRaftJoin is used to add a new node to the raft cluster
+func (b *SystemBackend) handleRaftJoin(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	raftBackend, resp := b.raftBackend()
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	nodeID := data.Get("node_id").(string)
+	if nodeID == "" {
+		return logical.ErrorResponse("node_id must be specified"), logical.ErrInvalidRequest
+	}
+	address := data.Get("address").(string)
+	if address == "" {
+		return logical.ErrorResponse("address must be specified"), logical.ErrInvalidRequest
+	}
+
+	if err := raftBackend.AddPeer(nodeID, address); err != nil {
+		b.Backend.Logger().Error("sys: failed to add raft peer", "node_id", nodeID, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleRaftRemovePeer is used to remove a node from the raft cluster
+func (b *SystemBackend) handleRaftRemovePeer(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	raftBackend, resp := b.raftBackend()
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	nodeID := data.Get("node_id").(string)
+	if nodeID == "" {
+		return logical.ErrorResponse("node_id must be specified"), logical.ErrInvalidRequest
+	}
+
+	if err := raftBackend.RemovePeer(nodeID); err != nil {
+		b.Backend.Logger().Error("sys: failed to remove raft peer", "node_id", nodeID, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// handleRaftConfiguration returns the members of the raft cluster
+func (b *SystemBackend) handleRaftConfiguration(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	raftBackend, resp := b.raftBackend()
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	peers, err := raftBackend.Peers()
+	if err != nil {
+		return handleError(err)
+	}
+
+	servers := make([]map[string]interface{}, 0, len(peers))
+	for _, peer := range peers {
+		servers = append(servers, map[string]interface{}{
+			"node_id": peer.ID,
+			"address": peer.Address,
+			"leader":  peer.Leader,
+			"voter":   peer.Voter,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"node_id": raftBackend.NodeID(),
+			"servers": servers,
+		},
+	}, nil
+}
+
+// handleRaftSnapshotRead returns a snapshot of the raft storage
+func (b *SystemBackend) handleRaftSnapshotRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	raftBackend, resp := b.raftBackend()
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	snapshot, err := raftBackend.Snapshot()
+	if err != nil {
+		b.Backend.Logger().Error("sys: failed to take raft snapshot", "error", err)
+		return handleError(err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"snapshot": base64.StdEncoding.EncodeToString(snapshot),
+		},
+	}, nil
+}
+
+// handleRaftSnapshotRestore replaces the raft storage with the given
+// snapshot, and then gives up active operation so that the core state is
+// loaded again from the restored data
+func (b *SystemBackend) handleRaftSnapshotRestore(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	raftBackend, resp := b.raftBackend()
+	if resp != nil {
+		return resp, logical.ErrInvalidRequest
+	}
+
+	snapshot, err := base64.StdEncoding.DecodeString(data.Get("snapshot").(string))
+	if err != nil {
+		return logical.ErrorResponse("snapshot must be base64 encoded"), logical.ErrInvalidRequest
+	}
+	if len(snapshot) == 0 {
+		return logical.ErrorResponse("snapshot must be specified"), logical.ErrInvalidRequest
+	}
+
+	if err := raftBackend.RestoreSnapshot(snapshot); err != nil {
+		b.Backend.Logger().Error("sys: failed to restore raft snapshot", "error", err)
+		return handleError(err)
+	}
+
+	// The cached values no longer reflect what is stored
+	if purgable, ok := b.Core.physical.(physical.Purgable); ok {
+		purgable.Purge()
+	}
+	b.Backend.Logger().Info("sys: restored raft snapshot")
+
+	b.Core.reloadFromStorage()
+
+	return nil, nil
+}
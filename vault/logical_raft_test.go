@@ -0,0 +1,131 @@
+package vault
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+func TestSystemBackend_raftNotConfigured(t *testing.T) {
+	b := testSystemBackend(t)
+
+	for _, path := range []string{"storage/raft/configuration", "storage/raft/snapshot"} {
+		req := logical.TestRequest(t, logical.ReadOperation, path)
+		resp, err := b.HandleRequest(req)
+		if err != logical.ErrInvalidRequest || !resp.IsError() {
+			t.Fatalf("%s: expected error, got %#v, %v", path, resp, err)
+		}
+	}
+}
+
+func TestSystemBackend_raftStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-raft")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm, err := physical.NewBackend("raft", logger, map[string]string{
+		"path":      dir,
+		"node_id":   "node1",
+		"address":   "127.0.0.1:0",
+		"bootstrap": "true",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	raftBackend := inm.(*physical.RaftBackend)
+
+	// Wait for the single node cluster to elect itself
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		peers, err := raftBackend.Peers()
+		if err == nil && len(peers) == 1 && peers[0].Leader {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("raft did not elect a leader: %#v, %v", peers, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	c, keys, root := TestCoreUnsealedBackend(t, raftBackend)
+	b, err := NewSystemBackend(c, &logical.BackendConfig{
+		Logger: c.logger,
+		System: logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour * 24,
+			MaxLeaseTTLVal:     time.Hour * 24 * 32,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := logical.TestRequest(t, logical.ReadOperation, "storage/raft/configuration")
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["node_id"] != "node1" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	servers := resp.Data["servers"].([]map[string]interface{})
+	if len(servers) != 1 || servers[0]["node_id"] != "node1" || servers[0]["leader"] != true {
+		t.Fatalf("bad: %#v", servers)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "storage/raft/join")
+	resp, err = b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest || !resp.IsError() {
+		t.Fatalf("expected error, got %#v, %v", resp, err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "storage/raft/snapshot")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	snapshot := resp.Data["snapshot"].(string)
+	if snapshot == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "storage/raft/snapshot")
+	req.Data["snapshot"] = snapshot
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Without HA the core seals, to be unsealed on the restored data
+	deadline = time.Now().Add(10 * time.Second)
+	for {
+		if sealed, _ := c.Sealed(); sealed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("core did not seal after the restore")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "sys/key-status")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
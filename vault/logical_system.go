@@ -63,12 +63,23 @@ func NewSystemBackend(core *Core, config *logical.BackendConfig) (logical.Backen
 				"replication/reindex",
 				"rotate",
 				"config/auditing/*",
+				"leases/lookup/*",
+				"storage/raft/*",
+				"plugins/catalog/*",
 			},
 
 			Unauthenticated: []string{
 				"wrapping/pubkey",
 				"replication/status",
 			},
+
+			StandbyRead: []string{
+				"capabilities",
+				"capabilities-self",
+				"metrics",
+				"policy",
+				"policy/*",
+			},
 		},
 
 		Paths: []*framework.Path{
@@ -218,6 +229,10 @@ func NewSystemBackend(core *Core, config *logical.BackendConfig) (logical.Backen
 						Type:        framework.TypeString,
 						Description: strings.TrimSpace(sysHelp["tune_max_lease_ttl"][0]),
 					},
+					"versioned": &framework.FieldSchema{
+						Type:        framework.TypeBool,
+						Description: strings.TrimSpace(sysHelp["tune_versioned"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -378,6 +393,42 @@ func NewSystemBackend(core *Core, config *logical.BackendConfig) (logical.Backen
 				HelpDescription: strings.TrimSpace(sysHelp["revoke-prefix"][1]),
 			},
 
+			&framework.Path{
+				Pattern: "leases/lookup/(?P<prefix>.+?)?",
+
+				Fields: map[string]*framework.FieldSchema{
+					"prefix": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["leases-list-prefix"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.ListOperation: b.handleLeaseLookupList,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases"][1]),
+			},
+
+			&framework.Path{
+				Pattern: "leases/lookup",
+
+				Fields: map[string]*framework.FieldSchema{
+					"lease_id": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["lease_id"][0]),
+					},
+				},
+
+				Callbacks: map[logical.Operation]framework.OperationFunc{
+					logical.UpdateOperation: b.handleLeaseLookup,
+				},
+
+				HelpSynopsis:    strings.TrimSpace(sysHelp["leases"][0]),
+				HelpDescription: strings.TrimSpace(sysHelp["leases"][1]),
+			},
+
 			&framework.Path{
 				Pattern: "auth$",
 
@@ -410,6 +461,10 @@ func NewSystemBackend(core *Core, config *logical.BackendConfig) (logical.Backen
 						Default:     false,
 						Description: strings.TrimSpace(sysHelp["mount_local"][0]),
 					},
+					"plugin_name": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Description: strings.TrimSpace(sysHelp["auth_plugin"][0]),
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -696,6 +751,11 @@ func NewSystemBackend(core *Core, config *logical.BackendConfig) (logical.Backen
 	}
 
 	b.Backend.Paths = append(b.Backend.Paths, replicationPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, raftStoragePaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, pluginCatalogPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, namespacePaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, metricsPaths(b)...)
+	b.Backend.Paths = append(b.Backend.Paths, controlGroupPaths(b)...)
 
 	b.Backend.Invalidate = b.invalidate
 
@@ -794,7 +854,8 @@ func (b *SystemBackend) handleCapabilities(req *logical.Request, d *framework.Fi
 	if token == "" {
 		token = req.ClientToken
 	}
-	capabilities, err := b.Core.Capabilities(token, d.Get("path").(string))
+	ns := b.Core.namespaceByPath(req.MountPoint)
+	capabilities, err := b.Core.Capabilities(token, ns.Path+d.Get("path").(string))
 	if err != nil {
 		return nil, err
 	}
@@ -910,6 +971,8 @@ func (b *SystemBackend) handleRekeyDeleteRecovery(
 // handleMountTable handles the "mounts" endpoint to provide the mount table
 func (b *SystemBackend) handleMountTable(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := b.Core.namespaceByPath(req.MountPoint)
+
 	b.Core.mountsLock.RLock()
 	defer b.Core.mountsLock.RUnlock()
 
@@ -918,6 +981,9 @@ func (b *SystemBackend) handleMountTable(
 	}
 
 	for _, entry := range b.Core.mounts.Entries {
+		if entry.NamespaceID != ns.entryID() {
+			continue
+		}
 		info := map[string]interface{}{
 			"type":        entry.Type,
 			"description": entry.Description,
@@ -928,8 +994,11 @@ func (b *SystemBackend) handleMountTable(
 			},
 			"local": entry.Local,
 		}
+		if entry.Config.PluginName != "" {
+			info["config"].(map[string]interface{})["plugin_name"] = entry.Config.PluginName
+		}
 
-		resp.Data[entry.Path] = info
+		resp.Data[strings.TrimPrefix(entry.Path, ns.Path)] = info
 	}
 
 	return resp, nil
@@ -953,6 +1022,10 @@ func (b *SystemBackend) handleMount(
 	description := data.Get("description").(string)
 
 	path = sanitizeMountPath(path)
+	ns, path, err := b.namespacePath(req, path)
+	if err != nil {
+		return handleError(err)
+	}
 
 	var config MountConfig
 
@@ -960,6 +1033,7 @@ func (b *SystemBackend) handleMount(
 		DefaultLeaseTTL string `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"`
 		MaxLeaseTTL     string `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`
 		ForceNoCache    bool   `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
+		PluginName      string `json:"plugin_name" structs:"plugin_name" mapstructure:"plugin_name"`
 	}
 	configMap := data.Get("config").(map[string]interface{})
 	if configMap != nil && len(configMap) != 0 {
@@ -1020,6 +1094,15 @@ func (b *SystemBackend) handleMount(
 			logical.ErrInvalidRequest
 	}
 
+	if logicalType == "plugin" {
+		if apiConfig.PluginName == "" {
+			return logical.ErrorResponse(
+					"plugin_name must be provided in the config for plugin backends"),
+				logical.ErrInvalidRequest
+		}
+		config.PluginName = apiConfig.PluginName
+	}
+
 	// Create the mount entry
 	me := &MountEntry{
 		Table:       mountTableType,
@@ -1028,6 +1111,7 @@ func (b *SystemBackend) handleMount(
 		Description: description,
 		Config:      config,
 		Local:       local,
+		NamespaceID: ns.entryID(),
 	}
 
 	// Attempt mount
@@ -1039,6 +1123,27 @@ func (b *SystemBackend) handleMount(
 	return nil, nil
 }
 
+// namespacePath returns the full path of a mount path given relative to the
+// namespace of the request, along with that namespace. Mounts of child
+// namespaces are managed through the child namespaces themselves.
+func (b *SystemBackend) namespacePath(req *logical.Request, path string) (*Namespace, string, error) {
+	if path == "/" {
+		return nil, "", logical.CodedError(400, "path cannot be blank")
+	}
+
+	ns := b.Core.namespaceByPath(req.MountPoint)
+	fullPath := ns.Path + path
+	if b.Core.namespaceConflict(ns, fullPath) != nil {
+		return nil, "", logical.CodedError(400, fmt.Sprintf("path '%s' is within a child namespace", path))
+	}
+	return ns, fullPath, nil
+}
+
+// policyStore returns the policy store of the namespace of the request
+func (b *SystemBackend) policyStore(req *logical.Request) *PolicyStore {
+	return b.Core.namespacePolicyStore(b.Core.namespaceByPath(req.MountPoint))
+}
+
 // used to intercept an HTTPCodedError so it goes back to callee
 func handleError(
 	err error) (*logical.Response, error) {
@@ -1063,6 +1168,10 @@ func (b *SystemBackend) handleUnmount(
 	}
 
 	suffix = sanitizeMountPath(suffix)
+	_, suffix, err := b.namespacePath(req, suffix)
+	if err != nil {
+		return handleError(err)
+	}
 
 	entry := b.Core.router.MatchingMountEntry(suffix)
 	if entry != nil && !entry.Local && repState == consts.ReplicationSecondary {
@@ -1097,6 +1206,15 @@ func (b *SystemBackend) handleRemount(
 	fromPath = sanitizeMountPath(fromPath)
 	toPath = sanitizeMountPath(toPath)
 
+	_, fromPath, err := b.namespacePath(req, fromPath)
+	if err != nil {
+		return handleError(err)
+	}
+	_, toPath, err = b.namespacePath(req, toPath)
+	if err != nil {
+		return handleError(err)
+	}
+
 	entry := b.Core.router.MatchingMountEntry(fromPath)
 	if entry != nil && !entry.Local && repState == consts.ReplicationSecondary {
 		return logical.ErrorResponse("cannot remount a non-local mount on a replication secondary"), nil
@@ -1120,7 +1238,7 @@ func (b *SystemBackend) handleAuthTuneRead(
 				"path must be specified as a string"),
 			logical.ErrInvalidRequest
 	}
-	return b.handleTuneReadCommon("auth/" + path)
+	return b.handleTuneReadCommon(req, "auth/"+path)
 }
 
 // handleMountTuneRead is used to get config settings on a backend
@@ -1136,12 +1254,17 @@ func (b *SystemBackend) handleMountTuneRead(
 	// This call will read both logical backend's configuration as well as auth backends'.
 	// Retaining this behavior for backward compatibility. If this behavior is not desired,
 	// an error can be returned if path has a prefix of "auth/".
-	return b.handleTuneReadCommon(path)
+	return b.handleTuneReadCommon(req, path)
 }
 
 // handleTuneReadCommon returns the config settings of a path
-func (b *SystemBackend) handleTuneReadCommon(path string) (*logical.Response, error) {
+func (b *SystemBackend) handleTuneReadCommon(
+	req *logical.Request, path string) (*logical.Response, error) {
 	path = sanitizeMountPath(path)
+	_, path, err := b.namespacePath(req, path)
+	if err != nil {
+		return handleError(err)
+	}
 
 	sysView := b.Core.router.MatchingSystemView(path)
 	if sysView == nil {
@@ -1174,7 +1297,7 @@ func (b *SystemBackend) handleAuthTuneWrite(
 		return logical.ErrorResponse("path must be specified as a string"),
 			logical.ErrInvalidRequest
 	}
-	return b.handleTuneWriteCommon("auth/"+path, data)
+	return b.handleTuneWriteCommon(req, "auth/"+path, data)
 }
 
 // handleMountTuneWrite is used to set config settings on a backend
@@ -1188,12 +1311,12 @@ func (b *SystemBackend) handleMountTuneWrite(
 	// This call will write both logical backend's configuration as well as auth backends'.
 	// Retaining this behavior for backward compatibility. If this behavior is not desired,
 	// an error can be returned if path has a prefix of "auth/".
-	return b.handleTuneWriteCommon(path, data)
+	return b.handleTuneWriteCommon(req, path, data)
 }
 
 // handleTuneWriteCommon is used to set config settings on a path
 func (b *SystemBackend) handleTuneWriteCommon(
-	path string, data *framework.FieldData) (*logical.Response, error) {
+	req *logical.Request, path string, data *framework.FieldData) (*logical.Response, error) {
 	b.Core.clusterParamsLock.RLock()
 	repState := b.Core.replicationState
 	b.Core.clusterParamsLock.RUnlock()
@@ -1208,6 +1331,12 @@ func (b *SystemBackend) handleTuneWriteCommon(
 		}
 	}
 
+	isAuth := strings.HasPrefix(path, "auth/")
+	_, path, err := b.namespacePath(req, path)
+	if err != nil {
+		return handleError(err)
+	}
+
 	mountEntry := b.Core.router.MatchingMountEntry(path)
 	if mountEntry == nil {
 		b.Backend.Logger().Error("sys: tune failed: no mount entry found", "path", path)
@@ -1219,12 +1348,27 @@ func (b *SystemBackend) handleTuneWriteCommon(
 
 	var lock *sync.RWMutex
 	switch {
-	case strings.HasPrefix(path, "auth/"):
+	case isAuth:
 		lock = &b.Core.authLock
 	default:
 		lock = &b.Core.mountsLock
 	}
 
+	// Upgrading a generic mount to a versioned kv mount
+	if versionedRaw, ok := data.GetOk("versioned"); ok && versionedRaw.(bool) {
+		if isAuth {
+			return handleError(fmt.Errorf("sys: cannot upgrade auth mount '%s'", path))
+		}
+
+		lock.Lock()
+		err := b.Core.upgradeMountToKV(path)
+		lock.Unlock()
+		if err != nil {
+			b.Backend.Logger().Error("sys: upgrading mount failed", "path", path, "error", err)
+			return handleError(err)
+		}
+	}
+
 	// Timing configuration parameters
 	{
 		var newDefault, newMax *time.Duration
@@ -1270,6 +1414,65 @@ func (b *SystemBackend) handleTuneWriteCommon(
 	return nil, nil
 }
 
+// handleLeaseLookup is used to view the metadata of a given LeaseID
+func (b *SystemBackend) handleLeaseLookup(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	leaseID := data.Get("lease_id").(string)
+	if leaseID == "" {
+		return logical.ErrorResponse("lease_id must be specified"),
+			logical.ErrInvalidRequest
+	}
+
+	leaseTimes, err := b.Core.expiration.FetchLeaseTimes(leaseID)
+	if err != nil {
+		b.Backend.Logger().Error("sys: error retrieving lease", "lease_id", leaseID, "error", err)
+		return handleError(err)
+	}
+	if leaseTimes == nil {
+		return logical.ErrorResponse("invalid lease"), logical.ErrInvalidRequest
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"id":           leaseID,
+			"issue_time":   leaseTimes.IssueTime,
+			"expire_time":  nil,
+			"last_renewal": nil,
+			"renewable":    leaseTimes.renewable() == nil,
+			"ttl":          int64(0),
+		},
+	}
+	if !leaseTimes.LastRenewalTime.IsZero() {
+		resp.Data["last_renewal"] = leaseTimes.LastRenewalTime
+	}
+	if !leaseTimes.ExpireTime.IsZero() {
+		resp.Data["expire_time"] = leaseTimes.ExpireTime
+
+		ttl := leaseTimes.ExpireTime.Sub(time.Now())
+		if ttl < 0 {
+			ttl = 0
+		}
+		resp.Data["ttl"] = int64(ttl.Seconds())
+	}
+	return resp, nil
+}
+
+// handleLeaseLookupList is used to list the leases under a given prefix
+func (b *SystemBackend) handleLeaseLookupList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	prefix := data.Get("prefix").(string)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix = prefix + "/"
+	}
+
+	keys, err := b.Core.expiration.idView.List(prefix)
+	if err != nil {
+		b.Backend.Logger().Error("sys: error listing leases", "prefix", prefix, "error", err)
+		return handleError(err)
+	}
+	return logical.ListResponse(keys), nil
+}
+
 // handleRenew is used to renew a lease with a given LeaseID
 func (b *SystemBackend) handleRenew(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -1341,6 +1544,8 @@ func (b *SystemBackend) handleRevokePrefixCommon(
 // handleAuthTable handles the "auth" endpoint to provide the auth table
 func (b *SystemBackend) handleAuthTable(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := b.Core.namespaceByPath(req.MountPoint)
+
 	b.Core.authLock.RLock()
 	defer b.Core.authLock.RUnlock()
 
@@ -1348,16 +1553,23 @@ func (b *SystemBackend) handleAuthTable(
 		Data: make(map[string]interface{}),
 	}
 	for _, entry := range b.Core.auth.Entries {
+		if entry.NamespaceID != ns.entryID() {
+			continue
+		}
 		info := map[string]interface{}{
 			"type":        entry.Type,
 			"description": entry.Description,
+			"accessor":    entry.Accessor,
 			"config": map[string]interface{}{
 				"default_lease_ttl": int64(entry.Config.DefaultLeaseTTL.Seconds()),
 				"max_lease_ttl":     int64(entry.Config.MaxLeaseTTL.Seconds()),
 			},
 			"local": entry.Local,
 		}
-		resp.Data[entry.Path] = info
+		if entry.Config.PluginName != "" {
+			info["config"].(map[string]interface{})["plugin_name"] = entry.Config.PluginName
+		}
+		resp.Data[strings.TrimPrefix(entry.Path, ns.Path)] = info
 	}
 	return resp, nil
 }
@@ -1385,7 +1597,21 @@ func (b *SystemBackend) handleEnableAuth(
 			logical.ErrInvalidRequest
 	}
 
+	var config MountConfig
+	if logicalType == "plugin" {
+		config.PluginName = data.Get("plugin_name").(string)
+		if config.PluginName == "" {
+			return logical.ErrorResponse(
+					"plugin_name must be provided for plugin backends"),
+				logical.ErrInvalidRequest
+		}
+	}
+
 	path = sanitizeMountPath(path)
+	ns, path, err := b.namespacePath(req, path)
+	if err != nil {
+		return handleError(err)
+	}
 
 	// Create the mount entry
 	me := &MountEntry{
@@ -1393,7 +1619,9 @@ func (b *SystemBackend) handleEnableAuth(
 		Path:        path,
 		Type:        logicalType,
 		Description: description,
+		Config:      config,
 		Local:       local,
+		NamespaceID: ns.entryID(),
 	}
 
 	// Attempt enabling
@@ -1413,6 +1641,10 @@ func (b *SystemBackend) handleDisableAuth(
 	}
 
 	suffix = sanitizeMountPath(suffix)
+	_, suffix, err := b.namespacePath(req, suffix)
+	if err != nil {
+		return handleError(err)
+	}
 
 	// Attempt disable
 	if existed, err := b.Core.disableCredential(suffix); existed && err != nil {
@@ -1426,7 +1658,7 @@ func (b *SystemBackend) handleDisableAuth(
 func (b *SystemBackend) handlePolicyList(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	// Get all the configured policies
-	policies, err := b.Core.policyStore.ListPolicies()
+	policies, err := b.policyStore(req).ListPolicies()
 
 	// Add the special "root" policy
 	policies = append(policies, "root")
@@ -1443,7 +1675,7 @@ func (b *SystemBackend) handlePolicyRead(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
 
-	policy, err := b.Core.policyStore.GetPolicy(name)
+	policy, err := b.policyStore(req).GetPolicy(name)
 	if err != nil {
 		return handleError(err)
 	}
@@ -1476,7 +1708,7 @@ func (b *SystemBackend) handlePolicySet(
 	parse.Name = strings.ToLower(name)
 
 	// Update the policy
-	if err := b.Core.policyStore.SetPolicy(parse); err != nil {
+	if err := b.policyStore(req).SetPolicy(parse); err != nil {
 		return handleError(err)
 	}
 	return nil, nil
@@ -1487,7 +1719,7 @@ func (b *SystemBackend) handlePolicyDelete(
 	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
 
-	if err := b.Core.policyStore.DeletePolicy(name); err != nil {
+	if err := b.policyStore(req).DeletePolicy(name); err != nil {
 		return handleError(err)
 	}
 	return nil, nil
@@ -1778,6 +2010,13 @@ func (b *SystemBackend) handleWrappingUnwrap(
 		token = req.ClientToken
 	}
 
+	// Requests held for a control group are replayed instead, once they
+	// have been authorized
+	resp, err := b.Core.unwrapControlGroupRequest(token)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
 		_, err := b.Core.tokenStore.UseTokenByID(token)
@@ -1816,7 +2055,7 @@ func (b *SystemBackend) handleWrappingUnwrap(
 		return nil, fmt.Errorf("could not decode response inside the cubbyhole")
 	}
 
-	resp := &logical.Response{
+	resp = &logical.Response{
 		Data: map[string]interface{}{},
 	}
 	if len(response) == 0 {
@@ -1894,6 +2133,13 @@ func (b *SystemBackend) handleWrappingRewrap(
 		token = req.ClientToken
 	}
 
+	// Requests held for a control group are replayed instead, once they
+	// have been authorized
+	resp, err := b.Core.unwrapControlGroupRequest(token)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
 		_, err := b.Core.tokenStore.UseTokenByID(token)
@@ -2099,8 +2345,8 @@ west coast.
 	},
 
 	"mount_config": {
-		`Configuration for this mount, such as default_lease_ttl
-and max_lease_ttl.`,
+		`Configuration for this mount, such as default_lease_ttl,
+max_lease_ttl and plugin_name.`,
 	},
 
 	"mount_local": {
@@ -2116,6 +2362,10 @@ and is unaffected by replication.`,
 		`The max lease TTL for this mount.`,
 	},
 
+	"tune_versioned": {
+		`If true, upgrades a generic mount to a versioned kv mount, migrating its existing data.`,
+	},
+
 	"remount": {
 		"Move the mount point of an already-mounted backend.",
 		`
@@ -2158,6 +2408,21 @@ lease and to prevent an automatic revocation.
 		"",
 	},
 
+	"leases": {
+		"View lease metadata or list leases by prefix.",
+		`
+		Writing a lease ID to this endpoint returns the issue time, expiration
+		time, last renewal time and renewability of the lease. Listing a
+		prefix under this endpoint returns the lease IDs and sub-prefixes
+		stored under it; listing requires sudo capability.
+		`,
+	},
+
+	"leases-list-prefix": {
+		"The path to list leases under. Example: \"aws/creds/deploy\"",
+		"",
+	},
+
 	"revoke": {
 		"Revoke a leased secret immediately",
 		`
@@ -2244,6 +2509,12 @@ Example: you might have an OAuth backend for GitHub, and one for Google Apps.
 		"",
 	},
 
+	"auth_plugin": {
+		`Name of the plugin in the plugin catalog serving this
+credential backend. Only used with the "plugin" type.`,
+		"",
+	},
+
 	"policy-list": {
 		`List the configured access control policies.`,
 		`
@@ -2345,6 +2616,132 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"raft-join": {
+		"Adds a node to the raft storage cluster.",
+		`
+		Adds the node with the given ID and raft address as a voting member of
+		the raft cluster. This must be called on the active node, after the new
+		node has been started with the raft storage backend.
+		`,
+	},
+
+	"raft-remove-peer": {
+		"Removes a node from the raft storage cluster.",
+		"",
+	},
+
+	"raft-configuration": {
+		"Returns the members of the raft storage cluster.",
+		"",
+	},
+
+	"raft-snapshot": {
+		"Saves or restores a snapshot of the raft storage.",
+		`
+		Reading this path returns a base64-encoded snapshot of all the data in
+		the raft storage. Writing a snapshot previously read from this path
+		replaces the data of the whole cluster with the snapshot contents.
+		`,
+	},
+
+	"plugin-catalog": {
+		"Configures the plugins known to vault",
+		`
+		This path responds to the following HTTP methods.
+		    LIST /
+		        Returns a list of names of configured plugins.
+
+		    GET /<name>
+		        Retrieve the metadata for the named plugin.
+
+		    PUT /<name>
+		        Add or update plugin.
+
+		    DELETE /<name>
+		        Delete the plugin with the given name.
+		`,
+	},
+	"plugin-catalog_name": {
+		"The name of the plugin",
+		"",
+	},
+	"plugin-catalog_sha-256": {
+		`The SHA256 sum of the executable used in the
+command field. This should be HEX encoded.`,
+		"",
+	},
+	"plugin-catalog_command": {
+		`The command used to start the plugin. The
+executable defined in this command must exist in vault's
+plugin directory.`,
+		"",
+	},
+
+	"namespaces": {
+		"Manages the child namespaces of the current namespace",
+		`
+		This path responds to the following HTTP methods.
+		    LIST /
+		        Returns the names of the child namespaces.
+
+		    GET /<name>
+		        Retrieve the ID and full path of the named namespace.
+
+		    PUT /<name>
+		        Create a child namespace with the given name.
+
+		    DELETE /<name>
+		        Delete the named namespace along with its mounts, credential
+		        backends, policies and tokens.
+		`,
+	},
+	"namespaces_name": {
+		"The name of the child namespace",
+		"",
+	},
+
+	"metrics": {
+		"Export the metrics aggregated in memory by this node.",
+		`
+		This path responds to the following HTTP methods.
+		    GET /
+		        Returns the metrics in the format given by the "format" query
+		        parameter: "json" (the default) returns the last complete
+		        aggregation interval, and "prometheus" returns cumulative
+		        metrics in the Prometheus text exposition format.
+		`,
+	},
+	"metrics-format": {
+		`The format of the metrics: "json" or "prometheus".`,
+		"",
+	},
+
+	"control-group-authorize": {
+		"Authorize a request held for a control group.",
+		`
+		This path responds to the following HTTP methods.
+		    POST /
+		        Records the approval of the calling token on each factor of
+		        the control group it satisfies, and returns whether the
+		        request is now fully authorized. The request is handled
+		        when its wrapping token is unwrapped.
+		`,
+	},
+	"control-group-request": {
+		"Look up a request held for a control group.",
+		`
+		This path responds to the following HTTP methods.
+		    POST /
+		        Returns the path and operation of the request, who made it,
+		        and the approvals of each factor of its control group. The
+		        data of the request is not returned.
+		`,
+	},
+	"control-group-accessor": {
+		"The accessor of the wrapping token returned for the held request.",
+		"",
+	},
+
 	"rekey_backup": {
 		"Allows fetching or deleting the backup of the rotated unseal keys.",
 		"",
@@ -0,0 +1,132 @@
+package vault
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// controlGroupPaths returns the paths used to inspect and authorize requests
+// held for a control group
+func controlGroupPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "control-group/authorize$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["control-group-accessor"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleControlGroupAuthorize,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-authorize"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["control-group-authorize"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "control-group/request$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["control-group-accessor"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleControlGroupRequest,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["control-group-request"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["control-group-request"][1]),
+		},
+	}
+}
+
+// handleControlGroupAuthorize records the approval of the calling token on
+// a held request
+func (b *SystemBackend) handleControlGroupAuthorize(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	accessor := data.Get("accessor").(string)
+	if accessor == "" {
+		return logical.ErrorResponse("missing \"accessor\" value in input"), logical.ErrInvalidRequest
+	}
+
+	te, err := b.Core.tokenStore.Lookup(req.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	if te == nil {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	held, err := b.Core.authorizeControlGroupRequest(accessor, te)
+	if err != nil {
+		if badReq, ok := err.(*logical.StatusBadRequest); ok {
+			return logical.ErrorResponse(badReq.Err), logical.ErrInvalidRequest
+		}
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"approved": held.approved(),
+		},
+	}, nil
+}
+
+// handleControlGroupRequest returns the status of a held request. The data
+// of the request is not returned.
+func (b *SystemBackend) handleControlGroupRequest(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	accessor := data.Get("accessor").(string)
+	if accessor == "" {
+		return logical.ErrorResponse("missing \"accessor\" value in input"), logical.ErrInvalidRequest
+	}
+
+	_, held, err := b.Core.controlGroupRequestByAccessor(accessor)
+	if err != nil {
+		if badReq, ok := err.(*logical.StatusBadRequest); ok {
+			return logical.ErrorResponse(badReq.Err), logical.ErrInvalidRequest
+		}
+		return nil, err
+	}
+	if held == nil {
+		return logical.ErrorResponse("no control group request found for the accessor"), logical.ErrInvalidRequest
+	}
+
+	factors := make([]map[string]interface{}, 0, len(held.ControlGroup.Factors))
+	for _, factor := range held.ControlGroup.Factors {
+		approvers := held.Approvals[factor.Name]
+		if approvers == nil {
+			approvers = []string{}
+		}
+		factors = append(factors, map[string]interface{}{
+			"name":               factor.Name,
+			"policies":           factor.Policies,
+			"group_names":        factor.GroupNames,
+			"required_approvals": factor.Approvals,
+			"approvers":          approvers,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"request_path":           held.Path,
+			"operation":              string(held.Operation),
+			"requester_entity_id":    held.RequesterEntityID,
+			"requester_accessor":     held.RequesterAccessor,
+			"requester_display_name": held.RequesterDisplayName,
+			"creation_time":          held.CreationTime.Format(time.RFC3339Nano),
+			"factors":                factors,
+			"approved":               held.approved(),
+		},
+	}, nil
+}
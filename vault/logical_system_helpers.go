@@ -2,7 +2,6 @@ package vault
 
 import (
 	"fmt"
-	"strings"
 	"time"
 )
 
@@ -66,7 +65,7 @@ func (b *SystemBackend) tuneMountTTLs(path string, me *MountEntry, newDefault, n
 	// Update the mount table
 	var err error
 	switch {
-	case strings.HasPrefix(path, "auth/"):
+	case me.Table == credentialTableType:
 		err = b.Core.persistAuth(b.Core.auth, me.Local)
 	default:
 		err = b.Core.persistMounts(b.Core.mounts, me.Local)
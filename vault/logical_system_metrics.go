@@ -0,0 +1,41 @@
+package vault
+
+import (
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// metricsPaths returns the paths used to read the node's metrics
+func metricsPaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "metrics$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"format": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["metrics-format"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleMetrics,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["metrics"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["metrics"][1]),
+		},
+	}
+}
+
+// handleMetrics returns the metrics in the requested format
+func (b *SystemBackend) handleMetrics(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if b.Core.metricsHelper == nil {
+		return logical.ErrorResponse("metrics are not enabled"), logical.ErrInvalidRequest
+	}
+
+	return b.Core.metricsHelper.ResponseForFormat(data.Get("format").(string))
+}
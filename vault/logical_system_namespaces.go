@@ -0,0 +1,123 @@
+package vault
+
+import (
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/logical/framework"
+)
+
+// namespacePaths returns the paths used to manage the child namespaces of
+// the namespace the system backend is reached through
+func namespacePaths(b *SystemBackend) []*framework.Path {
+	return []*framework.Path{
+		&framework.Path{
+			Pattern: "namespaces/?$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.handleNamespaceList,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["namespaces"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["namespaces"][1]),
+		},
+
+		&framework.Path{
+			Pattern: "namespaces/(?P<name>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["namespaces_name"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleNamespaceCreate,
+				logical.ReadOperation:   b.handleNamespaceRead,
+				logical.DeleteOperation: b.handleNamespaceDelete,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["namespaces"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["namespaces"][1]),
+		},
+	}
+}
+
+// handleNamespaceList returns the names of the child namespaces
+func (b *SystemBackend) handleNamespaceList(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := b.Core.namespaceByPath(req.MountPoint)
+	return logical.ListResponse(b.Core.listNamespaces(ns)), nil
+}
+
+// handleNamespaceCreate creates a child namespace
+func (b *SystemBackend) handleNamespaceCreate(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := strings.TrimSuffix(data.Get("name").(string), "/")
+	if name == "" {
+		return logical.ErrorResponse("missing namespace name"), logical.ErrInvalidRequest
+	}
+
+	parent := b.Core.namespaceByPath(req.MountPoint)
+	ns, err := b.Core.createNamespace(parent, name)
+	if err != nil {
+		b.Backend.Logger().Error("sys: namespace creation failed", "name", name, "error", err)
+		return handleError(err)
+	}
+
+	return namespaceResponse(ns), nil
+}
+
+// handleNamespaceRead returns a child namespace
+func (b *SystemBackend) handleNamespaceRead(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := b.childNamespace(req, data)
+	if ns == nil {
+		return nil, nil
+	}
+
+	return namespaceResponse(ns), nil
+}
+
+// handleNamespaceDelete deletes a child namespace along with everything
+// within it
+func (b *SystemBackend) handleNamespaceDelete(
+	req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns := b.childNamespace(req, data)
+	if ns == nil {
+		return nil, nil
+	}
+
+	if err := b.Core.deleteNamespace(ns); err != nil {
+		b.Backend.Logger().Error("sys: namespace deletion failed", "path", ns.Path, "error", err)
+		return handleError(err)
+	}
+	return nil, nil
+}
+
+// childNamespace returns the child namespace named in the request, or nil
+// if it does not exist
+func (b *SystemBackend) childNamespace(req *logical.Request, data *framework.FieldData) *Namespace {
+	parent := b.Core.namespaceByPath(req.MountPoint)
+	name := strings.TrimSuffix(data.Get("name").(string), "/")
+	if name == "" || strings.Contains(name, "/") {
+		return nil
+	}
+
+	path := parent.Path + name + "/"
+	ns := b.Core.namespaceByPath(path)
+	if ns.Path != path {
+		return nil
+	}
+	return ns
+}
+
+func namespaceResponse(ns *Namespace) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"id":   ns.ID,
+			"path": ns.Path,
+		},
+	}
+}
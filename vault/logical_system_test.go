@@ -7,8 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
 )
@@ -25,6 +27,9 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"replication/reindex",
 		"rotate",
 		"config/auditing/*",
+		"leases/lookup/*",
+		"storage/raft/*",
+		"plugins/catalog/*",
 	}
 
 	b := testSystemBackend(t)
@@ -75,6 +80,16 @@ func TestSystemBackend_mounts(t *testing.T) {
 			},
 			"local": true,
 		},
+		"identity/": map[string]interface{}{
+			"description": "identity store",
+			"type":        "identity",
+			"config": map[string]interface{}{
+				"default_lease_ttl": resp.Data["identity/"].(map[string]interface{})["config"].(map[string]interface{})["default_lease_ttl"].(int64),
+				"max_lease_ttl":     resp.Data["identity/"].(map[string]interface{})["config"].(map[string]interface{})["max_lease_ttl"].(int64),
+				"force_no_cache":    false,
+			},
+			"local": false,
+		},
 	}
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("Got:\n%#v\nExpected:\n%#v", resp.Data, exp)
@@ -284,6 +299,51 @@ func TestSystemBackend_remount(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_tuneMount_versioned(t *testing.T) {
+	c, b, root := testCoreSystemBackend(t)
+
+	req := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"bar": "baz"},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tuneReq := logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	tuneReq.Data["versioned"] = true
+	resp, err := b.HandleRequest(tuneReq)
+	if err != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	if entry := c.router.MatchingMountEntry("secret/"); entry == nil || entry.Type != "kv" {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/data/foo",
+		ClientToken: root,
+	}
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Only generic mounts can be upgraded
+	tuneReq = logical.TestRequest(t, logical.UpdateOperation, "mounts/cubbyhole/tune")
+	tuneReq.Data["versioned"] = true
+	if _, err := b.HandleRequest(tuneReq); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
 func TestSystemBackend_remount_invalid(t *testing.T) {
 	b := testSystemBackend(t)
 
@@ -487,6 +547,109 @@ func TestSystemBackend_revoke_invalidID(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_leases(t *testing.T) {
+	core, b, root := testCoreSystemBackend(t)
+
+	// Create a key with a lease
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.Data["lease"] = "1h"
+	req.ClientToken = root
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Read a key with a LeaseID
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err = core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Read lease
+	req = logical.TestRequest(t, logical.UpdateOperation, "leases/lookup")
+	req.Data["lease_id"] = resp.Secret.LeaseID
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["renewable"] != true {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["expire_time"] == nil || resp.Data["last_renewal"] != nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if ttl := resp.Data["ttl"].(int64); ttl <= 0 || ttl > 3600 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Invalid lease
+	req = logical.TestRequest(t, logical.UpdateOperation, "leases/lookup")
+	req.Data["lease_id"] = "invalid"
+	resp, err = b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request, got err: %v", err)
+	}
+}
+
+func TestSystemBackend_leases_list(t *testing.T) {
+	core, b, root := testCoreSystemBackend(t)
+
+	// Create a key with a lease
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.Data["lease"] = "1h"
+	req.ClientToken = root
+	resp, err := core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Read a key with a LeaseID
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = root
+	resp, err = core.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Secret == nil || resp.Secret.LeaseID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	leaseID := resp.Secret.LeaseID
+
+	// List top level
+	req = logical.TestRequest(t, logical.ListOperation, "leases/lookup/")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"secret/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// List the leases under the prefix
+	req = logical.TestRequest(t, logical.ListOperation, "leases/lookup/secret/foo")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 1 || "secret/foo/"+keys[0] != leaseID {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
 func TestSystemBackend_revokePrefix(t *testing.T) {
 	core, b, root := testCoreSystemBackend(t)
 
@@ -617,6 +780,11 @@ func TestSystemBackend_authTable(t *testing.T) {
 			"local": false,
 		},
 	}
+	accessor := resp.Data["token/"].(map[string]interface{})["accessor"]
+	if accessor == nil || accessor == "" {
+		t.Fatalf("missing accessor: %#v", resp.Data)
+	}
+	exp["token/"].(map[string]interface{})["accessor"] = accessor
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
@@ -1071,6 +1239,45 @@ func TestSystemBackend_rotate(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_Metrics(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+
+	// Metrics are only available when the server set up the sinks
+	req := logical.TestRequest(t, logical.ReadOperation, "metrics")
+	_, err := b.HandleRequest(req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("err: %v", err)
+	}
+
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	prom := metricsutil.NewPrometheusSink(0)
+	c.metricsHelper = metricsutil.NewMetricsHelper(inm, prom)
+	prom.SetGauge([]string{"vault", "token", "count"}, 2)
+
+	req = logical.TestRequest(t, logical.ReadOperation, "metrics")
+	req.Data["format"] = "prometheus"
+	resp, err := b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data[logical.HTTPContentType] != metricsutil.PrometheusContentType {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	body := string(resp.Data[logical.HTTPRawBody].([]byte))
+	if !strings.Contains(body, "vault_token_count 2\n") {
+		t.Fatalf("bad: %s", body)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "metrics")
+	resp, err = b.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := resp.Data["gauges"]; !ok {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
 func testSystemBackend(t *testing.T) logical.Backend {
 	c, _, _ := TestCoreUnsealed(t)
 	bc := &logical.BackendConfig{
@@ -47,12 +47,14 @@ var (
 		"auth/",
 		"sys/",
 		"cubbyhole/",
+		"identity/",
 	}
 
 	untunableMounts = []string{
 		"cubbyhole/",
 		"sys/",
 		"audit/",
+		"identity/",
 	}
 
 	// singletonMounts can only exist in one location and are
@@ -60,6 +62,7 @@ var (
 	singletonMounts = []string{
 		"cubbyhole",
 		"system",
+		"identity",
 	}
 )
 
@@ -131,15 +134,17 @@ func (t *MountTable) sortEntriesByPath() *MountTable {
 
 // MountEntry is used to represent a mount table entry
 type MountEntry struct {
-	Table       string            `json:"table"`             // The table it belongs to
-	Path        string            `json:"path"`              // Mount Path
-	Type        string            `json:"type"`              // Logical backend Type
-	Description string            `json:"description"`       // User-provided description
-	UUID        string            `json:"uuid"`              // Barrier view UUID
-	Config      MountConfig       `json:"config"`            // Configuration related to this mount (but not backend-derived)
-	Options     map[string]string `json:"options"`           // Backend options
-	Local       bool              `json:"local"`             // Local mounts are not replicated or affected by replication
-	Tainted     bool              `json:"tainted,omitempty"` // Set as a Write-Ahead flag for unmount/remount
+	Table       string            `json:"table"`                  // The table it belongs to
+	Path        string            `json:"path"`                   // Mount Path
+	Type        string            `json:"type"`                   // Logical backend Type
+	Description string            `json:"description"`            // User-provided description
+	UUID        string            `json:"uuid"`                   // Barrier view UUID
+	Accessor    string            `json:"accessor"`               // Unique and stable identifier of the mount, unlike its path
+	Config      MountConfig       `json:"config"`                 // Configuration related to this mount (but not backend-derived)
+	Options     map[string]string `json:"options"`                // Backend options
+	Local       bool              `json:"local"`                  // Local mounts are not replicated or affected by replication
+	Tainted     bool              `json:"tainted,omitempty"`      // Set as a Write-Ahead flag for unmount/remount
+	NamespaceID string            `json:"namespace_id,omitempty"` // Namespace of the mount, empty for the root namespace
 }
 
 // MountConfig is used to hold settable options
@@ -147,6 +152,18 @@ type MountConfig struct {
 	DefaultLeaseTTL time.Duration `json:"default_lease_ttl" structs:"default_lease_ttl" mapstructure:"default_lease_ttl"` // Override for global default
 	MaxLeaseTTL     time.Duration `json:"max_lease_ttl" structs:"max_lease_ttl" mapstructure:"max_lease_ttl"`             // Override for global default
 	ForceNoCache    bool          `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`          // Override for global default
+	PluginName      string        `json:"plugin_name,omitempty" structs:"plugin_name,omitempty" mapstructure:"plugin_name"`
+}
+
+// backendConfig returns the configuration given to the factory of the
+// backend of the mount entry
+func (e *MountEntry) backendConfig() map[string]string {
+	if e.Config.PluginName == "" {
+		return nil
+	}
+	return map[string]string{
+		"plugin_name": e.Config.PluginName,
+	}
 }
 
 // Returns a deep copy of the mount entry
@@ -161,11 +178,64 @@ func (e *MountEntry) Clone() *MountEntry {
 		Type:        e.Type,
 		Description: e.Description,
 		UUID:        e.UUID,
+		Accessor:    e.Accessor,
 		Config:      e.Config,
 		Options:     optClone,
 		Local:       e.Local,
 		Tainted:     e.Tainted,
+		NamespaceID: e.NamespaceID,
+	}
+}
+
+// generateMountAccessor returns a new accessor for a mount of the given type
+func (c *Core) generateMountAccessor(entryType string) (string, error) {
+	for {
+		randBytes, err := uuid.GenerateRandomBytes(4)
+		if err != nil {
+			return "", err
+		}
+		accessor := fmt.Sprintf("%s_%08x", entryType, randBytes)
+		if c.router.MatchingMountByAccessor(accessor) == nil {
+			return accessor, nil
+		}
+	}
+}
+
+// setMountAccessors generates the accessors of the entries of the table that
+// have none yet, returning whether any entry was changed. The table may not
+// be routed yet, so new accessors are checked against its own entries too.
+func (c *Core) setMountAccessors(table *MountTable) (bool, error) {
+	prefix := ""
+	if table.Type == credentialTableType {
+		prefix = "auth_"
+	}
+
+	accessors := make(map[string]struct{}, len(table.Entries))
+	for _, entry := range table.Entries {
+		if entry.Accessor != "" {
+			accessors[entry.Accessor] = struct{}{}
+		}
 	}
+
+	changed := false
+	for _, entry := range table.Entries {
+		if entry.Accessor != "" {
+			continue
+		}
+		for {
+			accessor, err := c.generateMountAccessor(prefix + entry.Type)
+			if err != nil {
+				return false, err
+			}
+			if _, ok := accessors[accessor]; !ok {
+				entry.Accessor = accessor
+				break
+			}
+		}
+		accessors[entry.Accessor] = struct{}{}
+		changed = true
+	}
+	return changed, nil
 }
 
 // Mount is used to mount a new backend to the mount table.
@@ -176,10 +246,20 @@ func (c *Core) mount(entry *MountEntry) error {
 	}
 
 	// Prevent protected paths from being mounted
-	for _, p := range protectedMounts {
-		if strings.HasPrefix(entry.Path, p) {
-			return logical.CodedError(403, fmt.Sprintf("cannot mount '%s'", entry.Path))
-		}
+	if c.protectedMountPath(entry.Path) {
+		return logical.CodedError(403, fmt.Sprintf("cannot mount '%s'", entry.Path))
+	}
+
+	// Mounts cannot overlap a child namespace
+	ns := c.namespaceByID(entry.NamespaceID)
+	if ns == nil {
+		return fmt.Errorf("unknown namespace")
+	}
+	if !ns.contains(entry.Path) {
+		return fmt.Errorf("mount path is not within namespace")
+	}
+	if other := c.namespaceConflict(ns, entry.Path); other != nil {
+		return logical.CodedError(409, fmt.Sprintf("existing namespace at %s", other.Path))
 	}
 
 	// Do not allow more than one instance of a singleton mount
@@ -205,11 +285,18 @@ func (c *Core) mount(entry *MountEntry) error {
 		}
 		entry.UUID = entryUUID
 	}
+	if entry.Accessor == "" {
+		accessor, err := c.generateMountAccessor(entry.Type)
+		if err != nil {
+			return err
+		}
+		entry.Accessor = accessor
+	}
 	viewPath := backendBarrierPrefix + entry.UUID + "/"
 	view := NewBarrierView(c.barrier, viewPath)
 	sysView := c.mountEntrySysView(entry)
 
-	backend, err := c.newLogicalBackend(entry.Type, sysView, view, nil)
+	backend, err := c.newLogicalBackend(entry.Type, sysView, view, entry.backendConfig())
 	if err != nil {
 		return err
 	}
@@ -250,10 +337,8 @@ func (c *Core) unmount(path string) (bool, error) {
 	}
 
 	// Prevent protected paths from being unmounted
-	for _, p := range protectedMounts {
-		if strings.HasPrefix(path, p) {
-			return true, fmt.Errorf("cannot unmount '%s'", path)
-		}
+	if c.protectedMountPath(path) {
+		return true, fmt.Errorf("cannot unmount '%s'", path)
 	}
 
 	// Verify exact match of the route
@@ -363,6 +448,18 @@ func (c *Core) taintMountEntry(path string) error {
 	return nil
 }
 
+// protectedMountPath returns whether the path, taken relative to the
+// namespace it is in, is one of the protected mounts
+func (c *Core) protectedMountPath(path string) bool {
+	rel, _ := c.namespaceByPath(path).relativePath(path)
+	for _, p := range protectedMounts {
+		if strings.HasPrefix(rel, p) {
+			return true
+		}
+	}
+	return false
+}
+
 // Remount is used to remount a path at a new mount point.
 func (c *Core) remount(src, dst string) error {
 	// Ensure we end the path in a slash
@@ -374,10 +471,8 @@ func (c *Core) remount(src, dst string) error {
 	}
 
 	// Prevent protected paths from being remounted
-	for _, p := range protectedMounts {
-		if strings.HasPrefix(src, p) {
-			return fmt.Errorf("cannot remount '%s'", src)
-		}
+	if c.protectedMountPath(src) {
+		return fmt.Errorf("cannot remount '%s'", src)
 	}
 
 	// Verify exact match of the route
@@ -390,6 +485,15 @@ func (c *Core) remount(src, dst string) error {
 		return fmt.Errorf("existing mount at '%s'", match)
 	}
 
+	// Mounts stay within their namespace
+	ns := c.namespaceByPath(src)
+	if c.protectedMountPath(dst) || !ns.contains(dst) {
+		return fmt.Errorf("cannot remount to '%s'", dst)
+	}
+	if other := c.namespaceConflict(ns, dst); other != nil {
+		return fmt.Errorf("existing namespace at '%s'", other.Path)
+	}
+
 	// Mark the entry as tainted
 	if err := c.taintMountEntry(src); err != nil {
 		return err
@@ -451,6 +555,99 @@ func (c *Core) remount(src, dst string) error {
 	return nil
 }
 
+// upgradeMountToKV converts a generic mount into a versioned kv mount. The
+// existing data is copied into versioned storage before the mount is routed
+// to the kv backend. The caller must hold the mounts lock.
+func (c *Core) upgradeMountToKV(path string) error {
+	// Ensure we end the path in a slash
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	var entry *MountEntry
+	for _, e := range c.mounts.Entries {
+		if e.Path == path {
+			entry = e
+			break
+		}
+	}
+	switch {
+	case entry == nil:
+		return fmt.Errorf("no matching mount at '%s'", path)
+	case entry.Type == "kv":
+		return nil
+	case entry.Type != "generic":
+		return fmt.Errorf("cannot upgrade mount of type %q, only generic mounts can be upgraded", entry.Type)
+	}
+
+	view := c.router.MatchingStorageView(path)
+	if view == nil {
+		return fmt.Errorf("no storage view found for '%s'", path)
+	}
+
+	// Stop requests from reaching the old backend while the data is moved
+	if err := c.router.Taint(path); err != nil {
+		return err
+	}
+	defer c.router.Untaint(path)
+
+	if err := checkKVUpgrade(view); err != nil {
+		return err
+	}
+
+	backend, err := c.newLogicalBackend("kv", c.mountEntrySysView(entry), view, nil)
+	if err != nil {
+		return err
+	}
+	kv, ok := backend.(*KVBackend)
+	if !ok {
+		return fmt.Errorf("unexpected kv backend of type %T", backend)
+	}
+
+	// The data is copied into the versioned layout before the mount is
+	// switched over, so that a failure leaves the generic mount as it was
+	if _, err := kv.migratePassthrough(); err != nil {
+		c.discardKVUpgrade(kv, path)
+		return fmt.Errorf("failed to migrate data of '%s': %v", path, err)
+	}
+
+	entry.Type = "kv"
+	if err := c.persistMounts(c.mounts, entry.Local); err != nil {
+		entry.Type = "generic"
+		c.discardKVUpgrade(kv, path)
+		c.logger.Error("core: failed to update mount table", "error", err)
+		return logical.CodedError(500, "failed to update mount table")
+	}
+
+	if err := c.router.Unmount(path); err != nil {
+		return err
+	}
+	if err := c.router.Mount(backend, path, entry, view); err != nil {
+		return err
+	}
+
+	// Initializing removes the passthrough copies of the data. This resumes
+	// whenever the kv backend is initialized, so a failure here only leaves
+	// entries behind that the kv backend does not serve.
+	if err := backend.Initialize(); err != nil {
+		c.logger.Error("core: failed to complete mount upgrade, it resumes when the mount is next loaded", "path", path, "error", err)
+		return fmt.Errorf("failed to complete upgrade of '%s': %v", path, err)
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: upgraded mount to versioned kv", "path", path)
+	}
+	return nil
+}
+
+// discardKVUpgrade removes the versioned copies of the data of a mount that
+// stays generic, so that they are not listed and the upgrade can be retried
+func (c *Core) discardKVUpgrade(kv *KVBackend, path string) {
+	if err := kv.discardUpgrade(); err != nil {
+		c.logger.Error("core: failed to remove data of aborted mount upgrade", "path", path, "error", err)
+	}
+}
+
 // loadMounts is invoked as part of postUnseal to load the mount table
 func (c *Core) loadMounts() error {
 	mountTable := &MountTable{}
@@ -527,6 +724,16 @@ func (c *Core) loadMounts() error {
 			}
 		}
 
+		// Upgrade to entries with accessors
+		changed, err := c.setMountAccessors(c.mounts)
+		if err != nil {
+			c.logger.Error("core: failed to generate mount accessors", "error", err)
+			return errLoadMountsFailed
+		}
+		if changed {
+			needPersist = true
+		}
+
 		// Done if we have restored the mount table and we don't need
 		// to persist
 		if !needPersist {
@@ -535,6 +742,10 @@ func (c *Core) loadMounts() error {
 	} else {
 		// Create and persist the default mount table
 		c.mounts = defaultMountTable()
+		if _, err := c.setMountAccessors(c.mounts); err != nil {
+			c.logger.Error("core: failed to generate mount accessors", "error", err)
+			return errLoadMountsFailed
+		}
 	}
 
 	if err := c.persistMounts(c.mounts, false); err != nil {
@@ -637,17 +848,22 @@ func (c *Core) setupMounts() error {
 		sysView := c.mountEntrySysView(entry)
 		// Initialize the backend
 		// Create the new backend
-		backend, err = c.newLogicalBackend(entry.Type, sysView, view, nil)
-		if err != nil {
-			c.logger.Error("core: failed to create mount entry", "path", entry.Path, "error", err)
-			return errLoadMountsFailed
-		}
-		if backend == nil {
+		backend, err = c.newLogicalBackend(entry.Type, sysView, view, entry.backendConfig())
+		if err == nil && backend == nil {
 			return fmt.Errorf("created mount entry of type %q is nil", entry.Type)
 		}
-
-		if err := backend.Initialize(); err != nil {
-			return err
+		if err == nil {
+			err = backend.Initialize()
+		}
+		if err != nil {
+			c.logger.Error("core: failed to create mount entry", "path", entry.Path, "error", err)
+			// A plugin that fails to start should not prevent Vault from
+			// unsealing; the mount stays routed to a backend reporting the
+			// failure instead
+			if entry.Config.PluginName == "" {
+				return errLoadMountsFailed
+			}
+			backend = newPluginUnavailableBackend(entry, sysView, err)
 		}
 
 		switch entry.Type {
@@ -657,6 +873,8 @@ func (c *Core) setupMounts() error {
 			ch := backend.(*CubbyholeBackend)
 			ch.saltUUID = entry.UUID
 			ch.storageView = view
+		case "identity":
+			c.identityStore = backend.(*IdentityStore)
 		}
 
 		// Mount the backend
@@ -785,7 +1003,20 @@ func requiredMountTable() *MountTable {
 		Description: "system endpoints used for control, policy and debugging",
 		UUID:        sysUUID,
 	}
+	identityUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		panic(fmt.Sprintf("could not create identity UUID: %v", err))
+	}
+	identityMount := &MountEntry{
+		Table:       mountTableType,
+		Path:        "identity/",
+		Type:        "identity",
+		Description: "identity store",
+		UUID:        identityUUID,
+	}
+
 	table.Entries = append(table.Entries, cubbyholeMount)
 	table.Entries = append(table.Entries, sysMount)
+	table.Entries = append(table.Entries, identityMount)
 	return table
 }
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -10,7 +11,10 @@ import (
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/compressutil"
 	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/logformat"
 	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
 )
 
 func TestCore_DefaultMountTable(t *testing.T) {
@@ -93,16 +97,18 @@ func TestCore_Mount_Local(t *testing.T) {
 		Type: mountTableType,
 		Entries: []*MountEntry{
 			&MountEntry{
-				Table: mountTableType,
-				Path:  "noop/",
-				Type:  "generic",
-				UUID:  "abcd",
+				Table:    mountTableType,
+				Path:     "noop/",
+				Type:     "generic",
+				UUID:     "abcd",
+				Accessor: "generic_abcd",
 			},
 			&MountEntry{
-				Table: mountTableType,
-				Path:  "noop2/",
-				Type:  "generic",
-				UUID:  "bcde",
+				Table:    mountTableType,
+				Path:     "noop2/",
+				Type:     "generic",
+				UUID:     "bcde",
+				Accessor: "generic_bcde",
 			},
 		},
 	}
@@ -589,7 +595,7 @@ func testCore_MountTable_UpgradeToTyped_Common(
 }
 
 func verifyDefaultTable(t *testing.T, table *MountTable) {
-	if len(table.Entries) != 3 {
+	if len(table.Entries) != 4 {
 		t.Fatalf("bad: %v", table.Entries)
 	}
 	table.sortEntriesByPath()
@@ -603,13 +609,20 @@ func verifyDefaultTable(t *testing.T, table *MountTable) {
 				t.Fatalf("bad: %v", entry)
 			}
 		case 1:
+			if entry.Path != "identity/" {
+				t.Fatalf("bad: %v", entry)
+			}
+			if entry.Type != "identity" {
+				t.Fatalf("bad: %v", entry)
+			}
+		case 2:
 			if entry.Path != "secret/" {
 				t.Fatalf("bad: %v", entry)
 			}
 			if entry.Type != "generic" {
 				t.Fatalf("bad: %v", entry)
 			}
-		case 2:
+		case 3:
 			if entry.Path != "sys/" {
 				t.Fatalf("bad: %v", entry)
 			}
@@ -628,3 +641,178 @@ func verifyDefaultTable(t *testing.T, table *MountTable) {
 		}
 	}
 }
+
+// failingMountsPhysical fails writes of the mount table once failPuts is set
+type failingMountsPhysical struct {
+	physical.Backend
+	failPuts bool
+}
+
+func (f *failingMountsPhysical) Put(entry *physical.Entry) error {
+	if f.failPuts && entry.Key == coreMountConfigPath {
+		return fmt.Errorf("injected failure")
+	}
+	return f.Backend.Put(entry)
+}
+
+func TestCore_UpgradeMountToKV_PersistFailure(t *testing.T) {
+	inm := &failingMountsPhysical{
+		Backend: physical.NewInmem(logformat.NewVaultLogger(log.LevelTrace)),
+	}
+	c, _, root := TestCoreUnsealedBackend(t, inm)
+
+	req := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/foo",
+		Data:        map[string]interface{}{"bar": "baz"},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	inm.failPuts = true
+	c.mountsLock.Lock()
+	err := c.upgradeMountToKV("secret")
+	c.mountsLock.Unlock()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	inm.failPuts = false
+
+	// The mount stays generic and its data untouched
+	if entry := c.router.MatchingMountEntry("secret/"); entry == nil || entry.Type != "generic" {
+		t.Fatalf("bad: %#v", entry)
+	}
+	req = &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "secret/foo",
+		ClientToken: root,
+	}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The versioned copies of the data are removed again
+	req = &logical.Request{
+		Operation:   logical.ListOperation,
+		Path:        "secret/",
+		ClientToken: root,
+	}
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if keys := resp.Data["keys"].([]string); !reflect.DeepEqual(keys, []string{"foo"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// Once the mount table can be written, the upgrade goes through
+	c.mountsLock.Lock()
+	err = c.upgradeMountToKV("secret")
+	c.mountsLock.Unlock()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req.Operation = logical.ReadOperation
+	req.Path = "secret/data/foo"
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
+func TestCore_UpgradeMountToKV_ReservedKey(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	// A secret named like the upgrade marker must not be taken for it
+	req := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/upgraded",
+		Data:        map[string]interface{}{"bar": "baz"},
+		ClientToken: root,
+	}
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c.mountsLock.Lock()
+	err := c.upgradeMountToKV("secret")
+	c.mountsLock.Unlock()
+	if err == nil || !strings.Contains(err.Error(), "reserved path") {
+		t.Fatalf("expected reserved path error, got: %v", err)
+	}
+	if entry := c.router.MatchingMountEntry("secret/"); entry == nil || entry.Type != "generic" {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
+
+func TestCore_SetupMounts_PluginUnavailable(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+
+	var pluginErr error
+	factory := func(conf *logical.BackendConfig) (logical.Backend, error) {
+		if pluginErr != nil {
+			return nil, pluginErr
+		}
+		return &NoopBackend{Login: []string{"login"}}, nil
+	}
+	c.logicalBackends["plugin"] = factory
+	c.credentialBackends["plugin"] = factory
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/foo")
+	req.Data["type"] = "plugin"
+	req.Data["config"] = map[string]interface{}{"plugin_name": "mock"}
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/auth/bar")
+	req.Data["type"] = "plugin"
+	req.Data["plugin_name"] = "mock"
+	req.ClientToken = root
+	if resp, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	// The plugin fails to start on the next unseal, which still succeeds
+	pluginErr = fmt.Errorf("injected failure")
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %v", err)
+		}
+	}
+	if sealed, _ := c.Sealed(); sealed {
+		t.Fatal("should not be sealed")
+	}
+
+	// Requests to the mounts report the failure
+	req = logical.TestRequest(t, logical.ReadOperation, "foo/bar")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err == nil || !strings.Contains(err.Error(), "injected failure") {
+		t.Fatalf("expected plugin error, got: %v", err)
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/bar/login")
+	if _, err := c.HandleRequest(req); err == nil || !strings.Contains(err.Error(), "injected failure") {
+		t.Fatalf("expected plugin error, got: %v", err)
+	}
+
+	// The mounts can still be disabled
+	for _, path := range []string{"sys/mounts/foo", "sys/auth/bar"} {
+		req := logical.TestRequest(t, logical.DeleteOperation, path)
+		req.ClientToken = root
+		if resp, err := c.HandleRequest(req); err != nil {
+			t.Fatalf("err: %v %v", err, resp)
+		}
+	}
+}
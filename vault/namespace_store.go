@@ -0,0 +1,483 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// namespaceStorePath is used to store the namespace table. Like the
+	// mount tables it is protected within the Vault itself.
+	namespaceStorePath = "core/namespaces/"
+
+	// namespaceSubPath is the sub-path used within the system barrier view
+	// to hold the policies of child namespaces
+	namespaceSubPath = "namespaces/"
+
+	// rootNamespaceID is the ID of the implicit root namespace
+	rootNamespaceID = "root"
+)
+
+var (
+	// rootNamespace holds everything that existed before namespaces and
+	// everything that isn't explicitly placed in a child namespace
+	rootNamespace = &Namespace{
+		ID:   rootNamespaceID,
+		Path: "",
+	}
+
+	// namespaceNameRegex restricts namespace names to a single path segment
+	namespaceNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+	// reservedNamespaceNames cannot be used as namespace names since they
+	// clash with the paths every namespace provides
+	reservedNamespaceNames = []string{
+		"sys",
+		"auth",
+		"cubbyhole",
+		"identity",
+	}
+
+	// namespaceAliasMounts are the mounts of the root namespace that are
+	// shared by every child namespace. The backends are mounted once and
+	// exposed under the path of each namespace.
+	namespaceAliasMounts = []string{
+		"sys/",
+		"cubbyhole/",
+		credentialRoutePrefix + "token/",
+	}
+
+	// namespaceAllowedPaths are the paths of the shared system and token backends
+	// that are available within child namespaces; everything else on those
+	// backends is only reachable from the root namespace. Entries ending in
+	// '*' are prefix matches.
+	namespaceAllowedPaths = []string{
+		"sys/mounts",
+		"sys/mounts/*",
+		"sys/remount",
+		"sys/auth",
+		"sys/auth/*",
+		"sys/policy",
+		"sys/policy/*",
+		"sys/namespaces",
+		"sys/namespaces/*",
+		"sys/capabilities",
+		"sys/capabilities-self",
+		"auth/token/create",
+		"auth/token/create-orphan",
+		"auth/token/lookup-self",
+		"auth/token/renew-self",
+		"auth/token/revoke-self",
+	}
+)
+
+// Namespace is an isolated tenant within Vault. Each namespace has its own
+// mounts, credential backends, policies and tokens, all of which live under
+// the path of the namespace.
+type Namespace struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+// isRoot returns whether this is the root namespace
+func (n *Namespace) isRoot() bool {
+	return n.ID == rootNamespaceID
+}
+
+// entryID returns the namespace ID to record on mount and token entries.
+// Entries in the root namespace leave it empty so that existing data is
+// unchanged.
+func (n *Namespace) entryID() string {
+	if n.isRoot() {
+		return ""
+	}
+	return n.ID
+}
+
+// contains returns whether the given path is within the namespace
+func (n *Namespace) contains(path string) bool {
+	return strings.HasPrefix(path, n.Path)
+}
+
+// relativePath returns the given path relative to the namespace, and whether
+// the path is within the namespace at all
+func (n *Namespace) relativePath(path string) (string, bool) {
+	if !n.contains(path) {
+		return "", false
+	}
+	return strings.TrimPrefix(path, n.Path), true
+}
+
+// NamespaceStore keeps track of the child namespaces and their policy stores
+type NamespaceStore struct {
+	view *BarrierView
+
+	lock         sync.RWMutex
+	namespaces   map[string]*Namespace
+	policyStores map[string]*PolicyStore
+}
+
+// loadNamespaces is invoked as part of postUnseal to load the namespace
+// table. It runs before the mounts are set up since routing credential
+// backends depends on the namespace they are in.
+func (c *Core) loadNamespaces() error {
+	store := &NamespaceStore{
+		view:         NewBarrierView(c.barrier, namespaceStorePath),
+		namespaces:   make(map[string]*Namespace),
+		policyStores: make(map[string]*PolicyStore),
+	}
+
+	ids, err := logical.CollectKeys(store.view)
+	if err != nil {
+		return errwrap.Wrapf("failed to list namespaces: {{err}}", err)
+	}
+	for _, id := range ids {
+		raw, err := store.view.Get(id)
+		if err != nil {
+			return errwrap.Wrapf("failed to read namespace: {{err}}", err)
+		}
+		if raw == nil {
+			continue
+		}
+
+		ns := new(Namespace)
+		if err := jsonutil.DecodeJSON(raw.Value, ns); err != nil {
+			return errwrap.Wrapf("failed to decode namespace: {{err}}", err)
+		}
+		store.namespaces[ns.ID] = ns
+	}
+
+	c.namespaceStore = store
+	return nil
+}
+
+// setupNamespaces is invoked after the mounts and credential backends have
+// been set up to create the policy stores of the namespaces and to expose the
+// shared backends within each of them
+func (c *Core) setupNamespaces() error {
+	store := c.namespaceStore
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	for _, ns := range store.namespaces {
+		if err := c.setupNamespace(ns); err != nil {
+			c.logger.Error("core: failed to set up namespace", "path", ns.Path, "error", err)
+			return err
+		}
+	}
+
+	if c.logger.IsInfo() && len(store.namespaces) > 0 {
+		c.logger.Info("core: set up namespaces", "count", len(store.namespaces))
+	}
+	return nil
+}
+
+// setupNamespace creates the policy store of a namespace and mounts the
+// shared backends under its path. The namespace store lock must be held.
+func (c *Core) setupNamespace(ns *Namespace) error {
+	view := c.systemBarrierView.SubView(namespaceSubPath + ns.ID + "/" + policySubPath)
+	ps := NewPolicyStore(view, &dynamicSystemView{core: c})
+	ps.namespacePath = ns.Path
+
+	policy, err := ps.GetPolicy("default")
+	if err != nil {
+		return errwrap.Wrapf("error fetching default policy from store: {{err}}", err)
+	}
+	if policy == nil {
+		if err := ps.createDefaultPolicy(); err != nil {
+			return err
+		}
+	}
+	c.namespaceStore.policyStores[ns.ID] = ps
+
+	for _, src := range namespaceAliasMounts {
+		if err := c.router.MountAlias(src, ns.Path+src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// teardownNamespaces is used to reverse loadNamespaces and setupNamespaces
+// when the vault is being sealed. The aliases are dropped along with the
+// router.
+func (c *Core) teardownNamespaces() error {
+	c.namespaceStore = nil
+	return nil
+}
+
+// namespaceByID returns the namespace with the given ID, or nil if it does
+// not exist. An empty ID refers to the root namespace.
+func (c *Core) namespaceByID(id string) *Namespace {
+	if id == "" || id == rootNamespaceID {
+		return rootNamespace
+	}
+	if c.namespaceStore == nil {
+		return nil
+	}
+
+	c.namespaceStore.lock.RLock()
+	defer c.namespaceStore.lock.RUnlock()
+	return c.namespaceStore.namespaces[id]
+}
+
+// namespaceByPath returns the deepest namespace containing the given path
+func (c *Core) namespaceByPath(path string) *Namespace {
+	if c.namespaceStore == nil {
+		return rootNamespace
+	}
+
+	c.namespaceStore.lock.RLock()
+	defer c.namespaceStore.lock.RUnlock()
+
+	match := rootNamespace
+	for _, ns := range c.namespaceStore.namespaces {
+		if ns.contains(path) && len(ns.Path) > len(match.Path) {
+			match = ns
+		}
+	}
+	return match
+}
+
+// namespacePolicyStore returns the policy store of a namespace
+func (c *Core) namespacePolicyStore(ns *Namespace) *PolicyStore {
+	if ns.isRoot() || c.namespaceStore == nil {
+		return c.policyStore
+	}
+
+	c.namespaceStore.lock.RLock()
+	defer c.namespaceStore.lock.RUnlock()
+	return c.namespaceStore.policyStores[ns.ID]
+}
+
+// tokenPolicyStore returns the policy store of the namespace a token belongs
+// to, or nil if that namespace no longer exists
+func (c *Core) tokenPolicyStore(te *TokenEntry) *PolicyStore {
+	ns := c.namespaceByID(te.NamespaceID)
+	if ns == nil {
+		return nil
+	}
+	return c.namespacePolicyStore(ns)
+}
+
+// namespaceConflict returns the namespace a mount at the given path in the
+// namespace ns would overlap with, if any. Mounts may not contain a child
+// namespace nor be placed inside one.
+func (c *Core) namespaceConflict(ns *Namespace, path string) *Namespace {
+	if c.namespaceStore == nil {
+		return nil
+	}
+
+	c.namespaceStore.lock.RLock()
+	defer c.namespaceStore.lock.RUnlock()
+
+	for _, other := range c.namespaceStore.namespaces {
+		// The namespace itself and its ancestors contain the path by design
+		if strings.HasPrefix(ns.Path, other.Path) {
+			continue
+		}
+		if strings.HasPrefix(other.Path, path) || strings.HasPrefix(path, other.Path) {
+			return other
+		}
+	}
+	return nil
+}
+
+// namespacePathAllowed returns whether a path, relative to a child
+// namespace, may be used within it
+func namespacePathAllowed(path string) bool {
+	if !strings.HasPrefix(path, "sys/") && !strings.HasPrefix(path, "auth/token/") {
+		return true
+	}
+
+	for _, allowed := range namespaceAllowedPaths {
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+			continue
+		}
+		if path == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// listNamespaces returns the names of the direct children of a namespace
+func (c *Core) listNamespaces(parent *Namespace) []string {
+	c.namespaceStore.lock.RLock()
+	defer c.namespaceStore.lock.RUnlock()
+
+	var names []string
+	for _, ns := range c.namespaceStore.namespaces {
+		if rel, ok := parent.relativePath(ns.Path); ok && isNamespaceChild(rel) {
+			names = append(names, rel)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isNamespaceChild returns whether a namespace path relative to another
+// namespace denotes a direct child of it
+func isNamespaceChild(rel string) bool {
+	return rel != "" && strings.Count(rel, "/") == 1
+}
+
+// createNamespace creates a new child namespace of the parent namespace
+func (c *Core) createNamespace(parent *Namespace, name string) (*Namespace, error) {
+	if !namespaceNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid namespace name %q", name)
+	}
+	for _, reserved := range reservedNamespaceNames {
+		if name == reserved {
+			return nil, fmt.Errorf("namespace name %q is reserved", name)
+		}
+	}
+
+	path := parent.Path + name + "/"
+
+	// The namespace cannot overlap any existing mount or credential backend
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		if strings.HasPrefix(entry.Path, path) || strings.HasPrefix(path, entry.Path) {
+			c.mountsLock.RUnlock()
+			return nil, logical.CodedError(409, fmt.Sprintf("existing mount at %s", entry.Path))
+		}
+	}
+	c.mountsLock.RUnlock()
+
+	c.authLock.RLock()
+	for _, entry := range c.auth.Entries {
+		if strings.HasPrefix(entry.Path, path) || strings.HasPrefix(path, entry.Path) {
+			c.authLock.RUnlock()
+			return nil, logical.CodedError(409, fmt.Sprintf("existing credential backend at %s", entry.Path))
+		}
+	}
+	c.authLock.RUnlock()
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	ns := &Namespace{
+		ID:   id,
+		Path: path,
+	}
+
+	store := c.namespaceStore
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	for _, existing := range store.namespaces {
+		if existing.Path == path {
+			return nil, logical.CodedError(409, fmt.Sprintf("namespace %q already exists", path))
+		}
+	}
+
+	buf, err := json.Marshal(ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode namespace: %v", err)
+	}
+	if err := store.view.Put(&logical.StorageEntry{Key: ns.ID, Value: buf}); err != nil {
+		return nil, fmt.Errorf("failed to persist namespace: %v", err)
+	}
+
+	store.namespaces[ns.ID] = ns
+	if err := c.setupNamespace(ns); err != nil {
+		return nil, err
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: created namespace", "path", ns.Path)
+	}
+	return ns, nil
+}
+
+// deleteNamespace removes a namespace along with its mounts, credential
+// backends and policies, and revokes the tokens issued within it. Namespaces
+// that still have children cannot be deleted.
+func (c *Core) deleteNamespace(ns *Namespace) error {
+	store := c.namespaceStore
+
+	store.lock.RLock()
+	for _, other := range store.namespaces {
+		if other.ID != ns.ID && ns.contains(other.Path) {
+			store.lock.RUnlock()
+			return logical.CodedError(400, fmt.Sprintf("namespace %q has child namespaces", ns.Path))
+		}
+	}
+	store.lock.RUnlock()
+
+	// Remove the mounts and credential backends of the namespace
+	c.mountsLock.RLock()
+	var mounts []string
+	for _, entry := range c.mounts.Entries {
+		if entry.NamespaceID == ns.ID {
+			mounts = append(mounts, entry.Path)
+		}
+	}
+	c.mountsLock.RUnlock()
+	for _, path := range mounts {
+		if _, err := c.unmount(path); err != nil {
+			return err
+		}
+	}
+
+	c.authLock.RLock()
+	var auths []string
+	for _, entry := range c.auth.Entries {
+		if entry.NamespaceID == ns.ID {
+			auths = append(auths, entry.Path)
+		}
+	}
+	c.authLock.RUnlock()
+	for _, path := range auths {
+		if _, err := c.disableCredential(path); err != nil {
+			return err
+		}
+	}
+
+	// Revoke the tokens created through the token store of the namespace;
+	// tokens without a lease are rejected once the namespace is gone
+	if err := c.expiration.RevokePrefix(ns.Path + credentialRoutePrefix + "token/"); err != nil {
+		return err
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	for _, src := range namespaceAliasMounts {
+		if err := c.router.Unmount(ns.Path + src); err != nil {
+			return err
+		}
+	}
+
+	if ps := store.policyStores[ns.ID]; ps != nil {
+		if err := logical.ClearView(ps.view); err != nil {
+			return err
+		}
+	}
+	if err := store.view.Delete(ns.ID); err != nil {
+		return fmt.Errorf("failed to delete namespace: %v", err)
+	}
+
+	delete(store.policyStores, ns.ID)
+	delete(store.namespaces, ns.ID)
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: deleted namespace", "path", ns.Path)
+	}
+	return nil
+}
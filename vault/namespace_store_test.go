@@ -0,0 +1,243 @@
+package vault
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/logical"
+)
+
+func testNamespaceRequest(t *testing.T, c *Core, token string, op logical.Operation,
+	path string, data map[string]interface{}) (*logical.Response, error) {
+	req := logical.TestRequest(t, op, path)
+	req.ClientToken = token
+	req.Data = data
+	return c.HandleRequest(req)
+}
+
+// testNamespaceSetup creates the namespace with a generic mount, a secret
+// and a token that may read it
+func testNamespaceSetup(t *testing.T, c *Core, root, name string) string {
+	idx := strings.LastIndex(name, "/") + 1
+	resp, err := testNamespaceRequest(t, c, root, logical.UpdateOperation,
+		name[:idx]+"sys/namespaces/"+name[idx:], nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["path"] != name+"/" || resp.Data["id"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	_, err = testNamespaceRequest(t, c, root, logical.UpdateOperation, name+"/sys/mounts/secret",
+		map[string]interface{}{"type": "generic"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, err = testNamespaceRequest(t, c, root, logical.UpdateOperation, name+"/secret/foo",
+		map[string]interface{}{"value": name})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, err = testNamespaceRequest(t, c, root, logical.UpdateOperation, name+"/sys/policy/reader",
+		map[string]interface{}{"rules": `path "secret/*" { capabilities = ["read"] }`})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err = testNamespaceRequest(t, c, root, logical.UpdateOperation, name+"/auth/token/create",
+		map[string]interface{}{"policies": []string{"reader"}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+	return resp.Auth.ClientToken
+}
+
+func TestNamespaces_Isolation(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	tokenA := testNamespaceSetup(t, c, root, "team-a")
+	testNamespaceSetup(t, c, root, "team-b")
+
+	// The mount lives in the namespace only
+	if c.router.MatchingMount("team-a/secret/foo") != "team-a/secret/" {
+		t.Fatalf("bad: %s", c.router.MatchingMount("team-a/secret/foo"))
+	}
+	if c.router.MatchingMount("secret/foo") != "secret/" {
+		t.Fatalf("bad: %s", c.router.MatchingMount("secret/foo"))
+	}
+
+	// The token reads within its namespace
+	resp, err := testNamespaceRequest(t, c, tokenA, logical.ReadOperation, "team-a/secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["value"] != "team-a" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// But not in the root or a sibling namespace
+	for _, path := range []string{"secret/foo", "team-b/secret/foo", "team-b/sys/mounts"} {
+		_, err = testNamespaceRequest(t, c, tokenA, logical.ReadOperation, path, nil)
+		if !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("%s: err: %v", path, err)
+		}
+	}
+
+	// The policy only exists in its namespace
+	policy, err := c.policyStore.GetPolicy("reader")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if policy != nil {
+		t.Fatalf("bad: %#v", policy)
+	}
+
+	// The token looks itself up through its namespace
+	resp, err = testNamespaceRequest(t, c, tokenA, logical.ReadOperation, "team-a/auth/token/lookup-self", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ns := c.namespaceByPath("team-a/")
+	if resp.Data["namespace_id"] != ns.ID || resp.Data["path"] != "team-a/auth/token/create" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Only the namespaces' mounts are listed within them
+	resp, err = testNamespaceRequest(t, c, root, logical.ReadOperation, "team-a/sys/mounts", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := resp.Data["secret/"]; !ok || len(resp.Data) != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestNamespaces_UnsupportedPaths(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testNamespaceSetup(t, c, root, "team-a")
+
+	paths := []string{
+		"team-a/sys/seal",
+		"team-a/sys/audit",
+		"team-a/auth/token/accessors",
+		"team-a/auth/token/roles/foo",
+	}
+	for _, path := range paths {
+		_, err := testNamespaceRequest(t, c, root, logical.ReadOperation, path, nil)
+		if err != logical.ErrUnsupportedPath {
+			t.Fatalf("%s: err: %v", path, err)
+		}
+	}
+}
+
+func TestNamespaces_Nested(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testNamespaceSetup(t, c, root, "team-a")
+	tokenB := testNamespaceSetup(t, c, root, "team-a/team-b")
+
+	resp, err := testNamespaceRequest(t, c, root, logical.ListOperation, "team-a/sys/namespaces/", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"team-b/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The nested token cannot read its parent
+	_, err = testNamespaceRequest(t, c, tokenB, logical.ReadOperation, "team-a/secret/foo", nil)
+	if !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A namespace with children cannot be deleted
+	_, err = testNamespaceRequest(t, c, root, logical.DeleteOperation, "sys/namespaces/team-a", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestNamespaces_Conflicts(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	testNamespaceSetup(t, c, root, "team-a")
+
+	names := []string{"secret", "sys", "auth", "team-a", "bad name"}
+	for _, name := range names {
+		_, err := testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/namespaces/"+name, nil)
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+
+	// Mounts cannot shadow a namespace
+	_, err := testNamespaceRequest(t, c, root, logical.UpdateOperation, "sys/mounts/team-a",
+		map[string]interface{}{"type": "generic"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestNamespaces_Delete(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	tokenA := testNamespaceSetup(t, c, root, "team-a")
+
+	_, err := testNamespaceRequest(t, c, root, logical.DeleteOperation, "sys/namespaces/team-a", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if match := c.router.MatchingMount("team-a/secret/foo"); match != "" {
+		t.Fatalf("bad: %s", match)
+	}
+	_, err = testNamespaceRequest(t, c, tokenA, logical.ReadOperation, "team-a/auth/token/lookup-self", nil)
+	if !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := testNamespaceRequest(t, c, root, logical.ReadOperation, "sys/namespaces/team-a", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The name can be reused
+	testNamespaceSetup(t, c, root, "team-a")
+}
+
+func TestNamespaces_Persist(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+	tokenA := testNamespaceSetup(t, c, root, "team-a")
+
+	conf := &CoreConfig{
+		Physical:     c.physical,
+		DisableMlock: true,
+	}
+	c2, err := NewCore(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i, key := range keys {
+		unseal, err := TestCoreUnseal(c2, key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if i+1 == len(keys) && !unseal {
+			t.Fatalf("should be unsealed")
+		}
+	}
+
+	resp, err := testNamespaceRequest(t, c2, tokenA, logical.ReadOperation, "team-a/secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["value"] != "team-a" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
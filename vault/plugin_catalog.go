@@ -0,0 +1,144 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/pluginutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+var (
+	pluginCatalogPath         = "core/plugin-catalog/"
+	ErrDirectoryNotConfigured = errors.New("could not set plugin, plugin directory is not configured")
+)
+
+// PluginCatalog keeps a record of plugins known to vault. External plugins need
+// to be registered to the catalog before they can be used in backends.
+type PluginCatalog struct {
+	catalogView *BarrierView
+	directory   string
+
+	lock sync.RWMutex
+}
+
+func (c *Core) setupPluginCatalog() error {
+	c.pluginCatalog = &PluginCatalog{
+		catalogView: NewBarrierView(c.barrier, pluginCatalogPath),
+		directory:   c.pluginDirectory,
+	}
+
+	if c.logger.IsInfo() {
+		c.logger.Info("core: successfully setup plugin catalog", "plugin-directory", c.pluginDirectory)
+	}
+
+	return nil
+}
+
+// Get retrieves a plugin with the specified name from the catalog. It returns
+// a PluginRunner or nil if the plugin is not registered.
+func (c *PluginCatalog) Get(name string) (*pluginutil.PluginRunner, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	out, err := c.catalogView.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve plugin %q: %v", name, err)
+	}
+	if out == nil {
+		return nil, nil
+	}
+
+	entry := new(pluginutil.PluginRunner)
+	if err := jsonutil.DecodeJSON(out.Value, entry); err != nil {
+		return nil, fmt.Errorf("failed to decode plugin entry: %v", err)
+	}
+
+	return entry, nil
+}
+
+// Set registers a new external plugin with the catalog, or updates an
+// existing external plugin. It takes the name, command and SHA256 of the
+// plugin. The command is the path of the executable relative to the plugin
+// directory, optionally followed by arguments separated by spaces.
+func (c *PluginCatalog) Set(name, command string, sha256 []byte) error {
+	if c.directory == "" {
+		return ErrDirectoryNotConfigured
+	}
+
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return errors.New("missing command value")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Best effort check to make sure the command isn't breaking out of the
+	// configured plugin directory
+	commandFull := filepath.Join(c.directory, parts[0])
+	sym, err := filepath.EvalSymlinks(commandFull)
+	if err != nil {
+		return fmt.Errorf("error while checking command: %v", err)
+	}
+	symAbs, err := filepath.Abs(filepath.Dir(sym))
+	if err != nil {
+		return fmt.Errorf("error while checking command: %v", err)
+	}
+	dirSym, err := filepath.EvalSymlinks(c.directory)
+	if err != nil {
+		return fmt.Errorf("error while checking plugin directory: %v", err)
+	}
+	if symAbs != dirSym {
+		return errors.New("can not execute files outside of configured plugin directory")
+	}
+
+	entry := &pluginutil.PluginRunner{
+		Name:    name,
+		Command: commandFull,
+		Args:    parts[1:],
+		Sha256:  sha256,
+	}
+
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin entry: %v", err)
+	}
+
+	logicalEntry := logical.StorageEntry{
+		Key:   name,
+		Value: buf,
+	}
+	if err := c.catalogView.Put(&logicalEntry); err != nil {
+		return fmt.Errorf("failed to persist plugin entry: %v", err)
+	}
+	return nil
+}
+
+// Delete is used to remove an external plugin from the catalog
+func (c *PluginCatalog) Delete(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.catalogView.Delete(name)
+}
+
+// List returns a sorted list of the names of the registered plugins
+func (c *PluginCatalog) List() ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	keys, err := logical.CollectKeys(c.catalogView)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
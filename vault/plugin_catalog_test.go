@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/pluginutil"
+)
+
+func testPluginCatalog(t *testing.T) (*PluginCatalog, string) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	dir, err := ioutil.TempDir("", "vault-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "test-plugin"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c.pluginCatalog.directory = dir
+	return c.pluginCatalog, dir
+}
+
+func TestPluginCatalog_CRUD(t *testing.T) {
+	catalog, dir := testPluginCatalog(t)
+	defer os.RemoveAll(dir)
+
+	p, err := catalog.Get("test-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	if err := catalog.Set("test-plugin", "test-plugin --test", []byte{'1'}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = catalog.Get("test-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &pluginutil.PluginRunner{
+		Name:    "test-plugin",
+		Command: filepath.Join(dir, "test-plugin"),
+		Args:    []string{"--test"},
+		Sha256:  []byte{'1'},
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, p)
+	}
+
+	if err := catalog.Set("another-plugin", "test-plugin", []byte{'2'}); err != nil {
+		t.Fatal(err)
+	}
+	names, err := catalog.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"another-plugin", "test-plugin"}) {
+		t.Fatalf("bad: %#v", names)
+	}
+
+	if err := catalog.Delete("test-plugin"); err != nil {
+		t.Fatal(err)
+	}
+	p, err = catalog.Get("test-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("bad: %#v", p)
+	}
+}
+
+func TestPluginCatalog_Set_outsideDirectory(t *testing.T) {
+	catalog, dir := testPluginCatalog(t)
+	defer os.RemoveAll(dir)
+
+	if err := catalog.Set("test-plugin", "../test-plugin", []byte{'1'}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Symlinks pointing out of the directory are rejected as well
+	if err := os.Symlink("/bin/sh", filepath.Join(dir, "sh")); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.Set("test-plugin", "sh", []byte{'1'}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPluginCatalog_Set_noDirectory(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+
+	err := c.pluginCatalog.Set("test-plugin", "test-plugin", []byte{'1'})
+	if err != ErrDirectoryNotConfigured {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -68,6 +68,10 @@ type PathCapabilities struct {
 	Glob         bool
 	Capabilities []string
 
+	// Templated is set if the prefix contains template parameters, which
+	// are rendered for each token when building its ACL
+	Templated bool
+
 	// These keys are used at the top level to make the HCL nicer; we store in
 	// the Permissions object though
 	MinWrappingTTLHCL    interface{}              `hcl:"min_wrapping_ttl"`
@@ -82,6 +86,37 @@ type Permissions struct {
 	MaxWrappingTTL     time.Duration
 	AllowedParameters  map[string][]interface{}
 	DeniedParameters   map[string][]interface{}
+
+	// ControlGroup requires requests to the path to be authorized before
+	// they are handled
+	ControlGroup *ControlGroup
+}
+
+// Clone returns a deep copy of the permissions
+func (p *Permissions) Clone() *Permissions {
+	clone := *p
+	clone.AllowedParameters = cloneParameters(p.AllowedParameters)
+	clone.DeniedParameters = cloneParameters(p.DeniedParameters)
+	if p.ControlGroup != nil {
+		clone.ControlGroup = p.ControlGroup.clone()
+	}
+	return &clone
+}
+
+func cloneParameters(params map[string][]interface{}) map[string][]interface{} {
+	if params == nil {
+		return nil
+	}
+	clone := make(map[string][]interface{}, len(params))
+	for key, values := range params {
+		if values == nil {
+			clone[key] = nil
+			continue
+		}
+		clone[key] = make([]interface{}, len(values))
+		copy(clone[key], values)
+	}
+	return clone
 }
 
 // Parse is used to parse the specified ACL rules into an
@@ -139,6 +174,7 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			"denied_parameters",
 			"min_wrapping_ttl",
 			"max_wrapping_ttl",
+			"control_group",
 		}
 		if err := checkHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
@@ -165,6 +201,26 @@ func parsePaths(result *Policy, list *ast.ObjectList) error {
 			pc.Glob = true
 		}
 
+		if isPolicyTemplate(pc.Prefix) {
+			if _, _, err := parsePolicyTemplate(pc.Prefix); err != nil {
+				return fmt.Errorf("path %q: %v", key, err)
+			}
+			pc.Templated = true
+		}
+
+		if obj, ok := item.Val.(*ast.ObjectType); ok {
+			if o := obj.List.Filter("control_group"); len(o.Items) > 0 {
+				if len(o.Items) > 1 {
+					return fmt.Errorf("path %q: only one control_group can be set", key)
+				}
+				cg, err := parseControlGroup(o.Items[0].Val)
+				if err != nil {
+					return multierror.Prefix(err, fmt.Sprintf("path %q: control_group:", key))
+				}
+				pc.Permissions.ControlGroup = cg
+			}
+		}
+
 		// Map old-style policies into capabilities
 		if len(pc.Policy) > 0 {
 			switch pc.Policy {
@@ -105,6 +105,10 @@ var (
 type PolicyStore struct {
 	view *BarrierView
 	lru  *lru.TwoQueueCache
+
+	// namespacePath is the path of the namespace the policies belong to;
+	// the paths of the policies are relative to it
+	namespacePath string
 }
 
 // PolicyEntry is used to store a policy by name
@@ -332,6 +336,12 @@ func (ps *PolicyStore) DeletePolicy(name string) error {
 // ACL is used to return an ACL which is built using the
 // named policies.
 func (ps *PolicyStore) ACL(names ...string) (*ACL, error) {
+	return ps.templatedACL(nil, names...)
+}
+
+// templatedACL returns an ACL built using the named policies, with their
+// templated paths rendered using the given data
+func (ps *PolicyStore) templatedACL(data *policyTemplateData, names ...string) (*ACL, error) {
 	// Fetch the policies
 	var policy []*Policy
 	for _, name := range names {
@@ -343,10 +353,11 @@ func (ps *PolicyStore) ACL(names ...string) (*ACL, error) {
 	}
 
 	// Construct the ACL
-	acl, err := NewACL(policy)
+	acl, err := newACL(policy, data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct ACL: %v", err)
 	}
+	acl.namespacePath = ps.namespacePath
 	return acl, nil
 }
 
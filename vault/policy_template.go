@@ -0,0 +1,155 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	policyTemplateOpen  = "{{"
+	policyTemplateClose = "}}"
+)
+
+// policyTemplateData holds the values the templated paths of a policy are
+// rendered with when building the ACL of a token
+type policyTemplateData struct {
+	token  *TokenEntry
+	entity *IdentityEntity
+}
+
+// lookup returns the value of a template parameter, and false if the
+// parameter refers to a value that is not set
+func (d *policyTemplateData) lookup(param string) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+
+	var value string
+	switch {
+	case param == "token.display_name", param == "auth.display_name":
+		if d.token != nil {
+			value = d.token.DisplayName
+		}
+	case param == "token.entity_id", param == "auth.entity_id":
+		if d.token != nil {
+			value = d.token.EntityID
+		}
+	case strings.HasPrefix(param, "token.metadata."), strings.HasPrefix(param, "auth.metadata."):
+		// Metadata of tokens created through the token store is chosen by
+		// the creator, so it cannot be trusted to scope anything
+		if d.token != nil && d.token.AuthMeta {
+			value = d.token.Meta[param[strings.Index(param, "metadata.")+len("metadata."):]]
+		}
+	case param == "identity.entity.id":
+		if d.entity != nil {
+			value = d.entity.ID
+		}
+	case param == "identity.entity.name":
+		if d.entity != nil {
+			value = d.entity.Name
+		}
+	case strings.HasPrefix(param, "identity.entity.metadata."):
+		if d.entity != nil {
+			value = d.entity.Metadata[strings.TrimPrefix(param, "identity.entity.metadata.")]
+		}
+	}
+
+	// An empty value would widen the path, and a value with path separators
+	// or glob characters could reach outside of it, so both count as missing
+	if value == "" || strings.ContainsAny(value, "/*+") {
+		return "", false
+	}
+	return value, true
+}
+
+// validPolicyTemplateParam checks if a template parameter is one that can be
+// rendered
+func validPolicyTemplateParam(param string) bool {
+	switch param {
+	case "token.display_name", "auth.display_name",
+		"token.entity_id", "auth.entity_id",
+		"identity.entity.id", "identity.entity.name":
+		return true
+	}
+	for _, prefix := range []string{"token.metadata.", "auth.metadata.", "identity.entity.metadata."} {
+		if strings.HasPrefix(param, prefix) && len(param) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePolicyTemplate splits a templated path into its literal parts and
+// the parameters between them. There is always one more part than there
+// are parameters.
+func parsePolicyTemplate(path string) ([]string, []string, error) {
+	var parts, params []string
+	for {
+		start := strings.Index(path, policyTemplateOpen)
+		if start == -1 {
+			if strings.Contains(path, policyTemplateClose) {
+				return nil, nil, fmt.Errorf("unmatched %q", policyTemplateClose)
+			}
+			return append(parts, path), params, nil
+		}
+
+		end := strings.Index(path[start:], policyTemplateClose)
+		if end == -1 {
+			return nil, nil, fmt.Errorf("unmatched %q", policyTemplateOpen)
+		}
+		end += start
+
+		param := strings.TrimSpace(path[start+len(policyTemplateOpen) : end])
+		if !validPolicyTemplateParam(param) {
+			return nil, nil, fmt.Errorf("invalid template parameter %q", param)
+		}
+
+		parts = append(parts, path[:start])
+		params = append(params, param)
+		path = path[end+len(policyTemplateClose):]
+	}
+}
+
+// isPolicyTemplate checks if a path contains template parameters
+func isPolicyTemplate(path string) bool {
+	return strings.Contains(path, policyTemplateOpen) || strings.Contains(path, policyTemplateClose)
+}
+
+// renderPolicyTemplate renders a templated path with the given data. The
+// second return value is false if any parameter refers to a missing value,
+// in which case the path must not grant anything.
+func renderPolicyTemplate(path string, data *policyTemplateData) (string, bool, error) {
+	parts, params, err := parsePolicyTemplate(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	var rendered bytes.Buffer
+	for i, param := range params {
+		value, ok := data.lookup(param)
+		if !ok {
+			return "", false, nil
+		}
+		rendered.WriteString(parts[i])
+		rendered.WriteString(value)
+	}
+	rendered.WriteString(parts[len(parts)-1])
+	return rendered.String(), true, nil
+}
+
+// policyTemplateData returns the data used to render the templated policy
+// paths of the given token
+func (c *Core) policyTemplateData(te *TokenEntry) (*policyTemplateData, error) {
+	data := &policyTemplateData{
+		token: te,
+	}
+	if te.EntityID != "" && c.identityStore != nil {
+		entity, err := c.identityStore.entityByID(te.EntityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch entity: %v", err)
+		}
+		data.entity = entity
+	}
+	return data, nil
+}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -312,3 +313,87 @@ path "/" {
 		t.Errorf("bad error: %s", err)
 	}
 }
+
+func TestPolicy_ParseTemplated(t *testing.T) {
+	p, err := Parse(strings.TrimSpace(`
+path "secret/{{token.metadata.app}}/*" {
+	capabilities = ["read"]
+}
+path "secret/static/*" {
+	capabilities = ["read"]
+}
+`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !p.Paths[0].Templated || p.Paths[0].Prefix != "secret/{{token.metadata.app}}/" || !p.Paths[0].Glob {
+		t.Fatalf("bad: %#v", p.Paths[0])
+	}
+	if p.Paths[1].Templated {
+		t.Fatalf("bad: %#v", p.Paths[1])
+	}
+
+	for _, path := range []string{
+		"secret/{{token.metadata}}",
+		"secret/{{token.policies}}",
+		"secret/{{token.metadata.app",
+		"secret/token.metadata.app}}",
+	} {
+		_, err := Parse(fmt.Sprintf(`path %q { capabilities = ["read"] }`, path))
+		if err == nil {
+			t.Fatalf("%s: expected error", path)
+		}
+	}
+}
+
+func TestPolicy_ParseControlGroup(t *testing.T) {
+	p, err := Parse(strings.TrimSpace(`
+path "secret/prod/*" {
+	capabilities = ["read"]
+	control_group = {
+		ttl = "4h"
+		factor "managers" {
+			policies = ["manager"]
+			approvals = 2
+		}
+		factor "security" {
+			group_names = ["security"]
+		}
+	}
+}
+`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expect := &ControlGroup{
+		TTL: 4 * time.Hour,
+		Factors: []*ControlGroupFactor{
+			&ControlGroupFactor{
+				Name:      "managers",
+				Policies:  []string{"manager"},
+				Approvals: 2,
+			},
+			&ControlGroupFactor{
+				Name:       "security",
+				GroupNames: []string{"security"},
+				Approvals:  1,
+			},
+		},
+	}
+	if !reflect.DeepEqual(p.Paths[0].Permissions.ControlGroup, expect) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v", expect, p.Paths[0].Permissions.ControlGroup)
+	}
+
+	for name, cg := range map[string]string{
+		"no factor":     `control_group = { ttl = "1h" }`,
+		"no approvers":  `control_group = { factor "a" { approvals = 1 } }`,
+		"bad approvals": `control_group = { factor "a" { policies = ["a"] approvals = -1 } }`,
+		"bad key":       `control_group = { factor "a" { policies = ["a"] foo = 1 } }`,
+		"bad ttl":       `control_group = { ttl = "foo" factor "a" { policies = ["a"] } }`,
+	} {
+		_, err := Parse(fmt.Sprintf(`path "secret/*" { capabilities = ["read"] %s }`, cg))
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/consts"
 	"github.com/hashicorp/vault/helper/jsonutil"
@@ -21,7 +22,7 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 	if c.sealed {
 		return nil, consts.ErrSealed
 	}
-	if c.standby {
+	if c.standby && !c.standbyReadAllowed(req) {
 		return nil, consts.ErrStandby
 	}
 
@@ -37,6 +38,14 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		return logical.ErrorResponse("cannot write to a path ending in '/'"), nil
 	}
 
+	// Child namespaces only expose part of the system and token backends they
+	// share with the root namespace
+	if ns := c.namespaceByPath(req.Path); !ns.isRoot() {
+		if rel, _ := ns.relativePath(req.Path); !namespacePathAllowed(rel) {
+			return logical.ErrorResponse(fmt.Sprintf("path %q is not available within a namespace", rel)), logical.ErrUnsupportedPath
+		}
+	}
+
 	var auth *logical.Auth
 	if c.router.LoginPath(req.Path) {
 		resp, auth, err = c.handleLoginRequest(req)
@@ -44,6 +53,12 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		resp, auth, err = c.handleRequest(req)
 	}
 
+	// Requests a standby turns out not to be able to serve go to the
+	// active node
+	if standbyErr := c.checkStandbyRequest(err); standbyErr != nil {
+		return nil, standbyErr
+	}
+
 	// Ensure we don't leak internal data
 	if resp != nil {
 		if resp.Secret != nil {
@@ -62,7 +77,8 @@ func (c *Core) HandleRequest(req *logical.Request) (resp *logical.Response, err
 		err == nil &&
 		!resp.IsError() &&
 		resp.WrapInfo != nil &&
-		resp.WrapInfo.TTL != 0
+		resp.WrapInfo.TTL != 0 &&
+		resp.WrapInfo.Token == ""
 
 	if wrapping {
 		cubbyResp, cubbyErr := c.wrapInCubbyhole(req, resp)
@@ -115,7 +131,12 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 	defer metrics.MeasureSince([]string{"core", "handle_request"}, time.Now())
 
 	// Validate the token
-	auth, te, ctErr := c.checkToken(req)
+	auth, te, cg, ctErr := c.checkToken(req)
+	// Tokens with limited uses are decremented on the active node
+	if c.standby && te != nil && te.NumUses != 0 {
+		return nil, nil, consts.ErrStandby
+	}
+
 	// We run this logic first because we want to decrement the use count even in the case of an error
 	if te != nil {
 		// Attempt to use the token (decrement NumUses)
@@ -176,13 +197,45 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 	// Attach the display name
 	req.DisplayName = auth.DisplayName
 
-	// Create an audit trail of the request
-	if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, nil); err != nil {
+	// Create an audit trail of the request. A standby only knows whether it
+	// serves the request itself once the request has been handled, and leaves
+	// the audit of the requests it forwards to the active node.
+	if c.standby {
+		refused := c.standbyRefusedWrites()
+		defer func() {
+			// Requests that attempted to write storage are forwarded
+			if c.standbyRefusedWrites() != refused {
+				retResp, retAuth, retErr = nil, nil, consts.ErrStandby
+				return
+			}
+			if retErr != nil && errwrap.Contains(retErr, consts.ErrStandby.Error()) {
+				return
+			}
+			if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, nil); err != nil {
+				c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
+				retResp = nil
+				retErr = multierror.Append(retErr, ErrInternalError)
+			}
+		}()
+	} else if err := c.auditBroker.LogRequest(auth, req, c.auditedHeaders, nil); err != nil {
 		c.logger.Error("core: failed to audit request", "path", req.Path, "error", err)
 		retErr = multierror.Append(retErr, ErrInternalError)
 		return nil, auth, retErr
 	}
 
+	// Requests to control grouped paths are held until they are authorized,
+	// and replayed when the returned token is unwrapped
+	if cg != nil && !req.ControlGroupApproved() {
+		if c.standby {
+			return nil, nil, consts.ErrStandby
+		}
+		resp, err := c.holdControlGroupRequest(req, te, cg)
+		if err != nil {
+			retErr = multierror.Append(retErr, err)
+		}
+		return resp, auth, retErr
+	}
+
 	// Route the request
 	resp, routeErr := c.router.Route(req)
 	if resp != nil {
@@ -261,6 +314,10 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 			}
 		}
 
+		if registerLease && c.standby {
+			return nil, auth, consts.ErrStandby
+		}
+
 		if registerLease {
 			leaseID, err := c.expiration.Register(req, resp)
 			if err != nil {
@@ -275,13 +332,18 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 	// Only the token store is allowed to return an auth block, for any
 	// other request this is an internal error. We exclude renewal of a token,
 	// since it does not need to be re-registered
-	if resp != nil && resp.Auth != nil && !strings.HasPrefix(req.Path, "auth/token/renew") {
-		if !strings.HasPrefix(req.Path, "auth/token/") {
+	authPath, _ := c.namespaceByPath(req.Path).relativePath(req.Path)
+	if resp != nil && resp.Auth != nil && !strings.HasPrefix(authPath, "auth/token/renew") {
+		if !strings.HasPrefix(authPath, "auth/token/") {
 			c.logger.Error("core: unexpected Auth response for non-token backend", "request_path", req.Path)
 			retErr = multierror.Append(retErr, ErrInternalError)
 			return nil, auth, retErr
 		}
 
+		if c.standby {
+			return nil, auth, consts.ErrStandby
+		}
+
 		// Register with the expiration manager. We use the token's actual path
 		// here because roles allow suffixes.
 		te, err := c.tokenStore.Lookup(resp.Auth.ClientToken)
@@ -291,10 +353,13 @@ func (c *Core) handleRequest(req *logical.Request) (retResp *logical.Response, r
 			return nil, nil, retErr
 		}
 
-		if err := c.expiration.RegisterAuth(te.Path, resp.Auth); err != nil {
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
-			retErr = multierror.Append(retErr, ErrInternalError)
-			return nil, auth, retErr
+		// Batch tokens expire on their own and have no lease
+		if te.Type != logical.TokenTypeBatch {
+			if err := c.expiration.RegisterAuth(te.Path, resp.Auth); err != nil {
+				c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+				retErr = multierror.Append(retErr, ErrInternalError)
+				return nil, auth, retErr
+			}
 		}
 	}
 
@@ -323,9 +388,13 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 		return nil, nil, ErrInternalError
 	}
 
+	// Logins create tokens in the namespace of the credential backend
+	ns := c.namespaceByPath(req.Path)
+	loginPath, _ := ns.relativePath(req.Path)
+
 	// The token store uses authentication even when creating a new token,
 	// so it's handled in handleRequest. It should not be reached here.
-	if strings.HasPrefix(req.Path, "auth/token/") {
+	if strings.HasPrefix(loginPath, "auth/token/") {
 		c.logger.Error("core: unexpected login request for token backend", "request_path", req.Path)
 		return nil, nil, ErrInternalError
 	}
@@ -384,7 +453,7 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 		}
 
 		// Determine the source of the login
-		source := c.router.MatchingMount(req.Path)
+		source := strings.TrimPrefix(c.router.MatchingMount(req.Path), ns.Path)
 		source = strings.TrimPrefix(source, credentialRoutePrefix)
 		source = strings.Replace(source, "/", "-", -1)
 
@@ -412,10 +481,23 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 			Path:         req.Path,
 			Policies:     auth.Policies,
 			Meta:         auth.Metadata,
+			AuthMeta:     true,
 			DisplayName:  auth.DisplayName,
 			CreationTime: time.Now().Unix(),
 			TTL:          auth.TTL,
 			NumUses:      auth.NumUses,
+			NamespaceID:  ns.entryID(),
+		}
+
+		tokenType, err := logical.ParseTokenType(string(auth.TokenType))
+		if err != nil {
+			c.logger.Error("core: invalid token type in login response", "request_path", req.Path, "error", err)
+			return nil, nil, ErrInternalError
+		}
+		auth.TokenType = tokenType
+		if tokenType == logical.TokenTypeBatch {
+			te.Type = logical.TokenTypeBatch
+			auth.Renewable = false
 		}
 
 		te.Policies = policyutil.SanitizePolicies(te.Policies, true)
@@ -427,6 +509,27 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 			}
 		}
 
+		// Map the authenticated user to its identity entity. Entities and
+		// their policies belong to the root namespace.
+		if auth.Alias != nil && c.identityStore != nil && ns.isRoot() {
+			mountEntry := c.router.MatchingMountEntry(req.Path)
+			if mountEntry == nil {
+				c.logger.Error("core: unable to look up mount entry for login path", "request_path", req.Path)
+				return nil, nil, ErrInternalError
+			}
+			entity, err := c.identityStore.CreateOrFetchEntity(mountEntry.Accessor, mountEntry.Type, auth.Alias)
+			if err != nil {
+				c.logger.Error("core: failed to fetch identity entity", "request_path", req.Path, "error", err)
+				return nil, nil, ErrInternalError
+			}
+			te.EntityID = entity.ID
+		}
+
+		// The lease keeps the policies granted by the backend, which it
+		// compares against on renewal; the entity policies are only merged
+		// into the token itself
+		auth.Policies = te.Policies
+
 		if err := c.tokenStore.create(&te); err != nil {
 			c.logger.Error("core: failed to create token", "error", err)
 			return nil, auth, ErrInternalError
@@ -435,12 +538,14 @@ func (c *Core) handleLoginRequest(req *logical.Request) (*logical.Response, *log
 		// Populate the client token and accessor
 		auth.ClientToken = te.ID
 		auth.Accessor = te.Accessor
-		auth.Policies = te.Policies
 
-		// Register with the expiration manager
-		if err := c.expiration.RegisterAuth(te.Path, auth); err != nil {
-			c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
-			return nil, auth, ErrInternalError
+		// Register with the expiration manager; batch tokens expire on their
+		// own
+		if te.Type != logical.TokenTypeBatch {
+			if err := c.expiration.RegisterAuth(te.Path, auth); err != nil {
+				c.logger.Error("core: failed to register token lease", "request_path", req.Path, "error", err)
+				return nil, auth, ErrInternalError
+			}
 		}
 
 		// Attach the display name, might be used by audit backends
@@ -22,13 +22,17 @@ type Router struct {
 	// to the backend. This is used to map a key back into the backend that owns it.
 	// For example, logical/uuid1/foobar -> secrets/ (generic backend) + foobar
 	storagePrefix *radix.Tree
+
+	// mountAccessorCache maps the accessor of a mount to its route entry
+	mountAccessorCache *radix.Tree
 }
 
 // NewRouter returns a new router
 func NewRouter() *Router {
 	r := &Router{
-		root:          radix.New(),
-		storagePrefix: radix.New(),
+		root:               radix.New(),
+		storagePrefix:      radix.New(),
+		mountAccessorCache: radix.New(),
 	}
 	return r
 }
@@ -41,6 +45,16 @@ type routeEntry struct {
 	storageView *BarrierView
 	rootPaths   *radix.Tree
 	loginPaths  *radix.Tree
+
+	// standbyPaths are the paths a standby node may serve locally
+	standbyPaths *radix.Tree
+
+	// rawBodyPaths are the paths whose request bodies are not parsed
+	rawBodyPaths *radix.Tree
+
+	// aliasOf is set to the prefix of the original mount when this entry
+	// exposes a backend at an additional prefix
+	aliasOf string
 }
 
 // SaltID is used to apply a salt and hash to an ID to make sure its not reversible
@@ -73,13 +87,42 @@ func (r *Router) Mount(backend logical.Backend, prefix string, mountEntry *Mount
 		storageView: storageView,
 		rootPaths:   pathsToRadix(paths.Root),
 		loginPaths:  pathsToRadix(paths.Unauthenticated),
+
+		standbyPaths: pathsToRadix(paths.StandbyRead),
+		rawBodyPaths: pathsToRadix(paths.RawRequestBody),
 	}
 	r.root.Insert(prefix, re)
 	r.storagePrefix.Insert(storageView.prefix, re)
+	if mountEntry.Accessor != "" {
+		r.mountAccessorCache.Insert(mountEntry.Accessor, re)
+	}
 
 	return nil
 }
 
+// MountAlias exposes the backend mounted at src at an additional prefix. The
+// alias shares the backend, mount entry and storage of the original mount;
+// unmounting the alias leaves the original mount untouched.
+func (r *Router) MountAlias(src, prefix string) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	raw, ok := r.root.Get(src)
+	if !ok {
+		return fmt.Errorf("no mount at '%s'", src)
+	}
+
+	// Check if this is a nested mount
+	if existing, _, ok := r.root.LongestPrefix(prefix); ok && existing != "" {
+		return fmt.Errorf("cannot mount under existing mount '%s'", existing)
+	}
+
+	re := *raw.(*routeEntry)
+	re.aliasOf = src
+	r.root.Insert(prefix, &re)
+	return nil
+}
+
 // Unmount is used to remove a logical backend from a given prefix
 func (r *Router) Unmount(prefix string) error {
 	r.l.Lock()
@@ -91,13 +134,22 @@ func (r *Router) Unmount(prefix string) error {
 		return nil
 	}
 
-	// Call backend's Cleanup routine
+	// Aliases share the backend of the original mount
 	re := raw.(*routeEntry)
+	if re.aliasOf != "" {
+		r.root.Delete(prefix)
+		return nil
+	}
+
+	// Call backend's Cleanup routine
 	re.backend.Cleanup()
 
 	// Purge from the radix trees
 	r.root.Delete(prefix)
 	r.storagePrefix.Delete(re.storageView.prefix)
+	if re.mountEntry.Accessor != "" {
+		r.mountAccessorCache.Delete(re.mountEntry.Accessor)
+	}
 	return nil
 }
 
@@ -174,6 +226,22 @@ func (r *Router) MatchingMountEntry(path string) *MountEntry {
 	return raw.(*routeEntry).mountEntry
 }
 
+// MatchingMountByAccessor returns the MountEntry of the mount with the given
+// accessor
+func (r *Router) MatchingMountByAccessor(accessor string) *MountEntry {
+	if accessor == "" {
+		return nil
+	}
+
+	r.l.RLock()
+	raw, ok := r.mountAccessorCache.Get(accessor)
+	r.l.RUnlock()
+	if !ok {
+		return nil
+	}
+	return raw.(*routeEntry).mountEntry
+}
+
 // MatchingMountEntry returns the MountEntry used for a path
 func (r *Router) MatchingBackend(path string) logical.Backend {
 	r.l.RLock()
@@ -264,12 +332,18 @@ func (r *Router) routeCommon(req *logical.Request, existenceCheck bool) (*logica
 	// Attach the storage view for the request
 	req.Storage = re.storageView
 
+	// Aliases are treated as the mount they expose
+	routedPath := originalPath
+	if re.aliasOf != "" {
+		routedPath = re.aliasOf + strings.TrimPrefix(originalPath, mount)
+	}
+
 	// Hash the request token unless this is the token backend
 	clientToken := req.ClientToken
 	switch {
-	case strings.HasPrefix(originalPath, "auth/token/"):
-	case strings.HasPrefix(originalPath, "sys/"):
-	case strings.HasPrefix(originalPath, "cubbyhole/"):
+	case strings.HasPrefix(routedPath, "auth/token/"):
+	case strings.HasPrefix(routedPath, "sys/"):
+	case strings.HasPrefix(routedPath, "cubbyhole/"):
 		// In order for the token store to revoke later, we need to have the same
 		// salted ID, so we double-salt what's going to the cubbyhole backend
 		req.ClientToken = re.SaltID(r.tokenStoreSalt.SaltID(req.ClientToken))
@@ -384,6 +458,65 @@ func (r *Router) LoginPath(path string) bool {
 	return match == remain
 }
 
+// StandbyReadPath checks if the given path may be served by a standby node
+func (r *Router) StandbyReadPath(path string) bool {
+	r.l.RLock()
+	mount, raw, ok := r.root.LongestPrefix(path)
+	r.l.RUnlock()
+	if !ok {
+		return false
+	}
+	re := raw.(*routeEntry)
+
+	// Trim to get remaining path
+	remain := strings.TrimPrefix(path, mount)
+
+	// Check the standbyPaths of this backend
+	match, raw, ok := re.standbyPaths.LongestPrefix(remain)
+	if !ok {
+		return false
+	}
+	prefixMatch := raw.(bool)
+
+	// Handle the prefix match case
+	if prefixMatch {
+		return strings.HasPrefix(remain, match)
+	}
+
+	// Handle the exact match case
+	return match == remain
+}
+
+// RawRequestBodyPath checks if the body of requests to the given path is
+// passed to the backend unparsed
+func (r *Router) RawRequestBodyPath(path string) bool {
+	r.l.RLock()
+	mount, raw, ok := r.root.LongestPrefix(path)
+	r.l.RUnlock()
+	if !ok {
+		return false
+	}
+	re := raw.(*routeEntry)
+
+	// Trim to get remaining path
+	remain := strings.TrimPrefix(path, mount)
+
+	// Check the rawBodyPaths of this backend
+	match, raw, ok := re.rawBodyPaths.LongestPrefix(remain)
+	if !ok {
+		return false
+	}
+	prefixMatch := raw.(bool)
+
+	// Handle the prefix match case
+	if prefixMatch {
+		return strings.HasPrefix(remain, match)
+	}
+
+	// Handle the exact match case
+	return match == remain
+}
+
 // pathsToRadix converts a the mapping of special paths to a mapping
 // of special paths to radix trees.
 func pathsToRadix(paths []string) *radix.Tree {
@@ -26,6 +26,11 @@ const (
 
 	// recoveryKeyPath is the path to the recovery key
 	recoveryKeyPath = "core/recovery-key"
+
+	// storedBarrierKeysPath is the path used to store the barrier unseal keys
+	// of seals supporting stored keys. The keys are encrypted by the seal,
+	// since they are stored in plaintext outside of the barrier.
+	storedBarrierKeysPath = "core/stored-barrier-keys"
 )
 
 type KeyNotFoundError struct {
@@ -114,16 +119,50 @@ func (d *DefaultSeal) BarrierConfig() (*SealConfig, error) {
 		return nil, err
 	}
 
+	conf, err := readBarrierConfig(d.core, d.BarrierType())
+	if err != nil || conf == nil {
+		return nil, err
+	}
+
+	d.config = conf
+	return d.config.Clone(), nil
+}
+
+func (d *DefaultSeal) SetBarrierConfig(config *SealConfig) error {
+	if err := d.checkCore(); err != nil {
+		return err
+	}
+
+	// Provide a way to wipe out the cached value (also prevents actually
+	// saving a nil config)
+	if config == nil {
+		d.config = nil
+		return nil
+	}
+
+	if err := writeBarrierConfig(d.core, d.BarrierType(), config); err != nil {
+		return err
+	}
+
+	d.config = config.Clone()
+
+	return nil
+}
+
+// readBarrierConfig reads the barrier seal configuration from the physical
+// storage of the core, checking that it was written by a seal of the given
+// type. It returns nil if Vault is not initialized.
+func readBarrierConfig(core *Core, sealType string) (*SealConfig, error) {
 	// Fetch the core configuration
-	pe, err := d.core.physical.Get(barrierSealConfigPath)
+	pe, err := core.physical.Get(barrierSealConfigPath)
 	if err != nil {
-		d.core.logger.Error("core: failed to read seal configuration", "error", err)
+		core.logger.Error("core: failed to read seal configuration", "error", err)
 		return nil, fmt.Errorf("failed to check seal configuration: %v", err)
 	}
 
 	// If the seal configuration is missing, we are not initialized
 	if pe == nil {
-		d.core.logger.Info("core: seal configuration missing, not initialized")
+		core.logger.Info("core: seal configuration missing, not initialized")
 		return nil, nil
 	}
 
@@ -131,43 +170,33 @@ func (d *DefaultSeal) BarrierConfig() (*SealConfig, error) {
 
 	// Decode the barrier entry
 	if err := jsonutil.DecodeJSON(pe.Value, &conf); err != nil {
-		d.core.logger.Error("core: failed to decode seal configuration", "error", err)
+		core.logger.Error("core: failed to decode seal configuration", "error", err)
 		return nil, fmt.Errorf("failed to decode seal configuration: %v", err)
 	}
 
-	switch conf.Type {
-	// This case should not be valid for other types as only this is the default
-	case "":
-		conf.Type = d.BarrierType()
-	case d.BarrierType():
-	default:
-		d.core.logger.Error("core: barrier seal type does not match loaded type", "barrier_seal_type", conf.Type, "loaded_seal_type", d.BarrierType())
-		return nil, fmt.Errorf("barrier seal type of %s does not match loaded type of %s", conf.Type, d.BarrierType())
+	// Configurations written before seal types were recorded can only come
+	// from the default seal
+	if conf.Type == "" {
+		conf.Type = "shamir"
+	}
+	if conf.Type != sealType {
+		core.logger.Error("core: barrier seal type does not match loaded type", "barrier_seal_type", conf.Type, "loaded_seal_type", sealType)
+		return nil, fmt.Errorf("barrier seal type of %s does not match loaded type of %s", conf.Type, sealType)
 	}
 
 	// Check for a valid seal configuration
 	if err := conf.Validate(); err != nil {
-		d.core.logger.Error("core: invalid seal configuration", "error", err)
+		core.logger.Error("core: invalid seal configuration", "error", err)
 		return nil, fmt.Errorf("seal validation failed: %v", err)
 	}
 
-	d.config = &conf
-	return d.config.Clone(), nil
+	return &conf, nil
 }
 
-func (d *DefaultSeal) SetBarrierConfig(config *SealConfig) error {
-	if err := d.checkCore(); err != nil {
-		return err
-	}
-
-	// Provide a way to wipe out the cached value (also prevents actually
-	// saving a nil config)
-	if config == nil {
-		d.config = nil
-		return nil
-	}
-
-	config.Type = d.BarrierType()
+// writeBarrierConfig stores the barrier seal configuration in the physical
+// storage of the core, recording the type of the seal
+func writeBarrierConfig(core *Core, sealType string, config *SealConfig) error {
+	config.Type = sealType
 
 	// Encode the seal configuration
 	buf, err := json.Marshal(config)
@@ -181,13 +210,11 @@ func (d *DefaultSeal) SetBarrierConfig(config *SealConfig) error {
 		Value: buf,
 	}
 
-	if err := d.core.physical.Put(pe); err != nil {
-		d.core.logger.Error("core: failed to write seal configuration", "error", err)
+	if err := core.physical.Put(pe); err != nil {
+		core.logger.Error("core: failed to write seal configuration", "error", err)
 		return fmt.Errorf("failed to write seal configuration: %v", err)
 	}
 
-	d.config = config.Clone()
-
 	return nil
 }
 
@@ -0,0 +1,414 @@
+package vault
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/jsonutil"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/physical"
+)
+
+// TransitEncrypter encrypts and decrypts data with a key held by a transit
+// backend
+type TransitEncrypter interface {
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
+
+// TransitSeal is a seal which protects the barrier unseal keys with a key of
+// the transit backend of another Vault, allowing Vault to unseal itself on
+// startup. Recovery keys are used in place of the unseal keys to authorize
+// root token generation and rekeying.
+type TransitSeal struct {
+	encrypter TransitEncrypter
+	core      *Core
+
+	// l protects the cached configurations
+	l              sync.Mutex
+	config         *SealConfig
+	recoveryConfig *SealConfig
+}
+
+// storedKeys is the storage format of the stored barrier keys
+type storedKeys struct {
+	Ciphertext string `json:"ciphertext"`
+}
+
+// NewTransitSeal returns a transit seal using the given encrypter
+func NewTransitSeal(encrypter TransitEncrypter) *TransitSeal {
+	return &TransitSeal{
+		encrypter: encrypter,
+	}
+}
+
+// NewTransitSealFromConfig returns a transit seal talking to the Vault
+// described by the given "seal" configuration stanza
+func NewTransitSealFromConfig(conf map[string]string) (*TransitSeal, error) {
+	encrypter, err := newAPITransitEncrypter(conf)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransitSeal(encrypter), nil
+}
+
+func (t *TransitSeal) checkCore() error {
+	if t.core == nil {
+		return fmt.Errorf("seal does not have a core set")
+	}
+	return nil
+}
+
+func (t *TransitSeal) SetCore(core *Core) {
+	t.core = core
+}
+
+func (t *TransitSeal) Init() error {
+	return nil
+}
+
+func (t *TransitSeal) Finalize() error {
+	return nil
+}
+
+func (t *TransitSeal) BarrierType() string {
+	return "transit"
+}
+
+func (t *TransitSeal) StoredKeysSupported() bool {
+	return true
+}
+
+func (t *TransitSeal) RecoveryKeySupported() bool {
+	return true
+}
+
+// SetStoredKeys encrypts the given keys with the transit key and stores the
+// result outside of the barrier
+func (t *TransitSeal) SetStoredKeys(keys [][]byte) error {
+	if err := t.checkCore(); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode keys for storage: %v", err)
+	}
+
+	ciphertext, err := t.encrypter.Encrypt(buf)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt keys for storage: %v", err)
+	}
+
+	value, err := json.Marshal(&storedKeys{
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode keys for storage: %v", err)
+	}
+
+	if err := t.core.physical.Put(&physical.Entry{
+		Key:   storedBarrierKeysPath,
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("failed to write keys to storage: %v", err)
+	}
+
+	return nil
+}
+
+// GetStoredKeys returns the stored keys, decrypted with the transit key
+func (t *TransitSeal) GetStoredKeys() ([][]byte, error) {
+	if err := t.checkCore(); err != nil {
+		return nil, err
+	}
+
+	pe, err := t.core.physical.Get(storedBarrierKeysPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stored keys: %v", err)
+	}
+	if pe == nil {
+		return nil, nil
+	}
+
+	var entry storedKeys
+	if err := jsonutil.DecodeJSON(pe.Value, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode stored keys: %v", err)
+	}
+
+	plaintext, err := t.encrypter.Decrypt(entry.Ciphertext)
+	if err != nil {
+		return nil, &KeyNotFoundError{Err: fmt.Errorf("failed to decrypt stored keys: %v", err)}
+	}
+
+	var keys [][]byte
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode stored keys: %v", err)
+	}
+
+	return keys, nil
+}
+
+func (t *TransitSeal) BarrierConfig() (*SealConfig, error) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	if t.config != nil {
+		return t.config.Clone(), nil
+	}
+
+	if err := t.checkCore(); err != nil {
+		return nil, err
+	}
+
+	conf, err := readBarrierConfig(t.core, t.BarrierType())
+	if err != nil || conf == nil {
+		return nil, err
+	}
+
+	t.config = conf
+	return t.config.Clone(), nil
+}
+
+func (t *TransitSeal) SetBarrierConfig(config *SealConfig) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	if err := t.checkCore(); err != nil {
+		return err
+	}
+
+	// Provide a way to wipe out the cached value (also prevents actually
+	// saving a nil config)
+	if config == nil {
+		t.config = nil
+		return nil
+	}
+
+	if err := writeBarrierConfig(t.core, t.BarrierType(), config); err != nil {
+		return err
+	}
+
+	t.config = config.Clone()
+
+	return nil
+}
+
+func (t *TransitSeal) RecoveryType() string {
+	return "shamir"
+}
+
+// RecoveryConfig returns the recovery key configuration, which is stored
+// inside the barrier
+func (t *TransitSeal) RecoveryConfig() (*SealConfig, error) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	if t.recoveryConfig != nil {
+		return t.recoveryConfig.Clone(), nil
+	}
+
+	if err := t.checkCore(); err != nil {
+		return nil, err
+	}
+
+	entry, err := t.core.barrier.Get(recoverySealConfigPath)
+	if err != nil {
+		t.core.logger.Error("core: failed to read recovery configuration", "error", err)
+		return nil, fmt.Errorf("failed to read recovery configuration: %v", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var conf SealConfig
+	if err := jsonutil.DecodeJSON(entry.Value, &conf); err != nil {
+		t.core.logger.Error("core: failed to decode recovery configuration", "error", err)
+		return nil, fmt.Errorf("failed to decode recovery configuration: %v", err)
+	}
+
+	if conf.Type != t.RecoveryType() {
+		return nil, fmt.Errorf("recovery seal type of %s does not match loaded type of %s", conf.Type, t.RecoveryType())
+	}
+
+	t.recoveryConfig = &conf
+	return t.recoveryConfig.Clone(), nil
+}
+
+func (t *TransitSeal) SetRecoveryConfig(config *SealConfig) error {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	if err := t.checkCore(); err != nil {
+		return err
+	}
+
+	// Provide a way to wipe out the cached value (also prevents actually
+	// saving a nil config)
+	if config == nil {
+		t.recoveryConfig = nil
+		return nil
+	}
+
+	config.Type = t.RecoveryType()
+
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode recovery configuration: %v", err)
+	}
+
+	if err := t.core.barrier.Put(&Entry{
+		Key:   recoverySealConfigPath,
+		Value: buf,
+	}); err != nil {
+		t.core.logger.Error("core: failed to write recovery configuration", "error", err)
+		return fmt.Errorf("failed to write recovery configuration: %v", err)
+	}
+
+	t.recoveryConfig = config.Clone()
+
+	return nil
+}
+
+// SetRecoveryKey stores the recovery key inside the barrier
+func (t *TransitSeal) SetRecoveryKey(key []byte) error {
+	if err := t.checkCore(); err != nil {
+		return err
+	}
+
+	if err := t.core.barrier.Put(&Entry{
+		Key:   recoveryKeyPath,
+		Value: key,
+	}); err != nil {
+		t.core.logger.Error("core: failed to write recovery key", "error", err)
+		return fmt.Errorf("failed to write recovery key: %v", err)
+	}
+
+	return nil
+}
+
+// VerifyRecoveryKey checks the given key against the stored recovery key
+func (t *TransitSeal) VerifyRecoveryKey(key []byte) error {
+	if err := t.checkCore(); err != nil {
+		return err
+	}
+
+	entry, err := t.core.barrier.Get(recoveryKeyPath)
+	if err != nil {
+		t.core.logger.Error("core: failed to read recovery key", "error", err)
+		return fmt.Errorf("failed to read recovery key: %v", err)
+	}
+	if entry == nil {
+		return fmt.Errorf("no recovery key found")
+	}
+
+	if subtle.ConstantTimeCompare(entry.Value, key) != 1 {
+		return fmt.Errorf("recovery key verification failed")
+	}
+
+	return nil
+}
+
+// apiTransitEncrypter uses the transit backend of a remote Vault through its
+// HTTP API
+type apiTransitEncrypter struct {
+	client    *api.Client
+	mountPath string
+	keyName   string
+}
+
+func newAPITransitEncrypter(conf map[string]string) (*apiTransitEncrypter, error) {
+	keyName := conf["key_name"]
+	if keyName == "" {
+		return nil, errors.New("key_name is required")
+	}
+
+	mountPath := conf["mount_path"]
+	if mountPath == "" {
+		mountPath = "transit"
+	}
+	mountPath = strings.Trim(mountPath, "/")
+
+	apiConfig := api.DefaultConfig()
+	if addr := conf["address"]; addr != "" {
+		apiConfig.Address = addr
+	}
+
+	tlsConfig := &api.TLSConfig{
+		CACert:        conf["tls_ca_cert"],
+		ClientCert:    conf["tls_client_cert"],
+		ClientKey:     conf["tls_client_key"],
+		TLSServerName: conf["tls_server_name"],
+	}
+	if raw, ok := conf["tls_skip_verify"]; ok {
+		skip, err := parseutil.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for tls_skip_verify: %v", err)
+		}
+		tlsConfig.Insecure = skip
+	}
+	if err := apiConfig.ConfigureTLS(tlsConfig); err != nil {
+		return nil, err
+	}
+
+	client, err := api.NewClient(apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	if token := conf["token"]; token != "" {
+		client.SetToken(token)
+	}
+	if client.Token() == "" {
+		return nil, errors.New("missing token for the transit seal")
+	}
+
+	return &apiTransitEncrypter{
+		client:    client,
+		mountPath: mountPath,
+		keyName:   keyName,
+	}, nil
+}
+
+func (e *apiTransitEncrypter) Encrypt(plaintext []byte) (string, error) {
+	secret, err := e.client.Logical().Write(fmt.Sprintf("%s/encrypt/%s", e.mountPath, e.keyName), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	})
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", errors.New("empty response from transit backend")
+	}
+
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok || ciphertext == "" {
+		return "", errors.New("no ciphertext in response from transit backend")
+	}
+
+	return ciphertext, nil
+}
+
+func (e *apiTransitEncrypter) Decrypt(ciphertext string) ([]byte, error) {
+	secret, err := e.client.Logical().Write(fmt.Sprintf("%s/decrypt/%s", e.mountPath, e.keyName), map[string]interface{}{
+		"ciphertext": ciphertext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("empty response from transit backend")
+	}
+
+	plaintext, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, errors.New("no plaintext in response from transit backend")
+	}
+
+	return base64.StdEncoding.DecodeString(plaintext)
+}
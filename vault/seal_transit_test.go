@@ -0,0 +1,231 @@
+package vault
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/vault/builtin/logical/transit"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// coreTransitEncrypter uses the transit backend of an in-process core,
+// which plays the remote Vault
+type coreTransitEncrypter struct {
+	core  *Core
+	token string
+}
+
+func (e *coreTransitEncrypter) Encrypt(plaintext []byte) (string, error) {
+	resp, err := e.core.HandleRequest(&logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "transit/encrypt/unseal",
+		ClientToken: e.token,
+		Data: map[string]interface{}{
+			"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Data["ciphertext"].(string), nil
+}
+
+func (e *coreTransitEncrypter) Decrypt(ciphertext string) ([]byte, error) {
+	resp, err := e.core.HandleRequest(&logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "transit/decrypt/unseal",
+		ClientToken: e.token,
+		Data: map[string]interface{}{
+			"ciphertext": ciphertext,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data["plaintext"] == nil {
+		return nil, fmt.Errorf("bad response: %#v", resp)
+	}
+	return base64.StdEncoding.DecodeString(resp.Data["plaintext"].(string))
+}
+
+// testTransitRemote returns an unsealed core with a transit key named
+// "unseal", along with its root token
+func testTransitRemote(t *testing.T) (*Core, string) {
+	remote, _, root := TestCoreUnsealed(t)
+	remote.logicalBackends["transit"] = transit.Factory
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/transit")
+	req.ClientToken = root
+	req.Data["type"] = "transit"
+	if _, err := remote.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "transit/keys/unseal")
+	req.ClientToken = root
+	if _, err := remote.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	return remote, root
+}
+
+func testTransitSealCore(t *testing.T, backend physical.Backend, encrypter TransitEncrypter) (*Core, error) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	conf := testCoreConfig(t, backend, logger)
+	conf.Seal = NewTransitSeal(encrypter)
+
+	return NewCore(conf)
+}
+
+func TestTransitSeal_Lifecycle(t *testing.T) {
+	remote, remoteRoot := testTransitRemote(t)
+	encrypter := &coreTransitEncrypter{core: remote, token: remoteRoot}
+
+	backend := physical.NewInmem(logformat.NewVaultLogger(log.LevelTrace))
+	c, err := testTransitSealCore(t, backend, encrypter)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	result, err := c.Initialize(&InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			StoredShares:    1,
+		},
+		RecoveryConfig: &SealConfig{
+			SecretShares:    5,
+			SecretThreshold: 3,
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(result.SecretShares) != 0 {
+		t.Fatalf("expected no unseal keys to be returned: %#v", result.SecretShares)
+	}
+	if len(result.RecoveryShares) != 5 {
+		t.Fatalf("expected five recovery keys: %#v", result.RecoveryShares)
+	}
+
+	// The stored key is encrypted by the remote transit key
+	pe, err := backend.Get(storedBarrierKeysPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pe == nil {
+		t.Fatal("expected stored keys")
+	}
+
+	if err := c.UnsealWithStoredKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := c.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+
+	conf, err := c.seal.BarrierConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if conf.Type != "transit" || conf.StoredShares != 1 {
+		t.Fatalf("bad: %#v", conf)
+	}
+
+	// A restarted core unseals itself
+	if err := c.Seal(result.RootToken); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	c, err = testTransitSealCore(t, backend, encrypter)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if sealed, _ := c.Sealed(); sealed {
+		t.Fatal("should be unsealed")
+	}
+
+	// The recovery keys are used to generate a root token and rekey
+	testCore_GenerateRoot_Update_OTP_Common(t, c, result.RecoveryShares[:3])
+	testCore_Rekey_Update_Common(t, c, result.RecoveryShares, result.RootToken, true)
+
+	// Without the remote transit key, the core stays sealed
+	if err := c.Seal(result.RootToken); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/transit")
+	req.Operation = logical.DeleteOperation
+	req.ClientToken = remoteRoot
+	if _, err := remote.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c, err = testTransitSealCore(t, backend, encrypter)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if _, ok := err.(*NonFatalError); !ok {
+		t.Fatalf("expected a non-fatal error, got %v", err)
+	}
+	if sealed, _ := c.Sealed(); !sealed {
+		t.Fatal("should be sealed")
+	}
+}
+
+func TestTransitSeal_BarrierTypeMismatch(t *testing.T) {
+	remote, remoteRoot := testTransitRemote(t)
+	encrypter := &coreTransitEncrypter{core: remote, token: remoteRoot}
+
+	// Initialize with the default seal
+	backend := physical.NewInmem(logformat.NewVaultLogger(log.LevelTrace))
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	c, err := NewCore(testCoreConfig(t, backend, logger))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	TestCoreInit(t, c)
+
+	c, err = testTransitSealCore(t, backend, encrypter)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := c.seal.BarrierConfig(); err == nil {
+		t.Fatal("expected seal type mismatch error")
+	}
+}
+
+func TestNewTransitSealFromConfig(t *testing.T) {
+	if _, err := NewTransitSealFromConfig(map[string]string{
+		"address": "http://127.0.0.1:8200",
+		"token":   "foo",
+	}); err == nil {
+		t.Fatal("expected error without key_name")
+	}
+
+	if _, err := NewTransitSealFromConfig(map[string]string{
+		"address":         "http://127.0.0.1:8200",
+		"token":           "foo",
+		"key_name":        "unseal",
+		"tls_skip_verify": "maybe",
+	}); err == nil {
+		t.Fatal("expected error with invalid tls_skip_verify")
+	}
+
+	seal, err := NewTransitSealFromConfig(map[string]string{
+		"address":    "http://127.0.0.1:8200",
+		"token":      "foo",
+		"key_name":   "unseal",
+		"mount_path": "/auto-unseal/",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	encrypter := seal.encrypter.(*apiTransitEncrypter)
+	if encrypter.mountPath != "auto-unseal" || encrypter.keyName != "unseal" || encrypter.client.Token() != "foo" {
+		t.Fatalf("bad: %#v", encrypter)
+	}
+}
@@ -0,0 +1,348 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+)
+
+const (
+	// standbyReadsRefreshInterval is how often a standby serving reads
+	// checks whether the mount, credential and audit tables changed
+	standbyReadsRefreshInterval = 5 * time.Second
+)
+
+// standbyPhysical sits between the barrier and the physical backend of a
+// core that serves reads as a standby. While those reads are served, it
+// reads around the physical cache, which the active node's writes do not
+// update, and refuses all writes so that only the active node ever changes
+// storage. Refused writes are counted so that the requests attempting them
+// can be forwarded to the active node.
+type standbyPhysical struct {
+	refusedWrites uint64
+	cached        physical.Backend
+	underlying    physical.Backend
+	readOnly      uint32
+}
+
+func (s *standbyPhysical) setReadOnly(readOnly bool) {
+	if readOnly {
+		atomic.StoreUint32(&s.readOnly, 1)
+		return
+	}
+
+	// Anything cached before is stale by now
+	if purgable, ok := s.cached.(physical.Purgable); ok {
+		purgable.Purge()
+	}
+	atomic.StoreUint32(&s.readOnly, 0)
+}
+
+func (s *standbyPhysical) backend() physical.Backend {
+	if atomic.LoadUint32(&s.readOnly) == 1 {
+		return s.underlying
+	}
+	return s.cached
+}
+
+func (s *standbyPhysical) refuseWrite() bool {
+	if atomic.LoadUint32(&s.readOnly) == 1 {
+		atomic.AddUint64(&s.refusedWrites, 1)
+		return true
+	}
+	return false
+}
+
+func (s *standbyPhysical) Put(entry *physical.Entry) error {
+	if s.refuseWrite() {
+		return logical.ErrReadOnly
+	}
+	return s.cached.Put(entry)
+}
+
+func (s *standbyPhysical) Get(key string) (*physical.Entry, error) {
+	return s.backend().Get(key)
+}
+
+func (s *standbyPhysical) Delete(key string) error {
+	if s.refuseWrite() {
+		return logical.ErrReadOnly
+	}
+	return s.cached.Delete(key)
+}
+
+func (s *standbyPhysical) List(prefix string) ([]string, error) {
+	return s.backend().List(prefix)
+}
+
+// standbyRefusedWrites returns the number of storage writes refused so far
+// while serving reads as a standby. A request during which the number changes
+// attempted to write and must be forwarded; with concurrent requests this may
+// forward a request that did not write itself, which is always safe.
+func (c *Core) standbyRefusedWrites() uint64 {
+	if sp, ok := c.barrierPhysical.(*standbyPhysical); ok {
+		return atomic.LoadUint64(&sp.refusedWrites)
+	}
+	return 0
+}
+
+// StandbyReadsEnabled checks if this node is a standby that currently
+// serves reads locally
+func (c *Core) StandbyReadsEnabled() bool {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	return !c.sealed && c.standby && c.standbyReads
+}
+
+// standbyReadAllowed checks if a standby may attempt to serve the request
+// locally. This must be called with the state lock held.
+func (c *Core) standbyReadAllowed(req *logical.Request) bool {
+	if !c.standbyReads {
+		return false
+	}
+
+	switch req.Operation {
+	case logical.ReadOperation, logical.ListOperation, logical.UpdateOperation:
+	default:
+		return false
+	}
+
+	// Logins and response wrapping always create tokens
+	if c.router.LoginPath(req.Path) {
+		return false
+	}
+	if req.WrapInfo != nil && req.WrapInfo.TTL != 0 {
+		return false
+	}
+
+	return c.router.StandbyReadPath(req.Path)
+}
+
+// runStandbyReads keeps the state used to serve reads as a standby up to
+// date until stopCh is closed, at which point the state is torn down again
+func (c *Core) runStandbyReads(doneCh, stopCh chan struct{}) {
+	defer close(doneCh)
+
+	var lastRouting, lastAudit string
+	for {
+		routing, audit, err := c.standbyStateHash()
+		switch {
+		case err != nil:
+			c.logger.Error("core: failed to check standby state", "error", err)
+		case routing != lastRouting || audit != lastAudit:
+			c.stateLock.Lock()
+			if err := c.refreshStandbyReads(routing != lastRouting, audit != lastAudit); err != nil {
+				c.logger.Error("core: failed to set up standby reads, forwarding all requests", "error", err)
+				c.teardownStandbyReads()
+				lastRouting, lastAudit = "", ""
+			} else {
+				lastRouting, lastAudit = routing, audit
+			}
+			c.stateLock.Unlock()
+		}
+
+		select {
+		case <-time.After(standbyReadsRefreshInterval):
+		case <-stopCh:
+			c.stateLock.Lock()
+			if c.standbyReads {
+				c.teardownStandbyReads()
+			}
+			c.stateLock.Unlock()
+			return
+		}
+	}
+}
+
+// standbyStateHash returns hashes of the stored tables a standby builds its
+// routing and its audit state from, so it can tell when the active node
+// changed them
+func (c *Core) standbyStateHash() (string, string, error) {
+	routingKeys := []string{
+		coreMountConfigPath,
+		coreLocalMountConfigPath,
+		coreAuthConfigPath,
+		coreLocalAuthConfigPath,
+	}
+	namespaces, err := c.barrier.List(namespaceStorePath)
+	if err != nil {
+		return "", "", err
+	}
+	for _, id := range namespaces {
+		routingKeys = append(routingKeys, namespaceStorePath+id)
+	}
+	routing, err := c.standbyKeysHash(routingKeys)
+	if err != nil {
+		return "", "", err
+	}
+
+	audit, err := c.standbyKeysHash([]string{
+		coreAuditConfigPath,
+		coreLocalAuditConfigPath,
+		systemBarrierPrefix + auditedHeadersSubPath + auditedHeadersEntry,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return routing, audit, nil
+}
+
+func (c *Core) standbyKeysHash(keys []string) (string, error) {
+	hash := sha256.New()
+	for _, key := range keys {
+		entry, err := c.barrier.Get(key)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(key))
+		if entry != nil {
+			hash.Write(entry.Value)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// refreshStandbyReads sets up the state needed to serve reads as a standby,
+// or if it is already set up, reloads the routing state (mounts, policies,
+// credential backends and namespaces) and the audit state as requested.
+// Unlike postUnseal it neither restores leases nor starts the rollback
+// manager, and storage is read-only throughout. This must be called with the
+// state lock held.
+func (c *Core) refreshStandbyReads(routing, audit bool) error {
+	starting := !c.standbyReads
+	if starting {
+		if sp, ok := c.barrierPhysical.(*standbyPhysical); ok {
+			sp.setReadOnly(true)
+		}
+
+		// Backends and the policy store set up from here on do not cache, as
+		// nothing would tell them about the active node's writes
+		c.standbyReads = true
+		routing, audit = true, true
+	}
+
+	if routing {
+		if !starting {
+			if err := c.teardownStandbyRouting(); err != nil {
+				return err
+			}
+		}
+		if err := c.setupStandbyRouting(); err != nil {
+			return err
+		}
+	}
+	if audit {
+		if !starting {
+			if err := c.teardownAudits(); err != nil {
+				return err
+			}
+		}
+		if err := c.loadAudits(); err != nil {
+			return err
+		}
+		if err := c.setupAudits(); err != nil {
+			return err
+		}
+		if err := c.setupAuditedHeadersConfig(); err != nil {
+			return err
+		}
+	}
+
+	if starting {
+		c.logger.Info("core: serving reads as a standby")
+	}
+	return nil
+}
+
+// setupStandbyRouting loads the mounts, policies, credential backends and
+// namespaces of a standby serving reads
+func (c *Core) setupStandbyRouting() error {
+	if err := c.ensureWrappingKey(); err != nil {
+		return err
+	}
+	if err := c.setupPluginCatalog(); err != nil {
+		return err
+	}
+	if err := c.loadNamespaces(); err != nil {
+		return err
+	}
+	if err := c.loadMounts(); err != nil {
+		return err
+	}
+	if err := c.setupMounts(); err != nil {
+		return err
+	}
+	if err := c.setupPolicyStore(); err != nil {
+		return err
+	}
+	if err := c.loadCredentials(); err != nil {
+		return err
+	}
+	if err := c.setupCredentials(); err != nil {
+		return err
+	}
+	if err := c.setupNamespaces(); err != nil {
+		return err
+	}
+
+	// The expiration manager is only used for lookups; the active node owns
+	// the lease timers
+	c.metricsMutex.Lock()
+	c.expiration = NewExpirationManager(c.router, c.systemBarrierView.SubView(expirationSubPath), c.tokenStore, c.logger)
+	c.tokenStore.SetExpirationManager(c.expiration)
+	c.metricsMutex.Unlock()
+	return nil
+}
+
+// teardownStandbyRouting tears down the state set up by setupStandbyRouting
+func (c *Core) teardownStandbyRouting() error {
+	var result error
+	if err := c.stopExpiration(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error stopping expiration: {{err}}", err))
+	}
+	if err := c.teardownCredentials(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down credentials: {{err}}", err))
+	}
+	if err := c.teardownPolicyStore(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down policy store: {{err}}", err))
+	}
+	if err := c.unloadMounts(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error unloading mounts: {{err}}", err))
+	}
+	if err := c.teardownNamespaces(); err != nil {
+		result = multierror.Append(result, errwrap.Wrapf("error tearing down namespaces: {{err}}", err))
+	}
+	return result
+}
+
+// teardownStandbyReads tears down the state set up by refreshStandbyReads,
+// leaving the rest of the core as it is. This must be called with the state
+// lock held.
+func (c *Core) teardownStandbyReads() {
+	c.standbyReads = false
+	if err := c.teardownAudits(); err != nil {
+		c.logger.Error("core: standby reads teardown failed", "error", err)
+	}
+	if err := c.teardownStandbyRouting(); err != nil {
+		c.logger.Error("core: standby reads teardown failed", "error", err)
+	}
+	if sp, ok := c.barrierPhysical.(*standbyPhysical); ok {
+		sp.setReadOnly(false)
+	}
+}
+
+// checkStandbyRequest returns ErrStandby if a request handled on a standby
+// needs the active node. This must be called with the state lock held.
+func (c *Core) checkStandbyRequest(err error) error {
+	if c.standby && err != nil && errwrap.Contains(err, consts.ErrStandby.Error()) {
+		return consts.ErrStandby
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/consts"
+	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/logical"
+	"github.com/hashicorp/vault/physical"
+	log "github.com/mgutz/logxi/v1"
+)
+
+// testStandbyReadsCores returns an active core and a standby core serving
+// reads, sharing their storage
+func testStandbyReadsCores(t *testing.T) (*Core, *Core, string) {
+	logger := logformat.NewVaultLogger(log.LevelTrace)
+	inm := physical.NewInmem(logger)
+	inmha := physical.NewInmemHA(logger)
+
+	core, err := NewCore(&CoreConfig{
+		Physical:     inm,
+		HAPhysical:   inmha,
+		RedirectAddr: "http://127.0.0.1:8200",
+		DisableMlock: true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keys, root := TestCoreInit(t, core)
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	TestWaitActive(t, core)
+
+	core2, err := NewCore(&CoreConfig{
+		Physical:           inm,
+		HAPhysical:         inmha,
+		RedirectAddr:       "http://127.0.0.1:8500",
+		DisableMlock:       true,
+		EnableStandbyReads: true,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(core2, TestKeyCopy(key)); err != nil {
+			t.Fatalf("unseal err: %s", err)
+		}
+	}
+	testWaitStandbyReads(t, core2, func() bool { return core2.StandbyReadsEnabled() })
+
+	return core, core2, root
+}
+
+func testWaitStandbyReads(t *testing.T, core *Core, cond func() bool) {
+	for start := time.Now(); time.Since(start) < 3*standbyReadsRefreshInterval; {
+		if cond() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the standby")
+}
+
+func testStandbyRequest(t *testing.T, c *Core, token string, op logical.Operation,
+	path string, data map[string]interface{}) (*logical.Response, error) {
+	req := logical.TestRequest(t, op, path)
+	req.ClientToken = token
+	req.Data = data
+	return c.HandleRequest(req)
+}
+
+func TestStandbyReads(t *testing.T) {
+	core, core2, root := testStandbyReadsCores(t)
+
+	_, err := testStandbyRequest(t, core, root, logical.UpdateOperation, "secret/foo",
+		map[string]interface{}{"value": "bar"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Reads are served by the standby
+	resp, err := testStandbyRequest(t, core2, root, logical.ReadOperation, "secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["value"] != "bar" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Writes on the active node are seen right away
+	_, err = testStandbyRequest(t, core, root, logical.UpdateOperation, "secret/foo",
+		map[string]interface{}{"value": "baz"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp, err = testStandbyRequest(t, core2, root, logical.ReadOperation, "secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["value"] != "baz" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Tokens are looked up locally
+	resp, err = testStandbyRequest(t, core2, root, logical.ReadOperation, "auth/token/lookup-self", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["id"] != root {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Writes, and paths not declared safe, need the active node
+	cases := []struct {
+		op   logical.Operation
+		path string
+	}{
+		{logical.UpdateOperation, "secret/foo"},
+		{logical.DeleteOperation, "secret/foo"},
+		{logical.ReadOperation, "sys/mounts"},
+		{logical.UpdateOperation, "auth/token/create"},
+	}
+	for _, tc := range cases {
+		_, err = testStandbyRequest(t, core2, root, tc.op, tc.path, map[string]interface{}{"value": "qux"})
+		if !errwrap.Contains(err, consts.ErrStandby.Error()) {
+			t.Fatalf("%s %s: err: %v", tc.op, tc.path, err)
+		}
+	}
+
+	// The failed write must not have gone through
+	resp, err = testStandbyRequest(t, core, root, logical.ReadOperation, "secret/foo", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["value"] != "baz" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Tokens with limited uses are decremented by the active node
+	resp, err = testStandbyRequest(t, core, root, logical.UpdateOperation, "auth/token/create",
+		map[string]interface{}{"num_uses": 2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = testStandbyRequest(t, core2, resp.Auth.ClientToken, logical.ReadOperation, "secret/foo", nil)
+	if !errwrap.Contains(err, consts.ErrStandby.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestStandbyReads_MountTableChange(t *testing.T) {
+	core, core2, root := testStandbyReadsCores(t)
+
+	_, err := testStandbyRequest(t, core, root, logical.UpdateOperation, "sys/mounts/other",
+		map[string]interface{}{"type": "generic"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = testStandbyRequest(t, core, root, logical.UpdateOperation, "other/foo",
+		map[string]interface{}{"value": "bar"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The standby picks up the new mount
+	testWaitStandbyReads(t, core2, func() bool {
+		resp, err := testStandbyRequest(t, core2, root, logical.ReadOperation, "other/foo", nil)
+		return err == nil && resp != nil && resp.Data["value"] == "bar"
+	})
+
+	// And takes over once the active node goes away
+	if err := core.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	TestWaitActive(t, core2)
+	if core2.StandbyReadsEnabled() {
+		t.Fatal("should not serve standby reads while active")
+	}
+
+	_, err = testStandbyRequest(t, core2, root, logical.UpdateOperation, "other/foo",
+		map[string]interface{}{"value": "baz"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestStandbyReads_Audit(t *testing.T) {
+	core, core2, root := testStandbyReadsCores(t)
+
+	noop := &NoopAudit{}
+	noop2 := &NoopAudit{}
+	core.auditBackends["noop"] = func(*audit.BackendConfig) (audit.Backend, error) {
+		return noop, nil
+	}
+	core2.stateLock.Lock()
+	core2.auditBackends["noop"] = func(*audit.BackendConfig) (audit.Backend, error) {
+		return noop2, nil
+	}
+	core2.stateLock.Unlock()
+
+	_, err := testStandbyRequest(t, core, root, logical.UpdateOperation, "sys/audit/noop",
+		map[string]interface{}{"type": "noop"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = testStandbyRequest(t, core, root, logical.UpdateOperation, "secret/foo",
+		map[string]interface{}{"value": "bar"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The standby picks up the new audit backend
+	testWaitStandbyReads(t, core2, func() bool {
+		_, err := testStandbyRequest(t, core2, root, logical.ReadOperation, "secret/foo", nil)
+		return err == nil && len(noop2.Req) > 0
+	})
+
+	// Requests served locally are audited by the standby
+	reqs, resps := len(noop2.Req), len(noop2.Resp)
+	if _, err := testStandbyRequest(t, core2, root, logical.ReadOperation, "secret/foo", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(noop2.Req) != reqs+1 || len(noop2.Resp) != resps+1 {
+		t.Fatalf("bad: %d %d", len(noop2.Req), len(noop2.Resp))
+	}
+
+	// Requests that are forwarded are left to the active node
+	_, err = testStandbyRequest(t, core2, root, logical.UpdateOperation, "secret/foo",
+		map[string]interface{}{"value": "baz"})
+	if !errwrap.Contains(err, consts.ErrStandby.Error()) {
+		t.Fatalf("err: %v", err)
+	}
+	if len(noop2.Req) != reqs+1 || len(noop2.Resp) != resps+1 {
+		t.Fatalf("bad: %d %d", len(noop2.Req), len(noop2.Resp))
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/logformat"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/salt"
 	"github.com/hashicorp/vault/logical"
 	"github.com/hashicorp/vault/logical/framework"
@@ -231,6 +232,12 @@ func TestCoreUnsealedBackend(t testing.TB, backend physical.Backend) (*Core, [][
 	return core, keys, token
 }
 
+// TestCoreSetMetricsHelper sets the helper serving sys/metrics, as the server
+// does when telemetry is configured
+func TestCoreSetMetricsHelper(core *Core, helper *metricsutil.MetricsHelper) {
+	core.metricsHelper = helper
+}
+
 func testTokenStore(t testing.TB, c *Core) *TokenStore {
 	me := &MountEntry{
 		Table:       credentialTableType,
@@ -422,11 +429,15 @@ func (n *noopAudit) Reload() error {
 type rawHTTP struct{}
 
 func (n *rawHTTP) HandleRequest(req *logical.Request) (*logical.Response, error) {
+	body := []byte("hello world")
+	if req.Path == "echo" {
+		body = req.RawBody
+	}
 	return &logical.Response{
 		Data: map[string]interface{}{
 			logical.HTTPStatusCode:  200,
 			logical.HTTPContentType: "plain/text",
-			logical.HTTPRawBody:     []byte("hello world"),
+			logical.HTTPRawBody:     body,
 		},
 	}, nil
 }
@@ -436,7 +447,10 @@ func (n *rawHTTP) HandleExistenceCheck(req *logical.Request) (bool, bool, error)
 }
 
 func (n *rawHTTP) SpecialPaths() *logical.Paths {
-	return &logical.Paths{Unauthenticated: []string{"*"}}
+	return &logical.Paths{
+		Unauthenticated: []string{"*"},
+		RawRequestBody:  []string{"echo"},
+	}
 }
 
 func (n *rawHTTP) System() logical.SystemView {
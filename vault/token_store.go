@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"crypto/cipher"
 	"encoding/json"
 	"fmt"
 	"regexp"
@@ -85,11 +86,18 @@ type TokenStore struct {
 
 	cubbyholeBackend *CubbyholeBackend
 
-	policyLookupFunc func(string) (*Policy, error)
+	policyLookupFunc func(string, string) (*Policy, error)
+
+	namespaceFunc func(string) *Namespace
+
+	identityPoliciesFunc func(string) ([]string, error)
 
 	tokenLocks []*locksutil.LockEntry
 
 	cubbyholeDestroyer func(*TokenStore, string) error
+
+	// batchTokenAEAD encrypts batch tokens
+	batchTokenAEAD cipher.AEAD
 }
 
 // NewTokenStore is used to construct a token store that is
@@ -105,7 +113,22 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 	}
 
 	if c.policyStore != nil {
-		t.policyLookupFunc = c.policyStore.GetPolicy
+		t.policyLookupFunc = func(namespaceID, name string) (*Policy, error) {
+			ns := c.namespaceByID(namespaceID)
+			if ns == nil {
+				return nil, fmt.Errorf("unknown namespace")
+			}
+			return c.namespacePolicyStore(ns).GetPolicy(name)
+		}
+	}
+
+	t.namespaceFunc = c.namespaceByPath
+
+	t.identityPoliciesFunc = func(entityID string) ([]string, error) {
+		if c.identityStore == nil {
+			return nil, nil
+		}
+		return c.identityStore.entityPolicies(entityID)
 	}
 
 	t.tokenLocks = locksutil.CreateLocks()
@@ -127,6 +150,16 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 				accessorPrefix,
 				parentPrefix,
 				"salt",
+				batchTokenKeyPath,
+			},
+
+			// Lookups only read the token entries
+			StandbyRead: []string{
+				"lookup",
+				"lookup/*",
+				"lookup-self",
+				"lookup-accessor",
+				"lookup-accessor/*",
 			},
 		},
 
@@ -202,6 +235,12 @@ func NewTokenStore(c *Core, config *logical.BackendConfig) (*TokenStore, error)
 						Default:     true,
 						Description: tokenRenewableHelp,
 					},
+
+					"token_type": &framework.FieldSchema{
+						Type:        framework.TypeString,
+						Default:     string(logical.TokenTypeService),
+						Description: tokenTypeHelp,
+					},
 				},
 
 				Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -478,7 +517,7 @@ func (ts *TokenStore) Initialize() error {
 	}
 	ts.salt = salt
 
-	return nil
+	return ts.setupBatchTokenKey()
 }
 
 // TokenEntry is used to represent a given token
@@ -498,9 +537,18 @@ type TokenEntry struct {
 	// Used for audit trails, this is something like "auth/user/login"
 	Path string `json:"path" mapstructure:"path" structs:"path"`
 
+	// If set, the ID of the namespace the token belongs to; the policies of
+	// the token are those of the namespace. Empty for the root namespace.
+	NamespaceID string `json:"namespace_id" mapstructure:"namespace_id" structs:"namespace_id"`
+
 	// Used for auditing. This could include things like "source", "user", "ip"
 	Meta map[string]string `json:"meta" mapstructure:"meta" structs:"meta"`
 
+	// Set if Meta was set by the credential backend the token was issued
+	// by, rather than by whoever created the token; only such metadata can
+	// be used to render templated policies
+	AuthMeta bool `json:"auth_meta,omitempty" mapstructure:"auth_meta" structs:"auth_meta"`
+
 	// Used for operators to be able to associate with the source
 	DisplayName string `json:"display_name" mapstructure:"display_name" structs:"display_name"`
 
@@ -530,6 +578,14 @@ type TokenEntry struct {
 	// backends are subject to those renewal rules.
 	Period time.Duration `json:"period" mapstructure:"period" structs:"period"`
 
+	// If set, the ID of the identity entity the token was issued to; the
+	// policies of the entity are merged into the token on creation
+	EntityID string `json:"entity_id" mapstructure:"entity_id" structs:"entity_id"`
+
+	// The type of the token. Only set for batch tokens, which are never
+	// stored.
+	Type logical.TokenType `json:"type,omitempty" mapstructure:"type" structs:"type"`
+
 	// These are the deprecated fields
 	DisplayNameDeprecated    string        `json:"DisplayName" mapstructure:"DisplayName" structs:"DisplayName"`
 	NumUsesDeprecated        int           `json:"NumUses" mapstructure:"NumUses" structs:"NumUses"`
@@ -566,6 +622,9 @@ type tsRoleEntry struct {
 	// If set, the token entry will have an explicit maximum TTL set, rather
 	// than deferring to role/mount values
 	ExplicitMaxTTL time.Duration `json:"explicit_max_ttl" mapstructure:"explicit_max_ttl" structs:"explicit_max_ttl"`
+
+	// The type of the tokens created using this role
+	TokenType logical.TokenType `json:"token_type" mapstructure:"token_type" structs:"token_type"`
 }
 
 type accessorEntry struct {
@@ -580,6 +639,16 @@ func (ts *TokenStore) SetExpirationManager(exp *ExpirationManager) {
 	ts.expiration = exp
 }
 
+// emitMetrics is invoked periodically to emit the number of tokens
+func (ts *TokenStore) emitMetrics() error {
+	keys, err := ts.view.List(lookupPrefix)
+	if err != nil {
+		return err
+	}
+	metrics.SetGauge([]string{"token", "count"}, float32(len(keys)))
+	return nil
+}
+
 // SaltID is used to apply a salt and hash to an ID to make sure its not reversible
 func (ts *TokenStore) SaltID(id string) string {
 	return ts.salt.SaltID(id)
@@ -663,6 +732,22 @@ func (ts *TokenStore) createAccessor(entry *TokenEntry) error {
 // a newly generated ID if not provided.
 func (ts *TokenStore) create(entry *TokenEntry) error {
 	defer metrics.MeasureSince([]string{"token", "create"}, time.Now())
+	// Add the policies of the entity the token is issued to
+	if entry.EntityID != "" && ts.identityPoliciesFunc != nil {
+		entityPolicies, err := ts.identityPoliciesFunc(entry.EntityID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch entity policies: %v", err)
+		}
+		entry.Policies = append(append([]string{}, entry.Policies...), entityPolicies...)
+	}
+
+	entry.Policies = policyutil.SanitizePolicies(entry.Policies, policyutil.DoNotAddDefaultPolicy)
+
+	// Batch tokens carry the entry themselves
+	if entry.Type == logical.TokenTypeBatch {
+		return ts.createBatch(entry)
+	}
+
 	// Generate an ID if necessary
 	if entry.ID == "" {
 		entryUUID, err := uuid.GenerateUUID()
@@ -672,8 +757,6 @@ func (ts *TokenStore) create(entry *TokenEntry) error {
 		entry.ID = entryUUID
 	}
 
-	entry.Policies = policyutil.SanitizePolicies(entry.Policies, policyutil.DoNotAddDefaultPolicy)
-
 	err := ts.createAccessor(entry)
 	if err != nil {
 		return err
@@ -804,6 +887,10 @@ func (ts *TokenStore) Lookup(id string) (*TokenEntry, error) {
 		return nil, fmt.Errorf("cannot lookup blank token")
 	}
 
+	if isBatchToken(id) {
+		return ts.lookupBatch(id)
+	}
+
 	lock := locksutil.LockForKey(ts.tokenLocks, id)
 	lock.RLock()
 	defer lock.RUnlock()
@@ -890,6 +977,9 @@ func (ts *TokenStore) Revoke(id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot revoke blank token")
 	}
+	if isBatchToken(id) {
+		return fmt.Errorf("batch tokens cannot be revoked")
+	}
 
 	return ts.revokeSalted(ts.SaltID(id))
 }
@@ -1012,6 +1102,9 @@ func (ts *TokenStore) RevokeTree(id string) error {
 	if id == "" {
 		return fmt.Errorf("cannot revoke blank token")
 	}
+	if isBatchToken(id) {
+		return fmt.Errorf("batch tokens cannot be revoked")
+	}
 
 	// Get the salted ID
 	saltedId := ts.SaltID(id)
@@ -1324,6 +1417,12 @@ func (ts *TokenStore) handleCreateCommon(
 			logical.ErrInvalidRequest
 	}
 
+	// Batch tokens cannot be revoked, so neither could their children
+	if parent.Type == logical.TokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot generate child tokens"),
+			logical.ErrInvalidRequest
+	}
+
 	// Check if the client token has sudo/root privileges for the requested path
 	isSudo := ts.System().SudoPrivilege(req.MountPoint+req.Path, req.ClientToken)
 
@@ -1341,6 +1440,7 @@ func (ts *TokenStore) handleCreateCommon(
 		DisplayName     string `mapstructure:"display_name"`
 		NumUses         int    `mapstructure:"num_uses"`
 		Period          string
+		Type            string
 	}
 	if err := mapstructure.WeakDecode(req.Data, &data); err != nil {
 		return logical.ErrorResponse(fmt.Sprintf(
@@ -1353,14 +1453,27 @@ func (ts *TokenStore) handleCreateCommon(
 			logical.ErrInvalidRequest
 	}
 
+	// The token store is shared by all namespaces and exposed under the path
+	// of each; tokens belong to the namespace they are created through
+	ns := rootNamespace
+	if ts.namespaceFunc != nil {
+		ns = ts.namespaceFunc(req.MountPoint)
+	}
+	if ns.entryID() != parent.NamespaceID && !isSudo {
+		return logical.ErrorResponse("root or sudo privileges required to create tokens in another namespace"),
+			logical.ErrInvalidRequest
+	}
+
 	// Setup the token entry
 	te := TokenEntry{
 		Parent: req.ClientToken,
 
-		// The mount point is always the same since we have only one token
-		// store; using req.MountPoint causes trouble in tests since they don't
-		// have an official mount
-		Path: fmt.Sprintf("auth/token/%s", req.Path),
+		// The mount point is always the same within a namespace since we have
+		// only one token store; using req.MountPoint causes trouble in tests
+		// since they don't have an official mount
+		Path: fmt.Sprintf("%sauth/token/%s", ns.Path, req.Path),
+
+		NamespaceID: ns.entryID(),
 
 		Meta:         data.Metadata,
 		DisplayName:  "token",
@@ -1373,6 +1486,11 @@ func (ts *TokenStore) handleCreateCommon(
 		renewable = *data.Renewable
 	}
 
+	tokenType, err := logical.ParseTokenType(data.Type)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
 	// If the role is not nil, we add the role name as part of the token's
 	// path. This makes it much easier to later revoke tokens that were issued
 	// by a role (using revoke-prefix). Users can further specify a PathSuffix
@@ -1390,6 +1508,19 @@ func (ts *TokenStore) handleCreateCommon(
 		if role.PathSuffix != "" {
 			te.Path = fmt.Sprintf("%s/%s", te.Path, role.PathSuffix)
 		}
+
+		// The role decides the type of its tokens
+		if role.TokenType != logical.TokenTypeDefault {
+			if data.Type != "" && tokenType != role.TokenType {
+				return logical.ErrorResponse(fmt.Sprintf("token type %q is not allowed by the role", data.Type)), logical.ErrInvalidRequest
+			}
+			tokenType = role.TokenType
+		}
+	}
+
+	if tokenType == logical.TokenTypeBatch {
+		te.Type = logical.TokenTypeBatch
+		renewable = false
 	}
 
 	// Attach the given display name if any
@@ -1699,11 +1830,12 @@ func (ts *TokenStore) handleCreateCommon(
 		},
 		ClientToken: te.ID,
 		Accessor:    te.Accessor,
+		TokenType:   tokenType,
 	}
 
 	if ts.policyLookupFunc != nil {
 		for _, p := range te.Policies {
-			policy, err := ts.policyLookupFunc(p)
+			policy, err := ts.policyLookupFunc(te.NamespaceID, p)
 			if err != nil {
 				return logical.ErrorResponse(fmt.Sprintf("could not look up policy %s", p)), nil
 			}
@@ -1828,13 +1960,18 @@ func (ts *TokenStore) handleLookup(
 		return logical.ErrorResponse("missing token ID"), logical.ErrInvalidRequest
 	}
 
-	lock := locksutil.LockForKey(ts.tokenLocks, id)
-	lock.RLock()
-	defer lock.RUnlock()
-
 	// Lookup the token
-	saltedId := ts.SaltID(id)
-	out, err := ts.lookupSalted(saltedId, true)
+	var out *TokenEntry
+	var err error
+	if isBatchToken(id) {
+		out, err = ts.lookupBatch(id)
+	} else {
+		lock := locksutil.LockForKey(ts.tokenLocks, id)
+		lock.RLock()
+		defer lock.RUnlock()
+
+		out, err = ts.lookupSalted(ts.SaltID(id), true)
+	}
 
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -1860,6 +1997,8 @@ func (ts *TokenStore) handleLookup(
 			"creation_ttl":     int64(out.TTL.Seconds()),
 			"ttl":              int64(0),
 			"explicit_max_ttl": int64(out.ExplicitMaxTTL.Seconds()),
+			"entity_id":        out.EntityID,
+			"type":             logical.TokenTypeService,
 		},
 	}
 
@@ -1867,6 +2006,10 @@ func (ts *TokenStore) handleLookup(
 		resp.Data["orphan"] = true
 	}
 
+	if out.NamespaceID != "" {
+		resp.Data["namespace_id"] = out.NamespaceID
+	}
+
 	if out.Role != "" {
 		resp.Data["role"] = out.Role
 	}
@@ -1874,6 +2017,17 @@ func (ts *TokenStore) handleLookup(
 		resp.Data["period"] = int64(out.Period.Seconds())
 	}
 
+	// Batch tokens have no lease; they expire after their TTL
+	if out.Type == logical.TokenTypeBatch {
+		resp.Data["type"] = logical.TokenTypeBatch
+		resp.Data["renewable"] = false
+		resp.Data["ttl"] = int64(time.Unix(out.CreationTime, 0).Add(out.TTL).Sub(time.Now().Round(time.Second)).Seconds())
+		if urltoken {
+			resp.AddWarning(`Using a token in the path is unsafe as the token can be logged in many places. Please use POST or PUT with the token passed in via the "token" parameter.`)
+		}
+		return resp, nil
+	}
+
 	// Fetch the last renewal time
 	leaseTimes, err := ts.expiration.FetchLeaseTimesByToken(out.Path, out.ID)
 	if err != nil {
@@ -1934,6 +2088,9 @@ func (ts *TokenStore) handleRenew(
 	if te == nil {
 		return logical.ErrorResponse("token not found"), logical.ErrInvalidRequest
 	}
+	if te.Type == logical.TokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot be renewed"), logical.ErrInvalidRequest
+	}
 
 	// Renew the token and its children
 	resp, err := ts.expiration.RenewToken(req, te.Path, te.ID, increment)
@@ -2093,6 +2250,7 @@ func (ts *TokenStore) tokenStoreRoleRead(
 			"orphan":              role.Orphan,
 			"path_suffix":         role.PathSuffix,
 			"renewable":           role.Renewable,
+			"token_type":          role.TokenType,
 		},
 	}
 
@@ -2156,6 +2314,18 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(
 		entry.Renewable = data.Get("renewable").(bool)
 	}
 
+	tokenTypeStr, ok := data.GetOk("token_type")
+	if !ok && req.Operation == logical.CreateOperation {
+		tokenTypeStr, ok = data.Get("token_type"), true
+	}
+	if ok {
+		tokenType, err := logical.ParseTokenType(tokenTypeStr.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		entry.TokenType = tokenType
+	}
+
 	var resp *logical.Response
 
 	explicitMaxTTLInt, ok := data.GetOk("explicit_max_ttl")
@@ -2255,6 +2425,9 @@ policy names.`
 no policies in the given list are requested. The parameter is a comma-delimited string of policy names.`
 	tokenOrphanHelp = `If true, tokens created via this role
 will be orphan tokens (have no parent)`
+	tokenTypeHelp = `The type of the tokens created via this role: "service"
+(the default) or "batch". Batch tokens are not persisted; they cannot be
+renewed or revoked and have no accessor.`
 	tokenPeriodHelp = `If set, tokens created via this role
 will have no max lifetime; instead, their
 renewal period will be fixed to this value.
@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/strutil"
+	"github.com/hashicorp/vault/logical"
+)
+
+const (
+	// batchTokenPrefix starts the ID of every batch token, so batch tokens
+	// can be told apart without decrypting them
+	batchTokenPrefix = "b."
+
+	// batchTokenKeyPath is the path of the key batch tokens are encrypted
+	// with in the token store view
+	batchTokenKeyPath = "batch-key"
+)
+
+// batchToken is the content of a batch token. The short names keep the
+// token small since it is sent with every request.
+type batchToken struct {
+	Parent       string            `json:"pa,omitempty"`
+	Policies     []string          `json:"po"`
+	Path         string            `json:"pt"`
+	NamespaceID  string            `json:"ns,omitempty"`
+	Meta         map[string]string `json:"m,omitempty"`
+	AuthMeta     bool              `json:"am,omitempty"`
+	DisplayName  string            `json:"dn"`
+	CreationTime int64             `json:"ct"`
+	TTL          time.Duration     `json:"t"`
+	Role         string            `json:"r,omitempty"`
+	EntityID     string            `json:"e,omitempty"`
+}
+
+// isBatchToken checks if the token ID is that of a batch token
+func isBatchToken(id string) bool {
+	return strings.HasPrefix(id, batchTokenPrefix)
+}
+
+// setupBatchTokenKey loads the key batch tokens are encrypted with,
+// generating it on first use
+func (ts *TokenStore) setupBatchTokenKey() error {
+	entry, err := ts.view.Get(batchTokenKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read batch token key: %v", err)
+	}
+
+	var key []byte
+	if entry != nil {
+		key = entry.Value
+	} else {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return fmt.Errorf("failed to generate batch token key: %v", err)
+		}
+		if err := ts.view.Put(&logical.StorageEntry{Key: batchTokenKeyPath, Value: key}); err != nil {
+			return fmt.Errorf("failed to persist batch token key: %v", err)
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	ts.batchTokenAEAD, err = cipher.NewGCM(block)
+	return err
+}
+
+// createBatch sets the ID of the entry to a batch token carrying it. Nothing
+// is written to storage.
+func (ts *TokenStore) createBatch(entry *TokenEntry) error {
+	switch {
+	case entry.ID != "":
+		return fmt.Errorf("batch tokens cannot have a custom ID")
+	case entry.NumUses != 0:
+		return fmt.Errorf("batch tokens cannot have a limited number of uses")
+	case entry.Period != 0:
+		return fmt.Errorf("batch tokens cannot be periodic")
+	case entry.TTL <= 0:
+		return fmt.Errorf("batch tokens must have a TTL")
+	case strutil.StrListContains(entry.Policies, "root"):
+		return fmt.Errorf("batch tokens cannot be root tokens")
+	}
+
+	if entry.Parent != "" {
+		parent, err := ts.Lookup(entry.Parent)
+		if err != nil {
+			return fmt.Errorf("failed to lookup parent: %v", err)
+		}
+		if parent == nil {
+			return fmt.Errorf("parent token not found")
+		}
+		if parent.Type == logical.TokenTypeBatch {
+			return fmt.Errorf("batch tokens cannot be parents")
+		}
+	}
+
+	plaintext, err := json.Marshal(&batchToken{
+		Parent:       entry.Parent,
+		Policies:     entry.Policies,
+		Path:         entry.Path,
+		NamespaceID:  entry.NamespaceID,
+		Meta:         entry.Meta,
+		AuthMeta:     entry.AuthMeta,
+		DisplayName:  entry.DisplayName,
+		CreationTime: entry.CreationTime,
+		TTL:          entry.TTL,
+		Role:         entry.Role,
+		EntityID:     entry.EntityID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode batch token: %v", err)
+	}
+
+	nonce := make([]byte, ts.batchTokenAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	ciphertext := ts.batchTokenAEAD.Seal(nonce, nonce, plaintext, nil)
+
+	entry.ID = batchTokenPrefix + base64.RawURLEncoding.EncodeToString(ciphertext)
+	entry.Accessor = ""
+	return nil
+}
+
+// lookupBatch decrypts a batch token. As with stored tokens, a nil entry
+// is returned for tokens that are not valid, which for batch tokens are
+// those that expired or whose parent was revoked.
+func (ts *TokenStore) lookupBatch(id string) (*TokenEntry, error) {
+	ciphertext, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(id, batchTokenPrefix))
+	if err != nil {
+		return nil, nil
+	}
+	nonceSize := ts.batchTokenAEAD.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, nil
+	}
+	plaintext, err := ts.batchTokenAEAD.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil {
+		return nil, nil
+	}
+
+	var bt batchToken
+	if err := json.Unmarshal(plaintext, &bt); err != nil {
+		return nil, fmt.Errorf("failed to decode batch token: %v", err)
+	}
+
+	if time.Now().After(time.Unix(bt.CreationTime, 0).Add(bt.TTL)) {
+		return nil, nil
+	}
+
+	// Batch tokens go away along with their parent
+	if bt.Parent != "" {
+		parent, err := ts.Lookup(bt.Parent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup parent: %v", err)
+		}
+		if parent == nil {
+			return nil, nil
+		}
+	}
+
+	return &TokenEntry{
+		ID:           id,
+		Type:         logical.TokenTypeBatch,
+		Parent:       bt.Parent,
+		Policies:     bt.Policies,
+		Path:         bt.Path,
+		NamespaceID:  bt.NamespaceID,
+		Meta:         bt.Meta,
+		AuthMeta:     bt.AuthMeta,
+		DisplayName:  bt.DisplayName,
+		CreationTime: bt.CreationTime,
+		TTL:          bt.TTL,
+		Role:         bt.Role,
+		EntityID:     bt.EntityID,
+	}, nil
+}
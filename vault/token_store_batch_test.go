@@ -0,0 +1,269 @@
+package vault
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestTokenStore_BatchToken(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+
+	// Create a parent so that the batch token can be invalidated
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = root
+	req.Data["policies"] = []string{"root"}
+	resp, err := c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	parent := resp.Auth.ClientToken
+
+	before, err := c.tokenStore.view.List(lookupPrefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/token/create")
+	req.ClientToken = parent
+	req.Data["policies"] = []string{"foo"}
+	req.Data["type"] = "batch"
+	req.Data["ttl"] = "1h"
+	req.Data["meta"] = map[string]string{"user": "armon"}
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	token := resp.Auth.ClientToken
+	if !strings.HasPrefix(token, batchTokenPrefix) {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+	if resp.Auth.Accessor != "" || resp.Auth.Renewable || resp.Auth.TokenType != logical.TokenTypeBatch {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// Nothing is persisted
+	after, err := c.tokenStore.view.List(lookupPrefix)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("batch token was stored: before %v, after %v", before, after)
+	}
+
+	te, err := c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expect := &TokenEntry{
+		ID:           token,
+		Type:         logical.TokenTypeBatch,
+		Parent:       parent,
+		Policies:     []string{"default", "foo"},
+		Path:         "auth/token/create",
+		Meta:         map[string]string{"user": "armon"},
+		DisplayName:  "token",
+		CreationTime: te.CreationTime,
+		TTL:          time.Hour,
+	}
+	if !reflect.DeepEqual(te, expect) {
+		t.Fatalf("bad: expected:%#v\nactual:%#v", expect, te)
+	}
+
+	// The token can be used and looked up
+	req = logical.TestRequest(t, logical.ReadOperation, "auth/token/lookup-self")
+	req.ClientToken = token
+	resp, err = c.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["type"] != logical.TokenTypeBatch || resp.Data["renewable"] != false || resp.Data["accessor"] != "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if ttl := resp.Data["ttl"].(int64); ttl <= 0 || ttl > 3600 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// But not renewed, used for the cubbyhole or to create other tokens
+	cases := []string{"auth/token/renew-self", "cubbyhole/foo", "auth/token/create"}
+	for _, path := range cases {
+		req = logical.TestRequest(t, logical.UpdateOperation, path)
+		req.ClientToken = token
+		req.Data["foo"] = "bar"
+		if _, err := c.HandleRequest(req); err == nil {
+			t.Fatalf("%s: expected error", path)
+		}
+	}
+	if err := c.tokenStore.Revoke(token); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Revoking the parent invalidates the token
+	if err := c.tokenStore.RevokeTree(parent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	te, err = c.tokenStore.Lookup(token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te != nil {
+		t.Fatalf("bad: %#v", te)
+	}
+}
+
+func TestTokenStore_BatchToken_Invalid(t *testing.T) {
+	_, ts, _, root := TestCoreWithTokenStore(t)
+
+	cases := map[string]map[string]interface{}{
+		"root":     {"type": "batch", "policies": []string{"root"}},
+		"id":       {"type": "batch", "id": "foo"},
+		"num_uses": {"type": "batch", "num_uses": 2},
+		"period":   {"type": "batch", "period": "1h"},
+		"type":     {"type": "foo"},
+	}
+	for name, data := range cases {
+		req := logical.TestRequest(t, logical.UpdateOperation, "create")
+		req.ClientToken = root
+		req.Data = data
+		if _, err := ts.HandleRequest(req); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+
+	// Tampered tokens are not valid
+	te := &TokenEntry{
+		Type:         logical.TokenTypeBatch,
+		Policies:     []string{"foo"},
+		TTL:          time.Hour,
+		CreationTime: time.Now().Unix(),
+	}
+	if err := ts.create(te); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out, err := ts.Lookup(te.ID); err != nil || out == nil {
+		t.Fatalf("bad: %#v %v", out, err)
+	}
+	for _, id := range []string{te.ID[:len(te.ID)-2], te.ID + "AA", batchTokenPrefix + "foo"} {
+		out, err := ts.Lookup(id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out != nil {
+			t.Fatalf("bad: %#v", out)
+		}
+	}
+
+	// Neither are expired ones
+	te = &TokenEntry{
+		Type:         logical.TokenTypeBatch,
+		Policies:     []string{"foo"},
+		TTL:          time.Hour,
+		CreationTime: time.Now().Add(-2 * time.Hour).Unix(),
+	}
+	if err := ts.createBatch(te); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := ts.Lookup(te.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestTokenStore_BatchToken_Role(t *testing.T) {
+	_, ts, _, root := TestCoreWithTokenStore(t)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "roles/test")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"token_type": "batch",
+	}
+	resp, err := ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "roles/test")
+	req.ClientToken = root
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if resp.Data["token_type"] != logical.TokenTypeBatch {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "create/test")
+	req.ClientToken = root
+	req.Data["policies"] = []string{"foo"}
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if !isBatchToken(resp.Auth.ClientToken) {
+		t.Fatalf("bad: %#v", resp.Auth)
+	}
+
+	// The role does not allow asking for another type
+	req.Data["type"] = "service"
+	if _, err := ts.HandleRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "roles/test")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"token_type": "foo",
+	}
+	resp, err = ts.HandleRequest(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected error: %#v", resp)
+	}
+}
+
+func TestCore_HandleLogin_BatchToken(t *testing.T) {
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies:  []string{"foo"},
+				TokenType: logical.TokenTypeBatch,
+			},
+		},
+	}
+	c, _, root := TestCoreUnsealed(t)
+	c.credentialBackends["noop"] = func(conf *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/foo")
+	req.Data["type"] = "noop"
+	req.ClientToken = root
+	if _, err := c.HandleRequest(req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	lresp, err := c.HandleRequest(&logical.Request{Path: "auth/foo/login"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !isBatchToken(lresp.Auth.ClientToken) || lresp.Auth.Renewable || lresp.Auth.Accessor != "" {
+		t.Fatalf("bad: %#v", lresp.Auth)
+	}
+
+	te, err := c.tokenStore.Lookup(lresp.Auth.ClientToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if te == nil || te.Path != "auth/foo/login" || !reflect.DeepEqual(te.Policies, []string{"default", "foo"}) {
+		t.Fatalf("bad: %#v", te)
+	}
+}
@@ -1358,6 +1358,8 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"creation_ttl":     int64(0),
 		"ttl":              int64(0),
 		"explicit_max_ttl": int64(0),
+		"entity_id":        "",
+		"type":             logical.TokenTypeService,
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1396,7 +1398,9 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"creation_ttl":     int64(3600),
 		"ttl":              int64(3600),
 		"explicit_max_ttl": int64(0),
+		"entity_id":        "",
 		"renewable":        true,
+		"type":             logical.TokenTypeService,
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1438,7 +1442,9 @@ func TestTokenStore_HandleRequest_Lookup(t *testing.T) {
 		"creation_ttl":     int64(3600),
 		"ttl":              int64(3600),
 		"explicit_max_ttl": int64(0),
+		"entity_id":        "",
 		"renewable":        true,
+		"type":             logical.TokenTypeService,
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1509,6 +1515,8 @@ func TestTokenStore_HandleRequest_LookupSelf(t *testing.T) {
 		"creation_ttl":     int64(0),
 		"ttl":              int64(0),
 		"explicit_max_ttl": int64(0),
+		"entity_id":        "",
+		"type":             logical.TokenTypeService,
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -1666,6 +1674,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
 		"renewable":           true,
+		"token_type":          logical.TokenTypeService,
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {
@@ -1710,6 +1719,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"explicit_max_ttl":    int64(0),
 		"renewable":           false,
+		"token_type":          logical.TokenTypeService,
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {
@@ -1747,6 +1757,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"path_suffix":         "happenin",
 		"period":              int64(0),
 		"renewable":           false,
+		"token_type":          logical.TokenTypeService,
 	}
 
 	if !reflect.DeepEqual(expected, resp.Data) {